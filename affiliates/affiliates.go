@@ -0,0 +1,135 @@
+package affiliates
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"linkshrink/splits"
+)
+
+// TotalBPS is 100% in basis points.
+const TotalBPS = 10000
+
+// MaxCodeLength is the longest affiliate code accepted.
+const MaxCodeLength = 64
+
+// tokenPrefix marks affiliate earnings tokens, so they are recognizable.
+const tokenPrefix = "aff_"
+
+// Affiliate is a partner of an owner who refers buyers to the owner's routes
+// with a code and earns commission on the purchases they make.
+type Affiliate struct {
+	ID     uint64 `json:"id"`
+	UserID uint64 `json:"-"`
+	// RouteID is the route the affiliate earns commission on, 0 on all of the
+	// owner's routes.
+	RouteID uint64 `json:"route_id,omitempty"`
+	Code    string `json:"code"`
+	// PayoutAddress is the address the commission is owed to.
+	PayoutAddress string `json:"payout_address"`
+	Label         string `json:"label,omitempty"`
+	// CommissionBPS is the share of each referred purchase in basis points.
+	CommissionBPS uint64 `json:"commission_bps"`
+
+	// Earnings sums the settled purchases the affiliate referred, it is only
+	// loaded by the store methods that say so.
+	Earnings Earnings `json:"earnings"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Earnings sums the settled purchases an affiliate referred, on mainnet and
+// on testnet. Amounts are in base units (USDC * 10^6).
+type Earnings struct {
+	SaleCount      uint64 `json:"sale_count"`
+	Sales          uint64 `json:"sales"`
+	Commission     uint64 `json:"commission"`
+	TestSaleCount  uint64 `json:"test_sale_count"`
+	TestSales      uint64 `json:"test_sales"`
+	TestCommission uint64 `json:"test_commission"`
+}
+
+// Sale is a settled purchase an affiliate referred.
+type Sale struct {
+	PurchaseID uint64 `json:"purchase_id"`
+	ShortCode  string `json:"short_code"`
+	// Price and Commission are in base units (USDC * 10^6).
+	Price      uint64 `json:"price"`
+	Commission uint64 `json:"commission"`
+	IsTest     bool   `json:"is_test"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Commission returns the share of a purchase owed to the affiliate that
+// referred it, nil if no affiliate did.
+func Commission(affiliate *Affiliate) *splits.Commission {
+	if affiliate == nil {
+		return nil
+	}
+	return &splits.Commission{
+		PayeeAddress: affiliate.PayoutAddress,
+		ShareBPS:     affiliate.CommissionBPS,
+	}
+}
+
+// NormalizeCode returns code the way affiliate codes are stored, codes are
+// not case sensitive.
+func NormalizeCode(code string) string {
+	return strings.ToLower(strings.TrimSpace(code))
+}
+
+// validCode reports whether a normalized code can be used in referral links.
+func validCode(code string) bool {
+	if code == "" || len(code) > MaxCodeLength {
+		return false
+	}
+	for _, r := range code {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeAddress returns address the way payout addresses are stored, EVM
+// addresses are not case sensitive.
+func NormalizeAddress(address string) string {
+	return strings.ToLower(strings.TrimSpace(address))
+}
+
+// validAddress reports whether a normalized address is a hex EVM address.
+func validAddress(address string) bool {
+	if len(address) != 42 || !strings.HasPrefix(address, "0x") {
+		return false
+	}
+	for _, r := range address[2:] {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// NewToken generates an affiliate earnings token and returns it with the hash
+// it is stored as.
+func NewToken() (token string, tokenHash string, err error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", "", fmt.Errorf("failed to generate affiliate token: %w", err)
+	}
+
+	token = tokenPrefix + hex.EncodeToString(tokenBytes)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the SHA-256 of an affiliate earnings token, the token
+// itself is never stored.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,70 @@
+package affiliates
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// TokenHeader carries the earnings token of an affiliate, alternatively
+	// passed as the TokenQueryParam query parameter.
+	TokenHeader     = "Proxy402-Affiliate"
+	TokenQueryParam = "token"
+)
+
+// Handler handles HTTP requests of affiliates.
+type Handler struct {
+	affiliateService *Service
+}
+
+// NewHandler creates a new Handler.
+func NewHandler(affiliateService *Service) *Handler {
+	return &Handler{
+		affiliateService: affiliateService,
+	}
+}
+
+// EarningsResponse represents an affiliate with its earnings and latest
+// referred sales.
+type EarningsResponse struct {
+	Affiliate *Affiliate `json:"affiliate"`
+	Sales     []Sale     `json:"sales"`
+}
+
+// GetEarnings returns the earnings and latest referred sales of the affiliate
+// identified by its earnings token. The view is read-only.
+func (h *Handler) GetEarnings(gCtx *gin.Context) {
+	token := gCtx.GetHeader(TokenHeader)
+	if token == "" {
+		token = gCtx.Query(TokenQueryParam)
+	}
+
+	ctx := gCtx.Request.Context()
+	affiliate, err := h.affiliateService.Authenticate(ctx, token)
+	if err != nil {
+		if errors.Is(err, ErrAffiliateNotFound) {
+			gCtx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else {
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve affiliate"})
+		}
+		return
+	}
+
+	sales, err := h.affiliateService.ListSales(ctx, affiliate.ID)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve affiliate sales"})
+		return
+	}
+	if sales == nil {
+		sales = []Sale{}
+	}
+
+	gCtx.JSON(http.StatusOK, EarningsResponse{
+		Affiliate: affiliate,
+		Sales:     sales,
+	})
+}
@@ -0,0 +1,44 @@
+package affiliates
+
+import (
+	"context"
+	"errors"
+)
+
+// Custom errors for affiliate operations
+var (
+	ErrAffiliateNotFound    = errors.New("affiliate not found")
+	ErrCodeTaken            = errors.New("affiliate code is already in use")
+	ErrInvalidCode          = errors.New("affiliate code must be 1-64 letters, digits, dashes or underscores")
+	ErrInvalidCommission    = errors.New("affiliate commission must be between 1 and 10000 basis points")
+	ErrInvalidPayoutAddress = errors.New("affiliate payout address must be a 0x-prefixed 20 byte hex address")
+)
+
+// Store provides access to the affiliate storage.
+type Store interface {
+	// CreateAffiliate creates an affiliate with the hash of its earnings
+	// token, ErrCodeTaken if the owner already has an affiliate with its code.
+	CreateAffiliate(ctx context.Context, affiliate *Affiliate, tokenHash string) (*Affiliate, error)
+
+	// ListAffiliatesByUserID retrieves the affiliates of an owner with their
+	// earnings.
+	ListAffiliatesByUserID(ctx context.Context, userID uint64) ([]Affiliate, error)
+
+	// GetAffiliate retrieves an affiliate of an owner with its earnings.
+	GetAffiliate(ctx context.Context, affiliateID, userID uint64) (*Affiliate, error)
+
+	// GetAffiliateByTokenHash retrieves the affiliate an earnings token
+	// belongs to with its earnings, ErrAffiliateNotFound if there is none.
+	GetAffiliateByTokenHash(ctx context.Context, tokenHash string) (*Affiliate, error)
+
+	// GetAffiliateForRoute retrieves an owner's affiliate by code if it earns
+	// commission on the route.
+	GetAffiliateForRoute(ctx context.Context, userID, routeID uint64, code string) (*Affiliate, error)
+
+	// DeleteAffiliate deletes an affiliate of an owner.
+	DeleteAffiliate(ctx context.Context, affiliateID, userID uint64) error
+
+	// ListAffiliateSales retrieves up to limit of the latest settled
+	// purchases an affiliate referred.
+	ListAffiliateSales(ctx context.Context, affiliateID uint64, limit uint64) ([]Sale, error)
+}
@@ -0,0 +1,94 @@
+package affiliates
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// maxSalesListed caps how many referred sales are listed at once.
+const maxSalesListed = 100
+
+// Service provides business logic for affiliates.
+type Service struct {
+	logger *slog.Logger
+	store  Store
+}
+
+// NewService creates a new affiliate Service.
+func NewService(logger *slog.Logger, store Store) *Service {
+	return &Service{
+		logger: logger,
+		store:  store,
+	}
+}
+
+// CreateAffiliate validates and creates an affiliate. The affiliate's
+// earnings token is returned, it is not stored and can't be retrieved again.
+func (s *Service) CreateAffiliate(ctx context.Context, affiliate *Affiliate) (*Affiliate, string, error) {
+	affiliate.Code = NormalizeCode(affiliate.Code)
+	if !validCode(affiliate.Code) {
+		return nil, "", ErrInvalidCode
+	}
+	affiliate.PayoutAddress = NormalizeAddress(affiliate.PayoutAddress)
+	if !validAddress(affiliate.PayoutAddress) {
+		return nil, "", ErrInvalidPayoutAddress
+	}
+	if affiliate.CommissionBPS == 0 || affiliate.CommissionBPS > TotalBPS {
+		return nil, "", ErrInvalidCommission
+	}
+	affiliate.Label = strings.TrimSpace(affiliate.Label)
+
+	token, tokenHash, err := NewToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	created, err := s.store.CreateAffiliate(ctx, affiliate, tokenHash)
+	if err != nil {
+		return nil, "", err
+	}
+	return created, token, nil
+}
+
+// ListAffiliates retrieves the affiliates of an owner with their earnings.
+func (s *Service) ListAffiliates(ctx context.Context, userID uint64) ([]Affiliate, error) {
+	return s.store.ListAffiliatesByUserID(ctx, userID)
+}
+
+// GetAffiliate retrieves an affiliate of an owner with its earnings.
+func (s *Service) GetAffiliate(ctx context.Context, affiliateID, userID uint64) (*Affiliate, error) {
+	return s.store.GetAffiliate(ctx, affiliateID, userID)
+}
+
+// DeleteAffiliate deletes an affiliate of an owner, its code stops being
+// credited. The commission of its past sales is kept.
+func (s *Service) DeleteAffiliate(ctx context.Context, affiliateID, userID uint64) error {
+	return s.store.DeleteAffiliate(ctx, affiliateID, userID)
+}
+
+// FindAffiliate retrieves the affiliate of the route's owner a referral code
+// belongs to.
+func (s *Service) FindAffiliate(ctx context.Context, ownerID, routeID uint64,
+	code string) (*Affiliate, error) {
+
+	code = NormalizeCode(code)
+	if !validCode(code) {
+		return nil, ErrAffiliateNotFound
+	}
+	return s.store.GetAffiliateForRoute(ctx, ownerID, routeID, code)
+}
+
+// Authenticate retrieves the affiliate an earnings token belongs to.
+func (s *Service) Authenticate(ctx context.Context, token string) (*Affiliate, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, ErrAffiliateNotFound
+	}
+	return s.store.GetAffiliateByTokenHash(ctx, HashToken(token))
+}
+
+// ListSales retrieves the latest settled purchases an affiliate referred.
+func (s *Service) ListSales(ctx context.Context, affiliateID uint64) ([]Sale, error) {
+	return s.store.ListAffiliateSales(ctx, affiliateID, maxSalesListed)
+}
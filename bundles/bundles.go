@@ -0,0 +1,44 @@
+package bundles
+
+import (
+	"time"
+)
+
+// MaxMembers is the largest number of routes a bundle can unlock.
+const MaxMembers = 100
+
+// Bundle is a paid route whose purchase pays for requests to a collection of
+// its owner's other routes. The bundle route's price and credits are what a
+// purchase costs and how many requests to its members it pays for.
+type Bundle struct {
+	RouteID uint64 `json:"-"`
+	// AccessSeconds limits how long after it was bought a purchase gives
+	// access to the members, 0 means until its credits are used.
+	AccessSeconds uint64 `json:"access_seconds"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExpiresAt returns when a purchase of the bundle made at purchasedAt stops
+// giving access, nil if it never does.
+func (b *Bundle) ExpiresAt(purchasedAt time.Time) *time.Time {
+	if b.AccessSeconds == 0 {
+		return nil
+	}
+	expiresAt := purchasedAt.Add(time.Duration(b.AccessSeconds) * time.Second)
+	return &expiresAt
+}
+
+// Member is a route a bundle unlocks.
+type Member struct {
+	RouteID      uint64  `json:"-"`
+	ShortCode    string  `json:"short_code"`
+	Method       string  `json:"method"`
+	ResourceType string  `json:"resource_type"`
+	Title        *string `json:"title,omitempty"`
+	Description  *string `json:"description,omitempty"`
+	// Price is what the route costs on its own, in base units
+	// (USDC * 10^6).
+	Price                  uint64 `json:"price"`
+	PaymentProtocolVersion uint16 `json:"payment_protocol_version"`
+}
@@ -0,0 +1,34 @@
+package bundles
+
+import (
+	"context"
+	"errors"
+
+	"linkshrink/purchases"
+)
+
+// Custom errors for bundle operations
+var (
+	ErrBundleNotFound = errors.New("bundle not found")
+	ErrNoMembers      = errors.New("a bundle must include at least one route")
+	ErrTooManyMembers = errors.New("a bundle can include at most 100 routes")
+	ErrInvalidMember  = errors.New("route can't be part of the bundle")
+	ErrAccessExpired  = errors.New("bundle purchase has expired")
+)
+
+// Store provides access to the bundle storage. Bundles are created with
+// their route by the paid route store.
+type Store interface {
+	// GetBundle retrieves the bundle of a bundle route.
+	GetBundle(ctx context.Context, routeID uint64) (*Bundle, error)
+
+	// ListBundleMembers retrieves the routes of a bundle that are not
+	// deleted, in the bundle's order.
+	ListBundleMembers(ctx context.Context, routeID uint64) ([]Member, error)
+
+	// GetBundlePurchaseByRouteIDAndPaymentHeader retrieves the latest
+	// settled purchase of a bundle the route is part of that was paid with
+	// the payment header.
+	GetBundlePurchaseByRouteIDAndPaymentHeader(ctx context.Context, routeID uint64,
+		paymentHeader string) (*purchases.Purchase, error)
+}
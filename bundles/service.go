@@ -0,0 +1,61 @@
+package bundles
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"linkshrink/purchases"
+	"linkshrink/utils"
+)
+
+// Service provides business logic for route bundles.
+type Service struct {
+	logger *slog.Logger
+	store  Store
+	clock  utils.Clock
+}
+
+// NewService creates a new bundle Service.
+func NewService(logger *slog.Logger, store Store, clock utils.Clock) *Service {
+	return &Service{
+		logger: logger,
+		store:  store,
+		clock:  clock,
+	}
+}
+
+// GetBundle retrieves the bundle of a bundle route.
+func (s *Service) GetBundle(ctx context.Context, routeID uint64) (*Bundle, error) {
+	return s.store.GetBundle(ctx, routeID)
+}
+
+// ListMembers retrieves the routes a bundle unlocks, in the bundle's order.
+func (s *Service) ListMembers(ctx context.Context, routeID uint64) ([]Member, error) {
+	return s.store.ListBundleMembers(ctx, routeID)
+}
+
+// FindPurchase retrieves the purchase of a bundle the route is part of that
+// was paid with the payment header, and the bundle it bought. Returns
+// purchases.ErrPurchaseNotFound if there is none and ErrAccessExpired if the
+// purchase no longer gives access.
+func (s *Service) FindPurchase(ctx context.Context, routeID uint64,
+	paymentHeader string) (*purchases.Purchase, *Bundle, error) {
+
+	purchase, err := s.store.GetBundlePurchaseByRouteIDAndPaymentHeader(ctx, routeID, paymentHeader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bundle, err := s.store.GetBundle(ctx, purchase.PaidRouteID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get bundle of purchase: %w", err)
+	}
+
+	expiresAt := bundle.ExpiresAt(purchase.CreatedAt)
+	if expiresAt != nil && !s.clock.Now().Before(*expiresAt) {
+		return purchase, bundle, ErrAccessExpired
+	}
+
+	return purchase, bundle, nil
+}
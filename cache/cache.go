@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"linkshrink/utils"
+)
+
+// ErrCacheMiss is returned when no fresh entry exists for a key.
+var ErrCacheMiss = errors.New("cache miss")
+
+// Entry is a cached upstream response.
+type Entry struct {
+	Status    int         `json:"status"`
+	Header    http.Header `json:"header"`
+	Body      []byte      `json:"body"`
+	ExpiresAt time.Time   `json:"expires_at"`
+}
+
+// Backend stores cache entries by key.
+type Backend interface {
+	// Get returns the entry stored under key or ErrCacheMiss.
+	Get(ctx context.Context, key string) (*Entry, error)
+
+	// Set stores the entry under key, replacing any previous value.
+	Set(ctx context.Context, key string, entry *Entry) error
+
+	// Delete removes the entry stored under key, if any.
+	Delete(ctx context.Context, key string) error
+}
+
+// Cache is a TTL-aware response cache on top of a Backend.
+type Cache struct {
+	backend Backend
+	cfg     *Config
+	clock   utils.Clock
+}
+
+// New creates a Cache using the backend selected in the config.
+func New(cfg *Config, clock utils.Clock) (*Cache, error) {
+	var backend Backend
+	switch cfg.Backend {
+	case "", BackendMemory:
+		backend = NewMemoryBackend(cfg.MaxEntries)
+	case BackendDisk:
+		diskBackend, err := NewDiskBackend(cfg.DiskPath)
+		if err != nil {
+			return nil, err
+		}
+		backend = diskBackend
+	default:
+		return nil, fmt.Errorf("unknown cache backend: %s", cfg.Backend)
+	}
+
+	return &Cache{
+		backend: backend,
+		cfg:     cfg,
+		clock:   clock,
+	}, nil
+}
+
+// DefaultTTL returns the TTL used for routes without their own TTL.
+func (c *Cache) DefaultTTL() time.Duration {
+	return time.Duration(c.cfg.DefaultTTLSeconds) * time.Second
+}
+
+// MaxObjectBytes returns the largest body size that will be cached.
+func (c *Cache) MaxObjectBytes() int64 {
+	return c.cfg.MaxObjectBytes
+}
+
+// Get returns a fresh entry for key or ErrCacheMiss.
+func (c *Cache) Get(ctx context.Context, key string) (*Entry, error) {
+	entry, err := c.backend.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.clock.Now().Before(entry.ExpiresAt) {
+		// Expired entries are dropped lazily.
+		_ = c.backend.Delete(ctx, key)
+		return nil, ErrCacheMiss
+	}
+
+	return entry, nil
+}
+
+// Set stores a response under key for the given TTL.
+func (c *Cache) Set(ctx context.Context, key string, status int,
+	header http.Header, body []byte, ttl time.Duration) error {
+
+	if int64(len(body)) > c.cfg.MaxObjectBytes {
+		return fmt.Errorf("response body of %d bytes exceeds cache limit", len(body))
+	}
+
+	return c.backend.Set(ctx, key, &Entry{
+		Status:    status,
+		Header:    StorableHeader(header),
+		Body:      body,
+		ExpiresAt: c.clock.Now().Add(ttl),
+	})
+}
+
+// Key builds the cache key for a request forwarded to a route. The key covers
+// the route, the method, the forwarded path and query, and the values of the
+// headers the route varies on.
+func Key(routeID uint64, method, path, rawQuery string,
+	header http.Header, varyHeaders []string) string {
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n%s\n%s\n%s\n", routeID, method, path, rawQuery)
+
+	names := make([]string, 0, len(varyHeaders))
+	for _, name := range varyHeaders {
+		names = append(names, http.CanonicalHeaderKey(name))
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(h, "%s: %s\n", name, strings.Join(header.Values(name), ","))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ParseVaryHeaders splits a comma separated header list.
+func ParseVaryHeaders(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			names = append(names, http.CanonicalHeaderKey(name))
+		}
+	}
+	return names
+}
+
+// unstoredHeaders are never replayed from a stored response, either because
+// they are hop-by-hop or because they are specific to a single response.
+var unstoredHeaders = map[string]bool{
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+	"Set-Cookie":          true,
+	"Date":                true,
+	"Content-Length":      true,
+}
+
+// StorableHeader returns a copy of a response header without the headers
+// that must not be replayed.
+func StorableHeader(header http.Header) http.Header {
+	stored := make(http.Header, len(header))
+	for name, values := range header {
+		if unstoredHeaders[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		stored[name] = append([]string(nil), values...)
+	}
+	return stored
+}
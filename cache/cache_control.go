@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseCacheControl parses a Cache-Control header into lower-cased directives.
+// Directives without a value map to an empty string.
+func parseCacheControl(header http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range header.Values("Cache-Control") {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			name, arg, _ := strings.Cut(part, "=")
+			directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(arg), `"`)
+		}
+	}
+	return directives
+}
+
+// RequestAllowsCached reports whether the client accepts a cached response.
+func RequestAllowsCached(header http.Header) bool {
+	directives := parseCacheControl(header)
+	_, noCache := directives["no-cache"]
+	_, noStore := directives["no-store"]
+	return !noCache && !noStore
+}
+
+// ResponseTTL returns how long an upstream response may be cached, capped at
+// maxTTL. The second return value is false if the response must not be cached.
+func ResponseTTL(status int, header http.Header, maxTTL time.Duration) (time.Duration, bool) {
+	if status != http.StatusOK || maxTTL <= 0 {
+		return 0, false
+	}
+
+	// Event streams never complete, so there is nothing to store.
+	if strings.HasPrefix(header.Get("Content-Type"), "text/event-stream") {
+		return 0, false
+	}
+
+	// Responses that set cookies are specific to a single client.
+	if len(header.Values("Set-Cookie")) > 0 {
+		return 0, false
+	}
+
+	directives := parseCacheControl(header)
+	for _, name := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := directives[name]; ok {
+			return 0, false
+		}
+	}
+
+	ttl := maxTTL
+	for _, name := range []string{"s-maxage", "max-age"} {
+		arg, ok := directives[name]
+		if !ok {
+			continue
+		}
+		seconds, err := strconv.Atoi(arg)
+		if err != nil || seconds <= 0 {
+			return 0, false
+		}
+		if upstreamTTL := time.Duration(seconds) * time.Second; upstreamTTL < ttl {
+			ttl = upstreamTTL
+		}
+		// s-maxage takes precedence over max-age for shared caches.
+		break
+	}
+
+	return ttl, true
+}
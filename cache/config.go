@@ -0,0 +1,28 @@
+package cache
+
+const (
+	// BackendMemory keeps cached responses in process memory.
+	BackendMemory = "memory"
+	// BackendDisk keeps cached responses as files under DiskPath.
+	BackendDisk = "disk"
+)
+
+// DefaultConfig returns all default values for the Config struct.
+func DefaultConfig() Config {
+	return Config{
+		Backend:           BackendMemory,
+		DiskPath:          "cache",
+		DefaultTTLSeconds: 60,
+		MaxObjectBytes:    1 << 20, // 1 MiB
+		MaxEntries:        1000,
+	}
+}
+
+// Config holds the configuration for the response cache.
+type Config struct {
+	Backend           string `long:"backend" description:"Response cache backend {memory, disk}"`
+	DiskPath          string `long:"disk_path" description:"Directory used by the disk cache backend"`
+	DefaultTTLSeconds int    `long:"default_ttl_seconds" description:"TTL used when a route does not configure one"`
+	MaxObjectBytes    int64  `long:"max_object_bytes" description:"Largest response body that will be cached"`
+	MaxEntries        int    `long:"max_entries" description:"Max number of entries kept by the memory backend"`
+}
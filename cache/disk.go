@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DiskBackend stores each entry as a JSON file named after its key.
+type DiskBackend struct {
+	dir string
+}
+
+// NewDiskBackend creates a DiskBackend rooted at dir, creating it if needed.
+func NewDiskBackend(dir string) (*DiskBackend, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &DiskBackend{dir: dir}, nil
+}
+
+func (b *DiskBackend) path(key string) string {
+	// Keys are hex digests, so they are safe to use as file names.
+	return filepath.Join(b.dir, key+".json")
+}
+
+// Get returns the entry stored under key or ErrCacheMiss.
+func (b *DiskBackend) Get(_ context.Context, key string) (*Entry, error) {
+	data, err := os.ReadFile(b.path(key))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrCacheMiss
+		}
+		return nil, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		// Treat corrupt files as a miss, they will be overwritten.
+		return nil, ErrCacheMiss
+	}
+
+	return &entry, nil
+}
+
+// Set stores the entry under key. The file is written to a temporary name
+// first so concurrent readers never see a partial entry.
+func (b *DiskBackend) Set(_ context.Context, key string, entry *Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(b.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), b.path(key))
+}
+
+// Delete removes the entry stored under key, if any.
+func (b *DiskBackend) Delete(_ context.Context, key string) error {
+	err := os.Remove(b.path(key))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete cache entry: %w", err)
+	}
+	return nil
+}
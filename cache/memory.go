@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// MemoryBackend is an in-process LRU backend.
+type MemoryBackend struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List
+	entries    map[string]*list.Element
+}
+
+type memoryItem struct {
+	key   string
+	entry *Entry
+}
+
+// NewMemoryBackend creates a MemoryBackend holding at most maxEntries entries.
+// A non-positive maxEntries means no limit.
+func NewMemoryBackend(maxEntries int) *MemoryBackend {
+	return &MemoryBackend{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the entry stored under key or ErrCacheMiss.
+func (b *MemoryBackend) Get(_ context.Context, key string) (*Entry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	elem, ok := b.entries[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	b.order.MoveToFront(elem)
+
+	return elem.Value.(*memoryItem).entry, nil
+}
+
+// Set stores the entry under key, evicting the least recently used entries
+// when the backend is full.
+func (b *MemoryBackend) Set(_ context.Context, key string, entry *Entry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elem, ok := b.entries[key]; ok {
+		elem.Value.(*memoryItem).entry = entry
+		b.order.MoveToFront(elem)
+		return nil
+	}
+
+	b.entries[key] = b.order.PushFront(&memoryItem{key: key, entry: entry})
+
+	for b.maxEntries > 0 && b.order.Len() > b.maxEntries {
+		oldest := b.order.Back()
+		b.order.Remove(oldest)
+		delete(b.entries, oldest.Value.(*memoryItem).key)
+	}
+
+	return nil
+}
+
+// Delete removes the entry stored under key, if any.
+func (b *MemoryBackend) Delete(_ context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elem, ok := b.entries[key]; ok {
+		b.order.Remove(elem)
+		delete(b.entries, key)
+	}
+
+	return nil
+}
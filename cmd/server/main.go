@@ -1,22 +1,39 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"log/slog"
 	"os"
+	"time"
 
+	x402http "github.com/coinbase/x402/go/http"
 	betterstackPkg "github.com/samber/slog-betterstack"
 	multiHandlerPkg "github.com/searKing/golang/go/log/slog"
 
+	"linkshrink/affiliates"
 	"linkshrink/auth"
+	"linkshrink/bundles"
+	"linkshrink/cache"
 	"linkshrink/cloudflare"
 	"linkshrink/config"
+	"linkshrink/coupons"
+	"linkshrink/idempotency"
+	"linkshrink/l402"
+	"linkshrink/ledger"
+	"linkshrink/packs"
+	"linkshrink/payers"
 	"linkshrink/purchases"
+	"linkshrink/rails"
+	"linkshrink/reconciler"
 	"linkshrink/routes"
 	"linkshrink/server"
+	"linkshrink/splits"
 	storePkg "linkshrink/store"
+	"linkshrink/upstreams"
 	"linkshrink/users"
 	"linkshrink/utils"
+	"linkshrink/wallets"
 )
 
 //go:embed templates
@@ -91,10 +108,68 @@ func main() {
 		logger.Error("Failed to create Cloudflare service", "error", err)
 		os.Exit(1)
 	}
-	paidRouteService := routes.NewPaidRouteService(logger, store, cloudflareService)
+	paidRouteService := routes.NewPaidRouteService(logger, store, cloudflareService, clock)
 	purchaseService := purchases.NewPurchaseService(logger, store)
 	authService := auth.NewAuthService(&cfg.Auth)
 
+	responseCache, err := cache.New(&cfg.Cache, clock)
+	if err != nil {
+		logger.Error("Failed to create response cache", "error", err)
+		os.Exit(1)
+	}
+
+	upstreamService := upstreams.NewService(logger, store, &cfg.Upstreams, clock)
+	healthChecker := upstreams.NewHealthChecker(logger, store, &cfg.Upstreams)
+	go healthChecker.Run(context.Background())
+
+	splitsService := splits.NewService(logger, store, &cfg.Splits)
+
+	ledgerService := ledger.NewService(logger, store, &cfg.Ledger)
+	go ledgerService.RunAudit(context.Background())
+
+	// Resolve purchases left pending by a restart while their payment was
+	// settled.
+	facilitatorClient := x402http.NewFacilitatorClient(&x402http.FacilitatorConfig{
+		URL: cfg.Routes.X402FacilitatorURL,
+	})
+	purchaseRecovery := purchases.NewRecovery(logger, store, &cfg.Purchases,
+		facilitatorClient, clock)
+	go purchaseRecovery.Run(context.Background())
+
+	routeScheduler := routes.NewScheduler(logger, store, &cfg.Routes, clock)
+	go routeScheduler.Run(context.Background())
+
+	settlementReconciler := reconciler.NewReconciler(logger, store, &cfg.Reconciler,
+		reconciler.NewStoreAlerter(logger, store), clock)
+	go settlementReconciler.Run(context.Background())
+
+	idempotencyService := idempotency.NewService(logger, store, &cfg.Idempotency, clock)
+	go idempotencyService.RunCleanup(context.Background())
+
+	couponService := coupons.NewService(logger, store, clock)
+	payerService := payers.NewService(logger, store)
+	packService := packs.NewService(logger, store)
+	walletService := wallets.NewService(logger, store, &cfg.Wallets, clock)
+	bundleService := bundles.NewService(logger, store, clock)
+	affiliateService := affiliates.NewService(logger, store)
+
+	// Routes may opt in to L402 Lightning payments if a node is configured.
+	var paymentRails []rails.Rail
+	if cfg.L402.LNDURL != "" {
+		lndClient, err := l402.NewLNDClient(cfg.L402.LNDURL, cfg.L402.LNDMacaroonHex,
+			cfg.L402.LNDTLSCertPath, time.Duration(cfg.L402.TimeoutSeconds)*time.Second)
+		if err != nil {
+			logger.Error("Failed to create LND client", "error", err)
+			os.Exit(1)
+		}
+		l402Rail, err := l402.NewRail(lndClient, &cfg.L402)
+		if err != nil {
+			logger.Error("Failed to create L402 payment rail", "error", err)
+			os.Exit(1)
+		}
+		paymentRails = append(paymentRails, l402Rail)
+	}
+
 	// Create and configure the server
 	srv := server.NewServer(
 		userService,
@@ -102,6 +177,19 @@ func main() {
 		purchaseService,
 		authService,
 		cloudflareService,
+		responseCache,
+		upstreamService,
+		splitsService,
+		ledgerService,
+		settlementReconciler,
+		idempotencyService,
+		couponService,
+		payerService,
+		packService,
+		walletService,
+		bundleService,
+		affiliateService,
+		paymentRails,
 
 		templatesFS,
 		staticFS,
@@ -271,6 +271,8 @@ document.addEventListener('DOMContentLoaded', function() {
         if (titleInput.value) formData.append('title', titleInput.value);
         if (descriptionInput.value) formData.append('description', descriptionInput.value);
         if (coverImageInput.files.length) formData.append('cover_image', coverImageInput.files[0]);
+        appendPayWhatYouWant(formData);
+        appendDiscoverable(formData);
         
         // Send request to create the route and get the signed URL
         const routeResponse = await fetch('/files/upload', {
@@ -304,6 +306,25 @@ document.addEventListener('DOMContentLoaded', function() {
         window.location.reload();
     }
     
+    // With pay what you want the price is the suggested amount and buyers
+    // may pay anything from the min price.
+    function appendPayWhatYouWant(formData) {
+        const payWhatYouWantInput = document.getElementById('pay-what-you-want-input');
+        const minPriceInput = document.getElementById('min-price-input');
+        if (payWhatYouWantInput && payWhatYouWantInput.checked) {
+            formData.append('pay_what_you_want', true);
+            if (minPriceInput.value) formData.append('min_price', minPriceInput.value);
+        }
+    }
+    
+    // Discoverable routes are listed publicly for x402 clients.
+    function appendDiscoverable(formData) {
+        const discoverableInput = document.getElementById('discoverable-input');
+        if (discoverableInput && discoverableInput.checked) {
+            formData.append('discoverable', true);
+        }
+    }
+    
     async function handleUrlSubmission() {
         // Create FormData object
         const formData = new FormData();
@@ -324,6 +345,22 @@ document.addEventListener('DOMContentLoaded', function() {
         if (titleInput.value) formData.append('title', titleInput.value);
         if (descriptionInput.value) formData.append('description', descriptionInput.value);
         if (coverImageInput.files.length) formData.append('cover_image', coverImageInput.files[0]);
+
+        const cacheEnabledInput = document.getElementById('cache-enabled-input');
+        const cacheTTLInput = document.getElementById('cache-ttl-input');
+        if (cacheEnabledInput && cacheEnabledInput.checked) {
+            formData.append('cache_enabled', true);
+            if (cacheTTLInput.value) formData.append('cache_ttl_seconds', cacheTTLInput.value);
+        }
+
+        const meteredInput = document.getElementById('metered-input');
+        const unitPriceInput = document.getElementById('unit-price-input');
+        if (meteredInput && meteredInput.checked) {
+            formData.append('billing_mode', 'metered');
+            formData.append('unit_price', unitPriceInput.value);
+        }
+        appendPayWhatYouWant(formData);
+        appendDiscoverable(formData);
         
         // Send request
         const response = await fetch('/links/shrink', {
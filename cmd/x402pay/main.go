@@ -0,0 +1,142 @@
+// Command x402pay makes an HTTP request and pays for it if it is answered
+// with 402 Payment Required, signing with the key in PRIVATE_KEY.
+//
+//	x402pay [flags] <url>
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+
+	"linkshrink/money"
+	"linkshrink/x402client"
+)
+
+// listFlag is a flag that can be repeated.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "x402pay:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	defaults := x402client.DefaultConfig()
+
+	var headers, networks listFlag
+	method := flag.String("X", http.MethodGet, "HTTP method of the request")
+	data := flag.String("d", "", "Request body, @file reads it from a file")
+	flag.Var(&headers, "H", "Request header as 'Name: value', can be repeated")
+	flag.Var(&networks, "network", "Network payments may be made on, such as base-sepolia or eip155:84532, can be repeated")
+	maxPerRequest := flag.String("max", money.USDC.Format(defaults.MaxPerRequest), "Most a single payment may cost in USDC, 0 means no limit")
+	maxTotal := flag.String("max-total", "0", "Most all payments may cost together in USDC, 0 means no limit")
+	count := flag.Int("n", 1, "Number of times the request is made, later requests reuse the payment while it has credits")
+	include := flag.Bool("i", false, "Print the response headers")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: x402pay [flags] <url>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	url := flag.Arg(0)
+
+	_ = godotenv.Load()
+	privateKey := os.Getenv("PRIVATE_KEY")
+	if privateKey == "" {
+		return errors.New("missing required environment variable PRIVATE_KEY")
+	}
+
+	cfg := defaults
+	cfg.Networks = networks
+	var err error
+	if cfg.MaxPerRequest, err = money.USDC.Parse(*maxPerRequest); err != nil {
+		return fmt.Errorf("invalid -max: %w", err)
+	}
+	if cfg.MaxPerSession, err = money.USDC.Parse(*maxTotal); err != nil {
+		return fmt.Errorf("invalid -max-total: %w", err)
+	}
+
+	transport, err := x402client.NewTransportFromPrivateKey(privateKey, nil, &cfg)
+	if err != nil {
+		return err
+	}
+	client := transport.NewClient()
+
+	body := *data
+	if strings.HasPrefix(body, "@") {
+		content, err := os.ReadFile(body[1:])
+		if err != nil {
+			return err
+		}
+		body = string(content)
+	}
+
+	for i := 0; i < *count; i++ {
+		paymentsBefore := len(transport.Payments())
+
+		req, err := http.NewRequest(strings.ToUpper(*method), url, strings.NewReader(body))
+		if err != nil {
+			return err
+		}
+		for _, header := range headers {
+			name, value, found := strings.Cut(header, ":")
+			if !found {
+				return fmt.Errorf("invalid header %q", header)
+			}
+			req.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		if payments := transport.Payments(); len(payments) > paymentsBefore {
+			payment := payments[len(payments)-1]
+			fmt.Fprintf(os.Stderr, "Paid %s USDC to %s on %s (x402 v%d)\n",
+				money.USDC.Format(payment.Amount), payment.PayTo, payment.Network, payment.Version)
+			if settlement, err := x402client.SettleResponse(resp); err == nil && settlement != nil {
+				fmt.Fprintf(os.Stderr, "Settled in transaction %s\n", settlement.Transaction)
+			}
+		}
+
+		fmt.Fprintln(os.Stderr, resp.Proto, resp.Status)
+		if *include {
+			_ = resp.Header.Write(os.Stderr)
+			fmt.Fprintln(os.Stderr)
+		}
+		_, err = io.Copy(os.Stdout, resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("request failed with status %s", resp.Status)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Spent %s USDC in total\n", money.USDC.Format(transport.Spent()))
+	return nil
+}
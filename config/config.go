@@ -5,16 +5,26 @@ import (
 	"log/slog"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 
 	"linkshrink/auth"
+	"linkshrink/cache"
 	"linkshrink/cloudflare"
+	"linkshrink/idempotency"
+	"linkshrink/l402"
+	"linkshrink/ledger"
+	"linkshrink/purchases"
+	"linkshrink/reconciler"
 	"linkshrink/routes"
+	"linkshrink/splits"
 	"linkshrink/store"
 	"linkshrink/ui"
+	"linkshrink/upstreams"
+	"linkshrink/wallets"
 )
 
 // Config holds application configuration values.
@@ -33,6 +43,11 @@ type Config struct {
 
 	AppPort string
 
+	// TrustedProxies are the addresses or CIDR ranges of the proxies whose
+	// X-Forwarded-For header is trusted for the client IP, which free trial
+	// requests are counted by. Without any, the connection's address is used.
+	TrustedProxies []string
+
 	// Auth configuration
 	Auth auth.Config `group:"auth" namespace:"auth"`
 
@@ -47,6 +62,33 @@ type Config struct {
 
 	// Cloudflare configuration
 	Cloudflare cloudflare.Config `group:"cloudflare" namespace:"cloudflare"`
+
+	// Response cache configuration
+	Cache cache.Config `group:"cache" namespace:"cache"`
+
+	// Upstream pool configuration
+	Upstreams upstreams.Config `group:"upstreams" namespace:"upstreams"`
+
+	// Revenue split configuration
+	Splits splits.Config `group:"splits" namespace:"splits"`
+
+	// Purchases configuration
+	Purchases purchases.Config `group:"purchases" namespace:"purchases"`
+
+	// Ledger configuration
+	Ledger ledger.Config `group:"ledger" namespace:"ledger"`
+
+	// Idempotency-Key configuration
+	Idempotency idempotency.Config `group:"idempotency" namespace:"idempotency"`
+
+	// On-chain settlement reconciler configuration
+	Reconciler reconciler.Config `group:"reconciler" namespace:"reconciler"`
+
+	// Prepaid wallet configuration
+	Wallets wallets.Config `group:"wallets" namespace:"wallets"`
+
+	// L402 Lightning payment rail configuration
+	L402 l402.Config `group:"l402" namespace:"l402"`
 }
 
 var AppConfig *Config
@@ -89,12 +131,23 @@ func DefaultConfig() *Config {
 		Routes: routes.Config{
 			X402FacilitatorURL:    "https://x402.org/facilitator",
 			X402MaxTimeoutSeconds: 300,
+			QuoteTimeoutSeconds:   5,
+			Discovery:             true,
 		},
 		Auth: auth.Config{
 			JWTExpirationHours: 72 * time.Hour,
 		},
-		UI:         ui.DefaultConfig(),
-		Cloudflare: cloudflare.DefaultConfig(),
+		UI:          ui.DefaultConfig(),
+		Cloudflare:  cloudflare.DefaultConfig(),
+		Cache:       cache.DefaultConfig(),
+		Upstreams:   upstreams.DefaultConfig(),
+		Splits:      splits.DefaultConfig(),
+		Purchases:   purchases.DefaultConfig(),
+		Ledger:      ledger.DefaultConfig(),
+		Idempotency: idempotency.DefaultConfig(),
+		Reconciler:  reconciler.DefaultConfig(),
+		Wallets:     wallets.DefaultConfig(),
+		L402:        l402.DefaultConfig(),
 	}
 }
 
@@ -115,6 +168,7 @@ func LoadConfig(logger *slog.Logger) *Config {
 	AppConfig.LogLevel = getEnv("LOG_LEVEL", AppConfig.LogLevel)
 	AppConfig.GinMode = getEnv("GIN_MODE", AppConfig.GinMode)
 	AppConfig.AppPort = getEnv("APP_PORT", AppConfig.AppPort)
+	AppConfig.TrustedProxies = getEnvList("TRUSTED_PROXIES", AppConfig.TrustedProxies)
 
 	// Store configuration.
 	AppConfig.Store.Host = getEnv("DB_HOST", AppConfig.Store.Host)
@@ -133,6 +187,13 @@ func LoadConfig(logger *slog.Logger) *Config {
 	AppConfig.Routes.X402MaxTimeoutSeconds = getEnvInt("X402_MAX_TIMEOUT_SECONDS", AppConfig.Routes.X402MaxTimeoutSeconds)
 	AppConfig.Routes.CDPAPIKeyID = getEnv("CDP_API_KEY_ID", "")
 	AppConfig.Routes.CDPAPIKeySecret = getEnv("CDP_API_KEY_SECRET", "")
+	AppConfig.Routes.QuoteTimeoutSeconds = getEnvInt("QUOTE_TIMEOUT_SECONDS", AppConfig.Routes.QuoteTimeoutSeconds)
+	AppConfig.Routes.OwnerPreview, _ = strconv.ParseBool(getEnv("OWNER_PREVIEW_ENABLED",
+		strconv.FormatBool(AppConfig.Routes.OwnerPreview)))
+	AppConfig.Routes.ScheduleIntervalSeconds = getEnvInt("ROUTE_SCHEDULE_INTERVAL_SECONDS",
+		AppConfig.Routes.ScheduleIntervalSeconds)
+	AppConfig.Routes.Discovery, _ = strconv.ParseBool(getEnv("DISCOVERY_ENABLED",
+		strconv.FormatBool(AppConfig.Routes.Discovery)))
 
 	// Auth configuration
 	AppConfig.Auth.JWTSecret = getEnvOrFatal("JWT_SECRET")
@@ -155,6 +216,64 @@ func LoadConfig(logger *slog.Logger) *Config {
 	AppConfig.Cloudflare.BucketName = getEnv("CLOUDFLARE_R2_BUCKET_NAME", "")
 	AppConfig.Cloudflare.PublicBucketName = getEnv("CLOUDFLARE_R2_PUBLIC_BUCKET_NAME", "")
 
+	// Response cache configuration
+	AppConfig.Cache.Backend = getEnv("CACHE_BACKEND", AppConfig.Cache.Backend)
+	AppConfig.Cache.DiskPath = getEnv("CACHE_DISK_PATH", AppConfig.Cache.DiskPath)
+	AppConfig.Cache.DefaultTTLSeconds = getEnvInt("CACHE_DEFAULT_TTL_SECONDS", AppConfig.Cache.DefaultTTLSeconds)
+	AppConfig.Cache.MaxObjectBytes = int64(getEnvInt("CACHE_MAX_OBJECT_BYTES", int(AppConfig.Cache.MaxObjectBytes)))
+	AppConfig.Cache.MaxEntries = getEnvInt("CACHE_MAX_ENTRIES", AppConfig.Cache.MaxEntries)
+
+	// Upstream pool settings
+	AppConfig.Upstreams.HealthCheckIntervalSeconds = getEnvInt("UPSTREAM_HEALTH_CHECK_INTERVAL_SECONDS", AppConfig.Upstreams.HealthCheckIntervalSeconds)
+	AppConfig.Upstreams.HealthCheckTimeoutSeconds = getEnvInt("UPSTREAM_HEALTH_CHECK_TIMEOUT_SECONDS", AppConfig.Upstreams.HealthCheckTimeoutSeconds)
+	AppConfig.Upstreams.UnhealthyThreshold = getEnvInt("UPSTREAM_UNHEALTHY_THRESHOLD", AppConfig.Upstreams.UnhealthyThreshold)
+	AppConfig.Upstreams.PassiveFailureThreshold = getEnvInt("UPSTREAM_PASSIVE_FAILURE_THRESHOLD", AppConfig.Upstreams.PassiveFailureThreshold)
+	AppConfig.Upstreams.EjectionSeconds = getEnvInt("UPSTREAM_EJECTION_SECONDS", AppConfig.Upstreams.EjectionSeconds)
+
+	// Revenue split settings
+	AppConfig.Splits.PlatformFeeBPS = uint64(getEnvInt("PLATFORM_FEE_BPS", int(AppConfig.Splits.PlatformFeeBPS)))
+	AppConfig.Splits.PlatformAddress = getEnv("PLATFORM_FEE_ADDRESS", AppConfig.Routes.X402PaymentAddress)
+
+	// Pending purchase recovery settings
+	AppConfig.Purchases.RecoveryIntervalSeconds = getEnvInt("PURCHASE_RECOVERY_INTERVAL_SECONDS", AppConfig.Purchases.RecoveryIntervalSeconds)
+	AppConfig.Purchases.PendingTimeoutSeconds = getEnvInt("PURCHASE_PENDING_TIMEOUT_SECONDS", AppConfig.Purchases.PendingTimeoutSeconds)
+	AppConfig.Purchases.RecoveryBatchSize = getEnvInt("PURCHASE_RECOVERY_BATCH_SIZE", AppConfig.Purchases.RecoveryBatchSize)
+
+	// Ledger settings
+	AppConfig.Ledger.AuditIntervalSeconds = getEnvInt("LEDGER_AUDIT_INTERVAL_SECONDS", AppConfig.Ledger.AuditIntervalSeconds)
+
+	// Idempotency-Key settings
+	AppConfig.Idempotency.TTLSeconds = getEnvInt("IDEMPOTENCY_TTL_SECONDS", AppConfig.Idempotency.TTLSeconds)
+	AppConfig.Idempotency.MaxBodyBytes = int64(getEnvInt("IDEMPOTENCY_MAX_BODY_BYTES", int(AppConfig.Idempotency.MaxBodyBytes)))
+	AppConfig.Idempotency.CleanupIntervalSeconds = getEnvInt("IDEMPOTENCY_CLEANUP_INTERVAL_SECONDS", AppConfig.Idempotency.CleanupIntervalSeconds)
+
+	// On-chain settlement reconciler settings
+	AppConfig.Reconciler.IntervalSeconds = getEnvInt("RECONCILER_INTERVAL_SECONDS", AppConfig.Reconciler.IntervalSeconds)
+	AppConfig.Reconciler.BatchSize = getEnvInt("RECONCILER_BATCH_SIZE", AppConfig.Reconciler.BatchSize)
+	AppConfig.Reconciler.Confirmations = getEnvInt("RECONCILER_CONFIRMATIONS", AppConfig.Reconciler.Confirmations)
+	AppConfig.Reconciler.ReceiptTimeoutSeconds = getEnvInt("RECONCILER_RECEIPT_TIMEOUT_SECONDS", AppConfig.Reconciler.ReceiptTimeoutSeconds)
+	AppConfig.Reconciler.RPCTimeoutSeconds = getEnvInt("RECONCILER_RPC_TIMEOUT_SECONDS", AppConfig.Reconciler.RPCTimeoutSeconds)
+	AppConfig.Reconciler.RPCURL = getEnv("RECONCILER_RPC_URL", AppConfig.Reconciler.RPCURL)
+	AppConfig.Reconciler.USDCAddress = getEnv("RECONCILER_USDC_ADDRESS", AppConfig.Reconciler.USDCAddress)
+	AppConfig.Reconciler.TestnetRPCURL = getEnv("RECONCILER_TESTNET_RPC_URL", AppConfig.Reconciler.TestnetRPCURL)
+	AppConfig.Reconciler.TestnetUSDCAddress = getEnv("RECONCILER_TESTNET_USDC_ADDRESS", AppConfig.Reconciler.TestnetUSDCAddress)
+
+	// Prepaid wallet settings
+	AppConfig.Wallets.MinTopUp = uint64(getEnvInt("WALLET_MIN_TOPUP", int(AppConfig.Wallets.MinTopUp)))
+	AppConfig.Wallets.MaxTopUp = uint64(getEnvInt("WALLET_MAX_TOPUP", int(AppConfig.Wallets.MaxTopUp)))
+	AppConfig.Wallets.ChallengeMaxAgeSeconds = getEnvInt("WALLET_CHALLENGE_MAX_AGE_SECONDS", AppConfig.Wallets.ChallengeMaxAgeSeconds)
+	AppConfig.Wallets.HistoryLimit = uint64(getEnvInt("WALLET_HISTORY_LIMIT", int(AppConfig.Wallets.HistoryLimit)))
+
+	// L402 Lightning payment rail settings
+	AppConfig.L402.LNDURL = getEnv("L402_LND_URL", AppConfig.L402.LNDURL)
+	AppConfig.L402.LNDMacaroonHex = getEnv("L402_LND_MACAROON", AppConfig.L402.LNDMacaroonHex)
+	AppConfig.L402.LNDTLSCertPath = getEnv("L402_LND_TLS_CERT_PATH", AppConfig.L402.LNDTLSCertPath)
+	AppConfig.L402.RootKeyHex = getEnv("L402_ROOT_KEY", AppConfig.L402.RootKeyHex)
+	AppConfig.L402.Network = getEnv("L402_NETWORK", AppConfig.L402.Network)
+	AppConfig.L402.SatsPerUSD = uint64(getEnvInt("L402_SATS_PER_USD", int(AppConfig.L402.SatsPerUSD)))
+	AppConfig.L402.InvoiceExpirySeconds = getEnvInt("L402_INVOICE_EXPIRY_SECONDS", AppConfig.L402.InvoiceExpirySeconds)
+	AppConfig.L402.TimeoutSeconds = getEnvInt("L402_TIMEOUT_SECONDS", AppConfig.L402.TimeoutSeconds)
+
 	logger.Info("Configuration loaded.")
 
 	return AppConfig
@@ -188,6 +307,23 @@ func getEnvDuration(key string, fallback time.Duration) time.Duration {
 	return fallback
 }
 
+// getEnvList retrieves a comma separated environment variable as a list or
+// returns a default.
+func getEnvList(key string, fallback []string) []string {
+	valueStr, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+
+	var values []string
+	for _, value := range strings.Split(valueStr, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // getEnvInt retrieves an environment variable as an int or returns a default.
 func getEnvInt(key string, fallback int) int {
 	if valueStr, exists := os.LookupEnv(key); exists {
@@ -0,0 +1,100 @@
+package coupons
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// Discount types of a coupon.
+const (
+	DiscountPercent = "percent"
+	DiscountFixed   = "fixed"
+)
+
+// TotalBPS is 100% in basis points.
+const TotalBPS = 10000
+
+// MaxCodeLength is the longest coupon code accepted.
+const MaxCodeLength = 64
+
+// Coupon is a promo code an owner hands out for discounted or free access to
+// their routes.
+type Coupon struct {
+	ID     uint64 `json:"id"`
+	UserID uint64 `json:"-"`
+	// RouteID is the route the coupon applies to, 0 applies it to all of the
+	// owner's routes.
+	RouteID      uint64 `json:"route_id,omitempty"`
+	Code         string `json:"code"`
+	DiscountType string `json:"discount_type"`
+	// DiscountValue is in basis points for percent discounts and in base
+	// units (USDC * 10^6) for fixed discounts.
+	DiscountValue uint64 `json:"discount_value"`
+	// MaxRedemptions caps how often the coupon is redeemed, 0 means no limit.
+	MaxRedemptions  uint64     `json:"max_redemptions"`
+	RedemptionCount uint64     `json:"redemption_count"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Discount returns the amount the coupon takes off price, which is never more
+// than the price. Percent discounts are rounded down.
+func (c *Coupon) Discount(price uint64) uint64 {
+	var discount uint64
+	switch c.DiscountType {
+	case DiscountPercent:
+		discount = price/TotalBPS*c.DiscountValue + price%TotalBPS*c.DiscountValue/TotalBPS
+	case DiscountFixed:
+		discount = c.DiscountValue
+	}
+	return min(discount, price)
+}
+
+// Redeemable reports whether the coupon can still be redeemed at now.
+func (c *Coupon) Redeemable(now time.Time) bool {
+	if c.ExpiresAt != nil && !now.Before(*c.ExpiresAt) {
+		return false
+	}
+	return c.MaxRedemptions == 0 || c.RedemptionCount < c.MaxRedemptions
+}
+
+// Redemption is a use of a coupon.
+type Redemption struct {
+	CouponID uint64
+	RouteID  uint64
+	// PurchaseID is the discounted purchase, 0 if the coupon made the
+	// request free.
+	PurchaseID uint64
+	// Discount is the amount taken off the price (USDC * 10^6).
+	Discount uint64
+}
+
+// NormalizeCode returns code the way coupon codes are stored, codes are not
+// case sensitive.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// validCode reports whether a normalized code only consists of letters,
+// digits, dashes and underscores.
+func validCode(code string) bool {
+	if code == "" || len(code) > MaxCodeLength {
+		return false
+	}
+	for _, r := range code {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// ClientKey identifies a trial client by the SHA-256 of its address, so
+// client addresses are not stored.
+func ClientKey(clientAddress string) string {
+	sum := sha256.Sum256([]byte(clientAddress))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,51 @@
+package coupons
+
+import (
+	"context"
+	"errors"
+)
+
+// Custom errors for coupon operations
+var (
+	ErrCouponNotFound    = errors.New("coupon not found")
+	ErrCouponUnavailable = errors.New("coupon has expired or reached its max redemptions")
+	ErrCodeTaken         = errors.New("coupon code is already in use")
+	ErrInvalidCode       = errors.New("coupon code must be 1-64 letters, digits, dashes or underscores")
+	ErrInvalidDiscount   = errors.New("coupon needs a percent discount of 1-10000 basis points or a fixed discount above 0")
+	ErrInvalidExpiry     = errors.New("coupon expiry must be in the future")
+)
+
+// Store provides access to the coupon and trial storage.
+type Store interface {
+	// CreateCoupon creates a coupon, ErrCodeTaken if the owner already has a
+	// coupon with its code.
+	CreateCoupon(ctx context.Context, coupon *Coupon) (*Coupon, error)
+
+	// ListCouponsByUserID retrieves the coupons of an owner.
+	ListCouponsByUserID(ctx context.Context, userID uint64) ([]Coupon, error)
+
+	// DeleteCoupon deletes a coupon of an owner.
+	DeleteCoupon(ctx context.Context, couponID, userID uint64) error
+
+	// GetCouponForRoute retrieves an owner's coupon by code if it applies to
+	// the route.
+	GetCouponForRoute(ctx context.Context, userID, routeID uint64, code string) (*Coupon, error)
+
+	// RedeemCoupon atomically counts a redemption of a coupon and records it,
+	// ErrCouponUnavailable if the coupon expired or is used up.
+	RedeemCoupon(ctx context.Context, redemption *Redemption) error
+
+	// GetRouteTrial retrieves the number of free requests each client gets
+	// on a route, 0 if the route has no trial.
+	GetRouteTrial(ctx context.Context, routeID uint64) (uint64, error)
+
+	// SetRouteTrial sets the number of free requests each client gets on a
+	// route.
+	SetRouteTrial(ctx context.Context, routeID, freeRequests uint64) error
+
+	// UseTrialRequest counts a free request of a client unless it used all
+	// freeRequests. Returns the number of free requests the client used and
+	// whether this request was counted.
+	UseTrialRequest(ctx context.Context, routeID uint64, clientKey string,
+		freeRequests uint64) (uint64, bool, error)
+}
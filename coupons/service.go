@@ -0,0 +1,124 @@
+package coupons
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"linkshrink/utils"
+)
+
+// Service provides business logic for coupons and free trials.
+type Service struct {
+	logger *slog.Logger
+	store  Store
+	clock  utils.Clock
+}
+
+// NewService creates a new coupon Service.
+func NewService(logger *slog.Logger, store Store, clock utils.Clock) *Service {
+	return &Service{
+		logger: logger,
+		store:  store,
+		clock:  clock,
+	}
+}
+
+// CreateCoupon validates and creates a coupon.
+func (s *Service) CreateCoupon(ctx context.Context, coupon *Coupon) (*Coupon, error) {
+	coupon.Code = NormalizeCode(coupon.Code)
+	if !validCode(coupon.Code) {
+		return nil, ErrInvalidCode
+	}
+
+	switch coupon.DiscountType {
+	case DiscountPercent:
+		if coupon.DiscountValue == 0 || coupon.DiscountValue > TotalBPS {
+			return nil, ErrInvalidDiscount
+		}
+	case DiscountFixed:
+		if coupon.DiscountValue == 0 {
+			return nil, ErrInvalidDiscount
+		}
+	default:
+		return nil, ErrInvalidDiscount
+	}
+
+	if coupon.ExpiresAt != nil && !coupon.ExpiresAt.After(s.clock.Now()) {
+		return nil, ErrInvalidExpiry
+	}
+
+	return s.store.CreateCoupon(ctx, coupon)
+}
+
+// ListCoupons retrieves the coupons of an owner.
+func (s *Service) ListCoupons(ctx context.Context, userID uint64) ([]Coupon, error) {
+	return s.store.ListCouponsByUserID(ctx, userID)
+}
+
+// DeleteCoupon deletes a coupon of an owner. Its past redemptions are kept.
+func (s *Service) DeleteCoupon(ctx context.Context, couponID, userID uint64) error {
+	return s.store.DeleteCoupon(ctx, couponID, userID)
+}
+
+// FindCoupon retrieves a redeemable coupon of the route's owner by code.
+func (s *Service) FindCoupon(ctx context.Context, ownerID, routeID uint64,
+	code string) (*Coupon, error) {
+
+	coupon, err := s.store.GetCouponForRoute(ctx, ownerID, routeID, NormalizeCode(code))
+	if err != nil {
+		return nil, err
+	}
+
+	if !coupon.Redeemable(s.clock.Now()) {
+		return nil, ErrCouponUnavailable
+	}
+
+	return coupon, nil
+}
+
+// Redeem records a use of a coupon. A redemption of a purchase whose payment
+// fails is released together with the purchase.
+func (s *Service) Redeem(ctx context.Context, redemption *Redemption) error {
+	err := s.store.RedeemCoupon(ctx, redemption)
+	if err != nil {
+		return fmt.Errorf("failed to redeem coupon %d: %w", redemption.CouponID, err)
+	}
+	return nil
+}
+
+// GetTrial retrieves the number of free requests each client gets on a
+// route.
+func (s *Service) GetTrial(ctx context.Context, routeID uint64) (uint64, error) {
+	return s.store.GetRouteTrial(ctx, routeID)
+}
+
+// SetTrial sets the number of free requests each client gets on a route, 0
+// ends the trial.
+func (s *Service) SetTrial(ctx context.Context, routeID, freeRequests uint64) error {
+	return s.store.SetRouteTrial(ctx, routeID, freeRequests)
+}
+
+// UseTrial uses one of the free requests a client gets on a route. Returns
+// how many free requests the client has left and whether the request is free.
+func (s *Service) UseTrial(ctx context.Context, routeID uint64,
+	clientAddress string) (uint64, bool, error) {
+
+	freeRequests, err := s.store.GetRouteTrial(ctx, routeID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get route trial: %w", err)
+	}
+	if freeRequests == 0 {
+		return 0, false, nil
+	}
+
+	used, ok, err := s.store.UseTrialRequest(ctx, routeID, ClientKey(clientAddress), freeRequests)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to use trial request: %w", err)
+	}
+	if !ok {
+		return 0, false, nil
+	}
+
+	return freeRequests - min(used, freeRequests), true, nil
+}
@@ -0,0 +1,17 @@
+package idempotency
+
+// DefaultConfig returns all default values for the Config struct.
+func DefaultConfig() Config {
+	return Config{
+		TTLSeconds:             86400,
+		MaxBodyBytes:           1 << 20,
+		CleanupIntervalSeconds: 3600,
+	}
+}
+
+// Config holds the configuration for idempotent paid requests.
+type Config struct {
+	TTLSeconds             int   `long:"ttl_seconds" description:"Seconds a response is replayed for retries with the same Idempotency-Key, 0 disables idempotency keys"`
+	MaxBodyBytes           int64 `long:"max_body_bytes" description:"Largest request or response body stored for an Idempotency-Key"`
+	CleanupIntervalSeconds int   `long:"cleanup_interval_seconds" description:"Seconds between removals of expired idempotency keys, 0 disables them"`
+}
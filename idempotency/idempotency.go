@@ -0,0 +1,47 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Claim is a key claimed for a request in progress.
+type Claim struct {
+	RouteID     uint64
+	Scope       string
+	Key         string
+	Fingerprint string
+}
+
+// Response is the upstream response stored for a key.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Record is a stored key. Response is nil while its request is in progress.
+type Record struct {
+	Claim
+	Response  *Response
+	ExpiresAt time.Time
+}
+
+// Scope returns the scope of keys sent with a payment header. A payment header
+// identifies the purchase a request is paid with, so keys are per purchase.
+func Scope(paymentHeader string) string {
+	sum := sha256.Sum256([]byte(paymentHeader))
+	return hex.EncodeToString(sum[:])
+}
+
+// Fingerprint returns the fingerprint of a request, covering everything that
+// is forwarded to the upstream except its headers.
+func Fingerprint(method, path, rawQuery string, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", method, path, rawQuery)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
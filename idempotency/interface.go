@@ -0,0 +1,36 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Custom errors for idempotency key operations
+var (
+	ErrKeyNotFound   = errors.New("idempotency key not found")
+	ErrKeyInProgress = errors.New("a request with this Idempotency-Key is still in progress")
+	ErrKeyMismatch   = errors.New("Idempotency-Key was already used for a different request")
+	ErrBodyTooLarge  = errors.New("body is too large for an idempotent request")
+)
+
+// Store provides access to the idempotency key storage.
+type Store interface {
+	// ClaimIdempotencyKey records a request in progress for a key unless the
+	// key is in use. Returns whether the key was claimed.
+	ClaimIdempotencyKey(ctx context.Context, claim *Claim, expiresAt time.Time) (bool, error)
+
+	// GetIdempotencyKey returns the record of a key, or ErrKeyNotFound.
+	GetIdempotencyKey(ctx context.Context, routeID uint64, scope string,
+		key string) (*Record, error)
+
+	// CompleteIdempotencyKey stores the response to a claimed key's request.
+	CompleteIdempotencyKey(ctx context.Context, claim *Claim, response *Response) error
+
+	// ReleaseIdempotencyKey removes a claimed key that has no response yet.
+	ReleaseIdempotencyKey(ctx context.Context, claim *Claim) error
+
+	// DeleteExpiredIdempotencyKeys removes the keys that expired before the
+	// given time and returns how many were removed.
+	DeleteExpiredIdempotencyKeys(ctx context.Context, before time.Time) (uint64, error)
+}
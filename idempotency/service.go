@@ -0,0 +1,120 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"linkshrink/utils"
+)
+
+// Service stores the responses to paid requests by Idempotency-Key, so
+// retries are replayed without paying or forwarding them again.
+type Service struct {
+	logger *slog.Logger
+	store  Store
+	cfg    *Config
+	clock  utils.Clock
+}
+
+// NewService creates a new idempotency key service.
+func NewService(logger *slog.Logger, store Store, cfg *Config,
+	clock utils.Clock) *Service {
+
+	return &Service{
+		logger: logger,
+		store:  store,
+		cfg:    cfg,
+		clock:  clock,
+	}
+}
+
+// Enabled reports whether Idempotency-Key headers are honoured.
+func (s *Service) Enabled() bool {
+	return s.cfg.TTLSeconds > 0
+}
+
+// MaxBodyBytes returns the largest request or response body stored for a key.
+func (s *Service) MaxBodyBytes() int64 {
+	return s.cfg.MaxBodyBytes
+}
+
+// Begin claims a key for a request. If the key was used before, the stored
+// response is returned for replay instead. Returns ErrKeyMismatch if the key
+// was used for a different request, and ErrKeyInProgress if the request it
+// was used for has no response yet.
+func (s *Service) Begin(ctx context.Context, claim *Claim) (*Response, error) {
+	expiresAt := s.clock.Now().Add(time.Duration(s.cfg.TTLSeconds) * time.Second)
+
+	claimed, err := s.store.ClaimIdempotencyKey(ctx, claim, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	if claimed {
+		return nil, nil
+	}
+
+	record, err := s.store.GetIdempotencyKey(ctx, claim.RouteID, claim.Scope, claim.Key)
+	if errors.Is(err, ErrKeyNotFound) {
+		// The request it was in use for was released in the meantime, the
+		// client can retry it.
+		return nil, ErrKeyInProgress
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	switch {
+	case record.Fingerprint != claim.Fingerprint:
+		return nil, ErrKeyMismatch
+
+	case record.Response == nil:
+		return nil, ErrKeyInProgress
+	}
+
+	return record.Response, nil
+}
+
+// Complete stores the response to a claimed key's request.
+func (s *Service) Complete(ctx context.Context, claim *Claim, response *Response) error {
+	if int64(len(response.Body)) > s.cfg.MaxBodyBytes {
+		return ErrBodyTooLarge
+	}
+	return s.store.CompleteIdempotencyKey(ctx, claim, response)
+}
+
+// Release removes a claimed key whose request didn't get a response, so the
+// request can be retried with it.
+func (s *Service) Release(ctx context.Context, claim *Claim) error {
+	return s.store.ReleaseIdempotencyKey(ctx, claim)
+}
+
+// RunCleanup removes expired keys every interval until the context is
+// cancelled.
+func (s *Service) RunCleanup(ctx context.Context) {
+	if s.cfg.CleanupIntervalSeconds <= 0 {
+		s.logger.Info("Idempotency key cleanup disabled")
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(s.cfg.CleanupIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.store.DeleteExpiredIdempotencyKeys(ctx, s.clock.Now())
+			if err != nil {
+				s.logger.Error("Failed to delete expired idempotency keys", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				s.logger.Debug("Deleted expired idempotency keys", "count", deleted)
+			}
+		}
+	}
+}
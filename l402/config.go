@@ -0,0 +1,24 @@
+package l402
+
+// DefaultConfig returns all default values for the Config struct.
+func DefaultConfig() Config {
+	return Config{
+		Network:              NetworkTestnet,
+		SatsPerUSD:           1000,
+		InvoiceExpirySeconds: 600,
+		TimeoutSeconds:       10,
+	}
+}
+
+// Config holds the configuration for the L402 payment rail.
+type Config struct {
+	LNDURL         string `long:"lnd_url" description:"REST endpoint of the LND node invoices are created with, empty disables L402"`
+	LNDMacaroonHex string `long:"lnd_macaroon" description:"Hex encoded LND macaroon allowed to create invoices"`
+	LNDTLSCertPath string `long:"lnd_tls_cert_path" description:"TLS certificate of the LND node, empty trusts the system roots"`
+	RootKeyHex     string `long:"root_key" description:"Hex encoded 32 byte key L402 macaroons are signed with"`
+
+	Network              string `long:"network" description:"Lightning network of the node, mainnet pays for real routes and any other network for test routes"`
+	SatsPerUSD           uint64 `long:"sats_per_usd" description:"Satoshis asked for each USD of a route's price"`
+	InvoiceExpirySeconds int    `long:"invoice_expiry_seconds" description:"Seconds an L402 invoice can be paid for"`
+	TimeoutSeconds       int    `long:"timeout_seconds" description:"Timeout for a single call to the LND node"`
+}
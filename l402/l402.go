@@ -0,0 +1,231 @@
+package l402
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"linkshrink/rails"
+)
+
+const (
+	// Name is how routes refer to the L402 rail.
+	Name = "l402"
+
+	// NetworkMainnet is the Lightning network paying for real routes, any
+	// other network pays for test routes.
+	NetworkMainnet = "mainnet"
+	NetworkTestnet = "testnet"
+
+	// location is the location of the macaroons the rail issues.
+	location = "proxy402"
+
+	hashSize = sha256.Size
+	// identifierVersion is the version of the L402 macaroon identifier, a
+	// version, the payment hash and a random token ID.
+	identifierVersion = 0
+	identifierSize    = 2 + hashSize + hashSize
+
+	// Caveats of the macaroons the rail issues.
+	caveatRoute   = "route"
+	caveatAmount  = "amount"
+	caveatCredits = "credits"
+)
+
+// authSchemes are the Authorization schemes of L402 credentials, LSAT is
+// what L402 was called before and is still sent by older clients.
+var authSchemes = []string{"L402", "LSAT"}
+
+// Rail is the L402 payment rail. Requests are asked to pay a Lightning
+// invoice and given a macaroon bound to its payment hash. The macaroon and
+// the invoice's preimage are the credential that pays for the route.
+//
+// The macaroon's caveats bind it to the route and record the amount and
+// credits it was issued for, so a credential keeps paying for what it was
+// bought for when the route's price changes.
+type Rail struct {
+	node          Node
+	rootKey       []byte
+	network       string
+	satsPerUSD    uint64
+	invoiceExpiry time.Duration
+}
+
+// NewRail creates a new L402 rail taking payments with the node.
+func NewRail(node Node, cfg *Config) (*Rail, error) {
+	rootKey, err := hex.DecodeString(cfg.RootKeyHex)
+	if err != nil || len(rootKey) != 32 {
+		return nil, errors.New("L402 root key must be 32 hex encoded bytes")
+	}
+	if cfg.SatsPerUSD == 0 {
+		return nil, errors.New("L402 sats per USD must be positive")
+	}
+
+	return &Rail{
+		node:          node,
+		rootKey:       rootKey,
+		network:       cfg.Network,
+		satsPerUSD:    cfg.SatsPerUSD,
+		invoiceExpiry: time.Duration(cfg.InvoiceExpirySeconds) * time.Second,
+	}, nil
+}
+
+// Name implements rails.Rail.
+func (r *Rail) Name() string {
+	return Name
+}
+
+// Serves implements rails.Rail, mainnet pays for real routes and any other
+// network for test routes.
+func (r *Rail) Serves(isTest bool) bool {
+	return (r.network == NetworkMainnet) != isTest
+}
+
+// TakeCredential implements rails.Rail, L402 credentials are sent as
+// "Authorization: L402 <macaroon>:<preimage>". Other Authorization headers
+// are left for the upstream.
+func (r *Rail) TakeCredential(req *http.Request) string {
+	authorization := req.Header.Get("Authorization")
+	scheme, credential, found := strings.Cut(authorization, " ")
+	if !found {
+		return ""
+	}
+	for _, authScheme := range authSchemes {
+		if strings.EqualFold(scheme, authScheme) {
+			req.Header.Del("Authorization")
+			return strings.TrimSpace(credential)
+		}
+	}
+	return ""
+}
+
+// Challenge implements rails.Rail. It creates an invoice for the charge and
+// returns it with a macaroon for its payment hash as
+// `L402 macaroon="...", invoice="..."`.
+func (r *Rail) Challenge(ctx context.Context, charge rails.Charge) (string, error) {
+	invoice, err := r.node.CreateInvoice(ctx, r.sats(charge.Amount), charge.Description, r.invoiceExpiry)
+	if err != nil {
+		return "", fmt.Errorf("failed to create invoice: %w", err)
+	}
+	if len(invoice.PaymentHash) != hashSize {
+		return "", errors.New("invoice has an invalid payment hash")
+	}
+
+	tokenID := make([]byte, hashSize)
+	if _, err := rand.Read(tokenID); err != nil {
+		return "", err
+	}
+	id := binary.BigEndian.AppendUint16(nil, identifierVersion)
+	id = append(id, invoice.PaymentHash...)
+	id = append(id, tokenID...)
+
+	m := newMacaroon(r.rootKey, location, id)
+	m.addCaveat(caveatRoute + "=" + charge.ShortCode)
+	m.addCaveat(caveatAmount + "=" + strconv.FormatUint(charge.Amount, 10))
+	m.addCaveat(caveatCredits + "=" + strconv.FormatUint(charge.Credits, 10))
+
+	return fmt.Sprintf(`L402 macaroon="%s", invoice="%s"`, m.encode(), invoice.PaymentRequest), nil
+}
+
+// proof is what an L402 payment is recorded with.
+type proof struct {
+	Macaroon    string `json:"macaroon"`
+	Preimage    string `json:"preimage"`
+	PaymentHash string `json:"payment_hash"`
+	Network     string `json:"network"`
+}
+
+// Verify implements rails.Rail. The credential is valid if the macaroon is
+// signed with the rail's root key and the preimage hashes to the payment
+// hash the macaroon was issued for, which only the invoice's payer learns.
+func (r *Rail) Verify(ctx context.Context, shortCode string, credential string) (*rails.Payment, error) {
+	encoded, preimageHex, found := strings.Cut(credential, ":")
+	if !found {
+		return nil, rails.ErrInvalidCredential
+	}
+	m, err := decodeMacaroon(encoded)
+	if err != nil || !m.verify(r.rootKey) {
+		return nil, rails.ErrInvalidCredential
+	}
+	if len(m.id) != identifierSize || binary.BigEndian.Uint16(m.id) != identifierVersion {
+		return nil, rails.ErrInvalidCredential
+	}
+	paymentHash := m.id[2 : 2+hashSize]
+
+	preimage, err := hex.DecodeString(preimageHex)
+	if err != nil || len(preimage) != hashSize {
+		return nil, rails.ErrInvalidCredential
+	}
+	if hash := sha256.Sum256(preimage); string(hash[:]) != string(paymentHash) {
+		return nil, rails.ErrInvalidCredential
+	}
+
+	route, ok := m.caveat(caveatRoute)
+	if !ok {
+		return nil, rails.ErrInvalidCredential
+	}
+	if route != shortCode {
+		return nil, rails.ErrWrongRoute
+	}
+	amount, err := caveatUint(m, caveatAmount)
+	if err != nil {
+		return nil, err
+	}
+	credits, err := caveatUint(m, caveatCredits)
+	if err != nil {
+		return nil, err
+	}
+
+	paymentHashHex := hex.EncodeToString(paymentHash)
+	network := "lightning:" + r.network
+	proofJSON, err := json.Marshal(proof{
+		Macaroon:    encoded,
+		Preimage:    preimageHex,
+		PaymentHash: paymentHashHex,
+		Network:     network,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rails.Payment{
+		Reference: Name + ":" + paymentHashHex,
+		Network:   network,
+		Nonce:     paymentHashHex,
+		Amount:    amount,
+		Credits:   credits,
+		Proof:     proofJSON,
+	}, nil
+}
+
+// sats converts an amount in base units (USDC * 10^6) to satoshis, rounded
+// up so an invoice never asks for less than the price.
+func (r *Rail) sats(amount uint64) uint64 {
+	sats := (amount*r.satsPerUSD + 999_999) / 1_000_000
+	if sats == 0 {
+		return 1
+	}
+	return sats
+}
+
+// caveatUint returns the value of a numeric caveat.
+func caveatUint(m *macaroon, name string) (uint64, error) {
+	value, ok := m.caveat(name)
+	if !ok {
+		return 0, rails.ErrInvalidCredential
+	}
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, rails.ErrInvalidCredential
+	}
+	return n, nil
+}
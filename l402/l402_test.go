@@ -0,0 +1,487 @@
+package l402
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"linkshrink/rails"
+	"linkshrink/utils"
+)
+
+const (
+	testRootKeyHex  = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	testMacaroonHex = "0201036c6e64"
+)
+
+var errInvoiceExpired = errors.New("invoice expired")
+
+// fakeInvoice is an invoice the fake LND node created.
+type fakeInvoice struct {
+	preimage  []byte
+	value     uint64
+	expiresAt time.Time
+}
+
+// fakeLND serves LND's POST /v1/invoices and pays the invoices it created
+// until they expire.
+type fakeLND struct {
+	mu       sync.Mutex
+	clock    *utils.MockClock
+	invoices map[string]*fakeInvoice
+	requests []addInvoiceRequest
+}
+
+func newFakeLND(t *testing.T, clock *utils.MockClock) (*fakeLND, *LNDClient) {
+	lnd := &fakeLND{clock: clock, invoices: make(map[string]*fakeInvoice)}
+	server := httptest.NewServer(http.HandlerFunc(lnd.serve))
+	t.Cleanup(server.Close)
+
+	client, err := NewLNDClient(server.URL, testMacaroonHex, "", time.Second)
+	if err != nil {
+		t.Fatalf("NewLNDClient() error = %v", err)
+	}
+	return lnd, client
+}
+
+func (l *fakeLND) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/v1/invoices" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Header.Get("Grpc-Metadata-macaroon") != testMacaroonHex {
+		http.Error(w, "verification failed", http.StatusUnauthorized)
+		return
+	}
+
+	var req addInvoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	value, err := strconv.ParseUint(req.Value, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid value", http.StatusBadRequest)
+		return
+	}
+	expiry, err := strconv.ParseInt(req.Expiry, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid expiry", http.StatusBadRequest)
+		return
+	}
+
+	preimage := make([]byte, hashSize)
+	rand.Read(preimage)
+	hash := sha256.Sum256(preimage)
+	paymentRequest := "lntb" + hex.EncodeToString(hash[:8])
+
+	l.mu.Lock()
+	l.requests = append(l.requests, req)
+	l.invoices[paymentRequest] = &fakeInvoice{
+		preimage:  preimage,
+		value:     value,
+		expiresAt: l.clock.Now().Add(time.Duration(expiry) * time.Second),
+	}
+	l.mu.Unlock()
+
+	json.NewEncoder(w).Encode(addInvoiceResponse{
+		RHash:          base64.StdEncoding.EncodeToString(hash[:]),
+		PaymentRequest: paymentRequest,
+	})
+}
+
+// pay pays an invoice and returns its hex encoded preimage, which an
+// expired invoice can't be paid for.
+func (l *fakeLND) pay(paymentRequest string) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	invoice, ok := l.invoices[paymentRequest]
+	if !ok {
+		return "", ErrUnknownInvoice
+	}
+	if !l.clock.Now().Before(invoice.expiresAt) {
+		return "", errInvoiceExpired
+	}
+	return hex.EncodeToString(invoice.preimage), nil
+}
+
+var challengePattern = regexp.MustCompile(`^L402 macaroon="([^"]+)", invoice="([^"]+)"$`)
+
+// parseChallenge returns the macaroon and invoice of a WWW-Authenticate
+// challenge.
+func parseChallenge(t *testing.T, challenge string) (string, string) {
+	t.Helper()
+
+	match := challengePattern.FindStringSubmatch(challenge)
+	if match == nil {
+		t.Fatalf("unexpected challenge %q", challenge)
+	}
+	return match[1], match[2]
+}
+
+func newTestRail(t *testing.T, node Node) *Rail {
+	t.Helper()
+
+	cfg := DefaultConfig()
+	cfg.RootKeyHex = testRootKeyHex
+	rail, err := NewRail(node, &cfg)
+	if err != nil {
+		t.Fatalf("NewRail() error = %v", err)
+	}
+	return rail
+}
+
+// buyCredential asks for a charge and pays its invoice, returning the
+// credential and the macaroon it was issued with.
+func buyCredential(t *testing.T, rail *Rail, lnd *fakeLND, charge rails.Charge) (string, string) {
+	t.Helper()
+
+	challenge, err := rail.Challenge(context.Background(), charge)
+	if err != nil {
+		t.Fatalf("Challenge() error = %v", err)
+	}
+	encoded, invoice := parseChallenge(t, challenge)
+	preimage, err := lnd.pay(invoice)
+	if err != nil {
+		t.Fatalf("failed to pay invoice: %v", err)
+	}
+	return encoded + ":" + preimage, encoded
+}
+
+func TestRailChallengeAndVerify(t *testing.T) {
+	clock := utils.NewMockClock()
+	lnd, client := newFakeLND(t, clock)
+	rail := newTestRail(t, client)
+
+	charge := rails.Charge{ShortCode: "abc", Description: "Payment for GET /abc", Amount: 2_500_000, Credits: 3}
+	credential, encoded := buyCredential(t, rail, lnd, charge)
+
+	if len(lnd.requests) != 1 {
+		t.Fatalf("LND got %d invoice requests, want 1", len(lnd.requests))
+	}
+	// 2.5 USD at the default 1000 sats per USD.
+	if got := lnd.requests[0]; got.Value != "2500" || got.Memo != charge.Description || got.Expiry != "600" {
+		t.Errorf("LND invoice request = %+v, want 2500 sats for 600 seconds", got)
+	}
+
+	payment, err := rail.Verify(context.Background(), "abc", credential)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if payment.Amount != charge.Amount || payment.Credits != charge.Credits {
+		t.Errorf("Verify() paid %d for %d credits, want %d for %d",
+			payment.Amount, payment.Credits, charge.Amount, charge.Credits)
+	}
+	if payment.Network != "lightning:testnet" || payment.Reference != Name+":"+payment.Nonce {
+		t.Errorf("Verify() network = %s, reference = %s", payment.Network, payment.Reference)
+	}
+
+	var recorded proof
+	if err := json.Unmarshal(payment.Proof, &recorded); err != nil {
+		t.Fatalf("invalid proof: %v", err)
+	}
+	if recorded.Macaroon != encoded || recorded.PaymentHash != payment.Nonce {
+		t.Errorf("Verify() proof = %+v", recorded)
+	}
+
+	// The same credential is the same payment every time it is presented.
+	again, err := rail.Verify(context.Background(), "abc", credential)
+	if err != nil || again.Reference != payment.Reference {
+		t.Errorf("Verify() again = %+v, %v, want reference %s", again, err, payment.Reference)
+	}
+}
+
+func TestVerifyCaveats(t *testing.T) {
+	clock := utils.NewMockClock()
+	lnd, client := newFakeLND(t, clock)
+	rail := newTestRail(t, client)
+
+	credential, encoded := buyCredential(t, rail, lnd,
+		rails.Charge{ShortCode: "abc", Amount: 1_000_000, Credits: 5})
+	_, preimage, _ := strings.Cut(credential, ":")
+
+	// reissue returns the credential with the macaroon changed by edit and
+	// signed with rootKey, or with its signature left as is if rootKey is
+	// nil.
+	reissue := func(edit func(m *macaroon), rootKey []byte) string {
+		m, err := decodeMacaroon(encoded)
+		if err != nil {
+			t.Fatalf("decodeMacaroon() error = %v", err)
+		}
+		edit(m)
+		if rootKey != nil {
+			caveats := m.caveats
+			signed := newMacaroon(rootKey, m.location, m.id)
+			for _, caveat := range caveats {
+				signed.addCaveat(caveat)
+			}
+			m = signed
+		}
+		return m.encode() + ":" + preimage
+	}
+	rootKey, _ := hex.DecodeString(testRootKeyHex)
+	otherKey := make([]byte, 32)
+
+	tests := []struct {
+		name        string
+		shortCode   string
+		credential  string
+		wantErr     error
+		wantAmount  uint64
+		wantCredits uint64
+	}{
+		{
+			name:        "valid",
+			shortCode:   "abc",
+			credential:  credential,
+			wantAmount:  1_000_000,
+			wantCredits: 5,
+		},
+		{
+			name:       "other route",
+			shortCode:  "xyz",
+			credential: credential,
+			wantErr:    rails.ErrWrongRoute,
+		},
+		{
+			name:      "route changed without signing",
+			shortCode: "xyz",
+			credential: reissue(func(m *macaroon) {
+				m.caveats[0] = caveatRoute + "=xyz"
+			}, nil),
+			wantErr: rails.ErrInvalidCredential,
+		},
+		{
+			name:      "amount changed without signing",
+			shortCode: "abc",
+			credential: reissue(func(m *macaroon) {
+				m.caveats[1] = caveatAmount + "=1"
+			}, nil),
+			wantErr: rails.ErrInvalidCredential,
+		},
+		{
+			name:      "caveat dropped without signing",
+			shortCode: "abc",
+			credential: reissue(func(m *macaroon) {
+				m.caveats = m.caveats[:2]
+			}, nil),
+			wantErr: rails.ErrInvalidCredential,
+		},
+		{
+			// Anyone holding a macaroon can add caveats, they only narrow
+			// it and don't replace the ones it was issued with.
+			name:        "caveats added by the holder",
+			shortCode:   "abc",
+			credential:  appendCaveats(t, encoded, preimage, caveatAmount+"=1", caveatCredits+"=1000"),
+			wantAmount:  1_000_000,
+			wantCredits: 5,
+		},
+		{
+			name:      "signed with another root key",
+			shortCode: "abc",
+			credential: reissue(func(m *macaroon) {
+				m.caveats[1] = caveatAmount + "=1"
+			}, otherKey),
+			wantErr: rails.ErrInvalidCredential,
+		},
+		{
+			name:      "missing credits caveat",
+			shortCode: "abc",
+			credential: reissue(func(m *macaroon) {
+				m.caveats = m.caveats[:2]
+			}, rootKey),
+			wantErr: rails.ErrInvalidCredential,
+		},
+		{
+			name:      "non-numeric amount caveat",
+			shortCode: "abc",
+			credential: reissue(func(m *macaroon) {
+				m.caveats[1] = caveatAmount + "=lots"
+			}, rootKey),
+			wantErr: rails.ErrInvalidCredential,
+		},
+		{
+			name:      "unknown identifier version",
+			shortCode: "abc",
+			credential: reissue(func(m *macaroon) {
+				m.id[1] = 1
+			}, rootKey),
+			wantErr: rails.ErrInvalidCredential,
+		},
+		{
+			name:       "not a macaroon",
+			shortCode:  "abc",
+			credential: "bm90IGEgbWFjYXJvb24:" + preimage,
+			wantErr:    rails.ErrInvalidCredential,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment, err := rail.Verify(context.Background(), tt.shortCode, tt.credential)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if payment.Amount != tt.wantAmount || payment.Credits != tt.wantCredits {
+				t.Errorf("Verify() paid %d for %d credits, want %d for %d",
+					payment.Amount, payment.Credits, tt.wantAmount, tt.wantCredits)
+			}
+		})
+	}
+}
+
+// appendCaveats adds caveats to an encoded macaroon like its holder can,
+// chaining them into its signature without the root key.
+func appendCaveats(t *testing.T, encoded string, preimage string, caveats ...string) string {
+	t.Helper()
+
+	m, err := decodeMacaroon(encoded)
+	if err != nil {
+		t.Fatalf("decodeMacaroon() error = %v", err)
+	}
+	for _, caveat := range caveats {
+		m.addCaveat(caveat)
+	}
+	return m.encode() + ":" + preimage
+}
+
+func TestVerifyPreimage(t *testing.T) {
+	clock := utils.NewMockClock()
+	lnd, client := newFakeLND(t, clock)
+	rail := newTestRail(t, client)
+
+	charge := rails.Charge{ShortCode: "abc", Amount: 1_000_000, Credits: 1}
+	credential, encoded := buyCredential(t, rail, lnd, charge)
+	_, preimage, _ := strings.Cut(credential, ":")
+	otherCredential, _ := buyCredential(t, rail, lnd, charge)
+	_, otherPreimage, _ := strings.Cut(otherCredential, ":")
+
+	wrongPreimage := make([]byte, hashSize)
+	rand.Read(wrongPreimage)
+	hash := sha256.Sum256(wrongPreimage)
+
+	tests := []struct {
+		name       string
+		credential string
+	}{
+		{name: "random preimage", credential: encoded + ":" + hex.EncodeToString(wrongPreimage)},
+		{name: "preimage of another invoice", credential: encoded + ":" + otherPreimage},
+		{name: "payment hash instead of preimage", credential: encoded + ":" + hex.EncodeToString(hash[:])},
+		{name: "short preimage", credential: encoded + ":" + preimage[:hashSize]},
+		{name: "not hex", credential: encoded + ":" + strings.Repeat("zz", hashSize)},
+		{name: "no preimage", credential: encoded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := rail.Verify(context.Background(), "abc", tt.credential)
+			if !errors.Is(err, rails.ErrInvalidCredential) {
+				t.Errorf("Verify() error = %v, want %v", err, rails.ErrInvalidCredential)
+			}
+		})
+	}
+}
+
+func TestExpiredInvoice(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := utils.NewMockClock()
+	clock.SetMockClockTime(start)
+	lnd, client := newFakeLND(t, clock)
+	rail := newTestRail(t, client)
+	expiry := time.Duration(DefaultConfig().InvoiceExpirySeconds) * time.Second
+
+	charge := rails.Charge{ShortCode: "abc", Amount: 1_000_000, Credits: 1}
+
+	// An invoice paid in time keeps paying for its credits once it expired.
+	paid, _ := buyCredential(t, rail, lnd, charge)
+
+	challenge, err := rail.Challenge(context.Background(), charge)
+	if err != nil {
+		t.Fatalf("Challenge() error = %v", err)
+	}
+	encoded, invoice := parseChallenge(t, challenge)
+
+	clock.SetMockClockTime(start.Add(expiry))
+
+	if _, err := rail.Verify(context.Background(), "abc", paid); err != nil {
+		t.Errorf("Verify() of invoice paid before it expired error = %v", err)
+	}
+
+	// The preimage of an expired invoice is never revealed, so the macaroon
+	// can't be completed into a credential.
+	if _, err := lnd.pay(invoice); !errors.Is(err, errInvoiceExpired) {
+		t.Fatalf("pay() of expired invoice error = %v, want %v", err, errInvoiceExpired)
+	}
+	_, err = rail.Verify(context.Background(), "abc", encoded+":"+strings.Repeat("00", hashSize))
+	if !errors.Is(err, rails.ErrInvalidCredential) {
+		t.Errorf("Verify() of expired invoice error = %v, want %v", err, rails.ErrInvalidCredential)
+	}
+}
+
+func TestLNDClientErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "error status",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "verification failed", http.StatusUnauthorized)
+			},
+		},
+		{
+			name: "short payment hash",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(addInvoiceResponse{
+					RHash:          base64.StdEncoding.EncodeToString([]byte("short")),
+					PaymentRequest: "lntb1",
+				})
+			},
+		},
+		{
+			name: "no payment request",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(addInvoiceResponse{
+					RHash: base64.StdEncoding.EncodeToString(make([]byte, hashSize)),
+				})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			client, err := NewLNDClient(server.URL, testMacaroonHex, "", time.Second)
+			if err != nil {
+				t.Fatalf("NewLNDClient() error = %v", err)
+			}
+			rail := newTestRail(t, client)
+
+			_, err = rail.Challenge(context.Background(), rails.Charge{ShortCode: "abc", Amount: 1, Credits: 1})
+			if err == nil {
+				t.Errorf("Challenge() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package l402
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Node creates Lightning invoices. It is implemented by LNDClient and by
+// MockNode to take payments without a node.
+type Node interface {
+	// CreateInvoice creates an invoice for amount satoshis that can be paid
+	// for expiry.
+	CreateInvoice(ctx context.Context, amount uint64, memo string,
+		expiry time.Duration) (*Invoice, error)
+}
+
+// Invoice is a Lightning invoice.
+type Invoice struct {
+	// PaymentHash is the SHA-256 hash of the preimage the payer receives
+	// once the invoice is paid.
+	PaymentHash []byte
+	// PaymentRequest is the BOLT 11 encoded invoice.
+	PaymentRequest string
+}
+
+// LNDClient is a Node using the REST API of an LND node, or of any node
+// implementing LND's invoice endpoint.
+type LNDClient struct {
+	url         string
+	macaroonHex string
+	httpClient  *http.Client
+}
+
+// NewLNDClient creates a new LNDClient for the endpoint, authenticated with
+// the hex encoded macaroon. The node's TLS certificate is trusted if
+// tlsCertPath is not empty.
+func NewLNDClient(url string, macaroonHex string, tlsCertPath string,
+	timeout time.Duration) (*LNDClient, error) {
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsCertPath != "" {
+		pem, err := os.ReadFile(tlsCertPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read LND TLS certificate: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, errors.New("invalid LND TLS certificate")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+	}
+
+	return &LNDClient{
+		url:         strings.TrimRight(url, "/"),
+		macaroonHex: macaroonHex,
+		httpClient:  &http.Client{Timeout: timeout, Transport: transport},
+	}, nil
+}
+
+type addInvoiceRequest struct {
+	Value  string `json:"value"`
+	Memo   string `json:"memo,omitempty"`
+	Expiry string `json:"expiry"`
+}
+
+type addInvoiceResponse struct {
+	RHash          string `json:"r_hash"`
+	PaymentRequest string `json:"payment_request"`
+}
+
+// CreateInvoice creates an invoice with LND's POST /v1/invoices.
+func (c *LNDClient) CreateInvoice(ctx context.Context, amount uint64, memo string,
+	expiry time.Duration) (*Invoice, error) {
+
+	// LND encodes 64 bit integers as strings in JSON.
+	body, err := json.Marshal(addInvoiceRequest{
+		Value:  strconv.FormatUint(amount, 10),
+		Memo:   memo,
+		Expiry: strconv.FormatInt(int64(expiry/time.Second), 10),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"/v1/invoices", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.macaroonHex != "" {
+		req.Header.Set("Grpc-Metadata-macaroon", c.macaroonHex)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach LND: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("LND returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var result addInvoiceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode LND invoice: %w", err)
+	}
+
+	// LND encodes bytes as standard base64 in JSON.
+	paymentHash, err := base64.StdEncoding.DecodeString(result.RHash)
+	if err != nil || len(paymentHash) != hashSize {
+		return nil, errors.New("LND returned an invalid payment hash")
+	}
+	if result.PaymentRequest == "" {
+		return nil, errors.New("LND returned no payment request")
+	}
+
+	return &Invoice{
+		PaymentHash:    paymentHash,
+		PaymentRequest: result.PaymentRequest,
+	}, nil
+}
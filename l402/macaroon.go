@@ -0,0 +1,210 @@
+package l402
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// Field types of the version 2 binary macaroon format.
+const (
+	macaroonVersion   = 2
+	fieldEOS          = 0
+	fieldLocation     = 1
+	fieldIdentifier   = 2
+	fieldVerification = 4
+	fieldSignature    = 6
+)
+
+var errInvalidMacaroon = errors.New("invalid macaroon")
+
+// keyGenerator derives the key macaroons are signed with from the root key,
+// like libmacaroons does.
+var keyGenerator = []byte("macaroons-key-generator")
+
+// macaroon is a macaroon with first party caveats only, encoded in the
+// version 2 binary format of libmacaroons and go-macaroon, so L402 clients
+// can decode it.
+type macaroon struct {
+	location  string
+	id        []byte
+	caveats   []string
+	signature []byte
+}
+
+// newMacaroon creates a macaroon signed with the root key.
+func newMacaroon(rootKey []byte, location string, id []byte) *macaroon {
+	return &macaroon{
+		location:  location,
+		id:        id,
+		signature: keyedHash(derivedKey(rootKey), id),
+	}
+}
+
+// addCaveat adds a first party caveat and chains it into the signature.
+func (m *macaroon) addCaveat(caveat string) {
+	m.caveats = append(m.caveats, caveat)
+	m.signature = keyedHash(m.signature, []byte(caveat))
+}
+
+// verify reports whether the macaroon and its caveats are signed with the
+// root key.
+func (m *macaroon) verify(rootKey []byte) bool {
+	signature := keyedHash(derivedKey(rootKey), m.id)
+	for _, caveat := range m.caveats {
+		signature = keyedHash(signature, []byte(caveat))
+	}
+	return hmac.Equal(signature, m.signature)
+}
+
+// caveat returns the value of the caveat with the given name, caveats are
+// written as name=value.
+func (m *macaroon) caveat(name string) (string, bool) {
+	for _, caveat := range m.caveats {
+		key, value, found := strings.Cut(caveat, "=")
+		if found && strings.TrimSpace(key) == name {
+			return strings.TrimSpace(value), true
+		}
+	}
+	return "", false
+}
+
+// encode returns the macaroon in the binary format, base64 encoded.
+func (m *macaroon) encode() string {
+	var buf bytes.Buffer
+	buf.WriteByte(macaroonVersion)
+	if m.location != "" {
+		writeField(&buf, fieldLocation, []byte(m.location))
+	}
+	writeField(&buf, fieldIdentifier, m.id)
+	buf.WriteByte(fieldEOS)
+	for _, caveat := range m.caveats {
+		writeField(&buf, fieldIdentifier, []byte(caveat))
+		buf.WriteByte(fieldEOS)
+	}
+	buf.WriteByte(fieldEOS)
+	writeField(&buf, fieldSignature, m.signature)
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// decodeMacaroon decodes a base64 encoded macaroon in the binary format.
+// Caveats with a verification ID are third party caveats, which are not
+// supported.
+func decodeMacaroon(encoded string) (*macaroon, error) {
+	data, err := decodeBase64(encoded)
+	if err != nil || len(data) == 0 || data[0] != macaroonVersion {
+		return nil, errInvalidMacaroon
+	}
+	r := &fieldReader{data: data[1:]}
+
+	m := &macaroon{}
+	fieldType, value, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+	if fieldType == fieldLocation {
+		m.location = string(value)
+		if fieldType, value, err = r.next(); err != nil {
+			return nil, err
+		}
+	}
+	if fieldType != fieldIdentifier {
+		return nil, errInvalidMacaroon
+	}
+	m.id = value
+	if fieldType, _, err = r.next(); err != nil || fieldType != fieldEOS {
+		return nil, errInvalidMacaroon
+	}
+
+	for {
+		fieldType, value, err = r.next()
+		if err != nil {
+			return nil, err
+		}
+		if fieldType == fieldEOS {
+			break
+		}
+		if fieldType == fieldLocation {
+			if fieldType, value, err = r.next(); err != nil {
+				return nil, err
+			}
+		}
+		if fieldType != fieldIdentifier {
+			return nil, errInvalidMacaroon
+		}
+		m.caveats = append(m.caveats, string(value))
+		if fieldType, _, err = r.next(); err != nil || fieldType != fieldEOS {
+			return nil, errInvalidMacaroon
+		}
+	}
+
+	fieldType, value, err = r.next()
+	if err != nil || fieldType != fieldSignature || len(value) != sha256.Size {
+		return nil, errInvalidMacaroon
+	}
+	m.signature = value
+
+	return m, nil
+}
+
+// fieldReader reads the fields of a binary macaroon.
+type fieldReader struct {
+	data []byte
+}
+
+// next returns the type and value of the next field, an EOS field has no
+// value.
+func (r *fieldReader) next() (byte, []byte, error) {
+	if len(r.data) == 0 {
+		return 0, nil, errInvalidMacaroon
+	}
+	fieldType := r.data[0]
+	r.data = r.data[1:]
+	if fieldType == fieldEOS {
+		return fieldType, nil, nil
+	}
+	if fieldType == fieldVerification {
+		return 0, nil, errInvalidMacaroon
+	}
+
+	length, n := binary.Uvarint(r.data)
+	if n <= 0 || length > uint64(len(r.data)-n) {
+		return 0, nil, errInvalidMacaroon
+	}
+	value := r.data[n : n+int(length)]
+	r.data = r.data[n+int(length):]
+	return fieldType, value, nil
+}
+
+// writeField writes a field with a length prefixed value.
+func writeField(buf *bytes.Buffer, fieldType byte, value []byte) {
+	buf.WriteByte(fieldType)
+	buf.Write(binary.AppendUvarint(nil, uint64(len(value))))
+	buf.Write(value)
+}
+
+// derivedKey returns the key macaroons are signed with for the root key.
+func derivedKey(rootKey []byte) []byte {
+	return keyedHash(keyGenerator, rootKey)
+}
+
+// keyedHash returns the HMAC-SHA256 of data with key.
+func keyedHash(key []byte, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// decodeBase64 decodes standard or URL safe base64, padded or not, as
+// clients send either.
+func decodeBase64(encoded string) ([]byte, error) {
+	encoded = strings.TrimRight(encoded, "=")
+	if strings.ContainsAny(encoded, "-_") {
+		return base64.RawURLEncoding.DecodeString(encoded)
+	}
+	return base64.RawStdEncoding.DecodeString(encoded)
+}
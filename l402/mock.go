@@ -0,0 +1,70 @@
+package l402
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrUnknownInvoice is returned by MockNode.Pay for invoices it did not
+// create.
+var ErrUnknownInvoice = errors.New("unknown invoice")
+
+// mockInvoicePrefix starts the payment requests of MockNode, which are not
+// valid BOLT 11 invoices.
+const mockInvoicePrefix = "lnmock"
+
+// MockNode is a Node that keeps invoices in memory and pays them on demand,
+// to take L402 payments in tests and local setups without a Lightning node.
+type MockNode struct {
+	mu        sync.Mutex
+	preimages map[string][]byte
+	amounts   map[string]uint64
+}
+
+// NewMockNode creates a new MockNode.
+func NewMockNode() *MockNode {
+	return &MockNode{
+		preimages: make(map[string][]byte),
+		amounts:   make(map[string]uint64),
+	}
+}
+
+// CreateInvoice creates an invoice with a random preimage.
+func (n *MockNode) CreateInvoice(ctx context.Context, amount uint64, memo string,
+	expiry time.Duration) (*Invoice, error) {
+
+	preimage := make([]byte, hashSize)
+	if _, err := rand.Read(preimage); err != nil {
+		return nil, err
+	}
+	paymentHash := sha256.Sum256(preimage)
+	paymentRequest := mockInvoicePrefix + hex.EncodeToString(paymentHash[:])
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.preimages[paymentRequest] = preimage
+	n.amounts[paymentRequest] = amount
+
+	return &Invoice{
+		PaymentHash:    paymentHash[:],
+		PaymentRequest: paymentRequest,
+	}, nil
+}
+
+// Pay pays an invoice the node created and returns the amount paid and the
+// hex encoded preimage, which completes the L402 credential.
+func (n *MockNode) Pay(paymentRequest string) (uint64, string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	preimage, ok := n.preimages[paymentRequest]
+	if !ok {
+		return 0, "", ErrUnknownInvoice
+	}
+	return n.amounts[paymentRequest], hex.EncodeToString(preimage), nil
+}
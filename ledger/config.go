@@ -0,0 +1,13 @@
+package ledger
+
+// DefaultConfig returns all default values for the Config struct.
+func DefaultConfig() Config {
+	return Config{
+		AuditIntervalSeconds: 3600,
+	}
+}
+
+// Config holds the configuration for the ledger.
+type Config struct {
+	AuditIntervalSeconds int `long:"audit_interval_seconds" description:"Seconds between ledger consistency checks, 0 disables them"`
+}
@@ -0,0 +1,41 @@
+package ledger
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/auth"
+)
+
+// Handler handles HTTP requests related to the ledger.
+type Handler struct {
+	ledgerService *Service
+}
+
+// NewHandler creates a new Handler.
+func NewHandler(ledgerService *Service) *Handler {
+	return &Handler{
+		ledgerService: ledgerService,
+	}
+}
+
+// GetBalances returns the balances of the user's route and earnings accounts.
+// Route accounts hold what was paid but not yet earned through use.
+func (h *Handler) GetBalances(gCtx *gin.Context) {
+	authPayload, exists := gCtx.Get(auth.AuthorizationPayloadKey)
+	if !exists {
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	payload := authPayload.(*auth.Claims)
+
+	balances, err := h.ledgerService.ListBalances(gCtx.Request.Context(), payload.UserID)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve balances"})
+		return
+	}
+
+	gCtx.JSON(http.StatusOK, gin.H{"balances": balances})
+}
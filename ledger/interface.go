@@ -0,0 +1,27 @@
+package ledger
+
+import (
+	"context"
+)
+
+// Store provides access to the ledger storage.
+type Store interface {
+	// ListLedgerBalancesByUserID retrieves the balances of a user's accounts.
+	ListLedgerBalancesByUserID(ctx context.Context, userID uint64) ([]AccountBalance, error)
+
+	// ListUnbalancedLedgerTransactions retrieves the transactions whose
+	// entries don't add up to zero.
+	ListUnbalancedLedgerTransactions(ctx context.Context) ([]UnbalancedTransaction, error)
+
+	// ListPurchasesWithoutLedger retrieves the IDs of paid purchases that
+	// were never posted to the ledger.
+	ListPurchasesWithoutLedger(ctx context.Context) ([]uint64, error)
+
+	// ListOverdrawnRouteAccounts retrieves the route accounts with a
+	// negative balance.
+	ListOverdrawnRouteAccounts(ctx context.Context) ([]AccountBalance, error)
+
+	// ListWalletsOffLedger retrieves the wallets whose balance differs from
+	// the balance of their ledger account.
+	ListWalletsOffLedger(ctx context.Context) ([]OffLedgerWallet, error)
+}
@@ -0,0 +1,184 @@
+package ledger
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Account kinds. Payer accounts are external wallets and go negative as they
+// pay. Route accounts hold what was paid for a route until it is earned by the
+// owner's user account, or paid out to payee and platform accounts. Wallet
+// accounts hold what a payer topped up with an owner until it is drawn.
+const (
+	AccountPayer    = "payer"
+	AccountRoute    = "route"
+	AccountUser     = "user"
+	AccountPayee    = "payee"
+	AccountPlatform = "platform"
+	AccountWallet   = "wallet"
+)
+
+// Transaction kinds.
+const (
+	KindPurchase   = "purchase"
+	KindFee        = "fee"
+	KindSplit      = "split"
+	KindCreditUse  = "credit_use"
+	KindUsage      = "usage"
+	KindTopUp      = "top_up"
+	KindWalletDraw = "wallet_draw"
+)
+
+// Account identifies a ledger account.
+type Account struct {
+	Kind      string
+	Reference string
+	// UserID is the user owning route and user accounts, 0 otherwise.
+	UserID uint64
+}
+
+// PayerAccount returns the account of the wallet a payment came from.
+func PayerAccount(address string) Account {
+	address = strings.ToLower(strings.TrimSpace(address))
+	if address == "" {
+		address = "unknown"
+	}
+	return Account{Kind: AccountPayer, Reference: address}
+}
+
+// RouteAccount returns the account of a paid route.
+func RouteAccount(routeID, userID uint64) Account {
+	return Account{
+		Kind:      AccountRoute,
+		Reference: strconv.FormatUint(routeID, 10),
+		UserID:    userID,
+	}
+}
+
+// UserAccount returns the earnings account of a user.
+func UserAccount(userID uint64) Account {
+	return Account{
+		Kind:      AccountUser,
+		Reference: strconv.FormatUint(userID, 10),
+		UserID:    userID,
+	}
+}
+
+// PayeeAccount returns the account of a partner sharing a route's revenue or
+// of an affiliate earning commission on it.
+func PayeeAccount(address string) Account {
+	return Account{Kind: AccountPayee, Reference: strings.ToLower(address)}
+}
+
+// PlatformAccount returns the account platform fees are owed to.
+func PlatformAccount(address string) Account {
+	return Account{Kind: AccountPlatform, Reference: strings.ToLower(address)}
+}
+
+// WalletAccount returns the account of a payer's wallet with an owner.
+func WalletAccount(walletID uint64) Account {
+	return Account{Kind: AccountWallet, Reference: strconv.FormatUint(walletID, 10)}
+}
+
+// Entry moves an amount in or out of an account. Positive amounts add to the
+// account's balance.
+type Entry struct {
+	Account Account
+	Amount  int64
+}
+
+// Transaction is a group of entries that are posted together and add up to
+// zero.
+type Transaction struct {
+	// Reference uniquely identifies the event the transaction records.
+	Reference  string
+	Kind       string
+	PurchaseID uint64
+	IsTest     bool
+	Entries    []Entry
+}
+
+// Balanced reports whether the entries of the transaction add up to zero.
+func (t *Transaction) Balanced() bool {
+	var sum int64
+	for _, entry := range t.Entries {
+		sum += entry.Amount
+	}
+	return sum == 0
+}
+
+// transfer appends the entries moving amount from one account to another.
+// Amounts are stored as BIGINT, so they never exceed the int64 range.
+func (t *Transaction) transfer(from, to Account, amount uint64) {
+	if amount == 0 {
+		return
+	}
+	t.Entries = append(t.Entries,
+		Entry{Account: from, Amount: -int64(amount)},
+		Entry{Account: to, Amount: int64(amount)},
+	)
+}
+
+// AccountBalance is the balance of an account, separately for test and real
+// network payments.
+type AccountBalance struct {
+	Kind       string `json:"kind"`
+	Reference  string `json:"reference"`
+	IsTest     bool   `json:"is_test"`
+	Balance    int64  `json:"balance"`
+	EntryCount uint64 `json:"entry_count"`
+}
+
+// UnbalancedTransaction is a transaction whose entries don't add up to zero.
+type UnbalancedTransaction struct {
+	ID        uint64
+	Reference string
+	Imbalance int64
+}
+
+// OffLedgerWallet is a wallet whose balance differs from the balance of its
+// ledger account.
+type OffLedgerWallet struct {
+	WalletID      uint64
+	Balance       uint64
+	LedgerBalance int64
+}
+
+// ConsistencyReport lists the problems found by a consistency check.
+type ConsistencyReport struct {
+	UnbalancedTransactions []UnbalancedTransaction
+	PurchasesWithoutLedger []uint64
+	OverdrawnRoutes        []AccountBalance
+	OffLedgerWallets       []OffLedgerWallet
+}
+
+// Consistent reports whether the check found no problems.
+func (r *ConsistencyReport) Consistent() bool {
+	return len(r.UnbalancedTransactions) == 0 &&
+		len(r.PurchasesWithoutLedger) == 0 &&
+		len(r.OverdrawnRoutes) == 0 &&
+		len(r.OffLedgerWallets) == 0
+}
+
+// purchaseReference returns the reference of a purchase's transaction, or of
+// a wallet top-up's or draw's with their ID.
+func purchaseReference(kind string, purchaseID uint64, suffix ...any) string {
+	reference := fmt.Sprintf("%s:%d", kind, purchaseID)
+	for _, s := range suffix {
+		reference += fmt.Sprintf(":%v", s)
+	}
+	return reference
+}
+
+// portion returns part/whole of amount, rounded down. Summing the differences
+// of consecutive portions up to whole always gives back exactly amount.
+func portion(amount, part, whole uint64) uint64 {
+	if whole == 0 || part >= whole {
+		return amount
+	}
+	hi, lo := bits.Mul64(amount, part)
+	quo, _ := bits.Div64(hi, lo, whole)
+	return quo
+}
@@ -0,0 +1,241 @@
+package ledger
+
+import (
+	"math"
+	"testing"
+
+	"linkshrink/splits"
+)
+
+// balances returns the balance of every account the transactions move
+// amounts in and out of.
+func balances(t *testing.T, txs []Transaction) map[Account]int64 {
+	t.Helper()
+
+	balances := make(map[Account]int64)
+	for _, tx := range txs {
+		if !tx.Balanced() {
+			t.Errorf("transaction %s is unbalanced", tx.Reference)
+		}
+		for _, entry := range tx.Entries {
+			balances[entry.Account] += entry.Amount
+		}
+	}
+	return balances
+}
+
+func TestSaleTransactions(t *testing.T) {
+	const (
+		price   = 10_000
+		routeID = 3
+		ownerID = 7
+	)
+	route := RouteAccount(routeID, ownerID)
+	payouts := []splits.Payout{
+		{Role: splits.RolePlatform, PayeeAddress: "0xPLATFORM", Amount: 500},
+		{Role: splits.RolePartner, PayeeAddress: "0xPartner", Amount: 2_000},
+		{Role: splits.RoleAffiliate, PayeeAddress: "0xAffiliate", Amount: 1_000},
+		// The owner's payout is what the route account keeps.
+		{Role: splits.RoleOwner, PayeeAddress: "0xOwner", Amount: 6_500},
+	}
+
+	tests := []struct {
+		name    string
+		sale    Sale
+		wantTxs int
+		want    map[Account]int64
+	}{
+		{
+			name:    "credits with splits",
+			sale:    Sale{Price: price, Payouts: payouts, Credits: 4},
+			wantTxs: 4,
+			want: map[Account]int64{
+				PayerAccount("0xPayer"):       -price,
+				PlatformAccount("0xplatform"): 500,
+				PayeeAccount("0xpartner"):     2_000,
+				PayeeAccount("0xaffiliate"):   1_000,
+				route:                         6_500 - 1_625,
+				UserAccount(ownerID):          1_625,
+			},
+		},
+		{
+			name:    "without payouts",
+			sale:    Sale{Price: price, Credits: 1},
+			wantTxs: 2,
+			want: map[Account]int64{
+				PayerAccount("0xPayer"): -price,
+				UserAccount(ownerID):    price,
+			},
+		},
+		{
+			name:    "metered",
+			sale:    Sale{Price: price, Payouts: payouts[:1], Credits: 1, Metered: true},
+			wantTxs: 2,
+			want: map[Account]int64{
+				PayerAccount("0xPayer"):       -price,
+				PlatformAccount("0xplatform"): 500,
+				route:                         price - 500,
+			},
+		},
+		{
+			name:    "bundle",
+			sale:    Sale{Price: price, Credits: 2, Bundle: true},
+			wantTxs: 1,
+			want: map[Account]int64{
+				PayerAccount("0xPayer"): -price,
+				route:                   price,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sale := tt.sale
+			sale.PurchaseID = 11
+			sale.RouteID = routeID
+			sale.OwnerID = ownerID
+			sale.Payer = " 0xPAYER "
+
+			txs, err := (&Service{}).SaleTransactions(sale)
+			if err != nil {
+				t.Fatalf("SaleTransactions() error = %v", err)
+			}
+			if len(txs) != tt.wantTxs {
+				t.Errorf("SaleTransactions() returned %d transactions, want %d", len(txs), tt.wantTxs)
+			}
+
+			got := balances(t, txs)
+			for account, want := range tt.want {
+				if got[account] != want {
+					t.Errorf("%s %s balance = %d, want %d", account.Kind, account.Reference,
+						got[account], want)
+				}
+			}
+			for account, balance := range got {
+				if _, ok := tt.want[account]; !ok && balance != 0 {
+					t.Errorf("unexpected %s %s balance %d", account.Kind, account.Reference, balance)
+				}
+			}
+		})
+	}
+}
+
+func TestPortion(t *testing.T) {
+	tests := []struct {
+		amount, part, whole uint64
+		want                uint64
+	}{
+		{amount: 100, part: 1, whole: 3, want: 33},
+		{amount: 100, part: 2, whole: 3, want: 66},
+		{amount: 100, part: 3, whole: 3, want: 100},
+		{amount: 100, part: 4, whole: 3, want: 100},
+		{amount: 100, part: 0, whole: 3, want: 0},
+		{amount: 100, part: 1, whole: 0, want: 100},
+		{amount: math.MaxInt64, part: math.MaxInt64 - 1, whole: math.MaxInt64, want: math.MaxInt64 - 1},
+	}
+
+	for _, tt := range tests {
+		if got := portion(tt.amount, tt.part, tt.whole); got != tt.want {
+			t.Errorf("portion(%d, %d, %d) = %d, want %d", tt.amount, tt.part, tt.whole, got, tt.want)
+		}
+	}
+}
+
+func TestCreditUseTransactionsEarnAllCredits(t *testing.T) {
+	for _, tt := range []struct{ earnings, credits uint64 }{
+		{earnings: 10_000, credits: 3},
+		{earnings: 7, credits: 10},
+		{earnings: 1, credits: 1},
+		{earnings: math.MaxInt64, credits: 7},
+	} {
+		// The first credit is posted with the sale.
+		sale, err := (&Service{}).SaleTransactions(Sale{PurchaseID: 1, RouteID: 2, OwnerID: 3,
+			Price: tt.earnings, Credits: tt.credits})
+		if err != nil {
+			t.Fatalf("SaleTransactions() error = %v", err)
+		}
+		txs := sale
+		for used := uint64(2); used <= tt.credits; used++ {
+			use, err := CreditUseTransactions(CreditUse{PurchaseID: 1, RouteID: 2, OwnerID: 3,
+				CreditsUsed: used, Credits: tt.credits}, tt.earnings)
+			if err != nil {
+				t.Fatalf("CreditUseTransactions() error = %v", err)
+			}
+			txs = append(txs, use...)
+		}
+
+		got := balances(t, txs)
+		if got[UserAccount(3)] != int64(tt.earnings) || got[RouteAccount(2, 3)] != 0 {
+			t.Errorf("%d credits of %d: owner earned %d and route kept %d, want all earned",
+				tt.credits, tt.earnings, got[UserAccount(3)], got[RouteAccount(2, 3)])
+		}
+	}
+}
+
+func TestUsageTransactions(t *testing.T) {
+	const earnings = 9_000
+	const price = 10_000
+
+	var txs []Transaction
+	var charged uint64
+	for _, charge := range []uint64{1, 333, 3_333, 0, price} {
+		after := min(charged+charge, price)
+		usage, err := UsageTransactions(Usage{PurchaseID: 1, RouteID: 2, OwnerID: 3,
+			ChargedBefore: charged, ChargedAfter: after, Price: price}, earnings)
+		if err != nil {
+			t.Fatalf("UsageTransactions() error = %v", err)
+		}
+		if charge == 0 && len(usage) != 0 {
+			t.Errorf("UsageTransactions() posted %d transactions for no charge", len(usage))
+		}
+		txs = append(txs, usage...)
+		charged = after
+	}
+
+	if got := balances(t, txs)[UserAccount(3)]; got != earnings {
+		t.Errorf("owner earned %d once the balance was used up, want %d", got, earnings)
+	}
+}
+
+func TestWalletTransactions(t *testing.T) {
+	const (
+		walletID = 5
+		routeID  = 2
+		ownerID  = 3
+	)
+
+	topUp, err := TopUpTransactions(TopUp{TopUpID: 1, WalletID: walletID, Payer: "0xPayer",
+		IsTest: true, Amount: 10_000})
+	if err != nil {
+		t.Fatalf("TopUpTransactions() error = %v", err)
+	}
+	draw, err := WalletDrawTransactions(WalletDraw{DrawID: 1, WalletID: walletID, RouteID: routeID,
+		OwnerID: ownerID, IsTest: true, Amount: 4_000})
+	if err != nil {
+		t.Fatalf("WalletDrawTransactions() error = %v", err)
+	}
+	// A free draw posts nothing.
+	free, err := WalletDrawTransactions(WalletDraw{DrawID: 2, WalletID: walletID, RouteID: routeID,
+		OwnerID: ownerID, IsTest: true})
+	if err != nil || len(free) != 0 {
+		t.Errorf("WalletDrawTransactions() for a free draw = %v, %v, want none", free, err)
+	}
+
+	got := balances(t, append(topUp, draw...))
+	want := map[Account]int64{
+		PayerAccount("0xpayer"):        -10_000,
+		WalletAccount(walletID):        6_000,
+		RouteAccount(routeID, ownerID): 0,
+		UserAccount(ownerID):           4_000,
+	}
+	for account, balance := range want {
+		if got[account] != balance {
+			t.Errorf("%s %s balance = %d, want %d", account.Kind, account.Reference, got[account], balance)
+		}
+	}
+	for _, tx := range draw {
+		if !tx.IsTest || tx.PurchaseID != 0 {
+			t.Errorf("draw transaction %s IsTest = %t, PurchaseID = %d", tx.Reference, tx.IsTest, tx.PurchaseID)
+		}
+	}
+}
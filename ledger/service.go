@@ -0,0 +1,310 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"linkshrink/splits"
+)
+
+// Service provides business logic for posting to and auditing the ledger.
+type Service struct {
+	cfg *Config
+
+	logger *slog.Logger
+	store  Store
+}
+
+// NewService creates a new ledger Service.
+func NewService(logger *slog.Logger, store Store, cfg *Config) *Service {
+	return &Service{
+		cfg:    cfg,
+		logger: logger,
+		store:  store,
+	}
+}
+
+// Sale describes a settled purchase of a route.
+type Sale struct {
+	PurchaseID uint64
+	RouteID    uint64
+	OwnerID    uint64
+	Payer      string
+	IsTest     bool
+	Price      uint64
+
+	// Payouts is how the price is divided between the owner, partners,
+	// affiliates and the platform. Without payouts the owner is owed the full price.
+	Payouts []splits.Payout
+
+	// Credits is the number of credits bought. The first one is used by the
+	// request that paid, unless the purchase is Metered or of a Bundle,
+	// whose credits are used by requests to the bundle's routes.
+	Credits uint64
+	Metered bool
+	Bundle  bool
+}
+
+// SaleTransactions returns the transactions posting a settled purchase: the
+// payment into the route account, the platform fee, partner shares and
+// affiliate commission out of it, and the first credit used. They are
+// posted with the purchase being settled, so a settled purchase is never
+// missing from the ledger.
+func (s *Service) SaleTransactions(sale Sale) ([]Transaction, error) {
+	route := RouteAccount(sale.RouteID, sale.OwnerID)
+
+	purchase := Transaction{
+		Reference:  purchaseReference(KindPurchase, sale.PurchaseID),
+		Kind:       KindPurchase,
+		PurchaseID: sale.PurchaseID,
+		IsTest:     sale.IsTest,
+	}
+	purchase.transfer(PayerAccount(sale.Payer), route, sale.Price)
+
+	fee := Transaction{
+		Reference:  purchaseReference(KindFee, sale.PurchaseID),
+		Kind:       KindFee,
+		PurchaseID: sale.PurchaseID,
+		IsTest:     sale.IsTest,
+	}
+	split := Transaction{
+		Reference:  purchaseReference(KindSplit, sale.PurchaseID),
+		Kind:       KindSplit,
+		PurchaseID: sale.PurchaseID,
+		IsTest:     sale.IsTest,
+	}
+
+	earnings := sale.Price
+	for _, payout := range sale.Payouts {
+		switch payout.Role {
+		case splits.RolePlatform:
+			fee.transfer(route, PlatformAccount(payout.PayeeAddress), payout.Amount)
+			earnings -= payout.Amount
+		case splits.RolePartner, splits.RoleAffiliate:
+			split.transfer(route, PayeeAccount(payout.PayeeAddress), payout.Amount)
+			earnings -= payout.Amount
+		}
+	}
+
+	txs := []Transaction{purchase, fee, split}
+	if !sale.Metered && !sale.Bundle {
+		creditUse := Transaction{
+			Reference:  purchaseReference(KindCreditUse, sale.PurchaseID, 1),
+			Kind:       KindCreditUse,
+			PurchaseID: sale.PurchaseID,
+			IsTest:     sale.IsTest,
+		}
+		creditUse.transfer(route, UserAccount(sale.OwnerID), portion(earnings, 1, sale.Credits))
+		txs = append(txs, creditUse)
+	}
+
+	return postable(txs...)
+}
+
+// CreditUse describes a credit of a purchase being used.
+type CreditUse struct {
+	PurchaseID uint64
+	RouteID    uint64
+	OwnerID    uint64
+	IsTest     bool
+
+	// CreditsUsed is the number of credits used including this one, known
+	// once the credit is used.
+	CreditsUsed uint64
+	Credits     uint64
+}
+
+// CreditUseTransactions returns the transaction posting the owner earning
+// their share of a used credit. Earnings is what the route account kept of
+// the purchase after fees and splits. It is posted with the credit being
+// used, so the ledger never misses a used credit.
+func CreditUseTransactions(use CreditUse, earnings uint64) ([]Transaction, error) {
+	amount := portion(earnings, use.CreditsUsed, use.Credits) -
+		portion(earnings, use.CreditsUsed-1, use.Credits)
+
+	tx := Transaction{
+		Reference:  purchaseReference(KindCreditUse, use.PurchaseID, use.CreditsUsed),
+		Kind:       KindCreditUse,
+		PurchaseID: use.PurchaseID,
+		IsTest:     use.IsTest,
+	}
+	tx.transfer(RouteAccount(use.RouteID, use.OwnerID), UserAccount(use.OwnerID), amount)
+
+	return postable(tx)
+}
+
+// Usage describes usage being charged to the balance of a metered purchase.
+type Usage struct {
+	PurchaseID uint64
+	RouteID    uint64
+	OwnerID    uint64
+	IsTest     bool
+
+	// ChargedBefore and ChargedAfter are the purchase's total amount
+	// charged before and after the usage, known once the usage is charged.
+	ChargedBefore uint64
+	ChargedAfter  uint64
+	Price         uint64
+}
+
+// UsageTransactions returns the transaction posting the owner earning their
+// share of charged usage. Earnings is what the route account kept of the
+// purchase after fees and splits. It is posted with the usage being charged.
+func UsageTransactions(usage Usage, earnings uint64) ([]Transaction, error) {
+	amount := portion(earnings, usage.ChargedAfter, usage.Price) -
+		portion(earnings, usage.ChargedBefore, usage.Price)
+
+	tx := Transaction{
+		Reference:  purchaseReference(KindUsage, usage.PurchaseID, usage.ChargedAfter),
+		Kind:       KindUsage,
+		PurchaseID: usage.PurchaseID,
+		IsTest:     usage.IsTest,
+	}
+	tx.transfer(RouteAccount(usage.RouteID, usage.OwnerID), UserAccount(usage.OwnerID), amount)
+
+	return postable(tx)
+}
+
+// TopUp describes a settled top-up of a payer's wallet with an owner.
+type TopUp struct {
+	TopUpID  uint64
+	WalletID uint64
+	Payer    string
+	IsTest   bool
+	Amount   uint64
+}
+
+// TopUpTransactions returns the transaction posting a top-up, the payment
+// into the wallet account. It is posted with the top-up adding to the
+// wallet's balance.
+func TopUpTransactions(topUp TopUp) ([]Transaction, error) {
+	tx := Transaction{
+		Reference: purchaseReference(KindTopUp, topUp.TopUpID),
+		Kind:      KindTopUp,
+		IsTest:    topUp.IsTest,
+	}
+	tx.transfer(PayerAccount(topUp.Payer), WalletAccount(topUp.WalletID), topUp.Amount)
+
+	return postable(tx)
+}
+
+// WalletDraw describes a request to a route being paid from a wallet.
+type WalletDraw struct {
+	DrawID   uint64
+	WalletID uint64
+	RouteID  uint64
+	OwnerID  uint64
+	IsTest   bool
+	Amount   uint64
+}
+
+// WalletDrawTransactions returns the transactions posting a wallet draw: the
+// payment out of the wallet account into the route account, and the owner
+// earning it with the request it paid for. They are posted with the draw
+// taking from the wallet's balance.
+func WalletDrawTransactions(draw WalletDraw) ([]Transaction, error) {
+	route := RouteAccount(draw.RouteID, draw.OwnerID)
+
+	payment := Transaction{
+		Reference: purchaseReference(KindWalletDraw, draw.DrawID),
+		Kind:      KindWalletDraw,
+		IsTest:    draw.IsTest,
+	}
+	payment.transfer(WalletAccount(draw.WalletID), route, draw.Amount)
+
+	creditUse := Transaction{
+		Reference: purchaseReference(KindCreditUse, draw.DrawID, KindWalletDraw),
+		Kind:      KindCreditUse,
+		IsTest:    draw.IsTest,
+	}
+	creditUse.transfer(route, UserAccount(draw.OwnerID), draw.Amount)
+
+	return postable(payment, creditUse)
+}
+
+// postable returns the transactions that have entries, checking each of
+// them balances.
+func postable(txs ...Transaction) ([]Transaction, error) {
+	nonEmpty := make([]Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if len(tx.Entries) == 0 {
+			continue
+		}
+		if !tx.Balanced() {
+			return nil, fmt.Errorf("ledger transaction %s is unbalanced", tx.Reference)
+		}
+		nonEmpty = append(nonEmpty, tx)
+	}
+	return nonEmpty, nil
+}
+
+// ListBalances retrieves the balances of a user's route and earnings accounts.
+func (s *Service) ListBalances(ctx context.Context, userID uint64) ([]AccountBalance, error) {
+	return s.store.ListLedgerBalancesByUserID(ctx, userID)
+}
+
+// CheckConsistency checks that every transaction balances, every paid
+// purchase was posted, no route account paid out more than it received and
+// every wallet's balance matches its account.
+func (s *Service) CheckConsistency(ctx context.Context) (*ConsistencyReport, error) {
+	unbalanced, err := s.store.ListUnbalancedLedgerTransactions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unbalanced transactions: %w", err)
+	}
+
+	missing, err := s.store.ListPurchasesWithoutLedger(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purchases without ledger: %w", err)
+	}
+
+	overdrawn, err := s.store.ListOverdrawnRouteAccounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list overdrawn route accounts: %w", err)
+	}
+
+	offLedger, err := s.store.ListWalletsOffLedger(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wallets off the ledger: %w", err)
+	}
+
+	return &ConsistencyReport{
+		UnbalancedTransactions: unbalanced,
+		PurchasesWithoutLedger: missing,
+		OverdrawnRoutes:        overdrawn,
+		OffLedgerWallets:       offLedger,
+	}, nil
+}
+
+// RunAudit checks the ledger's consistency every interval until the context
+// is cancelled, logging any problems found.
+func (s *Service) RunAudit(ctx context.Context) {
+	if s.cfg.AuditIntervalSeconds <= 0 {
+		s.logger.Info("Ledger audits disabled")
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(s.cfg.AuditIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := s.CheckConsistency(ctx)
+			if err != nil {
+				s.logger.Error("Ledger audit failed", "error", err)
+				continue
+			}
+			if !report.Consistent() {
+				s.logger.Error("Ledger audit found inconsistencies",
+					"unbalancedTransactions", report.UnbalancedTransactions,
+					"purchasesWithoutLedger", report.PurchasesWithoutLedger,
+					"overdrawnRoutes", report.OverdrawnRoutes,
+					"offLedgerWallets", report.OffLedgerWallets)
+			}
+		}
+	}
+}
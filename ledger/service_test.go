@@ -0,0 +1,78 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// auditStore is a Store reporting fixed consistency problems.
+type auditStore struct {
+	Store
+
+	unbalanced []UnbalancedTransaction
+	missing    []uint64
+	overdrawn  []AccountBalance
+	offLedger  []OffLedgerWallet
+	err        error
+}
+
+func (s *auditStore) ListUnbalancedLedgerTransactions(ctx context.Context) ([]UnbalancedTransaction, error) {
+	return s.unbalanced, s.err
+}
+
+func (s *auditStore) ListPurchasesWithoutLedger(ctx context.Context) ([]uint64, error) {
+	return s.missing, nil
+}
+
+func (s *auditStore) ListOverdrawnRouteAccounts(ctx context.Context) ([]AccountBalance, error) {
+	return s.overdrawn, nil
+}
+
+func (s *auditStore) ListWalletsOffLedger(ctx context.Context) ([]OffLedgerWallet, error) {
+	return s.offLedger, nil
+}
+
+func TestCheckConsistency(t *testing.T) {
+	errStore := errors.New("store failed")
+
+	tests := []struct {
+		name           string
+		store          *auditStore
+		wantConsistent bool
+		wantErr        error
+	}{
+		{name: "consistent", store: &auditStore{}, wantConsistent: true},
+		{
+			name:  "unbalanced transaction",
+			store: &auditStore{unbalanced: []UnbalancedTransaction{{ID: 1, Reference: "fee:1", Imbalance: 5}}},
+		},
+		{name: "purchase without ledger", store: &auditStore{missing: []uint64{4}}},
+		{
+			name:  "overdrawn route",
+			store: &auditStore{overdrawn: []AccountBalance{{Kind: AccountRoute, Reference: "2", Balance: -1}}},
+		},
+		{
+			name:  "wallet off the ledger",
+			store: &auditStore{offLedger: []OffLedgerWallet{{WalletID: 5, Balance: 100, LedgerBalance: 90}}},
+		},
+		{name: "store error", store: &auditStore{err: errStore}, wantErr: errStore},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(nil, tt.store, nil)
+
+			report, err := service.CheckConsistency(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CheckConsistency() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if report.Consistent() != tt.wantConsistent {
+				t.Errorf("Consistent() = %t, want %t for %+v", report.Consistent(), tt.wantConsistent, report)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MaxAmount is the largest amount that can be stored, the range of the BIGINT
+// columns amounts are stored in.
+const MaxAmount uint64 = math.MaxInt64
+
+// Custom errors for amount parsing
+var (
+	ErrInvalidAmount  = errors.New("invalid amount: must be a decimal number")
+	ErrNegativeAmount = errors.New("amount must be greater or equal to 0")
+	ErrTooPrecise     = errors.New("amount has more decimals than the asset supports")
+	ErrAmountTooLarge = errors.New("amount is too large")
+)
+
+// Asset is a token that amounts are denominated in. Amounts are always held
+// as integers in the smallest unit of the asset.
+type Asset struct {
+	Symbol   string
+	Decimals uint8
+}
+
+// USDC is the asset route prices are denominated in.
+var USDC = Asset{Symbol: "USDC", Decimals: 6}
+
+// Parse converts a decimal string such as "0.29" to base units of the asset.
+// Parsing is exact, amounts with more decimals than the asset supports are
+// rejected rather than rounded.
+func (a Asset) Parse(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "-") {
+		return 0, ErrNegativeAmount
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, ErrInvalidAmount
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return 0, ErrInvalidAmount
+	}
+
+	// Trailing zeros past the asset's decimals don't change the amount.
+	if len(frac) > int(a.Decimals) {
+		if strings.TrimRight(frac[a.Decimals:], "0") != "" {
+			return 0, ErrTooPrecise
+		}
+		frac = frac[:a.Decimals]
+	}
+	frac += strings.Repeat("0", int(a.Decimals)-len(frac))
+
+	var amount uint64
+	for _, digit := range whole + frac {
+		next := amount*10 + uint64(digit-'0')
+		if amount > MaxAmount/10 || next > MaxAmount {
+			return 0, ErrAmountTooLarge
+		}
+		amount = next
+	}
+
+	return amount, nil
+}
+
+// Format converts an amount in base units to a decimal string with all of
+// the asset's decimals, e.g. "0.290000".
+func (a Asset) Format(amount uint64) string {
+	digits := strconv.FormatUint(amount, 10)
+	if a.Decimals == 0 {
+		return digits
+	}
+
+	if len(digits) <= int(a.Decimals) {
+		digits = strings.Repeat("0", int(a.Decimals)-len(digits)+1) + digits
+	}
+	point := len(digits) - int(a.Decimals)
+	return digits[:point] + "." + digits[point:]
+}
+
+// FormatCompact converts an amount in base units to the shortest decimal
+// string representing it, e.g. "0.29" or "1".
+func (a Asset) FormatCompact(amount uint64) string {
+	formatted := a.Format(amount)
+	if a.Decimals == 0 {
+		return formatted
+	}
+	return strings.TrimSuffix(strings.TrimRight(formatted, "0"), ".")
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package money
+
+import (
+	"errors"
+	"testing"
+	"testing/quick"
+)
+
+func TestParseFormatRoundTrip(t *testing.T) {
+	assets := []Asset{USDC, {Symbol: "SATS", Decimals: 0}, {Symbol: "ETH", Decimals: 18}}
+	for _, asset := range assets {
+		roundTrip := func(amount uint64) bool {
+			amount %= MaxAmount + 1
+
+			parsed, err := asset.Parse(asset.Format(amount))
+			if err != nil || parsed != amount {
+				return false
+			}
+			parsed, err = asset.Parse(asset.FormatCompact(amount))
+			return err == nil && parsed == amount
+		}
+		if err := quick.Check(roundTrip, nil); err != nil {
+			t.Errorf("%s: %v", asset.Symbol, err)
+		}
+
+		for _, amount := range []uint64{0, 1, 10, MaxAmount - 1, MaxAmount} {
+			if !roundTrip(amount) {
+				t.Errorf("%s: %d does not round trip", asset.Symbol, amount)
+			}
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		amount  uint64
+		format  string
+		compact string
+	}{
+		{0, "0.000000", "0"},
+		{1, "0.000001", "0.000001"},
+		{290000, "0.290000", "0.29"},
+		{1000000, "1.000000", "1"},
+		{12345678, "12.345678", "12.345678"},
+		{MaxAmount, "9223372036854.775807", "9223372036854.775807"},
+	}
+	for _, test := range tests {
+		if got := USDC.Format(test.amount); got != test.format {
+			t.Errorf("Format(%d) = %q, want %q", test.amount, got, test.format)
+		}
+		if got := USDC.FormatCompact(test.amount); got != test.compact {
+			t.Errorf("FormatCompact(%d) = %q, want %q", test.amount, got, test.compact)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input  string
+		amount uint64
+		err    error
+	}{
+		{"0.29", 290000, nil},
+		{" 1 ", 1000000, nil},
+		{".5", 500000, nil},
+		{"5.", 5000000, nil},
+		{"0.000001", 1, nil},
+		{"0.1000000000", 100000, nil},
+		{"9223372036854.775807", MaxAmount, nil},
+
+		// Amounts are rejected rather than rounded or truncated.
+		{"0.0000001", 0, ErrTooPrecise},
+		{"0.0000011", 0, ErrTooPrecise},
+		{"1.2345675", 0, ErrTooPrecise},
+		{"9223372036854.775808", 0, ErrAmountTooLarge},
+		{"9223372036855", 0, ErrAmountTooLarge},
+		{"18446744073709.551616", 0, ErrAmountTooLarge},
+		{"99999999999999999999999", 0, ErrAmountTooLarge},
+
+		{"-1", 0, ErrNegativeAmount},
+		{"", 0, ErrInvalidAmount},
+		{".", 0, ErrInvalidAmount},
+		{"1e6", 0, ErrInvalidAmount},
+		{"1,5", 0, ErrInvalidAmount},
+		{"+1", 0, ErrInvalidAmount},
+		{"1.2.3", 0, ErrInvalidAmount},
+	}
+	for _, test := range tests {
+		amount, err := USDC.Parse(test.input)
+		if !errors.Is(err, test.err) {
+			t.Errorf("Parse(%q) error = %v, want %v", test.input, err, test.err)
+			continue
+		}
+		if amount != test.amount {
+			t.Errorf("Parse(%q) = %d, want %d", test.input, amount, test.amount)
+		}
+	}
+}
+
+func TestParseOverflowWithoutDecimals(t *testing.T) {
+	sats := Asset{Symbol: "SATS", Decimals: 0}
+
+	if amount, err := sats.Parse("9223372036854775807"); err != nil || amount != MaxAmount {
+		t.Errorf("Parse(max) = %d, %v, want %d", amount, err, MaxAmount)
+	}
+	for _, input := range []string{"9223372036854775808", "18446744073709551615", "18446744073709551616"} {
+		if _, err := sats.Parse(input); !errors.Is(err, ErrAmountTooLarge) {
+			t.Errorf("Parse(%q) error = %v, want %v", input, err, ErrAmountTooLarge)
+		}
+	}
+	if _, err := sats.Parse("1.5"); !errors.Is(err, ErrTooPrecise) {
+		t.Errorf("Parse(%q) error = %v, want %v", "1.5", err, ErrTooPrecise)
+	}
+}
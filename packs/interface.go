@@ -0,0 +1,27 @@
+package packs
+
+import (
+	"context"
+	"errors"
+)
+
+// Custom errors for credit pack operations
+var (
+	ErrPackNotFound   = errors.New("credit pack not found")
+	ErrPriceTaken     = errors.New("route already offers credits at this price")
+	ErrInvalidCredits = errors.New("pack credits must be between 1 and 2147483647")
+	ErrInvalidPrice   = errors.New("pack price must be greater than zero")
+)
+
+// Store provides access to the credit pack storage.
+type Store interface {
+	// CreateRoutePack creates a credit pack of a route, ErrPriceTaken if the
+	// route has another pack with the same price.
+	CreateRoutePack(ctx context.Context, pack *Pack) (*Pack, error)
+
+	// ListRoutePacks retrieves the credit packs of a route, cheapest first.
+	ListRoutePacks(ctx context.Context, routeID uint64) ([]Pack, error)
+
+	// DeleteRoutePack deletes a credit pack of a route.
+	DeleteRoutePack(ctx context.Context, packID, routeID uint64) error
+}
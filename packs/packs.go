@@ -0,0 +1,18 @@
+package packs
+
+import (
+	"time"
+)
+
+// Pack is a number of credits a route sells for a price, besides the route's
+// own price and credits.
+type Pack struct {
+	ID          uint64 `json:"id"`
+	PaidRouteID uint64 `json:"-"`
+	Credits     uint64 `json:"credits"`
+	// Price is in base units (USDC * 10^6).
+	Price uint64 `json:"price"`
+	Label string `json:"label,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,54 @@
+package packs
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"strings"
+
+	"linkshrink/money"
+)
+
+// Service provides business logic for managing credit packs.
+type Service struct {
+	logger *slog.Logger
+	store  Store
+}
+
+// NewService creates a new credit pack Service.
+func NewService(logger *slog.Logger, store Store) *Service {
+	return &Service{
+		logger: logger,
+		store:  store,
+	}
+}
+
+// CreatePack validates and creates a credit pack of a route. Payments are
+// matched to a pack by their amount, so the pack may not cost the same as the
+// route itself.
+func (s *Service) CreatePack(ctx context.Context, pack *Pack, routePrice uint64) (*Pack, error) {
+	// Purchases store their credits as a 32 bit integer.
+	if pack.Credits == 0 || pack.Credits > math.MaxInt32 {
+		return nil, ErrInvalidCredits
+	}
+	if pack.Price == 0 || pack.Price > money.MaxAmount {
+		return nil, ErrInvalidPrice
+	}
+	if pack.Price == routePrice {
+		return nil, ErrPriceTaken
+	}
+	pack.Label = strings.TrimSpace(pack.Label)
+
+	return s.store.CreateRoutePack(ctx, pack)
+}
+
+// ListPacks retrieves the credit packs of a route, cheapest first.
+func (s *Service) ListPacks(ctx context.Context, routeID uint64) ([]Pack, error) {
+	return s.store.ListRoutePacks(ctx, routeID)
+}
+
+// DeletePack deletes a credit pack of a route. Purchases of the pack keep
+// their credits.
+func (s *Service) DeletePack(ctx context.Context, packID, routeID uint64) error {
+	return s.store.DeleteRoutePack(ctx, packID, routeID)
+}
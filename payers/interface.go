@@ -0,0 +1,32 @@
+package payers
+
+import (
+	"context"
+	"errors"
+)
+
+// Custom errors for payer rule operations
+var (
+	ErrRuleNotFound     = errors.New("payer rule not found")
+	ErrRuleExists       = errors.New("payer already has a rule for this route")
+	ErrInvalidAddress   = errors.New("payer address must be a 0x-prefixed 20 byte hex address")
+	ErrInvalidTreatment = errors.New("payer treatment must be free, discount or block")
+	ErrInvalidDiscount  = errors.New("discount rules need a discount of 1-10000 basis points")
+)
+
+// Store provides access to the payer rule storage.
+type Store interface {
+	// CreatePayerRule creates a payer rule, ErrRuleExists if the owner
+	// already has a rule for the payer on the same route.
+	CreatePayerRule(ctx context.Context, rule *Rule) (*Rule, error)
+
+	// ListPayerRulesByUserID retrieves the payer rules of an owner.
+	ListPayerRulesByUserID(ctx context.Context, userID uint64) ([]Rule, error)
+
+	// DeletePayerRule deletes a payer rule of an owner.
+	DeletePayerRule(ctx context.Context, ruleID, userID uint64) error
+
+	// GetPayerRuleForRoute retrieves the rule of an owner for a payer on a
+	// route, ErrRuleNotFound if there is none.
+	GetPayerRuleForRoute(ctx context.Context, userID, routeID uint64, payerAddress string) (*Rule, error)
+}
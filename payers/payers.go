@@ -0,0 +1,63 @@
+package payers
+
+import (
+	"strings"
+	"time"
+)
+
+// Treatments of payments from a payer.
+const (
+	// TreatmentFree lets the payer through once its payment verified,
+	// without settling it.
+	TreatmentFree = "free"
+	// TreatmentDiscount takes DiscountBPS off the price asked of the payer.
+	TreatmentDiscount = "discount"
+	// TreatmentBlock rejects the payer's requests.
+	TreatmentBlock = "block"
+)
+
+// TotalBPS is 100% in basis points.
+const TotalBPS = 10000
+
+// Rule is how an owner's routes treat payments from a payer address.
+type Rule struct {
+	ID     uint64 `json:"id"`
+	UserID uint64 `json:"-"`
+	// RouteID is the route the rule applies to, 0 applies it to all of the
+	// owner's routes. A route's rule takes precedence.
+	RouteID      uint64 `json:"route_id,omitempty"`
+	PayerAddress string `json:"payer_address"`
+	Treatment    string `json:"treatment"`
+	// DiscountBPS is the discount of TreatmentDiscount rules in basis points.
+	DiscountBPS uint64 `json:"discount_bps,omitempty"`
+	Label       string `json:"label,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Discount returns the amount the rule takes off price, rounded down.
+func (r *Rule) Discount(price uint64) uint64 {
+	if r.Treatment != TreatmentDiscount {
+		return 0
+	}
+	return price/TotalBPS*r.DiscountBPS + price%TotalBPS*r.DiscountBPS/TotalBPS
+}
+
+// NormalizeAddress returns address the way payer addresses are stored, EVM
+// addresses are not case sensitive.
+func NormalizeAddress(address string) string {
+	return strings.ToLower(strings.TrimSpace(address))
+}
+
+// validAddress reports whether a normalized address is a hex EVM address.
+func validAddress(address string) bool {
+	if len(address) != 42 || !strings.HasPrefix(address, "0x") {
+		return false
+	}
+	for _, r := range address[2:] {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
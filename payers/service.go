@@ -0,0 +1,69 @@
+package payers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// Service provides business logic for payer rules.
+type Service struct {
+	logger *slog.Logger
+	store  Store
+}
+
+// NewService creates a new payer rule Service.
+func NewService(logger *slog.Logger, store Store) *Service {
+	return &Service{
+		logger: logger,
+		store:  store,
+	}
+}
+
+// CreateRule validates and creates a payer rule.
+func (s *Service) CreateRule(ctx context.Context, rule *Rule) (*Rule, error) {
+	rule.PayerAddress = NormalizeAddress(rule.PayerAddress)
+	if !validAddress(rule.PayerAddress) {
+		return nil, ErrInvalidAddress
+	}
+
+	switch rule.Treatment {
+	case TreatmentFree, TreatmentBlock:
+		rule.DiscountBPS = 0
+	case TreatmentDiscount:
+		if rule.DiscountBPS == 0 || rule.DiscountBPS > TotalBPS {
+			return nil, ErrInvalidDiscount
+		}
+	default:
+		return nil, ErrInvalidTreatment
+	}
+
+	return s.store.CreatePayerRule(ctx, rule)
+}
+
+// ListRules retrieves the payer rules of an owner.
+func (s *Service) ListRules(ctx context.Context, userID uint64) ([]Rule, error) {
+	return s.store.ListPayerRulesByUserID(ctx, userID)
+}
+
+// DeleteRule deletes a payer rule of an owner.
+func (s *Service) DeleteRule(ctx context.Context, ruleID, userID uint64) error {
+	return s.store.DeletePayerRule(ctx, ruleID, userID)
+}
+
+// FindRule retrieves the rule of a route's owner for a payer, nil if the
+// payer is treated like any other.
+func (s *Service) FindRule(ctx context.Context, ownerID, routeID uint64,
+	payerAddress string) (*Rule, error) {
+
+	payerAddress = NormalizeAddress(payerAddress)
+	if payerAddress == "" {
+		return nil, nil
+	}
+
+	rule, err := s.store.GetPayerRuleForRoute(ctx, ownerID, routeID, payerAddress)
+	if errors.Is(err, ErrRuleNotFound) {
+		return nil, nil
+	}
+	return rule, err
+}
@@ -0,0 +1,18 @@
+package paywall
+
+// DefaultConfig returns all default values for the Config struct.
+func DefaultConfig() Config {
+	return Config{
+		FacilitatorURL:    "https://x402.org/facilitator",
+		Testnet:           true,
+		MaxTimeoutSeconds: 60,
+	}
+}
+
+// Config holds the configuration of a Paywall.
+type Config struct {
+	FacilitatorURL    string `long:"facilitator_url" description:"URL of the x402 facilitator verifying and settling payments"`
+	PayTo             string `long:"pay_to" description:"Address payments are made to, unless a route sets its own"`
+	Testnet           bool   `long:"testnet" description:"Take payments on Base Sepolia instead of Base"`
+	MaxTimeoutSeconds int    `long:"max_timeout_seconds" description:"Seconds a payment authorization must stay valid for"`
+}
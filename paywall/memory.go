@@ -0,0 +1,82 @@
+package paywall
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore is an in-process Store. Its purchases are lost on restart, so
+// credits left are only good as long as the process runs.
+type MemoryStore struct {
+	mu        sync.Mutex
+	purchases map[memoryKey]*Purchase
+}
+
+type memoryKey struct {
+	route         string
+	paymentHeader string
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		purchases: make(map[memoryKey]*Purchase),
+	}
+}
+
+// CreatePurchase records a verified payment before it is settled.
+func (s *MemoryStore) CreatePurchase(_ context.Context, purchase *Purchase) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := memoryKey{route: purchase.Route, paymentHeader: purchase.PaymentHeader}
+	if _, ok := s.purchases[key]; ok {
+		return ErrPaymentReused
+	}
+
+	stored := *purchase
+	s.purchases[key] = &stored
+	return nil
+}
+
+// SettlePurchase marks the purchase settled in the transaction.
+func (s *MemoryStore) SettlePurchase(_ context.Context, route, paymentHeader, transaction string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purchase, ok := s.purchases[memoryKey{route: route, paymentHeader: paymentHeader}]
+	if !ok {
+		return ErrPurchaseNotFound
+	}
+	purchase.Settled = true
+	purchase.Transaction = transaction
+	return nil
+}
+
+// DeletePurchase removes a purchase whose payment failed to settle.
+func (s *MemoryStore) DeletePurchase(_ context.Context, route, paymentHeader string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.purchases, memoryKey{route: route, paymentHeader: paymentHeader})
+	return nil
+}
+
+// UseCredit uses a credit of the settled purchase made with the payment
+// header.
+func (s *MemoryStore) UseCredit(_ context.Context, route, paymentHeader string) (*Purchase, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purchase, ok := s.purchases[memoryKey{route: route, paymentHeader: paymentHeader}]
+	if !ok || !purchase.Settled {
+		return nil, ErrPurchaseNotFound
+	}
+	if purchase.CreditsUsed >= purchase.CreditsAvailable {
+		return nil, ErrNoCreditsLeft
+	}
+	purchase.CreditsUsed++
+
+	used := *purchase
+	return &used, nil
+}
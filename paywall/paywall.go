@@ -0,0 +1,480 @@
+// Package paywall charges for HTTP handlers with x402 payments. A Paywall is
+// configured with the routes it charges for and used as net/http or gin
+// middleware, so handlers of any Go service can be paid for without running
+// the proxy. Both x402 protocol versions are supported, with a payment good
+// for a number of requests to its route, kept in a pluggable Store.
+//
+//	pw, err := paywall.New(&cfg, paywall.Routes{
+//		"GET /weather":  {Price: "0.01"},
+//		"POST /reports": {Price: "0.50", Credits: 10, Version: 1},
+//	})
+//	http.ListenAndServe(":8080", pw.Handler(mux))
+package paywall
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	x402core "github.com/coinbase/x402/go"
+	x402http "github.com/coinbase/x402/go/http"
+	x402evmserver "github.com/coinbase/x402/go/mechanisms/evm/exact/server"
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/money"
+	"linkshrink/utils"
+)
+
+// ErrPaymentRejected is wrapped by errors of an OnVerified hook that reject
+// the payment itself, which is answered with a 402 instead of a 500.
+var ErrPaymentRejected = errors.New("payment rejected")
+
+// Headers of the x402 protocol versions.
+const (
+	paymentHeaderV1         = "X-PAYMENT"
+	paymentResponseHeaderV1 = "X-PAYMENT-RESPONSE"
+	paymentHeaderV2         = "PAYMENT-SIGNATURE"
+)
+
+// Route is what a route costs. Routes are keyed by a pattern of an optional
+// method and a path, in which * matches anything and [name] a path segment,
+// such as "GET /reports/[id]" or "/api/*".
+type Route struct {
+	// Price is in USDC, such as "0.01".
+	Price string
+	// Credits is the number of requests a payment pays for, 0 means 1.
+	// Later requests present the same payment header to use them.
+	Credits uint64
+	// Version is the x402 protocol version payments are taken with, 0
+	// means 2.
+	Version int
+	// PayTo overrides the address of the config.
+	PayTo       string
+	Description string
+	MimeType    string
+}
+
+// Routes maps route patterns to what the routes cost. A request is charged
+// for the route with the longest matching pattern.
+type Routes map[string]Route
+
+// VerifiedHook is called with a verified payment before it is settled. The
+// payment is not settled if it returns an error, errors wrapping
+// ErrPaymentRejected are reported to the payer.
+type VerifiedHook func(r *http.Request, purchase *Purchase) error
+
+// SettledHook is called with a settled payment before the request is
+// handled.
+type SettledHook func(r *http.Request, purchase *Purchase)
+
+// Option is the type for options accepted by New.
+type Option func(*Paywall)
+
+// WithStore keeps purchases in the store instead of a MemoryStore.
+func WithStore(store Store) Option {
+	return func(p *Paywall) {
+		p.store = store
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(p *Paywall) {
+		p.logger = logger
+	}
+}
+
+func WithClock(clock utils.Clock) Option {
+	return func(p *Paywall) {
+		p.clock = clock
+	}
+}
+
+func WithOnVerified(hook VerifiedHook) Option {
+	return func(p *Paywall) {
+		p.onVerified = hook
+	}
+}
+
+func WithOnSettled(hook SettledHook) Option {
+	return func(p *Paywall) {
+		p.onSettled = hook
+	}
+}
+
+// Paywall is middleware charging for the routes it is configured with.
+// Requests to other routes are passed through.
+type Paywall struct {
+	cfg        *Config
+	logger     *slog.Logger
+	store      Store
+	clock      utils.Clock
+	onVerified VerifiedHook
+	onSettled  SettledHook
+
+	network     x402core.Network
+	routes      []*route
+	facilitator *x402http.HTTPFacilitatorClient
+
+	// resourceServer is shared by the v2 routes and initialized with the
+	// facilitator on the first v2 payment.
+	resourceServer *x402core.X402ResourceServer
+	initMu         sync.Mutex
+	initialized    bool
+}
+
+// route is a compiled route.
+type route struct {
+	Route
+	pattern string
+	method  string
+	path    *regexp.Regexp
+	amount  uint64
+	payTo   string
+
+	// server takes v2 payments for the route.
+	server *x402http.HTTPServer
+}
+
+// New creates a new Paywall charging for the routes.
+func New(cfg *Config, routes Routes, opts ...Option) (*Paywall, error) {
+	network := x402core.Network("eip155:8453")
+	if cfg.Testnet {
+		network = x402core.Network("eip155:84532")
+	}
+
+	facilitator := x402http.NewFacilitatorClient(&x402http.FacilitatorConfig{
+		URL: cfg.FacilitatorURL,
+	})
+
+	p := &Paywall{
+		cfg:         cfg,
+		logger:      slog.Default(),
+		store:       NewMemoryStore(),
+		clock:       utils.NewRealClock(),
+		network:     network,
+		facilitator: facilitator,
+		resourceServer: x402core.Newx402ResourceServer(
+			x402core.WithFacilitatorClient(facilitator),
+			x402core.WithSchemeServer(network, x402evmserver.NewExactEvmScheme()),
+		),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	for pattern, config := range routes {
+		compiled, err := p.compileRoute(pattern, config)
+		if err != nil {
+			return nil, fmt.Errorf("route %q: %w", pattern, err)
+		}
+		p.routes = append(p.routes, compiled)
+	}
+	sort.Slice(p.routes, func(i, j int) bool {
+		if len(p.routes[i].pattern) != len(p.routes[j].pattern) {
+			return len(p.routes[i].pattern) > len(p.routes[j].pattern)
+		}
+		return p.routes[i].pattern < p.routes[j].pattern
+	})
+
+	return p, nil
+}
+
+// segmentPattern matches the [name] placeholders of a quoted route path.
+var segmentPattern = regexp.MustCompile(`\\\[[^\]]+\\\]`)
+
+// compileRoute checks a route and compiles its pattern.
+func (p *Paywall) compileRoute(pattern string, config Route) (*route, error) {
+	method, path := "", pattern
+	if fields := strings.Fields(pattern); len(fields) == 2 {
+		method, path = strings.ToUpper(fields[0]), fields[1]
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, errors.New("path must start with /")
+	}
+
+	expression := regexp.QuoteMeta(path)
+	expression = strings.ReplaceAll(expression, `\*`, `.*`)
+	expression = segmentPattern.ReplaceAllString(expression, `[^/]+`)
+	compiledPath, err := regexp.Compile("^" + expression + "$")
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := money.USDC.Parse(config.Price)
+	if err != nil {
+		return nil, fmt.Errorf("invalid price: %w", err)
+	}
+	if amount == 0 {
+		return nil, errors.New("price must be positive")
+	}
+
+	if config.Credits == 0 {
+		config.Credits = 1
+	}
+	if config.Version == 0 {
+		config.Version = 2
+	}
+	if config.Version != 1 && config.Version != 2 {
+		return nil, fmt.Errorf("unsupported x402 version %d", config.Version)
+	}
+
+	payTo := config.PayTo
+	if payTo == "" {
+		payTo = p.cfg.PayTo
+	}
+	if payTo == "" {
+		return nil, errors.New("no address to pay to")
+	}
+
+	compiled := &route{
+		Route:   config,
+		pattern: pattern,
+		method:  method,
+		path:    compiledPath,
+		amount:  amount,
+		payTo:   payTo,
+	}
+	if config.Version == 2 {
+		compiled.server = x402http.Wrappedx402HTTPResourceServer(x402http.RoutesConfig{
+			"*": {
+				Accepts: []x402http.PaymentOption{{
+					Scheme:            "exact",
+					PayTo:             payTo,
+					Price:             money.USDC.Format(amount),
+					Network:           p.network,
+					MaxTimeoutSeconds: p.cfg.MaxTimeoutSeconds,
+				}},
+				Description: config.Description,
+				MimeType:    config.MimeType,
+			},
+		}, p.resourceServer)
+	}
+
+	return compiled, nil
+}
+
+// match returns the route the request is charged for, nil if it is free.
+func (p *Paywall) match(r *http.Request) *route {
+	for _, route := range p.routes {
+		if (route.method == "" || route.method == r.Method) && route.path.MatchString(r.URL.Path) {
+			return route
+		}
+	}
+	return nil
+}
+
+// Handler returns net/http middleware charging for requests to next.
+func (p *Paywall) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paid, ok := p.process(w, r)
+		if !ok {
+			return
+		}
+		next.ServeHTTP(w, paid)
+	})
+}
+
+// Gin returns gin middleware charging for requests.
+func (p *Paywall) Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		paid, ok := p.process(c.Writer, c.Request)
+		if !ok {
+			c.Abort()
+			return
+		}
+		c.Request = paid
+		c.Next()
+	}
+}
+
+// purchaseKey is the context key of the purchase a request was paid with.
+type purchaseKey struct{}
+
+// PurchaseFromContext returns the purchase the request of the context was
+// paid with, nil if it was not charged for.
+func PurchaseFromContext(ctx context.Context) *Purchase {
+	purchase, _ := ctx.Value(purchaseKey{}).(*Purchase)
+	return purchase
+}
+
+// process charges for the request. Returns the request with the purchase it
+// was paid with in its context and true if it is to be handled, false if a
+// response was sent instead.
+func (p *Paywall) process(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	route := p.match(r)
+	if route == nil {
+		return r, true
+	}
+
+	headerName := paymentHeaderV2
+	if route.Version == 1 {
+		headerName = paymentHeaderV1
+	}
+
+	if header := r.Header.Get(headerName); header != "" {
+		purchase, err := p.store.UseCredit(r.Context(), route.pattern, header)
+		switch {
+		case err == nil:
+			return withPurchase(r, purchase), true
+		case errors.Is(err, ErrNoCreditsLeft):
+			// A payment whose credits are used up is answered like a
+			// request without payment.
+			r = r.Clone(r.Context())
+			r.Header.Del(headerName)
+		case !errors.Is(err, ErrPurchaseNotFound):
+			p.logger.Error("Failed to use purchase credit", "route", route.pattern, "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Failed to look up payment"})
+			return nil, false
+		}
+	}
+
+	var payment *verifiedPayment
+	var ok bool
+	if route.Version == 1 {
+		payment, ok = p.verifyV1(w, r, route)
+	} else {
+		payment, ok = p.verifyV2(w, r, route)
+	}
+	if !ok {
+		return nil, false
+	}
+
+	purchase, ok := p.settle(w, r, route, payment)
+	if !ok {
+		return nil, false
+	}
+	return withPurchase(r, purchase), true
+}
+
+// verifiedPayment is a payment verified against a route's requirements.
+type verifiedPayment struct {
+	header       string
+	version      int
+	network      string
+	payer        string
+	amount       uint64
+	payload      []byte
+	requirements []byte
+
+	// settle settles the payment, returning the transaction, the payer and
+	// the headers reporting the settlement.
+	settle func(ctx context.Context) (*settlement, error)
+}
+
+// settlement is a settled payment.
+type settlement struct {
+	transaction string
+	payer       string
+	headers     map[string]string
+}
+
+// settle records a verified payment as a pending purchase and settles it.
+// The purchase is recorded before the payment is settled, so a settled
+// payment always has a record.
+func (p *Paywall) settle(w http.ResponseWriter, r *http.Request, route *route,
+	payment *verifiedPayment) (*Purchase, bool) {
+
+	ctx := r.Context()
+	purchase := &Purchase{
+		Route:               route.pattern,
+		PaymentHeader:       payment.header,
+		Version:             payment.version,
+		Network:             payment.network,
+		Payer:               payment.payer,
+		PayTo:               route.payTo,
+		Amount:              payment.amount,
+		CreditsAvailable:    route.Credits,
+		CreditsUsed:         1,
+		PaymentPayload:      payment.payload,
+		PaymentRequirements: payment.requirements,
+		CreatedAt:           p.clock.Now(),
+	}
+
+	if p.onVerified != nil {
+		if err := p.onVerified(r, purchase); err != nil {
+			if errors.Is(err, ErrPaymentRejected) {
+				writeJSON(w, http.StatusPaymentRequired, map[string]any{"error": err.Error(), "x402Version": payment.version})
+				return nil, false
+			}
+			p.logger.Error("Verified payment hook failed", "route", route.pattern, "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Internal server error before payment settlement."})
+			return nil, false
+		}
+	}
+
+	err := p.store.CreatePurchase(ctx, purchase)
+	if errors.Is(err, ErrPaymentReused) {
+		writeJSON(w, http.StatusPaymentRequired, map[string]any{"error": err.Error(), "x402Version": payment.version})
+		return nil, false
+	}
+	if err != nil {
+		p.logger.Error("Failed to save pending purchase", "route", route.pattern, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Internal server error before payment settlement."})
+		return nil, false
+	}
+
+	settled, err := payment.settle(ctx)
+	if err != nil {
+		p.logger.Info("Payment settlement failed", "route", route.pattern, "error", err)
+		if err := p.store.DeletePurchase(ctx, route.pattern, payment.header); err != nil {
+			p.logger.Error("Failed to delete unsettled purchase", "route", route.pattern, "error", err)
+		}
+		writeJSON(w, http.StatusPaymentRequired, map[string]any{"error": err.Error(), "x402Version": payment.version})
+		return nil, false
+	}
+
+	purchase.Settled = true
+	purchase.Transaction = settled.transaction
+	if settled.payer != "" {
+		purchase.Payer = settled.payer
+	}
+	for name, value := range settled.headers {
+		w.Header().Set(name, value)
+	}
+
+	// The payment is settled, so the request is handled even if marking
+	// the purchase fails. Its other credits are lost then.
+	err = p.store.SettlePurchase(ctx, route.pattern, payment.header, settled.transaction)
+	if err != nil {
+		p.logger.Error("Failed to mark purchase settled", "route", route.pattern,
+			"transaction", settled.transaction, "error", err)
+	}
+
+	p.logger.Info("Settled payment", "route", route.pattern, "amount", purchase.Amount,
+		"payer", purchase.Payer, "transaction", purchase.Transaction)
+
+	if p.onSettled != nil {
+		p.onSettled(r, purchase)
+	}
+
+	return purchase, true
+}
+
+// withPurchase returns the request with the purchase in its context.
+func withPurchase(r *http.Request, purchase *Purchase) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), purchaseKey{}, purchase))
+}
+
+// requestURL returns the URL the request was made to.
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "https" {
+		scheme = "https"
+	} else if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, r.Host, r.URL.RequestURI())
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,60 @@
+package paywall
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Custom errors for purchases
+var (
+	ErrPurchaseNotFound = errors.New("purchase not found")
+	ErrNoCreditsLeft    = errors.New("payment has no credits left")
+	ErrPaymentReused    = errors.New("payment was already used")
+)
+
+// Purchase is a payment for a route, good for its credits. The payment
+// header it was made with is presented again to use them.
+type Purchase struct {
+	// Route is the pattern of the route the payment was made for.
+	Route         string
+	PaymentHeader string
+	// Version is the x402 protocol version the payment was made with.
+	Version int
+	Network string
+	Payer   string
+	PayTo   string
+	// Amount is in USDC base units (USDC * 10^6).
+	Amount uint64
+
+	CreditsAvailable uint64
+	CreditsUsed      uint64
+
+	Settled     bool
+	Transaction string
+
+	PaymentPayload      []byte
+	PaymentRequirements []byte
+
+	CreatedAt time.Time
+}
+
+// Store keeps the purchases of a Paywall. Purchases are keyed by their route
+// and payment header.
+type Store interface {
+	// CreatePurchase records a verified payment before it is settled, with
+	// its first credit used. Returns ErrPaymentReused if a purchase was
+	// recorded with the payment header for the route before.
+	CreatePurchase(ctx context.Context, purchase *Purchase) error
+
+	// SettlePurchase marks the purchase settled in the transaction.
+	SettlePurchase(ctx context.Context, route, paymentHeader, transaction string) error
+
+	// DeletePurchase removes a purchase whose payment failed to settle.
+	DeletePurchase(ctx context.Context, route, paymentHeader string) error
+
+	// UseCredit uses a credit of the settled purchase made with the payment
+	// header. Returns ErrPurchaseNotFound if there is none and
+	// ErrNoCreditsLeft if its credits are used up.
+	UseCredit(ctx context.Context, route, paymentHeader string) (*Purchase, error)
+}
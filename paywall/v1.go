@@ -0,0 +1,125 @@
+package paywall
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	x402types "github.com/coinbase/x402/go/types"
+)
+
+// USDC contracts payments are made with, by x402 v1 network name.
+const (
+	usdcBase        = "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"
+	usdcBaseSepolia = "0x036CbD53842c5426634e7929541eC2318f3dCF7e"
+)
+
+// requirementsV1 returns the x402 v1 payment requirements of the route.
+func (p *Paywall) requirementsV1(r *http.Request, route *route) x402types.PaymentRequirementsV1 {
+	network, asset := "base", usdcBase
+	// The EIP-712 domain of the USDC contract, which signatures are made
+	// for.
+	extra := json.RawMessage(`{"name":"USD Coin","version":"2"}`)
+	if p.cfg.Testnet {
+		network, asset = "base-sepolia", usdcBaseSepolia
+		extra = json.RawMessage(`{"name":"USDC","version":"2"}`)
+	}
+
+	return x402types.PaymentRequirementsV1{
+		Scheme:            "exact",
+		Network:           network,
+		MaxAmountRequired: strconv.FormatUint(route.amount, 10),
+		Resource:          requestURL(r),
+		Description:       route.Description,
+		MimeType:          route.MimeType,
+		PayTo:             route.payTo,
+		MaxTimeoutSeconds: p.cfg.MaxTimeoutSeconds,
+		Asset:             asset,
+		Extra:             &extra,
+	}
+}
+
+// verifyV1 verifies the x402 v1 payment of the request with the facilitator.
+// Requests without a valid payment are answered with 402.
+func (p *Paywall) verifyV1(w http.ResponseWriter, r *http.Request, route *route) (*verifiedPayment, bool) {
+	requirements := p.requirementsV1(r, route)
+	paymentRequired := func(errMsg string) (*verifiedPayment, bool) {
+		writeJSON(w, http.StatusPaymentRequired, map[string]any{
+			"error":       errMsg,
+			"accepts":     []x402types.PaymentRequirementsV1{requirements},
+			"x402Version": 1,
+		})
+		return nil, false
+	}
+
+	header := r.Header.Get(paymentHeaderV1)
+	if header == "" {
+		return paymentRequired("X-PAYMENT header is required")
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(header)
+	if err != nil {
+		return paymentRequired("invalid X-PAYMENT header")
+	}
+	if version, err := x402types.DetectVersion(payload); err != nil || version != 1 {
+		return paymentRequired("invalid x402 version for v1 route")
+	}
+	if _, err := x402types.ToPaymentPayloadV1(payload); err != nil {
+		return paymentRequired("failed to decode payment payload")
+	}
+
+	requirementsJSON, err := json.Marshal(requirements)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error(), "x402Version": 1})
+		return nil, false
+	}
+
+	verifyResponse, err := p.facilitator.Verify(r.Context(), payload, requirementsJSON)
+	if err != nil || verifyResponse == nil || !verifyResponse.IsValid {
+		errMsg := "invalid payment"
+		if err != nil {
+			errMsg = err.Error()
+		} else if verifyResponse != nil && verifyResponse.InvalidReason != "" {
+			errMsg = verifyResponse.InvalidReason
+		}
+		return paymentRequired(errMsg)
+	}
+
+	return &verifiedPayment{
+		header:       header,
+		version:      1,
+		network:      requirements.Network,
+		payer:        verifyResponse.Payer,
+		amount:       route.amount,
+		payload:      payload,
+		requirements: requirementsJSON,
+		settle: func(ctx context.Context) (*settlement, error) {
+			settleResponse, err := p.facilitator.Settle(ctx, payload, requirementsJSON)
+			if err != nil {
+				return nil, err
+			}
+			if settleResponse == nil || !settleResponse.Success {
+				errMsg := "payment settlement failed"
+				if settleResponse != nil && settleResponse.ErrorReason != "" {
+					errMsg = settleResponse.ErrorReason
+				}
+				return nil, errors.New(errMsg)
+			}
+
+			settleResponseJSON, err := json.Marshal(settleResponse)
+			if err != nil {
+				return nil, err
+			}
+			return &settlement{
+				transaction: settleResponse.Transaction,
+				payer:       settleResponse.Payer,
+				headers: map[string]string{
+					paymentResponseHeaderV1: base64.StdEncoding.EncodeToString(settleResponseJSON),
+				},
+			}, nil
+		},
+	}, true
+}
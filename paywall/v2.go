@@ -0,0 +1,135 @@
+package paywall
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	x402http "github.com/coinbase/x402/go/http"
+)
+
+// requestAdapter exposes a request to the x402 v2 server.
+type requestAdapter struct {
+	r *http.Request
+}
+
+func (a requestAdapter) GetHeader(name string) string { return a.r.Header.Get(name) }
+func (a requestAdapter) GetMethod() string            { return a.r.Method }
+func (a requestAdapter) GetPath() string              { return a.r.URL.Path }
+func (a requestAdapter) GetURL() string               { return requestURL(a.r) }
+func (a requestAdapter) GetAcceptHeader() string      { return a.r.Header.Get("Accept") }
+func (a requestAdapter) GetUserAgent() string         { return a.r.UserAgent() }
+
+// initialize fetches what the facilitator supports for the v2 server, once
+// it succeeded.
+func (p *Paywall) initialize(ctx context.Context) error {
+	p.initMu.Lock()
+	defer p.initMu.Unlock()
+
+	if p.initialized {
+		return nil
+	}
+	if err := p.resourceServer.Initialize(ctx); err != nil {
+		return err
+	}
+	p.initialized = true
+	return nil
+}
+
+// verifyV2 verifies the x402 v2 payment of the request with the facilitator.
+// Requests without a valid payment are answered with 402, and a paywall page
+// for browsers.
+func (p *Paywall) verifyV2(w http.ResponseWriter, r *http.Request, route *route) (*verifiedPayment, bool) {
+	ctx := r.Context()
+	if err := p.initialize(ctx); err != nil {
+		p.logger.Error("Failed to initialize x402 v2 server", "route", route.pattern, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Unable to initialize payment server"})
+		return nil, false
+	}
+
+	result := route.server.ProcessHTTPRequest(ctx, x402http.HTTPRequestContext{
+		Adapter: requestAdapter{r: r},
+		Path:    r.URL.Path,
+		Method:  r.Method,
+	}, nil)
+
+	switch result.Type {
+	case x402http.ResultPaymentError:
+		if result.Response == nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Unexpected payment response"})
+			return nil, false
+		}
+		writeResponse(w, result.Response)
+		return nil, false
+	case x402http.ResultPaymentVerified:
+		// Continue below.
+	default:
+		p.logger.Error("Unexpected x402 v2 payment result", "route", route.pattern, "resultType", result.Type)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Internal payment processing error"})
+		return nil, false
+	}
+
+	if result.PaymentPayload == nil || result.PaymentRequirements == nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Internal payment verification error"})
+		return nil, false
+	}
+	payload, requirements := *result.PaymentPayload, *result.PaymentRequirements
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Internal server error after payment verification."})
+		return nil, false
+	}
+	requirementsJSON, err := json.Marshal(requirements)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Internal server error after payment verification."})
+		return nil, false
+	}
+
+	amount, err := strconv.ParseUint(requirements.Amount, 10, 64)
+	if err != nil {
+		amount = route.amount
+	}
+
+	return &verifiedPayment{
+		header:       r.Header.Get(paymentHeaderV2),
+		version:      2,
+		network:      string(requirements.Network),
+		amount:       amount,
+		payload:      payloadJSON,
+		requirements: requirementsJSON,
+		settle: func(ctx context.Context) (*settlement, error) {
+			settled := route.server.ProcessSettlement(ctx, payload, requirements)
+			if !settled.Success {
+				return nil, errors.New(settled.ErrorReason)
+			}
+			return &settlement{
+				transaction: settled.Transaction,
+				payer:       settled.Payer,
+				headers:     settled.Headers,
+			}, nil
+		},
+	}, true
+}
+
+// writeResponse writes the response the x402 v2 server asked for.
+func writeResponse(w http.ResponseWriter, response *x402http.HTTPResponseInstructions) {
+	for name, value := range response.Headers {
+		w.Header().Set(name, value)
+	}
+
+	if response.IsHTML {
+		body, _ := response.Body.(string)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(response.Status)
+		_, _ = w.Write([]byte(body))
+		return
+	}
+	if response.Body == nil {
+		w.WriteHeader(response.Status)
+		return
+	}
+	writeJSON(w, response.Status, response.Body)
+}
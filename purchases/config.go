@@ -0,0 +1,17 @@
+package purchases
+
+// DefaultConfig returns all default values for the Config struct.
+func DefaultConfig() Config {
+	return Config{
+		RecoveryIntervalSeconds: 300,
+		PendingTimeoutSeconds:   600,
+		RecoveryBatchSize:       50,
+	}
+}
+
+// Config holds the configuration for the recovery of pending purchases.
+type Config struct {
+	RecoveryIntervalSeconds int `long:"recovery_interval_seconds" description:"Seconds between recoveries of stuck pending purchases, 0 only recovers on startup"`
+	PendingTimeoutSeconds   int `long:"pending_timeout_seconds" description:"Seconds after which a pending purchase is considered stuck"`
+	RecoveryBatchSize       int `long:"recovery_batch_size" description:"Maximum number of pending purchases recovered per run"`
+}
@@ -3,6 +3,9 @@ package purchases
 import (
 	"context"
 	"errors"
+	"time"
+
+	"linkshrink/ledger"
 )
 
 // Custom errors for purchase operations
@@ -10,11 +13,20 @@ var (
 	ErrPurchaseNotFound = errors.New("purchase not found")
 	ErrNoStats          = errors.New("no purchase statistics available")
 	ErrPaymentHeader    = errors.New("payment header cannot be empty")
+	ErrNoCreditsLeft    = errors.New("purchase has no credits left")
+	ErrNotPending       = errors.New("purchase is not pending")
+	ErrNoPaymentNonce   = errors.New("payment payload has no authorization nonce")
+	ErrPaymentReused    = errors.New("payment authorization was already used")
+	ErrSoldOut          = errors.New("route sold out before the payment was made")
 )
 
 // Store provides access to the purchase storage.
 type Store interface {
-	// Create inserts a new purchase in the database and returns the ID.
+	// Create inserts a new purchase in the database and returns the ID. The
+	// purchase's payment nonce is registered with it, returns
+	// ErrPaymentReused if it was already used for another purchase. The
+	// purchase counts as a sale of its route, returns ErrSoldOut if the
+	// route's sales limit is reached.
 	CreatePurchase(ctx context.Context, purchase *Purchase) (uint64, error)
 
 	// ListPurchasesByUserID retrieves all purchases for a specific user.
@@ -31,6 +43,42 @@ type Store interface {
 	// GetPurchaseByRouteIDAndPaymentHeader retrieves a purchase if it exists for the given route and payment header.
 	GetPurchaseByRouteIDAndPaymentHeader(ctx context.Context, routeID uint64, paymentHeader string) (*Purchase, error)
 
-	// IncrementPurchaseCreditsUsed increments the credits used for a purchase.
-	IncrementPurchaseCreditsUsed(ctx context.Context, purchaseID uint64) error
+	// ChargePurchaseUsage records usage units on the purchase of usage and
+	// charges amount for them, capped at the price paid for the purchase.
+	// The charge is posted to the ledger with it. Returns the updated
+	// purchase and the amount actually charged.
+	ChargePurchaseUsage(ctx context.Context, usage ledger.Usage,
+		usageUnits uint64, amount uint64) (*Purchase, uint64, error)
+
+	// SettlePurchase atomically marks a pending purchase as settled with the
+	// settle response and records its accounting. Returns ErrNotPending if
+	// the purchase is not pending.
+	SettlePurchase(ctx context.Context, purchaseID uint64, settleResponse []byte,
+		accounting *Accounting) error
+
+	// FailPurchase marks a pending purchase as failed to settle for the given
+	// reason and releases its payment nonce and coupon redemption. Returns
+	// ErrNotPending if the purchase is not pending.
+	FailPurchase(ctx context.Context, purchaseID uint64, reason string) error
+
+	// FlagPendingPurchase records why a pending purchase needs to be resolved
+	// manually, which excludes it from automatic recovery.
+	FlagPendingPurchase(ctx context.Context, purchaseID uint64, reason string) error
+
+	// GetPurchaseByID retrieves a purchase by its ID.
+	GetPurchaseByID(ctx context.Context, purchaseID uint64) (*Purchase, error)
+
+	// ListPendingPurchasesByUserID retrieves the pending purchases of all
+	// routes of a user.
+	ListPendingPurchasesByUserID(ctx context.Context, userID uint64) ([]Purchase, error)
+
+	// ListStalePendingPurchases retrieves up to limit pending purchases
+	// created before the given time that are not flagged for review.
+	ListStalePendingPurchases(ctx context.Context, before time.Time, limit uint64) ([]Purchase, error)
+
+	// IncrementPurchaseCreditsUsed increments the credits used for the
+	// purchase of use, posts the used credit to the ledger with it and
+	// returns the number of credits used.
+	// Returns ErrNoCreditsLeft if all credits are already used.
+	IncrementPurchaseCreditsUsed(ctx context.Context, use ledger.CreditUse) (uint64, error)
 }
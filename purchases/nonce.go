@@ -0,0 +1,64 @@
+package purchases
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// v1Networks maps the network names of x402 v1 payments to the CAIP-2
+// identifiers used by v2, so the same authorization is recognized under both
+// protocol versions.
+var v1Networks = map[string]string{
+	"base":         "eip155:8453",
+	"base-sepolia": "eip155:84532",
+}
+
+// PaymentNonce identifies the EIP-3009 authorization a payment transfers its
+// funds with. An authorization can only settle once, so it can only be used
+// for a single purchase.
+type PaymentNonce struct {
+	Network string
+	Payer   string
+	Nonce   string
+}
+
+// ParsePaymentNonce extracts the authorization of an x402 v1 or v2 exact EVM
+// payment payload.
+func ParsePaymentNonce(paymentPayloadJSON []byte) (*PaymentNonce, error) {
+	var payment struct {
+		Network  string `json:"network"`
+		Accepted struct {
+			Network string `json:"network"`
+		} `json:"accepted"`
+		Payload struct {
+			Authorization struct {
+				From  string `json:"from"`
+				Nonce string `json:"nonce"`
+			} `json:"authorization"`
+		} `json:"payload"`
+	}
+	err := json.Unmarshal(paymentPayloadJSON, &payment)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payment payload: %w", err)
+	}
+
+	network := payment.Accepted.Network
+	if network == "" {
+		network = payment.Network
+	}
+	if caip2, ok := v1Networks[network]; ok {
+		network = caip2
+	}
+
+	authorization := payment.Payload.Authorization
+	if network == "" || authorization.From == "" || authorization.Nonce == "" {
+		return nil, ErrNoPaymentNonce
+	}
+
+	return &PaymentNonce{
+		Network: network,
+		Payer:   strings.ToLower(authorization.From),
+		Nonce:   strings.ToLower(authorization.Nonce),
+	}, nil
+}
@@ -0,0 +1,89 @@
+package purchases
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParsePaymentNonce(t *testing.T) {
+	want := &PaymentNonce{
+		Network: "eip155:84532",
+		Payer:   "0x857b06519e91e3a54538791bdbb0e22373e36b66",
+		Nonce:   "0xf3746613c2d920b5fdabc0856f2aeb2d4f88ee6037b8cc5d04a71a4462f13480",
+	}
+
+	// The same authorization, as paid for different routes under both
+	// protocol versions.
+	payloads := map[string]string{
+		"v1": `{"x402Version":1,"scheme":"exact","network":"base-sepolia","payload":{
+			"signature":"0x2d6a","authorization":{
+			"from":"0x857b06519E91e3A54538791bDbb0E22373e36b66",
+			"to":"0x209693Bc6afc0C5328bA36FaF03C514EF312287C","value":"10000",
+			"nonce":"0xf3746613c2d920b5fdabc0856f2aeb2d4f88ee6037b8cc5d04a71a4462f13480"}}}`,
+		"v1 CAIP-2 network": `{"x402Version":1,"scheme":"exact","network":"eip155:84532","payload":{
+			"authorization":{"from":"0x857b06519e91e3a54538791bdbb0e22373e36b66",
+			"nonce":"0xF3746613C2D920B5FDABC0856F2AEB2D4F88EE6037B8CC5D04A71A4462F13480"}}}`,
+		"v2": `{"x402Version":2,"accepted":{"scheme":"exact","network":"eip155:84532",
+			"amount":"20000","payTo":"0x1111111111111111111111111111111111111111"},"payload":{
+			"signature":"0x2d6a","authorization":{
+			"from":"0x857B06519E91E3A54538791BDBB0E22373E36B66",
+			"nonce":"0xf3746613c2d920b5fdabc0856f2aeb2d4f88ee6037b8cc5d04a71a4462f13480"}}}`,
+		"v2 with v1 network name": `{"x402Version":2,"accepted":{"network":"base-sepolia"},"payload":{
+			"authorization":{"from":"0x857b06519e91e3a54538791bdbb0e22373e36b66",
+			"nonce":"0xf3746613c2d920b5fdabc0856f2aeb2d4f88ee6037b8cc5d04a71a4462f13480"}}}`,
+	}
+
+	for name, payload := range payloads {
+		nonce, err := ParsePaymentNonce([]byte(payload))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if *nonce != *want {
+			t.Errorf("%s: got %+v, want %+v", name, nonce, want)
+		}
+	}
+}
+
+func TestParsePaymentNonceDistinguishesAuthorizations(t *testing.T) {
+	base := `{"x402Version":1,"network":"base","payload":{"authorization":{"from":"0xaa","nonce":"0x01"}}}`
+	others := []string{
+		`{"x402Version":1,"network":"base-sepolia","payload":{"authorization":{"from":"0xaa","nonce":"0x01"}}}`,
+		`{"x402Version":1,"network":"base","payload":{"authorization":{"from":"0xbb","nonce":"0x01"}}}`,
+		`{"x402Version":1,"network":"base","payload":{"authorization":{"from":"0xaa","nonce":"0x02"}}}`,
+	}
+
+	nonce, err := ParsePaymentNonce([]byte(base))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, other := range others {
+		otherNonce, err := ParsePaymentNonce([]byte(other))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *otherNonce == *nonce {
+			t.Errorf("%s has the same authorization as %s", other, base)
+		}
+	}
+}
+
+func TestParsePaymentNonceErrors(t *testing.T) {
+	tests := map[string]string{
+		"no network":       `{"payload":{"authorization":{"from":"0xaa","nonce":"0x01"}}}`,
+		"no payer":         `{"network":"base","payload":{"authorization":{"nonce":"0x01"}}}`,
+		"no nonce":         `{"network":"base","payload":{"authorization":{"from":"0xaa"}}}`,
+		"no authorization": `{"network":"base","payload":{"transaction":"0x01"}}`,
+	}
+	for name, payload := range tests {
+		if _, err := ParsePaymentNonce([]byte(payload)); !errors.Is(err, ErrNoPaymentNonce) {
+			t.Errorf("%s: got %v, want %v", name, err, ErrNoPaymentNonce)
+		}
+	}
+
+	if _, err := ParsePaymentNonce([]byte("not json")); err == nil ||
+		errors.Is(err, ErrNoPaymentNonce) {
+
+		t.Errorf("invalid JSON: got %v", err)
+	}
+}
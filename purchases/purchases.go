@@ -2,6 +2,20 @@ package purchases
 
 import (
 	"time"
+
+	"linkshrink/ledger"
+	"linkshrink/splits"
+)
+
+const (
+	// StatusPending is the status of a purchase whose payment is verified
+	// but not settled yet.
+	StatusPending = "pending"
+	// StatusSettled is the status of a purchase whose payment settled.
+	StatusSettled = "settled"
+	// StatusFailed is the status of a purchase whose payment failed to
+	// settle.
+	StatusFailed = "failed"
 )
 
 type Purchase struct {
@@ -15,6 +29,27 @@ type Purchase struct {
 	CreditsUsed      uint64 `json:"credits_used,omitempty"`
 	IsTest           bool   `json:"is_test"`
 
+	// PackID is the credit pack of the route the purchase bought, zero if it
+	// bought the route's default credits.
+	PackID uint64 `json:"pack_id,omitempty"`
+
+	// AffiliateID is the affiliate the purchase was referred by, zero if it
+	// was not referred. AffiliateCommission is what the affiliate is owed
+	// for it, set once the payment settled.
+	AffiliateID         uint64 `json:"affiliate_id,omitempty"`
+	AffiliateCommission uint64 `json:"affiliate_commission,omitempty"`
+
+	// Status tracks the settlement of the purchase's payment. SettleError
+	// holds why a payment failed to settle, or why a pending purchase needs
+	// to be resolved manually.
+	Status      string `json:"status"`
+	SettleError string `json:"settle_error,omitempty"`
+
+	// UsageUnits and AmountCharged track upstream reported usage for
+	// purchases of metered routes, where Price is the prepaid balance.
+	UsageUnits    uint64 `json:"usage_units,omitempty"`
+	AmountCharged uint64 `json:"amount_charged,omitempty"`
+
 	PaidRouteID   uint64 `json:"-"`
 	PaidToAddress string `json:"-"`
 
@@ -22,10 +57,35 @@ type Purchase struct {
 	PaymentPayload []byte `json:"-"`
 	SettleResponse []byte `json:"-"`
 
+	// PaymentRequirements are the requirements the payment was verified
+	// against, kept to verify it again while the purchase is pending.
+	PaymentRequirements []byte `json:"-"`
+
+	// PaymentNonce is the authorization of the payment, registered when the
+	// purchase is created. It is not loaded with the purchase.
+	PaymentNonce *PaymentNonce `json:"-"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Accounting is how the payment of a settled purchase is accounted for,
+// recorded together with the purchase being settled.
+type Accounting struct {
+	// Payouts is what each payee is owed for the purchase.
+	Payouts []splits.Payout
+	// LedgerTransactions post the sale to the ledger.
+	LedgerTransactions []ledger.Transaction
+}
+
+// Balance returns what is left of the prepaid amount of a metered purchase.
+func (p *Purchase) Balance() uint64 {
+	if p.AmountCharged >= p.Price {
+		return 0
+	}
+	return p.Price - p.AmountCharged
+}
+
 // DailyStats represents purchase statistics for a single day
 type DailyStats struct {
 	Date         string `json:"date"`
@@ -0,0 +1,130 @@
+package purchases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	x402core "github.com/coinbase/x402/go"
+
+	"linkshrink/utils"
+)
+
+// Verifier verifies a payment payload against its requirements, as the x402
+// facilitator client does.
+type Verifier interface {
+	Verify(ctx context.Context, payloadBytes []byte,
+		requirementsBytes []byte) (*x402core.VerifyResponse, error)
+}
+
+// Recovery resolves purchases left pending because the process stopped, or
+// failed to record the outcome, while their payment was being settled.
+//
+// A pending purchase whose payment still verifies was never settled, so it is
+// marked as failed and the buyer can pay again. A payment that no longer
+// verifies was either settled or expired, which the facilitator can't tell
+// apart, so the purchase is flagged for its route owner to resolve.
+type Recovery struct {
+	logger   *slog.Logger
+	store    Store
+	cfg      *Config
+	verifier Verifier
+	clock    utils.Clock
+}
+
+// NewRecovery creates a new recovery of pending purchases.
+func NewRecovery(logger *slog.Logger, store Store, cfg *Config,
+	verifier Verifier, clock utils.Clock) *Recovery {
+
+	return &Recovery{
+		logger:   logger,
+		store:    store,
+		cfg:      cfg,
+		verifier: verifier,
+		clock:    clock,
+	}
+}
+
+// Run recovers stuck pending purchases on startup and then every interval
+// until the context is cancelled.
+func (r *Recovery) Run(ctx context.Context) {
+	err := r.RecoverOnce(ctx)
+	if err != nil {
+		r.logger.Error("Failed to recover pending purchases", "error", err)
+	}
+
+	if r.cfg.RecoveryIntervalSeconds <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(r.cfg.RecoveryIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := r.RecoverOnce(ctx)
+			if err != nil {
+				r.logger.Error("Failed to recover pending purchases", "error", err)
+			}
+		}
+	}
+}
+
+// RecoverOnce resolves a batch of pending purchases older than the pending
+// timeout.
+func (r *Recovery) RecoverOnce(ctx context.Context) error {
+	timeout := time.Duration(r.cfg.PendingTimeoutSeconds) * time.Second
+	stale, err := r.store.ListStalePendingPurchases(ctx, r.clock.Now().Add(-timeout),
+		uint64(max(r.cfg.RecoveryBatchSize, 1)))
+	if err != nil {
+		return fmt.Errorf("failed to list stale pending purchases: %w", err)
+	}
+
+	for _, purchase := range stale {
+		err := r.recover(ctx, &purchase)
+		if err != nil && !errors.Is(err, ErrNotPending) {
+			r.logger.Error("Failed to recover pending purchase",
+				"purchaseID", purchase.ID, "shortCode", purchase.ShortCode, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// recover resolves a single stuck pending purchase.
+func (r *Recovery) recover(ctx context.Context, purchase *Purchase) error {
+	if len(purchase.PaymentRequirements) == 0 {
+		return r.store.FlagPendingPurchase(ctx, purchase.ID,
+			"no payment requirements recorded to verify the payment")
+	}
+
+	resp, err := r.verifier.Verify(ctx, purchase.PaymentPayload, purchase.PaymentRequirements)
+
+	var verifyErr *x402core.VerifyError
+	switch {
+	case errors.As(err, &verifyErr):
+		resp = &x402core.VerifyResponse{InvalidReason: verifyErr.Error()}
+
+	case err != nil:
+		// The facilitator is unavailable, try again on the next run.
+		return fmt.Errorf("failed to verify payment: %w", err)
+	}
+
+	if resp.IsValid {
+		r.logger.Info("Failing pending purchase that never settled",
+			"purchaseID", purchase.ID, "shortCode", purchase.ShortCode)
+		return r.store.FailPurchase(ctx, purchase.ID,
+			"payment was not settled and is still unused")
+	}
+
+	r.logger.Warn("Pending purchase needs to be resolved manually",
+		"purchaseID", purchase.ID, "shortCode", purchase.ShortCode,
+		"invalidReason", resp.InvalidReason)
+	return r.store.FlagPendingPurchase(ctx, purchase.ID,
+		fmt.Sprintf("payment may have settled, it no longer verifies: %s", resp.InvalidReason))
+}
@@ -3,6 +3,9 @@ package purchases
 import (
 	"context"
 	"log/slog"
+	"math"
+
+	"linkshrink/ledger"
 )
 
 // PurchaseService provides business logic for managing purchases.
@@ -43,7 +46,86 @@ func (s *PurchaseService) GetPurchaseByRouteIDAndPaymentHeader(ctx context.Conte
 	return s.store.GetPurchaseByRouteIDAndPaymentHeader(ctx, routeID, paymentHeader)
 }
 
-// IncrementCreditsUsed calls the store to increment the used credits for a purchase.
-func (s *PurchaseService) IncrementCreditsUsed(ctx context.Context, purchaseID uint64) error {
-	return s.store.IncrementPurchaseCreditsUsed(ctx, purchaseID)
+// CreatePendingPurchase records a purchase whose payment is verified but not
+// settled yet, so the payment is never settled without a record of it.
+//
+// The payment's authorization is registered with the purchase, so it can't
+// be used for another purchase on any route, under either protocol version.
+// Returns ErrPaymentReused if it already was.
+func (s *PurchaseService) CreatePendingPurchase(ctx context.Context, purchase *Purchase) (uint64, error) {
+	nonce, err := ParsePaymentNonce(purchase.PaymentPayload)
+	if err != nil {
+		return 0, err
+	}
+
+	purchase.Status = StatusPending
+	purchase.SettleResponse = nil
+	purchase.PaymentNonce = nonce
+	return s.store.CreatePurchase(ctx, purchase)
+}
+
+// CreateFreePurchase records the access of a free payer, whose payment is
+// verified but never settled. The payment's authorization is registered like
+// that of any other purchase, so it lets the payer through only once.
+// Returns ErrPaymentReused if it already was used.
+func (s *PurchaseService) CreateFreePurchase(ctx context.Context, purchase *Purchase) (uint64, error) {
+	nonce, err := ParsePaymentNonce(purchase.PaymentPayload)
+	if err != nil {
+		return 0, err
+	}
+
+	purchase.Price = 0
+	purchase.Status = StatusSettled
+	purchase.SettleResponse = nil
+	purchase.PaymentNonce = nonce
+	return s.store.CreatePurchase(ctx, purchase)
+}
+
+// SettlePurchase marks a pending purchase as settled with the facilitator's
+// settle response, recording its payouts and posting it to the ledger in the
+// same database transaction.
+func (s *PurchaseService) SettlePurchase(ctx context.Context, purchaseID uint64,
+	settleResponse []byte, accounting *Accounting) error {
+
+	return s.store.SettlePurchase(ctx, purchaseID, settleResponse, accounting)
+}
+
+// FailPurchase marks a pending purchase as failed to settle. Its payment
+// didn't transfer any funds, so the payment and any coupon it redeemed can
+// be used again.
+func (s *PurchaseService) FailPurchase(ctx context.Context, purchaseID uint64, reason string) error {
+	return s.store.FailPurchase(ctx, purchaseID, reason)
+}
+
+// GetPurchaseByID retrieves a purchase by its ID.
+func (s *PurchaseService) GetPurchaseByID(ctx context.Context, purchaseID uint64) (*Purchase, error) {
+	return s.store.GetPurchaseByID(ctx, purchaseID)
+}
+
+// ListPendingPurchases retrieves the pending purchases of all routes of a
+// user.
+func (s *PurchaseService) ListPendingPurchases(ctx context.Context, userID uint64) ([]Purchase, error) {
+	return s.store.ListPendingPurchasesByUserID(ctx, userID)
+}
+
+// IncrementCreditsUsed calls the store to increment the used credits for the
+// purchase of use, posting the used credit to the ledger, and returns the
+// number of credits used.
+func (s *PurchaseService) IncrementCreditsUsed(ctx context.Context, use ledger.CreditUse) (uint64, error) {
+	return s.store.IncrementPurchaseCreditsUsed(ctx, use)
+}
+
+// ChargeUsage records upstream reported usage on the purchase of usage, of a
+// metered route, and charges it at unitPrice, up to the purchase's remaining
+// balance, posting the charge to the ledger. It returns the updated purchase
+// and the amount actually charged.
+func (s *PurchaseService) ChargeUsage(ctx context.Context, usage ledger.Usage,
+	usageUnits uint64, unitPrice uint64) (*Purchase, uint64, error) {
+
+	amount := usageUnits * unitPrice
+	if unitPrice != 0 && amount/unitPrice != usageUnits {
+		amount = math.MaxUint64
+	}
+
+	return s.store.ChargePurchaseUsage(ctx, usage, usageUnits, amount)
 }
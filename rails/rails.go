@@ -0,0 +1,69 @@
+package rails
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Custom errors for payment rails
+var (
+	ErrInvalidCredential = errors.New("invalid payment credential")
+	ErrWrongRoute        = errors.New("payment credential is for another route")
+)
+
+// Charge is a payment a rail asks for to access a route.
+type Charge struct {
+	ShortCode   string
+	Description string
+	// Amount is the price in base units (USDC * 10^6), the rail converts it
+	// to its own currency.
+	Amount uint64
+	// Credits is the number of requests the payment pays for.
+	Credits uint64
+}
+
+// Payment is a payment a rail verified.
+type Payment struct {
+	// Reference identifies the payment, every request presenting the same
+	// credential has the same reference.
+	Reference string
+	// Network and Nonce identify the payment across routes and rails, so it
+	// pays for a single purchase.
+	Network string
+	Nonce   string
+
+	// Amount and Credits are what the payment was asked for, in base units
+	// (USDC * 10^6).
+	Amount  uint64
+	Credits uint64
+
+	// Proof is the credential and what it proves, recorded with the
+	// purchase.
+	Proof []byte
+}
+
+// Rail is a way to pay for routes besides x402. Routes accept the rails they
+// opt in to, a rail asks for payments with a WWW-Authenticate challenge and
+// is paid with the credential the challenge leads to.
+type Rail interface {
+	// Name is how routes refer to the rail.
+	Name() string
+
+	// Serves reports whether the rail takes payments for test routes or for
+	// real ones.
+	Serves(isTest bool) bool
+
+	// TakeCredential removes the rail's credential from the request, so it is
+	// not proxied, and returns it. Returns an empty string if the request has
+	// none.
+	TakeCredential(req *http.Request) string
+
+	// Challenge returns the WWW-Authenticate value asking for the charge.
+	Challenge(ctx context.Context, charge Charge) (string, error)
+
+	// Verify checks the credential is a completed payment for the route of
+	// the short code. Returns ErrInvalidCredential if it is not a valid
+	// payment and ErrWrongRoute if it pays for another route.
+	Verify(ctx context.Context, shortCode string, credential string) (*Payment, error)
+}
@@ -0,0 +1,28 @@
+package reconciler
+
+// DefaultConfig returns all default values for the Config struct.
+func DefaultConfig() Config {
+	return Config{
+		IntervalSeconds:       60,
+		BatchSize:             50,
+		Confirmations:         3,
+		ReceiptTimeoutSeconds: 600,
+		RPCTimeoutSeconds:     10,
+		USDCAddress:           "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		TestnetUSDCAddress:    "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+	}
+}
+
+// Config holds the configuration for the settlement reconciler.
+type Config struct {
+	IntervalSeconds       int `long:"interval_seconds" description:"Seconds between reconciliation runs, 0 disables the reconciler"`
+	BatchSize             int `long:"batch_size" description:"Settlements checked per reconciliation run"`
+	Confirmations         int `long:"confirmations" description:"Blocks needed before a settlement is confirmed"`
+	ReceiptTimeoutSeconds int `long:"receipt_timeout_seconds" description:"Seconds after which a settlement without receipt has failed"`
+	RPCTimeoutSeconds     int `long:"rpc_timeout_seconds" description:"Timeout for a single JSON-RPC call"`
+
+	RPCURL             string `long:"rpc_url" description:"JSON-RPC endpoint of Base mainnet, empty skips mainnet settlements"`
+	USDCAddress        string `long:"usdc_address" description:"USDC contract address on Base mainnet"`
+	TestnetRPCURL      string `long:"testnet_rpc_url" description:"JSON-RPC endpoint of Base Sepolia, empty skips testnet settlements"`
+	TestnetUSDCAddress string `long:"testnet_usdc_address" description:"USDC contract address on Base Sepolia"`
+}
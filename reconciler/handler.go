@@ -0,0 +1,72 @@
+package reconciler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/auth"
+)
+
+// Handler handles HTTP requests related to settlement reconciliation.
+type Handler struct {
+	reconciler *Reconciler
+}
+
+// NewHandler creates a new Handler.
+func NewHandler(reconciler *Reconciler) *Handler {
+	return &Handler{
+		reconciler: reconciler,
+	}
+}
+
+// GetAlerts returns the settlement problems of the user's routes they have
+// not dismissed.
+func (h *Handler) GetAlerts(gCtx *gin.Context) {
+	authPayload, exists := gCtx.Get(auth.AuthorizationPayloadKey)
+	if !exists {
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	payload := authPayload.(*auth.Claims)
+
+	alerts, err := h.reconciler.ListAlerts(gCtx.Request.Context(), payload.UserID)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve settlement alerts"})
+		return
+	}
+
+	gCtx.JSON(http.StatusOK, gin.H{"alerts": alerts})
+}
+
+// DismissAlert hides a settlement problem of the user's routes.
+func (h *Handler) DismissAlert(gCtx *gin.Context) {
+	authPayload, exists := gCtx.Get(auth.AuthorizationPayloadKey)
+	if !exists {
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	payload := authPayload.(*auth.Claims)
+
+	alertID, err := strconv.ParseUint(gCtx.Param("alertID"), 10, 64)
+	if err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
+		return
+	}
+
+	err = h.reconciler.DismissAlert(gCtx.Request.Context(), payload.UserID, alertID)
+	if errors.Is(err, ErrAlertNotFound) {
+		gCtx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dismiss settlement alert"})
+		return
+	}
+
+	gCtx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,48 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+)
+
+// Custom errors for settlement reconciliation
+var (
+	ErrAlertNotFound = errors.New("settlement alert not found")
+)
+
+// Store provides access to the settlement check storage.
+type Store interface {
+	// CreateSettlementCheck queues the settlement of a purchase for
+	// on-chain verification.
+	CreateSettlementCheck(ctx context.Context, purchaseID uint64,
+		network string, txHash string) error
+
+	// ListUnsettledChecks retrieves the settlements still to be verified,
+	// least recently checked first.
+	ListUnsettledChecks(ctx context.Context, limit uint64) ([]Check, error)
+
+	// UpdateSettlementCheck records the result of an on-chain verification.
+	UpdateSettlementCheck(ctx context.Context, check *Check) error
+
+	// CountSettlementChecksByRouteID retrieves the number of purchases of a
+	// route per settlement status.
+	CountSettlementChecksByRouteID(ctx context.Context, routeID uint64) (map[string]uint64, error)
+
+	// CreateSettlementAlert records a settlement problem for the owner of
+	// the purchased route.
+	CreateSettlementAlert(ctx context.Context, check *Check) error
+
+	// ListSettlementAlertsByUserID retrieves the alerts a user has not
+	// dismissed, newest first.
+	ListSettlementAlertsByUserID(ctx context.Context, userID uint64) ([]Alert, error)
+
+	// DismissSettlementAlert hides an alert of a user. Returns
+	// ErrAlertNotFound if the user has no such alert shown.
+	DismissSettlementAlert(ctx context.Context, userID, alertID uint64) error
+}
+
+// Alerter is notified when the settlement of a purchase turns out to be
+// failed, reorged or not matching the purchase.
+type Alerter interface {
+	SettlementProblem(ctx context.Context, check *Check)
+}
@@ -0,0 +1,347 @@
+package reconciler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+	"time"
+
+	"linkshrink/utils"
+)
+
+// Settlement statuses.
+const (
+	StatusPending   = "pending"
+	StatusConfirmed = "confirmed"
+	StatusFailed    = "failed"
+	StatusReorged   = "reorged"
+	StatusMismatch  = "mismatch"
+)
+
+// TransferTopic is the topic of the ERC-20 Transfer(address,address,uint256)
+// event.
+const TransferTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+// Check is the on-chain verification of the settlement of a purchase.
+type Check struct {
+	PurchaseID    uint64
+	Network       string
+	TxHash        string
+	Status        string
+	BlockNumber   uint64
+	BlockHash     string
+	Confirmations uint64
+	LastError     string
+
+	// What the purchase expects the settlement to have paid.
+	Price       uint64
+	PayTo       string
+	ShortCode   string
+	OwnerID     uint64
+	PurchasedAt time.Time
+}
+
+// Alert is a settlement problem shown to the owner of the purchased route.
+type Alert struct {
+	ID         uint64    `json:"id"`
+	PurchaseID uint64    `json:"purchase_id"`
+	ShortCode  string    `json:"short_code"`
+	Price      uint64    `json:"price"`
+	IsTest     bool      `json:"is_test"`
+	Status     string    `json:"status"`
+	Reason     string    `json:"reason"`
+	Network    string    `json:"network"`
+	TxHash     string    `json:"tx_hash"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// chain is a network settlements are verified on.
+type chain struct {
+	client       Client
+	tokenAddress string
+}
+
+// Reconciler verifies the settlements reported by the facilitator against
+// the chain they were settled on.
+type Reconciler struct {
+	cfg     *Config
+	chains  map[string]*chain
+	alerter Alerter
+	clock   utils.Clock
+
+	logger *slog.Logger
+	store  Store
+}
+
+// NewReconciler creates a new Reconciler with the networks that have an
+// RPC endpoint configured.
+func NewReconciler(logger *slog.Logger, store Store, cfg *Config,
+	alerter Alerter, clock utils.Clock) *Reconciler {
+
+	r := &Reconciler{
+		cfg:     cfg,
+		chains:  make(map[string]*chain),
+		alerter: alerter,
+		clock:   clock,
+		logger:  logger,
+		store:   store,
+	}
+
+	timeout := time.Duration(cfg.RPCTimeoutSeconds) * time.Second
+	if cfg.RPCURL != "" {
+		r.RegisterNetwork(NewRPCClient(cfg.RPCURL, timeout), cfg.USDCAddress,
+			"base", "eip155:8453")
+	}
+	if cfg.TestnetRPCURL != "" {
+		r.RegisterNetwork(NewRPCClient(cfg.TestnetRPCURL, timeout), cfg.TestnetUSDCAddress,
+			"base-sepolia", "eip155:84532")
+	}
+
+	return r
+}
+
+// RegisterNetwork verifies settlements on the named networks with client,
+// expecting payments in the token at tokenAddress.
+func (r *Reconciler) RegisterNetwork(client Client, tokenAddress string, names ...string) {
+	c := &chain{client: client, tokenAddress: tokenAddress}
+	for _, name := range names {
+		r.chains[name] = c
+	}
+}
+
+// Track queues the settlement of a new purchase for verification.
+func (r *Reconciler) Track(ctx context.Context, purchaseID uint64, settleResponseJSON []byte) error {
+	var settlement struct {
+		Transaction string `json:"transaction"`
+		Network     string `json:"network"`
+	}
+	if err := json.Unmarshal(settleResponseJSON, &settlement); err != nil {
+		return fmt.Errorf("invalid settle response: %w", err)
+	}
+
+	return r.store.CreateSettlementCheck(ctx, purchaseID, settlement.Network, settlement.Transaction)
+}
+
+// StatusCounts retrieves the number of purchases of a route per settlement
+// status.
+func (r *Reconciler) StatusCounts(ctx context.Context, routeID uint64) (map[string]uint64, error) {
+	return r.store.CountSettlementChecksByRouteID(ctx, routeID)
+}
+
+// ListAlerts retrieves the settlement problems of a user's routes they have
+// not dismissed.
+func (r *Reconciler) ListAlerts(ctx context.Context, userID uint64) ([]Alert, error) {
+	return r.store.ListSettlementAlertsByUserID(ctx, userID)
+}
+
+// DismissAlert hides a settlement problem of a user's routes.
+func (r *Reconciler) DismissAlert(ctx context.Context, userID, alertID uint64) error {
+	return r.store.DismissSettlementAlert(ctx, userID, alertID)
+}
+
+// Run reconciles settlements every interval until the context is cancelled.
+func (r *Reconciler) Run(ctx context.Context) {
+	if r.cfg.IntervalSeconds <= 0 {
+		r.logger.Info("Settlement reconciler disabled")
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(r.cfg.IntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.ReconcileOnce(ctx); err != nil {
+				r.logger.Error("Settlement reconciliation run failed", "error", err)
+			}
+		}
+	}
+}
+
+// ReconcileOnce verifies one batch of unsettled purchases.
+func (r *Reconciler) ReconcileOnce(ctx context.Context) error {
+	checks, err := r.store.ListUnsettledChecks(ctx, uint64(r.cfg.BatchSize))
+	if err != nil {
+		return fmt.Errorf("failed to list unsettled checks: %w", err)
+	}
+
+	for i := range checks {
+		check := &checks[i]
+		previousStatus := check.Status
+
+		r.verify(ctx, check)
+
+		if err := r.store.UpdateSettlementCheck(ctx, check); err != nil {
+			return fmt.Errorf("failed to update settlement check: %w", err)
+		}
+
+		if check.Status != previousStatus {
+			r.logger.Info("Settlement status changed", "purchaseID", check.PurchaseID,
+				"shortCode", check.ShortCode, "from", previousStatus, "to", check.Status,
+				"reason", check.LastError)
+
+			switch check.Status {
+			case StatusFailed, StatusReorged, StatusMismatch:
+				r.alerter.SettlementProblem(ctx, check)
+			}
+		}
+	}
+
+	return nil
+}
+
+// verify checks a settlement on-chain and updates its status. Errors talking
+// to the chain leave the status unchanged so the check is retried.
+func (r *Reconciler) verify(ctx context.Context, check *Check) {
+	if check.TxHash == "" {
+		check.Status = StatusFailed
+		check.LastError = "settle response has no transaction"
+		return
+	}
+
+	c, ok := r.chains[check.Network]
+	if !ok {
+		check.LastError = fmt.Sprintf("no RPC endpoint for network %q", check.Network)
+		return
+	}
+
+	receipt, err := c.client.TransactionReceipt(ctx, check.TxHash)
+	if err != nil {
+		check.LastError = err.Error()
+		return
+	}
+
+	if receipt == nil {
+		switch {
+		case check.BlockHash != "":
+			// We saw the transaction in a block that is no longer part of
+			// the chain. Keep checking in case it is included again.
+			check.Status = StatusReorged
+			check.LastError = fmt.Sprintf("transaction no longer in block %d", check.BlockNumber)
+			check.BlockNumber, check.BlockHash, check.Confirmations = 0, "", 0
+
+		case r.clock.Now().Sub(check.PurchasedAt) > time.Duration(r.cfg.ReceiptTimeoutSeconds)*time.Second:
+			check.Status = StatusFailed
+			check.LastError = "transaction not found on-chain"
+
+		default:
+			check.LastError = "transaction not mined yet"
+		}
+		return
+	}
+
+	check.BlockNumber = receipt.BlockNumber
+	check.BlockHash = receipt.BlockHash
+
+	if receipt.Status != 1 {
+		check.Status = StatusFailed
+		check.LastError = "transaction reverted"
+		return
+	}
+
+	amount := transferredAmount(receipt.Logs, c.tokenAddress, check.PayTo)
+	if amount.Sign() == 0 {
+		check.Status = StatusMismatch
+		check.LastError = fmt.Sprintf("no token transfer to %s", check.PayTo)
+		return
+	}
+	if !amount.IsUint64() || amount.Uint64() != check.Price {
+		check.Status = StatusMismatch
+		check.LastError = fmt.Sprintf("transferred %s, expected %d", amount, check.Price)
+		return
+	}
+
+	head, err := c.client.BlockNumber(ctx)
+	if err != nil {
+		check.LastError = err.Error()
+		return
+	}
+	if head >= receipt.BlockNumber {
+		check.Confirmations = head - receipt.BlockNumber + 1
+	}
+
+	if check.Confirmations >= uint64(r.cfg.Confirmations) {
+		check.Status = StatusConfirmed
+		check.LastError = ""
+	} else {
+		check.Status = StatusPending
+		check.LastError = fmt.Sprintf("%d of %d confirmations", check.Confirmations, r.cfg.Confirmations)
+	}
+}
+
+// transferredAmount returns the total amount of the token transferred to
+// payTo by the logs of a transaction.
+func transferredAmount(logs []Log, tokenAddress string, payTo string) *big.Int {
+	total := new(big.Int)
+	for _, log := range logs {
+		if !strings.EqualFold(log.Address, tokenAddress) || len(log.Topics) != 3 ||
+			!strings.EqualFold(log.Topics[0], TransferTopic) {
+
+			continue
+		}
+
+		// Indexed addresses are left padded to 32 bytes.
+		to := log.Topics[2]
+		if len(to) < 40 || !strings.EqualFold(to[len(to)-40:], strings.TrimPrefix(payTo, "0x")) {
+			continue
+		}
+
+		value, ok := new(big.Int).SetString(strings.TrimPrefix(log.Data, "0x"), 16)
+		if !ok {
+			continue
+		}
+		total.Add(total, value)
+	}
+	return total
+}
+
+// LogAlerter is an Alerter reporting settlement problems in the logs.
+type LogAlerter struct {
+	logger *slog.Logger
+}
+
+// NewLogAlerter creates a new LogAlerter.
+func NewLogAlerter(logger *slog.Logger) *LogAlerter {
+	return &LogAlerter{logger: logger}
+}
+
+// SettlementProblem logs a settlement problem for the route owner.
+func (a *LogAlerter) SettlementProblem(_ context.Context, check *Check) {
+	a.logger.Error("Settlement of purchase could not be verified on-chain",
+		"purchaseID", check.PurchaseID, "shortCode", check.ShortCode,
+		"ownerID", check.OwnerID, "status", check.Status, "txHash", check.TxHash,
+		"network", check.Network, "reason", check.LastError)
+}
+
+// StoreAlerter is an Alerter recording settlement problems for the route
+// owner, who is shown them on their dashboard until dismissed. Problems are
+// logged as well.
+type StoreAlerter struct {
+	*LogAlerter
+
+	store Store
+}
+
+// NewStoreAlerter creates a new StoreAlerter.
+func NewStoreAlerter(logger *slog.Logger, store Store) *StoreAlerter {
+	return &StoreAlerter{
+		LogAlerter: NewLogAlerter(logger),
+		store:      store,
+	}
+}
+
+// SettlementProblem records a settlement problem for the route owner.
+func (a *StoreAlerter) SettlementProblem(ctx context.Context, check *Check) {
+	a.LogAlerter.SettlementProblem(ctx, check)
+
+	if err := a.store.CreateSettlementAlert(ctx, check); err != nil {
+		a.logger.Error("Failed to record settlement alert for route owner",
+			"purchaseID", check.PurchaseID, "ownerID", check.OwnerID, "error", err)
+	}
+}
@@ -0,0 +1,453 @@
+package reconciler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"linkshrink/utils"
+)
+
+const (
+	testToken = "0x036CbD53842c5426634e7929541eC2318f3dCF7e"
+	testPayTo = "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"
+	testTx    = "0xabc"
+)
+
+// rpcStub is a JSON-RPC endpoint serving canned receipts and a chain head.
+type rpcStub struct {
+	mu       sync.Mutex
+	receipts map[string]any
+	head     uint64
+	calls    []string
+}
+
+func newRPCStub(t *testing.T) (*rpcStub, *httptest.Server) {
+	stub := &rpcStub{receipts: make(map[string]any)}
+	server := httptest.NewServer(http.HandlerFunc(stub.serve))
+	t.Cleanup(server.Close)
+	return stub, server
+}
+
+func (s *rpcStub) serve(w http.ResponseWriter, r *http.Request) {
+	var req rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, req.Method)
+
+	var result any
+	switch req.Method {
+	case "eth_getTransactionReceipt":
+		result = s.receipts[req.Params[0].(string)]
+	case "eth_blockNumber":
+		result = fmt.Sprintf("0x%x", s.head)
+	default:
+		json.NewEncoder(w).Encode(map[string]any{
+			"jsonrpc": "2.0", "id": req.ID,
+			"error": map[string]any{"code": -32601, "message": "method not found"},
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+}
+
+func (s *rpcStub) setReceipt(txHash string, receipt any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.receipts[txHash] = receipt
+}
+
+func (s *rpcStub) setHead(head uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.head = head
+}
+
+// transferReceipt returns a receipt of a transaction transferring amount of
+// the token to payTo.
+func transferReceipt(status string, blockNumber uint64, blockHash string,
+	token, payTo string, amount uint64) map[string]any {
+
+	return map[string]any{
+		"status":      status,
+		"blockNumber": fmt.Sprintf("0x%x", blockNumber),
+		"blockHash":   blockHash,
+		"logs": []map[string]any{{
+			"address": token,
+			"topics": []string{
+				TransferTopic,
+				"0x000000000000000000000000" + strings.Repeat("1", 40),
+				"0x000000000000000000000000" + strings.ToLower(strings.TrimPrefix(payTo, "0x")),
+			},
+			"data": fmt.Sprintf("0x%064x", amount),
+		}},
+	}
+}
+
+// memoryStore is a Store keeping settlement checks and alerts in memory.
+type memoryStore struct {
+	checks map[uint64]*Check
+	alerts []Alert
+}
+
+func newMemoryStore(checks ...Check) *memoryStore {
+	s := &memoryStore{checks: make(map[uint64]*Check)}
+	for i := range checks {
+		s.checks[checks[i].PurchaseID] = &checks[i]
+	}
+	return s
+}
+
+func (s *memoryStore) CreateSettlementCheck(_ context.Context, purchaseID uint64,
+	network string, txHash string) error {
+
+	if _, ok := s.checks[purchaseID]; !ok {
+		s.checks[purchaseID] = &Check{PurchaseID: purchaseID, Network: network,
+			TxHash: txHash, Status: StatusPending}
+	}
+	return nil
+}
+
+func (s *memoryStore) ListUnsettledChecks(_ context.Context, limit uint64) ([]Check, error) {
+	var checks []Check
+	for _, check := range s.checks {
+		if check.Status == StatusPending || check.Status == StatusReorged {
+			checks = append(checks, *check)
+		}
+	}
+	return checks[:min(uint64(len(checks)), limit)], nil
+}
+
+func (s *memoryStore) UpdateSettlementCheck(_ context.Context, check *Check) error {
+	updated := *check
+	s.checks[check.PurchaseID] = &updated
+	return nil
+}
+
+func (s *memoryStore) CountSettlementChecksByRouteID(context.Context, uint64) (map[string]uint64, error) {
+	counts := make(map[string]uint64)
+	for _, check := range s.checks {
+		counts[check.Status]++
+	}
+	return counts, nil
+}
+
+func (s *memoryStore) CreateSettlementAlert(_ context.Context, check *Check) error {
+	s.alerts = append(s.alerts, Alert{
+		ID:         uint64(len(s.alerts) + 1),
+		PurchaseID: check.PurchaseID,
+		ShortCode:  check.ShortCode,
+		Price:      check.Price,
+		Status:     check.Status,
+		Reason:     check.LastError,
+		Network:    check.Network,
+		TxHash:     check.TxHash,
+	})
+	return nil
+}
+
+func (s *memoryStore) ListSettlementAlertsByUserID(context.Context, uint64) ([]Alert, error) {
+	return s.alerts, nil
+}
+
+func (s *memoryStore) DismissSettlementAlert(_ context.Context, _, alertID uint64) error {
+	for i, alert := range s.alerts {
+		if alert.ID == alertID {
+			s.alerts = append(s.alerts[:i], s.alerts[i+1:]...)
+			return nil
+		}
+	}
+	return ErrAlertNotFound
+}
+
+// newTestReconciler creates a Reconciler verifying base-sepolia settlements
+// against the RPC stub at url, recording alerts in store.
+func newTestReconciler(store *memoryStore, url string, clock utils.Clock) *Reconciler {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	cfg := DefaultConfig()
+	cfg.TestnetRPCURL = url
+	cfg.TestnetUSDCAddress = testToken
+
+	return NewReconciler(logger, store, &cfg, NewStoreAlerter(logger, store), clock)
+}
+
+func pendingCheck(purchasedAt time.Time) Check {
+	return Check{
+		PurchaseID:  1,
+		Network:     "base-sepolia",
+		TxHash:      testTx,
+		Status:      StatusPending,
+		Price:       10000,
+		PayTo:       testPayTo,
+		ShortCode:   "abc123",
+		OwnerID:     7,
+		PurchasedAt: purchasedAt,
+	}
+}
+
+func TestReconcileOnce(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		receipt     any
+		head        uint64
+		purchasedAt time.Time
+		status      string
+		reason      string
+		alerted     bool
+	}{
+		{
+			name:        "confirmed",
+			receipt:     transferReceipt("0x1", 100, "0xb1", testToken, testPayTo, 10000),
+			head:        102,
+			purchasedAt: now,
+			status:      StatusConfirmed,
+		},
+		{
+			name:        "awaiting confirmations",
+			receipt:     transferReceipt("0x1", 100, "0xb1", testToken, testPayTo, 10000),
+			head:        100,
+			purchasedAt: now,
+			status:      StatusPending,
+			reason:      "1 of 3 confirmations",
+		},
+		{
+			name:        "reverted",
+			receipt:     transferReceipt("0x0", 100, "0xb1", testToken, testPayTo, 10000),
+			purchasedAt: now,
+			status:      StatusFailed,
+			reason:      "transaction reverted",
+			alerted:     true,
+		},
+		{
+			name:        "underpaid",
+			receipt:     transferReceipt("0x1", 100, "0xb1", testToken, testPayTo, 9999),
+			purchasedAt: now,
+			status:      StatusMismatch,
+			reason:      "transferred 9999, expected 10000",
+			alerted:     true,
+		},
+		{
+			name:        "paid to someone else",
+			receipt:     transferReceipt("0x1", 100, "0xb1", testToken, "0x"+strings.Repeat("2", 40), 10000),
+			purchasedAt: now,
+			status:      StatusMismatch,
+			reason:      "no token transfer to " + testPayTo,
+			alerted:     true,
+		},
+		{
+			name:        "paid in another token",
+			receipt:     transferReceipt("0x1", 100, "0xb1", "0x"+strings.Repeat("3", 40), testPayTo, 10000),
+			purchasedAt: now,
+			status:      StatusMismatch,
+			reason:      "no token transfer to " + testPayTo,
+			alerted:     true,
+		},
+		{
+			name:        "not mined yet",
+			purchasedAt: now.Add(-time.Minute),
+			status:      StatusPending,
+			reason:      "transaction not mined yet",
+		},
+		{
+			name:        "never mined",
+			purchasedAt: now.Add(-time.Hour),
+			status:      StatusFailed,
+			reason:      "transaction not found on-chain",
+			alerted:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub, server := newRPCStub(t)
+			if test.receipt != nil {
+				stub.setReceipt(testTx, test.receipt)
+			}
+			stub.setHead(test.head)
+
+			clock := utils.NewMockClock()
+			clock.SetMockClockTime(now)
+			store := newMemoryStore(pendingCheck(test.purchasedAt))
+			r := newTestReconciler(store, server.URL, clock)
+
+			if err := r.ReconcileOnce(context.Background()); err != nil {
+				t.Fatalf("ReconcileOnce: %v", err)
+			}
+
+			check := store.checks[1]
+			if check.Status != test.status || check.LastError != test.reason {
+				t.Errorf("check is %s (%q), want %s (%q)", check.Status, check.LastError,
+					test.status, test.reason)
+			}
+
+			alerts, err := r.ListAlerts(context.Background(), 7)
+			if err != nil {
+				t.Fatalf("ListAlerts: %v", err)
+			}
+			if !test.alerted {
+				if len(alerts) != 0 {
+					t.Errorf("got alerts %+v, want none", alerts)
+				}
+				return
+			}
+			if len(alerts) != 1 {
+				t.Fatalf("got %d alerts, want 1", len(alerts))
+			}
+			alert := alerts[0]
+			if alert.PurchaseID != 1 || alert.ShortCode != "abc123" || alert.Status != test.status ||
+				alert.Reason != test.reason || alert.TxHash != testTx {
+
+				t.Errorf("alert is %+v", alert)
+			}
+		})
+	}
+}
+
+func TestReconcileReorg(t *testing.T) {
+	stub, server := newRPCStub(t)
+	stub.setReceipt(testTx, transferReceipt("0x1", 100, "0xb1", testToken, testPayTo, 10000))
+	stub.setHead(100)
+
+	clock := utils.NewMockClock()
+	store := newMemoryStore(pendingCheck(clock.Now()))
+	r := newTestReconciler(store, server.URL, clock)
+	ctx := context.Background()
+
+	if err := r.ReconcileOnce(ctx); err != nil {
+		t.Fatalf("ReconcileOnce: %v", err)
+	}
+	if check := store.checks[1]; check.Status != StatusPending || check.BlockHash != "0xb1" {
+		t.Fatalf("check is %s in block %q, want pending in 0xb1", check.Status, check.BlockHash)
+	}
+
+	// The block the transaction was in is dropped from the chain.
+	stub.setReceipt(testTx, nil)
+	if err := r.ReconcileOnce(ctx); err != nil {
+		t.Fatalf("ReconcileOnce: %v", err)
+	}
+	if check := store.checks[1]; check.Status != StatusReorged || check.BlockHash != "" {
+		t.Fatalf("check is %s in block %q, want reorged", check.Status, check.BlockHash)
+	}
+	if len(store.alerts) != 1 || store.alerts[0].Status != StatusReorged {
+		t.Fatalf("alerts are %+v, want one reorged alert", store.alerts)
+	}
+
+	// A reorged settlement staying reorged doesn't alert again.
+	if err := r.ReconcileOnce(ctx); err != nil {
+		t.Fatalf("ReconcileOnce: %v", err)
+	}
+	if len(store.alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1", len(store.alerts))
+	}
+
+	// The transaction is included again in another block.
+	stub.setReceipt(testTx, transferReceipt("0x1", 101, "0xb2", testToken, testPayTo, 10000))
+	stub.setHead(110)
+	if err := r.ReconcileOnce(ctx); err != nil {
+		t.Fatalf("ReconcileOnce: %v", err)
+	}
+	if check := store.checks[1]; check.Status != StatusConfirmed || check.BlockHash != "0xb2" {
+		t.Fatalf("check is %s in block %q, want confirmed in 0xb2", check.Status, check.BlockHash)
+	}
+
+	// The owner dismisses the alert of the reorg.
+	if err := r.DismissAlert(ctx, 7, store.alerts[0].ID); err != nil {
+		t.Fatalf("DismissAlert: %v", err)
+	}
+	if alerts, _ := r.ListAlerts(ctx, 7); len(alerts) != 0 {
+		t.Fatalf("got alerts %+v after dismissing", alerts)
+	}
+}
+
+func TestReconcileRPCErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		reason  string
+	}{
+		{
+			name: "server error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			},
+			reason: "eth_getTransactionReceipt returned status 503",
+		},
+		{
+			name: "rpc error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, `{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"rate limited"}}`)
+			},
+			reason: "eth_getTransactionReceipt failed: rate limited (code -32005)",
+		},
+		{
+			name: "invalid receipt",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, `{"jsonrpc":"2.0","id":1,"result":{"status":"1","blockNumber":"0x1"}}`)
+			},
+			reason: `invalid receipt status: quantity "1" is not hex encoded`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+
+			clock := utils.NewMockClock()
+			store := newMemoryStore(pendingCheck(clock.Now().Add(-time.Hour)))
+			r := newTestReconciler(store, server.URL, clock)
+
+			if err := r.ReconcileOnce(context.Background()); err != nil {
+				t.Fatalf("ReconcileOnce: %v", err)
+			}
+
+			// Errors talking to the chain are retried, not reported as failures.
+			check := store.checks[1]
+			if check.Status != StatusPending || check.LastError != test.reason {
+				t.Errorf("check is %s (%q), want pending (%q)", check.Status, check.LastError, test.reason)
+			}
+			if len(store.alerts) != 0 {
+				t.Errorf("got alerts %+v, want none", store.alerts)
+			}
+		})
+	}
+}
+
+func TestReconcileUnknownNetwork(t *testing.T) {
+	stub, server := newRPCStub(t)
+
+	clock := utils.NewMockClock()
+	check := pendingCheck(clock.Now())
+	check.Network = "eip155:1"
+	store := newMemoryStore(check)
+	r := newTestReconciler(store, server.URL, clock)
+
+	if err := r.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce: %v", err)
+	}
+	if got := store.checks[1]; got.Status != StatusPending ||
+		got.LastError != `no RPC endpoint for network "eip155:1"` {
+
+		t.Errorf("check is %s (%q)", got.Status, got.LastError)
+	}
+	if len(stub.calls) != 0 {
+		t.Errorf("RPC endpoint was called: %v", stub.calls)
+	}
+}
@@ -0,0 +1,173 @@
+package reconciler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Client queries an EVM chain. It is implemented by RPCClient and can be
+// stubbed to reconcile without a node.
+type Client interface {
+	// TransactionReceipt returns the receipt of a transaction, or nil if the
+	// transaction is not (or no longer) included in the chain.
+	TransactionReceipt(ctx context.Context, txHash string) (*Receipt, error)
+
+	// BlockNumber returns the number of the most recent block.
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+// Receipt is the receipt of a mined transaction.
+type Receipt struct {
+	// Status is 1 for successful and 0 for reverted transactions.
+	Status      uint64
+	BlockNumber uint64
+	BlockHash   string
+	Logs        []Log
+}
+
+// Log is an event emitted by a transaction.
+type Log struct {
+	Address string
+	Topics  []string
+	Data    string
+}
+
+// RPCClient is a Client using an Ethereum JSON-RPC endpoint, such as a
+// hosted Base node or a local anvil or hardhat node.
+type RPCClient struct {
+	url        string
+	httpClient *http.Client
+	nextID     atomic.Uint64
+}
+
+// NewRPCClient creates a new RPCClient for the endpoint.
+func NewRPCClient(url string, timeout time.Duration) *RPCClient {
+	return &RPCClient{
+		url:        url,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      uint64 `json:"id"`
+	Method  string `json:"method"`
+	Params  []any  `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// call invokes a JSON-RPC method and decodes its result.
+func (c *RPCClient) call(ctx context.Context, method string, result any, params ...any) error {
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      c.nextID.Add(1),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", method, resp.StatusCode)
+	}
+
+	var rpcResp rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("invalid %s response: %w", method, err)
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("%s failed: %s (code %d)", method, rpcResp.Error.Message, rpcResp.Error.Code)
+	}
+
+	return json.Unmarshal(rpcResp.Result, result)
+}
+
+// TransactionReceipt returns the receipt of a transaction, or nil if the
+// transaction is not included in the chain.
+func (c *RPCClient) TransactionReceipt(ctx context.Context, txHash string) (*Receipt, error) {
+	var raw *struct {
+		Status      string `json:"status"`
+		BlockNumber string `json:"blockNumber"`
+		BlockHash   string `json:"blockHash"`
+		Logs        []struct {
+			Address string   `json:"address"`
+			Topics  []string `json:"topics"`
+			Data    string   `json:"data"`
+		} `json:"logs"`
+	}
+	if err := c.call(ctx, "eth_getTransactionReceipt", &raw, txHash); err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	status, err := parseQuantity(raw.Status)
+	if err != nil {
+		return nil, fmt.Errorf("invalid receipt status: %w", err)
+	}
+	blockNumber, err := parseQuantity(raw.BlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("invalid receipt block number: %w", err)
+	}
+
+	receipt := &Receipt{
+		Status:      status,
+		BlockNumber: blockNumber,
+		BlockHash:   raw.BlockHash,
+		Logs:        make([]Log, len(raw.Logs)),
+	}
+	for i, log := range raw.Logs {
+		receipt.Logs[i] = Log{
+			Address: log.Address,
+			Topics:  log.Topics,
+			Data:    log.Data,
+		}
+	}
+
+	return receipt, nil
+}
+
+// BlockNumber returns the number of the most recent block.
+func (c *RPCClient) BlockNumber(ctx context.Context) (uint64, error) {
+	var raw string
+	if err := c.call(ctx, "eth_blockNumber", &raw); err != nil {
+		return 0, err
+	}
+	return parseQuantity(raw)
+}
+
+// parseQuantity parses a hex encoded JSON-RPC quantity such as "0x1b4".
+func parseQuantity(s string) (uint64, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return 0, fmt.Errorf("quantity %q is not hex encoded", s)
+	}
+	return strconv.ParseUint(s[2:], 16, 64)
+}
@@ -0,0 +1,189 @@
+package routes
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/auth"
+	"linkshrink/payers"
+	"linkshrink/purchases"
+)
+
+const (
+	// PayerHeader lets a client name the address it pays from before it
+	// pays, so the payment asked of it reflects the owner's rule for it.
+	PayerHeader = "Proxy402-Payer"
+	// AccessHeader marks requests let through without a payment of their
+	// own, AccessOwner, AccessPayer, AccessWallet or AccessBundle.
+	AccessHeader = "Proxy402-Access"
+	AccessOwner  = "owner"
+	AccessPayer  = "payer"
+	AccessWallet = "wallet"
+	AccessBundle = "bundle"
+)
+
+// tryOwnerPreview lets the route's owner through without paying when the
+// request carries their dashboard session. Returns true if the request is an
+// owner preview, which is logged as such rather than counted as an access.
+func (h *PaidRouteHandler) tryOwnerPreview(gCtx *gin.Context, route *PaidRoute) bool {
+	if !h.config.OwnerPreview {
+		return false
+	}
+
+	token, err := gCtx.Cookie(auth.JWTCookie)
+	if err != nil || token == "" {
+		return false
+	}
+
+	claims, err := h.authenticator.ValidateJWT(token)
+	if err != nil || claims.UserID != route.UserID {
+		return false
+	}
+
+	h.logger.Info("Owner preview access", "shortCode", route.ShortCode,
+		"routeID", route.ID, "userID", claims.UserID, "method", gCtx.Request.Method)
+	gCtx.Header(AccessHeader, AccessOwner)
+	setStreamCharge(gCtx, freeStreamCharge)
+
+	// The session is meant for the dashboard, not the upstream.
+	removeRequestCookie(gCtx.Request, auth.JWTCookie)
+
+	return true
+}
+
+// removeRequestCookie removes a cookie from the request's Cookie header.
+func removeRequestCookie(req *http.Request, name string) {
+	cookies := req.Cookies()
+	req.Header.Del("Cookie")
+	for _, cookie := range cookies {
+		if cookie.Name != name {
+			req.AddCookie(cookie)
+		}
+	}
+}
+
+// requestPayer returns the payer address of the request's payment, or the
+// address the client names with PayerHeader if it did not pay yet. Either is
+// only trusted for more than blocking once the payment verified.
+func requestPayer(gCtx *gin.Context, route *PaidRoute) string {
+	paymentHeader := gCtx.GetHeader(getPaymentHeaderNameForRoute(route))
+	if paymentHeader != "" {
+		paymentPayloadJSON, err := base64.StdEncoding.DecodeString(paymentHeader)
+		if err == nil {
+			if payer := paymentPayer(paymentPayloadJSON); payer != "" {
+				return payer
+			}
+		}
+	}
+
+	return gCtx.GetHeader(PayerHeader)
+}
+
+// findPayerRule looks up the owner's rule for the request's payer, nil if
+// there is none. It sends an error response and returns requestHandled=true
+// if the payer is blocked.
+func (h *PaidRouteHandler) findPayerRule(gCtx *gin.Context,
+	route *PaidRoute) (rule *payers.Rule, requestHandled bool) {
+
+	payer := requestPayer(gCtx, route)
+	if payer == "" {
+		return nil, false
+	}
+
+	rule, err := h.payerService.FindRule(gCtx.Request.Context(), route.UserID, route.ID, payer)
+	if err != nil {
+		h.logger.Error("Failed to look up payer rule", "shortCode", route.ShortCode,
+			"payer", payer, "error", err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up payer"})
+		return nil, true
+	}
+
+	if rule != nil && rule.Treatment == payers.TreatmentBlock {
+		h.logger.Warn("Rejected request of blocked payer", "shortCode", route.ShortCode,
+			"payer", rule.PayerAddress, "ruleID", rule.ID)
+		gCtx.JSON(http.StatusForbidden, gin.H{"error": "Payer is blocked from this route"})
+		return nil, true
+	}
+
+	return rule, false
+}
+
+// isFreePayer reports whether the rule lets its payer through for free.
+func isFreePayer(rule *payers.Rule) bool {
+	return rule != nil && rule.Treatment == payers.TreatmentFree
+}
+
+// applyPayerRule returns the route priced with the payer's discount.
+func applyPayerRule(route *PaidRoute, rule *payers.Rule) *PaidRoute {
+	if rule == nil || rule.Treatment != payers.TreatmentDiscount {
+		return route
+	}
+
+	discountedRoute := *route
+	discountedRoute.Price -= rule.Discount(route.Price)
+
+	return &discountedRoute
+}
+
+// letFreePayerThrough lets a request through whose payment verified and was
+// made by a free payer. The payment is not settled, but it is recorded as a
+// free purchase so its authorization can't let the payer through again.
+// Returns:
+//   - letThrough: true if the request may be proxied without payment.
+//   - requestHandled: true if an error response was sent.
+func (h *PaidRouteHandler) letFreePayerThrough(gCtx *gin.Context, route *PaidRoute,
+	rule *payers.Rule, paymentPayloadJSON []byte) (letThrough bool, requestHandled bool) {
+
+	payer := payers.NormalizeAddress(paymentPayer(paymentPayloadJSON))
+	if payer != rule.PayerAddress {
+		h.logger.Warn("Verified payment is not from the free payer", "shortCode", route.ShortCode,
+			"payer", payer, "ruleID", rule.ID)
+		gCtx.JSON(http.StatusPaymentRequired, gin.H{"error": "Payment is not from the listed payer"})
+		return false, true
+	}
+
+	purchase := &purchases.Purchase{
+		ShortCode:        route.ShortCode,
+		TargetURL:        route.TargetURL,
+		Method:           route.Method,
+		Type:             route.Type,
+		CreditsAvailable: route.Credits,
+		CreditsUsed:      1,
+		IsTest:           route.IsTest,
+		PaidRouteID:      route.ID,
+		PaymentHeader:    gCtx.GetHeader(getPaymentHeaderNameForRoute(route)),
+		PaymentPayload:   paymentPayloadJSON,
+	}
+	_, err := h.purchaseService.CreateFreePurchase(gCtx.Request.Context(), purchase)
+	switch {
+	case errors.Is(err, purchases.ErrPaymentReused), errors.Is(err, purchases.ErrNoPaymentNonce):
+		h.logger.Warn("Rejected free payer's payment", "shortCode", route.ShortCode,
+			"payer", payer, "ruleID", rule.ID, "error", err)
+		gCtx.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+		return false, true
+	case errors.Is(err, purchases.ErrSoldOut):
+		gCtx.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		return false, true
+	case err != nil:
+		h.logger.Error("Failed to save free payer purchase", "shortCode", route.ShortCode,
+			"payer", payer, "error", err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record free access"})
+		return false, true
+	}
+
+	h.logger.Info("Free payer access", "shortCode", route.ShortCode,
+		"routeID", route.ID, "payer", payer, "ruleID", rule.ID)
+	gCtx.Header(AccessHeader, AccessPayer)
+	setStreamCharge(gCtx, freeStreamCharge)
+
+	err = h.paidRouteService.IncrementAccessCount(gCtx.Request.Context(), route.ShortCode)
+	if err != nil {
+		h.logger.Error("Failed to increment access count (free payer)",
+			"shortCode", route.ShortCode, "error", err)
+	}
+
+	return true, false
+}
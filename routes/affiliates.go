@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/affiliates"
+	"linkshrink/splits"
+)
+
+const (
+	// ReferralHeader carries the code of the affiliate that referred the
+	// buyer, alternatively passed as the ReferralQueryParam query parameter.
+	ReferralHeader     = "Proxy402-Referral"
+	ReferralQueryParam = "ref"
+)
+
+// resolveAffiliate looks up the affiliate that referred the request, if any.
+// Unknown codes are ignored, a stale referral link still sells. The code is
+// only removed from the query when it is an affiliate's, the upstream may
+// use a ref parameter of its own.
+func (h *PaidRouteHandler) resolveAffiliate(gCtx *gin.Context, route *PaidRoute) *affiliates.Affiliate {
+	query := gCtx.Request.URL.Query()
+	code := gCtx.GetHeader(ReferralHeader)
+	fromQuery := code == ""
+	if fromQuery {
+		code = query.Get(ReferralQueryParam)
+	}
+	if code == "" {
+		return nil
+	}
+	gCtx.Request.Header.Del(ReferralHeader)
+
+	affiliate, err := h.affiliateService.FindAffiliate(gCtx.Request.Context(), route.UserID, route.ID, code)
+	if err != nil {
+		if errors.Is(err, affiliates.ErrAffiliateNotFound) {
+			h.logger.Debug("Ignoring unknown referral code", "shortCode", route.ShortCode, "code", code)
+		} else {
+			h.logger.Error("Failed to look up affiliate", "shortCode", route.ShortCode, "error", err)
+		}
+		return nil
+	}
+
+	if fromQuery {
+		query.Del(ReferralQueryParam)
+		gCtx.Request.URL.RawQuery = query.Encode()
+	}
+	return affiliate
+}
+
+// referralURL returns the access URL of a route with the referral code of the
+// affiliate, so the referral is carried through the payment challenge.
+func referralURL(accessURL string, affiliate *affiliates.Affiliate) string {
+	if affiliate == nil {
+		return accessURL
+	}
+	return withQueryParam(accessURL, ReferralQueryParam, affiliate.Code)
+}
+
+// withQueryParam returns rawURL with the query parameter key set to value.
+func withQueryParam(rawURL, key, value string) string {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := target.Query()
+	query.Set(key, value)
+	target.RawQuery = query.Encode()
+	return target.String()
+}
+
+// purchaseCommission returns the commission owed on a purchase to the
+// affiliate that referred it, nil if none did or the affiliate was deleted
+// since.
+func (h *PaidRouteHandler) purchaseCommission(ctx context.Context, route *PaidRoute,
+	affiliateID uint64) *splits.Commission {
+
+	if affiliateID == 0 {
+		return nil
+	}
+
+	affiliate, err := h.affiliateService.GetAffiliate(ctx, affiliateID, route.UserID)
+	if err != nil {
+		if !errors.Is(err, affiliates.ErrAffiliateNotFound) {
+			h.logger.Error("Failed to look up affiliate of purchase", "shortCode", route.ShortCode,
+				"affiliateID", affiliateID, "error", err)
+		}
+		return nil
+	}
+	return affiliates.Commission(affiliate)
+}
@@ -0,0 +1,183 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/affiliates"
+	"linkshrink/auth"
+)
+
+// CreateAffiliateRequest defines the body for creating an affiliate.
+type CreateAffiliateRequest struct {
+	Code          string `form:"code" json:"code" binding:"required"`
+	PayoutAddress string `form:"payout_address" json:"payout_address" binding:"required"`
+	CommissionBPS uint64 `form:"commission_bps" json:"commission_bps" binding:"required"` // Basis points, 1000 = 10% of each referred purchase
+	RouteID       uint64 `form:"route_id" json:"route_id" binding:"omitempty"`            // Optional, all of the owner's routes if empty
+	Label         string `form:"label" json:"label" binding:"omitempty"`
+}
+
+// CreateAffiliateResponse represents a created affiliate with its earnings
+// token, which is only returned once.
+type CreateAffiliateResponse struct {
+	*affiliates.Affiliate
+	Token string `json:"token"`
+}
+
+// GetAffiliates handles GET requests for the affiliates of the user with the
+// sales they referred.
+func (h *PaidRouteHandler) GetAffiliates(gCtx *gin.Context) {
+	authPayload, exists := gCtx.Get(auth.AuthorizationPayloadKey)
+	if !exists {
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	payload := authPayload.(*auth.Claims)
+
+	userAffiliates, err := h.affiliateService.ListAffiliates(gCtx.Request.Context(), payload.UserID)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve affiliates"})
+		return
+	}
+	if userAffiliates == nil {
+		userAffiliates = []affiliates.Affiliate{}
+	}
+
+	gCtx.JSON(http.StatusOK, gin.H{"affiliates": userAffiliates})
+}
+
+// CreateAffiliate handles POST requests to create an affiliate earning
+// commission on one or all of the user's routes.
+func (h *PaidRouteHandler) CreateAffiliate(gCtx *gin.Context) {
+	var req CreateAffiliateRequest
+	if err := gCtx.ShouldBind(&req); err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	authPayload, exists := gCtx.Get(auth.AuthorizationPayloadKey)
+	if !exists {
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	payload := authPayload.(*auth.Claims)
+
+	ctx := gCtx.Request.Context()
+	if req.RouteID != 0 {
+		_, err := h.paidRouteService.GetUserRoute(ctx, req.RouteID, payload.UserID)
+		if err != nil {
+			if errors.Is(err, ErrRouteNotFound) || errors.Is(err, ErrRouteNoPermission) {
+				gCtx.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+			} else {
+				gCtx.Error(err)
+				gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve route"})
+			}
+			return
+		}
+	}
+
+	created, token, err := h.affiliateService.CreateAffiliate(ctx, &affiliates.Affiliate{
+		UserID:        payload.UserID,
+		RouteID:       req.RouteID,
+		Code:          req.Code,
+		PayoutAddress: req.PayoutAddress,
+		Label:         req.Label,
+		CommissionBPS: req.CommissionBPS,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, affiliates.ErrCodeTaken):
+			gCtx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, affiliates.ErrInvalidCode), errors.Is(err, affiliates.ErrInvalidCommission),
+			errors.Is(err, affiliates.ErrInvalidPayoutAddress):
+
+			gCtx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create affiliate"})
+		}
+		return
+	}
+
+	gCtx.JSON(http.StatusCreated, CreateAffiliateResponse{
+		Affiliate: created,
+		Token:     token,
+	})
+}
+
+// DeleteAffiliate handles DELETE requests for an affiliate of the user.
+func (h *PaidRouteHandler) DeleteAffiliate(gCtx *gin.Context) {
+	affiliateID, err := strconv.ParseUint(gCtx.Param("affiliateID"), 10, 64)
+	if err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid affiliate ID format"})
+		return
+	}
+
+	authPayload, exists := gCtx.Get(auth.AuthorizationPayloadKey)
+	if !exists {
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	payload := authPayload.(*auth.Claims)
+
+	err = h.affiliateService.DeleteAffiliate(gCtx.Request.Context(), affiliateID, payload.UserID)
+	if err != nil {
+		if errors.Is(err, affiliates.ErrAffiliateNotFound) {
+			gCtx.JSON(http.StatusNotFound, gin.H{"error": "Affiliate not found"})
+		} else {
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete affiliate"})
+		}
+		return
+	}
+
+	gCtx.Status(http.StatusOK)
+}
+
+// GetAffiliateSales handles GET requests for the latest sales an affiliate of
+// the user referred.
+func (h *PaidRouteHandler) GetAffiliateSales(gCtx *gin.Context) {
+	affiliateID, err := strconv.ParseUint(gCtx.Param("affiliateID"), 10, 64)
+	if err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid affiliate ID format"})
+		return
+	}
+
+	authPayload, exists := gCtx.Get(auth.AuthorizationPayloadKey)
+	if !exists {
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	payload := authPayload.(*auth.Claims)
+
+	ctx := gCtx.Request.Context()
+	affiliate, err := h.affiliateService.GetAffiliate(ctx, affiliateID, payload.UserID)
+	if err != nil {
+		if errors.Is(err, affiliates.ErrAffiliateNotFound) {
+			gCtx.JSON(http.StatusNotFound, gin.H{"error": "Affiliate not found"})
+		} else {
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve affiliate"})
+		}
+		return
+	}
+
+	sales, err := h.affiliateService.ListSales(ctx, affiliate.ID)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve affiliate sales"})
+		return
+	}
+	if sales == nil {
+		sales = []affiliates.Sale{}
+	}
+
+	gCtx.JSON(http.StatusOK, affiliates.EarningsResponse{
+		Affiliate: affiliate,
+		Sales:     sales,
+	})
+}
@@ -0,0 +1,78 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AvailabilityRequest defines the optional fields of a route creation request
+// that limit when the route can be used and how often it can be bought.
+type AvailabilityRequest struct {
+	AvailableFrom  string `form:"available_from" json:"available_from" binding:"omitempty"`   // Optional, RFC 3339 timestamp
+	AvailableUntil string `form:"available_until" json:"available_until" binding:"omitempty"` // Optional, RFC 3339 timestamp
+	MaxSales       uint64 `form:"max_sales" json:"max_sales" binding:"omitempty"`             // Optional, 0 means no limit
+}
+
+// RemainingStock returns how many more times a route with a sales limit can
+// be bought.
+func (r *PaidRoute) RemainingStock() uint64 {
+	if r.SoldCount >= r.MaxSales {
+		return 0
+	}
+	return r.MaxSales - r.SoldCount
+}
+
+// remainingStock returns the route's remaining stock for responses, nil if
+// it has no sales limit.
+func remainingStock(route *PaidRoute) *uint64 {
+	if route.MaxSales == 0 {
+		return nil
+	}
+	remaining := route.RemainingStock()
+	return &remaining
+}
+
+// CheckAvailability returns ErrRouteNotStarted or ErrRouteEnded if the
+// route's availability window is not open at now, and ErrRouteSoldOut if its
+// sales limit is reached.
+func (r *PaidRoute) CheckAvailability(now time.Time) error {
+	switch {
+	case r.AvailableFrom != nil && now.Before(*r.AvailableFrom):
+		return ErrRouteNotStarted
+	case r.AvailableUntil != nil && !now.Before(*r.AvailableUntil):
+		return ErrRouteEnded
+	case r.MaxSales > 0 && r.RemainingStock() == 0:
+		return ErrRouteSoldOut
+	}
+	return nil
+}
+
+// respondUnavailable sends the response for a route that is not available,
+// 425 Too Early with a Retry-After header before its availability window
+// opens, 410 Gone once it closed or the route sold out.
+func (h *PaidRouteHandler) respondUnavailable(gCtx *gin.Context, route *PaidRoute, err error) {
+	h.logger.Info("Request to unavailable route", "shortCode", route.ShortCode, "reason", err)
+
+	switch {
+	case errors.Is(err, ErrRouteNotStarted):
+		wait := h.paidRouteService.UntilAvailable(route)
+		gCtx.Header("Retry-After", strconv.FormatInt(int64(math.Ceil(wait.Seconds())), 10))
+		gCtx.JSON(http.StatusTooEarly, gin.H{
+			"error":          fmt.Sprintf("%s, it opens at %s", err, route.AvailableFrom.Format(time.RFC3339)),
+			"available_from": route.AvailableFrom,
+		})
+	case errors.Is(err, ErrRouteEnded):
+		gCtx.JSON(http.StatusGone, gin.H{
+			"error":           fmt.Sprintf("%s, it closed at %s", err, route.AvailableUntil.Format(time.RFC3339)),
+			"available_until": route.AvailableUntil,
+		})
+	default:
+		gCtx.JSON(http.StatusGone, gin.H{"error": err.Error(), "max_sales": route.MaxSales})
+	}
+}
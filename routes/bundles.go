@@ -0,0 +1,79 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/bundles"
+	"linkshrink/purchases"
+)
+
+// tryBundlePurchase uses a credit of the purchase of a bundle the route is
+// part of, if the request's payment header paid for one.
+// Returns:
+//   - usedExistingCredit: true if a credit of a bundle purchase was used.
+//   - proceedToNewPayment: true if the request has to pay for the route.
+func (h *PaidRouteHandler) tryBundlePurchase(gCtx *gin.Context, route *PaidRoute,
+	paymentHeader string) (usedExistingCredit bool, proceedToNewPayment bool) {
+
+	ctx := gCtx.Request.Context()
+	purchase, bundle, err := h.bundleService.FindPurchase(ctx, route.ID, paymentHeader)
+	if err != nil {
+		if errors.Is(err, bundles.ErrAccessExpired) {
+			h.logger.Info("Bundle purchase (via header) has expired. Proceeding to new payment.",
+				"shortCode", route.ShortCode, "purchaseID", purchase.ID)
+		} else if !errors.Is(err, purchases.ErrPurchaseNotFound) {
+			h.logger.Debug("Error checking for bundle purchase with payment header",
+				"shortCode", route.ShortCode, "error", err)
+		}
+		return false, true
+	}
+
+	if purchase.CreditsUsed >= purchase.CreditsAvailable {
+		h.logger.Info("Bundle purchase (via header) has no credits left. Proceeding to new payment.",
+			"shortCode", route.ShortCode, "purchaseID", purchase.ID)
+		return false, true
+	}
+
+	// The bundle's route earned the sale, so it earns the used credit too.
+	// Bundles only include routes of their own owner.
+	bundleRoute := &PaidRoute{
+		ID:        bundle.RouteID,
+		ShortCode: purchase.ShortCode,
+		UserID:    route.UserID,
+	}
+	_, err = h.purchaseService.IncrementCreditsUsed(ctx, creditUse(bundleRoute, purchase))
+	if err != nil {
+		h.logger.Error("Failed to increment credits_used for bundle purchase. Proceeding to new payment.",
+			"shortCode", route.ShortCode, "purchaseID", purchase.ID, "error", err)
+		return false, true
+	}
+	setStreamCharge(gCtx, h.purchaseStreamCharge(bundleRoute, purchase))
+
+	h.logger.Info("Successfully used a credit from bundle purchase via payment header.",
+		"shortCode", route.ShortCode, "bundleShortCode", purchase.ShortCode, "purchaseID", purchase.ID)
+	gCtx.Header(AccessHeader, AccessBundle)
+
+	if err := h.paidRouteService.IncrementAccessCount(ctx, route.ShortCode); err != nil {
+		h.logger.Error("Failed to increment access count (bundle purchase)",
+			"shortCode", route.ShortCode, "purchaseID", purchase.ID, "error", err)
+	}
+
+	return true, false
+}
+
+// serveBundle responds to a paid request to a bundle route with the routes
+// it unlocks, which accept the request's payment header until the purchase's
+// credits are used or it expires.
+func (h *PaidRouteHandler) serveBundle(gCtx *gin.Context, route *PaidRoute) {
+	response, err := h.bundleResponse(gCtx, route)
+	if err != nil {
+		h.logger.Error("Failed to retrieve bundle", "shortCode", route.ShortCode, "error", err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bundle"})
+		return
+	}
+
+	gCtx.JSON(http.StatusOK, response)
+}
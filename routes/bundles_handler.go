@@ -0,0 +1,187 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/auth"
+	"linkshrink/bundles"
+	"linkshrink/money"
+)
+
+// CreateBundleRequest defines the body for creating a bundle of routes.
+type CreateBundleRequest struct {
+	Title         string   `form:"title" json:"title" binding:"required"`
+	Description   string   `form:"description" json:"description" binding:"omitempty"`
+	Price         string   `form:"price" json:"price" binding:"required"` // In USDC, e.g. "5.00"
+	IsTest        bool     `form:"is_test" json:"is_test" binding:"omitempty"`
+	Type          string   `form:"type" json:"type" binding:"omitempty"`                     // Optional, defaults to "credit"
+	Credits       uint64   `form:"credits" json:"credits" binding:"omitempty"`               // Optional, defaults to 1
+	AccessSeconds uint64   `form:"access_seconds" json:"access_seconds" binding:"omitempty"` // Optional, 0 never expires
+	RouteIDs      []uint64 `form:"route_ids" json:"route_ids" binding:"required"`
+
+	AvailabilityRequest
+}
+
+// BundleMemberResponse represents a route a bundle unlocks.
+type BundleMemberResponse struct {
+	ShortCode    string  `json:"short_code"`
+	AccessURL    string  `json:"access_url"`
+	Method       string  `json:"method"`
+	ResourceType string  `json:"resource_type"`
+	Title        *string `json:"title,omitempty"`
+	Description  *string `json:"description,omitempty"`
+	Price        string  `json:"price"`
+	// PaymentHeader is the header requests to the route present the
+	// bundle's payment in.
+	PaymentHeader string `json:"payment_header"`
+}
+
+// BundleResponse represents a bundle and the routes it unlocks.
+type BundleResponse struct {
+	ID          uint64  `json:"id,omitempty"`
+	ShortCode   string  `json:"short_code"`
+	AccessURL   string  `json:"access_url"`
+	PageURL     string  `json:"page_url"`
+	Title       *string `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+
+	Price         string `json:"price"`
+	Credits       uint64 `json:"credits"`
+	AccessSeconds uint64 `json:"access_seconds"`
+	IsTest        bool   `json:"is_test"`
+
+	Members []BundleMemberResponse `json:"members"`
+}
+
+// bundleResponse describes a bundle route and the routes it unlocks.
+func (h *PaidRouteHandler) bundleResponse(gCtx *gin.Context, route *PaidRoute) (*BundleResponse, error) {
+	ctx := gCtx.Request.Context()
+	bundle, err := h.bundleService.GetBundle(ctx, route.ID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := h.bundleService.ListMembers(ctx, route.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := fmt.Sprintf("%s://%s", getRequestScheme(gCtx), gCtx.Request.Host)
+	response := &BundleResponse{
+		ShortCode:     route.ShortCode,
+		AccessURL:     fmt.Sprintf("%s/%s", baseURL, route.ShortCode),
+		PageURL:       fmt.Sprintf("%s/bundles/%s", baseURL, route.ShortCode),
+		Title:         route.Title,
+		Description:   route.Description,
+		Price:         money.USDC.Format(route.Price),
+		Credits:       route.Credits,
+		AccessSeconds: bundle.AccessSeconds,
+		IsTest:        route.IsTest,
+		Members:       make([]BundleMemberResponse, len(members)),
+	}
+	for i, member := range members {
+		response.Members[i] = BundleMemberResponse{
+			ShortCode:     member.ShortCode,
+			AccessURL:     fmt.Sprintf("%s/%s", baseURL, member.ShortCode),
+			Method:        member.Method,
+			ResourceType:  member.ResourceType,
+			Title:         member.Title,
+			Description:   member.Description,
+			Price:         money.USDC.Format(member.Price),
+			PaymentHeader: getPaymentHeaderNameForVersion(member.PaymentProtocolVersion),
+		}
+	}
+
+	return response, nil
+}
+
+// CreateBundleHandler handles POST requests to create a bundle of the user's
+// routes, sold under its own short code and price.
+func (h *PaidRouteHandler) CreateBundleHandler(gCtx *gin.Context) {
+	var req CreateBundleRequest
+	if err := gCtx.ShouldBind(&req); err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	setDefaultTypeAndCredits(&req.Type, &req.Credits)
+
+	if _, err := money.USDC.Parse(req.Price); err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid price: " + err.Error()})
+		return
+	}
+
+	authPayload, exists := gCtx.Get(auth.AuthorizationPayloadKey)
+	if !exists {
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	payload := authPayload.(*auth.Claims)
+
+	route, err := h.paidRouteService.CreateBundleRoute(gCtx.Request.Context(), &req, payload.UserID)
+	if err != nil {
+		if errors.Is(err, bundles.ErrNoMembers) || errors.Is(err, bundles.ErrTooManyMembers) ||
+			errors.Is(err, bundles.ErrInvalidMember) || errors.Is(err, ErrInvalidAvailability) {
+
+			gCtx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create bundle"})
+		}
+		return
+	}
+
+	response, err := h.bundleResponse(gCtx, route)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bundle"})
+		return
+	}
+	response.ID = route.ID
+
+	gCtx.JSON(http.StatusCreated, response)
+}
+
+// GetBundlePage handles public GET requests for a bundle's landing page,
+// which lists the routes the bundle unlocks. Browsers get an HTML page, other
+// clients the bundle as JSON.
+func (h *PaidRouteHandler) GetBundlePage(gCtx *gin.Context) {
+	shortCode := gCtx.Param("shortCode")
+
+	route, err := h.paidRouteService.FindEnabledRouteByShortCode(gCtx.Request.Context(), shortCode)
+	if err == nil && route.ResourceType != ResourceTypeBundle {
+		err = bundles.ErrBundleNotFound
+	}
+	if err != nil {
+		if errors.Is(err, ErrRouteNotFound) || errors.Is(err, bundles.ErrBundleNotFound) {
+			gCtx.JSON(http.StatusNotFound, gin.H{"error": "Bundle not found or is disabled."})
+		} else {
+			h.logger.Error("Error retrieving bundle route", "shortCode", shortCode, "error", err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving bundle."})
+		}
+		return
+	}
+
+	response, err := h.bundleResponse(gCtx, route)
+	if err != nil {
+		h.logger.Error("Failed to retrieve bundle", "shortCode", shortCode, "error", err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bundle"})
+		return
+	}
+
+	isWebBrowser := strings.Contains(gCtx.GetHeader("Accept"), "text/html") &&
+		strings.Contains(gCtx.GetHeader("User-Agent"), "Mozilla")
+	if !isWebBrowser {
+		gCtx.JSON(http.StatusOK, response)
+		return
+	}
+
+	gCtx.HTML(http.StatusOK, "bundle.html", gin.H{
+		"Bundle":   response,
+		"CoverURL": route.CoverImageURL,
+	})
+}
@@ -6,6 +6,11 @@ func DefaultConfig() *Config {
 		X402PaymentAddress:    "",
 		X402FacilitatorURL:    "https://x402.org/facilitator",
 		X402MaxTimeoutSeconds: 300,
+		QuoteTimeoutSeconds:   5,
+		OwnerPreview:          true,
+		Discovery:             true,
+
+		ScheduleIntervalSeconds: 30,
 	}
 }
 
@@ -16,4 +21,9 @@ type Config struct {
 	X402MaxTimeoutSeconds int    `long:"x402_max_timeout_seconds" description:"Max timeout seconds for X402"`
 	CDPAPIKeyID           string `long:"cdp_api_key_id" description:"API key ID for CDP"`
 	CDPAPIKeySecret       string `long:"cdp_api_key_secret" description:"API key secret for CDP"`
+	QuoteTimeoutSeconds   int    `long:"quote_timeout_seconds" description:"Timeout for calls to route quote hooks"`
+	OwnerPreview          bool   `long:"owner_preview" description:"Let route owners with a dashboard session through without paying"`
+	Discovery             bool   `long:"discovery" description:"List routes opted in to discovery at /discovery/resources"`
+
+	ScheduleIntervalSeconds int `long:"schedule_interval_seconds" description:"How often scheduled price changes and availability windows are applied, 0 disables it"`
 }
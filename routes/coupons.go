@@ -0,0 +1,164 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/coupons"
+	"linkshrink/purchases"
+)
+
+const (
+	// CouponHeader carries the coupon code a buyer redeems, alternatively
+	// passed as the CouponQueryParam query parameter.
+	CouponHeader     = "Proxy402-Coupon"
+	CouponQueryParam = "coupon"
+	// TrialRemainingHeader reports how many free trial requests the client
+	// has left on a route.
+	TrialRemainingHeader = "Proxy402-Trial-Remaining"
+)
+
+// tryTrialRequest uses one of the route's free trial requests for a client
+// that did not send a payment. Returns true if the request is free.
+func (h *PaidRouteHandler) tryTrialRequest(gCtx *gin.Context, route *PaidRoute) bool {
+	// Metered routes charge usage against a prepaid balance, which a trial
+	// request does not have.
+	if route.BillingMode == BillingModeMetered ||
+		gCtx.GetHeader(getPaymentHeaderNameForRoute(route)) != "" {
+
+		return false
+	}
+
+	client := gCtx.ClientIP()
+	remaining, ok, err := h.couponService.UseTrial(gCtx.Request.Context(), route.ID, client)
+	if err != nil {
+		// Fall back to asking for payment.
+		h.logger.Error("Failed to use trial request", "shortCode", route.ShortCode, "error", err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+
+	h.logger.Debug("Serving free trial request", "shortCode", route.ShortCode, "remaining", remaining)
+	gCtx.Header(TrialRemainingHeader, strconv.FormatUint(remaining, 10))
+	stripCoupon(gCtx)
+
+	// Every time slice of a stream session uses another free request.
+	setStreamCharge(gCtx, func(ctx context.Context) error {
+		_, ok, err := h.couponService.UseTrial(ctx, route.ID, client)
+		if err == nil && !ok {
+			return purchases.ErrNoCreditsLeft
+		}
+		return err
+	})
+
+	err = h.paidRouteService.IncrementAccessCount(gCtx.Request.Context(), route.ShortCode)
+	if err != nil {
+		h.logger.Error("Failed to increment access count (trial request)",
+			"shortCode", route.ShortCode, "error", err)
+	}
+
+	return true
+}
+
+// stripCoupon removes the coupon code from the request, it is meant for the
+// proxy, not the upstream.
+func stripCoupon(gCtx *gin.Context) {
+	gCtx.Request.Header.Del(CouponHeader)
+
+	query := gCtx.Request.URL.Query()
+	if query.Has(CouponQueryParam) {
+		query.Del(CouponQueryParam)
+		gCtx.Request.URL.RawQuery = query.Encode()
+	}
+}
+
+// resolveCoupon looks up the coupon the request redeems, if any. It sends an
+// error response and returns requestHandled=true if the coupon can't be used.
+func (h *PaidRouteHandler) resolveCoupon(gCtx *gin.Context,
+	route *PaidRoute) (coupon *coupons.Coupon, requestHandled bool) {
+
+	query := gCtx.Request.URL.Query()
+	code := gCtx.GetHeader(CouponHeader)
+	if code == "" {
+		code = query.Get(CouponQueryParam)
+	}
+	if code == "" {
+		return nil, false
+	}
+
+	stripCoupon(gCtx)
+
+	coupon, err := h.couponService.FindCoupon(gCtx.Request.Context(), route.UserID, route.ID, code)
+	if err != nil {
+		if errors.Is(err, coupons.ErrCouponNotFound) || errors.Is(err, coupons.ErrCouponUnavailable) {
+			gCtx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			h.logger.Error("Failed to look up coupon", "shortCode", route.ShortCode, "error", err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up coupon"})
+		}
+		return nil, true
+	}
+
+	return coupon, false
+}
+
+// applyCoupon returns the route priced with the coupon's discount and the
+// discount.
+func applyCoupon(route *PaidRoute, coupon *coupons.Coupon) (*PaidRoute, uint64) {
+	if coupon == nil {
+		return route, 0
+	}
+
+	discount := coupon.Discount(route.Price)
+	discountedRoute := *route
+	discountedRoute.Price -= discount
+
+	return &discountedRoute, discount
+}
+
+// redeemFreeRequest redeems a coupon that makes the request free, in which
+// case no payment is asked for.
+// Returns:
+//   - redeemed: true if the request may be proxied without payment.
+//   - requestHandled: true if an error response was sent.
+func (h *PaidRouteHandler) redeemFreeRequest(gCtx *gin.Context, route *PaidRoute,
+	coupon *coupons.Coupon, discount uint64) (redeemed bool, requestHandled bool) {
+
+	if route.BillingMode == BillingModeMetered {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Coupon cannot make a metered route free"})
+		return false, true
+	}
+
+	err := h.couponService.Redeem(gCtx.Request.Context(), &coupons.Redemption{
+		CouponID: coupon.ID,
+		RouteID:  route.ID,
+		Discount: discount,
+	})
+	if err != nil {
+		if errors.Is(err, coupons.ErrCouponUnavailable) {
+			gCtx.JSON(http.StatusBadRequest, gin.H{"error": coupons.ErrCouponUnavailable.Error()})
+		} else {
+			h.logger.Error("Failed to redeem coupon", "shortCode", route.ShortCode,
+				"couponID", coupon.ID, "error", err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to redeem coupon"})
+		}
+		return false, true
+	}
+
+	h.logger.Info("Serving request made free by coupon", "shortCode", route.ShortCode,
+		"couponID", coupon.ID)
+
+	err = h.paidRouteService.IncrementAccessCount(gCtx.Request.Context(), route.ShortCode)
+	if err != nil {
+		h.logger.Error("Failed to increment access count (free coupon)",
+			"shortCode", route.ShortCode, "error", err)
+	}
+
+	return true, false
+}
@@ -0,0 +1,239 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/auth"
+	"linkshrink/coupons"
+	"linkshrink/money"
+)
+
+// CreateCouponRequest defines the body for creating a coupon. Exactly one of
+// PercentOffBPS and AmountOff is set.
+type CreateCouponRequest struct {
+	Code           string     `form:"code" json:"code" binding:"required"`
+	RouteID        uint64     `form:"route_id" json:"route_id" binding:"omitempty"`               // Optional, all of the owner's routes if empty
+	PercentOffBPS  uint64     `form:"percent_off_bps" json:"percent_off_bps" binding:"omitempty"` // Basis points, 2500 = 25% off
+	AmountOff      string     `form:"amount_off" json:"amount_off" binding:"omitempty"`           // Fixed discount in USDC, e.g. "0.01"
+	MaxRedemptions uint64     `form:"max_redemptions" json:"max_redemptions" binding:"omitempty"` // Optional, 0 means no limit
+	ExpiresAt      *time.Time `form:"expires_at" json:"expires_at" binding:"omitempty"`           // Optional, RFC 3339
+}
+
+// UpdateTrialRequest defines the body for setting a route's free trial.
+type UpdateTrialRequest struct {
+	FreeRequests uint64 `form:"free_requests" json:"free_requests"` // Free requests per client, 0 ends the trial
+}
+
+// CouponResponse represents a coupon of the user.
+type CouponResponse struct {
+	ID              uint64     `json:"id"`
+	Code            string     `json:"code"`
+	RouteID         uint64     `json:"route_id,omitempty"`
+	DiscountType    string     `json:"discount_type"`
+	PercentOffBPS   uint64     `json:"percent_off_bps,omitempty"`
+	AmountOff       string     `json:"amount_off,omitempty"`
+	MaxRedemptions  uint64     `json:"max_redemptions"`
+	RedemptionCount uint64     `json:"redemption_count"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// newCouponResponse converts a coupon to its API representation.
+func newCouponResponse(coupon *coupons.Coupon) CouponResponse {
+	response := CouponResponse{
+		ID:              coupon.ID,
+		Code:            coupon.Code,
+		RouteID:         coupon.RouteID,
+		DiscountType:    coupon.DiscountType,
+		MaxRedemptions:  coupon.MaxRedemptions,
+		RedemptionCount: coupon.RedemptionCount,
+		ExpiresAt:       coupon.ExpiresAt,
+		CreatedAt:       coupon.CreatedAt,
+	}
+	if coupon.DiscountType == coupons.DiscountPercent {
+		response.PercentOffBPS = coupon.DiscountValue
+	} else {
+		response.AmountOff = money.USDC.Format(coupon.DiscountValue)
+	}
+	return response
+}
+
+// GetCoupons handles GET requests for the coupons of the user.
+func (h *PaidRouteHandler) GetCoupons(gCtx *gin.Context) {
+	authPayload, exists := gCtx.Get(auth.AuthorizationPayloadKey)
+	if !exists {
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	payload := authPayload.(*auth.Claims)
+
+	userCoupons, err := h.couponService.ListCoupons(gCtx.Request.Context(), payload.UserID)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve coupons"})
+		return
+	}
+
+	response := make([]CouponResponse, len(userCoupons))
+	for i := range userCoupons {
+		response[i] = newCouponResponse(&userCoupons[i])
+	}
+
+	gCtx.JSON(http.StatusOK, gin.H{"coupons": response})
+}
+
+// CreateCoupon handles POST requests to create a coupon for one or all of
+// the user's routes.
+func (h *PaidRouteHandler) CreateCoupon(gCtx *gin.Context) {
+	var req CreateCouponRequest
+	if err := gCtx.ShouldBind(&req); err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	authPayload, exists := gCtx.Get(auth.AuthorizationPayloadKey)
+	if !exists {
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	payload := authPayload.(*auth.Claims)
+
+	coupon := &coupons.Coupon{
+		UserID:         payload.UserID,
+		RouteID:        req.RouteID,
+		Code:           req.Code,
+		MaxRedemptions: req.MaxRedemptions,
+		ExpiresAt:      req.ExpiresAt,
+	}
+
+	switch {
+	case req.PercentOffBPS > 0 && req.AmountOff == "":
+		coupon.DiscountType = coupons.DiscountPercent
+		coupon.DiscountValue = req.PercentOffBPS
+
+	case req.PercentOffBPS == 0 && req.AmountOff != "":
+		amountOff, err := money.USDC.Parse(req.AmountOff)
+		if err != nil {
+			gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount_off: " + err.Error()})
+			return
+		}
+		coupon.DiscountType = coupons.DiscountFixed
+		coupon.DiscountValue = amountOff
+
+	default:
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Set exactly one of percent_off_bps and amount_off"})
+		return
+	}
+
+	ctx := gCtx.Request.Context()
+	if req.RouteID != 0 {
+		_, err := h.paidRouteService.GetUserRoute(ctx, req.RouteID, payload.UserID)
+		if err != nil {
+			if errors.Is(err, ErrRouteNotFound) || errors.Is(err, ErrRouteNoPermission) {
+				gCtx.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+			} else {
+				gCtx.Error(err)
+				gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve route"})
+			}
+			return
+		}
+	}
+
+	created, err := h.couponService.CreateCoupon(ctx, coupon)
+	if err != nil {
+		switch {
+		case errors.Is(err, coupons.ErrCodeTaken):
+			gCtx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, coupons.ErrInvalidCode), errors.Is(err, coupons.ErrInvalidDiscount),
+			errors.Is(err, coupons.ErrInvalidExpiry):
+
+			gCtx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create coupon"})
+		}
+		return
+	}
+
+	gCtx.JSON(http.StatusCreated, newCouponResponse(created))
+}
+
+// DeleteCoupon handles DELETE requests for a coupon of the user.
+func (h *PaidRouteHandler) DeleteCoupon(gCtx *gin.Context) {
+	couponID, err := strconv.ParseUint(gCtx.Param("couponID"), 10, 64)
+	if err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid coupon ID format"})
+		return
+	}
+
+	authPayload, exists := gCtx.Get(auth.AuthorizationPayloadKey)
+	if !exists {
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	payload := authPayload.(*auth.Claims)
+
+	err = h.couponService.DeleteCoupon(gCtx.Request.Context(), couponID, payload.UserID)
+	if err != nil {
+		if errors.Is(err, coupons.ErrCouponNotFound) {
+			gCtx.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		} else {
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete coupon"})
+		}
+		return
+	}
+
+	gCtx.Status(http.StatusOK)
+}
+
+// GetRouteTrial handles GET requests for the free trial of a route.
+func (h *PaidRouteHandler) GetRouteTrial(gCtx *gin.Context) {
+	route := h.getOwnedRoute(gCtx)
+	if route == nil {
+		return
+	}
+
+	freeRequests, err := h.couponService.GetTrial(gCtx.Request.Context(), route.ID)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve trial"})
+		return
+	}
+
+	gCtx.JSON(http.StatusOK, gin.H{"free_requests": freeRequests})
+}
+
+// UpdateRouteTrial handles PUT requests to set how many requests to a route
+// each client gets for free before having to pay.
+func (h *PaidRouteHandler) UpdateRouteTrial(gCtx *gin.Context) {
+	var req UpdateTrialRequest
+	if err := gCtx.ShouldBind(&req); err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	route := h.getOwnedRoute(gCtx)
+	if route == nil {
+		return
+	}
+
+	if req.FreeRequests > 0 && route.BillingMode == BillingModeMetered {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Metered routes can't have a free trial"})
+		return
+	}
+
+	err := h.couponService.SetTrial(gCtx.Request.Context(), route.ID, req.FreeRequests)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update trial"})
+		return
+	}
+
+	gCtx.JSON(http.StatusOK, gin.H{"free_requests": req.FreeRequests})
+}
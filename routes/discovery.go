@@ -0,0 +1,150 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"path/filepath"
+
+	"github.com/coinbase/x402/go/extensions/bazaar"
+	extensiontypes "github.com/coinbase/x402/go/extensions/types"
+)
+
+const (
+	// DefaultDiscoveryLimit is the page size of the discovery list when none
+	// is asked for.
+	DefaultDiscoveryLimit = 20
+	// MaxDiscoveryLimit is the largest page size of the discovery list.
+	MaxDiscoveryLimit = 100
+)
+
+// DiscoveryFilter selects the routes listed for discovery.
+type DiscoveryFilter struct {
+	// Method, empty lists routes of any method.
+	Method string
+	// IsTest lists the routes of testnet or mainnet, nil lists both.
+	IsTest *bool
+	// MaxPrice is in base units (USDC * 10^6), 0 means no limit.
+	MaxPrice uint64
+	// PayTo lists the routes paying an address, empty lists any.
+	PayTo string
+	// DefaultPayTo is the address routes of owners without a payment
+	// address pay to.
+	DefaultPayTo string
+	// Search matches the title and description of routes, empty lists any.
+	Search string
+
+	Limit  uint64
+	Offset uint64
+}
+
+// validateMimeType checks the media type a route declares, if any.
+func validateMimeType(mimeType string) error {
+	if mimeType == "" {
+		return nil
+	}
+	if _, _, err := mime.ParseMediaType(mimeType); err != nil {
+		return fmt.Errorf("invalid MIME type: %w", err)
+	}
+	return nil
+}
+
+// routeMimeType returns the media type of the route's responses, guessed
+// from the name of file routes that don't declare one. Empty if unknown.
+func routeMimeType(route *PaidRoute) string {
+	if route.MimeType != "" {
+		return route.MimeType
+	}
+	if route.ResourceType == "file" && route.OriginalFilename != nil {
+		return mime.TypeByExtension(filepath.Ext(*route.OriginalFilename))
+	}
+	return ""
+}
+
+// discoveryOutputSchema returns the outputSchema of the route's x402 v1
+// payment requirements, which describes how the route is called, and with the
+// route's schemas what it takes and responds with, in the format bazaar
+// facilitators catalog resources with.
+func discoveryOutputSchema(route *PaidRoute) *json.RawMessage {
+	input := map[string]any{
+		"type":         "http",
+		"method":       route.Method,
+		"discoverable": route.Discoverable,
+	}
+	if len(route.QuerySchema) > 0 {
+		input["queryParams"] = route.QuerySchema
+	}
+	if len(route.BodySchema) > 0 {
+		input["bodyType"] = "json"
+		input["bodyFields"] = route.BodySchema
+	}
+
+	schema := map[string]any{"input": input}
+	if len(route.ResponseSchema) > 0 {
+		schema["output"] = route.ResponseSchema
+	}
+
+	outputSchema, err := json.Marshal(schema)
+	if err != nil {
+		return nil
+	}
+	raw := json.RawMessage(outputSchema)
+	return &raw
+}
+
+// discoveryExtensions returns the extensions of the route's x402 v2 payment
+// required responses, the bazaar declaration of discoverable routes.
+func discoveryExtensions(route *PaidRoute) map[string]interface{} {
+	if !route.Discoverable {
+		return nil
+	}
+
+	extension, err := schemaDiscoveryExtension(route)
+	if err != nil || !bazaar.ValidateDiscoveryExtension(extension).Valid {
+		// Facilitators drop declarations whose examples don't match their
+		// schemas, so the route is declared without them.
+		extension, err = bazaar.DeclareDiscoveryExtension(route.Method, nil, nil, "", nil)
+		if err != nil {
+			return nil
+		}
+	}
+	return map[string]interface{}{bazaar.BAZAAR: extension}
+}
+
+// schemaDiscoveryExtension declares the route with its schemas, the input
+// schema being the body schema of body methods and the query schema of the
+// others. The first of the examples of each schema is the example of the
+// declaration.
+func schemaDiscoveryExtension(route *PaidRoute) (extensiontypes.DiscoveryExtension, error) {
+	inputSchema := schemaObject(route.QuerySchema)
+	if isBodyMethod(route.Method) {
+		inputSchema = schemaObject(route.BodySchema)
+	}
+	responseSchema := schemaObject(route.ResponseSchema)
+
+	var output *extensiontypes.OutputConfig
+	if example := schemaExample(responseSchema); example != nil {
+		output = &extensiontypes.OutputConfig{Example: example, Schema: responseSchema}
+	}
+
+	extension, err := bazaar.DeclareDiscoveryExtension(route.Method, schemaExample(inputSchema),
+		inputSchema, "", output)
+	if err != nil {
+		return extension, err
+	}
+
+	// Without an example the response schema is declared on its own.
+	if output == nil && responseSchema != nil {
+		if properties, ok := extension.Schema["properties"].(map[string]interface{}); ok {
+			properties["output"] = map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"type":    map[string]interface{}{"type": "string"},
+					"example": responseSchema,
+				},
+				"required": []string{"type"},
+			}
+		}
+	}
+	return extension, nil
+}
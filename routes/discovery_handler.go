@@ -0,0 +1,238 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	x402types "github.com/coinbase/x402/go/types"
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/money"
+	"linkshrink/x402"
+)
+
+// DiscoveryResource is a route in the discovery list, in the format of the
+// x402 bazaar discovery list.
+type DiscoveryResource struct {
+	Resource    string `json:"resource"`
+	Type        string `json:"type"`
+	X402Version int    `json:"x402Version"`
+	// Accepts holds payment requirements of the route's x402 version.
+	Accepts     any                    `json:"accepts"`
+	Extensions  map[string]interface{} `json:"extensions,omitempty"`
+	LastUpdated time.Time              `json:"lastUpdated"`
+	Metadata    map[string]any         `json:"metadata,omitempty"`
+}
+
+// DiscoveryPagination is the position of a page in the discovery list.
+type DiscoveryPagination struct {
+	Limit  uint64 `json:"limit"`
+	Offset uint64 `json:"offset"`
+	Total  uint64 `json:"total"`
+}
+
+// DiscoveryResponse is a page of the discovery list.
+type DiscoveryResponse struct {
+	X402Version int                 `json:"x402Version"`
+	Items       []DiscoveryResource `json:"items"`
+	Pagination  DiscoveryPagination `json:"pagination"`
+}
+
+// ListDiscoveryResources handles GET requests for the public list of the
+// routes opted in to discovery. The list is filtered by the type, method,
+// network, max_price (in USDC), pay_to and q (searched in titles and
+// descriptions) query parameters and paged with limit and offset.
+func (h *PaidRouteHandler) ListDiscoveryResources(gCtx *gin.Context) {
+	if !h.config.Discovery {
+		gCtx.JSON(http.StatusNotFound, gin.H{"error": "Discovery is disabled"})
+		return
+	}
+
+	filter, err := parseDiscoveryFilter(gCtx)
+	if err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.DefaultPayTo = h.config.X402PaymentAddress
+
+	response := DiscoveryResponse{
+		X402Version: 1,
+		Items:       []DiscoveryResource{},
+		Pagination: DiscoveryPagination{
+			Limit:  filter.Limit,
+			Offset: filter.Offset,
+		},
+	}
+
+	// Routes are only ever called over HTTP.
+	if resourceType := gCtx.Query("type"); resourceType != "" && resourceType != "http" {
+		gCtx.JSON(http.StatusOK, response)
+		return
+	}
+
+	ctx := gCtx.Request.Context()
+	routes, total, err := h.paidRouteService.ListDiscoverableRoutes(ctx, filter)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve routes"})
+		return
+	}
+	response.Pagination.Total = total
+
+	// Owners usually have several routes on a page.
+	payTo := make(map[uint64]string)
+	for i := range routes {
+		route := &routes[i]
+
+		address, ok := payTo[route.UserID]
+		if !ok {
+			user, err := h.userService.GetUserByID(ctx, route.UserID)
+			if err != nil {
+				gCtx.Error(err)
+				gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve routes"})
+				return
+			}
+			address = h.config.X402PaymentAddress
+			if user.PaymentAddress != "" {
+				address = user.PaymentAddress
+			}
+			payTo[route.UserID] = address
+		}
+
+		resource, err := h.discoveryResource(gCtx, route, address)
+		if err != nil {
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve routes"})
+			return
+		}
+		response.Items = append(response.Items, *resource)
+	}
+
+	gCtx.JSON(http.StatusOK, response)
+}
+
+// parseDiscoveryFilter reads the filter of the discovery list from the query
+// parameters.
+func parseDiscoveryFilter(gCtx *gin.Context) (*DiscoveryFilter, error) {
+	filter := &DiscoveryFilter{
+		Method: strings.ToUpper(gCtx.Query("method")),
+		PayTo:  gCtx.Query("pay_to"),
+		Search: gCtx.Query("q"),
+		Limit:  DefaultDiscoveryLimit,
+	}
+
+	// Networks are accepted by their x402 v1 name and CAIP-2 ID.
+	switch network := gCtx.Query("network"); network {
+	case "":
+	case "base", "eip155:8453":
+		isTest := false
+		filter.IsTest = &isTest
+	case "base-sepolia", "eip155:84532":
+		isTest := true
+		filter.IsTest = &isTest
+	default:
+		return nil, fmt.Errorf("unsupported network %q", network)
+	}
+
+	if maxPrice := gCtx.Query("max_price"); maxPrice != "" {
+		var err error
+		if filter.MaxPrice, err = money.USDC.Parse(maxPrice); err != nil {
+			return nil, fmt.Errorf("invalid max_price: %w", err)
+		}
+	}
+
+	if limit := gCtx.Query("limit"); limit != "" {
+		value, err := strconv.ParseUint(limit, 10, 64)
+		if err != nil || value == 0 {
+			return nil, errors.New("limit must be a positive integer")
+		}
+		filter.Limit = min(value, MaxDiscoveryLimit)
+	}
+
+	if offset := gCtx.Query("offset"); offset != "" {
+		value, err := strconv.ParseUint(offset, 10, 64)
+		if err != nil || value > math.MaxInt32 {
+			return nil, errors.New("invalid offset")
+		}
+		filter.Offset = value
+	}
+
+	return filter, nil
+}
+
+// discoveryResource describes a route in the discovery list, with the
+// payment requirements its payment required responses carry.
+func (h *PaidRouteHandler) discoveryResource(gCtx *gin.Context, route *PaidRoute,
+	payTo string) (*DiscoveryResource, error) {
+
+	accessURL := fmt.Sprintf("%s://%s/%s", getRequestScheme(gCtx), gCtx.Request.Host, route.ShortCode)
+	mimeType := routeMimeType(route)
+
+	resource := &DiscoveryResource{
+		Resource:    accessURL,
+		Type:        "http",
+		LastUpdated: route.UpdatedAt,
+		Metadata: map[string]any{
+			"method":  route.Method,
+			"credits": route.Credits,
+			"pricing": discoveryPricing(route),
+		},
+	}
+	if route.Title != nil {
+		resource.Metadata["title"] = *route.Title
+	}
+	if route.Description != nil {
+		resource.Metadata["description"] = *route.Description
+	}
+	if mimeType != "" {
+		resource.Metadata["mimeType"] = mimeType
+	}
+
+	options := []x402.Options{
+		x402.WithTestnet(route.IsTest),
+		x402.WithMaxTimeoutSeconds(h.config.X402MaxTimeoutSeconds),
+	}
+
+	switch getRoutePaymentVersion(route) {
+	case PaymentProtocolVersionV1:
+		requirements, err := x402.RequirementsV1(route.Price, payTo, append(options,
+			x402.WithResource(accessURL),
+			x402.WithDescription(fmt.Sprintf("Payment for %s %s", route.Method, accessURL)),
+			x402.WithMimeType(mimeType),
+			x402.WithOutputSchema(discoveryOutputSchema(route)),
+		)...)
+		if err != nil {
+			return nil, err
+		}
+		resource.X402Version = 1
+		resource.Accepts = []x402types.PaymentRequirementsV1{requirements}
+	default:
+		resource.X402Version = 2
+		resource.Accepts = []x402types.PaymentRequirements{
+			x402.RequirementsV2(route.Price, payTo, options...),
+		}
+		resource.Extensions = discoveryExtensions(route)
+	}
+
+	return resource, nil
+}
+
+// discoveryPricing describes how the route is priced, its listed price is
+// only what it costs for fixed priced routes.
+func discoveryPricing(route *PaidRoute) string {
+	switch {
+	case route.BillingMode == BillingModeMetered:
+		return "metered"
+	case route.QuoteURL != "":
+		return "quote"
+	case route.PayWhatYouWant:
+		return "pay_what_you_want"
+	default:
+		return "fixed"
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"math/big"
 	"mime/multipart"
 	"net/http"
 	"net/http/httputil"
@@ -23,9 +22,23 @@ import (
 	x402evmserver "github.com/coinbase/x402/go/mechanisms/evm/exact/server"
 	"github.com/gin-gonic/gin"
 
+	"linkshrink/affiliates"
 	"linkshrink/auth"
+	"linkshrink/bundles"
+	"linkshrink/cache"
+	"linkshrink/coupons"
+	"linkshrink/idempotency"
+	"linkshrink/ledger"
+	"linkshrink/money"
+	"linkshrink/packs"
+	"linkshrink/payers"
 	"linkshrink/purchases"
+	"linkshrink/rails"
+	"linkshrink/reconciler"
+	"linkshrink/splits"
+	"linkshrink/upstreams"
 	"linkshrink/users"
+	"linkshrink/wallets"
 	"linkshrink/x402"
 )
 
@@ -34,7 +47,20 @@ type PaidRouteHandler struct {
 	paidRouteService *PaidRouteService
 	purchaseService  *purchases.PurchaseService
 	userService      *users.UserService
-	priceUtils       PriceUtils
+	responseCache    *cache.Cache
+	upstreamService  *upstreams.Service
+	splitsService    *splits.Service
+	ledgerService    *ledger.Service
+	reconciler       *reconciler.Reconciler
+	idempotency      *idempotency.Service
+	couponService    *coupons.Service
+	payerService     *payers.Service
+	packService      *packs.Service
+	walletService    *wallets.Service
+	bundleService    *bundles.Service
+	affiliateService *affiliates.Service
+	paymentRails     map[string]rails.Rail
+	authenticator    auth.Authenticator
 
 	config *Config
 	logger *slog.Logger
@@ -43,13 +69,37 @@ type PaidRouteHandler struct {
 // NewPaidRouteHandler creates a new PaidRouteHandler.
 func NewPaidRouteHandler(routeService *PaidRouteService,
 	purchaseService *purchases.PurchaseService, userService *users.UserService,
-	config *Config, logger *slog.Logger) *PaidRouteHandler {
+	responseCache *cache.Cache, upstreamService *upstreams.Service,
+	splitsService *splits.Service, ledgerService *ledger.Service,
+	settlementReconciler *reconciler.Reconciler, idempotencyService *idempotency.Service,
+	couponService *coupons.Service, payerService *payers.Service, packService *packs.Service,
+	walletService *wallets.Service, bundleService *bundles.Service,
+	affiliateService *affiliates.Service, paymentRails []rails.Rail,
+	authenticator auth.Authenticator, config *Config, logger *slog.Logger) *PaidRouteHandler {
+
+	railsByName := make(map[string]rails.Rail, len(paymentRails))
+	for _, rail := range paymentRails {
+		railsByName[rail.Name()] = rail
+	}
 
 	return &PaidRouteHandler{
 		paidRouteService: routeService,
 		purchaseService:  purchaseService,
 		userService:      userService,
-		priceUtils:       NewPriceUtils(),
+		responseCache:    responseCache,
+		upstreamService:  upstreamService,
+		splitsService:    splitsService,
+		ledgerService:    ledgerService,
+		reconciler:       settlementReconciler,
+		idempotency:      idempotencyService,
+		couponService:    couponService,
+		payerService:     payerService,
+		packService:      packService,
+		walletService:    walletService,
+		bundleService:    bundleService,
+		affiliateService: affiliateService,
+		paymentRails:     railsByName,
+		authenticator:    authenticator,
 
 		config: config,
 		logger: logger,
@@ -107,6 +157,32 @@ type CreatePaidRouteRequest struct {
 	CoverImage  *multipart.FileHeader `form:"cover_image" binding:"omitempty"`
 	Title       string                `form:"title" binding:"omitempty"`       // Optional title
 	Description string                `form:"description" binding:"omitempty"` // Optional description
+
+	CacheEnabled     bool   `form:"cache_enabled" binding:"omitempty"`      // Optional, cache upstream responses
+	CacheTTLSeconds  uint64 `form:"cache_ttl_seconds" binding:"omitempty"`  // Optional, 0 uses the default TTL
+	CacheVaryHeaders string `form:"cache_vary_headers" binding:"omitempty"` // Optional, comma separated header names
+
+	StreamMaxSeconds    uint64 `form:"stream_max_seconds" binding:"omitempty"`    // Optional, 0 means no session limit
+	StreamCreditSeconds uint64 `form:"stream_credit_seconds" binding:"omitempty"` // Optional, 0 charges one credit per connection
+
+	BillingMode string `form:"billing_mode" binding:"omitempty"` // Optional, "fixed" (default) or "metered"
+	UnitPrice   string `form:"unit_price" binding:"omitempty"`   // Required for metered routes, price per usage unit
+
+	QuoteURL       string `form:"quote_url" binding:"omitempty,url"`     // Optional, prices each request dynamically
+	PayWhatYouWant bool   `form:"pay_what_you_want" binding:"omitempty"` // Optional, the buyer chooses the amount and price is the suggested one
+	MinPrice       string `form:"min_price" binding:"omitempty"`         // Optional, lower bound for quoted and chosen prices
+	MaxPrice       string `form:"max_price" binding:"omitempty"`         // Optional, upper bound for quoted and chosen prices
+
+	PaymentRails []string `form:"payment_rails" binding:"omitempty"` // Optional, payment rails accepted besides x402, such as "l402"
+
+	Discoverable bool   `form:"discoverable" binding:"omitempty"` // Optional, lists the route on the public discovery endpoint
+	MimeType     string `form:"mime_type" binding:"omitempty"`    // Optional, media type of the route's responses
+
+	BodySchema     string `form:"body_schema" binding:"omitempty"`     // Optional, JSON Schema requests bodies are validated against
+	QuerySchema    string `form:"query_schema" binding:"omitempty"`    // Optional, JSON Schema query parameters are validated against
+	ResponseSchema string `form:"response_schema" binding:"omitempty"` // Optional, JSON Schema of upstream responses, checked for test routes
+
+	AvailabilityRequest
 }
 
 // Validate performs business rule validation for the route creation request.
@@ -124,9 +200,89 @@ func (r *CreatePaidRouteRequest) Validate() error {
 		return errors.New("invalid HTTP method provided")
 	}
 
+	if r.CacheEnabled && upperMethod != "GET" {
+		return errors.New("response caching is only supported for GET routes")
+	}
+
+	switch r.BillingMode {
+	case "", BillingModeFixed:
+	case BillingModeMetered:
+		// Cached responses never reach the upstream, so there is no usage to charge.
+		if r.CacheEnabled {
+			return errors.New("response caching is not supported for metered routes")
+		}
+		unitPrice, err := money.USDC.Parse(r.UnitPrice)
+		if err != nil {
+			return fmt.Errorf("invalid unit price: %w", err)
+		}
+		if unitPrice == 0 {
+			return errors.New("metered routes require a unit price")
+		}
+	default:
+		return errors.New("invalid billing mode provided")
+	}
+
+	if r.QuoteURL != "" {
+		if r.BillingMode == BillingModeMetered {
+			return errors.New("quote hooks are not supported for metered routes")
+		}
+		// A quote prices a single request, so it can't pay for several.
+		if r.Credits > 1 {
+			return errors.New("routes with a quote hook must have a single credit")
+		}
+
+		var minPrice, maxPrice uint64
+		var err error
+		if r.MinPrice != "" {
+			if minPrice, err = money.USDC.Parse(r.MinPrice); err != nil {
+				return fmt.Errorf("invalid min price: %w", err)
+			}
+		}
+		if r.MaxPrice != "" {
+			if maxPrice, err = money.USDC.Parse(r.MaxPrice); err != nil {
+				return fmt.Errorf("invalid max price: %w", err)
+			}
+		}
+		if maxPrice > 0 && maxPrice < minPrice {
+			return errors.New("max price must be greater or equal to min price")
+		}
+	}
+
+	if r.PayWhatYouWant {
+		if r.QuoteURL != "" {
+			return errors.New("pay what you want routes can't have a quote hook")
+		}
+		if r.BillingMode == BillingModeMetered {
+			return errors.New("pay what you want is not supported for metered routes")
+		}
+		if err := validatePayWhatYouWant(r.Price, r.MinPrice, r.MaxPrice); err != nil {
+			return err
+		}
+	}
+
+	if len(r.PaymentRails) > 0 && (r.BillingMode == BillingModeMetered || r.QuoteURL != "" || r.PayWhatYouWant) {
+		return errors.New("payment rails are only supported for fixed price routes")
+	}
+
+	if err := validateMimeType(r.MimeType); err != nil {
+		return err
+	}
+
+	if r.BodySchema != "" && !isBodyMethod(upperMethod) {
+		return errors.New("body schemas are only supported for POST, PUT and PATCH routes")
+	}
+	if err := validateSchema("body schema", r.BodySchema); err != nil {
+		return err
+	}
+	if err := validateSchema("query schema", r.QuerySchema); err != nil {
+		return err
+	}
+	if err := validateSchema("response schema", r.ResponseSchema); err != nil {
+		return err
+	}
+
 	// Validate Price - we just need validation, not the conversion
-	priceUtils := NewPriceUtils()
-	_, err := priceUtils.ParsePrice(r.Price)
+	_, err := money.USDC.Parse(r.Price)
 	return err
 }
 
@@ -143,6 +299,9 @@ func (h *PaidRouteHandler) CreateURLRouteHandler(gCtx *gin.Context) {
 	setDefaultTypeAndCredits(&req.Type, &req.Credits)
 
 	err = req.Validate()
+	if err == nil {
+		err = h.validatePaymentRails(req.PaymentRails)
+	}
 	if err != nil {
 		gCtx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -175,13 +334,42 @@ func (h *PaidRouteHandler) CreateURLRouteHandler(gCtx *gin.Context) {
 
 		AccessURL: accessURL,
 
-		Price:                  h.priceUtils.FormatPrice(route.Price),
+		Price:                  money.USDC.Format(route.Price),
 		Type:                   route.Type,
 		Credits:                route.Credits,
 		PaymentProtocolVersion: route.PaymentProtocolVersion,
 		IsTest:                 route.IsTest,
 		IsEnabled:              route.IsEnabled,
 
+		CacheEnabled:    route.CacheEnabled,
+		CacheTTLSeconds: route.CacheTTLSeconds,
+
+		StreamMaxSeconds:    route.StreamMaxSeconds,
+		StreamCreditSeconds: route.StreamCreditSeconds,
+
+		BillingMode: route.BillingMode,
+		UnitPrice:   h.formatUnitPrice(route),
+
+		QuotePricing:   route.QuoteURL != "",
+		PayWhatYouWant: route.PayWhatYouWant,
+		MinPrice:       money.USDC.Format(route.MinPrice),
+		MaxPrice:       money.USDC.Format(route.MaxPrice),
+
+		AvailableFrom:  route.AvailableFrom,
+		AvailableUntil: route.AvailableUntil,
+		MaxSales:       route.MaxSales,
+		SoldCount:      route.SoldCount,
+		RemainingStock: remainingStock(route),
+
+		PaymentRails: route.PaymentRails,
+
+		Discoverable: route.Discoverable,
+		MimeType:     route.MimeType,
+
+		BodySchema:     route.BodySchema,
+		QuerySchema:    route.QuerySchema,
+		ResponseSchema: route.ResponseSchema,
+
 		Title:       route.Title,
 		Description: route.Description,
 		CoverURL:    route.CoverImageURL,
@@ -207,13 +395,36 @@ type CreateFileRouteRequest struct {
 	CoverImage       *multipart.FileHeader `form:"cover_image" binding:"omitempty"`
 	Title            string                `form:"title" binding:"omitempty"`       // Optional title
 	Description      string                `form:"description" binding:"omitempty"` // Optional description
+
+	PayWhatYouWant bool   `form:"pay_what_you_want" binding:"omitempty"` // Optional, the buyer chooses the amount and price is the suggested one
+	MinPrice       string `form:"min_price" binding:"omitempty"`         // Optional, lower bound for chosen prices
+	MaxPrice       string `form:"max_price" binding:"omitempty"`         // Optional, upper bound for chosen prices
+
+	PaymentRails []string `form:"payment_rails" binding:"omitempty"` // Optional, payment rails accepted besides x402, such as "l402"
+
+	Discoverable bool   `form:"discoverable" binding:"omitempty"` // Optional, lists the route on the public discovery endpoint
+	MimeType     string `form:"mime_type" binding:"omitempty"`    // Optional, media type of the route's responses
+
+	AvailabilityRequest
 }
 
 // Validate performs business rule validation for the file route creation request.
 func (r *CreateFileRouteRequest) Validate() error {
+	if r.PayWhatYouWant {
+		if err := validatePayWhatYouWant(r.Price, r.MinPrice, r.MaxPrice); err != nil {
+			return err
+		}
+		if len(r.PaymentRails) > 0 {
+			return errors.New("payment rails are only supported for fixed price routes")
+		}
+	}
+
+	if err := validateMimeType(r.MimeType); err != nil {
+		return err
+	}
+
 	// Validate Price - we just need validation, not the conversion
-	priceUtils := NewPriceUtils()
-	_, err := priceUtils.ParsePrice(r.Price)
+	_, err := money.USDC.Parse(r.Price)
 	return err
 }
 
@@ -237,6 +448,9 @@ func (h *PaidRouteHandler) CreateFileRouteHandler(gCtx *gin.Context) {
 	setDefaultTypeAndCredits(&req.Type, &req.Credits)
 
 	err = req.Validate()
+	if err == nil {
+		err = h.validatePaymentRails(req.PaymentRails)
+	}
 	if err != nil {
 		gCtx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -265,12 +479,21 @@ func (h *PaidRouteHandler) CreateFileRouteHandler(gCtx *gin.Context) {
 }
 
 // getAndValidateRoute retrieves the paid route configuration based on the shortCode
-// from the request path and validates the request method.
+// from the request path and validates the request method and the route's availability.
 // It sends an error response and returns shouldReturn=true if validation fails or route is not found.
 func (h *PaidRouteHandler) getAndValidateRoute(gCtx *gin.Context) (*PaidRoute, bool) {
 	shortCode := gCtx.Param("shortCode")
 
-	route, err := h.paidRouteService.FindEnabledRouteByShortCode(gCtx.Request.Context(), shortCode)
+	route, err := h.paidRouteService.FindAvailableRouteByShortCode(gCtx.Request.Context(), shortCode)
+	// Buyers of a sold out route keep using their purchases, only new
+	// payments are rejected once they are made.
+	if errors.Is(err, ErrRouteSoldOut) && gCtx.GetHeader(getPaymentHeaderNameForRoute(route)) != "" {
+		err = nil
+	}
+	if errors.Is(err, ErrRouteNotStarted) || errors.Is(err, ErrRouteEnded) || errors.Is(err, ErrRouteSoldOut) {
+		h.respondUnavailable(gCtx, route, err)
+		return nil, true
+	}
 	if err != nil {
 		if errors.Is(err, ErrRouteNotFound) {
 			h.logger.Error("Route not found", "shortCode", shortCode)
@@ -313,13 +536,14 @@ func (h *PaidRouteHandler) tryExistingPayment(gCtx *gin.Context, route *PaidRout
 
 	existingPurchase, err := h.purchaseService.GetPurchaseByRouteIDAndPaymentHeader(gCtx.Request.Context(), route.ID, clientPaymentHeader)
 
+	if errors.Is(err, purchases.ErrPurchaseNotFound) {
+		// The payment may have bought a bundle the route is part of.
+		return h.tryBundlePurchase(gCtx, route, clientPaymentHeader)
+	}
 	if err != nil {
-		if !errors.Is(err, purchases.ErrPurchaseNotFound) {
-			// Log actual errors, but still proceed to new payment as a fallback.
-			h.logger.Debug("Error checking for existing purchase with payment header",
-				"shortCode", route.ShortCode, "paymentHeader", clientPaymentHeader, "error", err)
-		}
-		// If purchase not found or other error, proceed to new payment.
+		// Log actual errors, but still proceed to new payment as a fallback.
+		h.logger.Debug("Error checking for existing purchase with payment header",
+			"shortCode", route.ShortCode, "paymentHeader", clientPaymentHeader, "error", err)
 		return false, true
 	}
 
@@ -327,10 +551,45 @@ func (h *PaidRouteHandler) tryExistingPayment(gCtx *gin.Context, route *PaidRout
 		return false, true
 	}
 
+	// Looking at a bought bundle uses none of its credits, they pay for
+	// requests to the bundle's routes.
+	if route.ResourceType == ResourceTypeBundle {
+		return true, false
+	}
+
+	return h.useExistingPurchase(gCtx, route, existingPurchase)
+}
+
+// useExistingPurchase uses a credit of an existing purchase of the route, or
+// of its balance on metered routes.
+// Returns:
+//   - usedExistingCredit: true if a credit was successfully used.
+//   - proceedToNewPayment: true if a new payment flow should be initiated (e.g., no credits, error using credit).
+func (h *PaidRouteHandler) useExistingPurchase(gCtx *gin.Context, route *PaidRoute,
+	existingPurchase *purchases.Purchase) (usedExistingCredit bool, proceedToNewPayment bool) {
+
 	h.logger.Debug("Existing purchase record found for payment header",
 		"shortCode", route.ShortCode, "purchaseID", existingPurchase.ID,
 		"creditsUsed", existingPurchase.CreditsUsed, "creditsAvailable", existingPurchase.CreditsAvailable)
 
+	// Metered purchases are a prepaid balance that usage is charged against
+	// once the upstream responds, rather than a number of credits.
+	if route.BillingMode == BillingModeMetered {
+		if existingPurchase.Balance() == 0 {
+			h.logger.Info("Existing metered purchase (via header) has no balance left. Proceeding to new payment.",
+				"shortCode", route.ShortCode, "purchaseID", existingPurchase.ID)
+			return false, true
+		}
+
+		if err := h.paidRouteService.IncrementAccessCount(gCtx.Request.Context(), route.ShortCode); err != nil {
+			h.logger.Error("Failed to increment access count (existing metered purchase)",
+				"shortCode", route.ShortCode, "purchaseID", existingPurchase.ID, "error", err)
+		}
+
+		setStreamCharge(gCtx, h.purchaseStreamCharge(route, existingPurchase))
+		return true, false
+	}
+
 	if existingPurchase.CreditsUsed >= existingPurchase.CreditsAvailable {
 		h.logger.Info("Existing purchase (via header) has no credits left. Proceeding to new payment.",
 			"shortCode", route.ShortCode, "purchaseID", existingPurchase.ID)
@@ -341,12 +600,14 @@ func (h *PaidRouteHandler) tryExistingPayment(gCtx *gin.Context, route *PaidRout
 	h.logger.Debug("Existing purchase has available credits. Attempting to use one credit.",
 		"shortCode", route.ShortCode, "purchaseID", existingPurchase.ID)
 
-	errIncrement := h.purchaseService.IncrementCreditsUsed(gCtx.Request.Context(), existingPurchase.ID)
+	_, errIncrement := h.purchaseService.IncrementCreditsUsed(gCtx.Request.Context(),
+		creditUse(route, existingPurchase))
 	if errIncrement != nil {
 		h.logger.Error("Failed to increment credits_used for existing purchase. Proceeding to new payment.",
 			"shortCode", route.ShortCode, "purchaseID", existingPurchase.ID, "error", errIncrement)
 		return false, true // Failed to use credit, proceed to new payment.
 	}
+	setStreamCharge(gCtx, h.purchaseStreamCharge(route, existingPurchase))
 
 	// Successfully used a credit from an existing purchase.
 	h.logger.Info("Successfully used a credit from existing purchase via payment header.",
@@ -363,17 +624,35 @@ func (h *PaidRouteHandler) tryExistingPayment(gCtx *gin.Context, route *PaidRout
 }
 
 // executeNewPaymentFlow handles the entire process of a new payment if an existing one isn't used.
+// The price asked for is discounted by the payer's rule and the coupon, if
+// there are any. Free payers are let through once their payment verified.
 // Returns:
 //   - paymentProcessedSuccessfully: true if a new payment was completed and purchase recorded.
 //   - requestHandled: true if a response was sent (e.g., 402, 500 error) and the main handler should stop.
-func (h *PaidRouteHandler) executeNewPaymentFlow(gCtx *gin.Context, route *PaidRoute) (paymentProcessedSuccessfully bool, requestHandled bool) {
+func (h *PaidRouteHandler) executeNewPaymentFlow(gCtx *gin.Context, route *PaidRoute,
+	coupon *coupons.Coupon, payerRule *payers.Rule,
+	affiliate *affiliates.Affiliate) (paymentProcessedSuccessfully bool, requestHandled bool) {
+
+	// Pay what you want routes are priced at the amount the buyer chooses.
+	route, requestHandled = h.chooseAmount(gCtx, route)
+	if requestHandled {
+		return false, true
+	}
+
+	// Requests without a payment are offered the route's other payment
+	// rails too. Coupons and payer rules apply to x402 payments only.
+	if gCtx.GetHeader(getPaymentHeaderNameForRoute(route)) == "" && coupon == nil && payerRule == nil {
+		accessURL := fmt.Sprintf("%s://%s/%s", getRequestScheme(gCtx), gCtx.Request.Host, route.ShortCode)
+		h.offerRails(gCtx, route, accessURL)
+	}
+
 	version := getRoutePaymentVersion(route)
 
 	switch version {
 	case PaymentProtocolVersionV1:
-		return h.executeNewPaymentFlowV1(gCtx, route)
+		return h.executeNewPaymentFlowV1(gCtx, route, coupon, payerRule, affiliate)
 	case PaymentProtocolVersionV2:
-		return h.executeNewPaymentFlowV2(gCtx, route)
+		return h.executeNewPaymentFlowV2(gCtx, route, coupon, payerRule, affiliate)
 	default:
 		h.logger.Error("Unsupported payment protocol version",
 			"shortCode", route.ShortCode, "routeID", route.ID, "paymentProtocolVersion", version)
@@ -382,19 +661,31 @@ func (h *PaidRouteHandler) executeNewPaymentFlow(gCtx *gin.Context, route *PaidR
 	}
 }
 
-func (h *PaidRouteHandler) executeNewPaymentFlowV1(gCtx *gin.Context, route *PaidRoute) (paymentProcessedSuccessfully bool, requestHandled bool) {
-	// Parse route.Price string to *big.Float
-	priceFloat, ok := new(big.Float).SetString(h.priceUtils.FormatPrice(route.Price))
-	if !ok {
-		h.logger.Error("Invalid price format in executeNewPaymentFlow", "shortCode", route.ShortCode, "price", route.Price)
-		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal configuration error for route price."})
-		return false, true // Request handled (error sent)
+func (h *PaidRouteHandler) executeNewPaymentFlowV1(gCtx *gin.Context, route *PaidRoute,
+	coupon *coupons.Coupon, payerRule *payers.Rule,
+	affiliate *affiliates.Affiliate) (paymentProcessedSuccessfully bool, requestHandled bool) {
+
+	// Price the payment and the purchase record with the pack the payment
+	// pays for and the discounted price.
+	options, requestHandled := h.paymentOptions(gCtx, route, coupon, payerRule)
+	if requestHandled {
+		return false, true
+	}
+	input := amountInput(route)
+	option := selectPaymentOption(gCtx, route, options)
+	route, discount := option.route, option.discount
+	if coupon != nil && route.Price == 0 {
+		return h.redeemFreeRequest(gCtx, route, coupon, discount)
 	}
 
 	scheme := getRequestScheme(gCtx)
 	accessURL := fmt.Sprintf("%s://%s/%s", scheme, gCtx.Request.Host, route.ShortCode)
 	h.logger.Debug("Access URL for new payment flow created", "shortCode", route.ShortCode, "accessURL", accessURL)
 
+	// The referral is part of the resource, so it is kept when the payer
+	// retries with a payment.
+	resourceURL := referralURL(accessURL, affiliate)
+
 	user, err := h.userService.GetUserByID(gCtx.Request.Context(), route.UserID)
 	if err != nil {
 		h.logger.Error("Error fetching user in executeNewPaymentFlow", "shortCode", route.ShortCode, "userID", route.UserID, "error", err)
@@ -424,12 +715,38 @@ func (h *PaidRouteHandler) executeNewPaymentFlowV1(gCtx *gin.Context, route *Pai
 		gCtx.Set("CoverURL", *route.CoverImageURL)
 	}
 
-	paymentPayloadJSON, settleResponseJSON := x402.Payment(gCtx, priceFloat, paymentAddress,
+	// The purchase is recorded as pending before the payment is settled, so
+	// a settled payment always has a record even if saving the outcome fails.
+	var purchaseID uint64
+	paymentHeaderForNewPurchase := gCtx.GetHeader(getPaymentHeaderNameForRoute(route))
+	verifiedPayloadJSON, settleResponseJSON := x402.Payment(gCtx, route.Price, paymentAddress,
 		x402.WithFacilitatorURL(h.config.X402FacilitatorURL),
 		x402.WithDescription(fmt.Sprintf("Payment for %s %s", route.Method, accessURL)),
-		x402.WithResource(accessURL),
+		x402.WithResource(resourceURL),
 		x402.WithTestnet(route.IsTest),
 		x402.WithMaxTimeoutSeconds(h.config.X402MaxTimeoutSeconds),
+		x402.WithMimeType(routeMimeType(route)),
+		x402.WithOutputSchema(discoveryOutputSchema(route)),
+		x402.WithAlternatives(paymentAlternatives(resourceURL, options, option)...),
+		x402.WithAmountInput(input),
+		x402.WithBeforeSettle(func(paymentPayloadJSON []byte, paymentRequirementsJSON []byte) error {
+			var err error
+			purchaseID, err = h.createPendingPurchase(gCtx.Request.Context(), route, paymentAddress,
+				paymentPayloadJSON, paymentRequirementsJSON, paymentHeaderForNewPurchase,
+				option.packID(), coupon, discount, affiliate)
+			switch {
+			case errors.Is(err, purchases.ErrPaymentReused), errors.Is(err, purchases.ErrNoPaymentNonce),
+				errors.Is(err, coupons.ErrCouponUnavailable), errors.Is(err, purchases.ErrSoldOut):
+				return fmt.Errorf("%w: %w", x402.ErrPaymentRejected, err)
+			case err != nil:
+				return errors.New("Internal server error before payment settlement.")
+			}
+			return nil
+		}),
+		x402.WithSettleFailed(func(reason string) {
+			h.failPendingPurchase(gCtx.Request.Context(), route, purchaseID, reason)
+		}),
+		x402.WithVerifyOnly(isFreePayer(payerRule)),
 	)
 
 	if gCtx.IsAborted() {
@@ -443,14 +760,19 @@ func (h *PaidRouteHandler) executeNewPaymentFlowV1(gCtx *gin.Context, route *Pai
 		return false, true
 	}
 
-	// If we get here, payment verification within x402.Payment succeeded.
-	paymentHeaderForNewPurchase := gCtx.GetHeader(getPaymentHeaderNameForRoute(route))
-	err = h.savePurchaseRecordJSON(gCtx.Request.Context(), route, paymentAddress, paymentPayloadJSON, settleResponseJSON, paymentHeaderForNewPurchase)
+	if isFreePayer(payerRule) {
+		return h.letFreePayerThrough(gCtx, route, payerRule, verifiedPayloadJSON)
+	}
+
+	// If we get here, payment verification and settlement within x402.Payment succeeded.
+	err = h.completePurchase(gCtx.Request.Context(), route, purchaseID, paymentAddress, settleResponseJSON,
+		affiliates.Commission(affiliate))
 	if err != nil {
 		h.logger.Error("Failed to save purchase record after new payment", "shortCode", route.ShortCode, "routeID", route.ID, "error", err)
 		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error after payment."})
 		return false, true // Request handled (error sent)
 	}
+	h.setNewPurchaseStreamCharge(gCtx, route, purchaseID)
 
 	// Increment payment count as a new payment was successfully processed and saved.
 	if err := h.paidRouteService.IncrementPaymentCount(gCtx.Request.Context(), route.ShortCode); err != nil {
@@ -470,7 +792,10 @@ func (h *PaidRouteHandler) executeNewPaymentFlowV1(gCtx *gin.Context, route *Pai
 	return true, false // New payment processed successfully, request not yet fully handled (proxying is next).
 }
 
-func (h *PaidRouteHandler) executeNewPaymentFlowV2(gCtx *gin.Context, route *PaidRoute) (paymentProcessedSuccessfully bool, requestHandled bool) {
+func (h *PaidRouteHandler) executeNewPaymentFlowV2(gCtx *gin.Context, route *PaidRoute,
+	coupon *coupons.Coupon, payerRule *payers.Rule,
+	affiliate *affiliates.Affiliate) (paymentProcessedSuccessfully bool, requestHandled bool) {
+
 	scheme := getRequestScheme(gCtx)
 	accessURL := fmt.Sprintf("%s://%s/%s", scheme, gCtx.Request.Host, route.ShortCode)
 
@@ -491,19 +816,66 @@ func (h *PaidRouteHandler) executeNewPaymentFlowV2(gCtx *gin.Context, route *Pai
 		network = x402core.Network("eip155:84532")
 	}
 
+	description := fmt.Sprintf("Payment for %s %s", route.Method, accessURL)
+	if route.QuoteURL != "" {
+		quote, err := h.quoteRequest(gCtx, route)
+		if errors.Is(err, errQuoteBodyTooLarge) {
+			gCtx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body is too large"})
+			return false, true
+		}
+		if err != nil {
+			h.logger.Error("Failed to quote price for request", "shortCode", route.ShortCode, "error", err)
+			gCtx.JSON(http.StatusBadGateway, gin.H{"error": "Unable to quote a price for this request"})
+			return false, true
+		}
+
+		h.logger.Debug("Quoted price for request", "shortCode", route.ShortCode,
+			"quoteID", quote.ID, "price", quote.Price)
+
+		// The quote ID is part of the payment description, so a payment made
+		// for one quote does not verify against the requirements of another.
+		description = fmt.Sprintf("%s (quote %s)", description, quote.ID)
+
+		// Price the payment and the purchase record with the quoted price.
+		quotedRoute := *route
+		quotedRoute.Price = quote.Price
+		route = &quotedRoute
+	}
+
+	// The payer's rule and the coupon discount the quoted price or the
+	// price of the pack the payment pays for.
+	options, requestHandled := h.paymentOptions(gCtx, route, coupon, payerRule)
+	if requestHandled {
+		return false, true
+	}
+	option := selectPaymentOption(gCtx, route, options)
+	if coupon != nil && option.route.Price == 0 {
+		return h.redeemFreeRequest(gCtx, option.route, coupon, option.discount)
+	}
+
+	// Each option is offered separately, the one the payment pays for is
+	// known once it verified.
+	accepts := make([]x402http.PaymentOption, 0, len(options))
+	for _, option := range options {
+		if option.route.Price == 0 {
+			continue
+		}
+		accepts = append(accepts, x402http.PaymentOption{
+			Scheme:            "exact",
+			PayTo:             paymentAddress,
+			Price:             money.USDC.Format(option.route.Price),
+			Network:           network,
+			MaxTimeoutSeconds: h.config.X402MaxTimeoutSeconds,
+		})
+	}
+
 	routesConfig := x402http.RoutesConfig{
 		"*": {
-			Accepts: []x402http.PaymentOption{
-				{
-					Scheme:            "exact",
-					PayTo:             paymentAddress,
-					Price:             h.priceUtils.FormatPrice(route.Price),
-					Network:           network,
-					MaxTimeoutSeconds: h.config.X402MaxTimeoutSeconds,
-				},
-			},
-			Resource:    accessURL,
-			Description: fmt.Sprintf("Payment for %s %s", route.Method, accessURL),
+			Accepts:     accepts,
+			Resource:    referralURL(accessURL, affiliate),
+			Description: description,
+			MimeType:    routeMimeType(route),
+			Extensions:  discoveryExtensions(route),
 		},
 	}
 
@@ -579,9 +951,62 @@ func (h *PaidRouteHandler) executeNewPaymentFlowV2(gCtx *gin.Context, route *Pai
 		return false, true
 	}
 
+	paymentPayloadJSON, err := json.Marshal(processResult.PaymentPayload)
+	if err != nil {
+		h.logger.Error("Failed to encode v2 payment payload", "shortCode", route.ShortCode, "error", err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error after payment verification."})
+		return false, true
+	}
+
+	paymentRequirementsJSON, err := json.Marshal(processResult.PaymentRequirements)
+	if err != nil {
+		h.logger.Error("Failed to encode v2 payment requirements", "shortCode", route.ShortCode, "error", err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error after payment verification."})
+		return false, true
+	}
+
+	// Price the purchase record with the option the payment verified
+	// against.
+	amount, err := strconv.ParseUint(processResult.PaymentRequirements.Amount, 10, 64)
+	matched := false
+	if err == nil {
+		option, matched = findPaymentOption(options, amount)
+	}
+	if !matched {
+		h.logger.Warn("Verified v2 payment matches no payment option", "shortCode", route.ShortCode,
+			"amount", processResult.PaymentRequirements.Amount)
+		gCtx.JSON(http.StatusPaymentRequired, gin.H{"error": errNoMatchingOption.Error(), "x402Version": 2})
+		return false, true
+	}
+	route, discount := option.route, option.discount
+
+	if isFreePayer(payerRule) {
+		return h.letFreePayerThrough(gCtx, route, payerRule, paymentPayloadJSON)
+	}
+
+	// The purchase is recorded as pending before the payment is settled, so
+	// a settled payment always has a record even if saving the outcome fails.
+	paymentHeaderForNewPurchase := gCtx.GetHeader(getPaymentHeaderNameForRoute(route))
+	purchaseID, err := h.createPendingPurchase(gCtx.Request.Context(), route, paymentAddress,
+		paymentPayloadJSON, paymentRequirementsJSON, paymentHeaderForNewPurchase,
+		option.packID(), coupon, discount, affiliate)
+	switch {
+	case errors.Is(err, purchases.ErrPaymentReused), errors.Is(err, purchases.ErrNoPaymentNonce),
+		errors.Is(err, coupons.ErrCouponUnavailable):
+		gCtx.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error(), "x402Version": 2})
+		return false, true
+	case errors.Is(err, purchases.ErrSoldOut):
+		gCtx.JSON(http.StatusGone, gin.H{"error": err.Error(), "x402Version": 2})
+		return false, true
+	case err != nil:
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error before payment settlement."})
+		return false, true
+	}
+
 	settleResponse, err := server.SettlePayment(gCtx.Request.Context(), *processResult.PaymentPayload, *processResult.PaymentRequirements)
 	if err != nil {
 		h.logger.Error("v2 settlement failed", "shortCode", route.ShortCode, "error", err)
+		h.failPendingPurchase(gCtx.Request.Context(), route, purchaseID, err.Error())
 		gCtx.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error(), "x402Version": 2})
 		return false, true
 	}
@@ -594,20 +1019,14 @@ func (h *PaidRouteHandler) executeNewPaymentFlowV2(gCtx *gin.Context, route *Pai
 	}
 	gCtx.Header("PAYMENT-RESPONSE", base64.StdEncoding.EncodeToString(settleResponseJSON))
 
-	paymentPayloadJSON, err := json.Marshal(processResult.PaymentPayload)
-	if err != nil {
-		h.logger.Error("Failed to encode v2 payment payload", "shortCode", route.ShortCode, "error", err)
-		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error after payment verification."})
-		return false, true
-	}
-
-	paymentHeaderForNewPurchase := gCtx.GetHeader(getPaymentHeaderNameForRoute(route))
-	err = h.savePurchaseRecordJSON(gCtx.Request.Context(), route, paymentAddress, paymentPayloadJSON, settleResponseJSON, paymentHeaderForNewPurchase)
+	err = h.completePurchase(gCtx.Request.Context(), route, purchaseID, paymentAddress, settleResponseJSON,
+		affiliates.Commission(affiliate))
 	if err != nil {
 		h.logger.Error("Failed to save purchase record after v2 payment", "shortCode", route.ShortCode, "routeID", route.ID, "error", err)
 		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error after payment."})
 		return false, true
 	}
+	h.setNewPurchaseStreamCharge(gCtx, route, purchaseID)
 
 	err = h.paidRouteService.IncrementPaymentCount(gCtx.Request.Context(), route.ShortCode)
 	if err != nil {
@@ -624,13 +1043,21 @@ func (h *PaidRouteHandler) executeNewPaymentFlowV2(gCtx *gin.Context, route *Pai
 	return true, false
 }
 
-// proxyRequest sets up and executes the reverse proxy to the target URL.
-func (h *PaidRouteHandler) proxyRequest(gCtx *gin.Context, route *PaidRoute) {
+// proxyRequest sets up and executes the reverse proxy to the target URL. The
+// upstream response is stored for the idempotency claim, if there is one.
+func (h *PaidRouteHandler) proxyRequest(gCtx *gin.Context, route *PaidRoute,
+	claim *idempotency.Claim) {
 	h.logger.Debug("Attempting to proxy request",
 		"shortCode", route.ShortCode,
 		"targetURL", route.TargetURL,
 		"resourceType", route.ResourceType)
 
+	// Bundles have no target, they list the routes they unlock.
+	if route.ResourceType == ResourceTypeBundle {
+		h.serveBundle(gCtx, route)
+		return
+	}
+
 	// Handle file resources differently - detect by ResourceType or URL format
 	if route.ResourceType == "file" || !strings.Contains(route.TargetURL, "://") {
 		h.logger.Debug("Detected file resource, generating presigned URL",
@@ -676,6 +1103,36 @@ func (h *PaidRouteHandler) proxyRequest(gCtx *gin.Context, route *PaidRoute) {
 		return
 	}
 
+	var cacheKey string
+	if h.isResponseCacheable(gCtx, route) {
+		cacheKey = responseCacheKey(gCtx, route, targetURL)
+		if h.serveCachedResponse(gCtx, route, cacheKey) {
+			return
+		}
+		gCtx.Header(CacheStatusHeader, "MISS")
+	}
+
+	// Routes with an upstream pool are proxied to the target picked by the
+	// route's load balancing strategy instead of the primary target URL.
+	selection, err := h.upstreamService.Select(gCtx.Request.Context(), route.ID,
+		upstreams.Strategy(route.LBStrategy))
+	if err != nil && !errors.Is(err, upstreams.ErrNoTargets) {
+		h.logger.Error("Failed to select upstream target, using primary target",
+			"shortCode", route.ShortCode, "error", err)
+	}
+	if selection != nil {
+		defer selection.Release()
+
+		targetURL, err = url.Parse(selection.Target.URL)
+		if err != nil {
+			selection.Observe(0, err)
+			h.logger.Error("Failed to parse upstream target URL for proxy",
+				"shortCode", route.ShortCode, "targetURL", selection.Target.URL, "error", err)
+			gCtx.JSON(http.StatusBadGateway, gin.H{"error": "Invalid upstream target"})
+			return
+		}
+	}
+
 	proxy := httputil.NewSingleHostReverseProxy(targetURL)
 
 	originalDirector := proxy.Director
@@ -694,9 +1151,47 @@ func (h *PaidRouteHandler) proxyRequest(gCtx *gin.Context, route *PaidRoute) {
 		}
 	}
 
+	var cacheHook func(*http.Response) error
+	if cacheKey != "" {
+		cacheHook = h.cacheResponseHook(gCtx.Request.Context(), route, cacheKey)
+	}
+
+	var idempotencyHook func(*http.Response) error
+	if claim != nil {
+		idempotencyHook = h.idempotencyResponseHook(gCtx.Request.Context(), route, claim)
+	}
+
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		if selection != nil {
+			selection.Observe(resp.StatusCode, nil)
+		}
+		if route.BillingMode == BillingModeMetered {
+			h.chargeUsage(gCtx.Request.Context(), route,
+				gCtx.GetHeader(getPaymentHeaderNameForRoute(route)), resp)
+		}
+		if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+			// Ask intermediate proxies not to buffer the event stream.
+			resp.Header.Set("X-Accel-Buffering", "no")
+		} else if route.IsTest && len(route.ResponseSchema) > 0 {
+			if err := h.checkResponseSchema(route, resp); err != nil {
+				return err
+			}
+		}
+		if cacheHook != nil {
+			return cacheHook(resp)
+		}
+		if idempotencyHook != nil {
+			return idempotencyHook(resp)
+		}
+		return nil
+	}
+
 	proxy.ErrorHandler = func(rw http.ResponseWriter, req *http.Request, err error) {
+		if selection != nil {
+			selection.Observe(0, err)
+		}
 		h.logger.Error("Reverse proxy error occurred",
-			"shortCode", route.ShortCode, "targetURL", route.TargetURL, "error", err)
+			"shortCode", route.ShortCode, "targetURL", targetURL.String(), "error", err)
 		// Avoid writing detailed errors to the client unless necessary
 		// Check if headers have already been written to avoid http.ErrHeaderSent panic
 		if !gCtx.Writer.Written() {
@@ -704,6 +1199,11 @@ func (h *PaidRouteHandler) proxyRequest(gCtx *gin.Context, route *PaidRoute) {
 		}
 	}
 
+	if isStreamRequest(gCtx.Request) {
+		h.serveStream(gCtx, route, proxy)
+		return
+	}
+
 	proxy.ServeHTTP(gCtx.Writer, gCtx.Request)
 }
 
@@ -714,19 +1214,79 @@ func (h *PaidRouteHandler) HandlePaidRoute(gCtx *gin.Context) {
 		return
 	}
 
-	usedExistingCredit, proceedToNewPayment := h.tryExistingPayment(gCtx, route)
+	// Requests that don't match the route's schemas are rejected before they
+	// are challenged or paid for, the upstream would reject them anyway.
+	if h.validateRequestSchemas(gCtx, route) {
+		return
+	}
+
+	if h.tryOwnerPreview(gCtx, route) {
+		h.proxyRequest(gCtx, route, nil)
+		return
+	}
+
+	// Retries with an Idempotency-Key are answered before anything is paid.
+	claim, requestHandled := h.beginIdempotentRequest(gCtx, route)
+	if requestHandled {
+		return
+	}
+	if claim != nil {
+		defer h.releaseIdempotentRequest(gCtx.Request.Context(), route, claim)
+	}
+
+	// Blocked payers are rejected before their payment is looked at.
+	payerRule, requestHandled := h.findPayerRule(gCtx, route)
+	if requestHandled {
+		return
+	}
+
+	// The referral code is taken off the request before it is proxied.
+	affiliate := h.resolveAffiliate(gCtx, route)
+
+	// Routes may also be paid with the payment rails they accept.
+	paidWithRail, requestHandled := h.tryRailPayment(gCtx, route, affiliate)
+	if requestHandled {
+		return
+	}
+
+	usedExistingCredit, proceedToNewPayment := paidWithRail, !paidWithRail
+	if !paidWithRail {
+		usedExistingCredit, proceedToNewPayment = h.tryExistingPayment(gCtx, route)
+	}
 
 	var newPaymentProcessedSuccessfully bool = false
 
+	// Clients without a payment may pay from their wallet with the owner.
+	var usedWalletBalance bool
 	if proceedToNewPayment {
+		usedWalletBalance, requestHandled = h.tryWalletDraw(gCtx, route)
+		if requestHandled {
+			return
+		}
+	}
+
+	// Clients without a payment may have free trial requests left.
+	usedTrialRequest := proceedToNewPayment && !usedWalletBalance && h.tryTrialRequest(gCtx, route)
+
+	if proceedToNewPayment && !usedWalletBalance && !usedTrialRequest {
+		// Free payers have no use for a coupon.
+		var coupon *coupons.Coupon
+		if !isFreePayer(payerRule) {
+			coupon, requestHandled = h.resolveCoupon(gCtx, route)
+			if requestHandled {
+				return
+			}
+		}
+
 		var requestAlreadyHandled bool
-		newPaymentProcessedSuccessfully, requestAlreadyHandled = h.executeNewPaymentFlow(gCtx, route)
+		newPaymentProcessedSuccessfully, requestAlreadyHandled = h.executeNewPaymentFlow(gCtx, route,
+			coupon, payerRule, affiliate)
 		if requestAlreadyHandled {
 			return
 		}
 	}
 
-	if !usedExistingCredit && !newPaymentProcessedSuccessfully {
+	if !usedExistingCredit && !newPaymentProcessedSuccessfully && !usedTrialRequest && !usedWalletBalance {
 		if !gCtx.IsAborted() {
 			h.logger.Error("Logical error: Reached proxy stage without a clear payment path and context not aborted.", "shortCode", route.ShortCode)
 			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server processing error."})
@@ -734,7 +1294,7 @@ func (h *PaidRouteHandler) HandlePaidRoute(gCtx *gin.Context) {
 		return
 	}
 
-	h.proxyRequest(gCtx, route)
+	h.proxyRequest(gCtx, route, claim)
 }
 
 // RouteResponse represents a standardized response for route data
@@ -758,6 +1318,36 @@ type RouteResponse struct {
 	IsTest    bool `json:"is_test"`
 	IsEnabled bool `json:"is_enabled"`
 
+	CacheEnabled    bool   `json:"cache_enabled"`
+	CacheTTLSeconds uint64 `json:"cache_ttl_seconds"`
+
+	StreamMaxSeconds    uint64 `json:"stream_max_seconds"`
+	StreamCreditSeconds uint64 `json:"stream_credit_seconds"`
+
+	BillingMode string `json:"billing_mode"`
+	UnitPrice   string `json:"unit_price,omitempty"`
+
+	QuotePricing   bool   `json:"quote_pricing"`
+	PayWhatYouWant bool   `json:"pay_what_you_want"`
+	MinPrice       string `json:"min_price"`
+	MaxPrice       string `json:"max_price"`
+
+	AvailableFrom  *time.Time `json:"available_from,omitempty"`
+	AvailableUntil *time.Time `json:"available_until,omitempty"`
+	MaxSales       uint64     `json:"max_sales"`
+	SoldCount      uint64     `json:"sold_count"`
+	// RemainingStock is omitted for routes without a sales limit.
+	RemainingStock *uint64 `json:"remaining_stock,omitempty"`
+
+	PaymentRails []string `json:"payment_rails,omitempty"`
+
+	Discoverable bool   `json:"discoverable"`
+	MimeType     string `json:"mime_type,omitempty"`
+
+	BodySchema     json.RawMessage `json:"body_schema,omitempty"`
+	QuerySchema    json.RawMessage `json:"query_schema,omitempty"`
+	ResponseSchema json.RawMessage `json:"response_schema,omitempty"`
+
 	AttemptCount uint64 `json:"attempt_count"`
 	PaymentCount uint64 `json:"payment_count"`
 	AccessCount  uint64 `json:"access_count"`
@@ -811,7 +1401,7 @@ func (h *PaidRouteHandler) GetUserPaidRoutes(gCtx *gin.Context) {
 			Title:                  route.Title,
 			Description:            route.Description,
 			CoverURL:               route.CoverImageURL,
-			Price:                  h.priceUtils.FormatPrice(route.Price),
+			Price:                  money.USDC.Format(route.Price),
 			Type:                   route.Type,
 			Credits:                route.Credits,
 			PaymentProtocolVersion: route.PaymentProtocolVersion,
@@ -819,6 +1409,35 @@ func (h *PaidRouteHandler) GetUserPaidRoutes(gCtx *gin.Context) {
 			IsTest:    route.IsTest,
 			IsEnabled: route.IsEnabled,
 
+			CacheEnabled:    route.CacheEnabled,
+			CacheTTLSeconds: route.CacheTTLSeconds,
+
+			StreamMaxSeconds:    route.StreamMaxSeconds,
+			StreamCreditSeconds: route.StreamCreditSeconds,
+
+			BillingMode: route.BillingMode,
+			UnitPrice:   h.formatUnitPrice(&route),
+
+			QuotePricing:   route.QuoteURL != "",
+			PayWhatYouWant: route.PayWhatYouWant,
+			MinPrice:       money.USDC.Format(route.MinPrice),
+			MaxPrice:       money.USDC.Format(route.MaxPrice),
+
+			AvailableFrom:  route.AvailableFrom,
+			AvailableUntil: route.AvailableUntil,
+			MaxSales:       route.MaxSales,
+			SoldCount:      route.SoldCount,
+			RemainingStock: remainingStock(&route),
+
+			PaymentRails: route.PaymentRails,
+
+			Discoverable: route.Discoverable,
+			MimeType:     route.MimeType,
+
+			BodySchema:     route.BodySchema,
+			QuerySchema:    route.QuerySchema,
+			ResponseSchema: route.ResponseSchema,
+
 			AttemptCount: route.AttemptCount,
 			PaymentCount: route.PaymentCount,
 			AccessCount:  route.AccessCount,
@@ -868,10 +1487,21 @@ func (h *PaidRouteHandler) DeleteUserPaidRoute(gCtx *gin.Context) {
 	gCtx.Status(http.StatusOK)
 }
 
-func (h *PaidRouteHandler) savePurchaseRecordJSON(gCtx context.Context,
+// createPendingPurchase records a verified payment as a pending purchase
+// before the payment is settled, attributed to the affiliate that referred
+// it, and redeems the coupon it was discounted with, if any.
+func (h *PaidRouteHandler) createPendingPurchase(gCtx context.Context,
 	route *PaidRoute, paymentAddress string,
-	paymentPayloadJSON []byte,
-	settleResponseJSON []byte, paymentHeader string) error {
+	paymentPayloadJSON []byte, paymentRequirementsJSON []byte,
+	paymentHeader string, packID uint64, coupon *coupons.Coupon,
+	discount uint64, affiliate *affiliates.Affiliate) (uint64, error) {
+
+	// If we create a purchase record, we know it's a new payment. (1 use
+	// already) The request paying for a bundle uses none of its credits.
+	var creditsUsed uint64 = 1
+	if route.ResourceType == ResourceTypeBundle {
+		creditsUsed = 0
+	}
 
 	// Create purchase record
 	purchase := &purchases.Purchase{
@@ -881,25 +1511,138 @@ func (h *PaidRouteHandler) savePurchaseRecordJSON(gCtx context.Context,
 		Price:            route.Price,
 		Type:             route.Type,
 		CreditsAvailable: route.Credits,
-		CreditsUsed:      1, // If we create a purchase record, we know it's a new payment. (1 use already)
+		CreditsUsed:      creditsUsed,
 		IsTest:           route.IsTest,
+		PackID:           packID,
 
 		PaidRouteID:   route.ID,
 		PaidToAddress: paymentAddress,
 
-		PaymentHeader:  paymentHeader,
-		PaymentPayload: paymentPayloadJSON,
-		SettleResponse: settleResponseJSON,
+		PaymentHeader:       paymentHeader,
+		PaymentPayload:      paymentPayloadJSON,
+		PaymentRequirements: paymentRequirementsJSON,
+	}
+	if affiliate != nil {
+		purchase.AffiliateID = affiliate.ID
 	}
-	h.logger.Info("Purchase record created", "purchase", fmt.Sprintf("%+v", purchase))
+	h.logger.Info("Pending purchase record created", "purchase", fmt.Sprintf("%+v", purchase))
 
-	_, err := h.purchaseService.CreatePurchase(gCtx, purchase)
+	purchaseID, err := h.purchaseService.CreatePendingPurchase(gCtx, purchase)
+	if errors.Is(err, purchases.ErrPaymentReused) || errors.Is(err, purchases.ErrNoPaymentNonce) ||
+		errors.Is(err, purchases.ErrSoldOut) {
+		h.logger.Warn("Rejected payment before settlement", "routeID", route.ID,
+			"shortCode", route.ShortCode, "error", err)
+		return 0, err
+	}
 	if err != nil {
-		h.logger.Error("Failed to save purchase record", "routeID", route.ID, "shortCode", route.ShortCode,
+		h.logger.Error("Failed to save pending purchase record", "routeID", route.ID, "shortCode", route.ShortCode,
 			"targetURL", route.TargetURL, "method", route.Method, "price", route.Price, "isTest", route.IsTest,
 			"createdAt", route.CreatedAt, "updatedAt", route.UpdatedAt, "error", err)
-		return fmt.Errorf("failed to save purchase record: %w", err)
+		return 0, fmt.Errorf("failed to save pending purchase record: %w", err)
+	}
+
+	if coupon == nil {
+		return purchaseID, nil
+	}
+
+	// The coupon is redeemed before the payment settles, so it is never
+	// redeemed more often than allowed. Failing the purchase releases it.
+	err = h.couponService.Redeem(gCtx, &coupons.Redemption{
+		CouponID:   coupon.ID,
+		RouteID:    route.ID,
+		PurchaseID: purchaseID,
+		Discount:   discount,
+	})
+	if err != nil {
+		h.failPendingPurchase(gCtx, route, purchaseID, "coupon could not be redeemed")
+		if errors.Is(err, coupons.ErrCouponUnavailable) {
+			h.logger.Warn("Rejected payment for unavailable coupon", "routeID", route.ID,
+				"shortCode", route.ShortCode, "couponID", coupon.ID)
+			return 0, err
+		}
+		h.logger.Error("Failed to redeem coupon", "routeID", route.ID,
+			"shortCode", route.ShortCode, "couponID", coupon.ID, "error", err)
+		return 0, fmt.Errorf("failed to redeem coupon: %w", err)
+	}
+
+	return purchaseID, nil
+}
+
+// failPendingPurchase marks a pending purchase whose payment failed to settle
+// as failed. If that fails too, the recovery resolves the purchase later.
+func (h *PaidRouteHandler) failPendingPurchase(gCtx context.Context,
+	route *PaidRoute, purchaseID uint64, reason string) {
+
+	err := h.purchaseService.FailPurchase(gCtx, purchaseID, reason)
+	if err != nil {
+		h.logger.Error("Failed to mark purchase as failed", "purchaseID", purchaseID,
+			"shortCode", route.ShortCode, "reason", reason, "error", err)
+	}
+}
+
+// completePurchase marks a pending purchase as settled, accounting for its
+// payment, including the commission of the affiliate that referred it if
+// commission is not nil, and queues the settlement for reconciliation.
+func (h *PaidRouteHandler) completePurchase(gCtx context.Context,
+	route *PaidRoute, purchaseID uint64, paymentAddress string,
+	settleResponseJSON []byte, commission *splits.Commission) error {
+
+	err := h.settlePurchase(gCtx, route, purchaseID, paymentAddress, settleResponseJSON, commission)
+	if err != nil {
+		return err
+	}
+
+	err = h.reconciler.Track(gCtx, purchaseID, settleResponseJSON)
+	if err != nil {
+		h.logger.Error("Failed to queue settlement for reconciliation", "purchaseID", purchaseID,
+			"shortCode", route.ShortCode, "error", err)
 	}
 
 	return nil
 }
+
+// settlePurchase marks a pending purchase as settled together with its
+// payouts and the ledger transactions posting its sale, so a settled
+// purchase is always accounted for.
+func (h *PaidRouteHandler) settlePurchase(gCtx context.Context,
+	route *PaidRoute, purchaseID uint64, paymentAddress string,
+	settleResponseJSON []byte, commission *splits.Commission) error {
+
+	accounting, err := h.purchaseAccounting(gCtx, route, purchaseID, paymentAddress,
+		settleResponseJSON, commission)
+	if err == nil {
+		err = h.purchaseService.SettlePurchase(gCtx, purchaseID, settleResponseJSON, accounting)
+	}
+	if err != nil {
+		// The settle response is logged, so the purchase left pending can be
+		// resolved with it.
+		h.logger.Error("Failed to mark purchase as settled", "purchaseID", purchaseID,
+			"shortCode", route.ShortCode, "settleResponse", string(settleResponseJSON), "error", err)
+		return fmt.Errorf("failed to mark purchase as settled: %w", err)
+	}
+
+	return nil
+}
+
+// purchaseAccounting divides a purchase's payment between its payees and
+// prepares the ledger transactions posting its sale.
+func (h *PaidRouteHandler) purchaseAccounting(gCtx context.Context,
+	route *PaidRoute, purchaseID uint64, paymentAddress string,
+	settleResponseJSON []byte, commission *splits.Commission) (*purchases.Accounting, error) {
+
+	payouts, err := h.splitsService.AllocatePayouts(gCtx, purchaseID, route.ID, route.Price,
+		paymentAddress, commission)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate purchase payouts: %w", err)
+	}
+
+	ledgerTxs, err := h.saleTransactions(route, purchaseID, settleResponseJSON, payouts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare ledger transactions: %w", err)
+	}
+
+	return &purchases.Accounting{
+		Payouts:            payouts,
+		LedgerTransactions: ledgerTxs,
+	}, nil
+}
@@ -0,0 +1,170 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/cache"
+	"linkshrink/idempotency"
+)
+
+const (
+	// IdempotencyKeyHeader lets clients retry a paid non-GET request without
+	// paying for it or forwarding it again.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks a response replayed for a retry.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// maxIdempotencyKeyLength is the longest Idempotency-Key accepted.
+	maxIdempotencyKeyLength = 255
+)
+
+// isRequestIdempotent reports whether the request is a paid request whose
+// Idempotency-Key is honoured.
+func (h *PaidRouteHandler) isRequestIdempotent(gCtx *gin.Context, route *PaidRoute) bool {
+	return h.idempotency.Enabled() &&
+		route.ResourceType == "url" &&
+		gCtx.Request.Method != http.MethodGet &&
+		gCtx.Request.Method != http.MethodHead &&
+		gCtx.GetHeader(IdempotencyKeyHeader) != "" &&
+		gCtx.GetHeader(getPaymentHeaderNameForRoute(route)) != "" &&
+		!isStreamRequest(gCtx.Request)
+}
+
+// beginIdempotentRequest claims the request's Idempotency-Key before it is
+// paid for. A retry of a request that already got a response is answered with
+// that response. Returns the claim, nil if the request is not idempotent, and
+// whether a response was sent.
+func (h *PaidRouteHandler) beginIdempotentRequest(gCtx *gin.Context,
+	route *PaidRoute) (*idempotency.Claim, bool) {
+
+	if !h.isRequestIdempotent(gCtx, route) {
+		return nil, false
+	}
+
+	key := gCtx.GetHeader(IdempotencyKeyHeader)
+	if len(key) > maxIdempotencyKeyLength {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+		return nil, true
+	}
+
+	// The body is part of the request's fingerprint, so it is read here and
+	// put back for the upstream.
+	limit := h.idempotency.MaxBodyBytes()
+	body, err := io.ReadAll(io.LimitReader(gCtx.Request.Body, limit+1))
+	if err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return nil, true
+	}
+	if int64(len(body)) > limit {
+		gCtx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": idempotency.ErrBodyTooLarge.Error()})
+		return nil, true
+	}
+	gCtx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	claim := &idempotency.Claim{
+		RouteID: route.ID,
+		Scope:   idempotency.Scope(gCtx.GetHeader(getPaymentHeaderNameForRoute(route))),
+		Key:     key,
+		Fingerprint: idempotency.Fingerprint(gCtx.Request.Method, gCtx.Request.URL.Path,
+			gCtx.Request.URL.RawQuery, body),
+	}
+
+	response, err := h.idempotency.Begin(gCtx.Request.Context(), claim)
+	switch {
+	case errors.Is(err, idempotency.ErrKeyMismatch), errors.Is(err, idempotency.ErrKeyInProgress):
+		gCtx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return nil, true
+
+	case err != nil:
+		h.logger.Error("Failed to claim idempotency key",
+			"shortCode", route.ShortCode, "error", err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process Idempotency-Key"})
+		return nil, true
+
+	case response != nil:
+		h.logger.Debug("Replaying response for idempotency key",
+			"shortCode", route.ShortCode, "status", response.Status)
+
+		for name, values := range response.Header {
+			for _, value := range values {
+				gCtx.Writer.Header().Add(name, value)
+			}
+		}
+		gCtx.Header(IdempotentReplayedHeader, "true")
+		gCtx.Status(response.Status)
+		if _, err := gCtx.Writer.Write(response.Body); err != nil {
+			h.logger.Error("Failed to write replayed response",
+				"shortCode", route.ShortCode, "error", err)
+		}
+		return nil, true
+	}
+
+	return claim, false
+}
+
+// releaseIdempotentRequest releases a claimed Idempotency-Key whose request
+// got no upstream response, e.g. because its payment failed, so the request
+// can be retried with it. Keys with a stored response are kept.
+func (h *PaidRouteHandler) releaseIdempotentRequest(ctx context.Context,
+	route *PaidRoute, claim *idempotency.Claim) {
+
+	// The client may have gone away, which is when it retries.
+	err := h.idempotency.Release(context.WithoutCancel(ctx), claim)
+	if err != nil {
+		h.logger.Error("Failed to release idempotency key",
+			"shortCode", route.ShortCode, "error", err)
+	}
+}
+
+// idempotencyResponseHook returns a reverse proxy ModifyResponse hook that
+// stores the upstream response for the claimed Idempotency-Key.
+func (h *PaidRouteHandler) idempotencyResponseHook(ctx context.Context, route *PaidRoute,
+	claim *idempotency.Claim) func(*http.Response) error {
+
+	return func(resp *http.Response) error {
+		limit := h.idempotency.MaxBodyBytes()
+		if resp.ContentLength > limit {
+			h.logger.Warn("Upstream response too large to store for idempotency key",
+				"shortCode", route.ShortCode, "contentLength", resp.ContentLength)
+			return nil
+		}
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+		if err != nil {
+			return err
+		}
+
+		if int64(len(body)) > limit {
+			h.logger.Warn("Upstream response too large to store for idempotency key",
+				"shortCode", route.ShortCode)
+
+			// Stream what was read followed by the rest.
+			resp.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+			return nil
+		}
+
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		err = h.idempotency.Complete(context.WithoutCancel(ctx), claim, &idempotency.Response{
+			Status: resp.StatusCode,
+			Header: cache.StorableHeader(resp.Header),
+			Body:   body,
+		})
+		if err != nil {
+			h.logger.Error("Failed to store response for idempotency key",
+				"shortCode", route.ShortCode, "error", err)
+		}
+
+		return nil
+	}
+}
@@ -3,13 +3,23 @@ package routes
 import (
 	"context"
 	"errors"
+	"time"
+
+	"linkshrink/bundles"
 )
 
 // Custom errors for route operations
 var (
-	ErrRouteNotFound     = errors.New("route not found")
-	ErrRouteDisabled     = errors.New("route is disabled")
-	ErrRouteNoPermission = errors.New("you do not have permission to access this route")
+	ErrRouteNotFound       = errors.New("route not found")
+	ErrRouteDisabled       = errors.New("route is disabled")
+	ErrRouteNoPermission   = errors.New("you do not have permission to access this route")
+	ErrRouteNotStarted     = errors.New("route is not available yet")
+	ErrRouteEnded          = errors.New("route is no longer available")
+	ErrRouteSoldOut        = errors.New("route is sold out")
+	ErrInvalidAvailability = errors.New("invalid availability")
+
+	ErrPriceChangeNotFound = errors.New("price change not found")
+	ErrInvalidPriceChange  = errors.New("invalid price change")
 )
 
 // Store provides access to the paid route storage.
@@ -17,6 +27,11 @@ type Store interface {
 	// CreateRoute inserts a new paid route in the database and returns the route.
 	CreateRoute(ctx context.Context, route *PaidRoute) (*PaidRoute, error)
 
+	// CreateBundleRoute atomically creates a bundle route, its bundle and the
+	// bundle's members, listed in the order of memberIDs.
+	CreateBundleRoute(ctx context.Context, route *PaidRoute, bundle *bundles.Bundle,
+		memberIDs []uint64) (*PaidRoute, error)
+
 	// FindRouteByID retrieves a paid route by ID.
 	FindRouteByID(ctx context.Context, id uint64) (*PaidRoute, error)
 
@@ -29,9 +44,18 @@ type Store interface {
 	// ListUserRoutes retrieves all paid routes for a specific user.
 	ListUserRoutes(ctx context.Context, userID uint64) ([]PaidRoute, error)
 
+	// ListDiscoverableRoutes retrieves a page of the enabled routes listed
+	// for discovery that match the filter, newest first, and how many
+	// routes match it in total.
+	ListDiscoverableRoutes(ctx context.Context, filter *DiscoveryFilter) ([]PaidRoute, uint64, error)
+
 	// DeleteRoute soft-deletes a paid route.
 	DeleteRoute(ctx context.Context, routeID uint64, userID uint64) error
 
+	// UpdateRouteLoadBalancing updates the load balancing settings of a route.
+	UpdateRouteLoadBalancing(ctx context.Context, routeID uint64, userID uint64,
+		strategy string, healthCheckPath string) error
+
 	// IncrementRouteAttemptCount increments the attempt_count for a route.
 	IncrementRouteAttemptCount(ctx context.Context, shortCode string) error
 
@@ -40,4 +64,23 @@ type Store interface {
 
 	// IncrementRouteAccessCount increments the access_count for a route.
 	IncrementRouteAccessCount(ctx context.Context, shortCode string) error
+
+	// DisableEndedRoutes disables the routes whose availability window
+	// closed at now and returns how many were.
+	DisableEndedRoutes(ctx context.Context, now time.Time) (int64, error)
+
+	// CreatePriceChange schedules a price change of a route.
+	CreatePriceChange(ctx context.Context, change *PriceChange) (*PriceChange, error)
+
+	// ListPriceChanges retrieves the price changes of a route, earliest
+	// first.
+	ListPriceChanges(ctx context.Context, routeID uint64) ([]PriceChange, error)
+
+	// DeletePriceChange deletes a price change of a route that was not
+	// applied yet, ErrPriceChangeNotFound if there is none.
+	DeletePriceChange(ctx context.Context, changeID, routeID uint64) error
+
+	// ApplyDuePriceChanges changes the prices of routes with up to limit of
+	// the price changes due at now, earliest first, and returns them.
+	ApplyDuePriceChanges(ctx context.Context, now time.Time, limit int) ([]PriceChange, error)
 }
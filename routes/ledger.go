@@ -0,0 +1,49 @@
+package routes
+
+import (
+	"encoding/json"
+
+	"linkshrink/ledger"
+	"linkshrink/purchases"
+	"linkshrink/splits"
+)
+
+// settlementPayer returns the payer address reported in a settle response,
+// which is the same for x402 v1 and v2.
+func settlementPayer(settleResponseJSON []byte) string {
+	var settlement struct {
+		Payer string `json:"payer"`
+	}
+	_ = json.Unmarshal(settleResponseJSON, &settlement)
+	return settlement.Payer
+}
+
+// saleTransactions returns the ledger transactions posting a new purchase.
+func (h *PaidRouteHandler) saleTransactions(route *PaidRoute, purchaseID uint64,
+	settleResponseJSON []byte, payouts []splits.Payout) ([]ledger.Transaction, error) {
+
+	return h.ledgerService.SaleTransactions(ledger.Sale{
+		PurchaseID: purchaseID,
+		RouteID:    route.ID,
+		OwnerID:    route.UserID,
+		Payer:      settlementPayer(settleResponseJSON),
+		IsTest:     route.IsTest,
+		Price:      route.Price,
+		Payouts:    payouts,
+		Credits:    route.Credits,
+		Metered:    route.BillingMode == BillingModeMetered,
+		Bundle:     route.ResourceType == ResourceTypeBundle,
+	})
+}
+
+// creditUse describes using a credit of a purchase of route, posted to the
+// ledger with the credit being used.
+func creditUse(route *PaidRoute, purchase *purchases.Purchase) ledger.CreditUse {
+	return ledger.CreditUse{
+		PurchaseID: purchase.ID,
+		RouteID:    route.ID,
+		OwnerID:    route.UserID,
+		IsTest:     purchase.IsTest,
+		Credits:    purchase.CreditsAvailable,
+	}
+}
@@ -0,0 +1,76 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"linkshrink/ledger"
+	"linkshrink/money"
+)
+
+const (
+	// UsageHeader is set by upstreams of metered routes to report the usage
+	// units of a response. It is passed on to the buyer.
+	UsageHeader = "Proxy402-Usage"
+	// AmountChargedHeader reports the total charged to the buyer's purchase.
+	AmountChargedHeader = "Proxy402-Amount-Charged"
+	// BalanceHeader reports what is left of the buyer's prepaid balance.
+	BalanceHeader = "Proxy402-Balance"
+)
+
+// formatUnitPrice returns the unit price of a metered route as a decimal
+// string, or an empty string for routes with a fixed price.
+func (h *PaidRouteHandler) formatUnitPrice(route *PaidRoute) string {
+	if route.BillingMode != BillingModeMetered {
+		return ""
+	}
+	return money.USDC.Format(route.UnitPrice)
+}
+
+// chargeUsage charges the usage reported in an upstream response to the
+// purchase the request was paid with, and reports the charge to the buyer.
+func (h *PaidRouteHandler) chargeUsage(ctx context.Context, route *PaidRoute,
+	paymentHeader string, resp *http.Response) {
+
+	usage := resp.Header.Get(UsageHeader)
+	if usage == "" {
+		h.logger.Warn("Metered route upstream did not report usage",
+			"shortCode", route.ShortCode, "status", resp.StatusCode)
+		return
+	}
+
+	units, err := strconv.ParseUint(strings.TrimSpace(usage), 10, 63)
+	if err != nil {
+		h.logger.Warn("Metered route upstream reported invalid usage",
+			"shortCode", route.ShortCode, "usage", usage, "error", err)
+		resp.Header.Del(UsageHeader)
+		return
+	}
+
+	purchase, err := h.purchaseService.GetPurchaseByRouteIDAndPaymentHeader(ctx, route.ID, paymentHeader)
+	if err != nil {
+		h.logger.Error("Failed to find purchase to charge usage",
+			"shortCode", route.ShortCode, "usage", units, "error", err)
+		return
+	}
+
+	charged, _, err := h.purchaseService.ChargeUsage(ctx, ledger.Usage{
+		PurchaseID: purchase.ID,
+		RouteID:    route.ID,
+		OwnerID:    route.UserID,
+		IsTest:     purchase.IsTest,
+	}, units, route.UnitPrice)
+	if err != nil {
+		h.logger.Error("Failed to charge usage to purchase",
+			"shortCode", route.ShortCode, "purchaseID", purchase.ID, "usage", units, "error", err)
+		return
+	}
+
+	h.logger.Debug("Charged usage to purchase", "shortCode", route.ShortCode,
+		"purchaseID", charged.ID, "usage", units, "amountCharged", charged.AmountCharged)
+
+	resp.Header.Set(AmountChargedHeader, money.USDC.Format(charged.AmountCharged))
+	resp.Header.Set(BalanceHeader, money.USDC.Format(charged.Balance()))
+}
@@ -0,0 +1,200 @@
+package routes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/coupons"
+	"linkshrink/packs"
+	"linkshrink/payers"
+	"linkshrink/x402"
+)
+
+// PackQueryParam selects the credit pack a buyer pays for. Without it, a
+// payment buys the pack whose price it pays.
+const PackQueryParam = "pack"
+
+// errNoMatchingOption is reported for v2 payments that verified against none
+// of the route's payment options.
+var errNoMatchingOption = errors.New("payment does not match a price of this route")
+
+// paymentOption is a way to pay for a route, its own price and credits or one
+// of its credit packs.
+type paymentOption struct {
+	// route is priced and credited for the option, with the payer's and the
+	// coupon's discount applied.
+	route *PaidRoute
+	// pack is nil for the route's own price and credits.
+	pack     *packs.Pack
+	discount uint64
+}
+
+// packID returns the ID of the option's pack, zero for the route's own
+// credits.
+func (o paymentOption) packID() uint64 {
+	if o.pack == nil {
+		return 0
+	}
+	return o.pack.ID
+}
+
+// packsApply reports whether the route can sell credit packs. Metered routes
+// prepay a balance, quoted routes price each request and pay what you want
+// routes let the buyer price it, so none of them sells credits.
+func packsApply(route *PaidRoute) bool {
+	return route.BillingMode != BillingModeMetered && route.QuoteURL == "" && !route.PayWhatYouWant
+}
+
+// paymentOptions returns the ways the request can pay for the route, the
+// route's own price first. If the request selects a pack with
+// PackQueryParam, that pack is the only option. It sends an error response
+// and returns requestHandled=true if the selected pack does not exist.
+func (h *PaidRouteHandler) paymentOptions(gCtx *gin.Context, route *PaidRoute,
+	coupon *coupons.Coupon, payerRule *payers.Rule) (options []paymentOption, requestHandled bool) {
+
+	query := gCtx.Request.URL.Query()
+	selected := query.Get(PackQueryParam)
+	if query.Has(PackQueryParam) {
+		// The selection is meant for the proxy, not the upstream.
+		query.Del(PackQueryParam)
+		gCtx.Request.URL.RawQuery = query.Encode()
+	}
+
+	var routePacks []packs.Pack
+	if packsApply(route) {
+		var err error
+		routePacks, err = h.packService.ListPacks(gCtx.Request.Context(), route.ID)
+		if err != nil {
+			h.logger.Error("Failed to list credit packs", "shortCode", route.ShortCode, "error", err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve credit packs"})
+			return nil, true
+		}
+	}
+
+	if selected != "" {
+		for i := range routePacks {
+			if strconv.FormatUint(routePacks[i].ID, 10) == selected {
+				return []paymentOption{priceOption(route, &routePacks[i], coupon, payerRule)}, false
+			}
+		}
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": packs.ErrPackNotFound.Error()})
+		return nil, true
+	}
+
+	options = make([]paymentOption, 0, len(routePacks)+1)
+	options = append(options, priceOption(route, nil, coupon, payerRule))
+	for i := range routePacks {
+		options = append(options, priceOption(route, &routePacks[i], coupon, payerRule))
+	}
+
+	return options, false
+}
+
+// priceOption prices the route for a pack, or for its own credits if pack is
+// nil, and applies the payer's and the coupon's discount.
+func priceOption(route *PaidRoute, pack *packs.Pack, coupon *coupons.Coupon,
+	payerRule *payers.Rule) paymentOption {
+
+	if pack != nil {
+		packRoute := *route
+		packRoute.Price = pack.Price
+		packRoute.Credits = pack.Credits
+		route = &packRoute
+	}
+
+	route = applyPayerRule(route, payerRule)
+	route, discount := applyCoupon(route, coupon)
+
+	return paymentOption{
+		route:    route,
+		pack:     pack,
+		discount: discount,
+	}
+}
+
+// selectPaymentOption returns the option whose price the request's payment
+// authorizes, the first option if it has no payment or matches none.
+func selectPaymentOption(gCtx *gin.Context, route *PaidRoute,
+	options []paymentOption) paymentOption {
+
+	amount, ok := paymentAmount(gCtx.GetHeader(getPaymentHeaderNameForRoute(route)))
+	if ok {
+		if option, found := findPaymentOption(options, amount); found {
+			return option
+		}
+	}
+	return options[0]
+}
+
+// findPaymentOption returns the option priced at amount.
+func findPaymentOption(options []paymentOption, amount uint64) (paymentOption, bool) {
+	for _, option := range options {
+		if option.route.Price == amount {
+			return option, true
+		}
+	}
+	return paymentOption{}, false
+}
+
+// paymentAmount returns the amount the authorization of an x402 v1 or v2
+// exact EVM payment header transfers.
+func paymentAmount(paymentHeader string) (uint64, bool) {
+	if paymentHeader == "" {
+		return 0, false
+	}
+	paymentPayloadJSON, err := base64.StdEncoding.DecodeString(paymentHeader)
+	if err != nil {
+		return 0, false
+	}
+
+	var payment struct {
+		Payload struct {
+			Authorization struct {
+				Value string `json:"value"`
+			} `json:"authorization"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(paymentPayloadJSON, &payment); err != nil {
+		return 0, false
+	}
+
+	amount, err := strconv.ParseUint(payment.Payload.Authorization.Value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return amount, true
+}
+
+// paymentAlternatives lists the options other than selected for the paywall.
+// Options made free by a coupon can't be paid for, so they are left out.
+func paymentAlternatives(accessURL string, options []paymentOption,
+	selected paymentOption) []x402.Alternative {
+
+	var alternatives []x402.Alternative
+	for _, option := range options {
+		if option.pack == selected.pack || option.route.Price == 0 {
+			continue
+		}
+
+		alternative := x402.Alternative{
+			Amount: option.route.Price,
+			Label:  fmt.Sprintf("%d credits", option.route.Credits),
+			URL:    accessURL,
+		}
+		if option.pack != nil {
+			if option.pack.Label != "" {
+				alternative.Label = option.pack.Label
+			}
+			alternative.URL = withQueryParam(accessURL, PackQueryParam, strconv.FormatUint(option.pack.ID, 10))
+		}
+		alternatives = append(alternatives, alternative)
+	}
+
+	return alternatives
+}
@@ -0,0 +1,113 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/money"
+	"linkshrink/packs"
+)
+
+// CreatePackRequest defines the body for adding a credit pack to a route.
+type CreatePackRequest struct {
+	Credits uint64 `form:"credits" json:"credits" binding:"required"`
+	Price   string `form:"price" json:"price" binding:"required"` // In USDC, e.g. "0.50"
+	Label   string `form:"label" json:"label" binding:"omitempty"`
+}
+
+// GetRoutePacks handles GET requests for the credit packs of a route.
+func (h *PaidRouteHandler) GetRoutePacks(gCtx *gin.Context) {
+	route := h.getOwnedRoute(gCtx)
+	if route == nil {
+		return
+	}
+
+	routePacks, err := h.packService.ListPacks(gCtx.Request.Context(), route.ID)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve credit packs"})
+		return
+	}
+	if routePacks == nil {
+		routePacks = []packs.Pack{}
+	}
+
+	gCtx.JSON(http.StatusOK, gin.H{"packs": routePacks})
+}
+
+// CreateRoutePack handles POST requests to offer a number of credits of a
+// route for a price, next to the route's own price and credits.
+func (h *PaidRouteHandler) CreateRoutePack(gCtx *gin.Context) {
+	var req CreatePackRequest
+	if err := gCtx.ShouldBind(&req); err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	price, err := money.USDC.Parse(req.Price)
+	if err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid price: " + err.Error()})
+		return
+	}
+
+	route := h.getOwnedRoute(gCtx)
+	if route == nil {
+		return
+	}
+
+	if !packsApply(route) {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Only fixed price routes can have credit packs"})
+		return
+	}
+
+	pack, err := h.packService.CreatePack(gCtx.Request.Context(), &packs.Pack{
+		PaidRouteID: route.ID,
+		Credits:     req.Credits,
+		Price:       price,
+		Label:       req.Label,
+	}, route.Price)
+	if err != nil {
+		if errors.Is(err, packs.ErrInvalidCredits) || errors.Is(err, packs.ErrInvalidPrice) {
+			gCtx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if errors.Is(err, packs.ErrPriceTaken) {
+			gCtx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else {
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create credit pack"})
+		}
+		return
+	}
+
+	gCtx.JSON(http.StatusCreated, pack)
+}
+
+// DeleteRoutePack handles DELETE requests to stop offering a credit pack.
+// Purchases of the pack keep their credits.
+func (h *PaidRouteHandler) DeleteRoutePack(gCtx *gin.Context) {
+	packID, err := strconv.ParseUint(gCtx.Param("packID"), 10, 64)
+	if err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pack ID format"})
+		return
+	}
+
+	route := h.getOwnedRoute(gCtx)
+	if route == nil {
+		return
+	}
+
+	err = h.packService.DeletePack(gCtx.Request.Context(), packID, route.ID)
+	if err != nil {
+		if errors.Is(err, packs.ErrPackNotFound) {
+			gCtx.JSON(http.StatusNotFound, gin.H{"error": "Credit pack not found"})
+		} else {
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete credit pack"})
+		}
+		return
+	}
+
+	gCtx.Status(http.StatusOK)
+}
@@ -0,0 +1,123 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/money"
+	"linkshrink/x402"
+)
+
+// AmountQueryParam sets the amount a buyer pays for a pay what you want
+// route. Without it, the buyer pays the route's suggested price.
+const AmountQueryParam = "amount"
+
+// validatePayWhatYouWant checks the prices of a pay what you want route,
+// the suggested price must be within its min and max price.
+func validatePayWhatYouWant(price, minPrice, maxPrice string) error {
+	suggested, err := money.USDC.Parse(price)
+	if err != nil {
+		return fmt.Errorf("invalid suggested price: %w", err)
+	}
+
+	minAmount, maxAmount, err := parsePriceBounds(minPrice, maxPrice)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case suggested == 0:
+		return errors.New("pay what you want routes require a suggested price")
+	case suggested < minAmount:
+		return errors.New("suggested price must be greater or equal to min price")
+	case maxAmount > 0 && suggested > maxAmount:
+		return errors.New("suggested price must be less or equal to max price")
+	}
+	return nil
+}
+
+// parsePriceBounds parses the optional min and max price of a route, an
+// empty price is 0.
+func parsePriceBounds(minPrice, maxPrice string) (minAmount, maxAmount uint64, err error) {
+	if minPrice != "" {
+		if minAmount, err = money.USDC.Parse(minPrice); err != nil {
+			return 0, 0, fmt.Errorf("invalid min price: %w", err)
+		}
+	}
+	if maxPrice != "" {
+		if maxAmount, err = money.USDC.Parse(maxPrice); err != nil {
+			return 0, 0, fmt.Errorf("invalid max price: %w", err)
+		}
+	}
+	return minAmount, maxAmount, nil
+}
+
+// checkChosenAmount returns an error describing why amount can't be paid for
+// the pay what you want route.
+func checkChosenAmount(route *PaidRoute, amount uint64) error {
+	switch {
+	case amount == 0:
+		return errors.New("amount must be greater than 0")
+	case amount < route.MinPrice:
+		return fmt.Errorf("amount must be at least %s USDC", money.USDC.FormatCompact(route.MinPrice))
+	case route.MaxPrice > 0 && amount > route.MaxPrice:
+		return fmt.Errorf("amount must be at most %s USDC", money.USDC.FormatCompact(route.MaxPrice))
+	}
+	return nil
+}
+
+// chooseAmount returns the pay what you want route priced at the amount the
+// request chooses with AmountQueryParam, at its suggested price if it
+// chooses none. Other routes are returned as they are. It sends an error
+// response and returns requestHandled=true if the amount is not accepted.
+func (h *PaidRouteHandler) chooseAmount(gCtx *gin.Context, route *PaidRoute) (*PaidRoute, bool) {
+	if !route.PayWhatYouWant {
+		return route, false
+	}
+
+	query := gCtx.Request.URL.Query()
+	chosen := query.Get(AmountQueryParam)
+	if query.Has(AmountQueryParam) {
+		// The amount is meant for the proxy, not the upstream.
+		query.Del(AmountQueryParam)
+		gCtx.Request.URL.RawQuery = query.Encode()
+	}
+	if chosen == "" {
+		return route, false
+	}
+
+	amount, err := money.USDC.Parse(chosen)
+	if err == nil {
+		err = checkChosenAmount(route, amount)
+	}
+	if err != nil {
+		h.logger.Info("Rejected chosen amount", "shortCode", route.ShortCode, "amount", chosen, "error", err)
+		gCtx.JSON(http.StatusBadRequest, gin.H{
+			"error":     "Invalid amount: " + err.Error(),
+			"min_price": money.USDC.Format(route.MinPrice),
+			"max_price": money.USDC.Format(route.MaxPrice),
+		})
+		return nil, true
+	}
+
+	chosenRoute := *route
+	chosenRoute.Price = amount
+	return &chosenRoute, false
+}
+
+// amountInput returns the paywall field for choosing the amount of a pay
+// what you want route priced at the chosen amount, nil for other routes.
+func amountInput(route *PaidRoute) *x402.AmountInput {
+	if !route.PayWhatYouWant {
+		return nil
+	}
+	return &x402.AmountInput{
+		Name:  AmountQueryParam,
+		Value: route.Price,
+		Min:   max(route.MinPrice, 1),
+		Max:   route.MaxPrice,
+	}
+}
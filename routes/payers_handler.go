@@ -0,0 +1,128 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/auth"
+	"linkshrink/payers"
+)
+
+// CreatePayerRuleRequest defines the body for creating a payer rule.
+type CreatePayerRuleRequest struct {
+	PayerAddress string `form:"payer_address" json:"payer_address" binding:"required"`
+	Treatment    string `form:"treatment" json:"treatment" binding:"required,oneof=free discount block"`
+	DiscountBPS  uint64 `form:"discount_bps" json:"discount_bps" binding:"omitempty"` // Required for discount rules, 2500 = 25% off
+	RouteID      uint64 `form:"route_id" json:"route_id" binding:"omitempty"`         // Optional, all of the owner's routes if empty
+	Label        string `form:"label" json:"label" binding:"omitempty"`
+}
+
+// GetPayerRules handles GET requests for the payer rules of the user.
+func (h *PaidRouteHandler) GetPayerRules(gCtx *gin.Context) {
+	authPayload, exists := gCtx.Get(auth.AuthorizationPayloadKey)
+	if !exists {
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	payload := authPayload.(*auth.Claims)
+
+	rules, err := h.payerService.ListRules(gCtx.Request.Context(), payload.UserID)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve payer rules"})
+		return
+	}
+	if rules == nil {
+		rules = []payers.Rule{}
+	}
+
+	gCtx.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// CreatePayerRule handles POST requests to let a payer through for free, at
+// a discount or to block it on one or all of the user's routes.
+func (h *PaidRouteHandler) CreatePayerRule(gCtx *gin.Context) {
+	var req CreatePayerRuleRequest
+	if err := gCtx.ShouldBind(&req); err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	authPayload, exists := gCtx.Get(auth.AuthorizationPayloadKey)
+	if !exists {
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	payload := authPayload.(*auth.Claims)
+
+	ctx := gCtx.Request.Context()
+	if req.RouteID != 0 {
+		_, err := h.paidRouteService.GetUserRoute(ctx, req.RouteID, payload.UserID)
+		if err != nil {
+			if errors.Is(err, ErrRouteNotFound) || errors.Is(err, ErrRouteNoPermission) {
+				gCtx.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+			} else {
+				gCtx.Error(err)
+				gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve route"})
+			}
+			return
+		}
+	}
+
+	rule, err := h.payerService.CreateRule(ctx, &payers.Rule{
+		UserID:       payload.UserID,
+		RouteID:      req.RouteID,
+		PayerAddress: req.PayerAddress,
+		Treatment:    req.Treatment,
+		DiscountBPS:  req.DiscountBPS,
+		Label:        req.Label,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, payers.ErrRuleExists):
+			gCtx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, payers.ErrInvalidAddress), errors.Is(err, payers.ErrInvalidTreatment),
+			errors.Is(err, payers.ErrInvalidDiscount):
+
+			gCtx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create payer rule"})
+		}
+		return
+	}
+
+	gCtx.JSON(http.StatusCreated, rule)
+}
+
+// DeletePayerRule handles DELETE requests for a payer rule of the user.
+func (h *PaidRouteHandler) DeletePayerRule(gCtx *gin.Context) {
+	ruleID, err := strconv.ParseUint(gCtx.Param("ruleID"), 10, 64)
+	if err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID format"})
+		return
+	}
+
+	authPayload, exists := gCtx.Get(auth.AuthorizationPayloadKey)
+	if !exists {
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	payload := authPayload.(*auth.Claims)
+
+	err = h.payerService.DeleteRule(gCtx.Request.Context(), ruleID, payload.UserID)
+	if err != nil {
+		if errors.Is(err, payers.ErrRuleNotFound) {
+			gCtx.JSON(http.StatusNotFound, gin.H{"error": "Payer rule not found"})
+		} else {
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete payer rule"})
+		}
+		return
+	}
+
+	gCtx.Status(http.StatusOK)
+}
@@ -0,0 +1,205 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	x402core "github.com/coinbase/x402/go"
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/auth"
+	"linkshrink/money"
+	"linkshrink/purchases"
+)
+
+// ResolvePurchaseRequest defines the body for resolving a pending purchase.
+type ResolvePurchaseRequest struct {
+	Status      string `form:"status" json:"status" binding:"required,oneof=settled failed"`
+	Transaction string `form:"transaction" json:"transaction" binding:"required_if=Status settled"` // Hash of the settlement transaction
+	Reason      string `form:"reason" json:"reason" binding:"omitempty"`                            // Optional, why the payment failed
+}
+
+// PendingPurchaseResponse represents a purchase whose payment is not known to
+// be settled.
+type PendingPurchaseResponse struct {
+	ID          uint64    `json:"id"`
+	ShortCode   string    `json:"short_code"`
+	Price       string    `json:"price"`
+	IsTest      bool      `json:"is_test"`
+	Payer       string    `json:"payer"`
+	Network     string    `json:"network"`
+	SettleError string    `json:"settle_error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// paymentPayer returns the payer address authorizing a payment, which is in
+// the same place for x402 v1 and v2 exact EVM payloads.
+func paymentPayer(paymentPayloadJSON []byte) string {
+	var payment struct {
+		Payload struct {
+			Authorization struct {
+				From string `json:"from"`
+			} `json:"authorization"`
+		} `json:"payload"`
+	}
+	_ = json.Unmarshal(paymentPayloadJSON, &payment)
+	return payment.Payload.Authorization.From
+}
+
+// paymentNetwork returns the network a payment was required on, which is in
+// the same place for x402 v1 and v2 requirements.
+func paymentNetwork(paymentRequirementsJSON []byte) string {
+	var requirements struct {
+		Network string `json:"network"`
+	}
+	_ = json.Unmarshal(paymentRequirementsJSON, &requirements)
+	return requirements.Network
+}
+
+// GetPendingPurchases handles GET requests for the purchases of the user's
+// routes whose payment is not known to be settled.
+func (h *PaidRouteHandler) GetPendingPurchases(gCtx *gin.Context) {
+	authPayload, exists := gCtx.Get(auth.AuthorizationPayloadKey)
+	if !exists {
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	payload := authPayload.(*auth.Claims)
+
+	pending, err := h.purchaseService.ListPendingPurchases(gCtx.Request.Context(), payload.UserID)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve pending purchases"})
+		return
+	}
+
+	response := make([]PendingPurchaseResponse, len(pending))
+	for i, purchase := range pending {
+		response[i] = PendingPurchaseResponse{
+			ID:          purchase.ID,
+			ShortCode:   purchase.ShortCode,
+			Price:       money.USDC.Format(purchase.Price),
+			IsTest:      purchase.IsTest,
+			Payer:       paymentPayer(purchase.PaymentPayload),
+			Network:     paymentNetwork(purchase.PaymentRequirements),
+			SettleError: purchase.SettleError,
+			CreatedAt:   purchase.CreatedAt,
+		}
+	}
+
+	gCtx.JSON(http.StatusOK, gin.H{"purchases": response})
+}
+
+// ResolvePendingPurchase handles POST requests by a route owner to resolve a
+// pending purchase that the recovery could not, either with the hash of the
+// transaction that settled it or as failed.
+func (h *PaidRouteHandler) ResolvePendingPurchase(gCtx *gin.Context) {
+	var req ResolvePurchaseRequest
+	if err := gCtx.ShouldBind(&req); err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	purchaseID, err := strconv.ParseUint(gCtx.Param("purchaseID"), 10, 64)
+	if err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid purchase ID format"})
+		return
+	}
+
+	authPayload, exists := gCtx.Get(auth.AuthorizationPayloadKey)
+	if !exists {
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	payload := authPayload.(*auth.Claims)
+
+	ctx := gCtx.Request.Context()
+	purchase, err := h.purchaseService.GetPurchaseByID(ctx, purchaseID)
+	if err != nil {
+		if errors.Is(err, purchases.ErrPurchaseNotFound) {
+			gCtx.JSON(http.StatusNotFound, gin.H{"error": "Purchase not found"})
+		} else {
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve purchase"})
+		}
+		return
+	}
+
+	route, err := h.paidRouteService.GetUserRoute(ctx, purchase.PaidRouteID, payload.UserID)
+	if err != nil {
+		if errors.Is(err, ErrRouteNotFound) || errors.Is(err, ErrRouteNoPermission) {
+			gCtx.JSON(http.StatusNotFound, gin.H{"error": "Purchase not found"})
+		} else {
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve route"})
+		}
+		return
+	}
+
+	if purchase.Status != purchases.StatusPending {
+		gCtx.JSON(http.StatusConflict, gin.H{"error": "Purchase is not pending"})
+		return
+	}
+
+	if req.Status == purchases.StatusFailed {
+		reason := req.Reason
+		if reason == "" {
+			reason = "resolved as failed by the route owner"
+		}
+
+		err = h.purchaseService.FailPurchase(ctx, purchase.ID, reason)
+		if err != nil {
+			if errors.Is(err, purchases.ErrNotPending) {
+				gCtx.JSON(http.StatusConflict, gin.H{"error": "Purchase is not pending"})
+				return
+			}
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve purchase"})
+			return
+		}
+
+		gCtx.JSON(http.StatusOK, gin.H{"status": purchases.StatusFailed})
+		return
+	}
+
+	settleResponseJSON, err := json.Marshal(x402core.SettleResponse{
+		Success:     true,
+		Payer:       paymentPayer(purchase.PaymentPayload),
+		Transaction: req.Transaction,
+		Network:     x402core.Network(paymentNetwork(purchase.PaymentRequirements)),
+	})
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve purchase"})
+		return
+	}
+
+	// Account for the purchase at what was paid for it and the credits it
+	// bought, which differ from the route's for quoted routes and packs.
+	paidRoute := *route
+	paidRoute.Price = purchase.Price
+	paidRoute.Credits = purchase.CreditsAvailable
+
+	err = h.completePurchase(ctx, &paidRoute, purchase.ID, purchase.PaidToAddress, settleResponseJSON,
+		h.purchaseCommission(ctx, route, purchase.AffiliateID))
+	if err != nil {
+		if errors.Is(err, purchases.ErrNotPending) {
+			gCtx.JSON(http.StatusConflict, gin.H{"error": "Purchase is not pending"})
+			return
+		}
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve purchase"})
+		return
+	}
+
+	err = h.paidRouteService.IncrementPaymentCount(ctx, route.ShortCode)
+	if err != nil {
+		h.logger.Error("Failed to increment payment count after resolving purchase",
+			"shortCode", route.ShortCode, "purchaseID", purchase.ID, "error", err)
+	}
+
+	gCtx.JSON(http.StatusOK, gin.H{"status": purchases.StatusSettled})
+}
@@ -0,0 +1,165 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/auth"
+	"linkshrink/upstreams"
+)
+
+// AddRouteTargetRequest defines the body for adding a target to a route's pool.
+type AddRouteTargetRequest struct {
+	TargetURL string `form:"target_url" json:"target_url" binding:"required,url"`
+	Weight    uint64 `form:"weight" json:"weight" binding:"omitempty"` // Optional, defaults to 1
+}
+
+// UpdateLoadBalancingRequest defines the body for updating a route's pool settings.
+type UpdateLoadBalancingRequest struct {
+	Strategy        string `form:"strategy" json:"strategy" binding:"omitempty"`                   // Optional, defaults to round_robin
+	HealthCheckPath string `form:"health_check_path" json:"health_check_path" binding:"omitempty"` // Optional, empty disables active checks
+}
+
+// PoolResponse represents the upstream pool of a route.
+type PoolResponse struct {
+	Strategy        string                   `json:"strategy"`
+	HealthCheckPath string                   `json:"health_check_path"`
+	Targets         []upstreams.TargetStatus `json:"targets"`
+}
+
+// getOwnedRoute resolves the :linkID path parameter to a route owned by the
+// authenticated user. It sends an error response and returns nil on failure.
+func (h *PaidRouteHandler) getOwnedRoute(gCtx *gin.Context) *PaidRoute {
+	routeID, err := strconv.ParseUint(gCtx.Param("linkID"), 10, 64)
+	if err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid route ID format"})
+		return nil
+	}
+
+	authPayload, exists := gCtx.Get(auth.AuthorizationPayloadKey)
+	if !exists {
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return nil
+	}
+	payload := authPayload.(*auth.Claims)
+
+	route, err := h.paidRouteService.GetUserRoute(gCtx.Request.Context(), routeID, payload.UserID)
+	if err != nil {
+		if errors.Is(err, ErrRouteNotFound) || errors.Is(err, ErrRouteNoPermission) {
+			gCtx.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+		} else {
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve route"})
+		}
+		return nil
+	}
+
+	return route
+}
+
+// GetRoutePool handles GET requests for the upstream pool of a route.
+func (h *PaidRouteHandler) GetRoutePool(gCtx *gin.Context) {
+	route := h.getOwnedRoute(gCtx)
+	if route == nil {
+		return
+	}
+
+	targets, err := h.upstreamService.PoolStatus(gCtx.Request.Context(), route.ID)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve upstream pool"})
+		return
+	}
+
+	gCtx.JSON(http.StatusOK, PoolResponse{
+		Strategy:        route.LBStrategy,
+		HealthCheckPath: route.HealthCheckPath,
+		Targets:         targets,
+	})
+}
+
+// AddRouteTarget handles POST requests to add a target to a route's pool.
+func (h *PaidRouteHandler) AddRouteTarget(gCtx *gin.Context) {
+	var req AddRouteTargetRequest
+	if err := gCtx.ShouldBind(&req); err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	route := h.getOwnedRoute(gCtx)
+	if route == nil {
+		return
+	}
+
+	if route.ResourceType != "url" {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Only URL routes can have an upstream pool"})
+		return
+	}
+
+	target, err := h.upstreamService.AddTarget(gCtx.Request.Context(), route.ID,
+		route.TargetURL, req.TargetURL, req.Weight)
+	if err != nil {
+		if errors.Is(err, upstreams.ErrInvalidTarget) {
+			gCtx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add upstream target"})
+		}
+		return
+	}
+
+	gCtx.JSON(http.StatusCreated, target)
+}
+
+// DeleteRouteTarget handles DELETE requests to remove a target from a route's pool.
+func (h *PaidRouteHandler) DeleteRouteTarget(gCtx *gin.Context) {
+	targetID, err := strconv.ParseUint(gCtx.Param("targetID"), 10, 64)
+	if err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target ID format"})
+		return
+	}
+
+	route := h.getOwnedRoute(gCtx)
+	if route == nil {
+		return
+	}
+
+	err = h.upstreamService.RemoveTarget(gCtx.Request.Context(), route.ID, targetID)
+	if err != nil {
+		if errors.Is(err, upstreams.ErrTargetNotFound) {
+			gCtx.JSON(http.StatusNotFound, gin.H{"error": "Upstream target not found"})
+		} else {
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove upstream target"})
+		}
+		return
+	}
+
+	gCtx.Status(http.StatusOK)
+}
+
+// UpdateRouteLoadBalancing handles PUT requests to update a route's pool settings.
+func (h *PaidRouteHandler) UpdateRouteLoadBalancing(gCtx *gin.Context) {
+	var req UpdateLoadBalancingRequest
+	if err := gCtx.ShouldBind(&req); err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	route := h.getOwnedRoute(gCtx)
+	if route == nil {
+		return
+	}
+
+	err := h.paidRouteService.UpdateLoadBalancing(gCtx.Request.Context(), route.ID,
+		route.UserID, req.Strategy, req.HealthCheckPath)
+	if err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	gCtx.Status(http.StatusOK)
+}
@@ -0,0 +1,125 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/money"
+	"linkshrink/packs"
+)
+
+// CreatePriceChangeRequest defines the body for scheduling a route's price
+// to change.
+type CreatePriceChangeRequest struct {
+	Price       string `form:"price" json:"price" binding:"required"`               // In USDC, e.g. "0.50"
+	EffectiveAt string `form:"effective_at" json:"effective_at" binding:"required"` // RFC 3339 timestamp
+}
+
+// GetRoutePriceChanges handles GET requests for the scheduled price changes
+// of a route, including the ones already applied.
+func (h *PaidRouteHandler) GetRoutePriceChanges(gCtx *gin.Context) {
+	route := h.getOwnedRoute(gCtx)
+	if route == nil {
+		return
+	}
+
+	changes, err := h.paidRouteService.ListPriceChanges(gCtx.Request.Context(), route.ID)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve price changes"})
+		return
+	}
+	if changes == nil {
+		changes = []PriceChange{}
+	}
+
+	gCtx.JSON(http.StatusOK, gin.H{"price_changes": changes})
+}
+
+// CreateRoutePriceChange handles POST requests to change the price of a route
+// at a time in the future.
+func (h *PaidRouteHandler) CreateRoutePriceChange(gCtx *gin.Context) {
+	var req CreatePriceChangeRequest
+	if err := gCtx.ShouldBind(&req); err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	price, err := money.USDC.Parse(req.Price)
+	if err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid price: " + err.Error()})
+		return
+	}
+	effectiveAt, err := time.Parse(time.RFC3339, req.EffectiveAt)
+	if err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid effective_at: must be an RFC 3339 timestamp"})
+		return
+	}
+
+	route := h.getOwnedRoute(gCtx)
+	if route == nil {
+		return
+	}
+
+	// Payments are matched to a pack by their amount, so the route may not
+	// change to the price of one of its packs.
+	if packsApply(route) {
+		routePacks, err := h.packService.ListPacks(gCtx.Request.Context(), route.ID)
+		if err != nil {
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve credit packs"})
+			return
+		}
+		for _, pack := range routePacks {
+			if pack.Price == price {
+				gCtx.JSON(http.StatusConflict, gin.H{"error": packs.ErrPriceTaken.Error()})
+				return
+			}
+		}
+	}
+
+	change, err := h.paidRouteService.SchedulePriceChange(gCtx.Request.Context(), route, price, effectiveAt)
+	if err != nil {
+		if errors.Is(err, ErrInvalidPriceChange) {
+			gCtx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule price change"})
+		}
+		return
+	}
+
+	gCtx.JSON(http.StatusCreated, change)
+}
+
+// DeleteRoutePriceChange handles DELETE requests to cancel a price change
+// that was not applied yet.
+func (h *PaidRouteHandler) DeleteRoutePriceChange(gCtx *gin.Context) {
+	changeID, err := strconv.ParseUint(gCtx.Param("priceChangeID"), 10, 64)
+	if err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid price change ID format"})
+		return
+	}
+
+	route := h.getOwnedRoute(gCtx)
+	if route == nil {
+		return
+	}
+
+	err = h.paidRouteService.DeletePriceChange(gCtx.Request.Context(), changeID, route.ID)
+	if err != nil {
+		if errors.Is(err, ErrPriceChangeNotFound) {
+			gCtx.JSON(http.StatusNotFound, gin.H{"error": "Price change not found or already applied"})
+		} else {
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete price change"})
+		}
+		return
+	}
+
+	gCtx.Status(http.StatusOK)
+}
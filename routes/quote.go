@@ -0,0 +1,155 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/money"
+)
+
+const (
+	// maxQuoteResponseBytes caps the size of a quote hook response.
+	maxQuoteResponseBytes = 64 * 1024
+	// maxQuoteBodyBytes caps the size of the request bodies priced by quote
+	// hooks, which are read before anything is paid.
+	maxQuoteBodyBytes = 1024 * 1024
+)
+
+// errQuoteBodyTooLarge is returned for requests with a body too large to be
+// priced.
+var errQuoteBodyTooLarge = errors.New("request body is too large")
+
+// QuoteRequest is sent to a route's quote hook to price a request.
+type QuoteRequest struct {
+	ShortCode   string `json:"short_code"`
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Query       string `json:"query,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	BodySize    int    `json:"body_size"`
+	BodySHA256  string `json:"body_sha256"`
+}
+
+// QuoteResponse is returned by a route's quote hook.
+type QuoteResponse struct {
+	// Price is a decimal USDC amount, e.g. "0.25".
+	Price string `json:"price"`
+}
+
+// Quote is the price of a single request.
+type Quote struct {
+	// ID identifies the request and price the quote was made for. It is
+	// bound to the payment requirements so a quote can't be reused for a
+	// different request.
+	ID    string
+	Price uint64
+}
+
+// clampQuotedPrice bounds a quoted price by the route's min and max price.
+func clampQuotedPrice(route *PaidRoute, price uint64) uint64 {
+	if price < route.MinPrice {
+		return route.MinPrice
+	}
+	if route.MaxPrice > 0 && price > route.MaxPrice {
+		return route.MaxPrice
+	}
+	return price
+}
+
+// quoteRequest asks the route's quote hook for the price of the request.
+// The request body is read to compute its digest and then restored.
+func (h *PaidRouteHandler) quoteRequest(gCtx *gin.Context, route *PaidRoute) (*Quote, error) {
+	var body []byte
+	if gCtx.Request.Body != nil {
+		var err error
+		body, err = io.ReadAll(io.LimitReader(gCtx.Request.Body, maxQuoteBodyBytes+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		if len(body) > maxQuoteBodyBytes {
+			return nil, errQuoteBodyTooLarge
+		}
+		gCtx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	bodyDigest := sha256.Sum256(body)
+
+	quoteReq := QuoteRequest{
+		ShortCode:   route.ShortCode,
+		Method:      gCtx.Request.Method,
+		Path:        gCtx.Request.URL.Path,
+		Query:       gCtx.Request.URL.RawQuery,
+		ContentType: gCtx.GetHeader("Content-Type"),
+		BodySize:    len(body),
+		BodySHA256:  hex.EncodeToString(bodyDigest[:]),
+	}
+	payload, err := json.Marshal(quoteReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode quote request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(gCtx.Request.Context(),
+		time.Duration(h.config.QuoteTimeoutSeconds)*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, route.QuoteURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create quote request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Let the upstream verify the quote request comes from the proxy.
+	user, err := h.userService.GetUserByID(ctx, route.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get route owner: %w", err)
+	}
+	if user.Proxy402Secret != "" {
+		req.Header.Set("Proxy402-Secret", user.Proxy402Secret)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call quote hook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("quote hook responded with status %d", resp.StatusCode)
+	}
+
+	var quoteResp QuoteResponse
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxQuoteResponseBytes)).Decode(&quoteResp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode quote response: %w", err)
+	}
+
+	price, err := money.USDC.Parse(quoteResp.Price)
+	if err != nil {
+		return nil, fmt.Errorf("quote hook returned an invalid price: %w", err)
+	}
+	price = clampQuotedPrice(route, price)
+
+	quoteDigest := sha256.New()
+	for _, part := range []string{
+		strconv.FormatUint(route.ID, 10), quoteReq.Method, quoteReq.Path,
+		quoteReq.Query, quoteReq.BodySHA256, strconv.FormatUint(price, 10),
+	} {
+		quoteDigest.Write([]byte(part))
+		quoteDigest.Write([]byte{0})
+	}
+
+	return &Quote{
+		ID:    hex.EncodeToString(quoteDigest.Sum(nil)[:16]),
+		Price: price,
+	}, nil
+}
@@ -0,0 +1,217 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/affiliates"
+	"linkshrink/purchases"
+	"linkshrink/rails"
+)
+
+// railsApply reports whether the route can be paid with payment rails other
+// than x402. Rails are paid the route's price for its credits, so routes
+// that can't sell credit packs can't be paid with them either. Bundles are
+// bought with x402 only.
+func railsApply(route *PaidRoute) bool {
+	return packsApply(route) && route.ResourceType != ResourceTypeBundle
+}
+
+// validatePaymentRails checks the payment rails a route opts in to are
+// configured.
+func (h *PaidRouteHandler) validatePaymentRails(names []string) error {
+	for i, name := range names {
+		if _, ok := h.paymentRails[name]; !ok {
+			return fmt.Errorf("unknown payment rail %q", name)
+		}
+		if slices.Contains(names[:i], name) {
+			return fmt.Errorf("payment rail %q is listed twice", name)
+		}
+	}
+	return nil
+}
+
+// routeRails returns the payment rails that take payments for the route.
+func (h *PaidRouteHandler) routeRails(route *PaidRoute) []rails.Rail {
+	if !railsApply(route) {
+		return nil
+	}
+
+	var routeRails []rails.Rail
+	for _, name := range route.PaymentRails {
+		rail, ok := h.paymentRails[name]
+		if ok && rail.Serves(route.IsTest) {
+			routeRails = append(routeRails, rail)
+		}
+	}
+	return routeRails
+}
+
+// offerRails adds a WWW-Authenticate challenge for each payment rail the
+// route accepts to the payment required response. A rail failing to issue a
+// challenge is left out, the route can still be paid with x402.
+func (h *PaidRouteHandler) offerRails(gCtx *gin.Context, route *PaidRoute, accessURL string) {
+	for _, rail := range h.routeRails(route) {
+		challenge, err := rail.Challenge(gCtx.Request.Context(), rails.Charge{
+			ShortCode:   route.ShortCode,
+			Description: fmt.Sprintf("Payment for %s %s", route.Method, accessURL),
+			Amount:      route.Price,
+			Credits:     route.Credits,
+		})
+		if err != nil {
+			h.logger.Error("Failed to issue payment rail challenge", "shortCode", route.ShortCode,
+				"rail", rail.Name(), "error", err)
+			continue
+		}
+		gCtx.Writer.Header().Add("WWW-Authenticate", challenge)
+	}
+}
+
+// tryRailPayment checks if the request carries the credential of a payment
+// rail the route accepts. The first request with a credential records its
+// payment as a purchase, attributed to the affiliate that referred it, and
+// later requests with it use the purchase's credits like x402 payments do.
+// Returns:
+//   - paid: true if the credential paid for the request.
+//   - requestHandled: true if a response was sent (e.g., invalid credential) and the main handler should stop.
+//
+// A credential whose credits are used up is answered like a request without
+// payment.
+func (h *PaidRouteHandler) tryRailPayment(gCtx *gin.Context, route *PaidRoute,
+	affiliate *affiliates.Affiliate) (paid bool, requestHandled bool) {
+
+	var rail rails.Rail
+	var credential string
+	for _, routeRail := range h.routeRails(route) {
+		credential = routeRail.TakeCredential(gCtx.Request)
+		if credential != "" {
+			rail = routeRail
+			break
+		}
+	}
+	if rail == nil {
+		return false, false
+	}
+
+	ctx := gCtx.Request.Context()
+	payment, err := rail.Verify(ctx, route.ShortCode, credential)
+	if errors.Is(err, rails.ErrInvalidCredential) || errors.Is(err, rails.ErrWrongRoute) {
+		h.logger.Info("Rejected payment rail credential", "shortCode", route.ShortCode,
+			"rail", rail.Name(), "error", err)
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return false, true
+	}
+	if err != nil {
+		h.logger.Error("Failed to verify payment rail credential", "shortCode", route.ShortCode,
+			"rail", rail.Name(), "error", err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify payment"})
+		return false, true
+	}
+
+	existingPurchase, err := h.purchaseService.GetPurchaseByRouteIDAndPaymentHeader(ctx, route.ID, payment.Reference)
+	if err == nil {
+		usedExistingCredit, _ := h.useExistingPurchase(gCtx, route, existingPurchase)
+		return usedExistingCredit, false
+	}
+	if !errors.Is(err, purchases.ErrPurchaseNotFound) {
+		h.logger.Error("Failed to look up payment rail purchase", "shortCode", route.ShortCode,
+			"reference", payment.Reference, "error", err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up payment"})
+		return false, true
+	}
+
+	// The purchase is priced with what the credential was issued for, which
+	// the route's price may have changed from since. It is created pending
+	// and settled with its accounting, rail payments are final once
+	// verified.
+	paidRoute := *route
+	paidRoute.Price = payment.Amount
+	paidRoute.Credits = payment.Credits
+
+	user, err := h.userService.GetUserByID(ctx, route.UserID)
+	if err != nil {
+		h.logger.Error("Error fetching user for payment rail purchase", "shortCode", route.ShortCode,
+			"userID", route.UserID, "error", err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error processing payment."})
+		return false, true
+	}
+	paymentAddress := h.config.X402PaymentAddress
+	if user.PaymentAddress != "" {
+		paymentAddress = user.PaymentAddress
+	}
+
+	purchase := &purchases.Purchase{
+		ShortCode:        route.ShortCode,
+		TargetURL:        route.TargetURL,
+		Method:           route.Method,
+		Price:            paidRoute.Price,
+		Type:             route.Type,
+		CreditsAvailable: paidRoute.Credits,
+		CreditsUsed:      1,
+		IsTest:           route.IsTest,
+		Status:           purchases.StatusPending,
+
+		PaidRouteID:   route.ID,
+		PaidToAddress: paymentAddress,
+
+		PaymentHeader:  payment.Reference,
+		PaymentPayload: payment.Proof,
+		PaymentNonce: &purchases.PaymentNonce{
+			Network: payment.Network,
+			Payer:   rail.Name(),
+			Nonce:   payment.Nonce,
+		},
+	}
+	if affiliate != nil {
+		purchase.AffiliateID = affiliate.ID
+	}
+
+	purchaseID, err := h.purchaseService.CreatePurchase(ctx, purchase)
+	if errors.Is(err, purchases.ErrPaymentReused) {
+		// A concurrent request with the same credential recorded it first.
+		existingPurchase, err := h.purchaseService.GetPurchaseByRouteIDAndPaymentHeader(ctx, route.ID, payment.Reference)
+		if err != nil {
+			gCtx.JSON(http.StatusConflict, gin.H{"error": purchases.ErrPaymentReused.Error()})
+			return false, true
+		}
+		usedExistingCredit, _ := h.useExistingPurchase(gCtx, route, existingPurchase)
+		return usedExistingCredit, false
+	}
+	if errors.Is(err, purchases.ErrSoldOut) {
+		gCtx.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		return false, true
+	}
+	if err != nil {
+		h.logger.Error("Failed to save payment rail purchase", "shortCode", route.ShortCode,
+			"rail", rail.Name(), "reference", payment.Reference, "error", err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error after payment."})
+		return false, true
+	}
+
+	// There is no on-chain settlement to reconcile.
+	err = h.settlePurchase(ctx, &paidRoute, purchaseID, paymentAddress, payment.Proof,
+		affiliates.Commission(affiliate))
+	if err != nil {
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error after payment."})
+		return false, true
+	}
+
+	h.logger.Info("Recorded payment rail purchase", "shortCode", route.ShortCode,
+		"rail", rail.Name(), "purchaseID", purchaseID, "amount", paidRoute.Price)
+	h.setNewPurchaseStreamCharge(gCtx, route, purchaseID)
+
+	if err := h.paidRouteService.IncrementPaymentCount(ctx, route.ShortCode); err != nil {
+		h.logger.Error("Failed to increment payment count after payment rail purchase",
+			"shortCode", route.ShortCode, "error", err)
+	}
+	if err := h.paidRouteService.IncrementAccessCount(ctx, route.ShortCode); err != nil {
+		h.logger.Error("Failed to increment access count after payment rail purchase",
+			"shortCode", route.ShortCode, "error", err)
+	}
+
+	return true, false
+}
@@ -0,0 +1,122 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/cache"
+)
+
+// CacheStatusHeader reports whether a response was served from the cache.
+const CacheStatusHeader = "X-Proxy402-Cache"
+
+// isResponseCacheable reports whether the response to this request may be
+// served from or stored in the response cache.
+func (h *PaidRouteHandler) isResponseCacheable(gCtx *gin.Context, route *PaidRoute) bool {
+	return h.responseCache != nil &&
+		route.CacheEnabled &&
+		route.ResourceType == "url" &&
+		gCtx.Request.Method == http.MethodGet &&
+		!isStreamRequest(gCtx.Request)
+}
+
+// routeCacheTTL returns the max TTL for a route's cached responses.
+func (h *PaidRouteHandler) routeCacheTTL(route *PaidRoute) time.Duration {
+	if route.CacheTTLSeconds > 0 {
+		return time.Duration(route.CacheTTLSeconds) * time.Second
+	}
+	return h.responseCache.DefaultTTL()
+}
+
+// responseCacheKey builds the cache key for the request forwarded to target.
+func responseCacheKey(gCtx *gin.Context, route *PaidRoute, targetURL *url.URL) string {
+	// Responses may be encoded differently depending on Accept-Encoding, so it
+	// is always part of the key.
+	varyHeaders := append(cache.ParseVaryHeaders(route.CacheVaryHeaders), "Accept-Encoding")
+
+	return cache.Key(route.ID, gCtx.Request.Method, targetURL.Path,
+		gCtx.Request.URL.RawQuery, gCtx.Request.Header, varyHeaders)
+}
+
+// serveCachedResponse writes the cached response for key if there is one.
+// Returns true if the request was served from the cache.
+func (h *PaidRouteHandler) serveCachedResponse(gCtx *gin.Context, route *PaidRoute, key string) bool {
+	if !cache.RequestAllowsCached(gCtx.Request.Header) {
+		return false
+	}
+
+	entry, err := h.responseCache.Get(gCtx.Request.Context(), key)
+	if err != nil {
+		if !errors.Is(err, cache.ErrCacheMiss) {
+			h.logger.Error("Failed to read response cache",
+				"shortCode", route.ShortCode, "error", err)
+		}
+		return false
+	}
+
+	h.logger.Debug("Serving response from cache", "shortCode", route.ShortCode)
+
+	for name, values := range entry.Header {
+		for _, value := range values {
+			gCtx.Writer.Header().Add(name, value)
+		}
+	}
+	gCtx.Header(CacheStatusHeader, "HIT")
+	gCtx.Status(entry.Status)
+	if _, err := gCtx.Writer.Write(entry.Body); err != nil {
+		h.logger.Error("Failed to write cached response",
+			"shortCode", route.ShortCode, "error", err)
+	}
+
+	return true
+}
+
+// cacheResponseHook returns a reverse proxy ModifyResponse hook that stores
+// cacheable upstream responses under key.
+func (h *PaidRouteHandler) cacheResponseHook(ctx context.Context, route *PaidRoute,
+	key string) func(*http.Response) error {
+
+	return func(resp *http.Response) error {
+		ttl, ok := cache.ResponseTTL(resp.StatusCode, resp.Header, h.routeCacheTTL(route))
+		if !ok {
+			return nil
+		}
+
+		limit := h.responseCache.MaxObjectBytes()
+		if resp.ContentLength > limit {
+			return nil
+		}
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+		if err != nil {
+			return err
+		}
+
+		if int64(len(body)) > limit {
+			// Too large to cache, stream what was read followed by the rest.
+			resp.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+			return nil
+		}
+
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		err = h.responseCache.Set(ctx, key, resp.StatusCode, resp.Header, body, ttl)
+		if err != nil {
+			h.logger.Error("Failed to store response in cache",
+				"shortCode", route.ShortCode, "error", err)
+		}
+
+		return nil
+	}
+}
@@ -1,8 +1,7 @@
 package routes
 
 import (
-	"fmt"
-	"strconv"
+	"encoding/json"
 	"time"
 )
 
@@ -11,6 +10,18 @@ const (
 	PaymentProtocolVersionV2 uint16 = 2
 )
 
+const (
+	// BillingModeFixed charges the route price for every request.
+	BillingModeFixed = "fixed"
+	// BillingModeMetered prepays the route price as a balance that upstream
+	// reported usage is charged against.
+	BillingModeMetered = "metered"
+)
+
+// ResourceTypeBundle is the resource type of bundle routes, which unlock a
+// collection of the owner's other routes instead of proxying to a target.
+const ResourceTypeBundle = "bundle"
+
 // PaidRoute represents a configurable, paid API route proxied by the service.
 type PaidRoute struct {
 	ID        uint64 `json:"-"`
@@ -20,7 +31,7 @@ type PaidRoute struct {
 	// TargetURL is the URL that the route will proxy requests to.
 	// If ResourceType is "file", this will be the R2 object key.
 	TargetURL string `json:"-"`
-	// Resource type is "url" or "file"
+	// Resource type is "url", "file" or ResourceTypeBundle
 	ResourceType string `json:"resource_type"`
 	// Original filename is the filename of the file uploaded by the user when resource_type is "file"
 	OriginalFilename *string `json:"original_filename,omitempty"`
@@ -41,6 +52,70 @@ type PaidRoute struct {
 	UserID                 uint64 `json:"-"`
 	IsEnabled              bool   `json:"is_enabled"`
 
+	// CacheEnabled opts the route into response caching (GET routes only).
+	CacheEnabled bool `json:"cache_enabled"`
+	// CacheTTLSeconds caps how long responses are cached, 0 uses the default TTL.
+	CacheTTLSeconds uint64 `json:"cache_ttl_seconds"`
+	// CacheVaryHeaders is a comma separated list of request headers that are
+	// part of the cache key.
+	CacheVaryHeaders string `json:"cache_vary_headers,omitempty"`
+
+	// LBStrategy is how requests are spread across the route's upstream pool.
+	LBStrategy string `json:"lb_strategy"`
+	// HealthCheckPath is requested on each pool target by the health checker.
+	// Empty disables active health checks.
+	HealthCheckPath string `json:"health_check_path,omitempty"`
+
+	// StreamMaxSeconds caps how long a WebSocket or SSE session may stay
+	// open, 0 means no limit.
+	StreamMaxSeconds uint64 `json:"stream_max_seconds"`
+	// StreamCreditSeconds is the time slice a credit pays for on a WebSocket
+	// or SSE session, 0 charges a single credit per connection.
+	StreamCreditSeconds uint64 `json:"stream_credit_seconds"`
+
+	// BillingMode is either BillingModeFixed or BillingModeMetered.
+	BillingMode string `json:"billing_mode"`
+	// UnitPrice is charged per usage unit reported by the upstream on
+	// metered routes, in base units (USDC * 10^6).
+	UnitPrice uint64 `json:"unit_price"`
+
+	// QuoteURL is called before issuing an x402 v2 payment request to price
+	// it dynamically. Empty means the route always costs Price.
+	QuoteURL string `json:"-"`
+	// PayWhatYouWant lets the buyer choose the amount, Price is only the
+	// suggested one.
+	PayWhatYouWant bool `json:"pay_what_you_want"`
+	// MinPrice and MaxPrice bound quoted and chosen prices, a MaxPrice of 0
+	// means no upper bound.
+	MinPrice uint64 `json:"min_price"`
+	MaxPrice uint64 `json:"max_price"`
+
+	// AvailableFrom and AvailableUntil limit when the route can be used,
+	// nil means no limit.
+	AvailableFrom  *time.Time `json:"available_from,omitempty"`
+	AvailableUntil *time.Time `json:"available_until,omitempty"`
+	// MaxSales caps how many times the route is bought in total, 0 means no
+	// limit. SoldCount is how many times it was bought so far.
+	MaxSales  uint64 `json:"max_sales"`
+	SoldCount uint64 `json:"sold_count"`
+
+	// PaymentRails lists the payment rails the route accepts besides x402.
+	PaymentRails []string `json:"payment_rails,omitempty"`
+
+	// Discoverable lists the route on the public discovery endpoint and
+	// marks it discoverable in its payment requirements.
+	Discoverable bool `json:"discoverable"`
+	// MimeType is the media type of the route's responses, empty if unknown.
+	MimeType string `json:"mime_type,omitempty"`
+
+	// BodySchema, QuerySchema and ResponseSchema are JSON Schemas of the
+	// request body, query parameters and response of URL routes, nil if the
+	// route declares none. Requests are validated against them before they
+	// are paid for.
+	BodySchema     json.RawMessage `json:"body_schema,omitempty"`
+	QuerySchema    json.RawMessage `json:"query_schema,omitempty"`
+	ResponseSchema json.RawMessage `json:"response_schema,omitempty"`
+
 	AttemptCount uint64 `json:"attempt_count"` // Track payment attempts (no payment header provided)
 	PaymentCount uint64 `json:"payment_count"` // Track successful payments (payment for x402)
 	AccessCount  uint64 `json:"access_count"`  // Track successful accesses (payment header provided)
@@ -50,33 +125,14 @@ type PaidRoute struct {
 	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
-// PriceUtils contains utility functions for working with prices
-type PriceUtils struct{}
-
-// FormatPrice converts price from integer (USDC * 10^6) to a decimal string
-func (PriceUtils) FormatPrice(priceInt uint64) string {
-	return fmt.Sprintf("%.6f", float64(priceInt)/1000000)
-}
-
-// ParsePrice converts a price string to integer units (USDC * 10^6)
-// Returns the price as uint64 and any error encountered
-func (PriceUtils) ParsePrice(priceStr string) (uint64, error) {
-	// Parse the price string to float
-	priceFloat, err := strconv.ParseFloat(priceStr, 64)
-	if err != nil {
-		return 0, fmt.Errorf("invalid price format: must be a decimal number")
-	}
-
-	// Validate price is not negative
-	if priceFloat < 0 {
-		return 0, fmt.Errorf("price must be greater or equal to 0")
-	}
-
-	// Convert to integer (USDC * 10^6)
-	return uint64(priceFloat * 1000000), nil
-}
+// PriceChange is a price a route changes to at a given time.
+type PriceChange struct {
+	ID          uint64 `json:"id"`
+	PaidRouteID uint64 `json:"-"`
+	// Price is in base units (USDC * 10^6).
+	Price       uint64     `json:"price"`
+	EffectiveAt time.Time  `json:"effective_at"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
 
-// NewPriceUtils creates a new PriceUtils instance
-func NewPriceUtils() PriceUtils {
-	return PriceUtils{}
+	CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,86 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"linkshrink/utils"
+)
+
+// priceChangeBatchSize caps how many price changes a scheduler run applies.
+const priceChangeBatchSize = 100
+
+// Scheduler applies scheduled price changes once they are due, and disables
+// routes once their availability window closes. A route is not enabled by
+// the scheduler, its availability window is checked on every request, so a
+// route its owner disabled stays disabled.
+type Scheduler struct {
+	logger *slog.Logger
+	store  Store
+	cfg    *Config
+	clock  utils.Clock
+}
+
+// NewScheduler creates a new Scheduler.
+func NewScheduler(logger *slog.Logger, store Store, cfg *Config, clock utils.Clock) *Scheduler {
+	return &Scheduler{
+		logger: logger,
+		store:  store,
+		cfg:    cfg,
+		clock:  clock,
+	}
+}
+
+// Run brings routes up to date with their schedule on startup and then every
+// interval until the context is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	if s.cfg.ScheduleIntervalSeconds <= 0 {
+		s.logger.Info("Route scheduler disabled")
+		return
+	}
+
+	if err := s.RunOnce(ctx); err != nil {
+		s.logger.Error("Route scheduler run failed", "error", err)
+	}
+
+	ticker := time.NewTicker(time.Duration(s.cfg.ScheduleIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.RunOnce(ctx); err != nil {
+				s.logger.Error("Route scheduler run failed", "error", err)
+			}
+		}
+	}
+}
+
+// RunOnce applies the price changes that are due and disables the routes
+// whose availability window closed.
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	now := s.clock.Now()
+
+	applied, err := s.store.ApplyDuePriceChanges(ctx, now, priceChangeBatchSize)
+	for _, change := range applied {
+		s.logger.Info("Applied scheduled price change", "routeID", change.PaidRouteID,
+			"priceChangeID", change.ID, "price", change.Price, "effectiveAt", change.EffectiveAt)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply price changes: %w", err)
+	}
+
+	disabled, err := s.store.DisableEndedRoutes(ctx, now)
+	if err != nil {
+		return fmt.Errorf("failed to disable ended routes: %w", err)
+	}
+	if disabled > 0 {
+		s.logger.Info("Disabled ended routes", "disabled", disabled)
+	}
+
+	return nil
+}
@@ -0,0 +1,290 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sort"
+	"testing"
+	"time"
+
+	"linkshrink/utils"
+)
+
+// scheduleStore is a Store keeping routes and price changes in memory,
+// applying them like the database does.
+type scheduleStore struct {
+	Store
+
+	routes  map[uint64]*PaidRoute
+	changes []PriceChange
+}
+
+func newScheduleStore(routes ...*PaidRoute) *scheduleStore {
+	store := &scheduleStore{routes: make(map[uint64]*PaidRoute)}
+	for _, route := range routes {
+		store.routes[route.ID] = route
+	}
+	return store
+}
+
+func (s *scheduleStore) FindRouteByShortCode(ctx context.Context, shortCode string) (*PaidRoute, error) {
+	for _, route := range s.routes {
+		if route.ShortCode == shortCode {
+			found := *route
+			return &found, nil
+		}
+	}
+	return nil, ErrRouteNotFound
+}
+
+func (s *scheduleStore) ApplyDuePriceChanges(ctx context.Context, now time.Time,
+	limit int) ([]PriceChange, error) {
+
+	sort.SliceStable(s.changes, func(i, j int) bool {
+		return s.changes[i].EffectiveAt.Before(s.changes[j].EffectiveAt)
+	})
+
+	var applied []PriceChange
+	for i := range s.changes {
+		change := &s.changes[i]
+		if len(applied) == limit || change.EffectiveAt.After(now) {
+			break
+		}
+		if change.AppliedAt != nil {
+			continue
+		}
+		appliedAt := now
+		change.AppliedAt = &appliedAt
+		s.routes[change.PaidRouteID].Price = change.Price
+		applied = append(applied, *change)
+	}
+	return applied, nil
+}
+
+func (s *scheduleStore) DisableEndedRoutes(ctx context.Context, now time.Time) (int64, error) {
+	var disabled int64
+	for _, route := range s.routes {
+		if route.IsEnabled && route.AvailableUntil != nil && !now.Before(*route.AvailableUntil) {
+			route.IsEnabled = false
+			disabled++
+		}
+	}
+	return disabled, nil
+}
+
+func newTestScheduler(store Store, clock utils.Clock) *Scheduler {
+	return NewScheduler(slog.New(slog.NewTextHandler(io.Discard, nil)), store, DefaultConfig(), clock)
+}
+
+func timeAt(t time.Time) *time.Time {
+	return &t
+}
+
+func TestSchedulerRunOnce(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := utils.NewMockClock()
+	clock.SetMockClockTime(start)
+
+	limited := &PaidRoute{ID: 1, ShortCode: "limited", Price: 1000, IsEnabled: true,
+		AvailableUntil: timeAt(start.Add(time.Hour))}
+	// Disabled by its owner while its window is open.
+	disabled := &PaidRoute{ID: 2, ShortCode: "disabled", Price: 1000,
+		AvailableFrom: timeAt(start.Add(-time.Hour)), AvailableUntil: timeAt(start.Add(2 * time.Hour))}
+	upcoming := &PaidRoute{ID: 3, ShortCode: "upcoming", Price: 1000, IsEnabled: true,
+		AvailableFrom: timeAt(start.Add(time.Hour))}
+
+	store := newScheduleStore(limited, disabled, upcoming)
+	store.changes = []PriceChange{
+		{ID: 1, PaidRouteID: 1, Price: 2000, EffectiveAt: start.Add(30 * time.Minute)},
+		{ID: 2, PaidRouteID: 3, Price: 3000, EffectiveAt: start.Add(90 * time.Minute)},
+	}
+	scheduler := newTestScheduler(store, clock)
+	ctx := context.Background()
+
+	steps := []struct {
+		name          string
+		at            time.Time
+		wantPrices    map[uint64]uint64
+		wantIsEnabled map[uint64]bool
+	}{
+		{
+			name:          "nothing due",
+			at:            start,
+			wantPrices:    map[uint64]uint64{1: 1000, 2: 1000, 3: 1000},
+			wantIsEnabled: map[uint64]bool{1: true, 2: false, 3: true},
+		},
+		{
+			name:          "price change due",
+			at:            start.Add(30 * time.Minute),
+			wantPrices:    map[uint64]uint64{1: 2000, 2: 1000, 3: 1000},
+			wantIsEnabled: map[uint64]bool{1: true, 2: false, 3: true},
+		},
+		{
+			name:          "window closed",
+			at:            start.Add(time.Hour),
+			wantPrices:    map[uint64]uint64{1: 2000, 2: 1000, 3: 1000},
+			wantIsEnabled: map[uint64]bool{1: false, 2: false, 3: true},
+		},
+		{
+			name:          "disabled route stays disabled",
+			at:            start.Add(90 * time.Minute),
+			wantPrices:    map[uint64]uint64{1: 2000, 2: 1000, 3: 3000},
+			wantIsEnabled: map[uint64]bool{1: false, 2: false, 3: true},
+		},
+	}
+
+	for _, step := range steps {
+		clock.SetMockClockTime(step.at)
+		if err := scheduler.RunOnce(ctx); err != nil {
+			t.Fatalf("%s: RunOnce() error = %v", step.name, err)
+		}
+		for id, want := range step.wantPrices {
+			if got := store.routes[id].Price; got != want {
+				t.Errorf("%s: route %d price = %d, want %d", step.name, id, got, want)
+			}
+		}
+		for id, want := range step.wantIsEnabled {
+			if got := store.routes[id].IsEnabled; got != want {
+				t.Errorf("%s: route %d IsEnabled = %t, want %t", step.name, id, got, want)
+			}
+		}
+	}
+}
+
+func TestSchedulerRunOnceBatchesPriceChanges(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := utils.NewMockClock()
+	clock.SetMockClockTime(start)
+
+	store := newScheduleStore(&PaidRoute{ID: 1, ShortCode: "busy", Price: 1, IsEnabled: true})
+	total := priceChangeBatchSize + priceChangeBatchSize/2
+	for i := range total {
+		store.changes = append(store.changes, PriceChange{
+			ID:          uint64(i + 1),
+			PaidRouteID: 1,
+			Price:       uint64(i + 1),
+			EffectiveAt: start.Add(-time.Duration(total-i) * time.Second),
+		})
+	}
+	scheduler := newTestScheduler(store, clock)
+
+	if err := scheduler.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	if got := store.routes[1].Price; got != priceChangeBatchSize {
+		t.Errorf("price after first run = %d, want %d", got, priceChangeBatchSize)
+	}
+
+	if err := scheduler.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	if got := store.routes[1].Price; got != uint64(total) {
+		t.Errorf("price after second run = %d, want %d", got, total)
+	}
+}
+
+func TestFindAvailableRouteByShortCode(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		route   PaidRoute
+		at      time.Time
+		wantErr error
+		wantNil bool
+	}{
+		{
+			name:  "enabled",
+			route: PaidRoute{IsEnabled: true},
+			at:    start,
+		},
+		{
+			name:    "disabled",
+			route:   PaidRoute{},
+			at:      start,
+			wantErr: ErrRouteNotFound,
+			wantNil: true,
+		},
+		{
+			name: "disabled within window",
+			route: PaidRoute{AvailableFrom: timeAt(start.Add(-time.Hour)),
+				AvailableUntil: timeAt(start.Add(time.Hour))},
+			at:      start,
+			wantErr: ErrRouteNotFound,
+			wantNil: true,
+		},
+		{
+			name:    "before window",
+			route:   PaidRoute{IsEnabled: true, AvailableFrom: timeAt(start)},
+			at:      start.Add(-time.Second),
+			wantErr: ErrRouteNotStarted,
+		},
+		{
+			name:  "window opens",
+			route: PaidRoute{IsEnabled: true, AvailableFrom: timeAt(start)},
+			at:    start,
+		},
+		{
+			name:    "window closes",
+			route:   PaidRoute{IsEnabled: true, AvailableUntil: timeAt(start)},
+			at:      start,
+			wantErr: ErrRouteEnded,
+		},
+		{
+			name:    "disabled after window closes",
+			route:   PaidRoute{AvailableUntil: timeAt(start)},
+			at:      start,
+			wantErr: ErrRouteEnded,
+		},
+		{
+			name:    "sold out",
+			route:   PaidRoute{IsEnabled: true, MaxSales: 3, SoldCount: 3},
+			at:      start,
+			wantErr: ErrRouteSoldOut,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := utils.NewMockClock()
+			clock.SetMockClockTime(tt.at)
+
+			route := tt.route
+			route.ID = 1
+			route.ShortCode = "drop"
+			service := NewPaidRouteService(slog.New(slog.NewTextHandler(io.Discard, nil)),
+				newScheduleStore(&route), nil, clock)
+
+			got, err := service.FindAvailableRouteByShortCode(context.Background(), "drop")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("FindAvailableRouteByShortCode() error = %v, want %v", err, tt.wantErr)
+			}
+			if (got == nil) != tt.wantNil {
+				t.Errorf("FindAvailableRouteByShortCode() route = %v, want nil %t", got, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestEndedRouteStaysGoneOnceDisabled(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := utils.NewMockClock()
+	clock.SetMockClockTime(start.Add(time.Hour))
+
+	store := newScheduleStore(&PaidRoute{ID: 1, ShortCode: "drop", Price: 1000, IsEnabled: true,
+		AvailableUntil: timeAt(start)})
+	if err := newTestScheduler(store, clock).RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	if store.routes[1].IsEnabled {
+		t.Fatalf("route still enabled after its window closed")
+	}
+
+	service := NewPaidRouteService(slog.New(slog.NewTextHandler(io.Discard, nil)), store, nil, clock)
+	route, err := service.FindAvailableRouteByShortCode(context.Background(), "drop")
+	if !errors.Is(err, ErrRouteEnded) || route == nil {
+		t.Errorf("FindAvailableRouteByShortCode() = %v, %v, want the route with %v", route, err, ErrRouteEnded)
+	}
+}
@@ -0,0 +1,234 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+const (
+	// SchemaViolationHeader flags responses of test routes that don't match
+	// the route's response schema, so owners notice their upstream drifted
+	// from the contract the route advertises.
+	SchemaViolationHeader = "Proxy402-Schema-Violation"
+
+	// maxSchemaBytes caps the size of a schema declared for a route.
+	maxSchemaBytes = 64 * 1024
+	// maxSchemaDocumentBytes caps the size of request and response bodies
+	// validated against a schema.
+	maxSchemaDocumentBytes = 1024 * 1024
+)
+
+// proxyQueryParams are the query parameters meant for the proxy, which are
+// taken off the request before it is proxied and so are not validated.
+var proxyQueryParams = []string{ReferralQueryParam, CouponQueryParam, PackQueryParam, AmountQueryParam}
+
+// isBodyMethod reports whether requests of the method carry a body.
+func isBodyMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	}
+	return false
+}
+
+// validateSchema checks a JSON Schema declared for a route, if any. The name
+// says which of the route's schemas it is in errors.
+func validateSchema(name, schema string) error {
+	if schema == "" {
+		return nil
+	}
+	if len(schema) > maxSchemaBytes {
+		return fmt.Errorf("%s is too large", name)
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(schema), &object); err != nil {
+		return fmt.Errorf("%s must be a JSON object", name)
+	}
+	if _, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(schema)); err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return nil
+}
+
+// schemaFromRequest returns a schema given in a request, nil if none is.
+func schemaFromRequest(schema string) json.RawMessage {
+	if schema == "" {
+		return nil
+	}
+	return json.RawMessage(schema)
+}
+
+// schemaObject decodes a route's schema, nil if it has none.
+func schemaObject(schema json.RawMessage) map[string]interface{} {
+	if len(schema) == 0 {
+		return nil
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(schema, &object); err != nil {
+		return nil
+	}
+	return object
+}
+
+// schemaExample returns the first of the examples of a schema, nil if it has
+// none.
+func schemaExample(schema map[string]interface{}) interface{} {
+	if examples, ok := schema["examples"].([]interface{}); ok && len(examples) > 0 {
+		return examples[0]
+	}
+	return nil
+}
+
+// validateDocument validates a JSON document against a schema. Returns what
+// doesn't match, nothing if the document is valid.
+func validateDocument(schema json.RawMessage, document gojsonschema.JSONLoader) ([]string, error) {
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(schema), document)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for _, resultErr := range result.Errors() {
+		violations = append(violations, resultErr.String())
+	}
+	return violations, nil
+}
+
+// queryDocument returns the query parameters of a request as a JSON object,
+// with the values of parameters given once as strings and of parameters
+// given several times as arrays of strings.
+func queryDocument(r *http.Request) map[string]interface{} {
+	query := r.URL.Query()
+	for _, name := range proxyQueryParams {
+		query.Del(name)
+	}
+
+	document := make(map[string]interface{}, len(query))
+	for name, values := range query {
+		if len(values) == 1 {
+			document[name] = values[0]
+		} else {
+			document[name] = values
+		}
+	}
+	return document
+}
+
+// validateRequestSchemas validates the query and body of the request against
+// the route's schemas, before anything is paid for it. It sends an error
+// response and returns requestHandled=true if the request is invalid.
+func (h *PaidRouteHandler) validateRequestSchemas(gCtx *gin.Context,
+	route *PaidRoute) (requestHandled bool) {
+
+	if len(route.QuerySchema) > 0 {
+		violations, err := validateDocument(route.QuerySchema,
+			gojsonschema.NewGoLoader(queryDocument(gCtx.Request)))
+		if err != nil {
+			h.logger.Error("Failed to validate request query",
+				"shortCode", route.ShortCode, "error", err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate request"})
+			return true
+		}
+		if len(violations) > 0 {
+			gCtx.JSON(http.StatusBadRequest, gin.H{
+				"error":      "Query parameters do not match the route's schema",
+				"violations": violations,
+			})
+			return true
+		}
+	}
+
+	if len(route.BodySchema) > 0 && isBodyMethod(gCtx.Request.Method) {
+		// The body is read to validate it and put back for the upstream.
+		var body []byte
+		if gCtx.Request.Body != nil {
+			var err error
+			body, err = io.ReadAll(io.LimitReader(gCtx.Request.Body, maxSchemaDocumentBytes+1))
+			if err != nil {
+				gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+				return true
+			}
+			if len(body) > maxSchemaDocumentBytes {
+				gCtx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body is too large"})
+				return true
+			}
+			gCtx.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		if !json.Valid(body) {
+			gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Request body must be JSON"})
+			return true
+		}
+
+		violations, err := validateDocument(route.BodySchema, gojsonschema.NewBytesLoader(body))
+		if err != nil {
+			h.logger.Error("Failed to validate request body",
+				"shortCode", route.ShortCode, "error", err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate request"})
+			return true
+		}
+		if len(violations) > 0 {
+			gCtx.JSON(http.StatusBadRequest, gin.H{
+				"error":      "Request body does not match the route's schema",
+				"violations": violations,
+			})
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkResponseSchema validates a successful JSON response of a test route
+// against the route's response schema, and flags it with the
+// SchemaViolationHeader if it doesn't match. The response is passed on as is
+// either way.
+func (h *PaidRouteHandler) checkResponseSchema(route *PaidRoute, resp *http.Response) error {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 ||
+		!strings.Contains(resp.Header.Get("Content-Type"), "json") ||
+		resp.ContentLength > maxSchemaDocumentBytes {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSchemaDocumentBytes+1))
+	if err != nil {
+		return err
+	}
+	if len(body) > maxSchemaDocumentBytes {
+		// Too large to validate, stream what was read followed by the rest.
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var violations []string
+	if !json.Valid(body) {
+		violations = []string{"response body is not JSON"}
+	} else {
+		violations, err = validateDocument(route.ResponseSchema, gojsonschema.NewBytesLoader(body))
+		if err != nil {
+			h.logger.Error("Failed to validate upstream response",
+				"shortCode", route.ShortCode, "error", err)
+			return nil
+		}
+	}
+
+	if len(violations) > 0 {
+		h.logger.Warn("Upstream response does not match the route's schema",
+			"shortCode", route.ShortCode, "violations", violations)
+		resp.Header.Set(SchemaViolationHeader, strings.Join(violations, "; "))
+	}
+	return nil
+}
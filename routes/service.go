@@ -6,8 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"linkshrink/bundles"
 	"linkshrink/cloudflare"
+	"linkshrink/money"
+	"linkshrink/upstreams"
+	"linkshrink/utils"
 	"log/slog"
+	"math"
 	"mime/multipart"
 	"net/url"
 	"strings"
@@ -17,19 +22,21 @@ import (
 // PaidRouteService provides business logic for managing paid routes.
 type PaidRouteService struct {
 	cloudflareService *cloudflare.Service
-	priceUtils        PriceUtils
 
 	logger *slog.Logger
 	store  Store
+	clock  utils.Clock
 }
 
 // NewPaidRouteService creates a new PaidRouteService.
-func NewPaidRouteService(logger *slog.Logger, store Store, cloudflareService *cloudflare.Service) *PaidRouteService {
+func NewPaidRouteService(logger *slog.Logger, store Store, cloudflareService *cloudflare.Service,
+	clock utils.Clock) *PaidRouteService {
+
 	return &PaidRouteService{
 		logger:            logger,
 		store:             store,
 		cloudflareService: cloudflareService,
-		priceUtils:        NewPriceUtils(),
+		clock:             clock,
 	}
 }
 
@@ -55,11 +62,36 @@ func (s *PaidRouteService) CreateURLRoute(ctx context.Context, req *CreatePaidRo
 	}
 
 	// 2. Convert price string to integer (USDC * 10^6)
-	priceInt, err := s.priceUtils.ParsePrice(req.Price)
+	priceInt, err := money.USDC.Parse(req.Price)
 	if err != nil {
 		return nil, err
 	}
 
+	billingMode := BillingModeFixed
+	var unitPriceInt uint64
+	if req.BillingMode == BillingModeMetered {
+		billingMode = BillingModeMetered
+		unitPriceInt, err = money.USDC.Parse(req.UnitPrice)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.QuoteURL != "" {
+		quoteURL, err := url.ParseRequestURI(req.QuoteURL)
+		if err != nil || (quoteURL.Scheme != "http" && quoteURL.Scheme != "https") {
+			return nil, errors.New("invalid quote URL provided")
+		}
+	}
+
+	var minPriceInt, maxPriceInt uint64
+	if req.QuoteURL != "" || req.PayWhatYouWant {
+		minPriceInt, maxPriceInt, err = parsePriceBounds(req.MinPrice, req.MaxPrice)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// 3. Create and Save Route (short code will be generated in the store)
 	route := &PaidRoute{
 		TargetURL:              req.TargetURL,
@@ -72,6 +104,34 @@ func (s *PaidRouteService) CreateURLRoute(ctx context.Context, req *CreatePaidRo
 		Credits:                req.Credits,
 		PaymentProtocolVersion: PaymentProtocolVersionV2,
 		ResourceType:           "url",
+
+		CacheEnabled:     req.CacheEnabled,
+		CacheTTLSeconds:  req.CacheTTLSeconds,
+		CacheVaryHeaders: req.CacheVaryHeaders,
+
+		StreamMaxSeconds:    req.StreamMaxSeconds,
+		StreamCreditSeconds: req.StreamCreditSeconds,
+
+		BillingMode: billingMode,
+		UnitPrice:   unitPriceInt,
+
+		QuoteURL:       req.QuoteURL,
+		PayWhatYouWant: req.PayWhatYouWant,
+		MinPrice:       minPriceInt,
+		MaxPrice:       maxPriceInt,
+
+		PaymentRails: req.PaymentRails,
+
+		Discoverable: req.Discoverable,
+		MimeType:     req.MimeType,
+
+		BodySchema:     schemaFromRequest(req.BodySchema),
+		QuerySchema:    schemaFromRequest(req.QuerySchema),
+		ResponseSchema: schemaFromRequest(req.ResponseSchema),
+	}
+
+	if err := s.setAvailability(route, &req.AvailabilityRequest); err != nil {
+		return nil, err
 	}
 
 	// Handle title and description if provided
@@ -102,11 +162,189 @@ func (s *PaidRouteService) CreateURLRoute(ctx context.Context, req *CreatePaidRo
 	return createdRoute, nil
 }
 
+// CreateBundleRoute validates the bundled routes and creates a bundle route
+// that unlocks them. Only the user's own fixed price routes on the bundle's
+// network can be bundled.
+func (s *PaidRouteService) CreateBundleRoute(ctx context.Context, req *CreateBundleRequest, userID uint64) (*PaidRoute, error) {
+	price, err := money.USDC.Parse(req.Price)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.RouteIDs) == 0 {
+		return nil, bundles.ErrNoMembers
+	}
+	if len(req.RouteIDs) > bundles.MaxMembers {
+		return nil, bundles.ErrTooManyMembers
+	}
+
+	bundled := make(map[uint64]bool, len(req.RouteIDs))
+	for _, routeID := range req.RouteIDs {
+		if bundled[routeID] {
+			return nil, fmt.Errorf("%w: route %d is listed twice", bundles.ErrInvalidMember, routeID)
+		}
+		bundled[routeID] = true
+
+		member, err := s.store.FindRouteByID(ctx, routeID)
+		if errors.Is(err, ErrRouteNotFound) || (err == nil && member.UserID != userID) {
+			return nil, fmt.Errorf("%w: route %d not found", bundles.ErrInvalidMember, routeID)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case member.ResourceType == ResourceTypeBundle:
+			return nil, fmt.Errorf("%w: route %d is a bundle", bundles.ErrInvalidMember, routeID)
+		// Metered, quoted and pay what you want routes price a request
+		// only once it is made, a bundle's credit can't pay for it.
+		case !packsApply(member):
+			return nil, fmt.Errorf("%w: route %d is not fixed price", bundles.ErrInvalidMember, routeID)
+		case member.IsTest != req.IsTest:
+			return nil, fmt.Errorf("%w: route %d is on another network", bundles.ErrInvalidMember, routeID)
+		}
+	}
+
+	route := &PaidRoute{
+		Method:                 "GET",
+		Price:                  price,
+		IsTest:                 req.IsTest,
+		UserID:                 userID,
+		IsEnabled:              true,
+		Type:                   req.Type,
+		Credits:                req.Credits,
+		PaymentProtocolVersion: PaymentProtocolVersionV2,
+		ResourceType:           ResourceTypeBundle,
+		BillingMode:            BillingModeFixed,
+		Title:                  &req.Title,
+	}
+	if req.Description != "" {
+		route.Description = &req.Description
+	}
+	if err := s.setAvailability(route, &req.AvailabilityRequest); err != nil {
+		return nil, err
+	}
+
+	createdRoute, err := s.store.CreateBundleRoute(ctx, route, &bundles.Bundle{
+		AccessSeconds: req.AccessSeconds,
+	}, req.RouteIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save bundle route: %w", err)
+	}
+
+	return createdRoute, nil
+}
+
 // FindEnabledRouteByShortCode retrieves an active route.
 func (s *PaidRouteService) FindEnabledRouteByShortCode(ctx context.Context, shortCode string) (*PaidRoute, error) {
 	return s.store.FindEnabledRouteByShortCode(ctx, shortCode)
 }
 
+// FindAvailableRouteByShortCode retrieves an enabled route that can be used
+// now. If the route is not available, it is returned with
+// ErrRouteNotStarted, ErrRouteEnded or ErrRouteSoldOut. A route whose window
+// ended is returned with ErrRouteEnded even once the scheduler disabled it.
+func (s *PaidRouteService) FindAvailableRouteByShortCode(ctx context.Context, shortCode string) (*PaidRoute, error) {
+	route, err := s.store.FindRouteByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	err = route.CheckAvailability(s.clock.Now())
+	if errors.Is(err, ErrRouteEnded) {
+		return route, err
+	}
+	if !route.IsEnabled {
+		return nil, ErrRouteNotFound
+	}
+
+	return route, err
+}
+
+// UntilAvailable returns how long it takes until the route's availability
+// window opens, zero if it is open.
+func (s *PaidRouteService) UntilAvailable(route *PaidRoute) time.Duration {
+	if route.AvailableFrom == nil {
+		return 0
+	}
+	return max(route.AvailableFrom.Sub(s.clock.Now()), 0)
+}
+
+// setAvailability limits when a new route can be used and how often it can
+// be bought. The availability window is checked on every request to the
+// route, before it opens the route is answered with 425 Too Early.
+func (s *PaidRouteService) setAvailability(route *PaidRoute, req *AvailabilityRequest) error {
+	if req.AvailableFrom != "" {
+		availableFrom, err := time.Parse(time.RFC3339, req.AvailableFrom)
+		if err != nil {
+			return fmt.Errorf("%w: available_from must be an RFC 3339 timestamp", ErrInvalidAvailability)
+		}
+		availableFrom = availableFrom.UTC()
+		route.AvailableFrom = &availableFrom
+	}
+	if req.AvailableUntil != "" {
+		availableUntil, err := time.Parse(time.RFC3339, req.AvailableUntil)
+		if err != nil {
+			return fmt.Errorf("%w: available_until must be an RFC 3339 timestamp", ErrInvalidAvailability)
+		}
+		availableUntil = availableUntil.UTC()
+		route.AvailableUntil = &availableUntil
+	}
+
+	now := s.clock.Now()
+	if route.AvailableUntil != nil {
+		if !now.Before(*route.AvailableUntil) {
+			return fmt.Errorf("%w: available_until must be in the future", ErrInvalidAvailability)
+		}
+		if route.AvailableFrom != nil && !route.AvailableFrom.Before(*route.AvailableUntil) {
+			return fmt.Errorf("%w: available_until must be after available_from", ErrInvalidAvailability)
+		}
+	}
+	if req.MaxSales > math.MaxInt64 {
+		return fmt.Errorf("%w: max_sales is too large", ErrInvalidAvailability)
+	}
+
+	route.MaxSales = req.MaxSales
+	return nil
+}
+
+// SchedulePriceChange schedules a route's price to change at a time in the
+// future.
+func (s *PaidRouteService) SchedulePriceChange(ctx context.Context, route *PaidRoute,
+	price uint64, effectiveAt time.Time) (*PriceChange, error) {
+
+	if price == 0 {
+		return nil, fmt.Errorf("%w: price must be greater than zero", ErrInvalidPriceChange)
+	}
+	if !effectiveAt.After(s.clock.Now()) {
+		return nil, fmt.Errorf("%w: effective_at must be in the future", ErrInvalidPriceChange)
+	}
+	// The price of a pay what you want route is its suggested amount, which
+	// must be one buyers can choose.
+	if route.PayWhatYouWant {
+		if err := checkChosenAmount(route, price); err != nil {
+			return nil, fmt.Errorf("%w: suggested %w", ErrInvalidPriceChange, err)
+		}
+	}
+
+	return s.store.CreatePriceChange(ctx, &PriceChange{
+		PaidRouteID: route.ID,
+		Price:       price,
+		EffectiveAt: effectiveAt.UTC(),
+	})
+}
+
+// ListPriceChanges retrieves the price changes of a route, earliest first.
+func (s *PaidRouteService) ListPriceChanges(ctx context.Context, routeID uint64) ([]PriceChange, error) {
+	return s.store.ListPriceChanges(ctx, routeID)
+}
+
+// DeletePriceChange cancels a price change of a route that was not applied
+// yet.
+func (s *PaidRouteService) DeletePriceChange(ctx context.Context, changeID, routeID uint64) error {
+	return s.store.DeletePriceChange(ctx, changeID, routeID)
+}
+
 // IncrementPaymentCount increments the payment count for a given short code.
 func (s *PaidRouteService) IncrementPaymentCount(ctx context.Context, shortCode string) error {
 	// Delegate to the store layer
@@ -142,6 +380,14 @@ func (s *PaidRouteService) ListUserRoutes(ctx context.Context, userID uint64) ([
 	return s.store.ListUserRoutes(ctx, userID)
 }
 
+// ListDiscoverableRoutes retrieves a page of the routes listed for discovery
+// and how many routes match the filter in total.
+func (s *PaidRouteService) ListDiscoverableRoutes(ctx context.Context,
+	filter *DiscoveryFilter) ([]PaidRoute, uint64, error) {
+
+	return s.store.ListDiscoverableRoutes(ctx, filter)
+}
+
 // DeleteRoute deletes a paid route if owned by the specified user.
 func (s *PaidRouteService) DeleteRoute(ctx context.Context, routeID uint64, userID uint64) error {
 	err := s.store.DeleteRoute(ctx, routeID, userID)
@@ -156,11 +402,41 @@ func (s *PaidRouteService) DeleteRoute(ctx context.Context, routeID uint64, user
 	return nil
 }
 
+// GetUserRoute retrieves a route if it is owned by the specified user.
+func (s *PaidRouteService) GetUserRoute(ctx context.Context, routeID uint64, userID uint64) (*PaidRoute, error) {
+	route, err := s.store.FindRouteByID(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+	if route.UserID != userID {
+		return nil, ErrRouteNoPermission
+	}
+	return route, nil
+}
+
+// UpdateLoadBalancing updates how requests are spread across a route's
+// upstream pool and the path used to health check its targets.
+func (s *PaidRouteService) UpdateLoadBalancing(ctx context.Context, routeID uint64,
+	userID uint64, strategy string, healthCheckPath string) error {
+
+	parsedStrategy, err := upstreams.ParseStrategy(strategy)
+	if err != nil {
+		return err
+	}
+
+	if healthCheckPath != "" && !strings.HasPrefix(healthCheckPath, "/") {
+		return errors.New("health check path must start with /")
+	}
+
+	return s.store.UpdateRouteLoadBalancing(ctx, routeID, userID,
+		string(parsedStrategy), healthCheckPath)
+}
+
 // CreateFileRoute creates a new paid route for a file and returns the route along with a signed upload URL.
 func (s *PaidRouteService) CreateFileRoute(ctx context.Context, req *CreateFileRouteRequest, userID uint64) (*PaidRoute, string, error) {
 
 	// Convert price string to integer (USDC * 10^6)
-	priceInt, err := s.priceUtils.ParsePrice(req.Price)
+	priceInt, err := money.USDC.Parse(req.Price)
 	if err != nil {
 		return nil, "", err
 	}
@@ -189,6 +465,20 @@ func (s *PaidRouteService) CreateFileRoute(ctx context.Context, req *CreateFileR
 		PaymentProtocolVersion: PaymentProtocolVersionV2,
 		ResourceType:           "file",
 		OriginalFilename:       &req.OriginalFilename,
+		BillingMode:            BillingModeFixed,
+		PayWhatYouWant:         req.PayWhatYouWant,
+		PaymentRails:           req.PaymentRails,
+		Discoverable:           req.Discoverable,
+		MimeType:               req.MimeType,
+	}
+	if req.PayWhatYouWant {
+		route.MinPrice, route.MaxPrice, err = parsePriceBounds(req.MinPrice, req.MaxPrice)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	if err := s.setAvailability(route, &req.AvailabilityRequest); err != nil {
+		return nil, "", err
 	}
 
 	// Handle title and description if provided
@@ -0,0 +1,95 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/splits"
+)
+
+// SplitRequest defines a single payee share of a route's revenue.
+type SplitRequest struct {
+	PayeeAddress string `json:"payee_address" binding:"required"`
+	Label        string `json:"label" binding:"omitempty"`
+	ShareBPS     uint64 `json:"share_bps" binding:"required"` // Basis points, 2000 = 20%
+}
+
+// UpdateSplitsRequest defines the JSON body for replacing a route's splits.
+type UpdateSplitsRequest struct {
+	Splits []SplitRequest `json:"splits" binding:"dive"`
+}
+
+// SplitsResponse represents the revenue splits of a route.
+type SplitsResponse struct {
+	Splits []splits.Split      `json:"splits"`
+	Totals []splits.PayeeTotal `json:"totals"`
+}
+
+// GetRouteSplits handles GET requests for the revenue splits of a route and
+// what each payee is owed so far.
+func (h *PaidRouteHandler) GetRouteSplits(gCtx *gin.Context) {
+	route := h.getOwnedRoute(gCtx)
+	if route == nil {
+		return
+	}
+
+	routeSplits, err := h.splitsService.ListSplits(gCtx.Request.Context(), route.ID)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve splits"})
+		return
+	}
+
+	totals, err := h.splitsService.ListPayeeTotals(gCtx.Request.Context(), route.ID)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve payout totals"})
+		return
+	}
+
+	gCtx.JSON(http.StatusOK, SplitsResponse{
+		Splits: routeSplits,
+		Totals: totals,
+	})
+}
+
+// UpdateRouteSplits handles PUT requests to replace the revenue splits of a
+// route. The owner receives whatever the splits and platform fee leave.
+func (h *PaidRouteHandler) UpdateRouteSplits(gCtx *gin.Context) {
+	var req UpdateSplitsRequest
+	if err := gCtx.ShouldBindJSON(&req); err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	route := h.getOwnedRoute(gCtx)
+	if route == nil {
+		return
+	}
+
+	newSplits := make([]splits.Split, len(req.Splits))
+	for i, split := range req.Splits {
+		newSplits[i] = splits.Split{
+			PayeeAddress: split.PayeeAddress,
+			Label:        split.Label,
+			ShareBPS:     split.ShareBPS,
+		}
+	}
+
+	routeSplits, err := h.splitsService.SetSplits(gCtx.Request.Context(), route.ID, newSplits)
+	if err != nil {
+		if errors.Is(err, splits.ErrInvalidShare) || errors.Is(err, splits.ErrSharesTooLarge) ||
+			errors.Is(err, splits.ErrInvalidPayee) {
+
+			gCtx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update splits"})
+		}
+		return
+	}
+
+	gCtx.JSON(http.StatusOK, gin.H{"splits": routeSplits})
+}
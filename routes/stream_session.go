@@ -0,0 +1,179 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/purchases"
+)
+
+// isStreamRequest reports whether the request opens a long-lived stream,
+// either a protocol upgrade such as WebSocket or a Server-Sent-Events stream.
+func isStreamRequest(req *http.Request) bool {
+	for _, value := range req.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+
+	for _, value := range req.Header.Values("Accept") {
+		if strings.Contains(strings.ToLower(value), "text/event-stream") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// streamChargeKey is the key of the gin context the charge for time slices
+// of a stream session opened by the request is kept under.
+const streamChargeKey = "streamCharge"
+
+// streamCharge charges a time slice of a stream session to the payment that
+// authorized the request opening it. It returns purchases.ErrNoCreditsLeft
+// once the payment can't pay for another slice.
+type streamCharge func(ctx context.Context) error
+
+// freeStreamCharge charges nothing, for requests let through without paying.
+func freeStreamCharge(context.Context) error {
+	return nil
+}
+
+// setStreamCharge keeps how time slices of a stream session opened by the
+// request are charged, by the payment that authorized the request.
+func setStreamCharge(gCtx *gin.Context, charge streamCharge) {
+	gCtx.Set(streamChargeKey, charge)
+}
+
+// purchaseStreamCharge charges time slices to the credits of a purchase. The
+// used credits are posted to the ledger for creditRoute, the route that sold
+// the purchase.
+func (h *PaidRouteHandler) purchaseStreamCharge(creditRoute *PaidRoute,
+	purchase *purchases.Purchase) streamCharge {
+
+	return func(ctx context.Context) error {
+		_, err := h.purchaseService.IncrementCreditsUsed(ctx, creditUse(creditRoute, purchase))
+		return err
+	}
+}
+
+// setNewPurchaseStreamCharge charges time slices of a stream session opened
+// by the request that made a new purchase to the purchase's credits.
+func (h *PaidRouteHandler) setNewPurchaseStreamCharge(gCtx *gin.Context, route *PaidRoute,
+	purchaseID uint64) {
+
+	if route.StreamCreditSeconds == 0 || !isStreamRequest(gCtx.Request) {
+		return
+	}
+
+	purchase, err := h.purchaseService.GetPurchaseByID(gCtx.Request.Context(), purchaseID)
+	if err != nil {
+		// The session ends at its first slice.
+		h.logger.Error("Failed to get purchase for stream session", "shortCode", route.ShortCode,
+			"purchaseID", purchaseID, "error", err)
+		return
+	}
+	setStreamCharge(gCtx, h.purchaseStreamCharge(route, purchase))
+}
+
+// startStreamSession returns the context a stream session is proxied with.
+// The context ends once the route's max session duration is reached or, for
+// routes charging per time slice, once the payment that authorized the
+// request can't pay for another slice. The returned cancel function must be
+// called when the session ends.
+func (h *PaidRouteHandler) startStreamSession(gCtx *gin.Context,
+	route *PaidRoute) (context.Context, context.CancelFunc) {
+
+	ctx := gCtx.Request.Context()
+	var cancel context.CancelFunc
+	if route.StreamMaxSeconds > 0 {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(route.StreamMaxSeconds)*time.Second)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	// The first slice was paid for when the request was authorized.
+	if route.StreamCreditSeconds > 0 {
+		value, _ := gCtx.Get(streamChargeKey)
+		charge, _ := value.(streamCharge)
+		go h.chargeStreamSlices(ctx, cancel, route, charge)
+	}
+
+	return ctx, cancel
+}
+
+// chargeStreamSlices charges every time slice the stream session stays open
+// for, and ends the session once its payment can't pay for another one.
+func (h *PaidRouteHandler) chargeStreamSlices(ctx context.Context,
+	cancel context.CancelFunc, route *PaidRoute, charge streamCharge) {
+
+	if charge == nil {
+		h.logger.Error("Stream session has no payment to charge, ending session",
+			"shortCode", route.ShortCode)
+		cancel()
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(route.StreamCreditSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			err := charge(ctx)
+			if errors.Is(err, purchases.ErrNoCreditsLeft) {
+				h.logger.Info("Stream session ran out of credits, ending session",
+					"shortCode", route.ShortCode)
+				cancel()
+				return
+			}
+			if err != nil {
+				if ctx.Err() == nil {
+					h.logger.Error("Failed to charge stream session, ending session",
+						"shortCode", route.ShortCode, "error", err)
+				}
+				cancel()
+				return
+			}
+
+			h.logger.Debug("Charged a time slice of stream session", "shortCode", route.ShortCode)
+		}
+	}
+}
+
+// serveStream proxies a stream session until either side closes it or the
+// session context ends.
+func (h *PaidRouteHandler) serveStream(gCtx *gin.Context, route *PaidRoute,
+	proxy *httputil.ReverseProxy) {
+
+	ctx, cancel := h.startStreamSession(gCtx, route)
+	defer cancel()
+
+	// Flush every write so events reach the client as they are produced.
+	proxy.FlushInterval = -1
+
+	// The reverse proxy aborts the response when copying the body fails,
+	// which is how a session ended by us stops. Let it end cleanly instead.
+	defer func() {
+		if r := recover(); r != nil {
+			if r != http.ErrAbortHandler || ctx.Err() == nil {
+				panic(r)
+			}
+			h.logger.Info("Stream session ended", "shortCode", route.ShortCode,
+				"reason", context.Cause(ctx))
+		}
+	}()
+
+	proxy.ServeHTTP(gCtx.Writer, gCtx.Request.WithContext(ctx))
+}
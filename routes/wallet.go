@@ -0,0 +1,216 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/money"
+	"linkshrink/payers"
+	"linkshrink/purchases"
+	"linkshrink/wallets"
+	"linkshrink/x402"
+)
+
+// tryWalletDraw pays for the request from the buyer's wallet with the route's
+// owner, if the request names one with wallets.TokenHeader or
+// wallets.SignatureHeader. A wallet with a balance too low falls back to
+// asking for payment.
+// Returns:
+//   - drawn: true if the request is paid for from the wallet.
+//   - requestHandled: true if an error response was sent.
+func (h *PaidRouteHandler) tryWalletDraw(gCtx *gin.Context, route *PaidRoute) (drawn bool, requestHandled bool) {
+	token := gCtx.GetHeader(wallets.TokenHeader)
+	signedChallenge := gCtx.GetHeader(wallets.SignatureHeader)
+	if token == "" && signedChallenge == "" {
+		return false, false
+	}
+
+	// The wallet credentials are meant for the proxy, not the upstream.
+	gCtx.Request.Header.Del(wallets.TokenHeader)
+	gCtx.Request.Header.Del(wallets.SignatureHeader)
+
+	// Metered and quoted routes price a request only once it is made, and a
+	// draw is taken before it. Bundles are bought for their routes, which a
+	// wallet pays for directly.
+	if !packsApply(route) || route.ResourceType == ResourceTypeBundle {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Wallets can only pay for fixed price routes"})
+		return false, true
+	}
+
+	ctx := gCtx.Request.Context()
+	wallet, err := h.walletService.Authenticate(ctx, route.UserID, route.IsTest, token, signedChallenge)
+	if err != nil {
+		if errors.Is(err, wallets.ErrWalletNotFound) || errors.Is(err, wallets.ErrInvalidSignature) ||
+			errors.Is(err, wallets.ErrChallengeExpired) || errors.Is(err, wallets.ErrChallengeUsed) {
+
+			gCtx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else {
+			h.logger.Error("Failed to authenticate wallet", "shortCode", route.ShortCode, "error", err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve wallet"})
+		}
+		return false, true
+	}
+
+	// The owner's rule for the wallet's payer applies like it does to its
+	// payments.
+	payerRule, err := h.payerService.FindRule(ctx, route.UserID, route.ID, wallet.PayerAddress)
+	if err != nil {
+		h.logger.Error("Failed to look up payer rule", "shortCode", route.ShortCode,
+			"payer", wallet.PayerAddress, "error", err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up payer"})
+		return false, true
+	}
+	if payerRule != nil && payerRule.Treatment == payers.TreatmentBlock {
+		gCtx.JSON(http.StatusForbidden, gin.H{"error": "Payer is blocked from this route"})
+		return false, true
+	}
+
+	price := applyPayerRule(route, payerRule).Price
+	if isFreePayer(payerRule) {
+		price = 0
+	}
+
+	updated, err := h.walletService.Draw(ctx, wallet, route.ID, price)
+	if errors.Is(err, wallets.ErrInsufficientBalance) {
+		h.logger.Info("Wallet balance too low, asking for payment", "shortCode", route.ShortCode,
+			"walletID", wallet.ID, "balance", wallet.Balance, "price", price)
+		gCtx.Header(wallets.BalanceHeader, strconv.FormatUint(wallet.Balance, 10))
+		return false, false
+	}
+	if errors.Is(err, purchases.ErrSoldOut) {
+		gCtx.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		return false, true
+	}
+	if err != nil {
+		h.logger.Error("Failed to draw from wallet", "shortCode", route.ShortCode,
+			"walletID", wallet.ID, "error", err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to draw from wallet"})
+		return false, true
+	}
+
+	h.logger.Info("Paid request from wallet", "shortCode", route.ShortCode,
+		"walletID", wallet.ID, "amount", price, "balance", updated.Balance)
+	gCtx.Header(AccessHeader, AccessWallet)
+	gCtx.Header(wallets.BalanceHeader, strconv.FormatUint(updated.Balance, 10))
+
+	// Every time slice of a stream session draws the price of a request.
+	setStreamCharge(gCtx, func(ctx context.Context) error {
+		_, err := h.walletService.Draw(ctx, wallet, route.ID, price)
+		if errors.Is(err, wallets.ErrInsufficientBalance) {
+			return purchases.ErrNoCreditsLeft
+		}
+		return err
+	})
+
+	err = h.paidRouteService.IncrementAccessCount(ctx, route.ShortCode)
+	if err != nil {
+		h.logger.Error("Failed to increment access count (wallet draw)",
+			"shortCode", route.ShortCode, "error", err)
+	}
+
+	return true, false
+}
+
+// HandleWalletTopUp handles requests to top up the buyer's wallet with an
+// owner by the amount query parameter, paid like a route with x402 to the
+// owner's payment address. The wallet of the request's wallets.TokenHeader is
+// topped up if there is one, otherwise the payer's wallet, which is created
+// with its first top-up. The access token of a created wallet is only ever
+// returned in that top-up's response.
+func (h *PaidRouteHandler) HandleWalletTopUp(gCtx *gin.Context) {
+	ownerID, err := strconv.ParseUint(gCtx.Param("ownerID"), 10, 64)
+	if err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid owner ID format"})
+		return
+	}
+
+	amount, err := money.USDC.Parse(gCtx.Query("amount"))
+	if err == nil {
+		err = h.walletService.ValidateTopUpAmount(amount)
+	}
+	if err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount: " + err.Error()})
+		return
+	}
+	isTest, _ := strconv.ParseBool(gCtx.DefaultQuery("test", "true"))
+
+	ctx := gCtx.Request.Context()
+	owner, err := h.userService.GetUserByID(ctx, ownerID)
+	if err != nil {
+		gCtx.JSON(http.StatusNotFound, gin.H{"error": "Owner not found"})
+		return
+	}
+
+	paymentAddress := h.config.X402PaymentAddress
+	if owner.PaymentAddress != "" {
+		paymentAddress = owner.PaymentAddress
+	}
+
+	token := gCtx.GetHeader(wallets.TokenHeader)
+	topUpURL := fmt.Sprintf("%s://%s%s?amount=%s&test=%t", getRequestScheme(gCtx),
+		gCtx.Request.Host, gCtx.Request.URL.Path, money.USDC.Format(amount), isTest)
+
+	// The top-up is recorded as pending before the payment is settled, so a
+	// settled payment always has a record even if crediting it fails.
+	topUp := &wallets.TopUp{Amount: amount}
+	var newToken string
+	_, settleResponseJSON := x402.Payment(gCtx, amount, paymentAddress,
+		x402.WithFacilitatorURL(h.config.X402FacilitatorURL),
+		x402.WithDescription(fmt.Sprintf("Wallet top-up of $%s", money.USDC.Format(amount))),
+		x402.WithResource(topUpURL),
+		x402.WithTestnet(isTest),
+		x402.WithMaxTimeoutSeconds(h.config.X402MaxTimeoutSeconds),
+		x402.WithBeforeSettle(func(paymentPayloadJSON []byte, paymentRequirementsJSON []byte) error {
+			topUp.PaymentPayload = paymentPayloadJSON
+			topUp.PaymentRequirements = paymentRequirementsJSON
+
+			var err error
+			_, newToken, err = h.walletService.BeginTopUp(ctx, ownerID, token, isTest, topUp)
+			switch {
+			case errors.Is(err, purchases.ErrPaymentReused), errors.Is(err, purchases.ErrNoPaymentNonce),
+				errors.Is(err, wallets.ErrWalletNotFound):
+				return fmt.Errorf("%w: %w", x402.ErrPaymentRejected, err)
+			case err != nil:
+				h.logger.Error("Failed to record pending wallet top-up", "ownerID", ownerID, "error", err)
+				return errors.New("Internal server error before payment settlement.")
+			}
+			return nil
+		}),
+		x402.WithSettleFailed(func(reason string) {
+			err := h.walletService.FailTopUp(ctx, topUp.ID, reason)
+			if err != nil {
+				h.logger.Error("Failed to mark wallet top-up as failed", "topUpID", topUp.ID,
+					"reason", reason, "error", err)
+			}
+		}),
+	)
+	if gCtx.IsAborted() {
+		return
+	}
+
+	wallet, err := h.walletService.SettleTopUp(ctx, topUp.ID, settleResponseJSON)
+	if err != nil {
+		// The settle response is logged, so the top-up left pending can be
+		// credited with it.
+		h.logger.Error("Failed to credit settled wallet top-up", "topUpID", topUp.ID,
+			"settleResponse", string(settleResponseJSON), "error", err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error after payment."})
+		return
+	}
+
+	topUp.Status = purchases.StatusSettled
+	h.logger.Info("Wallet topped up", "ownerID", ownerID, "walletID", wallet.ID,
+		"amount", amount, "balance", wallet.Balance)
+	gCtx.Header(wallets.BalanceHeader, strconv.FormatUint(wallet.Balance, 10))
+
+	response := gin.H{"wallet": wallet, "top_up": topUp}
+	if newToken != "" {
+		response["wallet_token"] = newToken
+	}
+	gCtx.JSON(http.StatusOK, response)
+}
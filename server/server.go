@@ -10,26 +10,52 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"linkshrink/affiliates"
 	"linkshrink/auth"
+	"linkshrink/bundles"
+	"linkshrink/cache"
 	"linkshrink/cloudflare"
 	"linkshrink/config"
+	"linkshrink/coupons"
+	"linkshrink/idempotency"
+	"linkshrink/ledger"
+	"linkshrink/packs"
+	"linkshrink/payers"
 	"linkshrink/purchases"
+	"linkshrink/rails"
+	"linkshrink/reconciler"
 	"linkshrink/routes"
+	"linkshrink/splits"
 	"linkshrink/ui"
+	"linkshrink/upstreams"
 	"linkshrink/users"
+	"linkshrink/wallets"
 )
 
 // Server represents the HTTP server and its dependencies
 type Server struct {
-	router          *gin.Engine
-	logger          *slog.Logger
-	config          *config.Config
-	templatesFS     embed.FS
-	staticFS        embed.FS
-	userService     *users.UserService
-	routeService    *routes.PaidRouteService
-	purchaseService *purchases.PurchaseService
-	authService     *auth.Service
+	router           *gin.Engine
+	logger           *slog.Logger
+	config           *config.Config
+	templatesFS      embed.FS
+	staticFS         embed.FS
+	userService      *users.UserService
+	routeService     *routes.PaidRouteService
+	purchaseService  *purchases.PurchaseService
+	authService      *auth.Service
+	responseCache    *cache.Cache
+	upstreamService  *upstreams.Service
+	splitsService    *splits.Service
+	ledgerService    *ledger.Service
+	reconciler       *reconciler.Reconciler
+	idempotency      *idempotency.Service
+	couponService    *coupons.Service
+	payerService     *payers.Service
+	packService      *packs.Service
+	walletService    *wallets.Service
+	bundleService    *bundles.Service
+	affiliateService *affiliates.Service
+	paymentRails     []rails.Rail
 }
 
 // NewServer creates and configures a new server instance
@@ -39,6 +65,19 @@ func NewServer(
 	purchaseService *purchases.PurchaseService,
 	authService *auth.Service,
 	cloudflareService *cloudflare.Service,
+	responseCache *cache.Cache,
+	upstreamService *upstreams.Service,
+	splitsService *splits.Service,
+	ledgerService *ledger.Service,
+	settlementReconciler *reconciler.Reconciler,
+	idempotencyService *idempotency.Service,
+	couponService *coupons.Service,
+	payerService *payers.Service,
+	packService *packs.Service,
+	walletService *wallets.Service,
+	bundleService *bundles.Service,
+	affiliateService *affiliates.Service,
+	paymentRails []rails.Rail,
 
 	templatesFS embed.FS,
 	staticFS embed.FS,
@@ -49,15 +88,28 @@ func NewServer(
 	router := gin.Default() // Includes Logger and Recovery middleware
 
 	return &Server{
-		router:          router,
-		logger:          logger,
-		config:          cfg,
-		templatesFS:     templatesFS,
-		staticFS:        staticFS,
-		userService:     userService,
-		routeService:    routeService,
-		purchaseService: purchaseService,
-		authService:     authService,
+		router:           router,
+		logger:           logger,
+		config:           cfg,
+		templatesFS:      templatesFS,
+		staticFS:         staticFS,
+		userService:      userService,
+		routeService:     routeService,
+		purchaseService:  purchaseService,
+		authService:      authService,
+		responseCache:    responseCache,
+		upstreamService:  upstreamService,
+		splitsService:    splitsService,
+		ledgerService:    ledgerService,
+		reconciler:       settlementReconciler,
+		idempotency:      idempotencyService,
+		couponService:    couponService,
+		payerService:     payerService,
+		packService:      packService,
+		walletService:    walletService,
+		bundleService:    bundleService,
+		affiliateService: affiliateService,
+		paymentRails:     paymentRails,
 	}
 }
 
@@ -66,9 +118,14 @@ func (s *Server) SetupRoutes() error {
 	// Create handlers
 	oauthHandler := auth.NewAuthHandler(s.userService, s.authService, &s.config.Auth)
 	paidRouteHandler := routes.NewPaidRouteHandler(s.routeService,
-		s.purchaseService, s.userService, &s.config.Routes, s.logger)
-	uiHandler := ui.NewUIHandler(s.routeService, s.authService, s.userService, &s.config.UI, s.templatesFS, s.logger)
+		s.purchaseService, s.userService, s.responseCache, s.upstreamService, s.splitsService, s.ledgerService, s.reconciler, s.idempotency, s.couponService, s.payerService, s.packService, s.walletService, s.bundleService, s.affiliateService, s.paymentRails, s.authService, &s.config.Routes, s.logger)
+	uiHandler := ui.NewUIHandler(s.routeService, s.authService, s.userService, s.upstreamService, s.splitsService, s.reconciler, &s.config.UI, s.templatesFS, s.logger)
 	purchaseHandler := purchases.NewPurchaseHandler(s.purchaseService)
+	splitsHandler := splits.NewHandler(s.splitsService)
+	ledgerHandler := ledger.NewHandler(s.ledgerService)
+	walletHandler := wallets.NewHandler(s.walletService)
+	affiliateHandler := affiliates.NewHandler(s.affiliateService)
+	reconcilerHandler := reconciler.NewHandler(s.reconciler)
 
 	// Parse HTML templates and set them before registering any routes or it will cause a warning
 	tmpl, err := template.ParseFS(s.templatesFS, "templates/*.html")
@@ -78,6 +135,13 @@ func (s *Server) SetupRoutes() error {
 	}
 	s.router.SetHTMLTemplate(tmpl)
 
+	// Only trust X-Forwarded-For from the configured proxies, clients could
+	// forge their IP otherwise.
+	if err := s.router.SetTrustedProxies(s.config.TrustedProxies); err != nil {
+		s.logger.Error("Invalid trusted proxies", "error", err)
+		return err
+	}
+
 	// Serve static files from embedded filesystem
 	staticFileSystem, err := fs.Sub(s.staticFS, "static")
 	if err != nil {
@@ -102,8 +166,16 @@ func (s *Server) SetupRoutes() error {
 	s.router.Any("/:shortCode", func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, HEAD, PATCH")
-		c.Header("Access-Control-Allow-Headers", "*")
-		c.Header("Access-Control-Expose-Headers", "*")
+		// Wildcards don't apply to credentialed requests, so the headers of
+		// payments and streams (resumed with Last-Event-ID, upgraded to
+		// WebSocket) are listed too, as are the headers the proxy adds to
+		// responses.
+		c.Header("Access-Control-Allow-Headers", "*, Authorization, Upgrade, Last-Event-ID")
+		c.Header("Access-Control-Expose-Headers",
+			"*, PAYMENT-REQUIRED, PAYMENT-RESPONSE, X-PAYMENT-RESPONSE, WWW-Authenticate, Retry-After, "+
+				"Proxy402-Access, Proxy402-Usage, Proxy402-Balance, Proxy402-Amount-Charged, "+
+				"Proxy402-Trial-Remaining, Proxy402-Wallet-Balance, Proxy402-Schema-Violation, "+
+				"X-Proxy402-Cache, Idempotent-Replayed, X-Accel-Buffering")
 		
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
@@ -113,6 +185,26 @@ func (s *Server) SetupRoutes() error {
 		paidRouteHandler.HandlePaidRoute(c)
 	})
 
+	// --- Prepaid wallets ---
+	// Buyers top up a wallet with an owner and check its balance and history
+	s.router.GET("/wallets/:ownerID", walletHandler.GetWallet)
+	s.router.Any("/wallets/:ownerID/topup", paidRouteHandler.HandleWalletTopUp)
+
+	// --- Bundles ---
+	// Landing page listing the routes a bundle unlocks
+	s.router.GET("/bundles/:shortCode", paidRouteHandler.GetBundlePage)
+
+	// --- Affiliates ---
+	// Affiliates check their earnings with the token they were given
+	s.router.GET("/affiliate/earnings", affiliateHandler.GetEarnings)
+
+	// --- Discovery ---
+	// x402 clients and bazaar crawlers find the routes owners opted in to
+	s.router.GET("/discovery/resources", func(c *gin.Context) {
+		c.Header("Access-Control-Allow-Origin", "*")
+		paidRouteHandler.ListDiscoveryResources(c)
+	})
+
 	// Group routes that require authentication
 	authRequired := s.router.Group("/")
 	authRequired.Use(auth.AuthMiddleware(s.authService)) // Using backward compatibility
@@ -122,6 +214,7 @@ func (s *Server) SetupRoutes() error {
 		// Rerouting /links/shrink to create a PaidRoute instead of a standard Link
 		authRequired.POST("/links/shrink", paidRouteHandler.CreateURLRouteHandler)
 		authRequired.POST("/files/upload", paidRouteHandler.CreateFileRouteHandler)
+		authRequired.POST("/bundles", paidRouteHandler.CreateBundleHandler)
 
 		// User-specific link management (standard links)
 		// These might become obsolete if only PaidRoutes are used
@@ -130,10 +223,67 @@ func (s *Server) SetupRoutes() error {
 		{
 			linksGroup.GET("", paidRouteHandler.GetUserPaidRoutes)
 			linksGroup.DELETE("/:linkID", paidRouteHandler.DeleteUserPaidRoute) // Note: Param is still :linkID
+
+			// Upstream pool management
+			linksGroup.GET("/:linkID/targets", paidRouteHandler.GetRoutePool)
+			linksGroup.POST("/:linkID/targets", paidRouteHandler.AddRouteTarget)
+			linksGroup.DELETE("/:linkID/targets/:targetID", paidRouteHandler.DeleteRouteTarget)
+			linksGroup.PUT("/:linkID/load-balancing", paidRouteHandler.UpdateRouteLoadBalancing)
+
+			// Revenue split management
+			linksGroup.GET("/:linkID/splits", paidRouteHandler.GetRouteSplits)
+			linksGroup.PUT("/:linkID/splits", paidRouteHandler.UpdateRouteSplits)
+
+			// Free trial management
+			linksGroup.GET("/:linkID/trial", paidRouteHandler.GetRouteTrial)
+			linksGroup.PUT("/:linkID/trial", paidRouteHandler.UpdateRouteTrial)
+
+			// Credit packs
+			linksGroup.GET("/:linkID/packs", paidRouteHandler.GetRoutePacks)
+			linksGroup.POST("/:linkID/packs", paidRouteHandler.CreateRoutePack)
+			linksGroup.DELETE("/:linkID/packs/:packID", paidRouteHandler.DeleteRoutePack)
+
+			// Scheduled price changes
+			linksGroup.GET("/:linkID/prices", paidRouteHandler.GetRoutePriceChanges)
+			linksGroup.POST("/:linkID/prices", paidRouteHandler.CreateRoutePriceChange)
+			linksGroup.DELETE("/:linkID/prices/:priceChangeID", paidRouteHandler.DeleteRoutePriceChange)
 		}
 
+		// Coupon management
+		authRequired.GET("/coupons", paidRouteHandler.GetCoupons)
+		authRequired.POST("/coupons", paidRouteHandler.CreateCoupon)
+		authRequired.DELETE("/coupons/:couponID", paidRouteHandler.DeleteCoupon)
+
+		// Payer allowlist and blocklist management
+		authRequired.GET("/payers", paidRouteHandler.GetPayerRules)
+		authRequired.POST("/payers", paidRouteHandler.CreatePayerRule)
+		authRequired.DELETE("/payers/:ruleID", paidRouteHandler.DeletePayerRule)
+
+		// Affiliate management
+		authRequired.GET("/affiliates", paidRouteHandler.GetAffiliates)
+		authRequired.POST("/affiliates", paidRouteHandler.CreateAffiliate)
+		authRequired.DELETE("/affiliates/:affiliateID", paidRouteHandler.DeleteAffiliate)
+		authRequired.GET("/affiliates/:affiliateID/sales", paidRouteHandler.GetAffiliateSales)
+
+		// Wallets buyers hold with the user
+		authRequired.GET("/wallets", walletHandler.GetWallets)
+
 		// Dashboard data endpoint
 		authRequired.GET("/dashboard/stats", purchaseHandler.GetDashboardStats)
+
+		// Per-payee payout rows, as JSON or CSV export
+		authRequired.GET("/payouts", splitsHandler.GetPayouts)
+
+		// Ledger account balances
+		authRequired.GET("/ledger/balances", ledgerHandler.GetBalances)
+
+		// Purchases left pending while their payment was settled
+		authRequired.GET("/purchases/pending", paidRouteHandler.GetPendingPurchases)
+		authRequired.POST("/purchases/:purchaseID/resolve", paidRouteHandler.ResolvePendingPurchase)
+
+		// Settlements of purchases the reconciler found a problem with
+		authRequired.GET("/settlement-alerts", reconcilerHandler.GetAlerts)
+		authRequired.POST("/settlement-alerts/:alertID/dismiss", reconcilerHandler.DismissAlert)
 	}
 
 	// Logout route
@@ -0,0 +1,14 @@
+package splits
+
+// DefaultConfig returns all default values for the Config struct.
+func DefaultConfig() Config {
+	return Config{
+		PlatformFeeBPS: 0,
+	}
+}
+
+// Config holds the configuration for revenue splits.
+type Config struct {
+	PlatformFeeBPS  uint64 `long:"platform_fee_bps" description:"Platform fee taken from each payment, in basis points"`
+	PlatformAddress string `long:"platform_address" description:"Address the platform fee is owed to"`
+}
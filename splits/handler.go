@@ -0,0 +1,68 @@
+package splits
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/auth"
+	"linkshrink/money"
+)
+
+// Handler handles HTTP requests related to revenue splits.
+type Handler struct {
+	splitsService *Service
+}
+
+// NewHandler creates a new Handler.
+func NewHandler(splitsService *Service) *Handler {
+	return &Handler{
+		splitsService: splitsService,
+	}
+}
+
+// GetPayouts returns the per-payee payout rows of the user's routes, as JSON
+// or, with format=csv, as a CSV export.
+func (h *Handler) GetPayouts(gCtx *gin.Context) {
+	authPayload, exists := gCtx.Get(auth.AuthorizationPayloadKey)
+	if !exists {
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	payload := authPayload.(*auth.Claims)
+
+	payouts, err := h.splitsService.ListUserPayouts(gCtx.Request.Context(), payload.UserID)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve payouts"})
+		return
+	}
+
+	if gCtx.Query("format") != "csv" {
+		gCtx.JSON(http.StatusOK, gin.H{"payouts": payouts})
+		return
+	}
+
+	gCtx.Header("Content-Disposition", `attachment; filename="payouts.csv"`)
+	gCtx.Header("Content-Type", "text/csv")
+	gCtx.Status(http.StatusOK)
+
+	w := csv.NewWriter(gCtx.Writer)
+	_ = w.Write([]string{"created_at", "short_code", "purchase_id",
+		"payee_address", "role", "share_bps", "amount"})
+	for _, payout := range payouts {
+		_ = w.Write([]string{
+			payout.CreatedAt.UTC().Format(time.RFC3339),
+			payout.ShortCode,
+			strconv.FormatUint(payout.PurchaseID, 10),
+			payout.PayeeAddress,
+			payout.Role,
+			strconv.FormatUint(payout.ShareBPS, 10),
+			money.USDC.Format(payout.Amount),
+		})
+	}
+	w.Flush()
+}
@@ -0,0 +1,28 @@
+package splits
+
+import (
+	"context"
+	"errors"
+)
+
+// Custom errors for revenue split operations
+var (
+	ErrInvalidShare   = errors.New("split shares must be between 1 and 10000 basis points")
+	ErrSharesTooLarge = errors.New("split shares and platform fee exceed 100%")
+	ErrInvalidPayee   = errors.New("split payee address cannot be empty")
+)
+
+// Store provides access to the revenue split storage.
+type Store interface {
+	// ListRouteSplits retrieves the revenue shares of a route.
+	ListRouteSplits(ctx context.Context, routeID uint64) ([]Split, error)
+
+	// ReplaceRouteSplits replaces all revenue shares of a route.
+	ReplaceRouteSplits(ctx context.Context, routeID uint64, splits []Split) ([]Split, error)
+
+	// ListPayoutTotalsByRouteID retrieves what each payee is owed for a route.
+	ListPayoutTotalsByRouteID(ctx context.Context, routeID uint64) ([]PayeeTotal, error)
+
+	// ListPayoutsByUserID retrieves the payouts of all routes of a user.
+	ListPayoutsByUserID(ctx context.Context, userID uint64) ([]Payout, error)
+}
@@ -0,0 +1,76 @@
+package splits
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Service provides business logic for managing revenue splits.
+type Service struct {
+	cfg *Config
+
+	logger *slog.Logger
+	store  Store
+}
+
+// NewService creates a new split Service.
+func NewService(logger *slog.Logger, store Store, cfg *Config) *Service {
+	return &Service{
+		cfg:    cfg,
+		logger: logger,
+		store:  store,
+	}
+}
+
+// ListSplits retrieves the revenue shares of a route.
+func (s *Service) ListSplits(ctx context.Context, routeID uint64) ([]Split, error) {
+	return s.store.ListRouteSplits(ctx, routeID)
+}
+
+// SetSplits replaces the revenue shares of a route. The shares together with
+// the platform fee may not exceed 100%, the owner receives what is left.
+func (s *Service) SetSplits(ctx context.Context, routeID uint64, splits []Split) ([]Split, error) {
+	totalBPS := s.cfg.PlatformFeeBPS
+	for i := range splits {
+		splits[i].PayeeAddress = strings.TrimSpace(splits[i].PayeeAddress)
+		if splits[i].PayeeAddress == "" {
+			return nil, ErrInvalidPayee
+		}
+		if splits[i].ShareBPS == 0 || splits[i].ShareBPS > TotalBPS {
+			return nil, ErrInvalidShare
+		}
+		totalBPS += splits[i].ShareBPS
+	}
+	if totalBPS > TotalBPS {
+		return nil, ErrSharesTooLarge
+	}
+
+	return s.store.ReplaceRouteSplits(ctx, routeID, splits)
+}
+
+// AllocatePayouts divides the price of a settled purchase between its
+// payees, including the affiliate that referred it if commission is not nil.
+// The payouts are recorded with the purchase being settled.
+func (s *Service) AllocatePayouts(ctx context.Context, purchaseID, routeID, price uint64,
+	ownerAddress string, commission *Commission) ([]Payout, error) {
+
+	splits, err := s.store.ListRouteSplits(ctx, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list route splits: %w", err)
+	}
+
+	return Allocate(purchaseID, routeID, price, ownerAddress,
+		s.cfg.PlatformAddress, s.cfg.PlatformFeeBPS, splits, commission), nil
+}
+
+// ListPayeeTotals retrieves what each payee is owed for a route.
+func (s *Service) ListPayeeTotals(ctx context.Context, routeID uint64) ([]PayeeTotal, error) {
+	return s.store.ListPayoutTotalsByRouteID(ctx, routeID)
+}
+
+// ListUserPayouts retrieves the payouts of all routes of a user.
+func (s *Service) ListUserPayouts(ctx context.Context, userID uint64) ([]Payout, error) {
+	return s.store.ListPayoutsByUserID(ctx, userID)
+}
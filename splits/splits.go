@@ -0,0 +1,143 @@
+package splits
+
+import (
+	"time"
+)
+
+// TotalBPS is 100% in basis points.
+const TotalBPS = 10000
+
+// Payee roles of a payout.
+const (
+	RoleAffiliate = "affiliate"
+	RoleOwner     = "owner"
+	RolePartner   = "partner"
+	RolePlatform  = "platform"
+)
+
+// Split is the share of a route's revenue owed to a partner.
+type Split struct {
+	ID           uint64 `json:"id"`
+	PaidRouteID  uint64 `json:"-"`
+	PayeeAddress string `json:"payee_address"`
+	Label        string `json:"label,omitempty"`
+	// ShareBPS is the share of each payment in basis points.
+	ShareBPS uint64 `json:"share_bps"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Payout is what a payee is owed for a single purchase.
+type Payout struct {
+	ID           uint64 `json:"id"`
+	PurchaseID   uint64 `json:"purchase_id"`
+	PaidRouteID  uint64 `json:"-"`
+	ShortCode    string `json:"short_code,omitempty"`
+	PayeeAddress string `json:"payee_address"`
+	Role         string `json:"role"`
+	ShareBPS     uint64 `json:"share_bps"`
+	// Amount is in base units (USDC * 10^6).
+	Amount uint64 `json:"amount"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Commission is the share of a purchase owed to the affiliate that referred
+// the buyer.
+type Commission struct {
+	PayeeAddress string
+	// ShareBPS is the share of the purchase in basis points.
+	ShareBPS uint64
+}
+
+// PayeeTotal is what a payee is owed across all purchases of a route.
+type PayeeTotal struct {
+	PayeeAddress string `json:"payee_address"`
+	Role         string `json:"role"`
+	Amount       uint64 `json:"amount"`
+	Count        uint64 `json:"count"`
+}
+
+// Allocate divides the price of a purchase between the platform, the route's
+// partners, the referring affiliate if commission is not nil, and the owner.
+// Shares are rounded down and the owner receives the remainder, so the
+// payouts always add up to the price. The commission comes out of the owner's
+// remainder and is capped by it.
+//
+// Splits are checked against the platform fee when they are saved, but the
+// fee can be raised afterwards. The platform is paid first, and splits that
+// no longer fit in what is left are scaled down to share it.
+func Allocate(purchaseID, routeID, price uint64, ownerAddress string,
+	platformAddress string, platformFeeBPS uint64, splits []Split, commission *Commission) []Payout {
+
+	if platformAddress == "" {
+		platformFeeBPS = 0
+	}
+	platformFeeBPS = min(platformFeeBPS, TotalBPS)
+	splits = fitSplits(splits, TotalBPS-platformFeeBPS)
+
+	payouts := make([]Payout, 0, len(splits)+3)
+	remainingBPS := uint64(TotalBPS)
+	remaining := price
+
+	addPayout := func(address, role string, shareBPS, amount uint64) {
+		payouts = append(payouts, Payout{
+			PurchaseID:   purchaseID,
+			PaidRouteID:  routeID,
+			PayeeAddress: address,
+			Role:         role,
+			ShareBPS:     shareBPS,
+			Amount:       amount,
+		})
+		remainingBPS -= shareBPS
+		remaining -= amount
+	}
+
+	if platformFeeBPS > 0 {
+		addPayout(platformAddress, RolePlatform, platformFeeBPS, shareOf(price, platformFeeBPS))
+	}
+	for _, split := range splits {
+		addPayout(split.PayeeAddress, RolePartner, split.ShareBPS, shareOf(price, split.ShareBPS))
+	}
+	if commission != nil {
+		addPayout(commission.PayeeAddress, RoleAffiliate, min(commission.ShareBPS, remainingBPS),
+			min(shareOf(price, commission.ShareBPS), remaining))
+	}
+
+	// Record the owner even when nothing is left, so every purchase has one.
+	payouts = append(payouts, Payout{
+		PurchaseID:   purchaseID,
+		PaidRouteID:  routeID,
+		PayeeAddress: ownerAddress,
+		Role:         RoleOwner,
+		ShareBPS:     remainingBPS,
+		Amount:       remaining,
+	})
+
+	return payouts
+}
+
+// fitSplits returns the splits scaled down, in proportion to their shares and
+// rounded down, to add up to at most availableBPS. Splits that fit are
+// returned as they are.
+func fitSplits(splits []Split, availableBPS uint64) []Split {
+	var totalBPS uint64
+	for _, split := range splits {
+		totalBPS += split.ShareBPS
+	}
+	if totalBPS <= availableBPS {
+		return splits
+	}
+
+	scaled := make([]Split, len(splits))
+	for i, split := range splits {
+		scaled[i] = split
+		scaled[i].ShareBPS = split.ShareBPS * availableBPS / totalBPS
+	}
+	return scaled
+}
+
+// shareOf returns shareBPS of amount, rounded down, without overflowing.
+func shareOf(amount, shareBPS uint64) uint64 {
+	return amount/TotalBPS*shareBPS + amount%TotalBPS*shareBPS/TotalBPS
+}
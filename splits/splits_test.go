@@ -0,0 +1,101 @@
+package splits
+
+import "testing"
+
+func TestAllocate(t *testing.T) {
+	partners := []Split{
+		{PayeeAddress: "0xpartner1", ShareBPS: 3000},
+		{PayeeAddress: "0xpartner2", ShareBPS: 2000},
+	}
+
+	tests := []struct {
+		name       string
+		price      uint64
+		feeBPS     uint64
+		splits     []Split
+		commission *Commission
+		want       []Payout
+	}{
+		{
+			name:   "fee and splits fit",
+			price:  1000000,
+			feeBPS: 500,
+			splits: partners,
+			want: []Payout{
+				{PayeeAddress: "0xplatform", Role: RolePlatform, ShareBPS: 500, Amount: 50000},
+				{PayeeAddress: "0xpartner1", Role: RolePartner, ShareBPS: 3000, Amount: 300000},
+				{PayeeAddress: "0xpartner2", Role: RolePartner, ShareBPS: 2000, Amount: 200000},
+				{PayeeAddress: "0xowner", Role: RoleOwner, ShareBPS: 4500, Amount: 450000},
+			},
+		},
+		{
+			name:   "fee raised after splits were saved",
+			price:  1000000,
+			feeBPS: 6000,
+			splits: partners,
+			want: []Payout{
+				{PayeeAddress: "0xplatform", Role: RolePlatform, ShareBPS: 6000, Amount: 600000},
+				{PayeeAddress: "0xpartner1", Role: RolePartner, ShareBPS: 2400, Amount: 240000},
+				{PayeeAddress: "0xpartner2", Role: RolePartner, ShareBPS: 1600, Amount: 160000},
+				{PayeeAddress: "0xowner", Role: RoleOwner, ShareBPS: 0, Amount: 0},
+			},
+		},
+		{
+			name:       "fee above 100%",
+			price:      999,
+			feeBPS:     12000,
+			splits:     partners,
+			commission: &Commission{PayeeAddress: "0xaffiliate", ShareBPS: 1000},
+			want: []Payout{
+				{PayeeAddress: "0xplatform", Role: RolePlatform, ShareBPS: 10000, Amount: 999},
+				{PayeeAddress: "0xpartner1", Role: RolePartner, ShareBPS: 0, Amount: 0},
+				{PayeeAddress: "0xpartner2", Role: RolePartner, ShareBPS: 0, Amount: 0},
+				{PayeeAddress: "0xaffiliate", Role: RoleAffiliate, ShareBPS: 0, Amount: 0},
+				{PayeeAddress: "0xowner", Role: RoleOwner, ShareBPS: 0, Amount: 0},
+			},
+		},
+		{
+			name:       "commission capped by the owner's remainder",
+			price:      1000,
+			feeBPS:     1000,
+			splits:     []Split{{PayeeAddress: "0xpartner1", ShareBPS: 8500}},
+			commission: &Commission{PayeeAddress: "0xaffiliate", ShareBPS: 1000},
+			want: []Payout{
+				{PayeeAddress: "0xplatform", Role: RolePlatform, ShareBPS: 1000, Amount: 100},
+				{PayeeAddress: "0xpartner1", Role: RolePartner, ShareBPS: 8500, Amount: 850},
+				{PayeeAddress: "0xaffiliate", Role: RoleAffiliate, ShareBPS: 500, Amount: 50},
+				{PayeeAddress: "0xowner", Role: RoleOwner, ShareBPS: 0, Amount: 0},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			payouts := Allocate(1, 2, test.price, "0xowner", "0xplatform", test.feeBPS,
+				test.splits, test.commission)
+
+			if len(payouts) != len(test.want) {
+				t.Fatalf("got %d payouts, want %d: %+v", len(payouts), len(test.want), payouts)
+			}
+			var totalBPS, total uint64
+			for i, payout := range payouts {
+				want := test.want[i]
+				want.PurchaseID, want.PaidRouteID = 1, 2
+				if payout != want {
+					t.Errorf("payout %d = %+v, want %+v", i, payout, want)
+				}
+				totalBPS += payout.ShareBPS
+				total += payout.Amount
+			}
+			if totalBPS != TotalBPS || total != test.price {
+				t.Errorf("payouts add up to %d bps and %d, want %d bps and %d",
+					totalBPS, total, TotalBPS, test.price)
+			}
+		})
+	}
+
+	// The saved splits are left as they are.
+	if partners[0].ShareBPS != 3000 || partners[1].ShareBPS != 2000 {
+		t.Errorf("splits were modified: %+v", partners)
+	}
+}
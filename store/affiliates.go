@@ -0,0 +1,212 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"linkshrink/affiliates"
+	"linkshrink/store/sqlc"
+)
+
+// CreateAffiliate creates an affiliate with the hash of its earnings token,
+// ErrCodeTaken if the owner already has an affiliate with its code.
+func (s *Store) CreateAffiliate(ctx context.Context, affiliate *affiliates.Affiliate,
+	tokenHash string) (*affiliates.Affiliate, error) {
+
+	dbAffiliate, err := s.queries.CreateAffiliate(ctx, sqlc.CreateAffiliateParams{
+		UserID:        int64(affiliate.UserID),
+		PaidRouteID:   nullableID(affiliate.RouteID),
+		Code:          affiliate.Code,
+		PayoutAddress: affiliate.PayoutAddress,
+		Label:         affiliate.Label,
+		CommissionBps: int32(affiliate.CommissionBPS),
+		TokenHash:     tokenHash,
+		CreatedAt:     s.clock.Now(),
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, affiliates.ErrCodeTaken
+		}
+		return nil, fmt.Errorf("failed to create affiliate: %w", err)
+	}
+
+	created := convertToAffiliateModel(dbAffiliate)
+	return &created, nil
+}
+
+// ListAffiliatesByUserID retrieves the affiliates of an owner with their
+// earnings.
+func (s *Store) ListAffiliatesByUserID(ctx context.Context, userID uint64) ([]affiliates.Affiliate, error) {
+	dbAffiliates, err := s.queries.ListAffiliatesByUserID(ctx, int64(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	userAffiliates := make([]affiliates.Affiliate, len(dbAffiliates))
+	for i, dbAffiliate := range dbAffiliates {
+		userAffiliates[i] = convertToAffiliateModel(dbAffiliate)
+	}
+
+	if err := s.loadAffiliateEarnings(ctx, userAffiliates); err != nil {
+		return nil, err
+	}
+	return userAffiliates, nil
+}
+
+// GetAffiliate retrieves an affiliate of an owner with its earnings.
+func (s *Store) GetAffiliate(ctx context.Context, affiliateID, userID uint64) (*affiliates.Affiliate, error) {
+	dbAffiliate, err := s.queries.GetAffiliate(ctx, sqlc.GetAffiliateParams{
+		ID:     int64(affiliateID),
+		UserID: int64(userID),
+	})
+	return s.affiliateWithEarnings(ctx, dbAffiliate, err)
+}
+
+// GetAffiliateByTokenHash retrieves the affiliate an earnings token belongs
+// to with its earnings.
+func (s *Store) GetAffiliateByTokenHash(ctx context.Context, tokenHash string) (*affiliates.Affiliate, error) {
+	dbAffiliate, err := s.queries.GetAffiliateByTokenHash(ctx, tokenHash)
+	return s.affiliateWithEarnings(ctx, dbAffiliate, err)
+}
+
+// affiliateWithEarnings converts a retrieved affiliate and loads its
+// earnings, err is the error retrieving it.
+func (s *Store) affiliateWithEarnings(ctx context.Context, dbAffiliate sqlc.Affiliate,
+	err error) (*affiliates.Affiliate, error) {
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, affiliates.ErrAffiliateNotFound
+		}
+		return nil, err
+	}
+
+	affiliate := []affiliates.Affiliate{convertToAffiliateModel(dbAffiliate)}
+	if err := s.loadAffiliateEarnings(ctx, affiliate); err != nil {
+		return nil, err
+	}
+	return &affiliate[0], nil
+}
+
+// loadAffiliateEarnings sets the earnings of affiliates from the settled
+// purchases they referred.
+func (s *Store) loadAffiliateEarnings(ctx context.Context, list []affiliates.Affiliate) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(list))
+	for i := range list {
+		ids[i] = int64(list[i].ID)
+	}
+
+	rows, err := s.queries.ListAffiliateEarnings(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to list affiliate earnings: %w", err)
+	}
+
+	earnings := make(map[uint64]affiliates.Earnings, len(rows))
+	for _, row := range rows {
+		earnings[uint64(row.AffiliateID)] = affiliates.Earnings{
+			SaleCount:      uint64(row.SaleCount),
+			Sales:          uint64(row.Sales),
+			Commission:     uint64(row.Commission),
+			TestSaleCount:  uint64(row.TestSaleCount),
+			TestSales:      uint64(row.TestSales),
+			TestCommission: uint64(row.TestCommission),
+		}
+	}
+	for i := range list {
+		list[i].Earnings = earnings[list[i].ID]
+	}
+	return nil
+}
+
+// GetAffiliateForRoute retrieves an owner's affiliate by code if it earns
+// commission on the route.
+func (s *Store) GetAffiliateForRoute(ctx context.Context, userID, routeID uint64,
+	code string) (*affiliates.Affiliate, error) {
+
+	dbAffiliate, err := s.queries.GetAffiliateForRoute(ctx, sqlc.GetAffiliateForRouteParams{
+		UserID:      int64(userID),
+		Code:        code,
+		PaidRouteID: nullableID(routeID),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, affiliates.ErrAffiliateNotFound
+		}
+		return nil, err
+	}
+
+	affiliate := convertToAffiliateModel(dbAffiliate)
+	return &affiliate, nil
+}
+
+// DeleteAffiliate deletes an affiliate of an owner.
+func (s *Store) DeleteAffiliate(ctx context.Context, affiliateID, userID uint64) error {
+	rows, err := s.queries.DeleteAffiliate(ctx, sqlc.DeleteAffiliateParams{
+		ID:        int64(affiliateID),
+		UserID:    int64(userID),
+		DeletedAt: pgtype.Timestamptz{Time: s.clock.Now(), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete affiliate: %w", err)
+	}
+	if rows == 0 {
+		return affiliates.ErrAffiliateNotFound
+	}
+	return nil
+}
+
+// ListAffiliateSales retrieves up to limit of the latest settled purchases an
+// affiliate referred.
+func (s *Store) ListAffiliateSales(ctx context.Context, affiliateID uint64,
+	limit uint64) ([]affiliates.Sale, error) {
+
+	dbSales, err := s.queries.ListAffiliateSales(ctx, sqlc.ListAffiliateSalesParams{
+		AffiliateID: nullableID(affiliateID),
+		Limit:       int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sales := make([]affiliates.Sale, len(dbSales))
+	for i, dbSale := range dbSales {
+		sales[i] = affiliates.Sale{
+			PurchaseID: uint64(dbSale.ID),
+			ShortCode:  dbSale.ShortCode,
+			Price:      uint64(dbSale.Price),
+			Commission: uint64(dbSale.AffiliateCommission),
+			IsTest:     dbSale.IsTest,
+			CreatedAt:  dbSale.CreatedAt,
+		}
+	}
+
+	return sales, nil
+}
+
+// convertToAffiliateModel converts a database affiliate to an
+// affiliates.Affiliate.
+func convertToAffiliateModel(dbAffiliate sqlc.Affiliate) affiliates.Affiliate {
+	affiliate := affiliates.Affiliate{
+		ID:            uint64(dbAffiliate.ID),
+		UserID:        uint64(dbAffiliate.UserID),
+		Code:          dbAffiliate.Code,
+		PayoutAddress: dbAffiliate.PayoutAddress,
+		Label:         dbAffiliate.Label,
+		CommissionBPS: uint64(dbAffiliate.CommissionBps),
+		CreatedAt:     dbAffiliate.CreatedAt,
+	}
+	if dbAffiliate.PaidRouteID.Valid {
+		affiliate.RouteID = uint64(dbAffiliate.PaidRouteID.Int64)
+	}
+	return affiliate
+}
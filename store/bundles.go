@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"linkshrink/bundles"
+	pkgPurchases "linkshrink/purchases"
+	"linkshrink/routes"
+	"linkshrink/store/sqlc"
+)
+
+// CreateBundleRoute atomically creates a bundle route, its bundle and the
+// bundle's members, listed in the order of memberIDs.
+func (s *Store) CreateBundleRoute(ctx context.Context, route *routes.PaidRoute,
+	bundle *bundles.Bundle, memberIDs []uint64) (*routes.PaidRoute, error) {
+
+	params, err := s.createPaidRouteParams(route)
+	if err != nil {
+		return nil, err
+	}
+
+	var created *routes.PaidRoute
+	err = s.ExecTx(ctx, func(q *sqlc.Queries) error {
+		dbRoute, err := q.CreatePaidRoute(ctx, params)
+		if err != nil {
+			return fmt.Errorf("failed to create paid route: %w", err)
+		}
+
+		_, err = q.CreateBundle(ctx, sqlc.CreateBundleParams{
+			PaidRouteID:   dbRoute.ID,
+			AccessSeconds: int64(bundle.AccessSeconds),
+			CreatedAt:     params.CreatedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create bundle: %w", err)
+		}
+
+		for i, memberID := range memberIDs {
+			err = q.AddBundleRoute(ctx, sqlc.AddBundleRouteParams{
+				BundleRouteID: dbRoute.ID,
+				PaidRouteID:   int64(memberID),
+				Position:      int32(i),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to add route to bundle: %w", err)
+			}
+		}
+
+		created = convertToPaidRouteModel(dbRoute)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// GetBundle retrieves the bundle of a bundle route.
+func (s *Store) GetBundle(ctx context.Context, routeID uint64) (*bundles.Bundle, error) {
+	dbBundle, err := s.queries.GetBundle(ctx, int64(routeID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, bundles.ErrBundleNotFound
+		}
+		return nil, fmt.Errorf("failed to get bundle: %w", err)
+	}
+
+	return &bundles.Bundle{
+		RouteID:       uint64(dbBundle.PaidRouteID),
+		AccessSeconds: uint64(dbBundle.AccessSeconds),
+		CreatedAt:     dbBundle.CreatedAt,
+	}, nil
+}
+
+// ListBundleMembers retrieves the routes of a bundle that are not deleted, in
+// the bundle's order.
+func (s *Store) ListBundleMembers(ctx context.Context, routeID uint64) ([]bundles.Member, error) {
+	dbRoutes, err := s.queries.ListBundleRoutes(ctx, int64(routeID))
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]bundles.Member, len(dbRoutes))
+	for i, dbRoute := range dbRoutes {
+		route := convertToPaidRouteModel(dbRoute)
+		members[i] = bundles.Member{
+			RouteID:                route.ID,
+			ShortCode:              route.ShortCode,
+			Method:                 route.Method,
+			ResourceType:           route.ResourceType,
+			Title:                  route.Title,
+			Description:            route.Description,
+			Price:                  route.Price,
+			PaymentProtocolVersion: route.PaymentProtocolVersion,
+		}
+	}
+
+	return members, nil
+}
+
+// GetBundlePurchaseByRouteIDAndPaymentHeader retrieves the latest settled
+// purchase of a bundle the route is part of that was paid with the payment
+// header.
+func (s *Store) GetBundlePurchaseByRouteIDAndPaymentHeader(ctx context.Context, routeID uint64,
+	paymentHeader string) (*pkgPurchases.Purchase, error) {
+
+	dbPurchase, err := s.queries.GetBundlePurchaseByRouteIDAndPaymentHeader(ctx,
+		sqlc.GetBundlePurchaseByRouteIDAndPaymentHeaderParams{
+			PaidRouteID:   int64(routeID),
+			PaymentHeader: pgtype.Text{String: paymentHeader, Valid: paymentHeader != ""},
+		})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, pkgPurchases.ErrPurchaseNotFound
+		}
+		return nil, fmt.Errorf("failed to get bundle purchase by route ID and payment header: %w", err)
+	}
+	return convertToPurchaseModel(dbPurchase), nil
+}
@@ -0,0 +1,207 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"linkshrink/coupons"
+	"linkshrink/store/sqlc"
+)
+
+// uniqueViolation is the Postgres error code of a unique constraint violation.
+const uniqueViolation = "23505"
+
+// CreateCoupon creates a coupon, ErrCodeTaken if the owner already has a
+// coupon with its code.
+func (s *Store) CreateCoupon(ctx context.Context, coupon *coupons.Coupon) (*coupons.Coupon, error) {
+	var expiresAt pgtype.Timestamptz
+	if coupon.ExpiresAt != nil {
+		expiresAt = pgtype.Timestamptz{Time: *coupon.ExpiresAt, Valid: true}
+	}
+
+	dbCoupon, err := s.queries.CreateCoupon(ctx, sqlc.CreateCouponParams{
+		UserID:         int64(coupon.UserID),
+		PaidRouteID:    nullableID(coupon.RouteID),
+		Code:           coupon.Code,
+		DiscountType:   coupon.DiscountType,
+		DiscountValue:  int64(coupon.DiscountValue),
+		MaxRedemptions: int64(coupon.MaxRedemptions),
+		ExpiresAt:      expiresAt,
+		CreatedAt:      s.clock.Now(),
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, coupons.ErrCodeTaken
+		}
+		return nil, fmt.Errorf("failed to create coupon: %w", err)
+	}
+
+	created := convertToCouponModel(dbCoupon)
+	return &created, nil
+}
+
+// ListCouponsByUserID retrieves the coupons of an owner.
+func (s *Store) ListCouponsByUserID(ctx context.Context, userID uint64) ([]coupons.Coupon, error) {
+	dbCoupons, err := s.queries.ListCouponsByUserID(ctx, int64(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	userCoupons := make([]coupons.Coupon, len(dbCoupons))
+	for i, dbCoupon := range dbCoupons {
+		userCoupons[i] = convertToCouponModel(dbCoupon)
+	}
+
+	return userCoupons, nil
+}
+
+// DeleteCoupon deletes a coupon of an owner.
+func (s *Store) DeleteCoupon(ctx context.Context, couponID, userID uint64) error {
+	rows, err := s.queries.DeleteCoupon(ctx, sqlc.DeleteCouponParams{
+		ID:        int64(couponID),
+		UserID:    int64(userID),
+		DeletedAt: pgtype.Timestamptz{Time: s.clock.Now(), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete coupon: %w", err)
+	}
+	if rows == 0 {
+		return coupons.ErrCouponNotFound
+	}
+	return nil
+}
+
+// GetCouponForRoute retrieves an owner's coupon by code if it applies to the
+// route.
+func (s *Store) GetCouponForRoute(ctx context.Context, userID, routeID uint64,
+	code string) (*coupons.Coupon, error) {
+
+	dbCoupon, err := s.queries.GetCouponForRoute(ctx, sqlc.GetCouponForRouteParams{
+		UserID:      int64(userID),
+		Code:        code,
+		PaidRouteID: nullableID(routeID),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, coupons.ErrCouponNotFound
+		}
+		return nil, err
+	}
+
+	coupon := convertToCouponModel(dbCoupon)
+	return &coupon, nil
+}
+
+// RedeemCoupon atomically counts a redemption of a coupon and records it.
+func (s *Store) RedeemCoupon(ctx context.Context, redemption *coupons.Redemption) error {
+	now := s.clock.Now()
+	return s.ExecTx(ctx, func(q *sqlc.Queries) error {
+		rows, err := q.IncrementCouponRedemptions(ctx, sqlc.IncrementCouponRedemptionsParams{
+			ID:        int64(redemption.CouponID),
+			UpdatedAt: now,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to count coupon redemption: %w", err)
+		}
+		if rows == 0 {
+			return coupons.ErrCouponUnavailable
+		}
+
+		return q.CreateCouponRedemption(ctx, sqlc.CreateCouponRedemptionParams{
+			CouponID:    int64(redemption.CouponID),
+			PaidRouteID: int64(redemption.RouteID),
+			PurchaseID:  nullableID(redemption.PurchaseID),
+			Discount:    int64(redemption.Discount),
+			CreatedAt:   now,
+		})
+	})
+}
+
+// releaseCouponRedemption releases the coupon redemption of a purchase, if it
+// has one, so the coupon can be redeemed again.
+func (s *Store) releaseCouponRedemption(ctx context.Context, q *sqlc.Queries,
+	purchaseID uint64) error {
+
+	couponID, err := q.DeleteCouponRedemptionByPurchaseID(ctx, nullableID(purchaseID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	return q.DecrementCouponRedemptions(ctx, sqlc.DecrementCouponRedemptionsParams{
+		ID:        couponID,
+		UpdatedAt: s.clock.Now(),
+	})
+}
+
+// GetRouteTrial retrieves the number of free requests each client gets on a
+// route, 0 if the route has no trial.
+func (s *Store) GetRouteTrial(ctx context.Context, routeID uint64) (uint64, error) {
+	trial, err := s.queries.GetRouteTrial(ctx, int64(routeID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return uint64(trial.FreeRequests), nil
+}
+
+// SetRouteTrial sets the number of free requests each client gets on a route.
+func (s *Store) SetRouteTrial(ctx context.Context, routeID, freeRequests uint64) error {
+	return s.queries.UpsertRouteTrial(ctx, sqlc.UpsertRouteTrialParams{
+		PaidRouteID:  int64(routeID),
+		FreeRequests: int64(freeRequests),
+		CreatedAt:    s.clock.Now(),
+	})
+}
+
+// UseTrialRequest counts a free request of a client unless it used all
+// freeRequests.
+func (s *Store) UseTrialRequest(ctx context.Context, routeID uint64, clientKey string,
+	freeRequests uint64) (uint64, bool, error) {
+
+	used, err := s.queries.UseTrialRequest(ctx, sqlc.UseTrialRequestParams{
+		PaidRouteID: int64(routeID),
+		ClientKey:   clientKey,
+		UsedCount:   int64(freeRequests),
+		CreatedAt:   s.clock.Now(),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	return uint64(used), true, nil
+}
+
+// convertToCouponModel converts a database coupon to a coupons.Coupon.
+func convertToCouponModel(dbCoupon sqlc.Coupon) coupons.Coupon {
+	coupon := coupons.Coupon{
+		ID:              uint64(dbCoupon.ID),
+		UserID:          uint64(dbCoupon.UserID),
+		Code:            dbCoupon.Code,
+		DiscountType:    dbCoupon.DiscountType,
+		DiscountValue:   uint64(dbCoupon.DiscountValue),
+		MaxRedemptions:  uint64(dbCoupon.MaxRedemptions),
+		RedemptionCount: uint64(dbCoupon.RedemptionCount),
+		CreatedAt:       dbCoupon.CreatedAt,
+	}
+	if dbCoupon.PaidRouteID.Valid {
+		coupon.RouteID = uint64(dbCoupon.PaidRouteID.Int64)
+	}
+	if dbCoupon.ExpiresAt.Valid {
+		expiresAt := dbCoupon.ExpiresAt.Time
+		coupon.ExpiresAt = &expiresAt
+	}
+	return coupon
+}
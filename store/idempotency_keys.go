@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"linkshrink/idempotency"
+	"linkshrink/store/sqlc"
+)
+
+// ClaimIdempotencyKey records a request in progress for a key unless the key
+// is in use. Returns whether the key was claimed.
+func (s *Store) ClaimIdempotencyKey(ctx context.Context, claim *idempotency.Claim,
+	expiresAt time.Time) (bool, error) {
+
+	rows, err := s.queries.ClaimIdempotencyKey(ctx, sqlc.ClaimIdempotencyKeyParams{
+		PaidRouteID:    int64(claim.RouteID),
+		Scope:          claim.Scope,
+		IdempotencyKey: claim.Key,
+		Fingerprint:    claim.Fingerprint,
+		ExpiresAt:      expiresAt,
+		CreatedAt:      s.clock.Now(),
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// GetIdempotencyKey returns the record of an unexpired key.
+func (s *Store) GetIdempotencyKey(ctx context.Context, routeID uint64,
+	scope string, key string) (*idempotency.Record, error) {
+
+	row, err := s.queries.GetIdempotencyKey(ctx, sqlc.GetIdempotencyKeyParams{
+		PaidRouteID:    int64(routeID),
+		Scope:          scope,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, idempotency.ErrKeyNotFound
+		}
+		return nil, err
+	}
+
+	if !s.clock.Now().Before(row.ExpiresAt) {
+		return nil, idempotency.ErrKeyNotFound
+	}
+
+	record := &idempotency.Record{
+		Claim: idempotency.Claim{
+			RouteID:     uint64(row.PaidRouteID),
+			Scope:       row.Scope,
+			Key:         row.IdempotencyKey,
+			Fingerprint: row.Fingerprint,
+		},
+		ExpiresAt: row.ExpiresAt,
+	}
+
+	if row.ResponseStatus != 0 {
+		var header http.Header
+		if len(row.ResponseHeader) > 0 {
+			err = json.Unmarshal(row.ResponseHeader, &header)
+			if err != nil {
+				return nil, fmt.Errorf("invalid stored response header: %w", err)
+			}
+		}
+
+		record.Response = &idempotency.Response{
+			Status: int(row.ResponseStatus),
+			Header: header,
+			Body:   row.ResponseBody,
+		}
+	}
+
+	return record, nil
+}
+
+// CompleteIdempotencyKey stores the response to a claimed key's request.
+func (s *Store) CompleteIdempotencyKey(ctx context.Context, claim *idempotency.Claim,
+	response *idempotency.Response) error {
+
+	header, err := json.Marshal(response.Header)
+	if err != nil {
+		return fmt.Errorf("failed to encode response header: %w", err)
+	}
+
+	return s.queries.CompleteIdempotencyKey(ctx, sqlc.CompleteIdempotencyKeyParams{
+		PaidRouteID:    int64(claim.RouteID),
+		Scope:          claim.Scope,
+		IdempotencyKey: claim.Key,
+		ResponseStatus: int32(response.Status),
+		ResponseHeader: header,
+		ResponseBody:   response.Body,
+		UpdatedAt:      s.clock.Now(),
+	})
+}
+
+// ReleaseIdempotencyKey removes a claimed key that has no response yet.
+func (s *Store) ReleaseIdempotencyKey(ctx context.Context, claim *idempotency.Claim) error {
+	return s.queries.ReleaseIdempotencyKey(ctx, sqlc.ReleaseIdempotencyKeyParams{
+		PaidRouteID:    int64(claim.RouteID),
+		Scope:          claim.Scope,
+		IdempotencyKey: claim.Key,
+	})
+}
+
+// DeleteExpiredIdempotencyKeys removes the keys that expired before the given
+// time.
+func (s *Store) DeleteExpiredIdempotencyKeys(ctx context.Context, before time.Time) (uint64, error) {
+	deleted, err := s.queries.DeleteExpiredIdempotencyKeys(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(deleted), nil
+}
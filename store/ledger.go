@@ -0,0 +1,172 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"linkshrink/ledger"
+	"linkshrink/store/sqlc"
+)
+
+// postLedgerTransactions posts ledger transactions and their entries in the
+// db transaction of q.
+func (s *Store) postLedgerTransactions(ctx context.Context, q *sqlc.Queries,
+	txs []ledger.Transaction) error {
+
+	now := s.clock.Now()
+	for _, tx := range txs {
+		txID, err := q.CreateLedgerTransaction(ctx, sqlc.CreateLedgerTransactionParams{
+			Reference:  tx.Reference,
+			Kind:       tx.Kind,
+			PurchaseID: nullableID(tx.PurchaseID),
+			IsTest:     tx.IsTest,
+			CreatedAt:  now,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Debug("Ledger transaction already posted", "reference", tx.Reference)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range tx.Entries {
+			accountID, err := q.CreateLedgerAccount(ctx, sqlc.CreateLedgerAccountParams{
+				Kind:      entry.Account.Kind,
+				Reference: entry.Account.Reference,
+				UserID:    nullableID(entry.Account.UserID),
+				CreatedAt: now,
+			})
+			if err != nil {
+				return err
+			}
+
+			err = q.CreateLedgerEntry(ctx, sqlc.CreateLedgerEntryParams{
+				TransactionID: txID,
+				AccountID:     accountID,
+				Amount:        entry.Amount,
+				CreatedAt:     now,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// purchaseEarnings returns what the route account kept of a purchase after
+// fees and splits, in the db transaction of q.
+func purchaseEarnings(ctx context.Context, q *sqlc.Queries, purchaseID uint64) (uint64, error) {
+	earnings, err := q.GetLedgerPurchaseEarnings(ctx, nullableID(purchaseID))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get purchase earnings: %w", err)
+	}
+	return uint64(max(earnings, 0)), nil
+}
+
+// ListLedgerBalancesByUserID retrieves the balances of a user's accounts.
+func (s *Store) ListLedgerBalancesByUserID(ctx context.Context, userID uint64) ([]ledger.AccountBalance, error) {
+	rows, err := s.queries.ListLedgerBalancesByUserID(ctx, nullableID(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make([]ledger.AccountBalance, len(rows))
+	for i, row := range rows {
+		balances[i] = ledger.AccountBalance{
+			Kind:       row.Kind,
+			Reference:  row.Reference,
+			IsTest:     row.IsTest,
+			Balance:    row.Balance,
+			EntryCount: uint64(row.EntryCount),
+		}
+	}
+
+	return balances, nil
+}
+
+// ListUnbalancedLedgerTransactions retrieves the transactions whose entries
+// don't add up to zero.
+func (s *Store) ListUnbalancedLedgerTransactions(ctx context.Context) ([]ledger.UnbalancedTransaction, error) {
+	rows, err := s.queries.ListUnbalancedLedgerTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	txs := make([]ledger.UnbalancedTransaction, len(rows))
+	for i, row := range rows {
+		txs[i] = ledger.UnbalancedTransaction{
+			ID:        uint64(row.ID),
+			Reference: row.Reference,
+			Imbalance: row.Imbalance,
+		}
+	}
+
+	return txs, nil
+}
+
+// ListPurchasesWithoutLedger retrieves the IDs of paid purchases that were
+// never posted to the ledger.
+func (s *Store) ListPurchasesWithoutLedger(ctx context.Context) ([]uint64, error) {
+	ids, err := s.queries.ListPurchasesWithoutLedger(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	purchaseIDs := make([]uint64, len(ids))
+	for i, id := range ids {
+		purchaseIDs[i] = uint64(id)
+	}
+
+	return purchaseIDs, nil
+}
+
+// ListOverdrawnRouteAccounts retrieves the route accounts with a negative
+// balance.
+func (s *Store) ListOverdrawnRouteAccounts(ctx context.Context) ([]ledger.AccountBalance, error) {
+	rows, err := s.queries.ListOverdrawnRouteAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make([]ledger.AccountBalance, len(rows))
+	for i, row := range rows {
+		balances[i] = ledger.AccountBalance{
+			Kind:      ledger.AccountRoute,
+			Reference: row.Reference,
+			Balance:   row.Balance,
+		}
+	}
+
+	return balances, nil
+}
+
+// ListWalletsOffLedger retrieves the wallets whose balance differs from the
+// balance of their ledger account.
+func (s *Store) ListWalletsOffLedger(ctx context.Context) ([]ledger.OffLedgerWallet, error) {
+	rows, err := s.queries.ListWalletsOffLedger(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	offLedger := make([]ledger.OffLedgerWallet, len(rows))
+	for i, row := range rows {
+		offLedger[i] = ledger.OffLedgerWallet{
+			WalletID:      uint64(row.ID),
+			Balance:       uint64(row.Balance),
+			LedgerBalance: row.LedgerBalance,
+		}
+	}
+
+	return offLedger, nil
+}
+
+// nullableID converts an ID to a nullable column value, 0 being NULL.
+func nullableID(id uint64) pgtype.Int8 {
+	return pgtype.Int8{Int64: int64(id), Valid: id != 0}
+}
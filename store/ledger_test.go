@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"linkshrink/ledger"
+	"linkshrink/purchases"
+	"linkshrink/routes"
+	"linkshrink/store/sqlc"
+)
+
+func newTestLedgerService(store *Store) *ledger.Service {
+	cfg := ledger.DefaultConfig()
+	return ledger.NewService(slog.New(slog.NewTextHandler(io.Discard, nil)), store, &cfg)
+}
+
+// balanceOf returns the balance of an account of kind in balances.
+func balanceOf(balances []ledger.AccountBalance, kind string) int64 {
+	for _, balance := range balances {
+		if balance.Kind == kind {
+			return balance.Balance
+		}
+	}
+	return 0
+}
+
+func TestCreditUsePostedWithPurchase(t *testing.T) {
+	store := newTestStore(t)
+	service := newTestPurchaseService(store)
+	ledgerService := newTestLedgerService(store)
+	ctx := context.Background()
+
+	const credits = 3
+	route := createTestRoute(t, store, routes.PaymentProtocolVersionV2)
+	purchase := pendingPurchase(route, paymentPayload(2, randomHex(t, 20), randomHex(t, 32)))
+	purchase.CreditsAvailable = credits
+	purchaseID, err := service.CreatePendingPurchase(ctx, purchase)
+	if err != nil {
+		t.Fatalf("failed to create purchase: %v", err)
+	}
+
+	txs, err := ledgerService.SaleTransactions(ledger.Sale{
+		PurchaseID: purchaseID,
+		RouteID:    route.ID,
+		OwnerID:    route.UserID,
+		IsTest:     true,
+		Price:      route.Price,
+		Credits:    credits,
+	})
+	if err != nil {
+		t.Fatalf("SaleTransactions() error = %v", err)
+	}
+	err = service.SettlePurchase(ctx, purchaseID, []byte(`{}`),
+		&purchases.Accounting{LedgerTransactions: txs})
+	if err != nil {
+		t.Fatalf("SettlePurchase() error = %v", err)
+	}
+
+	use := ledger.CreditUse{PurchaseID: purchaseID, RouteID: route.ID, OwnerID: route.UserID,
+		IsTest: true, Credits: credits}
+	for want := uint64(2); want <= credits; want++ {
+		used, err := service.IncrementCreditsUsed(ctx, use)
+		if err != nil || used != want {
+			t.Fatalf("IncrementCreditsUsed() = %d, %v, want %d", used, err, want)
+		}
+	}
+	_, err = service.IncrementCreditsUsed(ctx, use)
+	if !errors.Is(err, purchases.ErrNoCreditsLeft) {
+		t.Errorf("IncrementCreditsUsed() with no credits left error = %v, want %v", err, purchases.ErrNoCreditsLeft)
+	}
+
+	// Every used credit was posted, so the owner earned the full price.
+	balances, err := ledgerService.ListBalances(ctx, route.UserID)
+	if err != nil {
+		t.Fatalf("ListBalances() error = %v", err)
+	}
+	if got := balanceOf(balances, ledger.AccountUser); got != int64(route.Price) {
+		t.Errorf("owner balance = %d, want %d", got, route.Price)
+	}
+	if got := balanceOf(balances, ledger.AccountRoute); got != 0 {
+		t.Errorf("route balance = %d, want 0", got)
+	}
+}
+
+func TestCheckConsistency(t *testing.T) {
+	store := newTestStore(t)
+	service := newTestPurchaseService(store)
+	ledgerService := newTestLedgerService(store)
+	ctx := context.Background()
+
+	// A purchase settled without its sale being posted.
+	route := createTestRoute(t, store, routes.PaymentProtocolVersionV2)
+	unposted, err := service.CreatePendingPurchase(ctx,
+		pendingPurchase(route, paymentPayload(2, randomHex(t, 20), randomHex(t, 32))))
+	if err != nil {
+		t.Fatalf("failed to create purchase: %v", err)
+	}
+	if err := service.SettlePurchase(ctx, unposted, []byte(`{}`), nil); err != nil {
+		t.Fatalf("SettlePurchase() error = %v", err)
+	}
+
+	// A transaction without entries, and a route account paying out what
+	// it never received.
+	empty := "credit_use:" + randomHex(t, 8)
+	err = store.ExecTx(ctx, func(q *sqlc.Queries) error {
+		return store.postLedgerTransactions(ctx, q, []ledger.Transaction{
+			{Reference: empty, Kind: ledger.KindCreditUse, IsTest: true},
+			{
+				Reference: "credit_use:" + randomHex(t, 8),
+				Kind:      ledger.KindCreditUse,
+				IsTest:    true,
+				Entries: []ledger.Entry{
+					{Account: ledger.RouteAccount(route.ID, route.UserID), Amount: -1},
+					{Account: ledger.UserAccount(route.UserID), Amount: 1},
+				},
+			},
+		})
+	})
+	if err != nil {
+		t.Fatalf("failed to post ledger transactions: %v", err)
+	}
+
+	report, err := ledgerService.CheckConsistency(ctx)
+	if err != nil {
+		t.Fatalf("CheckConsistency() error = %v", err)
+	}
+	if report.Consistent() {
+		t.Fatalf("CheckConsistency() found no problems")
+	}
+	if !slices.Contains(report.PurchasesWithoutLedger, unposted) {
+		t.Errorf("purchase %d missing from %v", unposted, report.PurchasesWithoutLedger)
+	}
+	if !slices.ContainsFunc(report.UnbalancedTransactions, func(tx ledger.UnbalancedTransaction) bool {
+		return tx.Reference == empty
+	}) {
+		t.Errorf("transaction %s missing from %v", empty, report.UnbalancedTransactions)
+	}
+	if !slices.ContainsFunc(report.OverdrawnRoutes, func(balance ledger.AccountBalance) bool {
+		return balance.Reference == ledger.RouteAccount(route.ID, route.UserID).Reference
+	}) {
+		t.Errorf("route %d missing from %v", route.ID, report.OverdrawnRoutes)
+	}
+}
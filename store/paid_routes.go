@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -23,10 +24,25 @@ const (
 
 // CreateRoute inserts a new paid route in the database and returns the ID.
 func (s *Store) CreateRoute(ctx context.Context, route *routes.PaidRoute) (*routes.PaidRoute, error) {
+	params, err := s.createPaidRouteParams(route)
+	if err != nil {
+		return nil, err
+	}
+
+	dbRoute, err := s.queries.CreatePaidRoute(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create paid route: %w", err)
+	}
 
+	return convertToPaidRouteModel(dbRoute), nil
+}
+
+// createPaidRouteParams prepares the insert of a new paid route with a
+// generated short code.
+func (s *Store) createPaidRouteParams(route *routes.PaidRoute) (sqlc.CreatePaidRouteParams, error) {
 	shortCode, err := utils.GenerateSecureShortCode(10)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate unique short code: %w", err)
+		return sqlc.CreatePaidRouteParams{}, fmt.Errorf("failed to generate unique short code: %w", err)
 	}
 
 	now := s.clock.Now()
@@ -59,6 +75,20 @@ func (s *Store) CreateRoute(ctx context.Context, route *routes.PaidRoute) (*rout
 		description.Valid = true
 	}
 
+	var availableFrom, availableUntil pgtype.Timestamptz
+	if route.AvailableFrom != nil {
+		availableFrom = pgtype.Timestamptz{Time: *route.AvailableFrom, Valid: true}
+	}
+	if route.AvailableUntil != nil {
+		availableUntil = pgtype.Timestamptz{Time: *route.AvailableUntil, Valid: true}
+	}
+
+	// A nil slice would be stored as NULL.
+	paymentRails := route.PaymentRails
+	if paymentRails == nil {
+		paymentRails = []string{}
+	}
+
 	params := sqlc.CreatePaidRouteParams{
 		ShortCode: shortCode,
 		TargetUrl: route.TargetURL,
@@ -70,12 +100,40 @@ func (s *Store) CreateRoute(ctx context.Context, route *routes.PaidRoute) (*rout
 		UserID:                 int64(route.UserID),
 		Title:                  title,
 		Description:            description,
-		Price:                  int32(route.Price),
+		Price:                  int64(route.Price),
 		Type:                   route.Type,
 		Credits:                int32(route.Credits),
 		PaymentProtocolVersion: int16(route.PaymentProtocolVersion),
 		CoverUrl:               coverUrl,
 
+		CacheEnabled:     route.CacheEnabled,
+		CacheTtlSeconds:  int32(route.CacheTTLSeconds),
+		CacheVaryHeaders: route.CacheVaryHeaders,
+
+		StreamMaxSeconds:    int32(route.StreamMaxSeconds),
+		StreamCreditSeconds: int32(route.StreamCreditSeconds),
+
+		BillingMode: route.BillingMode,
+		UnitPrice:   int64(route.UnitPrice),
+
+		QuoteUrl:       route.QuoteURL,
+		PayWhatYouWant: route.PayWhatYouWant,
+		MinPrice:       int64(route.MinPrice),
+		MaxPrice:       int64(route.MaxPrice),
+
+		AvailableFrom:  availableFrom,
+		AvailableUntil: availableUntil,
+		MaxSales:       int64(route.MaxSales),
+
+		PaymentRails: paymentRails,
+
+		Discoverable: route.Discoverable,
+		MimeType:     route.MimeType,
+
+		BodySchema:     route.BodySchema,
+		QuerySchema:    route.QuerySchema,
+		ResponseSchema: route.ResponseSchema,
+
 		IsTest:    route.IsTest,
 		IsEnabled: route.IsEnabled,
 
@@ -87,12 +145,7 @@ func (s *Store) CreateRoute(ctx context.Context, route *routes.PaidRoute) (*rout
 		UpdatedAt: now,
 	}
 
-	dbRoute, err := s.queries.CreatePaidRoute(ctx, params)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create paid route: %w", err)
-	}
-
-	return convertToPaidRouteModel(dbRoute), nil
+	return params, nil
 }
 
 // FindRouteByID retrieves a paid route by ID.
@@ -149,6 +202,51 @@ func (s *Store) ListUserRoutes(ctx context.Context, userID uint64) ([]routes.Pai
 	return routes, nil
 }
 
+// ListDiscoverableRoutes retrieves a page of the enabled routes listed for
+// discovery that match the filter and how many routes match it in total.
+func (s *Store) ListDiscoverableRoutes(ctx context.Context,
+	filter *routes.DiscoveryFilter) ([]routes.PaidRoute, uint64, error) {
+
+	var isTest pgtype.Bool
+	if filter.IsTest != nil {
+		isTest = pgtype.Bool{Bool: *filter.IsTest, Valid: true}
+	}
+
+	countParams := sqlc.CountDiscoverablePaidRoutesParams{
+		Method:       filter.Method,
+		IsTest:       isTest,
+		MaxPrice:     int64(filter.MaxPrice),
+		PayTo:        filter.PayTo,
+		DefaultPayTo: filter.DefaultPayTo,
+		Search:       filter.Search,
+	}
+	total, err := s.queries.CountDiscoverablePaidRoutes(ctx, countParams)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count discoverable routes: %w", err)
+	}
+
+	dbRoutes, err := s.queries.ListDiscoverablePaidRoutes(ctx, sqlc.ListDiscoverablePaidRoutesParams{
+		Method:       countParams.Method,
+		IsTest:       countParams.IsTest,
+		MaxPrice:     countParams.MaxPrice,
+		PayTo:        countParams.PayTo,
+		DefaultPayTo: countParams.DefaultPayTo,
+		Search:       countParams.Search,
+		RowLimit:     int32(filter.Limit),
+		RowOffset:    int32(filter.Offset),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list discoverable routes: %w", err)
+	}
+
+	routes := make([]routes.PaidRoute, len(dbRoutes))
+	for i, dbRoute := range dbRoutes {
+		routes[i] = *convertToPaidRouteModel(dbRoute)
+	}
+
+	return routes, uint64(total), nil
+}
+
 // DeleteRoute soft-deletes a paid route.
 func (s *Store) DeleteRoute(ctx context.Context, routeID uint64, userID uint64) error {
 	now := s.clock.Now()
@@ -166,6 +264,26 @@ func (s *Store) DeleteRoute(ctx context.Context, routeID uint64, userID uint64)
 	return nil
 }
 
+// UpdateRouteLoadBalancing updates the load balancing settings of a route.
+func (s *Store) UpdateRouteLoadBalancing(ctx context.Context, routeID uint64, userID uint64,
+	strategy string, healthCheckPath string) error {
+
+	rows, err := s.queries.UpdatePaidRouteLoadBalancing(ctx, sqlc.UpdatePaidRouteLoadBalancingParams{
+		ID:              int64(routeID),
+		UserID:          int64(userID),
+		LbStrategy:      strategy,
+		HealthCheckPath: healthCheckPath,
+		UpdatedAt:       s.clock.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update load balancing: %w", err)
+	}
+	if rows == 0 {
+		return routes.ErrRouteNoPermission
+	}
+	return nil
+}
+
 // IncrementRouteAttemptCount increments the attempt_count for a route.
 func (s *Store) IncrementRouteAttemptCount(ctx context.Context, shortCode string) error {
 	now := s.clock.Now()
@@ -205,6 +323,16 @@ func (s *Store) IncrementRouteAccessCount(ctx context.Context, shortCode string)
 	return nil
 }
 
+// DisableEndedRoutes disables the routes whose availability window closed
+// at now.
+func (s *Store) DisableEndedRoutes(ctx context.Context, now time.Time) (int64, error) {
+	disabled, err := s.queries.DisableEndedPaidRoutes(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to disable ended routes: %w", err)
+	}
+	return disabled, nil
+}
+
 // CheckShortCodeExists checks if a short code already exists.
 func (s *Store) CheckShortCodeExists(ctx context.Context, shortCode string) (bool, error) {
 	exists, err := s.queries.CheckShortCodeExists(ctx, shortCode)
@@ -264,6 +392,36 @@ func convertToPaidRouteModel(dbRoute sqlc.PaidRoute) *routes.PaidRoute {
 		IsTest:    dbRoute.IsTest,
 		IsEnabled: dbRoute.IsEnabled,
 
+		CacheEnabled:     dbRoute.CacheEnabled,
+		CacheTTLSeconds:  uint64(dbRoute.CacheTtlSeconds),
+		CacheVaryHeaders: dbRoute.CacheVaryHeaders,
+
+		LBStrategy:      dbRoute.LbStrategy,
+		HealthCheckPath: dbRoute.HealthCheckPath,
+
+		StreamMaxSeconds:    uint64(dbRoute.StreamMaxSeconds),
+		StreamCreditSeconds: uint64(dbRoute.StreamCreditSeconds),
+
+		BillingMode: dbRoute.BillingMode,
+		UnitPrice:   uint64(dbRoute.UnitPrice),
+
+		QuoteURL:       dbRoute.QuoteUrl,
+		PayWhatYouWant: dbRoute.PayWhatYouWant,
+		MinPrice:       uint64(dbRoute.MinPrice),
+		MaxPrice:       uint64(dbRoute.MaxPrice),
+
+		MaxSales:  uint64(dbRoute.MaxSales),
+		SoldCount: uint64(dbRoute.SoldCount),
+
+		PaymentRails: dbRoute.PaymentRails,
+
+		Discoverable: dbRoute.Discoverable,
+		MimeType:     dbRoute.MimeType,
+
+		BodySchema:     dbRoute.BodySchema,
+		QuerySchema:    dbRoute.QuerySchema,
+		ResponseSchema: dbRoute.ResponseSchema,
+
 		AttemptCount: uint64(dbRoute.AttemptCount),
 		PaymentCount: uint64(dbRoute.PaymentCount),
 		AccessCount:  uint64(dbRoute.AccessCount),
@@ -277,6 +435,16 @@ func convertToPaidRouteModel(dbRoute sqlc.PaidRoute) *routes.PaidRoute {
 		route.DeletedAt = &deletedAt
 	}
 
+	if dbRoute.AvailableFrom.Valid {
+		availableFrom := dbRoute.AvailableFrom.Time
+		route.AvailableFrom = &availableFrom
+	}
+
+	if dbRoute.AvailableUntil.Valid {
+		availableUntil := dbRoute.AvailableUntil.Time
+		route.AvailableUntil = &availableUntil
+	}
+
 	if dbRoute.OriginalFilename.Valid {
 		filename := dbRoute.OriginalFilename.String
 		route.OriginalFilename = &filename
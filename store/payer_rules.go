@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"linkshrink/payers"
+	"linkshrink/store/sqlc"
+)
+
+// CreatePayerRule creates a payer rule, ErrRuleExists if the owner already
+// has a rule for the payer on the same route.
+func (s *Store) CreatePayerRule(ctx context.Context, rule *payers.Rule) (*payers.Rule, error) {
+	dbRule, err := s.queries.CreatePayerRule(ctx, sqlc.CreatePayerRuleParams{
+		UserID:       int64(rule.UserID),
+		PaidRouteID:  nullableID(rule.RouteID),
+		PayerAddress: rule.PayerAddress,
+		Treatment:    rule.Treatment,
+		DiscountBps:  int32(rule.DiscountBPS),
+		Label:        rule.Label,
+		CreatedAt:    s.clock.Now(),
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, payers.ErrRuleExists
+		}
+		return nil, fmt.Errorf("failed to create payer rule: %w", err)
+	}
+
+	created := convertToPayerRuleModel(dbRule)
+	return &created, nil
+}
+
+// ListPayerRulesByUserID retrieves the payer rules of an owner.
+func (s *Store) ListPayerRulesByUserID(ctx context.Context, userID uint64) ([]payers.Rule, error) {
+	dbRules, err := s.queries.ListPayerRulesByUserID(ctx, int64(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]payers.Rule, len(dbRules))
+	for i, dbRule := range dbRules {
+		rules[i] = convertToPayerRuleModel(dbRule)
+	}
+
+	return rules, nil
+}
+
+// DeletePayerRule deletes a payer rule of an owner.
+func (s *Store) DeletePayerRule(ctx context.Context, ruleID, userID uint64) error {
+	rows, err := s.queries.DeletePayerRule(ctx, sqlc.DeletePayerRuleParams{
+		ID:     int64(ruleID),
+		UserID: int64(userID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete payer rule: %w", err)
+	}
+	if rows == 0 {
+		return payers.ErrRuleNotFound
+	}
+	return nil
+}
+
+// GetPayerRuleForRoute retrieves the rule of an owner for a payer on a route,
+// preferring a rule for the route over a rule for all routes.
+func (s *Store) GetPayerRuleForRoute(ctx context.Context, userID, routeID uint64,
+	payerAddress string) (*payers.Rule, error) {
+
+	dbRule, err := s.queries.GetPayerRuleForRoute(ctx, sqlc.GetPayerRuleForRouteParams{
+		UserID:       int64(userID),
+		PayerAddress: payerAddress,
+		PaidRouteID:  nullableID(routeID),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, payers.ErrRuleNotFound
+		}
+		return nil, err
+	}
+
+	rule := convertToPayerRuleModel(dbRule)
+	return &rule, nil
+}
+
+// convertToPayerRuleModel converts a database payer rule to a payers.Rule.
+func convertToPayerRuleModel(dbRule sqlc.PayerRule) payers.Rule {
+	rule := payers.Rule{
+		ID:           uint64(dbRule.ID),
+		UserID:       uint64(dbRule.UserID),
+		PayerAddress: dbRule.PayerAddress,
+		Treatment:    dbRule.Treatment,
+		DiscountBPS:  uint64(dbRule.DiscountBps),
+		Label:        dbRule.Label,
+		CreatedAt:    dbRule.CreatedAt,
+	}
+	if dbRule.PaidRouteID.Valid {
+		rule.RouteID = uint64(dbRule.PaidRouteID.Int64)
+	}
+	return rule
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 
+	"linkshrink/ledger"
+	"linkshrink/money"
 	pkgPurchases "linkshrink/purchases"
 	"linkshrink/store/sqlc"
 )
@@ -21,7 +23,7 @@ func (s *Store) CreatePurchase(ctx context.Context, purchase *pkgPurchases.Purch
 		ShortCode:        purchase.ShortCode,
 		TargetUrl:        purchase.TargetURL,
 		Method:           purchase.Method,
-		Price:            int32(purchase.Price),
+		Price:            int64(purchase.Price),
 		Type:             purchase.Type,
 		CreditsAvailable: int32(purchase.CreditsAvailable),
 		CreditsUsed:      int32(purchase.CreditsUsed),
@@ -34,13 +36,60 @@ func (s *Store) CreatePurchase(ctx context.Context, purchase *pkgPurchases.Purch
 		PaymentPayload: []byte(purchase.PaymentPayload),
 		SettleResponse: []byte(purchase.SettleResponse),
 
+		Status:              purchase.Status,
+		PaymentRequirements: purchase.PaymentRequirements,
+		PackID:              nullableID(purchase.PackID),
+		AffiliateID:         nullableID(purchase.AffiliateID),
+
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 
-	ID, err := s.queries.CreatePurchase(ctx, params)
+	if params.Status == "" {
+		params.Status = pkgPurchases.StatusSettled
+	}
+
+	var ID int64
+	err := s.ExecTx(ctx, func(q *sqlc.Queries) error {
+		// Concurrent purchases of a route with a sales limit wait on each
+		// other here, so the limit is never exceeded.
+		reserved, err := q.ReserveRouteSale(ctx, int64(purchase.PaidRouteID))
+		if err != nil {
+			return fmt.Errorf("failed to reserve route sale: %w", err)
+		}
+		if reserved == 0 {
+			return pkgPurchases.ErrSoldOut
+		}
+
+		ID, err = q.CreatePurchase(ctx, params)
+		if err != nil {
+			return fmt.Errorf("failed to create purchase: %w", err)
+		}
+
+		if purchase.PaymentNonce == nil {
+			return nil
+		}
+
+		// Concurrent purchases with the same nonce wait on each other here,
+		// only the first one to commit registers it.
+		registered, err := q.RegisterPaymentNonce(ctx, sqlc.RegisterPaymentNonceParams{
+			Network:    purchase.PaymentNonce.Network,
+			Payer:      purchase.PaymentNonce.Payer,
+			Nonce:      purchase.PaymentNonce.Nonce,
+			PurchaseID: nullableID(uint64(ID)),
+			CreatedAt:  now,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to register payment nonce: %w", err)
+		}
+		if registered == 0 {
+			return pkgPurchases.ErrPaymentReused
+		}
+
+		return nil
+	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to create purchase: %w", err)
+		return 0, err
 	}
 
 	return uint64(ID), nil
@@ -82,6 +131,9 @@ func (s *Store) ListPurchasesByShortCode(ctx context.Context, shortCode string)
 			&p.IsTest, &p.PaymentPayload, &p.SettleResponse, &p.PaidRouteID,
 			&p.PaidToAddress, &p.CreatedAt, &p.UpdatedAt,
 			&p.Type, &p.CreditsAvailable, &p.CreditsUsed, &p.PaymentHeader,
+			&p.UsageUnits, &p.AmountCharged,
+			&p.Status, &p.PaymentRequirements, &p.SettleError, &p.PackID,
+			&p.AffiliateID, &p.AffiliateCommission,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan purchase row: %w", err)
 		}
@@ -110,7 +162,7 @@ func (s *Store) GetDailyStatsByUserID(ctx context.Context,
 	}
 
 	dbStats, err := s.queries.GetDailyStats(ctx, sqlc.GetDailyStatsParams{
-		UserID:  int64(userID),
+		UserID:  nullableID(userID),
 		Column2: daysText,
 	})
 	if err != nil {
@@ -128,25 +180,14 @@ func (s *Store) GetDailyStatsByUserID(ctx context.Context,
 
 	stats := make([]pkgPurchases.DailyStats, len(dbStats))
 	for i, dbStat := range dbStats {
-		// Converting TestEarnings and RealEarnings from interface{} to int64
-		var testEarnings, realEarnings int64
-
-		if te, ok := dbStat.TestEarnings.(int64); ok {
-			testEarnings = te
-		}
-
-		if re, ok := dbStat.RealEarnings.(int64); ok {
-			realEarnings = re
-		}
-
 		stats[i] = pkgPurchases.DailyStats{
 			Date:         dbStat.Date,
 			Count:        uint64(dbStat.Count),
-			Earnings:     uint64(dbStat.Earnings), // Convert int32 to int64
+			Earnings:     uint64(dbStat.Earnings),
 			TestCount:    uint64(dbStat.TestCount),
-			TestEarnings: uint64(testEarnings),
+			TestEarnings: uint64(dbStat.TestEarnings),
 			RealCount:    uint64(dbStat.RealCount),
-			RealEarnings: uint64(realEarnings),
+			RealEarnings: uint64(dbStat.RealEarnings),
 		}
 	}
 
@@ -224,6 +265,14 @@ func convertToPurchaseModel(dbPurchase sqlc.Purchase) *pkgPurchases.Purchase {
 		CreditsUsed:      uint64(dbPurchase.CreditsUsed),
 		IsTest:           dbPurchase.IsTest,
 
+		Status:      dbPurchase.Status,
+		SettleError: dbPurchase.SettleError,
+
+		UsageUnits:    uint64(dbPurchase.UsageUnits),
+		AmountCharged: uint64(dbPurchase.AmountCharged),
+
+		AffiliateCommission: uint64(dbPurchase.AffiliateCommission),
+
 		PaidRouteID:   uint64(dbPurchase.PaidRouteID),
 		PaidToAddress: dbPurchase.PaidToAddress,
 
@@ -231,9 +280,17 @@ func convertToPurchaseModel(dbPurchase sqlc.Purchase) *pkgPurchases.Purchase {
 		PaymentPayload: []byte(dbPurchase.PaymentPayload),
 		SettleResponse: []byte(dbPurchase.SettleResponse),
 
+		PaymentRequirements: dbPurchase.PaymentRequirements,
+
 		CreatedAt: dbPurchase.CreatedAt,
 		UpdatedAt: dbPurchase.UpdatedAt,
 	}
+	if dbPurchase.PackID.Valid {
+		purchase.PackID = uint64(dbPurchase.PackID.Int64)
+	}
+	if dbPurchase.AffiliateID.Valid {
+		purchase.AffiliateID = uint64(dbPurchase.AffiliateID.Int64)
+	}
 
 	return purchase
 }
@@ -253,15 +310,244 @@ func (s *Store) GetPurchaseByRouteIDAndPaymentHeader(ctx context.Context, routeI
 	return convertToPurchaseModel(dbPurchase), nil
 }
 
-// IncrementPurchaseCreditsUsed increments the credits_used count for a specific purchase ID.
-func (s *Store) IncrementPurchaseCreditsUsed(ctx context.Context, purchaseID uint64) error {
-	params := sqlc.IncrementPurchaseCreditsUsedParams{
-		ID:        int64(purchaseID),
-		UpdatedAt: s.clock.Now(),
+// ChargePurchaseUsage records usage units on the purchase of usage and
+// charges amount for them, capped at the price paid for the purchase, and
+// posts the charge to the ledger in the same transaction. It returns the
+// updated purchase and the amount that was actually charged.
+func (s *Store) ChargePurchaseUsage(ctx context.Context, usage ledger.Usage,
+	usageUnits uint64, amount uint64) (*pkgPurchases.Purchase, uint64, error) {
+
+	// The query charges no more than is left of the price and saturates the
+	// usage units, so anything above the column range can be clamped.
+	amount = min(amount, money.MaxAmount)
+	usageUnits = min(usageUnits, money.MaxAmount)
+
+	var row sqlc.ChargePurchaseUsageRow
+	err := s.ExecTx(ctx, func(q *sqlc.Queries) error {
+		var err error
+		row, err = q.ChargePurchaseUsage(ctx, sqlc.ChargePurchaseUsageParams{
+			ID:            int64(usage.PurchaseID),
+			UsageUnits:    int64(usageUnits),
+			AmountCharged: int64(amount),
+			UpdatedAt:     s.clock.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		usage.ChargedBefore = uint64(row.PreviousAmountCharged)
+		usage.ChargedAfter = uint64(row.AmountCharged)
+		usage.Price = uint64(row.Price)
+		earnings, err := purchaseEarnings(ctx, q, usage.PurchaseID)
+		if err != nil {
+			return err
+		}
+		txs, err := ledger.UsageTransactions(usage, earnings)
+		if err != nil {
+			return err
+		}
+		return s.postLedgerTransactions(ctx, q, txs)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, 0, pkgPurchases.ErrPurchaseNotFound
+		}
+		return nil, 0, fmt.Errorf("failed to charge purchase usage: %w", err)
+	}
+
+	purchase := convertToPurchaseModel(sqlc.Purchase{
+		ID:                  row.ID,
+		ShortCode:           row.ShortCode,
+		TargetUrl:           row.TargetUrl,
+		Method:              row.Method,
+		Price:               row.Price,
+		IsTest:              row.IsTest,
+		PaymentPayload:      row.PaymentPayload,
+		SettleResponse:      row.SettleResponse,
+		PaidRouteID:         row.PaidRouteID,
+		PaidToAddress:       row.PaidToAddress,
+		CreatedAt:           row.CreatedAt,
+		UpdatedAt:           row.UpdatedAt,
+		Type:                row.Type,
+		CreditsAvailable:    row.CreditsAvailable,
+		CreditsUsed:         row.CreditsUsed,
+		PaymentHeader:       row.PaymentHeader,
+		UsageUnits:          row.UsageUnits,
+		AmountCharged:       row.AmountCharged,
+		Status:              row.Status,
+		PaymentRequirements: row.PaymentRequirements,
+		SettleError:         row.SettleError,
+		PackID:              row.PackID,
+		AffiliateID:         row.AffiliateID,
+		AffiliateCommission: row.AffiliateCommission,
+	})
+
+	return purchase, uint64(row.AmountCharged - row.PreviousAmountCharged), nil
+}
+
+// IncrementPurchaseCreditsUsed increments the credits_used count for the
+// purchase of use, posts the used credit to the ledger in the same
+// transaction and returns the new count.
+func (s *Store) IncrementPurchaseCreditsUsed(ctx context.Context, use ledger.CreditUse) (uint64, error) {
+	err := s.ExecTx(ctx, func(q *sqlc.Queries) error {
+		creditsUsed, err := q.IncrementPurchaseCreditsUsed(ctx, sqlc.IncrementPurchaseCreditsUsedParams{
+			ID:        int64(use.PurchaseID),
+			UpdatedAt: s.clock.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		use.CreditsUsed = uint64(creditsUsed)
+		earnings, err := purchaseEarnings(ctx, q, use.PurchaseID)
+		if err != nil {
+			return err
+		}
+		txs, err := ledger.CreditUseTransactions(use, earnings)
+		if err != nil {
+			return err
+		}
+		return s.postLedgerTransactions(ctx, q, txs)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, pkgPurchases.ErrNoCreditsLeft
+		}
+		return 0, fmt.Errorf("failed to increment credits used: %w", err)
 	}
-	err := s.queries.IncrementPurchaseCreditsUsed(ctx, params)
+	return use.CreditsUsed, nil
+}
+
+// SettlePurchase atomically marks a pending purchase as settled with the
+// settle response, records its payouts and posts its ledger transactions.
+func (s *Store) SettlePurchase(ctx context.Context, purchaseID uint64,
+	settleResponse []byte, accounting *pkgPurchases.Accounting) error {
+
+	return s.ExecTx(ctx, func(q *sqlc.Queries) error {
+		rows, err := q.SettlePurchase(ctx, sqlc.SettlePurchaseParams{
+			ID:             int64(purchaseID),
+			SettleResponse: settleResponse,
+			UpdatedAt:      s.clock.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to settle purchase: %w", err)
+		}
+		if rows == 0 {
+			return pkgPurchases.ErrNotPending
+		}
+		if accounting == nil {
+			return nil
+		}
+
+		err = s.createPurchasePayouts(ctx, q, accounting.Payouts)
+		if err != nil {
+			return fmt.Errorf("failed to create purchase payouts: %w", err)
+		}
+
+		err = s.postLedgerTransactions(ctx, q, accounting.LedgerTransactions)
+		if err != nil {
+			return fmt.Errorf("failed to post ledger transactions: %w", err)
+		}
+		return nil
+	})
+}
+
+// FailPurchase marks a pending purchase as failed to settle and releases its
+// payment nonce, coupon redemption and the sale of its route.
+func (s *Store) FailPurchase(ctx context.Context, purchaseID uint64, reason string) error {
+	return s.ExecTx(ctx, func(q *sqlc.Queries) error {
+		rows, err := q.FailPurchase(ctx, sqlc.FailPurchaseParams{
+			ID:          int64(purchaseID),
+			SettleError: reason,
+			UpdatedAt:   s.clock.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to mark purchase as failed: %w", err)
+		}
+		if rows == 0 {
+			return pkgPurchases.ErrNotPending
+		}
+
+		err = q.ReleasePaymentNonce(ctx, nullableID(purchaseID))
+		if err != nil {
+			return fmt.Errorf("failed to release payment nonce: %w", err)
+		}
+
+		err = s.releaseCouponRedemption(ctx, q, purchaseID)
+		if err != nil {
+			return fmt.Errorf("failed to release coupon redemption: %w", err)
+		}
+
+		err = q.ReleasePurchaseRouteSale(ctx, int64(purchaseID))
+		if err != nil {
+			return fmt.Errorf("failed to release route sale: %w", err)
+		}
+		return nil
+	})
+}
+
+// FlagPendingPurchase records why a pending purchase needs to be resolved
+// manually.
+func (s *Store) FlagPendingPurchase(ctx context.Context, purchaseID uint64, reason string) error {
+	rows, err := s.queries.FlagPendingPurchase(ctx, sqlc.FlagPendingPurchaseParams{
+		ID:          int64(purchaseID),
+		SettleError: reason,
+		UpdatedAt:   s.clock.Now(),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to execute increment credits_used query: %w", err)
+		return fmt.Errorf("failed to flag pending purchase: %w", err)
+	}
+	if rows == 0 {
+		return pkgPurchases.ErrNotPending
 	}
 	return nil
 }
+
+// GetPurchaseByID retrieves a purchase by its ID.
+func (s *Store) GetPurchaseByID(ctx context.Context, purchaseID uint64) (*pkgPurchases.Purchase, error) {
+	dbPurchase, err := s.queries.GetPurchaseByID(ctx, int64(purchaseID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, pkgPurchases.ErrPurchaseNotFound
+		}
+		return nil, fmt.Errorf("failed to get purchase: %w", err)
+	}
+	return convertToPurchaseModel(dbPurchase), nil
+}
+
+// ListPendingPurchasesByUserID retrieves the pending purchases of all routes
+// of a user.
+func (s *Store) ListPendingPurchasesByUserID(ctx context.Context,
+	userID uint64) ([]pkgPurchases.Purchase, error) {
+
+	dbPurchases, err := s.queries.ListPendingPurchasesByUserID(ctx, int64(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending purchases: %w", err)
+	}
+
+	purchases := make([]pkgPurchases.Purchase, len(dbPurchases))
+	for i, dbPurchase := range dbPurchases {
+		purchases[i] = *convertToPurchaseModel(dbPurchase)
+	}
+	return purchases, nil
+}
+
+// ListStalePendingPurchases retrieves up to limit pending purchases created
+// before the given time that are not flagged for review.
+func (s *Store) ListStalePendingPurchases(ctx context.Context, before time.Time,
+	limit uint64) ([]pkgPurchases.Purchase, error) {
+
+	dbPurchases, err := s.queries.ListStalePendingPurchases(ctx, sqlc.ListStalePendingPurchasesParams{
+		CreatedAt: before,
+		Limit:     int32(min(limit, MaxLimit)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stale pending purchases: %w", err)
+	}
+
+	purchases := make([]pkgPurchases.Purchase, len(dbPurchases))
+	for i, dbPurchase := range dbPurchases {
+		purchases[i] = *convertToPurchaseModel(dbPurchase)
+	}
+	return purchases, nil
+}
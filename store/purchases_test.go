@@ -0,0 +1,239 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+
+	"linkshrink/ledger"
+	"linkshrink/purchases"
+	"linkshrink/routes"
+)
+
+// paymentPayload returns an x402 exact EVM payment payload of the given
+// protocol version for the authorization of payer with nonce.
+func paymentPayload(version int, payer, nonce string) []byte {
+	if version == 1 {
+		return []byte(fmt.Sprintf(`{"x402Version":1,"scheme":"exact","network":"base-sepolia",`+
+			`"payload":{"signature":"0x01","authorization":{"from":%q,"nonce":%q}}}`, payer, nonce))
+	}
+	return []byte(fmt.Sprintf(`{"x402Version":2,"accepted":{"scheme":"exact","network":"eip155:84532"},`+
+		`"payload":{"signature":"0x01","authorization":{"from":%q,"nonce":%q}}}`, payer, nonce))
+}
+
+// pendingPurchase returns a purchase of route paid with payload.
+func pendingPurchase(route *routes.PaidRoute, payload []byte) *purchases.Purchase {
+	return &purchases.Purchase{
+		ShortCode:        route.ShortCode,
+		TargetURL:        route.TargetURL,
+		Method:           route.Method,
+		Price:            route.Price,
+		Type:             route.Type,
+		CreditsAvailable: route.Credits,
+		CreditsUsed:      1,
+		IsTest:           route.IsTest,
+		PaidRouteID:      route.ID,
+		PaymentHeader:    string(payload),
+		PaymentPayload:   payload,
+	}
+}
+
+func newTestPurchaseService(store *Store) *purchases.PurchaseService {
+	return purchases.NewPurchaseService(slog.New(slog.NewTextHandler(io.Discard, nil)), store)
+}
+
+func TestPaymentNonceReusedAcrossRoutes(t *testing.T) {
+	store := newTestStore(t)
+	service := newTestPurchaseService(store)
+	ctx := context.Background()
+
+	v1Route := createTestRoute(t, store, routes.PaymentProtocolVersionV1)
+	v2Route := createTestRoute(t, store, routes.PaymentProtocolVersionV2)
+	otherV1Route := createTestRoute(t, store, routes.PaymentProtocolVersionV1)
+
+	payer := randomHex(t, 20)
+	nonce := randomHex(t, 32)
+
+	firstID, err := service.CreatePendingPurchase(ctx, pendingPurchase(v1Route, paymentPayload(1, payer, nonce)))
+	if err != nil {
+		t.Fatalf("first purchase: %v", err)
+	}
+
+	// The same authorization can't pay for another route, under either
+	// protocol version and however its addresses are cased.
+	reuses := map[string]*purchases.Purchase{
+		"same version": pendingPurchase(otherV1Route, paymentPayload(1, payer, nonce)),
+		"other version": pendingPurchase(v2Route,
+			paymentPayload(2, "0x"+strings.ToUpper(payer[2:]), "0x"+strings.ToUpper(nonce[2:]))),
+		"same route": pendingPurchase(v1Route, paymentPayload(2, payer, nonce)),
+	}
+	for name, purchase := range reuses {
+		_, err := service.CreatePendingPurchase(ctx, purchase)
+		if !errors.Is(err, purchases.ErrPaymentReused) {
+			t.Errorf("%s: got %v, want %v", name, err, purchases.ErrPaymentReused)
+		}
+	}
+
+	// A different authorization of the same payer is a new payment.
+	_, err = service.CreatePendingPurchase(ctx, pendingPurchase(v2Route,
+		paymentPayload(2, payer, randomHex(t, 32))))
+	if err != nil {
+		t.Errorf("purchase with a new nonce: %v", err)
+	}
+
+	// A payment that failed to settle transferred nothing and can be used
+	// again, on any route.
+	err = service.FailPurchase(ctx, firstID, "test")
+	if err != nil {
+		t.Fatalf("failed to fail purchase: %v", err)
+	}
+	_, err = service.CreatePendingPurchase(ctx, pendingPurchase(v2Route, paymentPayload(2, payer, nonce)))
+	if err != nil {
+		t.Errorf("purchase after the first failed: %v", err)
+	}
+	_, err = service.CreatePendingPurchase(ctx, pendingPurchase(otherV1Route, paymentPayload(1, payer, nonce)))
+	if !errors.Is(err, purchases.ErrPaymentReused) {
+		t.Errorf("reuse after the nonce was registered again: got %v, want %v", err, purchases.ErrPaymentReused)
+	}
+}
+
+func TestPaymentNonceConcurrentSubmissions(t *testing.T) {
+	store := newTestStore(t)
+	service := newTestPurchaseService(store)
+	ctx := context.Background()
+
+	const submissions = 16
+
+	payer := randomHex(t, 20)
+	nonce := randomHex(t, 32)
+
+	// Half of the submissions are to the same route and half to others, as
+	// a payment replayed against several routes at once would be.
+	sameRoute := createTestRoute(t, store, routes.PaymentProtocolVersionV2)
+	purchasesToCreate := make([]*purchases.Purchase, submissions)
+	for i := range purchasesToCreate {
+		route := sameRoute
+		if i%2 == 1 {
+			route = createTestRoute(t, store, uint16(1+i%4/2))
+		}
+		purchasesToCreate[i] = pendingPurchase(route,
+			paymentPayload(int(route.PaymentProtocolVersion), payer, nonce))
+	}
+
+	var (
+		wg    sync.WaitGroup
+		start = make(chan struct{})
+		errs  = make([]error, submissions)
+	)
+	for i, purchase := range purchasesToCreate {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, errs[i] = service.CreatePendingPurchase(ctx, purchase)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	var won, reused int
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			won++
+		case errors.Is(err, purchases.ErrPaymentReused):
+			reused++
+		default:
+			t.Errorf("submission %d: unexpected error %v", i, err)
+		}
+	}
+	if won != 1 || reused != submissions-1 {
+		t.Errorf("%d submissions won and %d were rejected as reused, want 1 and %d",
+			won, reused, submissions-1)
+	}
+}
+
+func TestFreePurchaseNonceReplayed(t *testing.T) {
+	store := newTestStore(t)
+	service := newTestPurchaseService(store)
+	ctx := context.Background()
+
+	route := createTestRoute(t, store, routes.PaymentProtocolVersionV2)
+	otherRoute := createTestRoute(t, store, routes.PaymentProtocolVersionV1)
+
+	payer := randomHex(t, 20)
+	nonce := randomHex(t, 32)
+
+	purchaseID, err := service.CreateFreePurchase(ctx, pendingPurchase(route, paymentPayload(2, payer, nonce)))
+	if err != nil {
+		t.Fatalf("CreateFreePurchase() error = %v", err)
+	}
+	purchase, err := service.GetPurchaseByID(ctx, purchaseID)
+	if err != nil {
+		t.Fatalf("GetPurchaseByID() error = %v", err)
+	}
+	if purchase.Price != 0 || purchase.Status != purchases.StatusSettled {
+		t.Errorf("free purchase price = %d, status = %s, want 0 and %s",
+			purchase.Price, purchase.Status, purchases.StatusSettled)
+	}
+
+	// The verified authorization lets its payer through once, free or paid.
+	replays := map[string]func() (uint64, error){
+		"free": func() (uint64, error) {
+			return service.CreateFreePurchase(ctx, pendingPurchase(route, paymentPayload(2, payer, nonce)))
+		},
+		"free on another route": func() (uint64, error) {
+			return service.CreateFreePurchase(ctx, pendingPurchase(otherRoute, paymentPayload(1, payer, nonce)))
+		},
+		"paid": func() (uint64, error) {
+			return service.CreatePendingPurchase(ctx, pendingPurchase(route, paymentPayload(2, payer, nonce)))
+		},
+	}
+	for name, replay := range replays {
+		if _, err := replay(); !errors.Is(err, purchases.ErrPaymentReused) {
+			t.Errorf("%s replay: got %v, want %v", name, err, purchases.ErrPaymentReused)
+		}
+	}
+}
+
+func TestChargePurchaseUsageHugeUsage(t *testing.T) {
+	store := newTestStore(t)
+	service := newTestPurchaseService(store)
+	ctx := context.Background()
+
+	route := createTestRoute(t, store, routes.PaymentProtocolVersionV2)
+	purchaseID, err := service.CreatePendingPurchase(ctx,
+		pendingPurchase(route, paymentPayload(2, randomHex(t, 20), randomHex(t, 32))))
+	if err != nil {
+		t.Fatalf("failed to create purchase: %v", err)
+	}
+
+	usage := ledger.Usage{PurchaseID: purchaseID, RouteID: route.ID, OwnerID: route.UserID, IsTest: true}
+	charged, amount, err := service.ChargeUsage(ctx, usage, 3, 1000)
+	if err != nil {
+		t.Fatalf("ChargeUsage() error = %v", err)
+	}
+	if amount != 3000 || charged.AmountCharged != 3000 {
+		t.Errorf("ChargeUsage() charged %d, total %d, want 3000", amount, charged.AmountCharged)
+	}
+
+	// Usage too large for the columns charges what is left of the price.
+	for range 2 {
+		charged, amount, err = service.ChargeUsage(ctx, usage, math.MaxInt64, math.MaxUint64)
+		if err != nil {
+			t.Fatalf("ChargeUsage() with huge usage error = %v", err)
+		}
+	}
+	if amount != 0 || charged.AmountCharged != route.Price {
+		t.Errorf("ChargeUsage() charged %d, total %d, want 0 and %d", amount, charged.AmountCharged, route.Price)
+	}
+	if charged.UsageUnits != math.MaxInt64 {
+		t.Errorf("usage units = %d, want %d", charged.UsageUnits, uint64(math.MaxInt64))
+	}
+}
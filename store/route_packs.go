@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"linkshrink/packs"
+	"linkshrink/store/sqlc"
+)
+
+// CreateRoutePack creates a credit pack of a route, ErrPriceTaken if the
+// route has another pack with the same price.
+func (s *Store) CreateRoutePack(ctx context.Context, pack *packs.Pack) (*packs.Pack, error) {
+	dbPack, err := s.queries.CreateRoutePack(ctx, sqlc.CreateRoutePackParams{
+		PaidRouteID: int64(pack.PaidRouteID),
+		Credits:     int64(pack.Credits),
+		Price:       int64(pack.Price),
+		Label:       pack.Label,
+		CreatedAt:   s.clock.Now(),
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, packs.ErrPriceTaken
+		}
+		return nil, fmt.Errorf("failed to create route pack: %w", err)
+	}
+
+	created := convertToPackModel(dbPack)
+	return &created, nil
+}
+
+// ListRoutePacks retrieves the credit packs of a route, cheapest first.
+func (s *Store) ListRoutePacks(ctx context.Context, routeID uint64) ([]packs.Pack, error) {
+	dbPacks, err := s.queries.ListRoutePacks(ctx, int64(routeID))
+	if err != nil {
+		return nil, err
+	}
+
+	routePacks := make([]packs.Pack, len(dbPacks))
+	for i, dbPack := range dbPacks {
+		routePacks[i] = convertToPackModel(dbPack)
+	}
+
+	return routePacks, nil
+}
+
+// DeleteRoutePack deletes a credit pack of a route. The pack is kept for the
+// purchases that bought it.
+func (s *Store) DeleteRoutePack(ctx context.Context, packID, routeID uint64) error {
+	rows, err := s.queries.DeleteRoutePack(ctx, sqlc.DeleteRoutePackParams{
+		ID:          int64(packID),
+		PaidRouteID: int64(routeID),
+		DeletedAt:   pgtype.Timestamptz{Time: s.clock.Now(), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete route pack: %w", err)
+	}
+	if rows == 0 {
+		return packs.ErrPackNotFound
+	}
+	return nil
+}
+
+func convertToPackModel(dbPack sqlc.RoutePack) packs.Pack {
+	return packs.Pack{
+		ID:          uint64(dbPack.ID),
+		PaidRouteID: uint64(dbPack.PaidRouteID),
+		Credits:     uint64(dbPack.Credits),
+		Price:       uint64(dbPack.Price),
+		Label:       dbPack.Label,
+		CreatedAt:   dbPack.CreatedAt,
+	}
+}
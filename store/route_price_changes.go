@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"linkshrink/routes"
+	"linkshrink/store/sqlc"
+)
+
+// CreatePriceChange schedules a price change of a route.
+func (s *Store) CreatePriceChange(ctx context.Context, change *routes.PriceChange) (*routes.PriceChange, error) {
+	dbChange, err := s.queries.CreateRoutePriceChange(ctx, sqlc.CreateRoutePriceChangeParams{
+		PaidRouteID: int64(change.PaidRouteID),
+		Price:       int64(change.Price),
+		EffectiveAt: change.EffectiveAt,
+		CreatedAt:   s.clock.Now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create route price change: %w", err)
+	}
+
+	created := convertToPriceChangeModel(dbChange)
+	return &created, nil
+}
+
+// ListPriceChanges retrieves the price changes of a route, earliest first.
+func (s *Store) ListPriceChanges(ctx context.Context, routeID uint64) ([]routes.PriceChange, error) {
+	dbChanges, err := s.queries.ListRoutePriceChanges(ctx, int64(routeID))
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]routes.PriceChange, len(dbChanges))
+	for i, dbChange := range dbChanges {
+		changes[i] = convertToPriceChangeModel(dbChange)
+	}
+
+	return changes, nil
+}
+
+// DeletePriceChange deletes a price change of a route that was not applied
+// yet.
+func (s *Store) DeletePriceChange(ctx context.Context, changeID, routeID uint64) error {
+	rows, err := s.queries.DeleteRoutePriceChange(ctx, sqlc.DeleteRoutePriceChangeParams{
+		ID:          int64(changeID),
+		PaidRouteID: int64(routeID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete route price change: %w", err)
+	}
+	if rows == 0 {
+		return routes.ErrPriceChangeNotFound
+	}
+	return nil
+}
+
+// ApplyDuePriceChanges changes the prices of routes with up to limit of the
+// price changes due at now, earliest first. Each change is applied in its own
+// transaction, so a later change of a route overrides an earlier one.
+func (s *Store) ApplyDuePriceChanges(ctx context.Context, now time.Time,
+	limit int) ([]routes.PriceChange, error) {
+
+	dbChanges, err := s.queries.ListDueRoutePriceChanges(ctx, sqlc.ListDueRoutePriceChangesParams{
+		EffectiveAt: now,
+		Limit:       int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due route price changes: %w", err)
+	}
+
+	applied := make([]routes.PriceChange, 0, len(dbChanges))
+	for _, dbChange := range dbChanges {
+		var marked int64
+		err := s.ExecTx(ctx, func(q *sqlc.Queries) error {
+			// A change marked by a concurrent run is left to it.
+			var err error
+			marked, err = q.MarkRoutePriceChangeApplied(ctx, sqlc.MarkRoutePriceChangeAppliedParams{
+				ID:        dbChange.ID,
+				AppliedAt: pgtype.Timestamptz{Time: now, Valid: true},
+			})
+			if err != nil || marked == 0 {
+				return err
+			}
+
+			// Changes of deleted routes are marked applied all the same.
+			_, err = q.UpdatePaidRoutePrice(ctx, sqlc.UpdatePaidRoutePriceParams{
+				ID:        dbChange.PaidRouteID,
+				Price:     dbChange.Price,
+				UpdatedAt: now,
+			})
+			return err
+		})
+		if err != nil {
+			return applied, fmt.Errorf("failed to apply route price change %d: %w", dbChange.ID, err)
+		}
+		if marked == 0 {
+			continue
+		}
+
+		dbChange.AppliedAt = pgtype.Timestamptz{Time: now, Valid: true}
+		applied = append(applied, convertToPriceChangeModel(dbChange))
+	}
+
+	return applied, nil
+}
+
+func convertToPriceChangeModel(dbChange sqlc.RoutePriceChange) routes.PriceChange {
+	change := routes.PriceChange{
+		ID:          uint64(dbChange.ID),
+		PaidRouteID: uint64(dbChange.PaidRouteID),
+		Price:       uint64(dbChange.Price),
+		EffectiveAt: dbChange.EffectiveAt,
+		CreatedAt:   dbChange.CreatedAt,
+	}
+
+	if dbChange.AppliedAt.Valid {
+		appliedAt := dbChange.AppliedAt.Time
+		change.AppliedAt = &appliedAt
+	}
+
+	return change
+}
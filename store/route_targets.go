@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"linkshrink/store/sqlc"
+	"linkshrink/upstreams"
+)
+
+// CreateRouteTarget adds a target to a route's pool and returns it.
+func (s *Store) CreateRouteTarget(ctx context.Context, target *upstreams.Target) (*upstreams.Target, error) {
+	now := s.clock.Now()
+	dbTarget, err := s.queries.CreateRouteTarget(ctx, sqlc.CreateRouteTargetParams{
+		PaidRouteID: int64(target.PaidRouteID),
+		TargetUrl:   target.URL,
+		Weight:      int32(target.Weight),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create route target: %w", err)
+	}
+
+	return convertToTargetModel(dbTarget), nil
+}
+
+// ListRouteTargets retrieves the pool of a route in failover order.
+func (s *Store) ListRouteTargets(ctx context.Context, routeID uint64) ([]upstreams.Target, error) {
+	dbTargets, err := s.queries.ListRouteTargets(ctx, int64(routeID))
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]upstreams.Target, len(dbTargets))
+	for i, dbTarget := range dbTargets {
+		targets[i] = *convertToTargetModel(dbTarget)
+	}
+
+	return targets, nil
+}
+
+// DeleteRouteTarget removes a target from a route's pool.
+func (s *Store) DeleteRouteTarget(ctx context.Context, routeID uint64, targetID uint64) error {
+	rows, err := s.queries.DeleteRouteTarget(ctx, sqlc.DeleteRouteTargetParams{
+		ID:          int64(targetID),
+		PaidRouteID: int64(routeID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete route target: %w", err)
+	}
+	if rows == 0 {
+		return upstreams.ErrTargetNotFound
+	}
+	return nil
+}
+
+// ListHealthCheckTargets retrieves all targets of routes with a health check path.
+func (s *Store) ListHealthCheckTargets(ctx context.Context) ([]upstreams.HealthCheckTarget, error) {
+	rows, err := s.queries.ListHealthCheckTargets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]upstreams.HealthCheckTarget, len(rows))
+	for i, row := range rows {
+		targets[i] = upstreams.HealthCheckTarget{
+			Target: *convertToTargetModel(sqlc.RouteTarget{
+				ID:                  row.ID,
+				PaidRouteID:         row.PaidRouteID,
+				TargetUrl:           row.TargetUrl,
+				Weight:              row.Weight,
+				IsHealthy:           row.IsHealthy,
+				ConsecutiveFailures: row.ConsecutiveFailures,
+				LastError:           row.LastError,
+				LastCheckedAt:       row.LastCheckedAt,
+				CreatedAt:           row.CreatedAt,
+				UpdatedAt:           row.UpdatedAt,
+			}),
+			HealthCheckPath: row.HealthCheckPath,
+		}
+	}
+
+	return targets, nil
+}
+
+// UpdateRouteTargetHealth records the result of an active health check.
+func (s *Store) UpdateRouteTargetHealth(ctx context.Context, targetID uint64,
+	healthy bool, consecutiveFailures uint64, lastError string) error {
+
+	now := s.clock.Now()
+	err := s.queries.UpdateRouteTargetHealth(ctx, sqlc.UpdateRouteTargetHealthParams{
+		ID:                  int64(targetID),
+		IsHealthy:           healthy,
+		ConsecutiveFailures: int32(consecutiveFailures),
+		LastError:           lastError,
+		LastCheckedAt:       pgtype.Timestamptz{Time: now, Valid: true},
+		UpdatedAt:           now,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update route target health: %w", err)
+	}
+	return nil
+}
+
+// Helper function to convert sqlc RouteTarget to upstreams.Target
+func convertToTargetModel(dbTarget sqlc.RouteTarget) *upstreams.Target {
+	target := &upstreams.Target{
+		ID:          uint64(dbTarget.ID),
+		PaidRouteID: uint64(dbTarget.PaidRouteID),
+		URL:         dbTarget.TargetUrl,
+		Weight:      uint64(dbTarget.Weight),
+
+		IsHealthy:           dbTarget.IsHealthy,
+		ConsecutiveFailures: uint64(dbTarget.ConsecutiveFailures),
+		LastError:           dbTarget.LastError,
+
+		CreatedAt: dbTarget.CreatedAt,
+		UpdatedAt: dbTarget.UpdatedAt,
+	}
+
+	if dbTarget.LastCheckedAt.Valid {
+		lastCheckedAt := dbTarget.LastCheckedAt.Time
+		target.LastCheckedAt = &lastCheckedAt
+	}
+
+	return target
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"linkshrink/reconciler"
+	"linkshrink/store/sqlc"
+)
+
+// CreateSettlementCheck queues the settlement of a purchase for on-chain
+// verification.
+func (s *Store) CreateSettlementCheck(ctx context.Context, purchaseID uint64,
+	network string, txHash string) error {
+
+	now := s.clock.Now()
+	err := s.queries.CreateSettlementCheck(ctx, sqlc.CreateSettlementCheckParams{
+		PurchaseID: int64(purchaseID),
+		Network:    network,
+		TxHash:     txHash,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create settlement check: %w", err)
+	}
+	return nil
+}
+
+// ListUnsettledChecks retrieves the settlements still to be verified, least
+// recently checked first.
+func (s *Store) ListUnsettledChecks(ctx context.Context, limit uint64) ([]reconciler.Check, error) {
+	rows, err := s.queries.ListUnsettledChecks(ctx, int32(min(limit, MaxLimit)))
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make([]reconciler.Check, len(rows))
+	for i, row := range rows {
+		checks[i] = reconciler.Check{
+			PurchaseID:  uint64(row.PurchaseID),
+			Network:     row.Network,
+			TxHash:      row.TxHash,
+			Status:      row.Status,
+			BlockNumber: uint64(row.BlockNumber),
+			BlockHash:   row.BlockHash,
+
+			Price:       uint64(row.Price),
+			PayTo:       row.PaidToAddress,
+			ShortCode:   row.ShortCode,
+			OwnerID:     uint64(row.UserID),
+			PurchasedAt: row.PurchasedAt,
+		}
+	}
+
+	return checks, nil
+}
+
+// UpdateSettlementCheck records the result of an on-chain verification.
+func (s *Store) UpdateSettlementCheck(ctx context.Context, check *reconciler.Check) error {
+	err := s.queries.UpdateSettlementCheck(ctx, sqlc.UpdateSettlementCheckParams{
+		PurchaseID:    int64(check.PurchaseID),
+		Status:        check.Status,
+		BlockNumber:   int64(check.BlockNumber),
+		BlockHash:     check.BlockHash,
+		Confirmations: int64(check.Confirmations),
+		LastError:     check.LastError,
+		CheckedAt:     pgtype.Timestamptz{Time: s.clock.Now(), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update settlement check: %w", err)
+	}
+	return nil
+}
+
+// CountSettlementChecksByRouteID retrieves the number of purchases of a route
+// per settlement status.
+func (s *Store) CountSettlementChecksByRouteID(ctx context.Context, routeID uint64) (map[string]uint64, error) {
+	rows, err := s.queries.CountSettlementChecksByRouteID(ctx, int64(routeID))
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]uint64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = uint64(row.Count)
+	}
+
+	return counts, nil
+}
+
+// CreateSettlementAlert records a settlement problem for the owner of the
+// purchased route.
+func (s *Store) CreateSettlementAlert(ctx context.Context, check *reconciler.Check) error {
+	err := s.queries.CreateSettlementAlert(ctx, sqlc.CreateSettlementAlertParams{
+		UserID:     int64(check.OwnerID),
+		PurchaseID: int64(check.PurchaseID),
+		Status:     check.Status,
+		Reason:     check.LastError,
+		Network:    check.Network,
+		TxHash:     check.TxHash,
+		CreatedAt:  s.clock.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create settlement alert: %w", err)
+	}
+	return nil
+}
+
+// ListSettlementAlertsByUserID retrieves the alerts a user has not
+// dismissed, newest first.
+func (s *Store) ListSettlementAlertsByUserID(ctx context.Context, userID uint64) ([]reconciler.Alert, error) {
+	rows, err := s.queries.ListSettlementAlertsByUserID(ctx, sqlc.ListSettlementAlertsByUserIDParams{
+		UserID: int64(userID),
+		Limit:  MaxLimit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list settlement alerts: %w", err)
+	}
+
+	alerts := make([]reconciler.Alert, len(rows))
+	for i, row := range rows {
+		alerts[i] = reconciler.Alert{
+			ID:         uint64(row.ID),
+			PurchaseID: uint64(row.PurchaseID),
+			ShortCode:  row.ShortCode,
+			Price:      uint64(row.Price),
+			IsTest:     row.IsTest,
+			Status:     row.Status,
+			Reason:     row.Reason,
+			Network:    row.Network,
+			TxHash:     row.TxHash,
+			CreatedAt:  row.CreatedAt,
+		}
+	}
+
+	return alerts, nil
+}
+
+// DismissSettlementAlert hides an alert of a user.
+func (s *Store) DismissSettlementAlert(ctx context.Context, userID, alertID uint64) error {
+	rows, err := s.queries.DismissSettlementAlert(ctx, sqlc.DismissSettlementAlertParams{
+		ID:          int64(alertID),
+		UserID:      int64(userID),
+		DismissedAt: pgtype.Timestamptz{Time: s.clock.Now(), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to dismiss settlement alert: %w", err)
+	}
+	if rows == 0 {
+		return reconciler.ErrAlertNotFound
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"linkshrink/splits"
+	"linkshrink/store/sqlc"
+)
+
+// ListRouteSplits retrieves the revenue shares of a route.
+func (s *Store) ListRouteSplits(ctx context.Context, routeID uint64) ([]splits.Split, error) {
+	dbSplits, err := s.queries.ListRouteSplits(ctx, int64(routeID))
+	if err != nil {
+		return nil, err
+	}
+
+	routeSplits := make([]splits.Split, len(dbSplits))
+	for i, dbSplit := range dbSplits {
+		routeSplits[i] = convertToSplitModel(dbSplit)
+	}
+
+	return routeSplits, nil
+}
+
+// ReplaceRouteSplits atomically replaces all revenue shares of a route.
+func (s *Store) ReplaceRouteSplits(ctx context.Context, routeID uint64,
+	newSplits []splits.Split) ([]splits.Split, error) {
+
+	now := s.clock.Now()
+	routeSplits := make([]splits.Split, 0, len(newSplits))
+	err := s.ExecTx(ctx, func(q *sqlc.Queries) error {
+		err := q.DeleteRouteSplits(ctx, int64(routeID))
+		if err != nil {
+			return err
+		}
+
+		for _, split := range newSplits {
+			dbSplit, err := q.CreateRouteSplit(ctx, sqlc.CreateRouteSplitParams{
+				PaidRouteID:  int64(routeID),
+				PayeeAddress: split.PayeeAddress,
+				Label:        split.Label,
+				ShareBps:     int32(split.ShareBPS),
+				CreatedAt:    now,
+			})
+			if err != nil {
+				return err
+			}
+			routeSplits = append(routeSplits, convertToSplitModel(dbSplit))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to replace route splits: %w", err)
+	}
+
+	return routeSplits, nil
+}
+
+// createPurchasePayouts records what each payee is owed for a purchase, and
+// the commission of its affiliate on the purchase, in the db transaction of
+// q.
+func (s *Store) createPurchasePayouts(ctx context.Context, q *sqlc.Queries,
+	payouts []splits.Payout) error {
+
+	now := s.clock.Now()
+	for _, payout := range payouts {
+		err := q.CreatePurchasePayout(ctx, sqlc.CreatePurchasePayoutParams{
+			PurchaseID:   int64(payout.PurchaseID),
+			PaidRouteID:  int64(payout.PaidRouteID),
+			PayeeAddress: payout.PayeeAddress,
+			Role:         payout.Role,
+			ShareBps:     int32(payout.ShareBPS),
+			Amount:       int64(payout.Amount),
+			CreatedAt:    now,
+		})
+		if err != nil {
+			return err
+		}
+
+		if payout.Role == splits.RoleAffiliate {
+			err = q.SetPurchaseAffiliateCommission(ctx, sqlc.SetPurchaseAffiliateCommissionParams{
+				ID:                  int64(payout.PurchaseID),
+				AffiliateCommission: int64(payout.Amount),
+				UpdatedAt:           now,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ListPayoutTotalsByRouteID retrieves what each payee is owed for a route.
+func (s *Store) ListPayoutTotalsByRouteID(ctx context.Context, routeID uint64) ([]splits.PayeeTotal, error) {
+	rows, err := s.queries.ListPayoutTotalsByRouteID(ctx, int64(routeID))
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make([]splits.PayeeTotal, len(rows))
+	for i, row := range rows {
+		totals[i] = splits.PayeeTotal{
+			PayeeAddress: row.PayeeAddress,
+			Role:         row.Role,
+			Amount:       uint64(row.Amount),
+			Count:        uint64(row.Count),
+		}
+	}
+
+	return totals, nil
+}
+
+// ListPayoutsByUserID retrieves the payouts of all routes of a user.
+func (s *Store) ListPayoutsByUserID(ctx context.Context, userID uint64) ([]splits.Payout, error) {
+	rows, err := s.queries.ListPayoutsByUserID(ctx, int64(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	payouts := make([]splits.Payout, len(rows))
+	for i, row := range rows {
+		payouts[i] = splits.Payout{
+			ID:           uint64(row.ID),
+			PurchaseID:   uint64(row.PurchaseID),
+			PaidRouteID:  uint64(row.PaidRouteID),
+			ShortCode:    row.ShortCode,
+			PayeeAddress: row.PayeeAddress,
+			Role:         row.Role,
+			ShareBPS:     uint64(row.ShareBps),
+			Amount:       uint64(row.Amount),
+			CreatedAt:    row.CreatedAt,
+		}
+	}
+
+	return payouts, nil
+}
+
+// Helper function to convert sqlc RouteSplit to splits.Split
+func convertToSplitModel(dbSplit sqlc.RouteSplit) splits.Split {
+	return splits.Split{
+		ID:           uint64(dbSplit.ID),
+		PaidRouteID:  uint64(dbSplit.PaidRouteID),
+		PayeeAddress: dbSplit.PayeeAddress,
+		Label:        dbSplit.Label,
+		ShareBPS:     uint64(dbSplit.ShareBps),
+		CreatedAt:    dbSplit.CreatedAt,
+	}
+}
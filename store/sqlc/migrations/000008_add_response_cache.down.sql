@@ -0,0 +1,4 @@
+ALTER TABLE paid_routes
+DROP COLUMN cache_vary_headers,
+DROP COLUMN cache_ttl_seconds,
+DROP COLUMN cache_enabled;
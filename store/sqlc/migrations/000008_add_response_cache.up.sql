@@ -0,0 +1,5 @@
+-- Add opt-in response caching settings to paid_routes
+ALTER TABLE paid_routes
+ADD COLUMN cache_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN cache_ttl_seconds INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN cache_vary_headers TEXT NOT NULL DEFAULT '';
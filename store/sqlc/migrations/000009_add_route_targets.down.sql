@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS route_targets;
+
+ALTER TABLE paid_routes
+DROP COLUMN health_check_path,
+DROP COLUMN lb_strategy;
@@ -0,0 +1,39 @@
+-- Add load balancing settings to paid_routes
+ALTER TABLE paid_routes
+ADD COLUMN lb_strategy VARCHAR(20) NOT NULL DEFAULT 'round_robin'
+    CHECK (lb_strategy IN ('round_robin', 'least_connections', 'failover')),
+ADD COLUMN health_check_path TEXT NOT NULL DEFAULT '';
+
+-- route_targets is a table that stores the upstream pool of a paid route.
+-- Routes without rows here proxy to paid_routes.target_url.
+CREATE TABLE IF NOT EXISTS route_targets (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- paid_route_id is the route this target belongs to.
+    paid_route_id BIGINT NOT NULL REFERENCES paid_routes(id),
+
+    -- target_url is the upstream URL requests are proxied to.
+    target_url TEXT NOT NULL,
+
+    -- weight is the relative share of traffic sent to this target.
+    weight INTEGER NOT NULL DEFAULT 1 CHECK (weight > 0),
+
+    -- is_healthy is the result of the last active health check.
+    is_healthy BOOLEAN NOT NULL DEFAULT TRUE,
+
+    -- consecutive_failures counts failed active health checks in a row.
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+
+    -- last_error is the error of the last failed health check.
+    last_error TEXT NOT NULL DEFAULT '',
+
+    -- last_checked_at is when the target was last health checked.
+    last_checked_at TIMESTAMPTZ,
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- Create index on paid_route_id for pool lookups
+CREATE INDEX IF NOT EXISTS idx_route_targets_paid_route_id ON route_targets (paid_route_id);
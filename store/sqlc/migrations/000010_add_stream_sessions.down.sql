@@ -0,0 +1,3 @@
+ALTER TABLE paid_routes
+DROP COLUMN stream_credit_seconds,
+DROP COLUMN stream_max_seconds;
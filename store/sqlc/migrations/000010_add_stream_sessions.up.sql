@@ -0,0 +1,4 @@
+-- Add long-lived stream (WebSocket/SSE) session settings to paid_routes
+ALTER TABLE paid_routes
+ADD COLUMN stream_max_seconds INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN stream_credit_seconds INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+ALTER TABLE purchases
+DROP COLUMN amount_charged,
+DROP COLUMN usage_units;
+
+ALTER TABLE paid_routes
+DROP COLUMN unit_price,
+DROP COLUMN billing_mode;
@@ -0,0 +1,8 @@
+-- Add metered billing based on upstream reported usage
+ALTER TABLE paid_routes
+ADD COLUMN billing_mode VARCHAR(20) NOT NULL DEFAULT 'fixed' CHECK (billing_mode IN ('fixed', 'metered')),
+ADD COLUMN unit_price INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE purchases
+ADD COLUMN usage_units BIGINT NOT NULL DEFAULT 0,
+ADD COLUMN amount_charged INTEGER NOT NULL DEFAULT 0;
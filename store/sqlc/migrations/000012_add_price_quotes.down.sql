@@ -0,0 +1,4 @@
+ALTER TABLE paid_routes
+DROP COLUMN max_price,
+DROP COLUMN min_price,
+DROP COLUMN quote_url;
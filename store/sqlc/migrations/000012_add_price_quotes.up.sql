@@ -0,0 +1,5 @@
+-- Add an optional upstream quote hook with price bounds to paid_routes
+ALTER TABLE paid_routes
+ADD COLUMN quote_url TEXT NOT NULL DEFAULT '',
+ADD COLUMN min_price INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN max_price INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,11 @@
+ALTER TABLE purchases
+DROP CONSTRAINT purchases_price_non_negative,
+ALTER COLUMN amount_charged TYPE INTEGER,
+ALTER COLUMN price TYPE INTEGER;
+
+ALTER TABLE paid_routes
+DROP CONSTRAINT paid_routes_price_non_negative,
+ALTER COLUMN max_price TYPE INTEGER,
+ALTER COLUMN min_price TYPE INTEGER,
+ALTER COLUMN unit_price TYPE INTEGER,
+ALTER COLUMN price TYPE INTEGER;
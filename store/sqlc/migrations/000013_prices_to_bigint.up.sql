@@ -0,0 +1,16 @@
+-- Store amounts as BIGINT base units so prices are not capped at about 2147 USDC
+ALTER TABLE paid_routes
+ALTER COLUMN price TYPE BIGINT,
+ALTER COLUMN unit_price TYPE BIGINT,
+ALTER COLUMN min_price TYPE BIGINT,
+ALTER COLUMN max_price TYPE BIGINT,
+ADD CONSTRAINT paid_routes_price_non_negative CHECK (
+    price >= 0 AND unit_price >= 0 AND min_price >= 0 AND max_price >= 0
+);
+
+ALTER TABLE purchases
+ALTER COLUMN price TYPE BIGINT,
+ALTER COLUMN amount_charged TYPE BIGINT,
+ADD CONSTRAINT purchases_price_non_negative CHECK (
+    price >= 0 AND amount_charged >= 0
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS purchase_payouts;
+DROP TABLE IF EXISTS route_splits;
@@ -0,0 +1,54 @@
+-- route_splits is a table that stores how the revenue of a paid route is
+-- shared with partners. Whatever is not shared goes to the route owner.
+CREATE TABLE IF NOT EXISTS route_splits (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- paid_route_id is the route this split belongs to.
+    paid_route_id BIGINT NOT NULL REFERENCES paid_routes(id),
+
+    -- payee_address is the address the share is owed to.
+    payee_address TEXT NOT NULL,
+
+    -- label is an optional name for the payee.
+    label TEXT NOT NULL DEFAULT '',
+
+    -- share_bps is the share of each payment in basis points (1/100 of a percent).
+    share_bps INTEGER NOT NULL CHECK (share_bps > 0 AND share_bps <= 10000),
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+-- Create index on paid_route_id for split lookups
+CREATE INDEX IF NOT EXISTS idx_route_splits_paid_route_id ON route_splits (paid_route_id);
+
+-- purchase_payouts is a table that stores what each payee is owed for a
+-- purchase, after the payment settled.
+CREATE TABLE IF NOT EXISTS purchase_payouts (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- purchase_id is the purchase the payout is for.
+    purchase_id BIGINT NOT NULL REFERENCES purchases(id),
+
+    -- paid_route_id is the route the purchase was made on.
+    paid_route_id BIGINT NOT NULL REFERENCES paid_routes(id),
+
+    -- payee_address is the address the amount is owed to.
+    payee_address TEXT NOT NULL,
+
+    -- role is 'owner', 'partner' or 'platform'.
+    role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'partner', 'platform')),
+
+    -- share_bps is the share of the payment in basis points.
+    share_bps INTEGER NOT NULL,
+
+    -- amount is the amount owed (USDC * 10^6).
+    amount BIGINT NOT NULL CHECK (amount >= 0),
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+-- Create indexes for payout reporting
+CREATE INDEX IF NOT EXISTS idx_purchase_payouts_purchase_id ON purchase_payouts (purchase_id);
+CREATE INDEX IF NOT EXISTS idx_purchase_payouts_paid_route_id ON purchase_payouts (paid_route_id);
@@ -0,0 +1,162 @@
+-- ledger_accounts is a table that stores the accounts of the double-entry
+-- ledger. An account's balance is the sum of its entries.
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- kind is 'payer', 'route', 'user', 'payee' or 'platform'.
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('payer', 'route', 'user', 'payee', 'platform')),
+
+    -- reference identifies the account within its kind, e.g. the route ID
+    -- or the payer address.
+    reference TEXT NOT NULL,
+
+    -- user_id is the user owning route and user accounts.
+    user_id BIGINT REFERENCES users(id),
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL,
+
+    UNIQUE (kind, reference)
+);
+
+-- Create index on user_id for balance lookups
+CREATE INDEX IF NOT EXISTS idx_ledger_accounts_user_id ON ledger_accounts (user_id);
+
+-- ledger_transactions is a table that stores groups of entries that were
+-- posted together and always add up to zero.
+CREATE TABLE IF NOT EXISTS ledger_transactions (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- reference uniquely identifies the event the transaction records, so
+    -- the same event is never posted twice.
+    reference TEXT NOT NULL UNIQUE,
+
+    -- kind is 'purchase', 'fee', 'split', 'credit_use', 'usage' or 'refund'.
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('purchase', 'fee', 'split', 'credit_use', 'usage', 'refund')),
+
+    -- purchase_id is the purchase the transaction belongs to.
+    purchase_id BIGINT REFERENCES purchases(id),
+
+    -- is_test is true for transactions of test network payments.
+    is_test BOOLEAN NOT NULL,
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+-- Create index on purchase_id for purchase lookups
+CREATE INDEX IF NOT EXISTS idx_ledger_transactions_purchase_id ON ledger_transactions (purchase_id);
+
+-- ledger_entries is a table that stores the movements of the ledger.
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- transaction_id is the transaction the entry was posted in.
+    transaction_id BIGINT NOT NULL REFERENCES ledger_transactions(id),
+
+    -- account_id is the account the entry moves funds in or out of.
+    account_id BIGINT NOT NULL REFERENCES ledger_accounts(id),
+
+    -- amount is the signed amount (USDC * 10^6), positive amounts add to
+    -- the account's balance.
+    amount BIGINT NOT NULL CHECK (amount <> 0),
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+-- Create indexes for balance queries
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_transaction_id ON ledger_entries (transaction_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account_id ON ledger_entries (account_id);
+
+-- Posted transactions and entries are never changed, corrections are posted
+-- as new transactions.
+CREATE OR REPLACE FUNCTION ledger_reject_change() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger rows are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER ledger_transactions_immutable
+    BEFORE UPDATE OR DELETE ON ledger_transactions
+    FOR EACH ROW EXECUTE FUNCTION ledger_reject_change();
+
+CREATE TRIGGER ledger_entries_immutable
+    BEFORE UPDATE OR DELETE ON ledger_entries
+    FOR EACH ROW EXECUTE FUNCTION ledger_reject_change();
+
+-- The entries of a transaction must add up to zero once the database
+-- transaction posting them commits.
+CREATE OR REPLACE FUNCTION ledger_check_balanced() RETURNS TRIGGER AS $$
+BEGIN
+    IF (SELECT SUM(amount) FROM ledger_entries
+        WHERE transaction_id = NEW.transaction_id) <> 0 THEN
+
+        RAISE EXCEPTION 'ledger transaction % is unbalanced', NEW.transaction_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER ledger_entries_balanced
+    AFTER INSERT ON ledger_entries
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION ledger_check_balanced();
+
+-- Backfill the purchases made before the ledger existed. Their full price is
+-- recognized as earnings of the route owner.
+INSERT INTO ledger_accounts (kind, reference, user_id, created_at)
+SELECT DISTINCT 'route', pr.id::TEXT, pr.user_id, NOW()
+FROM purchases p
+JOIN paid_routes pr ON p.paid_route_id = pr.id
+WHERE p.price > 0
+ON CONFLICT (kind, reference) DO NOTHING;
+
+INSERT INTO ledger_accounts (kind, reference, user_id, created_at)
+SELECT DISTINCT 'user', pr.user_id::TEXT, pr.user_id, NOW()
+FROM purchases p
+JOIN paid_routes pr ON p.paid_route_id = pr.id
+WHERE p.price > 0
+ON CONFLICT (kind, reference) DO NOTHING;
+
+INSERT INTO ledger_accounts (kind, reference, created_at)
+SELECT DISTINCT 'payer', COALESCE(NULLIF(LOWER(p.settle_response->>'payer'), ''), 'unknown'), NOW()
+FROM purchases p
+WHERE p.price > 0
+ON CONFLICT (kind, reference) DO NOTHING;
+
+INSERT INTO ledger_transactions (reference, kind, purchase_id, is_test, created_at)
+SELECT 'purchase:' || p.id, 'purchase', p.id, p.is_test, p.created_at
+FROM purchases p
+WHERE p.price > 0;
+
+INSERT INTO ledger_transactions (reference, kind, purchase_id, is_test, created_at)
+SELECT 'credit_use:' || p.id || ':backfill', 'credit_use', p.id, p.is_test, p.created_at
+FROM purchases p
+WHERE p.price > 0;
+
+INSERT INTO ledger_entries (transaction_id, account_id, amount, created_at)
+SELECT t.id, a.id, -p.price, p.created_at
+FROM purchases p
+JOIN ledger_transactions t ON t.reference = 'purchase:' || p.id
+JOIN ledger_accounts a ON a.kind = 'payer'
+    AND a.reference = COALESCE(NULLIF(LOWER(p.settle_response->>'payer'), ''), 'unknown');
+
+INSERT INTO ledger_entries (transaction_id, account_id, amount, created_at)
+SELECT t.id, a.id, p.price, p.created_at
+FROM purchases p
+JOIN ledger_transactions t ON t.reference = 'purchase:' || p.id
+JOIN ledger_accounts a ON a.kind = 'route' AND a.reference = p.paid_route_id::TEXT;
+
+INSERT INTO ledger_entries (transaction_id, account_id, amount, created_at)
+SELECT t.id, a.id, -p.price, p.created_at
+FROM purchases p
+JOIN ledger_transactions t ON t.reference = 'credit_use:' || p.id || ':backfill'
+JOIN ledger_accounts a ON a.kind = 'route' AND a.reference = p.paid_route_id::TEXT;
+
+INSERT INTO ledger_entries (transaction_id, account_id, amount, created_at)
+SELECT t.id, a.id, p.price, p.created_at
+FROM purchases p
+JOIN paid_routes pr ON p.paid_route_id = pr.id
+JOIN ledger_transactions t ON t.reference = 'credit_use:' || p.id || ':backfill'
+JOIN ledger_accounts a ON a.kind = 'user' AND a.reference = pr.user_id::TEXT;
@@ -0,0 +1,47 @@
+-- settlement_checks is a table that stores the on-chain verification of the
+-- settlement of each purchase.
+CREATE TABLE IF NOT EXISTS settlement_checks (
+    -- purchase_id is the purchase whose settlement is checked.
+    purchase_id BIGINT PRIMARY KEY REFERENCES purchases(id),
+
+    -- network is the network the facilitator reported settling on.
+    network TEXT NOT NULL,
+
+    -- tx_hash is the settlement transaction reported by the facilitator.
+    tx_hash TEXT NOT NULL,
+
+    -- status is 'pending', 'confirmed', 'failed', 'reorged' or 'mismatch'.
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'confirmed', 'failed', 'reorged', 'mismatch')),
+
+    -- block_number and block_hash are where the transaction was last seen.
+    block_number BIGINT NOT NULL DEFAULT 0,
+    block_hash TEXT NOT NULL DEFAULT '',
+
+    -- confirmations is the number of blocks including the transaction's.
+    confirmations BIGINT NOT NULL DEFAULT 0,
+
+    -- last_error describes why the settlement is not confirmed.
+    last_error TEXT NOT NULL DEFAULT '',
+
+    -- checked_at is when the settlement was last checked on-chain.
+    checked_at TIMESTAMPTZ,
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- Create index on status and checked_at for the reconciler's queue
+CREATE INDEX IF NOT EXISTS idx_settlement_checks_status_checked_at ON settlement_checks (status, checked_at);
+
+-- Queue the purchases made before settlements were checked.
+INSERT INTO settlement_checks (purchase_id, network, tx_hash, created_at, updated_at)
+SELECT
+    id,
+    COALESCE(settle_response->>'network', ''),
+    COALESCE(settle_response->>'transaction', ''),
+    NOW(),
+    NOW()
+FROM purchases
+ON CONFLICT (purchase_id) DO NOTHING;
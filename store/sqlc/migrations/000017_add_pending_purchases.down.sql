@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_purchases_pending;
+
+DELETE FROM purchases WHERE status <> 'settled';
+
+ALTER TABLE purchases ALTER COLUMN settle_response SET NOT NULL;
+
+ALTER TABLE purchases
+DROP COLUMN IF EXISTS settle_error,
+DROP COLUMN IF EXISTS payment_requirements,
+DROP COLUMN IF EXISTS status;
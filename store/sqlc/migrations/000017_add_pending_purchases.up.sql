@@ -0,0 +1,12 @@
+-- Record purchases before their payment is settled, so a payment is never
+-- settled without a record of it.
+ALTER TABLE purchases
+ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'settled' CHECK (status IN ('pending', 'settled', 'failed')),
+ADD COLUMN payment_requirements JSONB,
+ADD COLUMN settle_error TEXT NOT NULL DEFAULT '';
+
+-- Pending purchases have no settle response yet.
+ALTER TABLE purchases ALTER COLUMN settle_response DROP NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_purchases_pending ON purchases (created_at)
+WHERE status = 'pending';
@@ -0,0 +1,48 @@
+-- payment_nonces registers the EIP-3009 authorization of every payment, so a
+-- payment can only be used for a single purchase across all routes and
+-- protocol versions.
+CREATE TABLE IF NOT EXISTS payment_nonces (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- network is the CAIP-2 network the authorization is valid on.
+    network TEXT NOT NULL,
+
+    -- payer is the lowercase address that signed the authorization.
+    payer TEXT NOT NULL,
+
+    -- nonce is the lowercase hex nonce of the authorization.
+    nonce TEXT NOT NULL,
+
+    -- purchase_id is the purchase the payment was used for.
+    purchase_id BIGINT NOT NULL REFERENCES purchases(id) ON DELETE CASCADE,
+
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_nonces_authorization
+ON payment_nonces (network, payer, nonce);
+
+CREATE INDEX IF NOT EXISTS idx_payment_nonces_purchase_id
+ON payment_nonces (purchase_id);
+
+-- Register the payments of existing purchases. v1 payloads name the network,
+-- v2 payloads carry it in the accepted requirements.
+INSERT INTO payment_nonces (network, payer, nonce, purchase_id, created_at)
+SELECT DISTINCT ON (network, payer, nonce) network, payer, nonce, id, created_at
+FROM (
+    SELECT
+        CASE COALESCE(payment_payload->'accepted'->>'network', payment_payload->>'network')
+            WHEN 'base' THEN 'eip155:8453'
+            WHEN 'base-sepolia' THEN 'eip155:84532'
+            ELSE COALESCE(payment_payload->'accepted'->>'network', payment_payload->>'network')
+        END AS network,
+        lower(payment_payload->'payload'->'authorization'->>'from') AS payer,
+        lower(payment_payload->'payload'->'authorization'->>'nonce') AS nonce,
+        id,
+        created_at
+    FROM purchases
+    WHERE status <> 'failed'
+) authorizations
+WHERE network IS NOT NULL AND payer IS NOT NULL AND nonce IS NOT NULL
+ORDER BY network, payer, nonce, id
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,33 @@
+-- idempotency_keys stores the responses to paid requests sent with an
+-- Idempotency-Key header, so retries are replayed instead of paid again.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- paid_route_id is the route the request was sent to.
+    paid_route_id BIGINT NOT NULL REFERENCES paid_routes(id) ON DELETE CASCADE,
+
+    -- scope is the SHA-256 of the payment header, identifying the purchase
+    -- the key belongs to.
+    scope TEXT NOT NULL,
+
+    -- idempotency_key is the client provided key.
+    idempotency_key TEXT NOT NULL,
+
+    -- fingerprint is the SHA-256 of the request the key was first used for.
+    fingerprint TEXT NOT NULL,
+
+    -- response_status is 0 while the request is in progress.
+    response_status INTEGER NOT NULL DEFAULT 0,
+    response_header JSONB,
+    response_body BYTEA,
+
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_idempotency_keys_key
+ON idempotency_keys (paid_route_id, scope, idempotency_key);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at
+ON idempotency_keys (expires_at);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS trial_uses;
+DROP TABLE IF EXISTS route_trials;
+DROP TABLE IF EXISTS coupon_redemptions;
+DROP TABLE IF EXISTS coupons;
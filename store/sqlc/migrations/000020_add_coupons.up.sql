@@ -0,0 +1,86 @@
+-- coupons stores the promo codes owners hand out for discounted or free
+-- access to their routes.
+CREATE TABLE IF NOT EXISTS coupons (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- user_id is the owner of the coupon.
+    user_id BIGINT NOT NULL REFERENCES users(id),
+
+    -- paid_route_id is the route the coupon applies to, NULL applies it to
+    -- all of the owner's routes.
+    paid_route_id BIGINT REFERENCES paid_routes(id),
+
+    -- code is the upper case code buyers pass with their requests.
+    code TEXT NOT NULL,
+
+    -- discount_type is 'percent' or 'fixed'.
+    discount_type VARCHAR(20) NOT NULL CHECK (discount_type IN ('percent', 'fixed')),
+
+    -- discount_value is in basis points for percent discounts and in base
+    -- units (USDC * 10^6) for fixed discounts.
+    discount_value BIGINT NOT NULL CHECK (discount_value > 0),
+
+    -- max_redemptions caps how often the coupon is redeemed, 0 means no limit.
+    max_redemptions BIGINT NOT NULL DEFAULT 0 CHECK (max_redemptions >= 0),
+    redemption_count BIGINT NOT NULL DEFAULT 0 CHECK (redemption_count >= 0),
+
+    -- expires_at is when the coupon stops being redeemable, NULL never.
+    expires_at TIMESTAMPTZ,
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    deleted_at TIMESTAMPTZ
+);
+
+-- Codes are unique among an owner's coupons that are not deleted.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_coupons_user_id_code
+ON coupons (user_id, code) WHERE deleted_at IS NULL;
+
+-- coupon_redemptions records each use of a coupon.
+CREATE TABLE IF NOT EXISTS coupon_redemptions (
+    id BIGSERIAL PRIMARY KEY,
+
+    coupon_id BIGINT NOT NULL REFERENCES coupons(id),
+    paid_route_id BIGINT NOT NULL REFERENCES paid_routes(id),
+
+    -- purchase_id is the discounted purchase, NULL if the coupon made the
+    -- request free.
+    purchase_id BIGINT UNIQUE REFERENCES purchases(id),
+
+    -- discount is the amount taken off the price (USDC * 10^6).
+    discount BIGINT NOT NULL CHECK (discount >= 0),
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_coupon_redemptions_coupon_id ON coupon_redemptions (coupon_id);
+
+-- route_trials stores how many requests to a route each client gets for free
+-- before having to pay.
+CREATE TABLE IF NOT EXISTS route_trials (
+    paid_route_id BIGINT PRIMARY KEY REFERENCES paid_routes(id),
+
+    free_requests BIGINT NOT NULL CHECK (free_requests >= 0),
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- trial_uses counts the free requests each client made to a route.
+CREATE TABLE IF NOT EXISTS trial_uses (
+    paid_route_id BIGINT NOT NULL REFERENCES paid_routes(id),
+
+    -- client_key is the SHA-256 of the client's address.
+    client_key TEXT NOT NULL,
+
+    used_count BIGINT NOT NULL CHECK (used_count > 0),
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+
+    PRIMARY KEY (paid_route_id, client_key)
+);
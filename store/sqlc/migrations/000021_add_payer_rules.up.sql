@@ -0,0 +1,31 @@
+-- payer_rules stores how payments from specific payer addresses are treated
+-- on an owner's routes.
+CREATE TABLE IF NOT EXISTS payer_rules (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- user_id is the owner of the rule.
+    user_id BIGINT NOT NULL REFERENCES users(id),
+
+    -- paid_route_id is the route the rule applies to, NULL applies it to all
+    -- of the owner's routes. A route's rule takes precedence.
+    paid_route_id BIGINT REFERENCES paid_routes(id) ON DELETE CASCADE,
+
+    -- payer_address is the lower case address the payment is made from.
+    payer_address TEXT NOT NULL,
+
+    -- treatment is 'free', 'discount' or 'block'.
+    treatment VARCHAR(20) NOT NULL CHECK (treatment IN ('free', 'discount', 'block')),
+
+    -- discount_bps is the discount of 'discount' rules in basis points.
+    discount_bps INTEGER NOT NULL DEFAULT 0 CHECK (discount_bps >= 0 AND discount_bps <= 10000),
+
+    -- label is an optional name for the payer.
+    label TEXT NOT NULL DEFAULT '',
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+-- A payer has a single rule per route and a single rule for all routes.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payer_rules_payer
+ON payer_rules (user_id, COALESCE(paid_route_id, 0), payer_address);
@@ -0,0 +1,2 @@
+ALTER TABLE purchases DROP COLUMN IF EXISTS pack_id;
+DROP TABLE IF EXISTS route_packs;
@@ -0,0 +1,28 @@
+-- route_packs stores the credit packs a route offers besides its own price
+-- and credits, e.g. 10 credits for $1 and 100 credits for $8.
+CREATE TABLE IF NOT EXISTS route_packs (
+    id BIGSERIAL PRIMARY KEY,
+
+    paid_route_id BIGINT NOT NULL REFERENCES paid_routes(id),
+
+    credits BIGINT NOT NULL CHECK (credits > 0),
+
+    -- price is in base units (USDC * 10^6).
+    price BIGINT NOT NULL CHECK (price > 0),
+
+    -- label is an optional name shown to buyers.
+    label TEXT NOT NULL DEFAULT '',
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL,
+    deleted_at TIMESTAMPTZ
+);
+
+-- Payments are matched to a pack by their amount, so prices are unique among
+-- a route's packs that are not deleted.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_route_packs_paid_route_id_price
+ON route_packs (paid_route_id, price) WHERE deleted_at IS NULL;
+
+-- pack_id is the pack a purchase bought, NULL for the route's own credits.
+ALTER TABLE purchases
+ADD COLUMN IF NOT EXISTS pack_id BIGINT REFERENCES route_packs(id);
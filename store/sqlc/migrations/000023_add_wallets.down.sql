@@ -0,0 +1,7 @@
+DELETE FROM payment_nonces WHERE wallet_topup_id IS NOT NULL;
+ALTER TABLE payment_nonces DROP CONSTRAINT IF EXISTS payment_nonces_single_use;
+ALTER TABLE payment_nonces DROP COLUMN IF EXISTS wallet_topup_id;
+ALTER TABLE payment_nonces ALTER COLUMN purchase_id SET NOT NULL;
+DROP TABLE IF EXISTS wallet_draws;
+DROP TABLE IF EXISTS wallet_topups;
+DROP TABLE IF EXISTS wallets;
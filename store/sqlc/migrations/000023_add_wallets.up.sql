@@ -0,0 +1,80 @@
+-- wallets stores the prepaid balances buyers hold with an owner, which any of
+-- the owner's routes can draw from without a payment per request.
+CREATE TABLE IF NOT EXISTS wallets (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- user_id is the owner the balance can be spent with.
+    user_id BIGINT NOT NULL REFERENCES users(id),
+
+    -- payer_address is the lower case address of the first top-up, which
+    -- signs wallet challenges.
+    payer_address TEXT NOT NULL,
+
+    -- token_hash is the SHA-256 of the wallet's access token.
+    token_hash TEXT NOT NULL UNIQUE,
+
+    -- is_test wallets are topped up on testnet and only pay for test routes.
+    is_test BOOLEAN NOT NULL,
+
+    -- balance is in base units (USDC * 10^6).
+    balance BIGINT NOT NULL DEFAULT 0 CHECK (balance >= 0),
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- A payer has a single wallet per owner and network.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wallets_payer
+ON wallets (user_id, payer_address, is_test);
+
+-- wallet_topups records the payments that add to a wallet's balance. Like
+-- purchases, they are recorded as pending before the payment is settled.
+CREATE TABLE IF NOT EXISTS wallet_topups (
+    id BIGSERIAL PRIMARY KEY,
+
+    wallet_id BIGINT NOT NULL REFERENCES wallets(id),
+
+    -- amount is in base units (USDC * 10^6).
+    amount BIGINT NOT NULL CHECK (amount > 0),
+
+    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'settled', 'failed')),
+    settle_error TEXT NOT NULL DEFAULT '',
+
+    payment_payload JSONB NOT NULL,
+    payment_requirements JSONB NOT NULL,
+    settle_response JSONB,
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_topups_wallet_id ON wallet_topups (wallet_id, created_at);
+
+-- wallet_draws records what each request paid from a wallet.
+CREATE TABLE IF NOT EXISTS wallet_draws (
+    id BIGSERIAL PRIMARY KEY,
+
+    wallet_id BIGINT NOT NULL REFERENCES wallets(id),
+    paid_route_id BIGINT NOT NULL REFERENCES paid_routes(id),
+
+    -- amount is in base units (USDC * 10^6).
+    amount BIGINT NOT NULL CHECK (amount >= 0),
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_draws_wallet_id ON wallet_draws (wallet_id, created_at);
+
+-- Top-up payments register their authorization like purchases do, so a
+-- payment can't both top up a wallet and buy a route.
+ALTER TABLE payment_nonces ALTER COLUMN purchase_id DROP NOT NULL;
+ALTER TABLE payment_nonces
+ADD COLUMN IF NOT EXISTS wallet_topup_id BIGINT REFERENCES wallet_topups(id) ON DELETE CASCADE;
+ALTER TABLE payment_nonces
+ADD CONSTRAINT payment_nonces_single_use CHECK ((purchase_id IS NULL) <> (wallet_topup_id IS NULL));
+
+CREATE INDEX IF NOT EXISTS idx_payment_nonces_wallet_topup_id
+ON payment_nonces (wallet_topup_id);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS bundle_routes;
+DROP TABLE IF EXISTS bundles;
+
+-- Bundle routes are kept for their purchases, so the original constraint is
+-- only enforced for new rows.
+ALTER TABLE paid_routes DROP CONSTRAINT IF EXISTS paid_routes_resource_type_check;
+ALTER TABLE paid_routes ADD CONSTRAINT paid_routes_resource_type_check
+CHECK (resource_type IN ('url', 'file')) NOT VALID;
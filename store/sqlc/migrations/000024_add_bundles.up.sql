@@ -0,0 +1,31 @@
+-- Bundles are paid routes with resource_type 'bundle' whose purchase pays for
+-- requests to a collection of the owner's other routes.
+ALTER TABLE paid_routes DROP CONSTRAINT IF EXISTS paid_routes_resource_type_check;
+ALTER TABLE paid_routes ADD CONSTRAINT paid_routes_resource_type_check
+CHECK (resource_type IN ('url', 'file', 'bundle'));
+
+-- bundles stores what a bundle's purchase grants besides its credits.
+CREATE TABLE IF NOT EXISTS bundles (
+    paid_route_id BIGINT PRIMARY KEY REFERENCES paid_routes(id),
+
+    -- access_seconds limits how long after it was bought a purchase gives
+    -- access to the bundle's routes, 0 means until its credits are used.
+    access_seconds BIGINT NOT NULL DEFAULT 0 CHECK (access_seconds >= 0),
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+-- bundle_routes stores the routes a bundle unlocks, in the order they are
+-- listed to buyers.
+CREATE TABLE IF NOT EXISTS bundle_routes (
+    bundle_route_id BIGINT NOT NULL REFERENCES bundles(paid_route_id),
+    paid_route_id BIGINT NOT NULL REFERENCES paid_routes(id),
+    position INTEGER NOT NULL,
+
+    PRIMARY KEY (bundle_route_id, paid_route_id)
+);
+
+-- Requests to a route look up the bundles it is part of.
+CREATE INDEX IF NOT EXISTS idx_bundle_routes_paid_route_id
+ON bundle_routes (paid_route_id);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS route_price_changes;
+
+ALTER TABLE paid_routes DROP CONSTRAINT IF EXISTS paid_routes_availability_check;
+
+ALTER TABLE paid_routes
+DROP COLUMN IF EXISTS sold_count,
+DROP COLUMN IF EXISTS max_sales,
+DROP COLUMN IF EXISTS available_until,
+DROP COLUMN IF EXISTS available_from;
@@ -0,0 +1,44 @@
+-- available_from and available_until limit when a route can be used, NULL
+-- means no limit. max_sales caps how many times the route is bought in total,
+-- by purchase or wallet draw, 0 means no limit. sold_count is how many times
+-- it was bought so far, failed purchases are not counted.
+ALTER TABLE paid_routes
+ADD COLUMN IF NOT EXISTS available_from TIMESTAMPTZ,
+ADD COLUMN IF NOT EXISTS available_until TIMESTAMPTZ,
+ADD COLUMN IF NOT EXISTS max_sales BIGINT NOT NULL DEFAULT 0 CHECK (max_sales >= 0),
+ADD COLUMN IF NOT EXISTS sold_count BIGINT NOT NULL DEFAULT 0 CHECK (sold_count >= 0);
+
+ALTER TABLE paid_routes ADD CONSTRAINT paid_routes_availability_check
+CHECK (available_from IS NULL OR available_until IS NULL OR available_from < available_until);
+
+UPDATE paid_routes SET sold_count = (
+    SELECT COUNT(*) FROM purchases
+    WHERE purchases.paid_route_id = paid_routes.id AND purchases.status <> 'failed'
+) + (
+    SELECT COUNT(*) FROM wallet_draws
+    WHERE wallet_draws.paid_route_id = paid_routes.id
+);
+
+-- route_price_changes stores the prices a route changes to at a given time.
+CREATE TABLE IF NOT EXISTS route_price_changes (
+    id BIGSERIAL PRIMARY KEY,
+
+    paid_route_id BIGINT NOT NULL REFERENCES paid_routes(id),
+
+    -- price is in base units (USDC * 10^6).
+    price BIGINT NOT NULL CHECK (price > 0),
+
+    effective_at TIMESTAMPTZ NOT NULL,
+    -- applied_at is set once the scheduler changed the route's price.
+    applied_at TIMESTAMPTZ,
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+-- The scheduler looks up the price changes that are due.
+CREATE INDEX IF NOT EXISTS idx_route_price_changes_due
+ON route_price_changes (effective_at) WHERE applied_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_route_price_changes_paid_route_id
+ON route_price_changes (paid_route_id);
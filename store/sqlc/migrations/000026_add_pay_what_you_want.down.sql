@@ -0,0 +1,2 @@
+ALTER TABLE paid_routes
+DROP COLUMN IF EXISTS pay_what_you_want;
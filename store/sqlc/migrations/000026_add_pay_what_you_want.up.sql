@@ -0,0 +1,5 @@
+-- pay_what_you_want lets buyers choose the amount they pay for a route. The
+-- route's price is the suggested amount, min_price the least accepted and
+-- max_price, if not 0, the most.
+ALTER TABLE paid_routes
+ADD COLUMN IF NOT EXISTS pay_what_you_want BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,11 @@
+DELETE FROM purchase_payouts WHERE role = 'affiliate';
+ALTER TABLE purchase_payouts DROP CONSTRAINT IF EXISTS purchase_payouts_role_check;
+ALTER TABLE purchase_payouts ADD CONSTRAINT purchase_payouts_role_check
+CHECK (role IN ('owner', 'partner', 'platform'));
+
+DROP INDEX IF EXISTS idx_purchases_affiliate_id;
+ALTER TABLE purchases
+DROP COLUMN IF EXISTS affiliate_commission,
+DROP COLUMN IF EXISTS affiliate_id;
+
+DROP TABLE IF EXISTS affiliates;
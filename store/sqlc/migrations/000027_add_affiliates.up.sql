@@ -0,0 +1,51 @@
+-- affiliates stores the partners an owner pays commission for the sales they
+-- refer to the owner's routes.
+CREATE TABLE IF NOT EXISTS affiliates (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- user_id is the owner the affiliate refers buyers to.
+    user_id BIGINT NOT NULL REFERENCES users(id),
+
+    -- paid_route_id is the route the affiliate earns commission on, NULL on
+    -- all of the owner's routes.
+    paid_route_id BIGINT REFERENCES paid_routes(id),
+
+    -- code is the lower case code referral links pass as ?ref=.
+    code TEXT NOT NULL,
+
+    -- payout_address is the lower case address the commission is owed to.
+    payout_address TEXT NOT NULL,
+
+    label TEXT NOT NULL DEFAULT '',
+
+    -- commission_bps is the share of each referred purchase in basis points.
+    commission_bps INTEGER NOT NULL CHECK (commission_bps > 0 AND commission_bps <= 10000),
+
+    -- token_hash is the SHA-256 of the token the affiliate views its
+    -- earnings with.
+    token_hash TEXT NOT NULL UNIQUE,
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    deleted_at TIMESTAMPTZ
+);
+
+-- Codes are unique among an owner's affiliates that are not deleted.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_affiliates_user_id_code
+ON affiliates (user_id, code) WHERE deleted_at IS NULL;
+
+-- affiliate_id is the affiliate that referred the purchase and
+-- affiliate_commission what it is owed for it (USDC * 10^6), set when the
+-- purchase's payouts are recorded.
+ALTER TABLE purchases
+ADD COLUMN IF NOT EXISTS affiliate_id BIGINT REFERENCES affiliates(id),
+ADD COLUMN IF NOT EXISTS affiliate_commission BIGINT NOT NULL DEFAULT 0 CHECK (affiliate_commission >= 0);
+
+CREATE INDEX IF NOT EXISTS idx_purchases_affiliate_id
+ON purchases (affiliate_id) WHERE affiliate_id IS NOT NULL;
+
+-- Affiliates are paid out of purchases like partners.
+ALTER TABLE purchase_payouts DROP CONSTRAINT IF EXISTS purchase_payouts_role_check;
+ALTER TABLE purchase_payouts ADD CONSTRAINT purchase_payouts_role_check
+CHECK (role IN ('owner', 'partner', 'platform', 'affiliate'));
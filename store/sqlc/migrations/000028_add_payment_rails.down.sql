@@ -0,0 +1,2 @@
+ALTER TABLE paid_routes
+DROP COLUMN IF EXISTS payment_rails;
@@ -0,0 +1,4 @@
+-- payment_rails lists the payment rails a route accepts besides x402, such
+-- as 'l402' for Lightning.
+ALTER TABLE paid_routes
+ADD COLUMN IF NOT EXISTS payment_rails TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_paid_routes_discoverable;
+ALTER TABLE paid_routes
+DROP COLUMN IF EXISTS mime_type,
+DROP COLUMN IF EXISTS discoverable;
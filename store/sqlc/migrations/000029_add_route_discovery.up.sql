@@ -0,0 +1,8 @@
+-- discoverable lists the route on the public discovery endpoint, and
+-- mime_type is the media type of what it responds with, '' if unknown.
+ALTER TABLE paid_routes
+ADD COLUMN IF NOT EXISTS discoverable BOOLEAN NOT NULL DEFAULT false,
+ADD COLUMN IF NOT EXISTS mime_type TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_paid_routes_discoverable
+ON paid_routes (created_at DESC) WHERE discoverable = true AND deleted_at IS NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE paid_routes
+DROP COLUMN IF EXISTS response_schema,
+DROP COLUMN IF EXISTS query_schema,
+DROP COLUMN IF EXISTS body_schema;
@@ -0,0 +1,6 @@
+-- JSON Schemas of the request body, query parameters and response of the
+-- route, NULL if it declares none.
+ALTER TABLE paid_routes
+ADD COLUMN IF NOT EXISTS body_schema JSONB,
+ADD COLUMN IF NOT EXISTS query_schema JSONB,
+ADD COLUMN IF NOT EXISTS response_schema JSONB;
@@ -0,0 +1,33 @@
+-- settlement_alerts is a table that stores the settlement problems found by
+-- the reconciler, for the owner of the purchased route to see.
+CREATE TABLE IF NOT EXISTS settlement_alerts (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- user_id is the owner of the purchased route.
+    user_id BIGINT NOT NULL REFERENCES users(id),
+
+    -- purchase_id is the purchase whose settlement has a problem.
+    purchase_id BIGINT NOT NULL REFERENCES purchases(id),
+
+    -- status is the settlement status raising the alert, 'failed',
+    -- 'reorged' or 'mismatch'.
+    status VARCHAR(20) NOT NULL CHECK (status IN ('failed', 'reorged', 'mismatch')),
+
+    -- reason describes the problem.
+    reason TEXT NOT NULL DEFAULT '',
+
+    -- network and tx_hash are the settlement the facilitator reported.
+    network TEXT NOT NULL,
+    tx_hash TEXT NOT NULL,
+
+    -- dismissed_at is when the owner dismissed the alert, NULL while it is
+    -- shown.
+    dismissed_at TIMESTAMPTZ,
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+-- Create index on user_id for the alerts shown on the dashboard
+CREATE INDEX IF NOT EXISTS idx_settlement_alerts_user_id
+    ON settlement_alerts (user_id, created_at DESC) WHERE dismissed_at IS NULL;
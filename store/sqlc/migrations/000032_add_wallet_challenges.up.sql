@@ -0,0 +1,17 @@
+-- wallet_challenges records the signed wallet challenges that were used, so
+-- each is accepted only once while it is recent enough.
+CREATE TABLE IF NOT EXISTS wallet_challenges (
+    wallet_id BIGINT NOT NULL REFERENCES wallets(id),
+
+    -- nonce is the payer's nonce of the challenge.
+    nonce TEXT NOT NULL,
+
+    -- expires_at is when the challenge is too old to be accepted, after
+    -- which it no longer needs to be recorded.
+    expires_at TIMESTAMPTZ NOT NULL,
+
+    -- Standard timestamp fields
+    created_at TIMESTAMPTZ NOT NULL,
+
+    PRIMARY KEY (wallet_id, nonce)
+);
@@ -0,0 +1,5 @@
+UPDATE paid_routes SET
+    is_enabled = false,
+    updated_at = NOW()
+WHERE is_enabled = true AND deleted_at IS NULL
+    AND available_from > NOW();
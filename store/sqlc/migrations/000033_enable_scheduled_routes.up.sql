@@ -0,0 +1,9 @@
+-- Routes are no longer created disabled until their availability window
+-- opens, the window is checked on every request instead. Enable the routes
+-- that were waiting for the scheduler to do so.
+UPDATE paid_routes SET
+    is_enabled = true,
+    updated_at = NOW()
+WHERE is_enabled = false AND deleted_at IS NULL
+    AND (available_from IS NOT NULL OR available_until IS NOT NULL)
+    AND (available_until IS NULL OR available_until > NOW());
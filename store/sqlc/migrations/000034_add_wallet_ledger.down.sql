@@ -0,0 +1,25 @@
+-- Ledger rows are immutable, so the wallet postings can only be removed with
+-- the triggers guarding them disabled.
+ALTER TABLE ledger_entries DISABLE TRIGGER ledger_entries_immutable;
+ALTER TABLE ledger_transactions DISABLE TRIGGER ledger_transactions_immutable;
+
+DELETE FROM ledger_entries
+WHERE transaction_id IN (
+    SELECT id FROM ledger_transactions
+    WHERE kind IN ('top_up', 'wallet_draw') OR reference LIKE 'credit\_use:%:wallet\_draw'
+);
+DELETE FROM ledger_transactions
+WHERE kind IN ('top_up', 'wallet_draw') OR reference LIKE 'credit\_use:%:wallet\_draw';
+
+ALTER TABLE ledger_entries ENABLE TRIGGER ledger_entries_immutable;
+ALTER TABLE ledger_transactions ENABLE TRIGGER ledger_transactions_immutable;
+
+DELETE FROM ledger_accounts WHERE kind = 'wallet';
+
+ALTER TABLE ledger_transactions DROP CONSTRAINT IF EXISTS ledger_transactions_kind_check;
+ALTER TABLE ledger_transactions ADD CONSTRAINT ledger_transactions_kind_check
+    CHECK (kind IN ('purchase', 'fee', 'split', 'credit_use', 'usage', 'refund'));
+
+ALTER TABLE ledger_accounts DROP CONSTRAINT IF EXISTS ledger_accounts_kind_check;
+ALTER TABLE ledger_accounts ADD CONSTRAINT ledger_accounts_kind_check
+    CHECK (kind IN ('payer', 'route', 'user', 'payee', 'platform'));
@@ -0,0 +1,93 @@
+-- Wallet balances are posted to the ledger. A wallet account holds what its
+-- payer topped up until a request draws it for a route.
+ALTER TABLE ledger_accounts DROP CONSTRAINT IF EXISTS ledger_accounts_kind_check;
+ALTER TABLE ledger_accounts ADD CONSTRAINT ledger_accounts_kind_check
+    CHECK (kind IN ('payer', 'route', 'user', 'payee', 'platform', 'wallet'));
+
+ALTER TABLE ledger_transactions DROP CONSTRAINT IF EXISTS ledger_transactions_kind_check;
+ALTER TABLE ledger_transactions ADD CONSTRAINT ledger_transactions_kind_check
+    CHECK (kind IN ('purchase', 'fee', 'split', 'credit_use', 'usage', 'refund', 'top_up', 'wallet_draw'));
+
+-- Backfill the top-ups and draws made before wallets were posted. A draw is
+-- earned by the route's owner right away.
+INSERT INTO ledger_accounts (kind, reference, created_at)
+SELECT 'wallet', w.id::TEXT, NOW()
+FROM wallets w
+ON CONFLICT (kind, reference) DO NOTHING;
+
+INSERT INTO ledger_accounts (kind, reference, created_at)
+SELECT DISTINCT 'payer', w.payer_address, NOW()
+FROM wallet_topups wt
+JOIN wallets w ON wt.wallet_id = w.id
+WHERE wt.status = 'settled'
+ON CONFLICT (kind, reference) DO NOTHING;
+
+INSERT INTO ledger_accounts (kind, reference, user_id, created_at)
+SELECT DISTINCT 'route', wd.paid_route_id::TEXT, w.user_id, NOW()
+FROM wallet_draws wd
+JOIN wallets w ON wd.wallet_id = w.id
+WHERE wd.amount > 0
+ON CONFLICT (kind, reference) DO NOTHING;
+
+INSERT INTO ledger_accounts (kind, reference, user_id, created_at)
+SELECT DISTINCT 'user', w.user_id::TEXT, w.user_id, NOW()
+FROM wallet_draws wd
+JOIN wallets w ON wd.wallet_id = w.id
+WHERE wd.amount > 0
+ON CONFLICT (kind, reference) DO NOTHING;
+
+INSERT INTO ledger_transactions (reference, kind, is_test, created_at)
+SELECT 'top_up:' || wt.id, 'top_up', w.is_test, wt.updated_at
+FROM wallet_topups wt
+JOIN wallets w ON wt.wallet_id = w.id
+WHERE wt.status = 'settled';
+
+INSERT INTO ledger_transactions (reference, kind, is_test, created_at)
+SELECT 'wallet_draw:' || wd.id, 'wallet_draw', w.is_test, wd.created_at
+FROM wallet_draws wd
+JOIN wallets w ON wd.wallet_id = w.id
+WHERE wd.amount > 0;
+
+INSERT INTO ledger_transactions (reference, kind, is_test, created_at)
+SELECT 'credit_use:' || wd.id || ':wallet_draw', 'credit_use', w.is_test, wd.created_at
+FROM wallet_draws wd
+JOIN wallets w ON wd.wallet_id = w.id
+WHERE wd.amount > 0;
+
+INSERT INTO ledger_entries (transaction_id, account_id, amount, created_at)
+SELECT t.id, a.id, -wt.amount, t.created_at
+FROM wallet_topups wt
+JOIN wallets w ON wt.wallet_id = w.id
+JOIN ledger_transactions t ON t.reference = 'top_up:' || wt.id
+JOIN ledger_accounts a ON a.kind = 'payer' AND a.reference = w.payer_address;
+
+INSERT INTO ledger_entries (transaction_id, account_id, amount, created_at)
+SELECT t.id, a.id, wt.amount, t.created_at
+FROM wallet_topups wt
+JOIN ledger_transactions t ON t.reference = 'top_up:' || wt.id
+JOIN ledger_accounts a ON a.kind = 'wallet' AND a.reference = wt.wallet_id::TEXT;
+
+INSERT INTO ledger_entries (transaction_id, account_id, amount, created_at)
+SELECT t.id, a.id, -wd.amount, t.created_at
+FROM wallet_draws wd
+JOIN ledger_transactions t ON t.reference = 'wallet_draw:' || wd.id
+JOIN ledger_accounts a ON a.kind = 'wallet' AND a.reference = wd.wallet_id::TEXT;
+
+INSERT INTO ledger_entries (transaction_id, account_id, amount, created_at)
+SELECT t.id, a.id, wd.amount, t.created_at
+FROM wallet_draws wd
+JOIN ledger_transactions t ON t.reference = 'wallet_draw:' || wd.id
+JOIN ledger_accounts a ON a.kind = 'route' AND a.reference = wd.paid_route_id::TEXT;
+
+INSERT INTO ledger_entries (transaction_id, account_id, amount, created_at)
+SELECT t.id, a.id, -wd.amount, t.created_at
+FROM wallet_draws wd
+JOIN ledger_transactions t ON t.reference = 'credit_use:' || wd.id || ':wallet_draw'
+JOIN ledger_accounts a ON a.kind = 'route' AND a.reference = wd.paid_route_id::TEXT;
+
+INSERT INTO ledger_entries (transaction_id, account_id, amount, created_at)
+SELECT t.id, a.id, wd.amount, t.created_at
+FROM wallet_draws wd
+JOIN wallets w ON wd.wallet_id = w.id
+JOIN ledger_transactions t ON t.reference = 'credit_use:' || wd.id || ':wallet_draw'
+JOIN ledger_accounts a ON a.kind = 'user' AND a.reference = w.user_id::TEXT;
@@ -10,12 +10,101 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type Affiliate struct {
+	ID            int64
+	UserID        int64
+	PaidRouteID   pgtype.Int8
+	Code          string
+	PayoutAddress string
+	Label         string
+	CommissionBps int32
+	TokenHash     string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     pgtype.Timestamptz
+}
+
+type Bundle struct {
+	PaidRouteID   int64
+	AccessSeconds int64
+	CreatedAt     time.Time
+}
+
+type BundleRoute struct {
+	BundleRouteID int64
+	PaidRouteID   int64
+	Position      int32
+}
+
+type Coupon struct {
+	ID              int64
+	UserID          int64
+	PaidRouteID     pgtype.Int8
+	Code            string
+	DiscountType    string
+	DiscountValue   int64
+	MaxRedemptions  int64
+	RedemptionCount int64
+	ExpiresAt       pgtype.Timestamptz
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       pgtype.Timestamptz
+}
+
+type CouponRedemption struct {
+	ID          int64
+	CouponID    int64
+	PaidRouteID int64
+	PurchaseID  pgtype.Int8
+	Discount    int64
+	CreatedAt   time.Time
+}
+
+type IdempotencyKey struct {
+	ID             int64
+	PaidRouteID    int64
+	Scope          string
+	IdempotencyKey string
+	Fingerprint    string
+	ResponseStatus int32
+	ResponseHeader []byte
+	ResponseBody   []byte
+	ExpiresAt      time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type LedgerAccount struct {
+	ID        int64
+	Kind      string
+	Reference string
+	UserID    pgtype.Int8
+	CreatedAt time.Time
+}
+
+type LedgerEntry struct {
+	ID            int64
+	TransactionID int64
+	AccountID     int64
+	Amount        int64
+	CreatedAt     time.Time
+}
+
+type LedgerTransaction struct {
+	ID         int64
+	Reference  string
+	Kind       string
+	PurchaseID pgtype.Int8
+	IsTest     bool
+	CreatedAt  time.Time
+}
+
 type PaidRoute struct {
 	ID                     int64
 	ShortCode              string
 	TargetUrl              string
 	Method                 string
-	Price                  int32
+	Price                  int64
 	IsTest                 bool
 	UserID                 int64
 	IsEnabled              bool
@@ -33,25 +122,170 @@ type PaidRoute struct {
 	Title                  pgtype.Text
 	Description            pgtype.Text
 	PaymentProtocolVersion int16
+	CacheEnabled           bool
+	CacheTtlSeconds        int32
+	CacheVaryHeaders       string
+	LbStrategy             string
+	HealthCheckPath        string
+	StreamMaxSeconds       int32
+	StreamCreditSeconds    int32
+	BillingMode            string
+	UnitPrice              int64
+	QuoteUrl               string
+	MinPrice               int64
+	MaxPrice               int64
+	AvailableFrom          pgtype.Timestamptz
+	AvailableUntil         pgtype.Timestamptz
+	MaxSales               int64
+	SoldCount              int64
+	PayWhatYouWant         bool
+	PaymentRails           []string
+	Discoverable           bool
+	MimeType               string
+	BodySchema             []byte
+	QuerySchema            []byte
+	ResponseSchema         []byte
+}
+
+type PayerRule struct {
+	ID           int64
+	UserID       int64
+	PaidRouteID  pgtype.Int8
+	PayerAddress string
+	Treatment    string
+	DiscountBps  int32
+	Label        string
+	CreatedAt    time.Time
+}
+
+type PaymentNonce struct {
+	ID            int64
+	Network       string
+	Payer         string
+	Nonce         string
+	PurchaseID    pgtype.Int8
+	CreatedAt     time.Time
+	WalletTopupID pgtype.Int8
 }
 
 type Purchase struct {
-	ID               int64
-	ShortCode        string
-	TargetUrl        string
-	Method           string
-	Price            int32
-	IsTest           bool
-	PaymentPayload   []byte
-	SettleResponse   []byte
-	PaidRouteID      int64
-	PaidToAddress    string
-	CreatedAt        time.Time
-	UpdatedAt        time.Time
-	Type             string
-	CreditsAvailable int32
-	CreditsUsed      int32
-	PaymentHeader    pgtype.Text
+	ID                  int64
+	ShortCode           string
+	TargetUrl           string
+	Method              string
+	Price               int64
+	IsTest              bool
+	PaymentPayload      []byte
+	SettleResponse      []byte
+	PaidRouteID         int64
+	PaidToAddress       string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Type                string
+	CreditsAvailable    int32
+	CreditsUsed         int32
+	PaymentHeader       pgtype.Text
+	UsageUnits          int64
+	AmountCharged       int64
+	Status              string
+	PaymentRequirements []byte
+	SettleError         string
+	PackID              pgtype.Int8
+	AffiliateID         pgtype.Int8
+	AffiliateCommission int64
+}
+
+type PurchasePayout struct {
+	ID           int64
+	PurchaseID   int64
+	PaidRouteID  int64
+	PayeeAddress string
+	Role         string
+	ShareBps     int32
+	Amount       int64
+	CreatedAt    time.Time
+}
+
+type RoutePack struct {
+	ID          int64
+	PaidRouteID int64
+	Credits     int64
+	Price       int64
+	Label       string
+	CreatedAt   time.Time
+	DeletedAt   pgtype.Timestamptz
+}
+
+type RoutePriceChange struct {
+	ID          int64
+	PaidRouteID int64
+	Price       int64
+	EffectiveAt time.Time
+	AppliedAt   pgtype.Timestamptz
+	CreatedAt   time.Time
+}
+
+type RouteSplit struct {
+	ID           int64
+	PaidRouteID  int64
+	PayeeAddress string
+	Label        string
+	ShareBps     int32
+	CreatedAt    time.Time
+}
+
+type RouteTarget struct {
+	ID                  int64
+	PaidRouteID         int64
+	TargetUrl           string
+	Weight              int32
+	IsHealthy           bool
+	ConsecutiveFailures int32
+	LastError           string
+	LastCheckedAt       pgtype.Timestamptz
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+type RouteTrial struct {
+	PaidRouteID  int64
+	FreeRequests int64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type SettlementAlert struct {
+	ID          int64
+	UserID      int64
+	PurchaseID  int64
+	Status      string
+	Reason      string
+	Network     string
+	TxHash      string
+	DismissedAt pgtype.Timestamptz
+	CreatedAt   time.Time
+}
+
+type SettlementCheck struct {
+	PurchaseID    int64
+	Network       string
+	TxHash        string
+	Status        string
+	BlockNumber   int64
+	BlockHash     string
+	Confirmations int64
+	LastError     string
+	CheckedAt     pgtype.Timestamptz
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type TrialUse struct {
+	PaidRouteID int64
+	ClientKey   string
+	UsedCount   int64
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
 }
 
 type User struct {
@@ -64,3 +298,42 @@ type User struct {
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 }
+
+type Wallet struct {
+	ID           int64
+	UserID       int64
+	PayerAddress string
+	TokenHash    string
+	IsTest       bool
+	Balance      int64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type WalletChallenge struct {
+	WalletID  int64
+	Nonce     string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+type WalletDraw struct {
+	ID          int64
+	WalletID    int64
+	PaidRouteID int64
+	Amount      int64
+	CreatedAt   time.Time
+}
+
+type WalletTopup struct {
+	ID                  int64
+	WalletID            int64
+	Amount              int64
+	Status              string
+	SettleError         string
+	PaymentPayload      []byte
+	PaymentRequirements []byte
+	SettleResponse      []byte
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
@@ -27,24 +27,68 @@ func (q *Queries) CheckShortCodeExists(ctx context.Context, shortCode string) (b
 	return exists, err
 }
 
+const countDiscoverablePaidRoutes = `-- name: CountDiscoverablePaidRoutes :one
+SELECT COUNT(*) FROM paid_routes
+JOIN users ON users.id = paid_routes.user_id
+WHERE paid_routes.discoverable = true AND paid_routes.is_enabled = true
+    AND paid_routes.deleted_at IS NULL
+    AND ($1::TEXT = '' OR paid_routes.method = $1::TEXT)
+    AND ($2::BOOLEAN IS NULL OR paid_routes.is_test = $2::BOOLEAN)
+    AND ($3::BIGINT = 0 OR paid_routes.price <= $3::BIGINT)
+    AND ($4::TEXT = '' OR LOWER(COALESCE(NULLIF(users.payment_address, ''), $5::TEXT)) = LOWER($4::TEXT))
+    AND ($6::TEXT = '' OR paid_routes.title ILIKE '%' || $6::TEXT || '%'
+        OR paid_routes.description ILIKE '%' || $6::TEXT || '%')
+`
+
+type CountDiscoverablePaidRoutesParams struct {
+	Method       string
+	IsTest       pgtype.Bool
+	MaxPrice     int64
+	PayTo        string
+	DefaultPayTo string
+	Search       string
+}
+
+// CountDiscoverablePaidRoutes counts the routes ListDiscoverablePaidRoutes
+// pages through.
+func (q *Queries) CountDiscoverablePaidRoutes(ctx context.Context, arg CountDiscoverablePaidRoutesParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countDiscoverablePaidRoutes,
+		arg.Method,
+		arg.IsTest,
+		arg.MaxPrice,
+		arg.PayTo,
+		arg.DefaultPayTo,
+		arg.Search,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createPaidRoute = `-- name: CreatePaidRoute :one
 INSERT INTO paid_routes (
     short_code, target_url, method, price, is_test,
     user_id, is_enabled, attempt_count, payment_count, access_count,
     created_at, updated_at,
     type, credits, payment_protocol_version, resource_type, original_filename, cover_url,
-    title, description
+    title, description, cache_enabled, cache_ttl_seconds, cache_vary_headers,
+    stream_max_seconds, stream_credit_seconds, billing_mode, unit_price,
+    quote_url, min_price, max_price, available_from, available_until, max_sales,
+    pay_what_you_want, payment_rails, discoverable, mime_type,
+    body_schema, query_schema, response_schema
 ) VALUES (
     $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12,
-    $13, $14, $15, $16, $17, $18, $19, $20
-) RETURNING id, short_code, target_url, method, price, is_test, user_id, is_enabled, attempt_count, payment_count, access_count, created_at, updated_at, deleted_at, type, credits, resource_type, original_filename, cover_url, title, description, payment_protocol_version
+    $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23,
+    $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34,
+    $35, $36, $37, $38, $39, $40
+) RETURNING id, short_code, target_url, method, price, is_test, user_id, is_enabled, attempt_count, payment_count, access_count, created_at, updated_at, deleted_at, type, credits, resource_type, original_filename, cover_url, title, description, payment_protocol_version, cache_enabled, cache_ttl_seconds, cache_vary_headers, lb_strategy, health_check_path, stream_max_seconds, stream_credit_seconds, billing_mode, unit_price, quote_url, min_price, max_price, available_from, available_until, max_sales, sold_count, pay_what_you_want, payment_rails, discoverable, mime_type, body_schema, query_schema, response_schema
 `
 
 type CreatePaidRouteParams struct {
 	ShortCode              string
 	TargetUrl              string
 	Method                 string
-	Price                  int32
+	Price                  int64
 	IsTest                 bool
 	UserID                 int64
 	IsEnabled              bool
@@ -61,6 +105,26 @@ type CreatePaidRouteParams struct {
 	CoverUrl               pgtype.Text
 	Title                  pgtype.Text
 	Description            pgtype.Text
+	CacheEnabled           bool
+	CacheTtlSeconds        int32
+	CacheVaryHeaders       string
+	StreamMaxSeconds       int32
+	StreamCreditSeconds    int32
+	BillingMode            string
+	UnitPrice              int64
+	QuoteUrl               string
+	MinPrice               int64
+	MaxPrice               int64
+	AvailableFrom          pgtype.Timestamptz
+	AvailableUntil         pgtype.Timestamptz
+	MaxSales               int64
+	PayWhatYouWant         bool
+	PaymentRails           []string
+	Discoverable           bool
+	MimeType               string
+	BodySchema             []byte
+	QuerySchema            []byte
+	ResponseSchema         []byte
 }
 
 // CreatePaidRoute creates a new paid route.
@@ -86,6 +150,26 @@ func (q *Queries) CreatePaidRoute(ctx context.Context, arg CreatePaidRouteParams
 		arg.CoverUrl,
 		arg.Title,
 		arg.Description,
+		arg.CacheEnabled,
+		arg.CacheTtlSeconds,
+		arg.CacheVaryHeaders,
+		arg.StreamMaxSeconds,
+		arg.StreamCreditSeconds,
+		arg.BillingMode,
+		arg.UnitPrice,
+		arg.QuoteUrl,
+		arg.MinPrice,
+		arg.MaxPrice,
+		arg.AvailableFrom,
+		arg.AvailableUntil,
+		arg.MaxSales,
+		arg.PayWhatYouWant,
+		arg.PaymentRails,
+		arg.Discoverable,
+		arg.MimeType,
+		arg.BodySchema,
+		arg.QuerySchema,
+		arg.ResponseSchema,
 	)
 	var i PaidRoute
 	err := row.Scan(
@@ -111,6 +195,29 @@ func (q *Queries) CreatePaidRoute(ctx context.Context, arg CreatePaidRouteParams
 		&i.Title,
 		&i.Description,
 		&i.PaymentProtocolVersion,
+		&i.CacheEnabled,
+		&i.CacheTtlSeconds,
+		&i.CacheVaryHeaders,
+		&i.LbStrategy,
+		&i.HealthCheckPath,
+		&i.StreamMaxSeconds,
+		&i.StreamCreditSeconds,
+		&i.BillingMode,
+		&i.UnitPrice,
+		&i.QuoteUrl,
+		&i.MinPrice,
+		&i.MaxPrice,
+		&i.AvailableFrom,
+		&i.AvailableUntil,
+		&i.MaxSales,
+		&i.SoldCount,
+		&i.PayWhatYouWant,
+		&i.PaymentRails,
+		&i.Discoverable,
+		&i.MimeType,
+		&i.BodySchema,
+		&i.QuerySchema,
+		&i.ResponseSchema,
 	)
 	return i, err
 }
@@ -133,8 +240,26 @@ func (q *Queries) DeletePaidRoute(ctx context.Context, arg DeletePaidRouteParams
 	return err
 }
 
+const disableEndedPaidRoutes = `-- name: DisableEndedPaidRoutes :execrows
+UPDATE paid_routes SET
+    is_enabled = false,
+    updated_at = $1
+WHERE is_enabled = true AND deleted_at IS NULL
+    AND available_until <= $1
+`
+
+// DisableEndedPaidRoutes disables the enabled routes whose availability
+// window closed.
+func (q *Queries) DisableEndedPaidRoutes(ctx context.Context, updatedAt time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, disableEndedPaidRoutes, updatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getEnabledPaidRouteByShortCode = `-- name: GetEnabledPaidRouteByShortCode :one
-SELECT id, short_code, target_url, method, price, is_test, user_id, is_enabled, attempt_count, payment_count, access_count, created_at, updated_at, deleted_at, type, credits, resource_type, original_filename, cover_url, title, description, payment_protocol_version FROM paid_routes
+SELECT id, short_code, target_url, method, price, is_test, user_id, is_enabled, attempt_count, payment_count, access_count, created_at, updated_at, deleted_at, type, credits, resource_type, original_filename, cover_url, title, description, payment_protocol_version, cache_enabled, cache_ttl_seconds, cache_vary_headers, lb_strategy, health_check_path, stream_max_seconds, stream_credit_seconds, billing_mode, unit_price, quote_url, min_price, max_price, available_from, available_until, max_sales, sold_count, pay_what_you_want, payment_rails, discoverable, mime_type, body_schema, query_schema, response_schema FROM paid_routes
 WHERE short_code = $1 AND is_enabled = true AND deleted_at IS NULL
 `
 
@@ -165,12 +290,35 @@ func (q *Queries) GetEnabledPaidRouteByShortCode(ctx context.Context, shortCode
 		&i.Title,
 		&i.Description,
 		&i.PaymentProtocolVersion,
+		&i.CacheEnabled,
+		&i.CacheTtlSeconds,
+		&i.CacheVaryHeaders,
+		&i.LbStrategy,
+		&i.HealthCheckPath,
+		&i.StreamMaxSeconds,
+		&i.StreamCreditSeconds,
+		&i.BillingMode,
+		&i.UnitPrice,
+		&i.QuoteUrl,
+		&i.MinPrice,
+		&i.MaxPrice,
+		&i.AvailableFrom,
+		&i.AvailableUntil,
+		&i.MaxSales,
+		&i.SoldCount,
+		&i.PayWhatYouWant,
+		&i.PaymentRails,
+		&i.Discoverable,
+		&i.MimeType,
+		&i.BodySchema,
+		&i.QuerySchema,
+		&i.ResponseSchema,
 	)
 	return i, err
 }
 
 const getPaidRouteByID = `-- name: GetPaidRouteByID :one
-SELECT id, short_code, target_url, method, price, is_test, user_id, is_enabled, attempt_count, payment_count, access_count, created_at, updated_at, deleted_at, type, credits, resource_type, original_filename, cover_url, title, description, payment_protocol_version FROM paid_routes
+SELECT id, short_code, target_url, method, price, is_test, user_id, is_enabled, attempt_count, payment_count, access_count, created_at, updated_at, deleted_at, type, credits, resource_type, original_filename, cover_url, title, description, payment_protocol_version, cache_enabled, cache_ttl_seconds, cache_vary_headers, lb_strategy, health_check_path, stream_max_seconds, stream_credit_seconds, billing_mode, unit_price, quote_url, min_price, max_price, available_from, available_until, max_sales, sold_count, pay_what_you_want, payment_rails, discoverable, mime_type, body_schema, query_schema, response_schema FROM paid_routes
 WHERE id = $1 AND deleted_at IS NULL
 `
 
@@ -201,12 +349,35 @@ func (q *Queries) GetPaidRouteByID(ctx context.Context, id int64) (PaidRoute, er
 		&i.Title,
 		&i.Description,
 		&i.PaymentProtocolVersion,
+		&i.CacheEnabled,
+		&i.CacheTtlSeconds,
+		&i.CacheVaryHeaders,
+		&i.LbStrategy,
+		&i.HealthCheckPath,
+		&i.StreamMaxSeconds,
+		&i.StreamCreditSeconds,
+		&i.BillingMode,
+		&i.UnitPrice,
+		&i.QuoteUrl,
+		&i.MinPrice,
+		&i.MaxPrice,
+		&i.AvailableFrom,
+		&i.AvailableUntil,
+		&i.MaxSales,
+		&i.SoldCount,
+		&i.PayWhatYouWant,
+		&i.PaymentRails,
+		&i.Discoverable,
+		&i.MimeType,
+		&i.BodySchema,
+		&i.QuerySchema,
+		&i.ResponseSchema,
 	)
 	return i, err
 }
 
 const getPaidRouteByShortCode = `-- name: GetPaidRouteByShortCode :one
-SELECT id, short_code, target_url, method, price, is_test, user_id, is_enabled, attempt_count, payment_count, access_count, created_at, updated_at, deleted_at, type, credits, resource_type, original_filename, cover_url, title, description, payment_protocol_version FROM paid_routes
+SELECT id, short_code, target_url, method, price, is_test, user_id, is_enabled, attempt_count, payment_count, access_count, created_at, updated_at, deleted_at, type, credits, resource_type, original_filename, cover_url, title, description, payment_protocol_version, cache_enabled, cache_ttl_seconds, cache_vary_headers, lb_strategy, health_check_path, stream_max_seconds, stream_credit_seconds, billing_mode, unit_price, quote_url, min_price, max_price, available_from, available_until, max_sales, sold_count, pay_what_you_want, payment_rails, discoverable, mime_type, body_schema, query_schema, response_schema FROM paid_routes
 WHERE short_code = $1 AND deleted_at IS NULL
 `
 
@@ -237,6 +408,29 @@ func (q *Queries) GetPaidRouteByShortCode(ctx context.Context, shortCode string)
 		&i.Title,
 		&i.Description,
 		&i.PaymentProtocolVersion,
+		&i.CacheEnabled,
+		&i.CacheTtlSeconds,
+		&i.CacheVaryHeaders,
+		&i.LbStrategy,
+		&i.HealthCheckPath,
+		&i.StreamMaxSeconds,
+		&i.StreamCreditSeconds,
+		&i.BillingMode,
+		&i.UnitPrice,
+		&i.QuoteUrl,
+		&i.MinPrice,
+		&i.MaxPrice,
+		&i.AvailableFrom,
+		&i.AvailableUntil,
+		&i.MaxSales,
+		&i.SoldCount,
+		&i.PayWhatYouWant,
+		&i.PaymentRails,
+		&i.Discoverable,
+		&i.MimeType,
+		&i.BodySchema,
+		&i.QuerySchema,
+		&i.ResponseSchema,
 	)
 	return i, err
 }
@@ -295,8 +489,112 @@ func (q *Queries) IncrementPaymentCount(ctx context.Context, arg IncrementPaymen
 	return err
 }
 
+const listDiscoverablePaidRoutes = `-- name: ListDiscoverablePaidRoutes :many
+SELECT paid_routes.id, paid_routes.short_code, paid_routes.target_url, paid_routes.method, paid_routes.price, paid_routes.is_test, paid_routes.user_id, paid_routes.is_enabled, paid_routes.attempt_count, paid_routes.payment_count, paid_routes.access_count, paid_routes.created_at, paid_routes.updated_at, paid_routes.deleted_at, paid_routes.type, paid_routes.credits, paid_routes.resource_type, paid_routes.original_filename, paid_routes.cover_url, paid_routes.title, paid_routes.description, paid_routes.payment_protocol_version, paid_routes.cache_enabled, paid_routes.cache_ttl_seconds, paid_routes.cache_vary_headers, paid_routes.lb_strategy, paid_routes.health_check_path, paid_routes.stream_max_seconds, paid_routes.stream_credit_seconds, paid_routes.billing_mode, paid_routes.unit_price, paid_routes.quote_url, paid_routes.min_price, paid_routes.max_price, paid_routes.available_from, paid_routes.available_until, paid_routes.max_sales, paid_routes.sold_count, paid_routes.pay_what_you_want, paid_routes.payment_rails, paid_routes.discoverable, paid_routes.mime_type, paid_routes.body_schema, paid_routes.query_schema, paid_routes.response_schema FROM paid_routes
+JOIN users ON users.id = paid_routes.user_id
+WHERE paid_routes.discoverable = true AND paid_routes.is_enabled = true
+    AND paid_routes.deleted_at IS NULL
+    AND ($1::TEXT = '' OR paid_routes.method = $1::TEXT)
+    AND ($2::BOOLEAN IS NULL OR paid_routes.is_test = $2::BOOLEAN)
+    AND ($3::BIGINT = 0 OR paid_routes.price <= $3::BIGINT)
+    AND ($4::TEXT = '' OR LOWER(COALESCE(NULLIF(users.payment_address, ''), $5::TEXT)) = LOWER($4::TEXT))
+    AND ($6::TEXT = '' OR paid_routes.title ILIKE '%' || $6::TEXT || '%'
+        OR paid_routes.description ILIKE '%' || $6::TEXT || '%')
+ORDER BY paid_routes.created_at DESC, paid_routes.id DESC
+LIMIT $7 OFFSET $8
+`
+
+type ListDiscoverablePaidRoutesParams struct {
+	Method       string
+	IsTest       pgtype.Bool
+	MaxPrice     int64
+	PayTo        string
+	DefaultPayTo string
+	Search       string
+	RowLimit     int32
+	RowOffset    int32
+}
+
+// ListDiscoverablePaidRoutes returns a page of the enabled routes listed for
+// discovery, newest first. Empty filters match any route, pay_to matches the
+// owner's payment address or default_pay_to for owners without one.
+func (q *Queries) ListDiscoverablePaidRoutes(ctx context.Context, arg ListDiscoverablePaidRoutesParams) ([]PaidRoute, error) {
+	rows, err := q.db.Query(ctx, listDiscoverablePaidRoutes,
+		arg.Method,
+		arg.IsTest,
+		arg.MaxPrice,
+		arg.PayTo,
+		arg.DefaultPayTo,
+		arg.Search,
+		arg.RowLimit,
+		arg.RowOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PaidRoute
+	for rows.Next() {
+		var i PaidRoute
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortCode,
+			&i.TargetUrl,
+			&i.Method,
+			&i.Price,
+			&i.IsTest,
+			&i.UserID,
+			&i.IsEnabled,
+			&i.AttemptCount,
+			&i.PaymentCount,
+			&i.AccessCount,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Type,
+			&i.Credits,
+			&i.ResourceType,
+			&i.OriginalFilename,
+			&i.CoverUrl,
+			&i.Title,
+			&i.Description,
+			&i.PaymentProtocolVersion,
+			&i.CacheEnabled,
+			&i.CacheTtlSeconds,
+			&i.CacheVaryHeaders,
+			&i.LbStrategy,
+			&i.HealthCheckPath,
+			&i.StreamMaxSeconds,
+			&i.StreamCreditSeconds,
+			&i.BillingMode,
+			&i.UnitPrice,
+			&i.QuoteUrl,
+			&i.MinPrice,
+			&i.MaxPrice,
+			&i.AvailableFrom,
+			&i.AvailableUntil,
+			&i.MaxSales,
+			&i.SoldCount,
+			&i.PayWhatYouWant,
+			&i.PaymentRails,
+			&i.Discoverable,
+			&i.MimeType,
+			&i.BodySchema,
+			&i.QuerySchema,
+			&i.ResponseSchema,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listUserPaidRoutes = `-- name: ListUserPaidRoutes :many
-SELECT id, short_code, target_url, method, price, is_test, user_id, is_enabled, attempt_count, payment_count, access_count, created_at, updated_at, deleted_at, type, credits, resource_type, original_filename, cover_url, title, description, payment_protocol_version FROM paid_routes
+SELECT id, short_code, target_url, method, price, is_test, user_id, is_enabled, attempt_count, payment_count, access_count, created_at, updated_at, deleted_at, type, credits, resource_type, original_filename, cover_url, title, description, payment_protocol_version, cache_enabled, cache_ttl_seconds, cache_vary_headers, lb_strategy, health_check_path, stream_max_seconds, stream_credit_seconds, billing_mode, unit_price, quote_url, min_price, max_price, available_from, available_until, max_sales, sold_count, pay_what_you_want, payment_rails, discoverable, mime_type, body_schema, query_schema, response_schema FROM paid_routes
 WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY created_at DESC
 `
@@ -334,6 +632,29 @@ func (q *Queries) ListUserPaidRoutes(ctx context.Context, userID int64) ([]PaidR
 			&i.Title,
 			&i.Description,
 			&i.PaymentProtocolVersion,
+			&i.CacheEnabled,
+			&i.CacheTtlSeconds,
+			&i.CacheVaryHeaders,
+			&i.LbStrategy,
+			&i.HealthCheckPath,
+			&i.StreamMaxSeconds,
+			&i.StreamCreditSeconds,
+			&i.BillingMode,
+			&i.UnitPrice,
+			&i.QuoteUrl,
+			&i.MinPrice,
+			&i.MaxPrice,
+			&i.AvailableFrom,
+			&i.AvailableUntil,
+			&i.MaxSales,
+			&i.SoldCount,
+			&i.PayWhatYouWant,
+			&i.PaymentRails,
+			&i.Discoverable,
+			&i.MimeType,
+			&i.BodySchema,
+			&i.QuerySchema,
+			&i.ResponseSchema,
 		); err != nil {
 			return nil, err
 		}
@@ -344,3 +665,84 @@ func (q *Queries) ListUserPaidRoutes(ctx context.Context, userID int64) ([]PaidR
 	}
 	return items, nil
 }
+
+const releasePurchaseRouteSale = `-- name: ReleasePurchaseRouteSale :exec
+UPDATE paid_routes SET
+    sold_count = sold_count - 1
+WHERE id = (SELECT paid_route_id FROM purchases WHERE purchases.id = $1) AND sold_count > 0
+`
+
+// ReleasePurchaseRouteSale uncounts the sale of a purchase's route.
+func (q *Queries) ReleasePurchaseRouteSale(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, releasePurchaseRouteSale, id)
+	return err
+}
+
+const reserveRouteSale = `-- name: ReserveRouteSale :execrows
+UPDATE paid_routes SET
+    sold_count = sold_count + 1
+WHERE id = $1 AND (max_sales = 0 OR sold_count < max_sales)
+`
+
+// ReserveRouteSale counts a sale of a route, unless its sales limit is
+// reached.
+func (q *Queries) ReserveRouteSale(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, reserveRouteSale, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updatePaidRouteLoadBalancing = `-- name: UpdatePaidRouteLoadBalancing :execrows
+UPDATE paid_routes SET
+    lb_strategy = $3,
+    health_check_path = $4,
+    updated_at = $5
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+`
+
+type UpdatePaidRouteLoadBalancingParams struct {
+	ID              int64
+	UserID          int64
+	LbStrategy      string
+	HealthCheckPath string
+	UpdatedAt       time.Time
+}
+
+// UpdatePaidRouteLoadBalancing updates the load balancing settings of a route.
+func (q *Queries) UpdatePaidRouteLoadBalancing(ctx context.Context, arg UpdatePaidRouteLoadBalancingParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updatePaidRouteLoadBalancing,
+		arg.ID,
+		arg.UserID,
+		arg.LbStrategy,
+		arg.HealthCheckPath,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updatePaidRoutePrice = `-- name: UpdatePaidRoutePrice :execrows
+UPDATE paid_routes SET
+    price = $2,
+    updated_at = $3
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+type UpdatePaidRoutePriceParams struct {
+	ID        int64
+	Price     int64
+	UpdatedAt time.Time
+}
+
+// UpdatePaidRoutePrice changes the price of a route.
+func (q *Queries) UpdatePaidRoutePrice(ctx context.Context, arg UpdatePaidRoutePriceParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updatePaidRoutePrice, arg.ID, arg.Price, arg.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
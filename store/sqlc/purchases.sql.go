@@ -12,34 +12,131 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const chargePurchaseUsage = `-- name: ChargePurchaseUsage :one
+WITH previous AS (
+    SELECT id, amount_charged FROM purchases
+    WHERE id = $1
+    FOR UPDATE
+)
+UPDATE purchases p
+SET usage_units = p.usage_units + LEAST($2, 9223372036854775807 - p.usage_units),
+    amount_charged = p.amount_charged + LEAST($3, p.price - p.amount_charged),
+    updated_at = $4
+FROM previous
+WHERE p.id = previous.id
+RETURNING p.id, p.short_code, p.target_url, p.method, p.price, p.is_test, p.payment_payload, p.settle_response, p.paid_route_id, p.paid_to_address, p.created_at, p.updated_at, p.type, p.credits_available, p.credits_used, p.payment_header, p.usage_units, p.amount_charged, p.status, p.payment_requirements, p.settle_error, p.pack_id, p.affiliate_id, p.affiliate_commission, previous.amount_charged AS previous_amount_charged
+`
+
+type ChargePurchaseUsageParams struct {
+	ID            int64
+	UsageUnits    int64
+	AmountCharged int64
+	UpdatedAt     time.Time
+}
+
+type ChargePurchaseUsageRow struct {
+	ID                    int64
+	ShortCode             string
+	TargetUrl             string
+	Method                string
+	Price                 int64
+	IsTest                bool
+	PaymentPayload        []byte
+	SettleResponse        []byte
+	PaidRouteID           int64
+	PaidToAddress         string
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	Type                  string
+	CreditsAvailable      int32
+	CreditsUsed           int32
+	PaymentHeader         pgtype.Text
+	UsageUnits            int64
+	AmountCharged         int64
+	Status                string
+	PaymentRequirements   []byte
+	SettleError           string
+	PackID                pgtype.Int8
+	AffiliateID           pgtype.Int8
+	AffiliateCommission   int64
+	PreviousAmountCharged int64
+}
+
+// ChargePurchaseUsage records usage on a purchase and charges its amount,
+// capped at what is left of the price paid for the purchase. Usage units
+// saturate at the column's range. The amount charged before is returned
+// alongside the updated purchase.
+func (q *Queries) ChargePurchaseUsage(ctx context.Context, arg ChargePurchaseUsageParams) (ChargePurchaseUsageRow, error) {
+	row := q.db.QueryRow(ctx, chargePurchaseUsage,
+		arg.ID,
+		arg.UsageUnits,
+		arg.AmountCharged,
+		arg.UpdatedAt,
+	)
+	var i ChargePurchaseUsageRow
+	err := row.Scan(
+		&i.ID,
+		&i.ShortCode,
+		&i.TargetUrl,
+		&i.Method,
+		&i.Price,
+		&i.IsTest,
+		&i.PaymentPayload,
+		&i.SettleResponse,
+		&i.PaidRouteID,
+		&i.PaidToAddress,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Type,
+		&i.CreditsAvailable,
+		&i.CreditsUsed,
+		&i.PaymentHeader,
+		&i.UsageUnits,
+		&i.AmountCharged,
+		&i.Status,
+		&i.PaymentRequirements,
+		&i.SettleError,
+		&i.PackID,
+		&i.AffiliateID,
+		&i.AffiliateCommission,
+		&i.PreviousAmountCharged,
+	)
+	return i, err
+}
+
 const createPurchase = `-- name: CreatePurchase :one
 INSERT INTO purchases (
     short_code, target_url, method, price, is_test,
     payment_payload, settle_response, paid_route_id, paid_to_address,
     created_at, updated_at,
-    type, credits_available, credits_used, payment_header
+    type, credits_available, credits_used, payment_header,
+    status, payment_requirements, pack_id, affiliate_id
 ) VALUES (
     $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11,
-    $12, $13, $14, $15
+    $12, $13, $14, $15, $16, $17, $18, $19
 ) RETURNING id
 `
 
 type CreatePurchaseParams struct {
-	ShortCode        string
-	TargetUrl        string
-	Method           string
-	Price            int32
-	IsTest           bool
-	PaymentPayload   []byte
-	SettleResponse   []byte
-	PaidRouteID      int64
-	PaidToAddress    string
-	CreatedAt        time.Time
-	UpdatedAt        time.Time
-	Type             string
-	CreditsAvailable int32
-	CreditsUsed      int32
-	PaymentHeader    pgtype.Text
+	ShortCode           string
+	TargetUrl           string
+	Method              string
+	Price               int64
+	IsTest              bool
+	PaymentPayload      []byte
+	SettleResponse      []byte
+	PaidRouteID         int64
+	PaidToAddress       string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Type                string
+	CreditsAvailable    int32
+	CreditsUsed         int32
+	PaymentHeader       pgtype.Text
+	Status              string
+	PaymentRequirements []byte
+	PackID              pgtype.Int8
+	AffiliateID         pgtype.Int8
 }
 
 // CreatePurchase creates a new purchase record.
@@ -60,28 +157,87 @@ func (q *Queries) CreatePurchase(ctx context.Context, arg CreatePurchaseParams)
 		arg.CreditsAvailable,
 		arg.CreditsUsed,
 		arg.PaymentHeader,
+		arg.Status,
+		arg.PaymentRequirements,
+		arg.PackID,
+		arg.AffiliateID,
 	)
 	var id int64
 	err := row.Scan(&id)
 	return id, err
 }
 
+const failPurchase = `-- name: FailPurchase :execrows
+UPDATE purchases
+SET status = 'failed', settle_error = $2, updated_at = $3
+WHERE id = $1 AND status = 'pending'
+`
+
+type FailPurchaseParams struct {
+	ID          int64
+	SettleError string
+	UpdatedAt   time.Time
+}
+
+// FailPurchase marks a pending purchase as failed to settle.
+func (q *Queries) FailPurchase(ctx context.Context, arg FailPurchaseParams) (int64, error) {
+	result, err := q.db.Exec(ctx, failPurchase,
+		arg.ID,
+		arg.SettleError,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const flagPendingPurchase = `-- name: FlagPendingPurchase :execrows
+UPDATE purchases
+SET settle_error = $2, updated_at = $3
+WHERE id = $1 AND status = 'pending'
+`
+
+type FlagPendingPurchaseParams struct {
+	ID          int64
+	SettleError string
+	UpdatedAt   time.Time
+}
+
+// FlagPendingPurchase records why a pending purchase could not be resolved
+// automatically.
+func (q *Queries) FlagPendingPurchase(ctx context.Context, arg FlagPendingPurchaseParams) (int64, error) {
+	result, err := q.db.Exec(ctx, flagPendingPurchase,
+		arg.ID,
+		arg.SettleError,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getDailyStats = `-- name: GetDailyStats :many
 SELECT 
-    to_char(p.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS date,
+    to_char(t.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS date,
     COUNT(*) AS count,
-    SUM(p.price) AS earnings,
-    COUNT(CASE WHEN p.is_test = true THEN 1 END) AS test_count,
-    COALESCE(SUM(CASE WHEN p.is_test = true THEN p.price ELSE 0 END), 0) AS test_earnings,
-    COUNT(CASE WHEN p.is_test = false THEN 1 END) AS real_count,
-    COALESCE(SUM(CASE WHEN p.is_test = false THEN p.price ELSE 0 END), 0) AS real_earnings
+    SUM(e.amount)::BIGINT AS earnings,
+    COUNT(CASE WHEN t.is_test = true THEN 1 END) AS test_count,
+    COALESCE(SUM(CASE WHEN t.is_test = true THEN e.amount ELSE 0 END), 0)::BIGINT AS test_earnings,
+    COUNT(CASE WHEN t.is_test = false THEN 1 END) AS real_count,
+    COALESCE(SUM(CASE WHEN t.is_test = false THEN e.amount ELSE 0 END), 0)::BIGINT AS real_earnings
 FROM 
-    purchases p
+    ledger_entries e
 JOIN 
-    paid_routes pr ON p.paid_route_id = pr.id
+    ledger_transactions t ON e.transaction_id = t.id
+JOIN 
+    ledger_accounts a ON e.account_id = a.id
 WHERE 
-    pr.user_id = $1
-    AND p.created_at >= CURRENT_TIMESTAMP - ($2 || ' days')::INTERVAL
+    a.user_id = $1
+    AND a.kind = 'route'
+    AND t.kind IN ('purchase', 'wallet_draw')
+    AND t.created_at >= CURRENT_TIMESTAMP - ($2 || ' days')::INTERVAL
 GROUP BY 
     date
 ORDER BY 
@@ -89,7 +245,7 @@ ORDER BY
 `
 
 type GetDailyStatsParams struct {
-	UserID  int64
+	UserID  pgtype.Int8
 	Column2 pgtype.Text
 }
 
@@ -98,12 +254,13 @@ type GetDailyStatsRow struct {
 	Count        int64
 	Earnings     int64
 	TestCount    int64
-	TestEarnings interface{}
+	TestEarnings int64
 	RealCount    int64
-	RealEarnings interface{}
+	RealEarnings int64
 }
 
 // GetDailyStats retrieves daily purchase stats for a specific user.
+// Requests paid from wallets count as purchases.
 func (q *Queries) GetDailyStats(ctx context.Context, arg GetDailyStatsParams) ([]GetDailyStatsRow, error) {
 	rows, err := q.db.Query(ctx, getDailyStats, arg.UserID, arg.Column2)
 	if err != nil {
@@ -133,7 +290,7 @@ func (q *Queries) GetDailyStats(ctx context.Context, arg GetDailyStatsParams) ([
 }
 
 const getPurchaseByID = `-- name: GetPurchaseByID :one
-SELECT id, short_code, target_url, method, price, is_test, payment_payload, settle_response, paid_route_id, paid_to_address, created_at, updated_at, type, credits_available, credits_used, payment_header FROM purchases
+SELECT id, short_code, target_url, method, price, is_test, payment_payload, settle_response, paid_route_id, paid_to_address, created_at, updated_at, type, credits_available, credits_used, payment_header, usage_units, amount_charged, status, payment_requirements, settle_error, pack_id, affiliate_id, affiliate_commission FROM purchases
 WHERE id = $1
 `
 
@@ -158,13 +315,21 @@ func (q *Queries) GetPurchaseByID(ctx context.Context, id int64) (Purchase, erro
 		&i.CreditsAvailable,
 		&i.CreditsUsed,
 		&i.PaymentHeader,
+		&i.UsageUnits,
+		&i.AmountCharged,
+		&i.Status,
+		&i.PaymentRequirements,
+		&i.SettleError,
+		&i.PackID,
+		&i.AffiliateID,
+		&i.AffiliateCommission,
 	)
 	return i, err
 }
 
 const getPurchaseByRouteIDAndPaymentHeader = `-- name: GetPurchaseByRouteIDAndPaymentHeader :one
-SELECT id, short_code, target_url, method, price, is_test, payment_payload, settle_response, paid_route_id, paid_to_address, created_at, updated_at, type, credits_available, credits_used, payment_header FROM purchases
-WHERE paid_route_id = $1 AND payment_header = $2
+SELECT id, short_code, target_url, method, price, is_test, payment_payload, settle_response, paid_route_id, paid_to_address, created_at, updated_at, type, credits_available, credits_used, payment_header, usage_units, amount_charged, status, payment_requirements, settle_error, pack_id, affiliate_id, affiliate_commission FROM purchases
+WHERE paid_route_id = $1 AND payment_header = $2 AND status = 'settled'
 ORDER BY created_at DESC LIMIT 1
 `
 
@@ -193,39 +358,53 @@ func (q *Queries) GetPurchaseByRouteIDAndPaymentHeader(ctx context.Context, arg
 		&i.CreditsAvailable,
 		&i.CreditsUsed,
 		&i.PaymentHeader,
+		&i.UsageUnits,
+		&i.AmountCharged,
+		&i.Status,
+		&i.PaymentRequirements,
+		&i.SettleError,
+		&i.PackID,
+		&i.AffiliateID,
+		&i.AffiliateCommission,
 	)
 	return i, err
 }
 
 const getTotalStats = `-- name: GetTotalStats :one
 SELECT 
-    COALESCE(SUM(p.price), 0) AS total_earnings,
+    COALESCE(SUM(e.amount), 0)::BIGINT AS total_earnings,
     COUNT(*) AS total_count
 FROM 
-    purchases p
+    ledger_entries e
+JOIN 
+    ledger_transactions t ON e.transaction_id = t.id
 JOIN 
-    paid_routes pr ON p.paid_route_id = pr.id
+    ledger_accounts a ON e.account_id = a.id
 WHERE 
-    pr.user_id = $1
+    a.user_id = $1
+    AND a.kind = 'route'
+    AND t.kind IN ('purchase', 'wallet_draw')
 `
 
 type GetTotalStatsRow struct {
-	TotalEarnings interface{}
+	TotalEarnings int64
 	TotalCount    int64
 }
 
 // GetTotalStats retrieves total purchase stats for a specific user.
-func (q *Queries) GetTotalStats(ctx context.Context, userID int64) (GetTotalStatsRow, error) {
+// Requests paid from wallets count as purchases.
+func (q *Queries) GetTotalStats(ctx context.Context, userID pgtype.Int8) (GetTotalStatsRow, error) {
 	row := q.db.QueryRow(ctx, getTotalStats, userID)
 	var i GetTotalStatsRow
 	err := row.Scan(&i.TotalEarnings, &i.TotalCount)
 	return i, err
 }
 
-const incrementPurchaseCreditsUsed = `-- name: IncrementPurchaseCreditsUsed :exec
+const incrementPurchaseCreditsUsed = `-- name: IncrementPurchaseCreditsUsed :one
 UPDATE purchases
 SET credits_used = credits_used + 1, updated_at = $2
 WHERE id = $1 AND credits_used < credits_available
+RETURNING credits_used
 `
 
 type IncrementPurchaseCreditsUsedParams struct {
@@ -233,13 +412,71 @@ type IncrementPurchaseCreditsUsedParams struct {
 	UpdatedAt time.Time
 }
 
-func (q *Queries) IncrementPurchaseCreditsUsed(ctx context.Context, arg IncrementPurchaseCreditsUsedParams) error {
-	_, err := q.db.Exec(ctx, incrementPurchaseCreditsUsed, arg.ID, arg.UpdatedAt)
-	return err
+// IncrementPurchaseCreditsUsed uses a credit of a purchase and returns the
+// number of credits used. No row is returned if no credit is left.
+func (q *Queries) IncrementPurchaseCreditsUsed(ctx context.Context, arg IncrementPurchaseCreditsUsedParams) (int32, error) {
+	row := q.db.QueryRow(ctx, incrementPurchaseCreditsUsed, arg.ID, arg.UpdatedAt)
+	var credits_used int32
+	err := row.Scan(&credits_used)
+	return credits_used, err
+}
+
+const listPendingPurchasesByUserID = `-- name: ListPendingPurchasesByUserID :many
+SELECT p.id, p.short_code, p.target_url, p.method, p.price, p.is_test, p.payment_payload, p.settle_response, p.paid_route_id, p.paid_to_address, p.created_at, p.updated_at, p.type, p.credits_available, p.credits_used, p.payment_header, p.usage_units, p.amount_charged, p.status, p.payment_requirements, p.settle_error, p.pack_id, p.affiliate_id, p.affiliate_commission FROM purchases p
+JOIN paid_routes pr ON p.paid_route_id = pr.id
+WHERE pr.user_id = $1 AND p.status = 'pending'
+ORDER BY p.created_at DESC
+`
+
+// ListPendingPurchasesByUserID retrieves the purchases of a user's routes that
+// are not settled yet.
+func (q *Queries) ListPendingPurchasesByUserID(ctx context.Context, userID int64) ([]Purchase, error) {
+	rows, err := q.db.Query(ctx, listPendingPurchasesByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Purchase
+	for rows.Next() {
+		var i Purchase
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortCode,
+			&i.TargetUrl,
+			&i.Method,
+			&i.Price,
+			&i.IsTest,
+			&i.PaymentPayload,
+			&i.SettleResponse,
+			&i.PaidRouteID,
+			&i.PaidToAddress,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Type,
+			&i.CreditsAvailable,
+			&i.CreditsUsed,
+			&i.PaymentHeader,
+			&i.UsageUnits,
+			&i.AmountCharged,
+			&i.Status,
+			&i.PaymentRequirements,
+			&i.SettleError,
+			&i.PackID,
+			&i.AffiliateID,
+			&i.AffiliateCommission,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const listPurchasesByUserID = `-- name: ListPurchasesByUserID :many
-SELECT p.id, p.short_code, p.target_url, p.method, p.price, p.is_test, p.payment_payload, p.settle_response, p.paid_route_id, p.paid_to_address, p.created_at, p.updated_at, p.type, p.credits_available, p.credits_used, p.payment_header FROM purchases p
+SELECT p.id, p.short_code, p.target_url, p.method, p.price, p.is_test, p.payment_payload, p.settle_response, p.paid_route_id, p.paid_to_address, p.created_at, p.updated_at, p.type, p.credits_available, p.credits_used, p.payment_header, p.usage_units, p.amount_charged, p.status, p.payment_requirements, p.settle_error, p.pack_id, p.affiliate_id, p.affiliate_commission FROM purchases p
 JOIN paid_routes pr ON p.paid_route_id = pr.id
 WHERE pr.user_id = $1
 ORDER BY p.created_at DESC
@@ -272,6 +509,14 @@ func (q *Queries) ListPurchasesByUserID(ctx context.Context, userID int64) ([]Pu
 			&i.CreditsAvailable,
 			&i.CreditsUsed,
 			&i.PaymentHeader,
+			&i.UsageUnits,
+			&i.AmountCharged,
+			&i.Status,
+			&i.PaymentRequirements,
+			&i.SettleError,
+			&i.PackID,
+			&i.AffiliateID,
+			&i.AffiliateCommission,
 		); err != nil {
 			return nil, err
 		}
@@ -282,3 +527,104 @@ func (q *Queries) ListPurchasesByUserID(ctx context.Context, userID int64) ([]Pu
 	}
 	return items, nil
 }
+
+const listStalePendingPurchases = `-- name: ListStalePendingPurchases :many
+SELECT id, short_code, target_url, method, price, is_test, payment_payload, settle_response, paid_route_id, paid_to_address, created_at, updated_at, type, credits_available, credits_used, payment_header, usage_units, amount_charged, status, payment_requirements, settle_error, pack_id, affiliate_id, affiliate_commission FROM purchases
+WHERE status = 'pending' AND settle_error = '' AND created_at < $1
+ORDER BY created_at
+LIMIT $2
+`
+
+type ListStalePendingPurchasesParams struct {
+	CreatedAt time.Time
+	Limit     int32
+}
+
+// ListStalePendingPurchases retrieves pending purchases created before the
+// given time that were not flagged for review yet.
+func (q *Queries) ListStalePendingPurchases(ctx context.Context, arg ListStalePendingPurchasesParams) ([]Purchase, error) {
+	rows, err := q.db.Query(ctx, listStalePendingPurchases, arg.CreatedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Purchase
+	for rows.Next() {
+		var i Purchase
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortCode,
+			&i.TargetUrl,
+			&i.Method,
+			&i.Price,
+			&i.IsTest,
+			&i.PaymentPayload,
+			&i.SettleResponse,
+			&i.PaidRouteID,
+			&i.PaidToAddress,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Type,
+			&i.CreditsAvailable,
+			&i.CreditsUsed,
+			&i.PaymentHeader,
+			&i.UsageUnits,
+			&i.AmountCharged,
+			&i.Status,
+			&i.PaymentRequirements,
+			&i.SettleError,
+			&i.PackID,
+			&i.AffiliateID,
+			&i.AffiliateCommission,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setPurchaseAffiliateCommission = `-- name: SetPurchaseAffiliateCommission :exec
+UPDATE purchases
+SET affiliate_commission = $2, updated_at = $3
+WHERE id = $1
+`
+
+type SetPurchaseAffiliateCommissionParams struct {
+	ID                  int64
+	AffiliateCommission int64
+	UpdatedAt           time.Time
+}
+
+func (q *Queries) SetPurchaseAffiliateCommission(ctx context.Context, arg SetPurchaseAffiliateCommissionParams) error {
+	_, err := q.db.Exec(ctx, setPurchaseAffiliateCommission, arg.ID, arg.AffiliateCommission, arg.UpdatedAt)
+	return err
+}
+
+const settlePurchase = `-- name: SettlePurchase :execrows
+UPDATE purchases
+SET status = 'settled', settle_response = $2, settle_error = '', updated_at = $3
+WHERE id = $1 AND status = 'pending'
+`
+
+type SettlePurchaseParams struct {
+	ID             int64
+	SettleResponse []byte
+	UpdatedAt      time.Time
+}
+
+// SettlePurchase marks a pending purchase as settled with the settle response.
+func (q *Queries) SettlePurchase(ctx context.Context, arg SettlePurchaseParams) (int64, error) {
+	result, err := q.db.Exec(ctx, settlePurchase,
+		arg.ID,
+		arg.SettleResponse,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -6,52 +6,272 @@ package sqlc
 
 import (
 	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
+	AddBundleRoute(ctx context.Context, arg AddBundleRouteParams) error
+	// ChargePurchaseUsage records usage on a purchase and charges its amount,
+	// capped at what is left of the price paid for the purchase. Usage units
+	// saturate at the column's range. The amount charged before is returned
+	// alongside the updated purchase.
+	ChargePurchaseUsage(ctx context.Context, arg ChargePurchaseUsageParams) (ChargePurchaseUsageRow, error)
 	// CheckShortCodeExists checks if a short code already exists.
 	CheckShortCodeExists(ctx context.Context, shortCode string) (bool, error)
+	// ClaimIdempotencyKey records a request in progress for a key. An expired key
+	// is claimed again, no row is affected if the key is in use.
+	ClaimIdempotencyKey(ctx context.Context, arg ClaimIdempotencyKeyParams) (int64, error)
+	// CompleteIdempotencyKey stores the response to the request of a key.
+	CompleteIdempotencyKey(ctx context.Context, arg CompleteIdempotencyKeyParams) error
+	// CountDiscoverablePaidRoutes counts the routes ListDiscoverablePaidRoutes
+	// pages through.
+	CountDiscoverablePaidRoutes(ctx context.Context, arg CountDiscoverablePaidRoutesParams) (int64, error)
+	// CountSettlementChecksByRouteID returns the number of purchases of a route
+	// per settlement status.
+	CountSettlementChecksByRouteID(ctx context.Context, paidRouteID int64) ([]CountSettlementChecksByRouteIDRow, error)
+	CreateAffiliate(ctx context.Context, arg CreateAffiliateParams) (Affiliate, error)
+	CreateBundle(ctx context.Context, arg CreateBundleParams) (Bundle, error)
+	CreateCoupon(ctx context.Context, arg CreateCouponParams) (Coupon, error)
+	CreateCouponRedemption(ctx context.Context, arg CreateCouponRedemptionParams) error
+	// CreateLedgerAccount returns the ID of a ledger account, creating it first
+	// if it doesn't exist yet.
+	CreateLedgerAccount(ctx context.Context, arg CreateLedgerAccountParams) (int64, error)
+	// CreateLedgerEntry posts an entry of a ledger transaction.
+	CreateLedgerEntry(ctx context.Context, arg CreateLedgerEntryParams) error
+	// CreateLedgerTransaction creates a ledger transaction and returns its ID.
+	// No row is returned if a transaction with the reference already exists.
+	CreateLedgerTransaction(ctx context.Context, arg CreateLedgerTransactionParams) (int64, error)
 	// CreatePaidRoute creates a new paid route.
 	CreatePaidRoute(ctx context.Context, arg CreatePaidRouteParams) (PaidRoute, error)
+	CreatePayerRule(ctx context.Context, arg CreatePayerRuleParams) (PayerRule, error)
 	// CreatePurchase creates a new purchase record.
 	CreatePurchase(ctx context.Context, arg CreatePurchaseParams) (int64, error)
+	// CreatePurchasePayout records what a payee is owed for a purchase.
+	CreatePurchasePayout(ctx context.Context, arg CreatePurchasePayoutParams) error
+	CreateRoutePack(ctx context.Context, arg CreateRoutePackParams) (RoutePack, error)
+	CreateRoutePriceChange(ctx context.Context, arg CreateRoutePriceChangeParams) (RoutePriceChange, error)
+	// CreateRouteSplit adds a revenue share of a route for a payee.
+	CreateRouteSplit(ctx context.Context, arg CreateRouteSplitParams) (RouteSplit, error)
+	// CreateRouteTarget adds an upstream target to a route's pool.
+	CreateRouteTarget(ctx context.Context, arg CreateRouteTargetParams) (RouteTarget, error)
+	// CreateSettlementAlert records a settlement problem for the owner of the
+	// purchased route.
+	CreateSettlementAlert(ctx context.Context, arg CreateSettlementAlertParams) error
+	// CreateSettlementCheck queues the settlement of a purchase for on-chain
+	// verification.
+	CreateSettlementCheck(ctx context.Context, arg CreateSettlementCheckParams) error
 	// CreateUser creates a new user record.
 	CreateUser(ctx context.Context, arg CreateUserParams) (int64, error)
+	// CreateWallet creates a payer's wallet with an owner. No row is returned if
+	// the payer already has one.
+	CreateWallet(ctx context.Context, arg CreateWalletParams) (Wallet, error)
+	CreateWalletDraw(ctx context.Context, arg CreateWalletDrawParams) (int64, error)
+	CreateWalletTopUp(ctx context.Context, arg CreateWalletTopUpParams) (int64, error)
+	// CreditWallet adds a settled top-up to a wallet's balance.
+	CreditWallet(ctx context.Context, arg CreditWalletParams) (Wallet, error)
+	// DebitWallet takes amount from a wallet's balance. No row is returned if
+	// the balance is too low.
+	DebitWallet(ctx context.Context, arg DebitWalletParams) (Wallet, error)
+	DecrementCouponRedemptions(ctx context.Context, arg DecrementCouponRedemptionsParams) error
+	DeleteAffiliate(ctx context.Context, arg DeleteAffiliateParams) (int64, error)
+	DeleteCoupon(ctx context.Context, arg DeleteCouponParams) (int64, error)
+	// DeleteCouponRedemptionByPurchaseID deletes the redemption of a purchase and
+	// returns the coupon it redeemed.
+	DeleteCouponRedemptionByPurchaseID(ctx context.Context, purchaseID pgtype.Int8) (int64, error)
+	// DeleteExpiredIdempotencyKeys removes the keys that expired before the
+	// given time.
+	DeleteExpiredIdempotencyKeys(ctx context.Context, expiresAt time.Time) (int64, error)
+	// DeleteExpiredWalletChallenges removes a wallet's challenges that are too
+	// old to be accepted anyway.
+	DeleteExpiredWalletChallenges(ctx context.Context, arg DeleteExpiredWalletChallengesParams) error
 	// DeletePaidRoute soft-deletes a paid route.
 	DeletePaidRoute(ctx context.Context, arg DeletePaidRouteParams) error
+	DeletePayerRule(ctx context.Context, arg DeletePayerRuleParams) (int64, error)
+	DeleteRoutePack(ctx context.Context, arg DeleteRoutePackParams) (int64, error)
+	// DeleteRoutePriceChange deletes a price change that was not applied yet.
+	DeleteRoutePriceChange(ctx context.Context, arg DeleteRoutePriceChangeParams) (int64, error)
+	// DeleteRouteSplits removes all revenue shares of a route.
+	DeleteRouteSplits(ctx context.Context, paidRouteID int64) error
+	// DeleteRouteTarget removes an upstream target from a route's pool.
+	DeleteRouteTarget(ctx context.Context, arg DeleteRouteTargetParams) (int64, error)
+	// DisableEndedPaidRoutes disables the enabled routes whose availability
+	// window closed.
+	DisableEndedPaidRoutes(ctx context.Context, updatedAt time.Time) (int64, error)
+	// DismissSettlementAlert hides an alert of a user from their dashboard.
+	DismissSettlementAlert(ctx context.Context, arg DismissSettlementAlertParams) (int64, error)
+	// FailPurchase marks a pending purchase as failed to settle.
+	FailPurchase(ctx context.Context, arg FailPurchaseParams) (int64, error)
+	FailWalletTopUp(ctx context.Context, arg FailWalletTopUpParams) (int64, error)
+	// FlagPendingPurchase records why a pending purchase could not be resolved
+	// automatically.
+	FlagPendingPurchase(ctx context.Context, arg FlagPendingPurchaseParams) (int64, error)
+	GetAffiliate(ctx context.Context, arg GetAffiliateParams) (Affiliate, error)
+	GetAffiliateByTokenHash(ctx context.Context, tokenHash string) (Affiliate, error)
+	// GetAffiliateForRoute retrieves an owner's affiliate by code if it earns
+	// commission on the route.
+	GetAffiliateForRoute(ctx context.Context, arg GetAffiliateForRouteParams) (Affiliate, error)
+	GetBundle(ctx context.Context, paidRouteID int64) (Bundle, error)
+	// GetBundlePurchaseByRouteIDAndPaymentHeader returns the latest settled
+	// purchase of a bundle the route is part of that was paid with the header.
+	GetBundlePurchaseByRouteIDAndPaymentHeader(ctx context.Context, arg GetBundlePurchaseByRouteIDAndPaymentHeaderParams) (Purchase, error)
+	// GetCouponForRoute retrieves an owner's coupon by code if it applies to the
+	// route.
+	GetCouponForRoute(ctx context.Context, arg GetCouponForRouteParams) (Coupon, error)
 	// GetDailyStats retrieves daily purchase stats for a specific user.
+	// Requests paid from wallets count as purchases.
 	GetDailyStats(ctx context.Context, arg GetDailyStatsParams) ([]GetDailyStatsRow, error)
 	// GetEnabledPaidRouteByShortCode returns an enabled paid route by its short code.
 	GetEnabledPaidRouteByShortCode(ctx context.Context, shortCode string) (PaidRoute, error)
+	// GetIdempotencyKey returns a key of a route and purchase.
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	// GetLedgerPurchaseEarnings returns what the route account kept of a
+	// purchase after fees and splits.
+	GetLedgerPurchaseEarnings(ctx context.Context, purchaseID pgtype.Int8) (int64, error)
 	// GetPaidRouteByID returns a paid route by ID.
 	GetPaidRouteByID(ctx context.Context, id int64) (PaidRoute, error)
 	// GetPaidRouteByShortCode returns a paid route by its short code.
 	GetPaidRouteByShortCode(ctx context.Context, shortCode string) (PaidRoute, error)
+	// GetPayerRuleForRoute retrieves the rule of an owner for a payer on a route,
+	// preferring a rule for the route over a rule for all routes.
+	GetPayerRuleForRoute(ctx context.Context, arg GetPayerRuleForRouteParams) (PayerRule, error)
 	// GetPurchaseByID returns a purchase by ID.
 	GetPurchaseByID(ctx context.Context, id int64) (Purchase, error)
 	GetPurchaseByRouteIDAndPaymentHeader(ctx context.Context, arg GetPurchaseByRouteIDAndPaymentHeaderParams) (Purchase, error)
+	GetRouteTrial(ctx context.Context, paidRouteID int64) (RouteTrial, error)
 	// GetTotalStats retrieves total purchase stats for a specific user.
-	GetTotalStats(ctx context.Context, userID int64) (GetTotalStatsRow, error)
+	// Requests paid from wallets count as purchases.
+	GetTotalStats(ctx context.Context, userID pgtype.Int8) (GetTotalStatsRow, error)
 	// GetUserByEmail returns a user by email.
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	// GetUserByGoogleID returns a user by Google ID.
 	GetUserByGoogleID(ctx context.Context, googleID string) (User, error)
 	// GetUserByID returns a user by ID.
 	GetUserByID(ctx context.Context, id int64) (User, error)
+	GetWalletByPayer(ctx context.Context, arg GetWalletByPayerParams) (Wallet, error)
+	GetWalletByTokenHash(ctx context.Context, arg GetWalletByTokenHashParams) (Wallet, error)
 	// IncrementAccessCount increments the access_count for a route.
 	IncrementAccessCount(ctx context.Context, arg IncrementAccessCountParams) error
 	// IncrementAttemptCount increments the attempt_count for a route.
 	IncrementAttemptCount(ctx context.Context, arg IncrementAttemptCountParams) error
+	// IncrementCouponRedemptions counts a redemption of a coupon unless it is
+	// deleted, expired or used up.
+	IncrementCouponRedemptions(ctx context.Context, arg IncrementCouponRedemptionsParams) (int64, error)
 	// IncrementPaymentCount increments the payment_count for a route.
 	IncrementPaymentCount(ctx context.Context, arg IncrementPaymentCountParams) error
-	IncrementPurchaseCreditsUsed(ctx context.Context, arg IncrementPurchaseCreditsUsedParams) error
+	// IncrementPurchaseCreditsUsed uses a credit of a purchase and returns the
+	// number of credits used. No row is returned if no credit is left.
+	IncrementPurchaseCreditsUsed(ctx context.Context, arg IncrementPurchaseCreditsUsedParams) (int32, error)
+	// ListAffiliateEarnings sums the settled purchases the given affiliates
+	// referred, per affiliate.
+	ListAffiliateEarnings(ctx context.Context, affiliateIds []int64) ([]ListAffiliateEarningsRow, error)
+	ListAffiliateSales(ctx context.Context, arg ListAffiliateSalesParams) ([]ListAffiliateSalesRow, error)
+	ListAffiliatesByUserID(ctx context.Context, userID int64) ([]Affiliate, error)
+	// ListBundleRoutes returns the routes of a bundle that are not deleted, in
+	// the bundle's order.
+	ListBundleRoutes(ctx context.Context, bundleRouteID int64) ([]PaidRoute, error)
+	ListCouponsByUserID(ctx context.Context, userID int64) ([]Coupon, error)
+	// ListDiscoverablePaidRoutes returns a page of the enabled routes listed for
+	// discovery, newest first. Empty filters match any route, pay_to matches the
+	// owner's payment address or default_pay_to for owners without one.
+	ListDiscoverablePaidRoutes(ctx context.Context, arg ListDiscoverablePaidRoutesParams) ([]PaidRoute, error)
+	// ListDueRoutePriceChanges returns the price changes not applied yet that
+	// are due, earliest first.
+	ListDueRoutePriceChanges(ctx context.Context, arg ListDueRoutePriceChangesParams) ([]RoutePriceChange, error)
+	// ListHealthCheckTargets returns the targets of enabled routes with a health check path.
+	ListHealthCheckTargets(ctx context.Context) ([]ListHealthCheckTargetsRow, error)
+	// ListLedgerBalancesByUserID returns the balances of the accounts of a user.
+	ListLedgerBalancesByUserID(ctx context.Context, userID pgtype.Int8) ([]ListLedgerBalancesByUserIDRow, error)
+	// ListOverdrawnRouteAccounts returns the route accounts that paid out more
+	// than they received.
+	ListOverdrawnRouteAccounts(ctx context.Context) ([]ListOverdrawnRouteAccountsRow, error)
+	ListPayerRulesByUserID(ctx context.Context, userID int64) ([]PayerRule, error)
+	// ListPayoutTotalsByRouteID returns what each payee is owed for a route.
+	ListPayoutTotalsByRouteID(ctx context.Context, paidRouteID int64) ([]ListPayoutTotalsByRouteIDRow, error)
+	// ListPayoutsByUserID returns the payouts of all routes of a user.
+	ListPayoutsByUserID(ctx context.Context, userID int64) ([]ListPayoutsByUserIDRow, error)
+	// ListPendingPurchasesByUserID retrieves the purchases of a user's routes that
+	// are not settled yet.
+	ListPendingPurchasesByUserID(ctx context.Context, userID int64) ([]Purchase, error)
 	// ListPurchasesByUserID retrieves all purchases for a specific user via paid_routes.
 	ListPurchasesByUserID(ctx context.Context, userID int64) ([]Purchase, error)
+	// ListPurchasesWithoutLedger returns the IDs of settled purchases that have no
+	// purchase transaction in the ledger.
+	ListPurchasesWithoutLedger(ctx context.Context) ([]int64, error)
+	// ListRoutePacks returns the packs of a route, cheapest first.
+	ListRoutePacks(ctx context.Context, paidRouteID int64) ([]RoutePack, error)
+	// ListRoutePriceChanges returns the price changes of a route, earliest first.
+	ListRoutePriceChanges(ctx context.Context, paidRouteID int64) ([]RoutePriceChange, error)
+	// ListRouteSplits returns the revenue shares of a route.
+	ListRouteSplits(ctx context.Context, paidRouteID int64) ([]RouteSplit, error)
+	// ListRouteTargets returns the upstream pool of a route in failover order.
+	ListRouteTargets(ctx context.Context, paidRouteID int64) ([]RouteTarget, error)
+	// ListSettlementAlertsByUserID returns the alerts a user has not dismissed,
+	// newest first, with the purchase they were raised for.
+	ListSettlementAlertsByUserID(ctx context.Context, arg ListSettlementAlertsByUserIDParams) ([]ListSettlementAlertsByUserIDRow, error)
+	// ListStalePendingPurchases retrieves pending purchases created before the
+	// given time that were not flagged for review yet.
+	ListStalePendingPurchases(ctx context.Context, arg ListStalePendingPurchasesParams) ([]Purchase, error)
+	// ListUnbalancedLedgerTransactions returns the transactions whose entries
+	// don't add up to zero, or that have no entries at all.
+	ListUnbalancedLedgerTransactions(ctx context.Context) ([]ListUnbalancedLedgerTransactionsRow, error)
+	// ListUnsettledChecks returns the settlements still to be verified, least
+	// recently checked first, with what the purchase expects to be paid.
+	ListUnsettledChecks(ctx context.Context, limit int32) ([]ListUnsettledChecksRow, error)
 	// ListUserPaidRoutes returns all paid routes for a specific user.
 	ListUserPaidRoutes(ctx context.Context, userID int64) ([]PaidRoute, error)
+	ListWalletDraws(ctx context.Context, arg ListWalletDrawsParams) ([]ListWalletDrawsRow, error)
+	ListWalletTopUps(ctx context.Context, arg ListWalletTopUpsParams) ([]WalletTopup, error)
+	ListWalletsByUserID(ctx context.Context, userID int64) ([]Wallet, error)
+	// ListWalletsOffLedger returns the wallets whose balance differs from the
+	// balance of their ledger account.
+	ListWalletsOffLedger(ctx context.Context) ([]ListWalletsOffLedgerRow, error)
+	MarkRoutePriceChangeApplied(ctx context.Context, arg MarkRoutePriceChangeAppliedParams) (int64, error)
+	// RegisterPaymentNonce registers the authorization of a payment for a
+	// purchase. No row is inserted if the authorization was already used.
+	RegisterPaymentNonce(ctx context.Context, arg RegisterPaymentNonceParams) (int64, error)
+	// RegisterTopUpPaymentNonce registers the authorization of a payment for a
+	// wallet top-up. No row is inserted if the authorization was already used.
+	RegisterTopUpPaymentNonce(ctx context.Context, arg RegisterTopUpPaymentNonceParams) (int64, error)
+	// ReleaseIdempotencyKey removes a key whose request is still in progress, so
+	// it can be retried.
+	ReleaseIdempotencyKey(ctx context.Context, arg ReleaseIdempotencyKeyParams) error
+	// ReleasePaymentNonce releases the authorization of a purchase's payment, so
+	// it can be used again.
+	ReleasePaymentNonce(ctx context.Context, purchaseID pgtype.Int8) error
+	// ReleasePurchaseRouteSale uncounts the sale of a purchase's route.
+	ReleasePurchaseRouteSale(ctx context.Context, id int64) error
+	// ReleaseTopUpPaymentNonce releases the authorization of a top-up's payment,
+	// so it can be used again.
+	ReleaseTopUpPaymentNonce(ctx context.Context, walletTopupID pgtype.Int8) error
+	// ReserveRouteSale counts a sale of a route, unless its sales limit is
+	// reached.
+	ReserveRouteSale(ctx context.Context, id int64) (int64, error)
+	SetPurchaseAffiliateCommission(ctx context.Context, arg SetPurchaseAffiliateCommissionParams) error
+	// SettlePurchase marks a pending purchase as settled with the settle response.
+	SettlePurchase(ctx context.Context, arg SettlePurchaseParams) (int64, error)
+	// SettleWalletTopUp marks a pending top-up as settled and returns it. No row
+	// is returned if it is not pending.
+	SettleWalletTopUp(ctx context.Context, arg SettleWalletTopUpParams) (WalletTopup, error)
+	// UpdatePaidRouteLoadBalancing updates the load balancing settings of a route.
+	UpdatePaidRouteLoadBalancing(ctx context.Context, arg UpdatePaidRouteLoadBalancingParams) (int64, error)
+	// UpdatePaidRoutePrice changes the price of a route.
+	UpdatePaidRoutePrice(ctx context.Context, arg UpdatePaidRoutePriceParams) (int64, error)
+	// UpdateRouteTargetHealth records the result of an active health check.
+	UpdateRouteTargetHealth(ctx context.Context, arg UpdateRouteTargetHealthParams) error
+	// UpdateSettlementCheck records the result of an on-chain verification.
+	UpdateSettlementCheck(ctx context.Context, arg UpdateSettlementCheckParams) error
 	// UpdateUserPaymentAddress updates a user's payment address.
 	UpdateUserPaymentAddress(ctx context.Context, arg UpdateUserPaymentAddressParams) (User, error)
 	UpdateUserProxySecret(ctx context.Context, arg UpdateUserProxySecretParams) (User, error)
+	UpsertRouteTrial(ctx context.Context, arg UpsertRouteTrialParams) error
+	// UseTrialRequest counts a free request of a client unless the client used
+	// all free requests. Returns the number of free requests the client used.
+	UseTrialRequest(ctx context.Context, arg UseTrialRequestParams) (int64, error)
+	// UseWalletChallenge records a wallet challenge as used. No row is inserted
+	// if it was already used.
+	UseWalletChallenge(ctx context.Context, arg UseWalletChallengeParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,57 @@
+-- name: CreateAffiliate :one
+INSERT INTO affiliates (
+    user_id, paid_route_id, code, payout_address, label, commission_bps,
+    token_hash, created_at, updated_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $8
+)
+RETURNING *;
+
+-- name: GetAffiliate :one
+SELECT * FROM affiliates
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: GetAffiliateByTokenHash :one
+SELECT * FROM affiliates
+WHERE token_hash = $1 AND deleted_at IS NULL;
+
+-- name: GetAffiliateForRoute :one
+-- GetAffiliateForRoute retrieves an owner's affiliate by code if it earns
+-- commission on the route.
+SELECT * FROM affiliates
+WHERE user_id = $1 AND code = $2
+  AND (paid_route_id IS NULL OR paid_route_id = $3)
+  AND deleted_at IS NULL;
+
+-- name: ListAffiliatesByUserID :many
+SELECT * FROM affiliates
+WHERE user_id = $1 AND deleted_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: DeleteAffiliate :execrows
+UPDATE affiliates
+SET deleted_at = $3, updated_at = $3
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: ListAffiliateEarnings :many
+-- ListAffiliateEarnings sums the settled purchases the given affiliates
+-- referred, per affiliate.
+SELECT
+    affiliate_id::BIGINT AS affiliate_id,
+    COUNT(CASE WHEN is_test = false THEN 1 END) AS sale_count,
+    COALESCE(SUM(CASE WHEN is_test = false THEN price ELSE 0 END), 0)::BIGINT AS sales,
+    COALESCE(SUM(CASE WHEN is_test = false THEN affiliate_commission ELSE 0 END), 0)::BIGINT AS commission,
+    COUNT(CASE WHEN is_test = true THEN 1 END) AS test_sale_count,
+    COALESCE(SUM(CASE WHEN is_test = true THEN price ELSE 0 END), 0)::BIGINT AS test_sales,
+    COALESCE(SUM(CASE WHEN is_test = true THEN affiliate_commission ELSE 0 END), 0)::BIGINT AS test_commission
+FROM purchases
+WHERE affiliate_id = ANY(@affiliate_ids::BIGINT[]) AND status = 'settled'
+GROUP BY affiliate_id;
+
+-- name: ListAffiliateSales :many
+SELECT p.id, pr.short_code, p.price, p.affiliate_commission, p.is_test, p.created_at
+FROM purchases p
+JOIN paid_routes pr ON p.paid_route_id = pr.id
+WHERE p.affiliate_id = $1 AND p.status = 'settled'
+ORDER BY p.created_at DESC, p.id DESC
+LIMIT $2;
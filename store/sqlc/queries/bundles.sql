@@ -0,0 +1,35 @@
+-- name: CreateBundle :one
+INSERT INTO bundles (
+    paid_route_id, access_seconds, created_at
+) VALUES (
+    $1, $2, $3
+)
+RETURNING *;
+
+-- name: AddBundleRoute :exec
+INSERT INTO bundle_routes (
+    bundle_route_id, paid_route_id, position
+) VALUES (
+    $1, $2, $3
+);
+
+-- name: GetBundle :one
+SELECT * FROM bundles
+WHERE paid_route_id = $1;
+
+-- name: ListBundleRoutes :many
+-- ListBundleRoutes returns the routes of a bundle that are not deleted, in
+-- the bundle's order.
+SELECT paid_routes.* FROM bundle_routes
+JOIN paid_routes ON paid_routes.id = bundle_routes.paid_route_id
+WHERE bundle_routes.bundle_route_id = $1 AND paid_routes.deleted_at IS NULL
+ORDER BY bundle_routes.position;
+
+-- name: GetBundlePurchaseByRouteIDAndPaymentHeader :one
+-- GetBundlePurchaseByRouteIDAndPaymentHeader returns the latest settled
+-- purchase of a bundle the route is part of that was paid with the header.
+SELECT purchases.* FROM purchases
+JOIN bundle_routes ON bundle_routes.bundle_route_id = purchases.paid_route_id
+WHERE bundle_routes.paid_route_id = $1 AND purchases.payment_header = $2
+    AND purchases.status = 'settled'
+ORDER BY purchases.created_at DESC LIMIT 1;
@@ -0,0 +1,80 @@
+-- name: CreateCoupon :one
+INSERT INTO coupons (
+    user_id, paid_route_id, code, discount_type, discount_value,
+    max_redemptions, expires_at, created_at, updated_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $8
+)
+RETURNING *;
+
+-- name: GetCouponForRoute :one
+-- GetCouponForRoute retrieves an owner's coupon by code if it applies to the
+-- route.
+SELECT * FROM coupons
+WHERE user_id = $1 AND code = $2
+  AND (paid_route_id IS NULL OR paid_route_id = $3)
+  AND deleted_at IS NULL;
+
+-- name: ListCouponsByUserID :many
+SELECT * FROM coupons
+WHERE user_id = $1 AND deleted_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: DeleteCoupon :execrows
+UPDATE coupons
+SET deleted_at = $3, updated_at = $3
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: IncrementCouponRedemptions :execrows
+-- IncrementCouponRedemptions counts a redemption of a coupon unless it is
+-- deleted, expired or used up.
+UPDATE coupons
+SET redemption_count = redemption_count + 1, updated_at = $2
+WHERE id = $1 AND deleted_at IS NULL
+  AND (max_redemptions = 0 OR redemption_count < max_redemptions)
+  AND (expires_at IS NULL OR expires_at > $2);
+
+-- name: DecrementCouponRedemptions :exec
+UPDATE coupons
+SET redemption_count = redemption_count - 1, updated_at = $2
+WHERE id = $1 AND redemption_count > 0;
+
+-- name: CreateCouponRedemption :exec
+INSERT INTO coupon_redemptions (
+    coupon_id, paid_route_id, purchase_id, discount, created_at
+) VALUES (
+    $1, $2, $3, $4, $5
+);
+
+-- name: DeleteCouponRedemptionByPurchaseID :one
+-- DeleteCouponRedemptionByPurchaseID deletes the redemption of a purchase and
+-- returns the coupon it redeemed.
+DELETE FROM coupon_redemptions
+WHERE purchase_id = $1
+RETURNING coupon_id;
+
+-- name: GetRouteTrial :one
+SELECT * FROM route_trials
+WHERE paid_route_id = $1;
+
+-- name: UpsertRouteTrial :exec
+INSERT INTO route_trials (
+    paid_route_id, free_requests, created_at, updated_at
+) VALUES (
+    $1, $2, $3, $3
+)
+ON CONFLICT (paid_route_id) DO UPDATE
+SET free_requests = EXCLUDED.free_requests, updated_at = EXCLUDED.updated_at;
+
+-- name: UseTrialRequest :one
+-- UseTrialRequest counts a free request of a client unless the client used
+-- all free requests. Returns the number of free requests the client used.
+INSERT INTO trial_uses (
+    paid_route_id, client_key, used_count, created_at, updated_at
+) VALUES (
+    $1, $2, 1, $4, $4
+)
+ON CONFLICT (paid_route_id, client_key) DO UPDATE
+SET used_count = trial_uses.used_count + 1, updated_at = EXCLUDED.updated_at
+WHERE trial_uses.used_count < $3
+RETURNING used_count;
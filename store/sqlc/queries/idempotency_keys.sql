@@ -0,0 +1,42 @@
+-- name: ClaimIdempotencyKey :execrows
+-- ClaimIdempotencyKey records a request in progress for a key. An expired key
+-- is claimed again, no row is affected if the key is in use.
+INSERT INTO idempotency_keys (
+    paid_route_id, scope, idempotency_key, fingerprint,
+    expires_at, created_at, updated_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $6
+)
+ON CONFLICT (paid_route_id, scope, idempotency_key) DO UPDATE
+SET fingerprint = EXCLUDED.fingerprint,
+    response_status = 0,
+    response_header = NULL,
+    response_body = NULL,
+    expires_at = EXCLUDED.expires_at,
+    created_at = EXCLUDED.created_at,
+    updated_at = EXCLUDED.updated_at
+WHERE idempotency_keys.expires_at <= EXCLUDED.created_at;
+
+-- name: CompleteIdempotencyKey :exec
+-- CompleteIdempotencyKey stores the response to the request of a key.
+UPDATE idempotency_keys
+SET response_status = $4, response_header = $5, response_body = $6, updated_at = $7
+WHERE paid_route_id = $1 AND scope = $2 AND idempotency_key = $3;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+-- DeleteExpiredIdempotencyKeys removes the keys that expired before the
+-- given time.
+DELETE FROM idempotency_keys
+WHERE expires_at <= $1;
+
+-- name: GetIdempotencyKey :one
+-- GetIdempotencyKey returns a key of a route and purchase.
+SELECT * FROM idempotency_keys
+WHERE paid_route_id = $1 AND scope = $2 AND idempotency_key = $3;
+
+-- name: ReleaseIdempotencyKey :exec
+-- ReleaseIdempotencyKey removes a key whose request is still in progress, so
+-- it can be retried.
+DELETE FROM idempotency_keys
+WHERE paid_route_id = $1 AND scope = $2 AND idempotency_key = $3
+    AND response_status = 0;
@@ -0,0 +1,105 @@
+-- name: CreateLedgerAccount :one
+-- CreateLedgerAccount returns the ID of a ledger account, creating it first
+-- if it doesn't exist yet.
+INSERT INTO ledger_accounts (
+    kind, reference, user_id, created_at
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (kind, reference) DO UPDATE SET kind = EXCLUDED.kind
+RETURNING id;
+
+-- name: CreateLedgerTransaction :one
+-- CreateLedgerTransaction creates a ledger transaction and returns its ID.
+-- No row is returned if a transaction with the reference already exists.
+INSERT INTO ledger_transactions (
+    reference, kind, purchase_id, is_test, created_at
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+ON CONFLICT (reference) DO NOTHING
+RETURNING id;
+
+-- name: CreateLedgerEntry :exec
+-- CreateLedgerEntry posts an entry of a ledger transaction.
+INSERT INTO ledger_entries (
+    transaction_id, account_id, amount, created_at
+) VALUES (
+    $1, $2, $3, $4
+);
+
+-- name: GetLedgerPurchaseEarnings :one
+-- GetLedgerPurchaseEarnings returns what the route account kept of a
+-- purchase after fees and splits.
+SELECT COALESCE(SUM(e.amount), 0)::BIGINT AS earnings
+FROM ledger_entries e
+JOIN ledger_transactions t ON e.transaction_id = t.id
+JOIN ledger_accounts a ON e.account_id = a.id
+WHERE t.purchase_id = $1
+    AND a.kind = 'route'
+    AND t.kind IN ('purchase', 'fee', 'split');
+
+-- name: ListLedgerBalancesByUserID :many
+-- ListLedgerBalancesByUserID returns the balances of the accounts of a user.
+SELECT
+    a.kind,
+    a.reference,
+    t.is_test,
+    SUM(e.amount)::BIGINT AS balance,
+    COUNT(*) AS entry_count
+FROM ledger_accounts a
+JOIN ledger_entries e ON e.account_id = a.id
+JOIN ledger_transactions t ON e.transaction_id = t.id
+WHERE a.user_id = $1
+GROUP BY a.kind, a.reference, t.is_test
+ORDER BY a.kind, a.reference, t.is_test;
+
+-- name: ListUnbalancedLedgerTransactions :many
+-- ListUnbalancedLedgerTransactions returns the transactions whose entries
+-- don't add up to zero, or that have no entries at all.
+SELECT
+    t.id,
+    t.reference,
+    COALESCE(SUM(e.amount), 0)::BIGINT AS imbalance
+FROM ledger_transactions t
+LEFT JOIN ledger_entries e ON e.transaction_id = t.id
+GROUP BY t.id, t.reference
+HAVING COALESCE(SUM(e.amount), 0) <> 0 OR COUNT(e.id) = 0
+ORDER BY t.id;
+
+-- name: ListPurchasesWithoutLedger :many
+-- ListPurchasesWithoutLedger returns the IDs of settled purchases that have no
+-- purchase transaction in the ledger.
+SELECT p.id FROM purchases p
+WHERE p.price > 0 AND p.status = 'settled' AND NOT EXISTS (
+    SELECT 1 FROM ledger_transactions t
+    WHERE t.purchase_id = p.id AND t.kind = 'purchase'
+)
+ORDER BY p.id;
+
+-- name: ListOverdrawnRouteAccounts :many
+-- ListOverdrawnRouteAccounts returns the route accounts that paid out more
+-- than they received.
+SELECT
+    a.reference,
+    SUM(e.amount)::BIGINT AS balance
+FROM ledger_accounts a
+JOIN ledger_entries e ON e.account_id = a.id
+WHERE a.kind = 'route'
+GROUP BY a.id, a.reference
+HAVING SUM(e.amount) < 0
+ORDER BY a.reference;
+
+-- name: ListWalletsOffLedger :many
+-- ListWalletsOffLedger returns the wallets whose balance differs from the
+-- balance of their ledger account.
+SELECT
+    w.id,
+    w.balance,
+    COALESCE(SUM(e.amount), 0)::BIGINT AS ledger_balance
+FROM wallets w
+LEFT JOIN ledger_accounts a ON a.kind = 'wallet' AND a.reference = w.id::TEXT
+LEFT JOIN ledger_entries e ON e.account_id = a.id
+GROUP BY w.id, w.balance
+HAVING w.balance <> COALESCE(SUM(e.amount), 0)
+ORDER BY w.id;
@@ -33,10 +33,16 @@ INSERT INTO paid_routes (
     user_id, is_enabled, attempt_count, payment_count, access_count,
     created_at, updated_at,
     type, credits, payment_protocol_version, resource_type, original_filename, cover_url,
-    title, description
+    title, description, cache_enabled, cache_ttl_seconds, cache_vary_headers,
+    stream_max_seconds, stream_credit_seconds, billing_mode, unit_price,
+    quote_url, min_price, max_price, available_from, available_until, max_sales,
+    pay_what_you_want, payment_rails, discoverable, mime_type,
+    body_schema, query_schema, response_schema
 ) VALUES (
     $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12,
-    $13, $14, $15, $16, $17, $18, $19, $20
+    $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23,
+    $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34,
+    $35, $36, $37, $38, $39, $40
 ) RETURNING *;
 
 -- name: IncrementAttemptCount :exec
@@ -64,4 +70,72 @@ WHERE short_code = $1 AND deleted_at IS NULL;
 -- DeletePaidRoute soft-deletes a paid route.
 UPDATE paid_routes SET
     deleted_at = $3
-WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL; 
\ No newline at end of file
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL; 
+
+-- name: UpdatePaidRouteLoadBalancing :execrows
+-- UpdatePaidRouteLoadBalancing updates the load balancing settings of a route.
+UPDATE paid_routes SET
+    lb_strategy = $3,
+    health_check_path = $4,
+    updated_at = $5
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: UpdatePaidRoutePrice :execrows
+-- UpdatePaidRoutePrice changes the price of a route.
+UPDATE paid_routes SET
+    price = $2,
+    updated_at = $3
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: ReserveRouteSale :execrows
+-- ReserveRouteSale counts a sale of a route, unless its sales limit is
+-- reached.
+UPDATE paid_routes SET
+    sold_count = sold_count + 1
+WHERE id = $1 AND (max_sales = 0 OR sold_count < max_sales);
+
+-- name: ReleasePurchaseRouteSale :exec
+-- ReleasePurchaseRouteSale uncounts the sale of a purchase's route.
+UPDATE paid_routes SET
+    sold_count = sold_count - 1
+WHERE id = (SELECT paid_route_id FROM purchases WHERE purchases.id = $1) AND sold_count > 0;
+
+-- name: DisableEndedPaidRoutes :execrows
+-- DisableEndedPaidRoutes disables the enabled routes whose availability
+-- window closed.
+UPDATE paid_routes SET
+    is_enabled = false,
+    updated_at = $1
+WHERE is_enabled = true AND deleted_at IS NULL
+    AND available_until <= $1;
+
+-- name: ListDiscoverablePaidRoutes :many
+-- ListDiscoverablePaidRoutes returns a page of the enabled routes listed for
+-- discovery, newest first. Empty filters match any route, pay_to matches the
+-- owner's payment address or default_pay_to for owners without one.
+SELECT paid_routes.* FROM paid_routes
+JOIN users ON users.id = paid_routes.user_id
+WHERE paid_routes.discoverable = true AND paid_routes.is_enabled = true
+    AND paid_routes.deleted_at IS NULL
+    AND (@method::TEXT = '' OR paid_routes.method = @method::TEXT)
+    AND (sqlc.narg('is_test')::BOOLEAN IS NULL OR paid_routes.is_test = sqlc.narg('is_test')::BOOLEAN)
+    AND (@max_price::BIGINT = 0 OR paid_routes.price <= @max_price::BIGINT)
+    AND (@pay_to::TEXT = '' OR LOWER(COALESCE(NULLIF(users.payment_address, ''), @default_pay_to::TEXT)) = LOWER(@pay_to::TEXT))
+    AND (@search::TEXT = '' OR paid_routes.title ILIKE '%' || @search::TEXT || '%'
+        OR paid_routes.description ILIKE '%' || @search::TEXT || '%')
+ORDER BY paid_routes.created_at DESC, paid_routes.id DESC
+LIMIT @row_limit OFFSET @row_offset;
+
+-- name: CountDiscoverablePaidRoutes :one
+-- CountDiscoverablePaidRoutes counts the routes ListDiscoverablePaidRoutes
+-- pages through.
+SELECT COUNT(*) FROM paid_routes
+JOIN users ON users.id = paid_routes.user_id
+WHERE paid_routes.discoverable = true AND paid_routes.is_enabled = true
+    AND paid_routes.deleted_at IS NULL
+    AND (@method::TEXT = '' OR paid_routes.method = @method::TEXT)
+    AND (sqlc.narg('is_test')::BOOLEAN IS NULL OR paid_routes.is_test = sqlc.narg('is_test')::BOOLEAN)
+    AND (@max_price::BIGINT = 0 OR paid_routes.price <= @max_price::BIGINT)
+    AND (@pay_to::TEXT = '' OR LOWER(COALESCE(NULLIF(users.payment_address, ''), @default_pay_to::TEXT)) = LOWER(@pay_to::TEXT))
+    AND (@search::TEXT = '' OR paid_routes.title ILIKE '%' || @search::TEXT || '%'
+        OR paid_routes.description ILIKE '%' || @search::TEXT || '%');
@@ -0,0 +1,25 @@
+-- name: CreatePayerRule :one
+INSERT INTO payer_rules (
+    user_id, paid_route_id, payer_address, treatment, discount_bps, label, created_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetPayerRuleForRoute :one
+-- GetPayerRuleForRoute retrieves the rule of an owner for a payer on a route,
+-- preferring a rule for the route over a rule for all routes.
+SELECT * FROM payer_rules
+WHERE user_id = $1 AND payer_address = $2
+  AND (paid_route_id IS NULL OR paid_route_id = $3)
+ORDER BY paid_route_id IS NULL
+LIMIT 1;
+
+-- name: ListPayerRulesByUserID :many
+SELECT * FROM payer_rules
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: DeletePayerRule :execrows
+DELETE FROM payer_rules
+WHERE id = $1 AND user_id = $2;
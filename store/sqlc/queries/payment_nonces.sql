@@ -0,0 +1,31 @@
+-- name: RegisterPaymentNonce :execrows
+-- RegisterPaymentNonce registers the authorization of a payment for a
+-- purchase. No row is inserted if the authorization was already used.
+INSERT INTO payment_nonces (
+    network, payer, nonce, purchase_id, created_at
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+ON CONFLICT (network, payer, nonce) DO NOTHING;
+
+-- name: ReleasePaymentNonce :exec
+-- ReleasePaymentNonce releases the authorization of a purchase's payment, so
+-- it can be used again.
+DELETE FROM payment_nonces
+WHERE purchase_id = $1;
+
+-- name: RegisterTopUpPaymentNonce :execrows
+-- RegisterTopUpPaymentNonce registers the authorization of a payment for a
+-- wallet top-up. No row is inserted if the authorization was already used.
+INSERT INTO payment_nonces (
+    network, payer, nonce, wallet_topup_id, created_at
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+ON CONFLICT (network, payer, nonce) DO NOTHING;
+
+-- name: ReleaseTopUpPaymentNonce :exec
+-- ReleaseTopUpPaymentNonce releases the authorization of a top-up's payment,
+-- so it can be used again.
+DELETE FROM payment_nonces
+WHERE wallet_topup_id = $1;
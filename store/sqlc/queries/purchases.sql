@@ -1,20 +1,60 @@
+-- name: ChargePurchaseUsage :one
+-- ChargePurchaseUsage records usage on a purchase and charges its amount,
+-- capped at what is left of the price paid for the purchase. Usage units
+-- saturate at the column's range. The amount charged before is returned
+-- alongside the updated purchase.
+WITH previous AS (
+    SELECT id, amount_charged FROM purchases
+    WHERE id = $1
+    FOR UPDATE
+)
+UPDATE purchases p
+SET usage_units = p.usage_units + LEAST($2, 9223372036854775807 - p.usage_units),
+    amount_charged = p.amount_charged + LEAST($3, p.price - p.amount_charged),
+    updated_at = $4
+FROM previous
+WHERE p.id = previous.id
+RETURNING p.*, previous.amount_charged AS previous_amount_charged;
+
 -- name: CreatePurchase :one
 -- CreatePurchase creates a new purchase record.
 INSERT INTO purchases (
     short_code, target_url, method, price, is_test,
     payment_payload, settle_response, paid_route_id, paid_to_address,
     created_at, updated_at,
-    type, credits_available, credits_used, payment_header
+    type, credits_available, credits_used, payment_header,
+    status, payment_requirements, pack_id, affiliate_id
 ) VALUES (
     $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11,
-    $12, $13, $14, $15
+    $12, $13, $14, $15, $16, $17, $18, $19
 ) RETURNING id;
 
+-- name: FailPurchase :execrows
+-- FailPurchase marks a pending purchase as failed to settle.
+UPDATE purchases
+SET status = 'failed', settle_error = $2, updated_at = $3
+WHERE id = $1 AND status = 'pending';
+
+-- name: FlagPendingPurchase :execrows
+-- FlagPendingPurchase records why a pending purchase could not be resolved
+-- automatically.
+UPDATE purchases
+SET settle_error = $2, updated_at = $3
+WHERE id = $1 AND status = 'pending';
+
 -- name: GetPurchaseByID :one
 -- GetPurchaseByID returns a purchase by ID.
 SELECT * FROM purchases
 WHERE id = $1;
 
+-- name: ListPendingPurchasesByUserID :many
+-- ListPendingPurchasesByUserID retrieves the purchases of a user's routes that
+-- are not settled yet.
+SELECT p.* FROM purchases p
+JOIN paid_routes pr ON p.paid_route_id = pr.id
+WHERE pr.user_id = $1 AND p.status = 'pending'
+ORDER BY p.created_at DESC;
+
 -- name: ListPurchasesByUserID :many
 -- ListPurchasesByUserID retrieves all purchases for a specific user via paid_routes.
 SELECT p.* FROM purchases p
@@ -22,23 +62,36 @@ JOIN paid_routes pr ON p.paid_route_id = pr.id
 WHERE pr.user_id = $1
 ORDER BY p.created_at DESC;
 
+-- name: ListStalePendingPurchases :many
+-- ListStalePendingPurchases retrieves pending purchases created before the
+-- given time that were not flagged for review yet.
+SELECT * FROM purchases
+WHERE status = 'pending' AND settle_error = '' AND created_at < $1
+ORDER BY created_at
+LIMIT $2;
+
 -- name: GetDailyStats :many
 -- GetDailyStats retrieves daily purchase stats for a specific user.
+-- Requests paid from wallets count as purchases.
 SELECT 
-    to_char(p.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS date,
+    to_char(t.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS date,
     COUNT(*) AS count,
-    SUM(p.price) AS earnings,
-    COUNT(CASE WHEN p.is_test = true THEN 1 END) AS test_count,
-    COALESCE(SUM(CASE WHEN p.is_test = true THEN p.price ELSE 0 END), 0) AS test_earnings,
-    COUNT(CASE WHEN p.is_test = false THEN 1 END) AS real_count,
-    COALESCE(SUM(CASE WHEN p.is_test = false THEN p.price ELSE 0 END), 0) AS real_earnings
+    SUM(e.amount)::BIGINT AS earnings,
+    COUNT(CASE WHEN t.is_test = true THEN 1 END) AS test_count,
+    COALESCE(SUM(CASE WHEN t.is_test = true THEN e.amount ELSE 0 END), 0)::BIGINT AS test_earnings,
+    COUNT(CASE WHEN t.is_test = false THEN 1 END) AS real_count,
+    COALESCE(SUM(CASE WHEN t.is_test = false THEN e.amount ELSE 0 END), 0)::BIGINT AS real_earnings
 FROM 
-    purchases p
+    ledger_entries e
+JOIN 
+    ledger_transactions t ON e.transaction_id = t.id
 JOIN 
-    paid_routes pr ON p.paid_route_id = pr.id
+    ledger_accounts a ON e.account_id = a.id
 WHERE 
-    pr.user_id = $1
-    AND p.created_at >= CURRENT_TIMESTAMP - ($2 || ' days')::INTERVAL
+    a.user_id = $1
+    AND a.kind = 'route'
+    AND t.kind IN ('purchase', 'wallet_draw')
+    AND t.created_at >= CURRENT_TIMESTAMP - ($2 || ' days')::INTERVAL
 GROUP BY 
     date
 ORDER BY 
@@ -46,22 +99,41 @@ ORDER BY
 
 -- name: GetTotalStats :one
 -- GetTotalStats retrieves total purchase stats for a specific user.
+-- Requests paid from wallets count as purchases.
 SELECT 
-    COALESCE(SUM(p.price), 0) AS total_earnings,
+    COALESCE(SUM(e.amount), 0)::BIGINT AS total_earnings,
     COUNT(*) AS total_count
 FROM 
-    purchases p
+    ledger_entries e
 JOIN 
-    paid_routes pr ON p.paid_route_id = pr.id
+    ledger_transactions t ON e.transaction_id = t.id
+JOIN 
+    ledger_accounts a ON e.account_id = a.id
 WHERE 
-    pr.user_id = $1;
+    a.user_id = $1
+    AND a.kind = 'route'
+    AND t.kind IN ('purchase', 'wallet_draw');
 
 -- name: GetPurchaseByRouteIDAndPaymentHeader :one
 SELECT * FROM purchases
-WHERE paid_route_id = $1 AND payment_header = $2
+WHERE paid_route_id = $1 AND payment_header = $2 AND status = 'settled'
 ORDER BY created_at DESC LIMIT 1;
 
--- name: IncrementPurchaseCreditsUsed :exec
+-- name: IncrementPurchaseCreditsUsed :one
+-- IncrementPurchaseCreditsUsed uses a credit of a purchase and returns the
+-- number of credits used. No row is returned if no credit is left.
 UPDATE purchases
 SET credits_used = credits_used + 1, updated_at = $2
-WHERE id = $1 AND credits_used < credits_available; 
\ No newline at end of file
+WHERE id = $1 AND credits_used < credits_available
+RETURNING credits_used;
+
+-- name: SettlePurchase :execrows
+-- SettlePurchase marks a pending purchase as settled with the settle response.
+UPDATE purchases
+SET status = 'settled', settle_response = $2, settle_error = '', updated_at = $3
+WHERE id = $1 AND status = 'pending';
+
+-- name: SetPurchaseAffiliateCommission :exec
+UPDATE purchases
+SET affiliate_commission = $2, updated_at = $3
+WHERE id = $1;
@@ -0,0 +1,18 @@
+-- name: CreateRoutePack :one
+INSERT INTO route_packs (
+    paid_route_id, credits, price, label, created_at
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: ListRoutePacks :many
+-- ListRoutePacks returns the packs of a route, cheapest first.
+SELECT * FROM route_packs
+WHERE paid_route_id = $1 AND deleted_at IS NULL
+ORDER BY price, id;
+
+-- name: DeleteRoutePack :execrows
+UPDATE route_packs
+SET deleted_at = $3
+WHERE id = $1 AND paid_route_id = $2 AND deleted_at IS NULL;
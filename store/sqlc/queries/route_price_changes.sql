@@ -0,0 +1,31 @@
+-- name: CreateRoutePriceChange :one
+INSERT INTO route_price_changes (
+    paid_route_id, price, effective_at, created_at
+) VALUES (
+    $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: ListRoutePriceChanges :many
+-- ListRoutePriceChanges returns the price changes of a route, earliest first.
+SELECT * FROM route_price_changes
+WHERE paid_route_id = $1
+ORDER BY effective_at, id;
+
+-- name: ListDueRoutePriceChanges :many
+-- ListDueRoutePriceChanges returns the price changes not applied yet that
+-- are due, earliest first.
+SELECT * FROM route_price_changes
+WHERE applied_at IS NULL AND effective_at <= $1
+ORDER BY effective_at, id
+LIMIT $2;
+
+-- name: MarkRoutePriceChangeApplied :execrows
+UPDATE route_price_changes
+SET applied_at = $2
+WHERE id = $1 AND applied_at IS NULL;
+
+-- name: DeleteRoutePriceChange :execrows
+-- DeleteRoutePriceChange deletes a price change that was not applied yet.
+DELETE FROM route_price_changes
+WHERE id = $1 AND paid_route_id = $2 AND applied_at IS NULL;
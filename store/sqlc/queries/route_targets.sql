@@ -0,0 +1,35 @@
+-- name: CreateRouteTarget :one
+-- CreateRouteTarget adds an upstream target to a route's pool.
+INSERT INTO route_targets (
+    paid_route_id, target_url, weight, created_at, updated_at
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: ListRouteTargets :many
+-- ListRouteTargets returns the upstream pool of a route in failover order.
+SELECT * FROM route_targets
+WHERE paid_route_id = $1
+ORDER BY id;
+
+-- name: DeleteRouteTarget :execrows
+-- DeleteRouteTarget removes an upstream target from a route's pool.
+DELETE FROM route_targets
+WHERE id = $1 AND paid_route_id = $2;
+
+-- name: ListHealthCheckTargets :many
+-- ListHealthCheckTargets returns the targets of enabled routes with a health check path.
+SELECT rt.*, pr.health_check_path FROM route_targets rt
+JOIN paid_routes pr ON rt.paid_route_id = pr.id
+WHERE pr.health_check_path <> '' AND pr.is_enabled = true AND pr.deleted_at IS NULL
+ORDER BY rt.id;
+
+-- name: UpdateRouteTargetHealth :exec
+-- UpdateRouteTargetHealth records the result of an active health check.
+UPDATE route_targets SET
+    is_healthy = $2,
+    consecutive_failures = $3,
+    last_error = $4,
+    last_checked_at = $5,
+    updated_at = $6
+WHERE id = $1;
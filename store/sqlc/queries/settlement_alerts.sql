@@ -0,0 +1,34 @@
+-- name: CreateSettlementAlert :exec
+-- CreateSettlementAlert records a settlement problem for the owner of the
+-- purchased route.
+INSERT INTO settlement_alerts (
+    user_id, purchase_id, status, reason, network, tx_hash, created_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+);
+
+-- name: ListSettlementAlertsByUserID :many
+-- ListSettlementAlertsByUserID returns the alerts a user has not dismissed,
+-- newest first, with the purchase they were raised for.
+SELECT
+    sa.id,
+    sa.purchase_id,
+    sa.status,
+    sa.reason,
+    sa.network,
+    sa.tx_hash,
+    sa.created_at,
+    p.short_code,
+    p.price,
+    p.is_test
+FROM settlement_alerts sa
+JOIN purchases p ON sa.purchase_id = p.id
+WHERE sa.user_id = $1 AND sa.dismissed_at IS NULL
+ORDER BY sa.created_at DESC, sa.id DESC
+LIMIT $2;
+
+-- name: DismissSettlementAlert :execrows
+-- DismissSettlementAlert hides an alert of a user from their dashboard.
+UPDATE settlement_alerts
+SET dismissed_at = $3
+WHERE id = $1 AND user_id = $2 AND dismissed_at IS NULL;
@@ -0,0 +1,53 @@
+-- name: CreateSettlementCheck :exec
+-- CreateSettlementCheck queues the settlement of a purchase for on-chain
+-- verification.
+INSERT INTO settlement_checks (
+    purchase_id, network, tx_hash, created_at, updated_at
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+ON CONFLICT (purchase_id) DO NOTHING;
+
+-- name: ListUnsettledChecks :many
+-- ListUnsettledChecks returns the settlements still to be verified, least
+-- recently checked first, with what the purchase expects to be paid.
+SELECT
+    sc.purchase_id,
+    sc.network,
+    sc.tx_hash,
+    sc.status,
+    sc.block_number,
+    sc.block_hash,
+    p.price,
+    p.paid_to_address,
+    p.short_code,
+    p.created_at AS purchased_at,
+    pr.user_id
+FROM settlement_checks sc
+JOIN purchases p ON sc.purchase_id = p.id
+JOIN paid_routes pr ON p.paid_route_id = pr.id
+WHERE sc.status IN ('pending', 'reorged')
+ORDER BY sc.checked_at NULLS FIRST, sc.purchase_id
+LIMIT $1;
+
+-- name: UpdateSettlementCheck :exec
+-- UpdateSettlementCheck records the result of an on-chain verification.
+UPDATE settlement_checks
+SET status = $2,
+    block_number = $3,
+    block_hash = $4,
+    confirmations = $5,
+    last_error = $6,
+    checked_at = $7,
+    updated_at = $7
+WHERE purchase_id = $1;
+
+-- name: CountSettlementChecksByRouteID :many
+-- CountSettlementChecksByRouteID returns the number of purchases of a route
+-- per settlement status.
+SELECT sc.status, COUNT(*) AS count
+FROM settlement_checks sc
+JOIN purchases p ON sc.purchase_id = p.id
+WHERE p.paid_route_id = $1
+GROUP BY sc.status
+ORDER BY sc.status;
@@ -0,0 +1,45 @@
+-- name: CreateRouteSplit :one
+-- CreateRouteSplit adds a revenue share of a route for a payee.
+INSERT INTO route_splits (
+    paid_route_id, payee_address, label, share_bps, created_at
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: ListRouteSplits :many
+-- ListRouteSplits returns the revenue shares of a route.
+SELECT * FROM route_splits
+WHERE paid_route_id = $1
+ORDER BY id;
+
+-- name: DeleteRouteSplits :exec
+-- DeleteRouteSplits removes all revenue shares of a route.
+DELETE FROM route_splits
+WHERE paid_route_id = $1;
+
+-- name: CreatePurchasePayout :exec
+-- CreatePurchasePayout records what a payee is owed for a purchase.
+INSERT INTO purchase_payouts (
+    purchase_id, paid_route_id, payee_address, role, share_bps, amount, created_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+);
+
+-- name: ListPayoutTotalsByRouteID :many
+-- ListPayoutTotalsByRouteID returns what each payee is owed for a route.
+SELECT
+    payee_address,
+    role,
+    SUM(amount)::BIGINT AS amount,
+    COUNT(*) AS count
+FROM purchase_payouts
+WHERE paid_route_id = $1
+GROUP BY payee_address, role
+ORDER BY amount DESC;
+
+-- name: ListPayoutsByUserID :many
+-- ListPayoutsByUserID returns the payouts of all routes of a user.
+SELECT pp.*, pr.short_code FROM purchase_payouts pp
+JOIN paid_routes pr ON pp.paid_route_id = pr.id
+WHERE pr.user_id = $1
+ORDER BY pp.created_at DESC, pp.id;
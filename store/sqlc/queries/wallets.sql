@@ -0,0 +1,97 @@
+-- name: CreateWallet :one
+-- CreateWallet creates a payer's wallet with an owner. No row is returned if
+-- the payer already has one.
+INSERT INTO wallets (
+    user_id, payer_address, token_hash, is_test, created_at, updated_at
+) VALUES (
+    $1, $2, $3, $4, $5, $5
+)
+ON CONFLICT (user_id, payer_address, is_test) DO NOTHING
+RETURNING *;
+
+-- name: GetWalletByPayer :one
+SELECT * FROM wallets
+WHERE user_id = $1 AND payer_address = $2 AND is_test = $3;
+
+-- name: GetWalletByTokenHash :one
+SELECT * FROM wallets
+WHERE user_id = $1 AND token_hash = $2;
+
+-- name: ListWalletsByUserID :many
+SELECT * FROM wallets
+WHERE user_id = $1
+ORDER BY updated_at DESC, id;
+
+-- name: CreditWallet :one
+-- CreditWallet adds a settled top-up to a wallet's balance.
+UPDATE wallets
+SET balance = balance + $2, updated_at = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: DebitWallet :one
+-- DebitWallet takes amount from a wallet's balance. No row is returned if
+-- the balance is too low.
+UPDATE wallets
+SET balance = balance - $2, updated_at = $3
+WHERE id = $1 AND balance >= $2
+RETURNING *;
+
+-- name: CreateWalletTopUp :one
+INSERT INTO wallet_topups (
+    wallet_id, amount, status, payment_payload, payment_requirements,
+    created_at, updated_at
+) VALUES (
+    $1, $2, 'pending', $3, $4, $5, $5
+)
+RETURNING id;
+
+-- name: SettleWalletTopUp :one
+-- SettleWalletTopUp marks a pending top-up as settled and returns it. No row
+-- is returned if it is not pending.
+UPDATE wallet_topups
+SET status = 'settled', settle_response = $2, updated_at = $3
+WHERE id = $1 AND status = 'pending'
+RETURNING *;
+
+-- name: FailWalletTopUp :execrows
+UPDATE wallet_topups
+SET status = 'failed', settle_error = $2, updated_at = $3
+WHERE id = $1 AND status = 'pending';
+
+-- name: ListWalletTopUps :many
+SELECT * FROM wallet_topups
+WHERE wallet_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2;
+
+-- name: CreateWalletDraw :one
+INSERT INTO wallet_draws (
+    wallet_id, paid_route_id, amount, created_at
+) VALUES (
+    $1, $2, $3, $4
+)
+RETURNING id;
+
+-- name: ListWalletDraws :many
+SELECT wd.*, pr.short_code FROM wallet_draws wd
+JOIN paid_routes pr ON wd.paid_route_id = pr.id
+WHERE wd.wallet_id = $1
+ORDER BY wd.created_at DESC, wd.id DESC
+LIMIT $2;
+
+-- name: UseWalletChallenge :execrows
+-- UseWalletChallenge records a wallet challenge as used. No row is inserted
+-- if it was already used.
+INSERT INTO wallet_challenges (
+    wallet_id, nonce, expires_at, created_at
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (wallet_id, nonce) DO NOTHING;
+
+-- name: DeleteExpiredWalletChallenges :exec
+-- DeleteExpiredWalletChallenges removes a wallet's challenges that are too
+-- old to be accepted anyway.
+DELETE FROM wallet_challenges
+WHERE wallet_id = $1 AND expires_at < $2;
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"testing"
+
+	"linkshrink/routes"
+	"linkshrink/users"
+	"linkshrink/utils"
+)
+
+// newTestStore connects to the database named by the TEST_DB_* environment
+// variables and applies the migrations. Tests using it are skipped when
+// TEST_DB_HOST is not set.
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	host := os.Getenv("TEST_DB_HOST")
+	if host == "" {
+		t.Skip("TEST_DB_HOST not set, skipping database test")
+	}
+
+	cfg := DefaultConfig()
+	cfg.Host = host
+	if port := os.Getenv("TEST_DB_PORT"); port != "" {
+		var err error
+		if cfg.Port, err = strconv.Atoi(port); err != nil {
+			t.Fatalf("invalid TEST_DB_PORT: %v", err)
+		}
+	}
+	for env, value := range map[string]*string{
+		"TEST_DB_USER":     &cfg.User,
+		"TEST_DB_PASSWORD": &cfg.Password,
+		"TEST_DB_NAME":     &cfg.DBName,
+	} {
+		if v := os.Getenv(env); v != "" {
+			*value = v
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := NewStore(logger, cfg, utils.NewRealClock())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	t.Cleanup(store.db.Close)
+
+	return store
+}
+
+// randomHex returns n random bytes hex encoded with a 0x prefix, so test
+// data doesn't collide with earlier runs against the same database.
+func randomHex(t *testing.T, n int) string {
+	t.Helper()
+
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return "0x" + hex.EncodeToString(b)
+}
+
+// createTestRoute creates a user and a route of theirs with the given
+// payment protocol version.
+func createTestRoute(t *testing.T, store *Store, version uint16) *routes.PaidRoute {
+	t.Helper()
+	ctx := context.Background()
+
+	id := randomHex(t, 8)
+	userID, err := store.CreateUser(ctx, &users.User{
+		Email:    id + "@example.com",
+		GoogleID: id,
+	})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	route, err := store.CreateRoute(ctx, &routes.PaidRoute{
+		TargetURL:              "https://example.com/" + id,
+		Method:                 "GET",
+		ResourceType:           "url",
+		UserID:                 userID,
+		Price:                  10000,
+		Type:                   "credit",
+		Credits:                1,
+		PaymentProtocolVersion: version,
+		BillingMode:            routes.BillingModeFixed,
+		IsTest:                 true,
+		IsEnabled:              true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create route: %v", err)
+	}
+
+	return route
+}
@@ -0,0 +1,387 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"linkshrink/ledger"
+	pkgPurchases "linkshrink/purchases"
+	"linkshrink/store/sqlc"
+	"linkshrink/wallets"
+)
+
+// CreateWallet creates a payer's wallet with an owner. Returns the existing
+// wallet and false if the payer already has one.
+func (s *Store) CreateWallet(ctx context.Context, wallet *wallets.Wallet,
+	tokenHash string) (*wallets.Wallet, bool, error) {
+
+	dbWallet, err := s.queries.CreateWallet(ctx, sqlc.CreateWalletParams{
+		UserID:       int64(wallet.UserID),
+		PayerAddress: wallet.PayerAddress,
+		TokenHash:    tokenHash,
+		IsTest:       wallet.IsTest,
+		CreatedAt:    s.clock.Now(),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		existing, err := s.GetWalletByPayer(ctx, wallet.UserID, wallet.PayerAddress, wallet.IsTest)
+		return existing, false, err
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create wallet: %w", err)
+	}
+
+	created := convertToWalletModel(dbWallet)
+	return &created, true, nil
+}
+
+// GetWalletByPayer retrieves a payer's wallet with an owner.
+func (s *Store) GetWalletByPayer(ctx context.Context, ownerID uint64, payerAddress string,
+	isTest bool) (*wallets.Wallet, error) {
+
+	dbWallet, err := s.queries.GetWalletByPayer(ctx, sqlc.GetWalletByPayerParams{
+		UserID:       int64(ownerID),
+		PayerAddress: payerAddress,
+		IsTest:       isTest,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, wallets.ErrWalletNotFound
+		}
+		return nil, fmt.Errorf("failed to get wallet by payer: %w", err)
+	}
+
+	wallet := convertToWalletModel(dbWallet)
+	return &wallet, nil
+}
+
+// GetWalletByTokenHash retrieves the wallet with an owner an access token
+// belongs to.
+func (s *Store) GetWalletByTokenHash(ctx context.Context, ownerID uint64,
+	tokenHash string) (*wallets.Wallet, error) {
+
+	dbWallet, err := s.queries.GetWalletByTokenHash(ctx, sqlc.GetWalletByTokenHashParams{
+		UserID:    int64(ownerID),
+		TokenHash: tokenHash,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, wallets.ErrWalletNotFound
+		}
+		return nil, fmt.Errorf("failed to get wallet by token: %w", err)
+	}
+
+	wallet := convertToWalletModel(dbWallet)
+	return &wallet, nil
+}
+
+// UseWalletChallenge records the nonce of a signed challenge for a wallet as
+// used until it expires, dropping the wallet's expired ones.
+func (s *Store) UseWalletChallenge(ctx context.Context, walletID uint64, nonce string,
+	expiresAt time.Time) error {
+
+	now := s.clock.Now()
+
+	return s.ExecTx(ctx, func(q *sqlc.Queries) error {
+		err := q.DeleteExpiredWalletChallenges(ctx, sqlc.DeleteExpiredWalletChallengesParams{
+			WalletID:  int64(walletID),
+			ExpiresAt: now,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete expired wallet challenges: %w", err)
+		}
+
+		used, err := q.UseWalletChallenge(ctx, sqlc.UseWalletChallengeParams{
+			WalletID:  int64(walletID),
+			Nonce:     nonce,
+			ExpiresAt: expiresAt,
+			CreatedAt: now,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record wallet challenge: %w", err)
+		}
+		if used == 0 {
+			return wallets.ErrChallengeUsed
+		}
+		return nil
+	})
+}
+
+// ListWalletsByUserID retrieves the wallets buyers hold with an owner.
+func (s *Store) ListWalletsByUserID(ctx context.Context, userID uint64) ([]wallets.Wallet, error) {
+	dbWallets, err := s.queries.ListWalletsByUserID(ctx, int64(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	userWallets := make([]wallets.Wallet, len(dbWallets))
+	for i, dbWallet := range dbWallets {
+		userWallets[i] = convertToWalletModel(dbWallet)
+	}
+
+	return userWallets, nil
+}
+
+// CreateWalletTopUp records a pending top-up and registers its payment nonce.
+func (s *Store) CreateWalletTopUp(ctx context.Context, topUp *wallets.TopUp) (uint64, error) {
+	now := s.clock.Now()
+
+	var ID int64
+	err := s.ExecTx(ctx, func(q *sqlc.Queries) error {
+		var err error
+		ID, err = q.CreateWalletTopUp(ctx, sqlc.CreateWalletTopUpParams{
+			WalletID:            int64(topUp.WalletID),
+			Amount:              int64(topUp.Amount),
+			PaymentPayload:      topUp.PaymentPayload,
+			PaymentRequirements: topUp.PaymentRequirements,
+			CreatedAt:           now,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create wallet top-up: %w", err)
+		}
+
+		if topUp.PaymentNonce == nil {
+			return nil
+		}
+
+		registered, err := q.RegisterTopUpPaymentNonce(ctx, sqlc.RegisterTopUpPaymentNonceParams{
+			Network:       topUp.PaymentNonce.Network,
+			Payer:         topUp.PaymentNonce.Payer,
+			Nonce:         topUp.PaymentNonce.Nonce,
+			WalletTopupID: nullableID(uint64(ID)),
+			CreatedAt:     now,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to register payment nonce: %w", err)
+		}
+		if registered == 0 {
+			return pkgPurchases.ErrPaymentReused
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(ID), nil
+}
+
+// SettleWalletTopUp atomically marks a pending top-up as settled, adds it to
+// its wallet's balance and posts it to the ledger.
+func (s *Store) SettleWalletTopUp(ctx context.Context, topUpID uint64,
+	settleResponse []byte) (*wallets.Wallet, error) {
+
+	now := s.clock.Now()
+
+	var wallet wallets.Wallet
+	err := s.ExecTx(ctx, func(q *sqlc.Queries) error {
+		dbTopUp, err := q.SettleWalletTopUp(ctx, sqlc.SettleWalletTopUpParams{
+			ID:             int64(topUpID),
+			SettleResponse: settleResponse,
+			UpdatedAt:      now,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return wallets.ErrTopUpNotPending
+		}
+		if err != nil {
+			return fmt.Errorf("failed to settle wallet top-up: %w", err)
+		}
+
+		dbWallet, err := q.CreditWallet(ctx, sqlc.CreditWalletParams{
+			ID:        dbTopUp.WalletID,
+			Balance:   dbTopUp.Amount,
+			UpdatedAt: now,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to credit wallet: %w", err)
+		}
+
+		txs, err := ledger.TopUpTransactions(ledger.TopUp{
+			TopUpID:  topUpID,
+			WalletID: uint64(dbWallet.ID),
+			Payer:    dbWallet.PayerAddress,
+			IsTest:   dbWallet.IsTest,
+			Amount:   uint64(dbTopUp.Amount),
+		})
+		if err != nil {
+			return err
+		}
+		err = s.postLedgerTransactions(ctx, q, txs)
+		if err != nil {
+			return fmt.Errorf("failed to post ledger transactions: %w", err)
+		}
+
+		wallet = convertToWalletModel(dbWallet)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &wallet, nil
+}
+
+// FailWalletTopUp marks a pending top-up as failed to settle and releases its
+// payment nonce.
+func (s *Store) FailWalletTopUp(ctx context.Context, topUpID uint64, reason string) error {
+	return s.ExecTx(ctx, func(q *sqlc.Queries) error {
+		rows, err := q.FailWalletTopUp(ctx, sqlc.FailWalletTopUpParams{
+			ID:          int64(topUpID),
+			SettleError: reason,
+			UpdatedAt:   s.clock.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to mark wallet top-up as failed: %w", err)
+		}
+		if rows == 0 {
+			return wallets.ErrTopUpNotPending
+		}
+
+		err = q.ReleaseTopUpPaymentNonce(ctx, nullableID(topUpID))
+		if err != nil {
+			return fmt.Errorf("failed to release payment nonce: %w", err)
+		}
+		return nil
+	})
+}
+
+// DrawWallet atomically takes amount from a wallet's balance, records the
+// draw for the route, which counts as a sale of the route, and posts it to
+// the ledger.
+func (s *Store) DrawWallet(ctx context.Context, walletID, routeID,
+	amount uint64) (*wallets.Wallet, error) {
+
+	now := s.clock.Now()
+
+	var wallet wallets.Wallet
+	err := s.ExecTx(ctx, func(q *sqlc.Queries) error {
+		dbWallet, err := q.DebitWallet(ctx, sqlc.DebitWalletParams{
+			ID:        int64(walletID),
+			Balance:   int64(amount),
+			UpdatedAt: now,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return wallets.ErrInsufficientBalance
+		}
+		if err != nil {
+			return fmt.Errorf("failed to debit wallet: %w", err)
+		}
+
+		reserved, err := q.ReserveRouteSale(ctx, int64(routeID))
+		if err != nil {
+			return fmt.Errorf("failed to reserve route sale: %w", err)
+		}
+		if reserved == 0 {
+			return pkgPurchases.ErrSoldOut
+		}
+
+		drawID, err := q.CreateWalletDraw(ctx, sqlc.CreateWalletDrawParams{
+			WalletID:    int64(walletID),
+			PaidRouteID: int64(routeID),
+			Amount:      int64(amount),
+			CreatedAt:   now,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record wallet draw: %w", err)
+		}
+
+		// Wallets only pay for routes of their owner.
+		txs, err := ledger.WalletDrawTransactions(ledger.WalletDraw{
+			DrawID:   uint64(drawID),
+			WalletID: walletID,
+			RouteID:  routeID,
+			OwnerID:  uint64(dbWallet.UserID),
+			IsTest:   dbWallet.IsTest,
+			Amount:   amount,
+		})
+		if err != nil {
+			return err
+		}
+		err = s.postLedgerTransactions(ctx, q, txs)
+		if err != nil {
+			return fmt.Errorf("failed to post ledger transactions: %w", err)
+		}
+
+		wallet = convertToWalletModel(dbWallet)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &wallet, nil
+}
+
+// ListWalletTopUps retrieves up to limit of a wallet's latest top-ups.
+func (s *Store) ListWalletTopUps(ctx context.Context, walletID uint64,
+	limit uint64) ([]wallets.TopUp, error) {
+
+	dbTopUps, err := s.queries.ListWalletTopUps(ctx, sqlc.ListWalletTopUpsParams{
+		WalletID: int64(walletID),
+		Limit:    int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	topUps := make([]wallets.TopUp, len(dbTopUps))
+	for i, dbTopUp := range dbTopUps {
+		topUps[i] = wallets.TopUp{
+			ID:                  uint64(dbTopUp.ID),
+			WalletID:            uint64(dbTopUp.WalletID),
+			Amount:              uint64(dbTopUp.Amount),
+			Status:              dbTopUp.Status,
+			SettleError:         dbTopUp.SettleError,
+			PaymentPayload:      dbTopUp.PaymentPayload,
+			PaymentRequirements: dbTopUp.PaymentRequirements,
+			SettleResponse:      dbTopUp.SettleResponse,
+			CreatedAt:           dbTopUp.CreatedAt,
+			UpdatedAt:           dbTopUp.UpdatedAt,
+		}
+	}
+
+	return topUps, nil
+}
+
+// ListWalletDraws retrieves up to limit of a wallet's latest draws.
+func (s *Store) ListWalletDraws(ctx context.Context, walletID uint64,
+	limit uint64) ([]wallets.Draw, error) {
+
+	dbDraws, err := s.queries.ListWalletDraws(ctx, sqlc.ListWalletDrawsParams{
+		WalletID: int64(walletID),
+		Limit:    int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	draws := make([]wallets.Draw, len(dbDraws))
+	for i, dbDraw := range dbDraws {
+		draws[i] = wallets.Draw{
+			ID:        uint64(dbDraw.ID),
+			WalletID:  uint64(dbDraw.WalletID),
+			RouteID:   uint64(dbDraw.PaidRouteID),
+			ShortCode: dbDraw.ShortCode,
+			Amount:    uint64(dbDraw.Amount),
+			CreatedAt: dbDraw.CreatedAt,
+		}
+	}
+
+	return draws, nil
+}
+
+func convertToWalletModel(dbWallet sqlc.Wallet) wallets.Wallet {
+	return wallets.Wallet{
+		ID:           uint64(dbWallet.ID),
+		UserID:       uint64(dbWallet.UserID),
+		PayerAddress: dbWallet.PayerAddress,
+		IsTest:       dbWallet.IsTest,
+		Balance:      uint64(dbWallet.Balance),
+		CreatedAt:    dbWallet.CreatedAt,
+		UpdatedAt:    dbWallet.UpdatedAt,
+	}
+}
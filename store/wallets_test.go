@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"linkshrink/ledger"
+	"linkshrink/routes"
+	"linkshrink/wallets"
+)
+
+func TestWalletDrawPostedToLedger(t *testing.T) {
+	store := newTestStore(t)
+	ledgerService := newTestLedgerService(store)
+	ctx := context.Background()
+
+	route := createTestRoute(t, store, routes.PaymentProtocolVersionV2)
+	wallet, created, err := store.CreateWallet(ctx, &wallets.Wallet{
+		UserID:       route.UserID,
+		PayerAddress: randomHex(t, 20),
+		IsTest:       true,
+	}, randomHex(t, 32))
+	if err != nil || !created {
+		t.Fatalf("CreateWallet() = %t, %v", created, err)
+	}
+
+	topUpID, err := store.CreateWalletTopUp(ctx, &wallets.TopUp{
+		WalletID:            wallet.ID,
+		Amount:              5 * route.Price,
+		PaymentPayload:      []byte(`{}`),
+		PaymentRequirements: []byte(`{}`),
+	})
+	if err != nil {
+		t.Fatalf("CreateWalletTopUp() error = %v", err)
+	}
+	if _, err := store.SettleWalletTopUp(ctx, topUpID, []byte(`{}`)); err != nil {
+		t.Fatalf("SettleWalletTopUp() error = %v", err)
+	}
+	if _, err := store.DrawWallet(ctx, wallet.ID, route.ID, route.Price); err != nil {
+		t.Fatalf("DrawWallet() error = %v", err)
+	}
+
+	// The draw is a sale of the route, earned by its owner.
+	stats, err := store.GetDailyStatsByUserID(ctx, route.UserID, 1)
+	if err != nil {
+		t.Fatalf("GetDailyStatsByUserID() error = %v", err)
+	}
+	if len(stats) != 1 || stats[0].Count != 1 || stats[0].Earnings != route.Price ||
+		stats[0].TestCount != 1 || stats[0].TestEarnings != route.Price {
+
+		t.Errorf("GetDailyStatsByUserID() = %+v, want a sale of %d", stats, route.Price)
+	}
+
+	balances, err := ledgerService.ListBalances(ctx, route.UserID)
+	if err != nil {
+		t.Fatalf("ListBalances() error = %v", err)
+	}
+	if got := balanceOf(balances, ledger.AccountUser); got != int64(route.Price) {
+		t.Errorf("owner balance = %d, want %d", got, route.Price)
+	}
+	if got := balanceOf(balances, ledger.AccountRoute); got != 0 {
+		t.Errorf("route balance = %d, want 0", got)
+	}
+
+	report, err := ledgerService.CheckConsistency(ctx)
+	if err != nil {
+		t.Fatalf("CheckConsistency() error = %v", err)
+	}
+	if slices.ContainsFunc(report.OffLedgerWallets, func(offLedger ledger.OffLedgerWallet) bool {
+		return offLedger.WalletID == wallet.ID
+	}) {
+		t.Errorf("wallet %d is off the ledger: %+v", wallet.ID, report.OffLedgerWallets)
+	}
+}
@@ -10,7 +10,11 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"linkshrink/auth"
+	"linkshrink/money"
+	"linkshrink/reconciler"
 	"linkshrink/routes"
+	"linkshrink/splits"
+	"linkshrink/upstreams"
 	"linkshrink/users"
 )
 
@@ -19,6 +23,9 @@ type UIHandler struct {
 	paidRouteService *routes.PaidRouteService
 	authService      *auth.Service
 	userService      *users.UserService
+	upstreamService  *upstreams.Service
+	splitsService    *splits.Service
+	reconciler       *reconciler.Reconciler
 
 	templatesFS embed.FS
 
@@ -29,12 +36,16 @@ type UIHandler struct {
 // NewUIHandler creates a new UIHandler instance
 func NewUIHandler(paidRouteService *routes.PaidRouteService,
 	authService *auth.Service, userService *users.UserService,
-	cfg *Config, templatesFS embed.FS, logger *slog.Logger) *UIHandler {
+	upstreamService *upstreams.Service, splitsService *splits.Service,
+	settlementReconciler *reconciler.Reconciler, cfg *Config, templatesFS embed.FS, logger *slog.Logger) *UIHandler {
 
 	return &UIHandler{
 		paidRouteService: paidRouteService,
 		authService:      authService,
 		userService:      userService,
+		upstreamService:  upstreamService,
+		splitsService:    splitsService,
+		reconciler:       settlementReconciler,
 
 		templatesFS: templatesFS,
 
@@ -82,6 +93,10 @@ type UIPaidRoute struct {
 	IsTest    bool
 	IsEnabled bool
 
+	// MaxSales is 0 for routes without a sales limit.
+	MaxSales       uint64
+	RemainingStock uint64
+
 	AttemptCount uint64
 	PaymentCount uint64
 	AccessCount  uint64
@@ -207,13 +222,16 @@ func (h *UIHandler) handleDashboard(gCtx *gin.Context) {
 			Description:   description,
 			CoverImageURL: coverImageURL,
 
-			Price:     strconv.FormatFloat(float64(link.Price)/1000000, 'f', -1, 64),
+			Price:     money.USDC.FormatCompact(link.Price),
 			Type:      link.Type,
 			Credits:   link.Credits,
 			IsTest:    link.IsTest,
 			UserID:    link.UserID,
 			IsEnabled: link.IsEnabled,
 
+			MaxSales:       link.MaxSales,
+			RemainingStock: link.RemainingStock(),
+
 			AttemptCount: link.AttemptCount,
 			PaymentCount: link.PaymentCount,
 			AccessCount:  link.AccessCount,
@@ -351,6 +369,62 @@ func (h *UIHandler) handleUpdatePaymentAddress(gCtx *gin.Context) {
 	})
 }
 
+// splitDisplayInfo is a revenue split formatted for display.
+type splitDisplayInfo struct {
+	PayeeAddress string
+	Label        string
+	Share        string
+}
+
+// payeeTotalDisplayInfo is what a payee is owed formatted for display.
+type payeeTotalDisplayInfo struct {
+	PayeeAddress string
+	Role         string
+	Amount       string
+	Count        uint64
+}
+
+// formatShare formats a share in basis points as a percentage, e.g. "2.5%".
+func formatShare(shareBPS uint64) string {
+	percent := money.Asset{Decimals: 2}.FormatCompact(shareBPS)
+	return percent + "%"
+}
+
+// getRouteSplitsDisplayInfo retrieves the revenue splits of a route and the
+// payout totals per payee, formatted for display.
+func (h *UIHandler) getRouteSplitsDisplayInfo(gCtx *gin.Context,
+	routeID uint64) ([]splitDisplayInfo, []payeeTotalDisplayInfo) {
+
+	routeSplits, err := h.splitsService.ListSplits(gCtx.Request.Context(), routeID)
+	if err != nil {
+		h.logger.Error("Failed to get route splits", "routeID", routeID, "error", err)
+	}
+	splitInfos := make([]splitDisplayInfo, len(routeSplits))
+	for i, split := range routeSplits {
+		splitInfos[i] = splitDisplayInfo{
+			PayeeAddress: split.PayeeAddress,
+			Label:        split.Label,
+			Share:        formatShare(split.ShareBPS),
+		}
+	}
+
+	totals, err := h.splitsService.ListPayeeTotals(gCtx.Request.Context(), routeID)
+	if err != nil {
+		h.logger.Error("Failed to get payout totals", "routeID", routeID, "error", err)
+	}
+	totalInfos := make([]payeeTotalDisplayInfo, len(totals))
+	for i, total := range totals {
+		totalInfos[i] = payeeTotalDisplayInfo{
+			PayeeAddress: total.PayeeAddress,
+			Role:         total.Role,
+			Amount:       money.USDC.FormatCompact(total.Amount),
+			Count:        total.Count,
+		}
+	}
+
+	return splitInfos, totalInfos
+}
+
 // handleRouteDetails handles the request to get details for a specific route
 func (h *UIHandler) handleRouteDetails(gCtx *gin.Context) {
 	// Get user ID from context (middleware ensures it exists)
@@ -407,11 +481,31 @@ func (h *UIHandler) handleRouteDetails(gCtx *gin.Context) {
 		target = targetRoute.TargetURL
 	}
 
+	// Get the upstream pool, if the route has one
+	var targets []upstreams.TargetStatus
+	if targetRoute.ResourceType == "url" {
+		targets, err = h.upstreamService.PoolStatus(gCtx.Request.Context(), targetRoute.ID)
+		if err != nil {
+			h.logger.Error("Failed to get upstream pool status",
+				"routeID", targetRoute.ID, "error", err)
+		}
+	}
+
+	// Get the revenue splits and what each payee is owed so far
+	routeSplits, payeeTotals := h.getRouteSplitsDisplayInfo(gCtx, targetRoute.ID)
+
+	// Get how many of the route's settlements were verified on-chain
+	settlements, err := h.reconciler.StatusCounts(gCtx.Request.Context(), targetRoute.ID)
+	if err != nil {
+		h.logger.Error("Failed to get settlement status counts",
+			"routeID", targetRoute.ID, "error", err)
+	}
+
 	// Get display info for this route
 	title, description, coverImageURL := h.getRouteDisplayInfo(targetRoute)
 
 	// Format the price
-	price := strconv.FormatFloat(float64(targetRoute.Price)/1000000, 'f', -1, 64)
+	price := money.USDC.FormatCompact(targetRoute.Price)
 
 	// Generate full access URL
 	baseURL := h.getBaseURL(gCtx)
@@ -419,23 +513,51 @@ func (h *UIHandler) handleRouteDetails(gCtx *gin.Context) {
 
 	// Render the HTML fragment with route details
 	gCtx.HTML(http.StatusOK, "route_details.html", gin.H{
-		"ID":            targetRoute.ID,
-		"ShortCode":     targetRoute.ShortCode,
-		"Target":        target,
-		"Method":        targetRoute.Method,
-		"ResourceType":  targetRoute.ResourceType,
-		"AccessURL":     accessURL,
-		"Title":         title,
-		"Description":   description,
-		"CoverImageURL": coverImageURL,
-		"Price":         price,
-		"Type":          targetRoute.Type,
-		"Credits":       targetRoute.Credits,
-		"IsTest":        targetRoute.IsTest,
-		"IsEnabled":     targetRoute.IsEnabled,
-		"AttemptCount":  targetRoute.AttemptCount,
-		"PaymentCount":  targetRoute.PaymentCount,
-		"AccessCount":   targetRoute.AccessCount,
-		"CreatedAt":     targetRoute.CreatedAt.Format("2006-01-02"),
+		"ID":                  targetRoute.ID,
+		"ShortCode":           targetRoute.ShortCode,
+		"Target":              target,
+		"Method":              targetRoute.Method,
+		"ResourceType":        targetRoute.ResourceType,
+		"AccessURL":           accessURL,
+		"Title":               title,
+		"Description":         description,
+		"CoverImageURL":       coverImageURL,
+		"Price":               price,
+		"Type":                targetRoute.Type,
+		"Credits":             targetRoute.Credits,
+		"IsTest":              targetRoute.IsTest,
+		"IsEnabled":           targetRoute.IsEnabled,
+		"CacheEnabled":        targetRoute.CacheEnabled,
+		"CacheTTLSeconds":     targetRoute.CacheTTLSeconds,
+		"CacheVaryHeaders":    targetRoute.CacheVaryHeaders,
+		"LBStrategy":          targetRoute.LBStrategy,
+		"HealthCheckPath":     targetRoute.HealthCheckPath,
+		"StreamMaxSeconds":    targetRoute.StreamMaxSeconds,
+		"StreamCreditSeconds": targetRoute.StreamCreditSeconds,
+		"IsMetered":           targetRoute.BillingMode == routes.BillingModeMetered,
+		"UnitPrice":           money.USDC.FormatCompact(targetRoute.UnitPrice),
+		"QuotePricing":        targetRoute.QuoteURL != "",
+		"PayWhatYouWant":      targetRoute.PayWhatYouWant,
+		"MinPrice":            money.USDC.FormatCompact(targetRoute.MinPrice),
+		"MaxPrice":            money.USDC.FormatCompact(targetRoute.MaxPrice),
+		"AvailableFrom":       targetRoute.AvailableFrom,
+		"AvailableUntil":      targetRoute.AvailableUntil,
+		"MaxSales":            targetRoute.MaxSales,
+		"SoldCount":           targetRoute.SoldCount,
+		"RemainingStock":      targetRoute.RemainingStock(),
+		"PaymentRails":        targetRoute.PaymentRails,
+		"Discoverable":        targetRoute.Discoverable,
+		"MimeType":            targetRoute.MimeType,
+		"BodySchema":          string(targetRoute.BodySchema),
+		"QuerySchema":         string(targetRoute.QuerySchema),
+		"ResponseSchema":      string(targetRoute.ResponseSchema),
+		"Targets":             targets,
+		"Splits":              routeSplits,
+		"PayeeTotals":         payeeTotals,
+		"Settlements":         settlements,
+		"AttemptCount":        targetRoute.AttemptCount,
+		"PaymentCount":        targetRoute.PaymentCount,
+		"AccessCount":         targetRoute.AccessCount,
+		"CreatedAt":           targetRoute.CreatedAt.Format("2006-01-02"),
 	})
 }
@@ -0,0 +1,181 @@
+package upstreams
+
+import (
+	"sync"
+	"time"
+
+	"linkshrink/utils"
+)
+
+// targetState is the in-memory state the balancer keeps per target.
+type targetState struct {
+	activeConnections int64
+
+	// currentWeight drives smooth weighted round robin.
+	currentWeight int64
+
+	// passiveFailures counts 5xx responses and proxy errors in a row.
+	passiveFailures int
+	ejectedUntil    time.Time
+}
+
+// Balancer picks targets from a pool and tracks passive health.
+type Balancer struct {
+	mu     sync.Mutex
+	states map[uint64]*targetState
+
+	cfg   *Config
+	clock utils.Clock
+}
+
+// NewBalancer creates a new Balancer.
+func NewBalancer(cfg *Config, clock utils.Clock) *Balancer {
+	return &Balancer{
+		states: make(map[uint64]*targetState),
+		cfg:    cfg,
+		clock:  clock,
+	}
+}
+
+func (b *Balancer) state(targetID uint64) *targetState {
+	state, ok := b.states[targetID]
+	if !ok {
+		state = &targetState{}
+		b.states[targetID] = state
+	}
+	return state
+}
+
+// Pick selects a target for a request and marks a connection as active on it.
+// Targets that are unhealthy or ejected are skipped. If no target is
+// available, every target is considered so that the route keeps serving.
+func (b *Balancer) Pick(targets []Target, strategy Strategy) *Selection {
+	if len(targets) == 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	candidates := make([]Target, 0, len(targets))
+	for _, target := range targets {
+		if target.IsHealthy && !now.Before(b.state(target.ID).ejectedUntil) {
+			candidates = append(candidates, target)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = targets
+	}
+
+	var picked Target
+	switch strategy {
+	case StrategyFailover:
+		picked = candidates[0]
+
+	case StrategyLeastConnections:
+		picked = candidates[0]
+		best := b.state(picked.ID)
+		for _, target := range candidates[1:] {
+			state := b.state(target.ID)
+			// Compare active/weight ratios without dividing.
+			if state.activeConnections*int64(picked.Weight) < best.activeConnections*int64(target.Weight) {
+				picked, best = target, state
+			}
+		}
+
+	default:
+		var total int64
+		var best *targetState
+		for _, target := range candidates {
+			state := b.state(target.ID)
+			state.currentWeight += int64(target.Weight)
+			total += int64(target.Weight)
+			if best == nil || state.currentWeight > best.currentWeight {
+				picked, best = target, state
+			}
+		}
+		best.currentWeight -= total
+	}
+
+	b.state(picked.ID).activeConnections++
+
+	return &Selection{Target: picked, balancer: b}
+}
+
+// observe records the outcome of a proxied request for passive health.
+func (b *Balancer) observe(targetID uint64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state(targetID)
+	if !failed {
+		state.passiveFailures = 0
+		return
+	}
+
+	state.passiveFailures++
+	if b.cfg.PassiveFailureThreshold > 0 && state.passiveFailures >= b.cfg.PassiveFailureThreshold {
+		state.ejectedUntil = b.clock.Now().Add(time.Duration(b.cfg.EjectionSeconds) * time.Second)
+		state.passiveFailures = 0
+	}
+}
+
+func (b *Balancer) release(targetID uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if state := b.state(targetID); state.activeConnections > 0 {
+		state.activeConnections--
+	}
+}
+
+// forget drops the state of a target removed from its pool.
+func (b *Balancer) forget(targetID uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.states, targetID)
+}
+
+// status returns the runtime status of a target.
+func (b *Balancer) status(target Target) TargetStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state(target.ID)
+	status := TargetStatus{
+		Target:            target,
+		ActiveConnections: state.activeConnections,
+	}
+	if b.clock.Now().Before(state.ejectedUntil) {
+		ejectedUntil := state.ejectedUntil
+		status.Ejected = true
+		status.EjectedUntil = &ejectedUntil
+	}
+	return status
+}
+
+// Selection is a target picked for a single request.
+type Selection struct {
+	Target Target
+
+	balancer    *Balancer
+	observeOnce sync.Once
+	releaseOnce sync.Once
+}
+
+// Observe records the upstream status code, or the proxy error, of the
+// request. Only the first call has an effect.
+func (s *Selection) Observe(statusCode int, err error) {
+	s.observeOnce.Do(func() {
+		s.balancer.observe(s.Target.ID, err != nil || statusCode >= 500)
+	})
+}
+
+// Release marks the request as finished. Only the first call has an effect.
+func (s *Selection) Release() {
+	s.releaseOnce.Do(func() {
+		s.balancer.release(s.Target.ID)
+	})
+}
@@ -0,0 +1,21 @@
+package upstreams
+
+// DefaultConfig returns all default values for the Config struct.
+func DefaultConfig() Config {
+	return Config{
+		HealthCheckIntervalSeconds: 30,
+		HealthCheckTimeoutSeconds:  5,
+		UnhealthyThreshold:         2,
+		PassiveFailureThreshold:    5,
+		EjectionSeconds:            30,
+	}
+}
+
+// Config holds the configuration for upstream pools.
+type Config struct {
+	HealthCheckIntervalSeconds int `long:"health_check_interval_seconds" description:"Seconds between active health checks"`
+	HealthCheckTimeoutSeconds  int `long:"health_check_timeout_seconds" description:"Timeout for a single active health check"`
+	UnhealthyThreshold         int `long:"unhealthy_threshold" description:"Failed active checks in a row before a target is marked unhealthy"`
+	PassiveFailureThreshold    int `long:"passive_failure_threshold" description:"5xx responses in a row before a target is ejected"`
+	EjectionSeconds            int `long:"ejection_seconds" description:"Seconds an ejected target is kept out of the pool"`
+}
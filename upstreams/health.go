@@ -0,0 +1,121 @@
+package upstreams
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// HealthChecker actively probes the targets of routes that configure a
+// health check path.
+type HealthChecker struct {
+	client *http.Client
+	cfg    *Config
+
+	logger *slog.Logger
+	store  Store
+}
+
+// NewHealthChecker creates a new HealthChecker.
+func NewHealthChecker(logger *slog.Logger, store Store, cfg *Config) *HealthChecker {
+	return &HealthChecker{
+		client: &http.Client{
+			Timeout: time.Duration(cfg.HealthCheckTimeoutSeconds) * time.Second,
+			// A redirect is an answer, there is no need to follow it.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		cfg:    cfg,
+		logger: logger,
+		store:  store,
+	}
+}
+
+// Run checks all targets every interval until the context is cancelled.
+func (c *HealthChecker) Run(ctx context.Context) {
+	if c.cfg.HealthCheckIntervalSeconds <= 0 {
+		c.logger.Info("Upstream health checks disabled")
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(c.cfg.HealthCheckIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.CheckAll(ctx); err != nil {
+				c.logger.Error("Upstream health check run failed", "error", err)
+			}
+		}
+	}
+}
+
+// CheckAll runs one health check against every target.
+func (c *HealthChecker) CheckAll(ctx context.Context) error {
+	targets, err := c.store.ListHealthCheckTargets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list health check targets: %w", err)
+	}
+
+	for _, target := range targets {
+		checkErr := c.check(ctx, target)
+
+		failures := uint64(0)
+		lastError := ""
+		if checkErr != nil {
+			failures = target.ConsecutiveFailures + 1
+			lastError = checkErr.Error()
+		}
+		healthy := checkErr == nil || failures < uint64(c.cfg.UnhealthyThreshold)
+
+		if healthy != target.IsHealthy {
+			c.logger.Warn("Upstream target health changed",
+				"routeID", target.PaidRouteID, "targetID", target.ID,
+				"targetURL", target.URL, "healthy", healthy, "error", lastError)
+		}
+
+		err := c.store.UpdateRouteTargetHealth(ctx, target.ID, healthy, failures, lastError)
+		if err != nil {
+			c.logger.Error("Failed to record upstream target health",
+				"targetID", target.ID, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// check probes a single target. Any non-5xx answer counts as healthy, as the
+// upstream is up even if it rejects unauthenticated requests.
+func (c *HealthChecker) check(ctx context.Context, target HealthCheckTarget) error {
+	baseURL, err := url.Parse(target.URL)
+	if err != nil {
+		return fmt.Errorf("invalid target URL: %w", err)
+	}
+	checkURL := baseURL.ResolveReference(&url.URL{Path: target.HealthCheckPath})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, checkURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "Proxy402-HealthCheck")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("health check returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package upstreams
+
+import (
+	"context"
+	"errors"
+)
+
+// Custom errors for upstream pool operations
+var (
+	ErrNoTargets       = errors.New("route has no upstream pool")
+	ErrTargetNotFound  = errors.New("upstream target not found")
+	ErrInvalidStrategy = errors.New("invalid load balancing strategy")
+	ErrInvalidTarget   = errors.New("invalid upstream target URL")
+)
+
+// Store provides access to the upstream target storage.
+type Store interface {
+	// CreateRouteTarget adds a target to a route's pool and returns it.
+	CreateRouteTarget(ctx context.Context, target *Target) (*Target, error)
+
+	// ListRouteTargets retrieves the pool of a route in failover order.
+	ListRouteTargets(ctx context.Context, routeID uint64) ([]Target, error)
+
+	// DeleteRouteTarget removes a target from a route's pool.
+	DeleteRouteTarget(ctx context.Context, routeID uint64, targetID uint64) error
+
+	// ListHealthCheckTargets retrieves all targets of routes with a health check path.
+	ListHealthCheckTargets(ctx context.Context) ([]HealthCheckTarget, error)
+
+	// UpdateRouteTargetHealth records the result of an active health check.
+	UpdateRouteTargetHealth(ctx context.Context, targetID uint64, healthy bool,
+		consecutiveFailures uint64, lastError string) error
+}
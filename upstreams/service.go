@@ -0,0 +1,120 @@
+package upstreams
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"linkshrink/utils"
+)
+
+// Service provides business logic for managing route upstream pools.
+type Service struct {
+	balancer *Balancer
+
+	logger *slog.Logger
+	store  Store
+}
+
+// NewService creates a new upstream Service.
+func NewService(logger *slog.Logger, store Store, cfg *Config, clock utils.Clock) *Service {
+	return &Service{
+		balancer: NewBalancer(cfg, clock),
+		logger:   logger,
+		store:    store,
+	}
+}
+
+// validateTargetURL checks that a target URL can be proxied to.
+func validateTargetURL(targetURL string) error {
+	parsedURL, err := url.ParseRequestURI(targetURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		return ErrInvalidTarget
+	}
+	return nil
+}
+
+// ListTargets retrieves the pool of a route.
+func (s *Service) ListTargets(ctx context.Context, routeID uint64) ([]Target, error) {
+	return s.store.ListRouteTargets(ctx, routeID)
+}
+
+// AddTarget adds a target to a route's pool. Routes start without a pool and
+// proxy to their primary target URL, so the primary is added to the pool first
+// when the pool is created.
+func (s *Service) AddTarget(ctx context.Context, routeID uint64, primaryURL string,
+	targetURL string, weight uint64) (*Target, error) {
+
+	if err := validateTargetURL(targetURL); err != nil {
+		return nil, err
+	}
+	if weight == 0 {
+		weight = 1
+	}
+
+	targets, err := s.store.ListRouteTargets(ctx, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list route targets: %w", err)
+	}
+
+	if len(targets) == 0 {
+		_, err := s.store.CreateRouteTarget(ctx, &Target{
+			PaidRouteID: routeID,
+			URL:         primaryURL,
+			Weight:      1,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to add primary target: %w", err)
+		}
+	}
+
+	target, err := s.store.CreateRouteTarget(ctx, &Target{
+		PaidRouteID: routeID,
+		URL:         targetURL,
+		Weight:      weight,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add route target: %w", err)
+	}
+
+	return target, nil
+}
+
+// RemoveTarget removes a target from a route's pool.
+func (s *Service) RemoveTarget(ctx context.Context, routeID uint64, targetID uint64) error {
+	if err := s.store.DeleteRouteTarget(ctx, routeID, targetID); err != nil {
+		return err
+	}
+	s.balancer.forget(targetID)
+	return nil
+}
+
+// Select picks the target a request to the route is proxied to. Returns
+// ErrNoTargets if the route has no pool. The caller must call Release on the
+// selection once the request is finished.
+func (s *Service) Select(ctx context.Context, routeID uint64, strategy Strategy) (*Selection, error) {
+	targets, err := s.store.ListRouteTargets(ctx, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list route targets: %w", err)
+	}
+	if len(targets) == 0 {
+		return nil, ErrNoTargets
+	}
+
+	return s.balancer.Pick(targets, strategy), nil
+}
+
+// PoolStatus retrieves the pool of a route along with its runtime status.
+func (s *Service) PoolStatus(ctx context.Context, routeID uint64) ([]TargetStatus, error) {
+	targets, err := s.store.ListRouteTargets(ctx, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list route targets: %w", err)
+	}
+
+	statuses := make([]TargetStatus, len(targets))
+	for i, target := range targets {
+		statuses[i] = s.balancer.status(target)
+	}
+	return statuses, nil
+}
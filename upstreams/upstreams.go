@@ -0,0 +1,68 @@
+package upstreams
+
+import (
+	"fmt"
+	"time"
+)
+
+// Strategy is how requests are spread across the targets of a pool.
+type Strategy string
+
+const (
+	// StrategyRoundRobin spreads requests by weight.
+	StrategyRoundRobin Strategy = "round_robin"
+	// StrategyLeastConnections picks the target with the fewest in-flight
+	// requests relative to its weight.
+	StrategyLeastConnections Strategy = "least_connections"
+	// StrategyFailover sends everything to the first available target.
+	StrategyFailover Strategy = "failover"
+)
+
+// ParseStrategy validates a strategy name. An empty name is round robin.
+func ParseStrategy(name string) (Strategy, error) {
+	switch Strategy(name) {
+	case "":
+		return StrategyRoundRobin, nil
+	case StrategyRoundRobin, StrategyLeastConnections, StrategyFailover:
+		return Strategy(name), nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidStrategy, name)
+	}
+}
+
+// Target is an upstream target in a route's pool.
+type Target struct {
+	ID          uint64 `json:"id"`
+	PaidRouteID uint64 `json:"-"`
+	URL         string `json:"url"`
+	Weight      uint64 `json:"weight"`
+
+	// IsHealthy is the result of the active health checks.
+	IsHealthy           bool       `json:"is_healthy"`
+	ConsecutiveFailures uint64     `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastCheckedAt       *time.Time `json:"last_checked_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// HealthCheckTarget is a target along with the path used to health check it.
+type HealthCheckTarget struct {
+	Target
+	HealthCheckPath string
+}
+
+// TargetStatus is the runtime status of a target.
+type TargetStatus struct {
+	Target
+
+	ActiveConnections int64      `json:"active_connections"`
+	Ejected           bool       `json:"ejected"`
+	EjectedUntil      *time.Time `json:"ejected_until,omitempty"`
+}
+
+// Available reports whether the target currently receives traffic.
+func (s TargetStatus) Available() bool {
+	return s.IsHealthy && !s.Ejected
+}
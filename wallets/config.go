@@ -0,0 +1,19 @@
+package wallets
+
+// DefaultConfig returns all default values for the Config struct.
+func DefaultConfig() Config {
+	return Config{
+		MinTopUp:               100000,
+		MaxTopUp:               100000000,
+		ChallengeMaxAgeSeconds: 300,
+		HistoryLimit:           50,
+	}
+}
+
+// Config holds the configuration for prepaid wallets.
+type Config struct {
+	MinTopUp               uint64 `long:"min_topup" description:"Smallest top-up in base units (USDC * 10^6)"`
+	MaxTopUp               uint64 `long:"max_topup" description:"Largest top-up in base units (USDC * 10^6)"`
+	ChallengeMaxAgeSeconds int    `long:"challenge_max_age_seconds" description:"Seconds a signed wallet challenge is accepted for"`
+	HistoryLimit           uint64 `long:"history_limit" description:"Number of top-ups and draws listed with a wallet"`
+}
@@ -0,0 +1,108 @@
+package wallets
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/auth"
+)
+
+const (
+	// TokenHeader carries the access token of the wallet a request pays with.
+	TokenHeader = "Proxy402-Wallet"
+	// SignatureHeader carries a signed wallet challenge instead of a token,
+	// as "<unix time>:<nonce>:<signature>" of ChallengeMessage.
+	SignatureHeader = "Proxy402-Wallet-Signature"
+	// BalanceHeader reports the balance left in the wallet.
+	BalanceHeader = "Proxy402-Wallet-Balance"
+)
+
+// Handler handles HTTP requests related to prepaid wallets.
+type Handler struct {
+	walletService *Service
+}
+
+// NewHandler creates a new Handler.
+func NewHandler(walletService *Service) *Handler {
+	return &Handler{
+		walletService: walletService,
+	}
+}
+
+// WalletResponse represents a wallet with its latest top-ups and draws.
+type WalletResponse struct {
+	Wallet *Wallet `json:"wallet"`
+	TopUps []TopUp `json:"top_ups"`
+	Draws  []Draw  `json:"draws"`
+}
+
+// GetWallets returns the wallets buyers hold with the user.
+func (h *Handler) GetWallets(gCtx *gin.Context) {
+	authPayload, exists := gCtx.Get(auth.AuthorizationPayloadKey)
+	if !exists {
+		gCtx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	payload := authPayload.(*auth.Claims)
+
+	userWallets, err := h.walletService.ListWallets(gCtx.Request.Context(), payload.UserID)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve wallets"})
+		return
+	}
+	if userWallets == nil {
+		userWallets = []Wallet{}
+	}
+
+	gCtx.JSON(http.StatusOK, gin.H{"wallets": userWallets})
+}
+
+// GetWallet returns the balance and history of the buyer's wallet with an
+// owner, identified like requests paying with it. Testnet wallets are
+// selected with test=true.
+func (h *Handler) GetWallet(gCtx *gin.Context) {
+	ownerID, err := strconv.ParseUint(gCtx.Param("ownerID"), 10, 64)
+	if err != nil {
+		gCtx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid owner ID format"})
+		return
+	}
+	isTest, _ := strconv.ParseBool(gCtx.DefaultQuery("test", "true"))
+
+	ctx := gCtx.Request.Context()
+	wallet, err := h.walletService.Authenticate(ctx, ownerID, isTest,
+		gCtx.GetHeader(TokenHeader), gCtx.GetHeader(SignatureHeader))
+	if err != nil {
+		if errors.Is(err, ErrWalletNotFound) || errors.Is(err, ErrInvalidSignature) ||
+			errors.Is(err, ErrChallengeExpired) || errors.Is(err, ErrChallengeUsed) {
+
+			gCtx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else {
+			gCtx.Error(err)
+			gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve wallet"})
+		}
+		return
+	}
+
+	topUps, draws, err := h.walletService.History(ctx, wallet.ID)
+	if err != nil {
+		gCtx.Error(err)
+		gCtx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve wallet history"})
+		return
+	}
+	if topUps == nil {
+		topUps = []TopUp{}
+	}
+	if draws == nil {
+		draws = []Draw{}
+	}
+
+	gCtx.JSON(http.StatusOK, WalletResponse{
+		Wallet: wallet,
+		TopUps: topUps,
+		Draws:  draws,
+	})
+}
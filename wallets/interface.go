@@ -0,0 +1,66 @@
+package wallets
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Custom errors for wallet operations
+var (
+	ErrWalletNotFound      = errors.New("wallet not found")
+	ErrInsufficientBalance = errors.New("wallet balance is too low")
+	ErrInvalidAmount       = errors.New("top-up amount is out of range")
+	ErrInvalidSignature    = errors.New("invalid wallet signature")
+	ErrChallengeExpired    = errors.New("wallet challenge expired")
+	ErrChallengeUsed       = errors.New("wallet challenge was already used")
+	ErrTopUpNotPending     = errors.New("top-up is not pending")
+)
+
+// Store provides access to the wallet storage.
+type Store interface {
+	// CreateWallet creates a payer's wallet with an owner. Returns the
+	// existing wallet and false if the payer already has one.
+	CreateWallet(ctx context.Context, wallet *Wallet, tokenHash string) (*Wallet, bool, error)
+
+	// GetWalletByPayer retrieves a payer's wallet with an owner,
+	// ErrWalletNotFound if there is none.
+	GetWalletByPayer(ctx context.Context, ownerID uint64, payerAddress string,
+		isTest bool) (*Wallet, error)
+
+	// GetWalletByTokenHash retrieves the wallet with an owner an access token
+	// belongs to, ErrWalletNotFound if there is none.
+	GetWalletByTokenHash(ctx context.Context, ownerID uint64, tokenHash string) (*Wallet, error)
+
+	// UseWalletChallenge records the nonce of a signed challenge for a wallet
+	// as used until expiresAt. Returns ErrChallengeUsed if it already was.
+	UseWalletChallenge(ctx context.Context, walletID uint64, nonce string, expiresAt time.Time) error
+
+	// ListWalletsByUserID retrieves the wallets buyers hold with an owner.
+	ListWalletsByUserID(ctx context.Context, userID uint64) ([]Wallet, error)
+
+	// CreateWalletTopUp records a pending top-up and registers its payment
+	// nonce, returns purchases.ErrPaymentReused if it was already used.
+	CreateWalletTopUp(ctx context.Context, topUp *TopUp) (uint64, error)
+
+	// SettleWalletTopUp marks a pending top-up as settled, adds it to its
+	// wallet's balance and posts it to the ledger. Returns
+	// ErrTopUpNotPending if it is not pending.
+	SettleWalletTopUp(ctx context.Context, topUpID uint64, settleResponse []byte) (*Wallet, error)
+
+	// FailWalletTopUp marks a pending top-up as failed to settle and releases
+	// its payment nonce. Returns ErrTopUpNotPending if it is not pending.
+	FailWalletTopUp(ctx context.Context, topUpID uint64, reason string) error
+
+	// DrawWallet takes amount from a wallet's balance for a request to a
+	// route and posts it to the ledger as earned by the route's owner.
+	// Returns ErrInsufficientBalance if the balance is too low, and
+	// purchases.ErrSoldOut if the route's sales limit is reached.
+	DrawWallet(ctx context.Context, walletID, routeID, amount uint64) (*Wallet, error)
+
+	// ListWalletTopUps retrieves up to limit of a wallet's latest top-ups.
+	ListWalletTopUps(ctx context.Context, walletID uint64, limit uint64) ([]TopUp, error)
+
+	// ListWalletDraws retrieves up to limit of a wallet's latest draws.
+	ListWalletDraws(ctx context.Context, walletID uint64, limit uint64) ([]Draw, error)
+}
@@ -0,0 +1,229 @@
+package wallets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"linkshrink/purchases"
+	"linkshrink/utils"
+)
+
+// maxChallengeNonceLength is the longest nonce a wallet challenge can have.
+const maxChallengeNonceLength = 64
+
+// Service provides business logic for prepaid wallets.
+type Service struct {
+	cfg *Config
+
+	logger *slog.Logger
+	store  Store
+	clock  utils.Clock
+}
+
+// NewService creates a new wallet Service.
+func NewService(logger *slog.Logger, store Store, cfg *Config, clock utils.Clock) *Service {
+	return &Service{
+		cfg:    cfg,
+		logger: logger,
+		store:  store,
+		clock:  clock,
+	}
+}
+
+// ValidateTopUpAmount checks a top-up amount against the configured bounds.
+func (s *Service) ValidateTopUpAmount(amount uint64) error {
+	if amount == 0 || amount < s.cfg.MinTopUp || amount > s.cfg.MaxTopUp {
+		return ErrInvalidAmount
+	}
+	return nil
+}
+
+// BeginTopUp records a verified top-up payment as pending before it is
+// settled. The payment tops up the wallet of the access token if one is
+// given, otherwise the payer's wallet with the owner, which is created if the
+// payer has none yet.
+//
+// A newly created wallet's access token is returned, it is not stored and
+// can't be retrieved again.
+func (s *Service) BeginTopUp(ctx context.Context, ownerID uint64, token string, isTest bool,
+	topUp *TopUp) (wallet *Wallet, newToken string, err error) {
+
+	nonce, err := purchases.ParsePaymentNonce(topUp.PaymentPayload)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if token != "" {
+		wallet, err = s.store.GetWalletByTokenHash(ctx, ownerID, HashToken(token))
+		if err != nil {
+			return nil, "", err
+		}
+		if wallet.IsTest != isTest {
+			return nil, "", ErrWalletNotFound
+		}
+	} else {
+		wallet, newToken, err = s.payerWallet(ctx, ownerID, nonce.Payer, isTest)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	topUp.WalletID = wallet.ID
+	topUp.Status = purchases.StatusPending
+	topUp.PaymentNonce = nonce
+	topUp.ID, err = s.store.CreateWalletTopUp(ctx, topUp)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return wallet, newToken, nil
+}
+
+// payerWallet retrieves the payer's wallet with an owner or creates it,
+// returning the access token of a created wallet.
+func (s *Service) payerWallet(ctx context.Context, ownerID uint64, payerAddress string,
+	isTest bool) (*Wallet, string, error) {
+
+	wallet, err := s.store.GetWalletByPayer(ctx, ownerID, payerAddress, isTest)
+	if err == nil {
+		return wallet, "", nil
+	}
+	if !errors.Is(err, ErrWalletNotFound) {
+		return nil, "", err
+	}
+
+	token, tokenHash, err := NewToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	wallet, created, err := s.store.CreateWallet(ctx, &Wallet{
+		UserID:       ownerID,
+		PayerAddress: payerAddress,
+		IsTest:       isTest,
+	}, tokenHash)
+	if err != nil {
+		return nil, "", err
+	}
+	if !created {
+		// A concurrent top-up of the payer created the wallet first.
+		token = ""
+	}
+
+	return wallet, token, nil
+}
+
+// SettleTopUp marks a pending top-up as settled and adds it to its wallet's
+// balance.
+func (s *Service) SettleTopUp(ctx context.Context, topUpID uint64, settleResponse []byte) (*Wallet, error) {
+	return s.store.SettleWalletTopUp(ctx, topUpID, settleResponse)
+}
+
+// FailTopUp marks a pending top-up as failed to settle, its payment can be
+// used again.
+func (s *Service) FailTopUp(ctx context.Context, topUpID uint64, reason string) error {
+	return s.store.FailWalletTopUp(ctx, topUpID, reason)
+}
+
+// Authenticate retrieves the wallet a request to an owner's route pays with,
+// identified by an access token or by a signed wallet challenge of the form
+// "<unix time>:<nonce>:<signature>". The wallet must be on the route's
+// network, and a challenge is accepted only once.
+func (s *Service) Authenticate(ctx context.Context, ownerID uint64, isTest bool,
+	token string, signedChallenge string) (*Wallet, error) {
+
+	if token != "" {
+		wallet, err := s.store.GetWalletByTokenHash(ctx, ownerID, HashToken(token))
+		if err != nil {
+			return nil, err
+		}
+		if wallet.IsTest != isTest {
+			return nil, ErrWalletNotFound
+		}
+		return wallet, nil
+	}
+
+	payer, nonce, expiresAt, err := s.verifyChallenge(ownerID, signedChallenge)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := s.store.GetWalletByPayer(ctx, ownerID, payer, isTest)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.IsTest != isTest {
+		return nil, ErrWalletNotFound
+	}
+
+	err = s.store.UseWalletChallenge(ctx, wallet.ID, nonce, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// verifyChallenge returns the payer that signed a wallet challenge for the
+// owner, if it is recent enough, with the challenge's nonce and when it is
+// too old to be accepted.
+func (s *Service) verifyChallenge(ownerID uint64,
+	signedChallenge string) (payer string, nonce string, expiresAt time.Time, err error) {
+
+	timestampStr, rest, ok := strings.Cut(signedChallenge, ":")
+	if !ok {
+		return "", "", time.Time{}, ErrInvalidSignature
+	}
+	nonce, signature, ok := strings.Cut(rest, ":")
+	if !ok || nonce == "" || len(nonce) > maxChallengeNonceLength {
+		return "", "", time.Time{}, ErrInvalidSignature
+	}
+	timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
+	if err != nil {
+		return "", "", time.Time{}, ErrInvalidSignature
+	}
+
+	maxAge := time.Duration(s.cfg.ChallengeMaxAgeSeconds) * time.Second
+	age := s.clock.Now().Sub(time.Unix(timestamp, 0))
+	if age > maxAge || age < -maxAge {
+		return "", "", time.Time{}, ErrChallengeExpired
+	}
+
+	payer, err = RecoverSigner(ChallengeMessage(ownerID, timestamp, nonce), signature)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	return payer, nonce, time.Unix(timestamp, 0).Add(maxAge), nil
+}
+
+// Draw pays amount for a request to a route from a wallet.
+func (s *Service) Draw(ctx context.Context, wallet *Wallet, routeID uint64, amount uint64) (*Wallet, error) {
+	updated, err := s.store.DrawWallet(ctx, wallet.ID, routeID, amount)
+	if err != nil && !errors.Is(err, ErrInsufficientBalance) {
+		return nil, fmt.Errorf("failed to draw from wallet: %w", err)
+	}
+	return updated, err
+}
+
+// History retrieves the latest top-ups and draws of a wallet.
+func (s *Service) History(ctx context.Context, walletID uint64) ([]TopUp, []Draw, error) {
+	topUps, err := s.store.ListWalletTopUps(ctx, walletID, s.cfg.HistoryLimit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list wallet top-ups: %w", err)
+	}
+
+	draws, err := s.store.ListWalletDraws(ctx, walletID, s.cfg.HistoryLimit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list wallet draws: %w", err)
+	}
+
+	return topUps, draws, nil
+}
+
+// ListWallets retrieves the wallets buyers hold with an owner.
+func (s *Service) ListWallets(ctx context.Context, ownerID uint64) ([]Wallet, error) {
+	return s.store.ListWalletsByUserID(ctx, ownerID)
+}
@@ -0,0 +1,183 @@
+package wallets
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"linkshrink/utils"
+)
+
+const testOwnerID = 7
+
+// challengeStore is a Store holding a single wallet and the challenges used
+// with it.
+type challengeStore struct {
+	Store
+
+	wallet *Wallet
+	used   map[string]time.Time
+}
+
+func (s *challengeStore) GetWalletByPayer(ctx context.Context, ownerID uint64, payerAddress string,
+	isTest bool) (*Wallet, error) {
+
+	if ownerID != s.wallet.UserID || payerAddress != s.wallet.PayerAddress || isTest != s.wallet.IsTest {
+		return nil, ErrWalletNotFound
+	}
+	wallet := *s.wallet
+	return &wallet, nil
+}
+
+func (s *challengeStore) UseWalletChallenge(ctx context.Context, walletID uint64, nonce string,
+	expiresAt time.Time) error {
+
+	key := fmt.Sprintf("%d:%s", walletID, nonce)
+	if _, ok := s.used[key]; ok {
+		return ErrChallengeUsed
+	}
+	s.used[key] = expiresAt
+	return nil
+}
+
+// newPayer returns a payer's key and lower case address.
+func newPayer(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key, strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex())
+}
+
+// signChallenge signs a wallet challenge like a wallet does, as
+// SignatureHeader carries it.
+func signChallenge(t *testing.T, key *ecdsa.PrivateKey, ownerID uint64, timestamp int64, nonce string) string {
+	t.Helper()
+
+	message := ChallengeMessage(ownerID, timestamp, nonce)
+	sig, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		t.Fatalf("failed to sign challenge: %v", err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return fmt.Sprintf("%d:%s:%s", timestamp, nonce, hexutil.Encode(sig))
+}
+
+func TestAuthenticateChallenge(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	clock := utils.NewMockClock()
+	clock.SetMockClockTime(now)
+
+	key, payer := newPayer(t)
+
+	store := &challengeStore{
+		wallet: &Wallet{ID: 1, UserID: testOwnerID, PayerAddress: payer, IsTest: true},
+		used:   make(map[string]time.Time),
+	}
+	cfg := DefaultConfig()
+	service := NewService(slog.New(slog.NewTextHandler(io.Discard, nil)), store, &cfg, clock)
+	ctx := context.Background()
+
+	challenge := signChallenge(t, key, testOwnerID, now.Unix(), "a1")
+	wallet, err := service.Authenticate(ctx, testOwnerID, true, "", challenge)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if wallet.ID != 1 {
+		t.Errorf("Authenticate() wallet = %d, want 1", wallet.ID)
+	}
+	wantExpiry := now.Add(time.Duration(cfg.ChallengeMaxAgeSeconds) * time.Second)
+	if got := store.used["1:a1"]; !got.Equal(wantExpiry) {
+		t.Errorf("challenge recorded until %v, want %v", got, wantExpiry)
+	}
+
+	// Replaying the challenge is rejected, a new nonce is accepted.
+	_, err = service.Authenticate(ctx, testOwnerID, true, "", challenge)
+	if !errors.Is(err, ErrChallengeUsed) {
+		t.Errorf("replayed Authenticate() error = %v, want %v", err, ErrChallengeUsed)
+	}
+	_, err = service.Authenticate(ctx, testOwnerID, true, "",
+		signChallenge(t, key, testOwnerID, now.Unix(), "a2"))
+	if err != nil {
+		t.Errorf("Authenticate() with new nonce error = %v", err)
+	}
+
+	// The nonce is part of the signed message.
+	_, nonceRest, _ := strings.Cut(challenge, ":")
+	_, signature, _ := strings.Cut(nonceRest, ":")
+	_, err = service.Authenticate(ctx, testOwnerID, true, "",
+		fmt.Sprintf("%d:a3:%s", now.Unix(), signature))
+	if !errors.Is(err, ErrWalletNotFound) {
+		t.Errorf("Authenticate() with swapped nonce error = %v, want %v", err, ErrWalletNotFound)
+	}
+}
+
+func TestVerifyChallenge(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	clock := utils.NewMockClock()
+	clock.SetMockClockTime(now)
+
+	cfg := DefaultConfig()
+	service := NewService(slog.New(slog.NewTextHandler(io.Discard, nil)), nil, &cfg, clock)
+	maxAge := int64(cfg.ChallengeMaxAgeSeconds)
+
+	key, payer := newPayer(t)
+	valid := signChallenge(t, key, testOwnerID, now.Unix(), "n")
+	otherOwner := signChallenge(t, key, testOwnerID+1, now.Unix(), "n")
+	old := signChallenge(t, key, testOwnerID, now.Unix()-maxAge-1, "n")
+	future := signChallenge(t, key, testOwnerID, now.Unix()+maxAge+1, "n")
+
+	tests := []struct {
+		name      string
+		challenge string
+		wantPayer string
+		wantErr   error
+	}{
+		{name: "valid", challenge: valid, wantPayer: payer},
+		{name: "other owner", challenge: otherOwner},
+		{name: "too old", challenge: old, wantErr: ErrChallengeExpired},
+		{name: "too far ahead", challenge: future, wantErr: ErrChallengeExpired},
+		{name: "no nonce", challenge: fmt.Sprintf("%d:0x00", now.Unix()), wantErr: ErrInvalidSignature},
+		{name: "empty nonce", challenge: fmt.Sprintf("%d::0x00", now.Unix()), wantErr: ErrInvalidSignature},
+		{
+			name:      "long nonce",
+			challenge: fmt.Sprintf("%d:%s:0x00", now.Unix(), strings.Repeat("n", maxChallengeNonceLength+1)),
+			wantErr:   ErrInvalidSignature,
+		},
+		{name: "bad timestamp", challenge: "soon:n:0x00", wantErr: ErrInvalidSignature},
+		{name: "bad signature", challenge: fmt.Sprintf("%d:n:0x00", now.Unix()), wantErr: ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, _, err := service.verifyChallenge(testOwnerID, tt.challenge)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("verifyChallenge() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifyChallenge() error = %v", err)
+			}
+			if tt.wantPayer != "" && got != tt.wantPayer {
+				t.Errorf("verifyChallenge() payer = %s, want %s", got, tt.wantPayer)
+			}
+			if tt.wantPayer == "" && got == payer {
+				t.Errorf("verifyChallenge() recovered the payer from a challenge for another owner")
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package wallets
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"linkshrink/purchases"
+)
+
+// tokenPrefix marks wallet access tokens, so they are recognizable in
+// client configuration.
+const tokenPrefix = "wlt_"
+
+// Wallet is a prepaid balance a buyer holds with an owner, which pays for
+// requests to any of the owner's routes.
+type Wallet struct {
+	ID     uint64 `json:"id"`
+	UserID uint64 `json:"-"`
+	// PayerAddress is the address of the wallet's first top-up, which signs
+	// wallet challenges.
+	PayerAddress string `json:"payer_address"`
+	IsTest       bool   `json:"is_test"`
+	// Balance is in base units (USDC * 10^6).
+	Balance uint64 `json:"balance"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TopUp is a payment that adds to a wallet's balance.
+type TopUp struct {
+	ID       uint64 `json:"id"`
+	WalletID uint64 `json:"-"`
+	// Amount is in base units (USDC * 10^6).
+	Amount uint64 `json:"amount"`
+
+	// Status tracks the settlement of the payment like it does for
+	// purchases, only settled top-ups add to the balance.
+	Status      string `json:"status"`
+	SettleError string `json:"settle_error,omitempty"`
+
+	PaymentPayload      []byte `json:"-"`
+	PaymentRequirements []byte `json:"-"`
+	SettleResponse      []byte `json:"-"`
+
+	// PaymentNonce is the authorization of the payment, registered when the
+	// top-up is created. It is not loaded with the top-up.
+	PaymentNonce *purchases.PaymentNonce `json:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Draw is what a single request to a route paid from a wallet.
+type Draw struct {
+	ID        uint64 `json:"id"`
+	WalletID  uint64 `json:"-"`
+	RouteID   uint64 `json:"-"`
+	ShortCode string `json:"short_code"`
+	// Amount is in base units (USDC * 10^6).
+	Amount uint64 `json:"amount"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewToken generates a wallet access token and returns it with the hash it
+// is stored as.
+func NewToken() (token string, tokenHash string, err error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", "", fmt.Errorf("failed to generate wallet token: %w", err)
+	}
+
+	token = tokenPrefix + hex.EncodeToString(tokenBytes)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the SHA-256 of a wallet access token, the token itself is
+// never stored.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ChallengeMessage is the message a payer signs to use its wallet with an
+// owner, at the given unix time. The nonce is chosen by the payer and makes
+// each challenge usable once.
+func ChallengeMessage(ownerID uint64, timestamp int64, nonce string) string {
+	return fmt.Sprintf("Proxy402 wallet access\nOwner: %d\nTimestamp: %d\nNonce: %s",
+		ownerID, timestamp, nonce)
+}
+
+// RecoverSigner returns the lower case address that signed message with an
+// EIP-191 personal signature.
+func RecoverSigner(message string, signature string) (string, error) {
+	sig, err := hexutil.Decode(strings.TrimSpace(signature))
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return "", ErrInvalidSignature
+	}
+
+	// Wallets sign with a recovery ID of 27 or 28.
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	publicKey, err := crypto.SigToPub(accounts.TextHash([]byte(message)), sig)
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+
+	return strings.ToLower(crypto.PubkeyToAddress(*publicKey).Hex()), nil
+}
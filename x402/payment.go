@@ -3,18 +3,27 @@ package x402
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"math/big"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 
 	x402http "github.com/coinbase/x402/go/http"
 	x402types "github.com/coinbase/x402/go/types"
 	"github.com/gin-gonic/gin"
+
+	"linkshrink/money"
 )
 
 const x402Version = 1
 
+// ErrPaymentRejected is wrapped by errors of a BeforeSettle hook that reject
+// the payment itself, which is answered with a 402 instead of a 500.
+var ErrPaymentRejected = errors.New("payment rejected")
+
 // PaymentOptions is the options for the Payment helper.
 type PaymentOptions struct {
 	Description       string
@@ -25,6 +34,46 @@ type PaymentOptions struct {
 	Testnet           bool
 	Resource          string
 	ResourceRootURL   string
+
+	// BeforeSettle is called with a verified payment before it is settled.
+	// The payment is not settled if it returns an error, errors wrapping
+	// ErrPaymentRejected are reported to the payer.
+	BeforeSettle func(paymentPayloadJSON []byte, paymentRequirementsJSON []byte) error
+	// SettleFailed is called with the reason a verified payment failed to
+	// settle.
+	SettleFailed func(reason string)
+	// VerifyOnly returns once the payment verified, without calling
+	// BeforeSettle or settling the payment.
+	VerifyOnly bool
+	// Alternatives are listed next to the amount when payment is required,
+	// the payment itself is only verified against the amount.
+	Alternatives []Alternative
+	// AmountInput lets the payer choose the amount on the paywall, nil only
+	// shows the amount.
+	AmountInput *AmountInput
+}
+
+// Alternative is another amount the resource can be paid with.
+type Alternative struct {
+	Amount uint64
+	// Label describes what the amount pays for.
+	Label string
+	// URL is where the paywall asks for the alternative amount.
+	URL string
+}
+
+// AmountInput is a paywall field the payer chooses the amount with. The
+// paywall is requested again with the chosen amount, the payment is verified
+// against the amount it is then asked for.
+type AmountInput struct {
+	// Name is the query parameter the chosen amount is sent in.
+	Name string
+	// Value is the amount chosen so far, before any discount of the amount
+	// asked for.
+	Value uint64
+	Min   uint64
+	// Max of 0 means no upper bound.
+	Max uint64
 }
 
 // Options is the type for options accepted by Payment.
@@ -78,26 +127,43 @@ func WithResourceRootURL(resourceRootURL string) Options {
 	}
 }
 
-// Amount: the decimal denominated amount to charge (ex: 0.01 for 1 cent).
-// Returns marshaled payload and settle response JSON bytes when payment succeeds.
-func Payment(c *gin.Context, amount *big.Float, address string, opts ...Options) (paymentPayloadJSON []byte, settleResponseJSON []byte) {
-	options := &PaymentOptions{
-		FacilitatorURL:    x402http.DefaultFacilitatorURL,
-		MaxTimeoutSeconds: 60,
-		Testnet:           true,
+func WithBeforeSettle(beforeSettle func(paymentPayloadJSON []byte, paymentRequirementsJSON []byte) error) Options {
+	return func(options *PaymentOptions) {
+		options.BeforeSettle = beforeSettle
 	}
-	for _, opt := range opts {
-		opt(options)
+}
+
+func WithSettleFailed(settleFailed func(reason string)) Options {
+	return func(options *PaymentOptions) {
+		options.SettleFailed = settleFailed
 	}
+}
 
-	c.Header("Payment-Protocol", "X402")
+func WithVerifyOnly(verifyOnly bool) Options {
+	return func(options *PaymentOptions) {
+		options.VerifyOnly = verifyOnly
+	}
+}
+
+func WithAlternatives(alternatives ...Alternative) Options {
+	return func(options *PaymentOptions) {
+		options.Alternatives = alternatives
+	}
+}
 
-	network := "base"
-	usdcAddress := "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"
-	if options.Testnet {
-		network = "base-sepolia"
-		usdcAddress = "0x036CbD53842c5426634e7929541eC2318f3dCF7e"
+func WithAmountInput(input *AmountInput) Options {
+	return func(options *PaymentOptions) {
+		options.AmountInput = input
 	}
+}
+
+// Amount: the amount to charge in USDC base units (ex: 10000 for 1 cent).
+// Returns marshaled payload and settle response JSON bytes when payment succeeds.
+// With VerifyOnly the settle response is nil.
+func Payment(c *gin.Context, amount uint64, address string, opts ...Options) (paymentPayloadJSON []byte, settleResponseJSON []byte) {
+	options := newPaymentOptions(opts)
+
+	c.Header("Payment-Protocol", "X402")
 
 	var resource string
 	if options.Resource == "" {
@@ -106,20 +172,8 @@ func Payment(c *gin.Context, amount *big.Float, address string, opts ...Options)
 		resource = options.Resource
 	}
 
-	maxAmountRequired, _ := new(big.Float).Mul(amount, big.NewFloat(1e6)).Int(nil)
-	paymentRequirements := x402types.PaymentRequirementsV1{
-		Scheme:            "exact",
-		Network:           network,
-		MaxAmountRequired: maxAmountRequired.String(),
-		Resource:          resource,
-		Description:       options.Description,
-		MimeType:          options.MimeType,
-		PayTo:             address,
-		MaxTimeoutSeconds: options.MaxTimeoutSeconds,
-		Asset:             usdcAddress,
-		OutputSchema:      options.OutputSchema,
-	}
-	if err := setUSDCInfoV1(&paymentRequirements, options.Testnet); err != nil {
+	paymentRequirements, err := requirementsV1(amount, address, resource, options)
+	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"error":       err.Error(),
 			"x402Version": x402Version,
@@ -180,6 +234,24 @@ func Payment(c *gin.Context, amount *big.Float, address string, opts ...Options)
 		return nil, nil
 	}
 
+	if options.VerifyOnly {
+		return decodedPayload, nil
+	}
+
+	if options.BeforeSettle != nil {
+		if err := options.BeforeSettle(decodedPayload, requirementsJSON); err != nil {
+			if errors.Is(err, ErrPaymentRejected) {
+				respondPaymentRequiredV1(c, isWebBrowser, resource, amount, options, paymentRequirements, paymentRequirementsJSON, err.Error())
+				return nil, nil
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":       err.Error(),
+				"x402Version": x402Version,
+			})
+			return nil, nil
+		}
+	}
+
 	settleResponse, err := facilitator.Settle(c.Request.Context(), decodedPayload, requirementsJSON)
 	if err != nil || settleResponse == nil || !settleResponse.Success {
 		errMsg := "payment settlement failed"
@@ -188,6 +260,9 @@ func Payment(c *gin.Context, amount *big.Float, address string, opts ...Options)
 		} else if settleResponse != nil && settleResponse.ErrorReason != "" {
 			errMsg = settleResponse.ErrorReason
 		}
+		if options.SettleFailed != nil {
+			options.SettleFailed(errMsg)
+		}
 		respondPaymentRequiredV1(c, isWebBrowser, resource, amount, options, paymentRequirements, paymentRequirementsJSON, errMsg)
 		return nil, nil
 	}
@@ -224,14 +299,40 @@ func setUSDCInfoV1(requirements *x402types.PaymentRequirementsV1, testnet bool)
 	return nil
 }
 
-func respondPaymentRequiredV1(c *gin.Context, isWebBrowser bool, resource string, amount *big.Float, options *PaymentOptions, requirements x402types.PaymentRequirementsV1, requirementsJSON []byte, errMsg string) {
+func respondPaymentRequiredV1(c *gin.Context, isWebBrowser bool, resource string, amount uint64, options *PaymentOptions, requirements x402types.PaymentRequirementsV1, requirementsJSON []byte, errMsg string) {
 	if isWebBrowser {
-		amountString := amount.Text('f', 6)
+		amountString := money.USDC.Format(amount)
 		description := options.Description
 		if contextDescription := c.GetString("Description"); contextDescription != "" {
 			description = contextDescription
 		}
 
+		alternatives := make([]gin.H, len(options.Alternatives))
+		for i, alternative := range options.Alternatives {
+			alternatives[i] = gin.H{
+				"Label":           alternative.Label,
+				"AmountFormatted": money.USDC.Format(alternative.Amount),
+				"URL":             alternative.URL,
+			}
+		}
+
+		var amountInput gin.H
+		if options.AmountInput != nil {
+			// A GET form replaces the query of its action, the resource's
+			// query is carried in hidden fields instead.
+			action, hidden := formTarget(resource, options.AmountInput.Name)
+			amountInput = gin.H{
+				"Action":         action,
+				"Hidden":         hidden,
+				"Name":           options.AmountInput.Name,
+				"ValueFormatted": money.USDC.Format(options.AmountInput.Value),
+				"MinFormatted":   money.USDC.Format(options.AmountInput.Min),
+			}
+			if options.AmountInput.Max > 0 {
+				amountInput["MaxFormatted"] = money.USDC.Format(options.AmountInput.Max)
+			}
+		}
+
 		c.HTML(http.StatusPaymentRequired, "payment_required.html", gin.H{
 			"Resource":                resource,
 			"Description":             description,
@@ -244,6 +345,8 @@ func respondPaymentRequiredV1(c *gin.Context, isWebBrowser bool, resource string
 			"IsTestnet":               options.Testnet,
 			"PaymentRequirements":     requirements,
 			"PaymentRequirementsJSON": string(requirementsJSON),
+			"Alternatives":            alternatives,
+			"AmountInput":             amountInput,
 		})
 		c.Abort()
 		return
@@ -252,9 +355,43 @@ func respondPaymentRequiredV1(c *gin.Context, isWebBrowser bool, resource string
 	if errMsg == "" {
 		errMsg = "X-PAYMENT header is required"
 	}
+	accepts := []x402types.PaymentRequirementsV1{requirements}
+	for _, alternative := range options.Alternatives {
+		alternativeRequirements := requirements
+		alternativeRequirements.MaxAmountRequired = strconv.FormatUint(alternative.Amount, 10)
+		accepts = append(accepts, alternativeRequirements)
+	}
 	c.AbortWithStatusJSON(http.StatusPaymentRequired, gin.H{
 		"error":       errMsg,
-		"accepts":     []x402types.PaymentRequirementsV1{requirements},
+		"accepts":     accepts,
 		"x402Version": x402Version,
 	})
 }
+
+// formTarget splits resource into the action of a GET form and the hidden
+// fields that keep its query parameters, except for the field named skip.
+func formTarget(resource string, skip string) (string, []gin.H) {
+	target, err := url.Parse(resource)
+	if err != nil || target.RawQuery == "" {
+		return resource, nil
+	}
+
+	query := target.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if key != skip {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var hidden []gin.H
+	for _, key := range keys {
+		for _, value := range query[key] {
+			hidden = append(hidden, gin.H{"Name": key, "Value": value})
+		}
+	}
+
+	target.RawQuery = ""
+	return target.String(), hidden
+}
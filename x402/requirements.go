@@ -0,0 +1,90 @@
+package x402
+
+import (
+	"strconv"
+
+	x402http "github.com/coinbase/x402/go/http"
+	x402evm "github.com/coinbase/x402/go/mechanisms/evm"
+	x402types "github.com/coinbase/x402/go/types"
+)
+
+// USDC contracts payments are made with.
+const (
+	usdcBase        = "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"
+	usdcBaseSepolia = "0x036CbD53842c5426634e7929541eC2318f3dCF7e"
+)
+
+// newPaymentOptions returns the default options with opts applied.
+func newPaymentOptions(opts []Options) *PaymentOptions {
+	options := &PaymentOptions{
+		FacilitatorURL:    x402http.DefaultFacilitatorURL,
+		MaxTimeoutSeconds: 60,
+		Testnet:           true,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// RequirementsV1 returns the x402 v1 requirements Payment issues for a
+// payment of amount to address, for the resource of the options.
+func RequirementsV1(amount uint64, address string, opts ...Options) (x402types.PaymentRequirementsV1, error) {
+	options := newPaymentOptions(opts)
+	return requirementsV1(amount, address, options.Resource, options)
+}
+
+func requirementsV1(amount uint64, address string, resource string,
+	options *PaymentOptions) (x402types.PaymentRequirementsV1, error) {
+
+	network := "base"
+	usdcAddress := usdcBase
+	if options.Testnet {
+		network = "base-sepolia"
+		usdcAddress = usdcBaseSepolia
+	}
+
+	requirements := x402types.PaymentRequirementsV1{
+		Scheme:            "exact",
+		Network:           network,
+		MaxAmountRequired: strconv.FormatUint(amount, 10),
+		Resource:          resource,
+		Description:       options.Description,
+		MimeType:          options.MimeType,
+		PayTo:             address,
+		MaxTimeoutSeconds: options.MaxTimeoutSeconds,
+		Asset:             usdcAddress,
+		OutputSchema:      options.OutputSchema,
+	}
+	if err := setUSDCInfoV1(&requirements, options.Testnet); err != nil {
+		return x402types.PaymentRequirementsV1{}, err
+	}
+	return requirements, nil
+}
+
+// RequirementsV2 returns the x402 v2 requirements of a payment of amount to
+// address in USDC, as the exact EVM scheme issues them. Only the Testnet and
+// MaxTimeoutSeconds options apply, v2 describes the resource apart from its
+// requirements.
+func RequirementsV2(amount uint64, address string, opts ...Options) x402types.PaymentRequirements {
+	options := newPaymentOptions(opts)
+
+	network := "eip155:8453"
+	if options.Testnet {
+		network = "eip155:84532"
+	}
+	usdc := x402evm.NetworkConfigs[network].DefaultAsset
+
+	return x402types.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           network,
+		Asset:             usdc.Address,
+		Amount:            strconv.FormatUint(amount, 10),
+		PayTo:             address,
+		MaxTimeoutSeconds: options.MaxTimeoutSeconds,
+		Extra: map[string]interface{}{
+			"name":    usdc.Name,
+			"version": usdc.Version,
+		},
+	}
+}
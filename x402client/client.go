@@ -0,0 +1,476 @@
+// Package x402client pays for HTTP requests to x402 protected resources,
+// such as proxy402 routes. Its Transport is an http.RoundTripper that answers
+// 402 responses with a signed EIP-3009 USDC authorization and retries the
+// request, within the spending limits it is configured with.
+package x402client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+
+	x402core "github.com/coinbase/x402/go"
+	x402evm "github.com/coinbase/x402/go/mechanisms/evm"
+	x402evmclient "github.com/coinbase/x402/go/mechanisms/evm/exact/client"
+	x402evmclientv1 "github.com/coinbase/x402/go/mechanisms/evm/exact/v1/client"
+	x402evmsigners "github.com/coinbase/x402/go/signers/evm"
+	x402types "github.com/coinbase/x402/go/types"
+)
+
+// Headers of the x402 protocol versions.
+const (
+	PaymentHeaderV1         = "X-PAYMENT"
+	PaymentResponseHeaderV1 = "X-PAYMENT-RESPONSE"
+
+	PaymentRequiredHeaderV2 = "PAYMENT-REQUIRED"
+	PaymentHeaderV2         = "PAYMENT-SIGNATURE"
+	PaymentResponseHeaderV2 = "PAYMENT-RESPONSE"
+)
+
+// v1Networks maps the x402 v1 network names payments can be made on to
+// their CAIP-2 IDs, which v2 uses.
+var v1Networks = map[string]string{
+	"base":         "eip155:8453",
+	"base-sepolia": "eip155:84532",
+}
+
+// Custom errors for paying requests
+var (
+	ErrNoPaymentOption    = errors.New("no payment option can be paid")
+	ErrOverRequestLimit   = errors.New("payment exceeds the per-request limit")
+	ErrOverSessionLimit   = errors.New("payment exceeds the per-session limit")
+	ErrInvalidPaymentInfo = errors.New("invalid payment required response")
+)
+
+// Payment is a payment a Transport made.
+type Payment struct {
+	// URL is the resource the payment was made for.
+	URL string
+	// Version is the x402 protocol version the payment was made with.
+	Version int
+	Network string
+	PayTo   string
+	// Amount is in base units of the asset paid with (USDC * 10^6).
+	Amount uint64
+}
+
+// Transport is an http.RoundTripper paying for requests answered with 402
+// Payment Required, for x402 v1 (requirements in the body) and v2
+// (requirements in the PAYMENT-REQUIRED header). A payment is reserved
+// against the spending limits before it is signed and released if the
+// retried request is answered with a 402 saying why the payment was
+// rejected. A payment whose retry gets no such answer, not even a response,
+// stays reserved as it may have been settled.
+//
+// Payments are reused for later requests to the same URL if CacheTokens is
+// set, and paid anew once the resource asks for payment again.
+type Transport struct {
+	base   http.RoundTripper
+	client *x402core.X402Client
+	cfg    Config
+
+	mu       sync.Mutex
+	spent    uint64
+	payments []Payment
+	tokens   map[string]token
+}
+
+// token is a payment header a resource accepted.
+type token struct {
+	header string
+	value  string
+}
+
+// NewTransport creates a new Transport signing payments with the signer and
+// sending requests with base, http.DefaultTransport if nil.
+func NewTransport(signer x402evm.ClientEvmSigner, base http.RoundTripper, cfg *Config) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	client := x402core.Newx402Client().
+		Register("eip155:*", x402evmclient.NewExactEvmScheme(signer))
+	for network := range v1Networks {
+		client.RegisterV1(x402core.Network(network), x402evmclientv1.NewExactEvmSchemeV1(signer))
+	}
+
+	return &Transport{
+		base:   base,
+		client: client,
+		cfg:    *cfg,
+		tokens: make(map[string]token),
+	}
+}
+
+// NewTransportFromPrivateKey creates a new Transport signing payments with
+// the hex encoded private key.
+func NewTransportFromPrivateKey(privateKeyHex string, base http.RoundTripper, cfg *Config) (*Transport, error) {
+	signer, err := x402evmsigners.NewClientSignerFromPrivateKey(privateKeyHex)
+	if err != nil {
+		return nil, err
+	}
+	return NewTransport(signer, base, cfg), nil
+}
+
+// NewClient returns an http.Client paying for its requests with the
+// transport.
+func (t *Transport) NewClient() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// Spent returns what the transport's payments cost so far, in base units.
+func (t *Transport) Spent() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.spent
+}
+
+// Payments returns the payments the transport made, oldest first.
+func (t *Transport) Payments() []Payment {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.payments)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The body is sent again with the payment, so it must be replayable.
+	req, err := replayable(req)
+	if err != nil {
+		return nil, err
+	}
+
+	key := tokenKey(req)
+	cached, hasToken := t.token(key)
+
+	first := req
+	if hasToken {
+		first = withHeader(req, cached.header, cached.value)
+	}
+	resp, err := t.base.RoundTrip(first)
+	if err != nil || resp.StatusCode != http.StatusPaymentRequired {
+		return resp, err
+	}
+	if hasToken {
+		// The cached payment has no credits left.
+		t.forgetToken(key, cached)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payment required response: %w", err)
+	}
+
+	payment, header, value, err := t.pay(req.Context(), req.URL.String(), resp.Header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// The payment stays reserved unless the resource rejects it. Without a
+	// response it may still have been settled.
+	paid, err := t.base.RoundTrip(withHeader(req, header, value))
+	if err != nil {
+		return nil, err
+	}
+	if paid.StatusCode == http.StatusPaymentRequired {
+		if rejected(paid) {
+			t.release(payment.Amount)
+		}
+		return paid, nil
+	}
+
+	t.mu.Lock()
+	t.payments = append(t.payments, *payment)
+	if t.cfg.CacheTokens && paid.StatusCode < http.StatusBadRequest {
+		t.tokens[key] = token{header: header, value: value}
+	}
+	t.mu.Unlock()
+
+	return paid, nil
+}
+
+// pay creates a payment for a 402 response and returns it with the header
+// carrying it. The payment is reserved against the spending limits.
+func (t *Transport) pay(ctx context.Context, url string, header http.Header,
+	body []byte) (*Payment, string, string, error) {
+
+	if encoded := header.Get(PaymentRequiredHeaderV2); encoded != "" {
+		return t.payV2(ctx, url, encoded)
+	}
+
+	var versioned struct {
+		X402Version int `json:"x402Version"`
+	}
+	if err := json.Unmarshal(body, &versioned); err != nil {
+		return nil, "", "", ErrInvalidPaymentInfo
+	}
+	switch versioned.X402Version {
+	case 1:
+		return t.payV1(ctx, url, body)
+	case 2:
+		return t.payV2(ctx, url, base64.StdEncoding.EncodeToString(body))
+	default:
+		return nil, "", "", fmt.Errorf("%w: unsupported x402 version %d", ErrInvalidPaymentInfo, versioned.X402Version)
+	}
+}
+
+// payV1 pays x402 v1 requirements, listed in the response body.
+func (t *Transport) payV1(ctx context.Context, url string, body []byte) (*Payment, string, string, error) {
+	var required x402types.PaymentRequiredV1
+	if err := json.Unmarshal(body, &required); err != nil {
+		return nil, "", "", ErrInvalidPaymentInfo
+	}
+
+	accepts := slices.DeleteFunc(required.Accepts, func(r x402types.PaymentRequirementsV1) bool {
+		return !t.allowsNetwork(r.Network)
+	})
+	if len(accepts) == 0 {
+		return nil, "", "", ErrNoPaymentOption
+	}
+	selected, err := t.client.SelectPaymentRequirementsV1(accepts)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("%w: %w", ErrNoPaymentOption, err)
+	}
+
+	payment, err := t.reserve(url, 1, selected.Network, selected.PayTo, selected.MaxAmountRequired)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	payload, err := t.client.CreatePaymentPayloadV1(ctx, selected)
+	if err != nil {
+		t.release(payment.Amount)
+		return nil, "", "", fmt.Errorf("failed to sign payment: %w", err)
+	}
+	value, err := encodePayload(payload)
+	if err != nil {
+		t.release(payment.Amount)
+		return nil, "", "", err
+	}
+
+	return payment, PaymentHeaderV1, value, nil
+}
+
+// payV2 pays x402 v2 requirements, base64 encoded in the PAYMENT-REQUIRED
+// header.
+func (t *Transport) payV2(ctx context.Context, url string, encoded string) (*Payment, string, string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, "", "", ErrInvalidPaymentInfo
+	}
+	var required x402types.PaymentRequired
+	if err := json.Unmarshal(decoded, &required); err != nil {
+		return nil, "", "", ErrInvalidPaymentInfo
+	}
+
+	accepts := slices.DeleteFunc(required.Accepts, func(r x402types.PaymentRequirements) bool {
+		return !t.allowsNetwork(r.Network)
+	})
+	if len(accepts) == 0 {
+		return nil, "", "", ErrNoPaymentOption
+	}
+	selected, err := t.client.SelectPaymentRequirements(accepts)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("%w: %w", ErrNoPaymentOption, err)
+	}
+
+	payment, err := t.reserve(url, 2, selected.Network, selected.PayTo, selected.Amount)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	payload, err := t.client.CreatePaymentPayload(ctx, selected, required.Resource, required.Extensions)
+	if err != nil {
+		t.release(payment.Amount)
+		return nil, "", "", fmt.Errorf("failed to sign payment: %w", err)
+	}
+	value, err := encodePayload(payload)
+	if err != nil {
+		t.release(payment.Amount)
+		return nil, "", "", err
+	}
+
+	return payment, PaymentHeaderV2, value, nil
+}
+
+// reserve checks a payment of amount against the spending limits and adds it
+// to what was spent.
+func (t *Transport) reserve(url string, version int, network string, payTo string,
+	amount string) (*Payment, error) {
+
+	value, err := strconv.ParseUint(amount, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid amount %q", ErrInvalidPaymentInfo, amount)
+	}
+	if t.cfg.MaxPerRequest > 0 && value > t.cfg.MaxPerRequest {
+		return nil, fmt.Errorf("%w: %d > %d", ErrOverRequestLimit, value, t.cfg.MaxPerRequest)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cfg.MaxPerSession > 0 && t.spent+value > t.cfg.MaxPerSession {
+		return nil, fmt.Errorf("%w: %d spent, %d more asked for, limit %d",
+			ErrOverSessionLimit, t.spent, value, t.cfg.MaxPerSession)
+	}
+	t.spent += value
+
+	return &Payment{
+		URL:     url,
+		Version: version,
+		Network: network,
+		PayTo:   payTo,
+		Amount:  value,
+	}, nil
+}
+
+// release takes back a reserved payment that was not made.
+func (t *Transport) release(amount uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spent -= amount
+}
+
+// rejected reports whether a 402 response to a paid request rejects the
+// payment, with an error in its x402 v2 PAYMENT-REQUIRED header or its JSON
+// body. The body is left to be read again.
+func rejected(resp *http.Response) bool {
+	if encoded := resp.Header.Get(PaymentRequiredHeaderV2); encoded != "" {
+		var required x402types.PaymentRequired
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err == nil && json.Unmarshal(decoded, &required) == nil && required.Error != "" {
+			return true
+		}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+
+	var required struct {
+		Error string `json:"error"`
+	}
+	return json.Unmarshal(body, &required) == nil && required.Error != ""
+}
+
+// allowsNetwork reports whether payments may be made on the network. v1
+// names and CAIP-2 IDs of the same network are interchangeable.
+func (t *Transport) allowsNetwork(network string) bool {
+	if len(t.cfg.Networks) == 0 {
+		return true
+	}
+	for _, allowed := range t.cfg.Networks {
+		if caip2(allowed) == caip2(network) {
+			return true
+		}
+	}
+	return false
+}
+
+// caip2 returns the CAIP-2 ID of a network.
+func caip2(network string) string {
+	if id, ok := v1Networks[network]; ok {
+		return id
+	}
+	return network
+}
+
+// token returns the cached payment for the key, if any.
+func (t *Transport) token(key string) (token, bool) {
+	if !t.cfg.CacheTokens {
+		return token{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	cached, ok := t.tokens[key]
+	return cached, ok
+}
+
+// forgetToken removes a cached payment, unless it was replaced already.
+func (t *Transport) forgetToken(key string, cached token) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tokens[key] == cached {
+		delete(t.tokens, key)
+	}
+}
+
+// tokenKey is what payments are cached by. A payment pays for a resource
+// whatever the query of the request.
+func tokenKey(req *http.Request) string {
+	return req.Method + " " + req.URL.Scheme + "://" + req.URL.Host + req.URL.Path
+}
+
+// replayable returns the request with a body that can be read again for the
+// retry.
+func replayable(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return req, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	clone := req.Clone(req.Context())
+	clone.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	clone.Body, _ = clone.GetBody()
+	return clone, nil
+}
+
+// withHeader returns a copy of the request with the header set and a fresh
+// body.
+func withHeader(req *http.Request, name string, value string) *http.Request {
+	clone := req.Clone(req.Context())
+	clone.Header.Set(name, value)
+	if req.GetBody != nil {
+		clone.Body, _ = req.GetBody()
+	}
+	return clone
+}
+
+// encodePayload encodes a payment payload for its header.
+func encodePayload(payload any) (string, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode payment: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(payloadJSON), nil
+}
+
+// SettleResponse returns the settlement a paid response carries in its
+// X-PAYMENT-RESPONSE or PAYMENT-RESPONSE header, nil if it has none.
+func SettleResponse(resp *http.Response) (*x402core.SettleResponse, error) {
+	encoded := resp.Header.Get(PaymentResponseHeaderV2)
+	if encoded == "" {
+		encoded = resp.Header.Get(PaymentResponseHeaderV1)
+	}
+	if encoded == "" {
+		return nil, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payment response header: %w", err)
+	}
+	var settlement x402core.SettleResponse
+	if err := json.Unmarshal(decoded, &settlement); err != nil {
+		return nil, fmt.Errorf("invalid payment response header: %w", err)
+	}
+	return &settlement, nil
+}
@@ -0,0 +1,349 @@
+package x402client
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"linkshrink/packs"
+	"linkshrink/routes"
+)
+
+// protocolVersions are the x402 versions routes are paid with, with the
+// network their testnet payments are made on.
+var protocolVersions = []struct {
+	version uint16
+	network string
+}{
+	{version: routes.PaymentProtocolVersionV1, network: "base-sepolia"},
+	{version: routes.PaymentProtocolVersionV2, network: "eip155:84532"},
+}
+
+func newTestTransport(t *testing.T, base http.RoundTripper, cfg Config) *Transport {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	transport, err := NewTransportFromPrivateKey(hex.EncodeToString(crypto.FromECDSA(key)), base, &cfg)
+	if err != nil {
+		t.Fatalf("NewTransportFromPrivateKey() error = %v", err)
+	}
+	return transport
+}
+
+func get(t *testing.T, client *http.Client, url string) (*http.Response, string, error) {
+	t.Helper()
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	return resp, string(body), nil
+}
+
+func TestTransportPays(t *testing.T) {
+	for _, pv := range protocolVersions {
+		t.Run(fmt.Sprintf("v%d", pv.version), func(t *testing.T) {
+			proxy := newTestProxy(t, pv.version, 2)
+			transport := newTestTransport(t, nil, DefaultConfig())
+			client := transport.NewClient()
+
+			resp, body, err := get(t, client, proxy.url())
+			if err != nil {
+				t.Fatalf("GET error = %v", err)
+			}
+			if resp.StatusCode != http.StatusOK || body != "paid" {
+				t.Fatalf("GET = %d %q, want 200 paid", resp.StatusCode, body)
+			}
+
+			settlement, err := SettleResponse(resp)
+			if err != nil || settlement == nil || settlement.Transaction != "0xabc" {
+				t.Errorf("SettleResponse() = %+v, %v, want transaction 0xabc", settlement, err)
+			}
+			if got := transport.Spent(); got != testPrice {
+				t.Errorf("Spent() = %d, want %d", got, testPrice)
+			}
+			payments := transport.Payments()
+			if len(payments) != 1 || payments[0].Version != int(pv.version) ||
+				payments[0].Network != pv.network || payments[0].PayTo != testPayTo ||
+				payments[0].Amount != testPrice {
+				t.Errorf("Payments() = %+v", payments)
+			}
+
+			// The payment's second credit is used with the cached token,
+			// the third request pays again.
+			for range 2 {
+				resp, _, err := get(t, client, proxy.url())
+				if err != nil || resp.StatusCode != http.StatusOK {
+					t.Fatalf("GET = %v, %v, want 200", resp, err)
+				}
+			}
+			if got := proxy.facilitator.settled; got != 2 {
+				t.Errorf("facilitator settled %d payments, want 2", got)
+			}
+			if got := transport.Spent(); got != 2*testPrice {
+				t.Errorf("Spent() = %d, want %d", got, 2*testPrice)
+			}
+			if got := len(proxy.store.settledPurchases()); got != 2 {
+				t.Errorf("proxy recorded %d purchases, want 2", got)
+			}
+		})
+	}
+}
+
+func TestTransportSelectsRequirements(t *testing.T) {
+	const packPrice = 3 * testPrice
+
+	tests := []struct {
+		name        string
+		query       string
+		cfg         Config
+		wantAmount  uint64
+		wantCredits uint64
+		wantErr     error
+	}{
+		{
+			name:        "route price",
+			cfg:         DefaultConfig(),
+			wantAmount:  testPrice,
+			wantCredits: 1,
+		},
+		{
+			name:        "selected pack",
+			query:       "?" + routes.PackQueryParam + "=7",
+			cfg:         DefaultConfig(),
+			wantAmount:  packPrice,
+			wantCredits: 5,
+		},
+		{
+			name:        "network by v1 name",
+			cfg:         Config{Networks: []string{"base-sepolia"}},
+			wantAmount:  testPrice,
+			wantCredits: 1,
+		},
+		{
+			name:    "network not offered",
+			cfg:     Config{Networks: []string{"eip155:8453"}},
+			wantErr: ErrNoPaymentOption,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := newTestProxy(t, routes.PaymentProtocolVersionV2, 1)
+			proxy.store.packs = []packs.Pack{{ID: 7, PaidRouteID: 1, Credits: 5, Price: packPrice}}
+			transport := newTestTransport(t, nil, tt.cfg)
+
+			resp, _, err := get(t, transport.NewClient(), proxy.url()+tt.query)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GET error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("GET status = %d, want 200", resp.StatusCode)
+			}
+
+			payments := transport.Payments()
+			if len(payments) != 1 || payments[0].Version != 2 || payments[0].Amount != tt.wantAmount {
+				t.Errorf("Payments() = %+v, want one v2 payment of %d", payments, tt.wantAmount)
+			}
+			purchases := proxy.store.settledPurchases()
+			if len(purchases) != 1 || purchases[0].Price != tt.wantAmount ||
+				purchases[0].CreditsAvailable != tt.wantCredits {
+				t.Errorf("proxy recorded %+v, want a purchase of %d credits for %d",
+					purchases, tt.wantCredits, tt.wantAmount)
+			}
+		})
+	}
+}
+
+func TestTransportLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr error
+	}{
+		{
+			name:    "over request limit",
+			cfg:     Config{MaxPerRequest: testPrice - 1},
+			wantErr: ErrOverRequestLimit,
+		},
+		{
+			name:    "over session limit",
+			cfg:     Config{MaxPerSession: testPrice - 1},
+			wantErr: ErrOverSessionLimit,
+		},
+		{
+			name:    "network not allowed",
+			cfg:     Config{Networks: []string{"eip155:8453"}},
+			wantErr: ErrNoPaymentOption,
+		},
+	}
+
+	for _, pv := range protocolVersions {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("v%d %s", pv.version, tt.name), func(t *testing.T) {
+				proxy := newTestProxy(t, pv.version, 1)
+				transport := newTestTransport(t, nil, tt.cfg)
+
+				_, _, err := get(t, transport.NewClient(), proxy.url())
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("GET error = %v, want %v", err, tt.wantErr)
+				}
+				if got := transport.Spent(); got != 0 {
+					t.Errorf("Spent() = %d, want 0", got)
+				}
+				if proxy.facilitator.verified != 0 {
+					t.Errorf("facilitator verified %d payments, want 0", proxy.facilitator.verified)
+				}
+			})
+		}
+	}
+}
+
+func TestTransportReleasesRejectedPayments(t *testing.T) {
+	tests := []struct {
+		name         string
+		invalid      string
+		settleFailed string
+	}{
+		{name: "invalid payment", invalid: "invalid_exact_evm_payload_signature"},
+		{name: "settlement failed", settleFailed: "insufficient_funds"},
+	}
+
+	for _, pv := range protocolVersions {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("v%d %s", pv.version, tt.name), func(t *testing.T) {
+				proxy := newTestProxy(t, pv.version, 1)
+				proxy.facilitator.invalid = tt.invalid
+				proxy.facilitator.settleFailed = tt.settleFailed
+				transport := newTestTransport(t, nil, Config{MaxPerSession: testPrice})
+
+				resp, body, err := get(t, transport.NewClient(), proxy.url())
+				if err != nil {
+					t.Fatalf("GET error = %v", err)
+				}
+				if resp.StatusCode != http.StatusPaymentRequired {
+					t.Errorf("GET status = %d, want 402", resp.StatusCode)
+				}
+				// The rejection is still readable by the caller.
+				resp.Body = io.NopCloser(strings.NewReader(body))
+				if !rejected(resp) {
+					t.Errorf("GET response does not say why the payment was rejected")
+				}
+				if got := transport.Spent(); got != 0 {
+					t.Errorf("Spent() = %d, want 0", got)
+				}
+				if got := len(transport.Payments()); got != 0 {
+					t.Errorf("Payments() has %d payments, want 0", got)
+				}
+
+				// The released reservation can pay once the facilitator
+				// accepts payments again.
+				proxy.facilitator.mu.Lock()
+				proxy.facilitator.invalid, proxy.facilitator.settleFailed = "", ""
+				proxy.facilitator.mu.Unlock()
+				resp, _, err = get(t, transport.NewClient(), proxy.url())
+				if err != nil || resp.StatusCode != http.StatusOK {
+					t.Errorf("GET after the rejection = %v, %v, want 200", resp, err)
+				}
+			})
+		}
+	}
+}
+
+// failingTransport sends paid requests but loses their responses, as if the
+// connection dropped once the payment was settled.
+type failingTransport struct{}
+
+var errConnectionLost = errors.New("connection lost")
+
+func (failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil || req.Header.Get(PaymentHeaderV1) == "" && req.Header.Get(PaymentHeaderV2) == "" {
+		return resp, err
+	}
+	resp.Body.Close()
+	return nil, errConnectionLost
+}
+
+func TestTransportKeepsUnansweredPaymentsReserved(t *testing.T) {
+	for _, pv := range protocolVersions {
+		t.Run(fmt.Sprintf("v%d", pv.version), func(t *testing.T) {
+			proxy := newTestProxy(t, pv.version, 1)
+			transport := newTestTransport(t, failingTransport{}, Config{MaxPerSession: testPrice})
+
+			_, _, err := get(t, transport.NewClient(), proxy.url())
+			if !errors.Is(err, errConnectionLost) {
+				t.Fatalf("GET error = %v, want %v", err, errConnectionLost)
+			}
+			if proxy.facilitator.settled != 1 {
+				t.Fatalf("facilitator settled %d payments, want 1", proxy.facilitator.settled)
+			}
+			if got := transport.Spent(); got != testPrice {
+				t.Errorf("Spent() = %d, want %d", got, testPrice)
+			}
+
+			// The settled payment counts against the session limit.
+			_, _, err = get(t, transport.NewClient(), proxy.url())
+			if !errors.Is(err, ErrOverSessionLimit) {
+				t.Errorf("second GET error = %v, want %v", err, ErrOverSessionLimit)
+			}
+		})
+	}
+}
+
+func TestTransportKeepsUnexplainedPaymentsReserved(t *testing.T) {
+	// A 402 to the paid request that doesn't say the payment was rejected.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(PaymentHeaderV1) != "" {
+			w.WriteHeader(http.StatusPaymentRequired)
+			return
+		}
+		w.WriteHeader(http.StatusPaymentRequired)
+		json.NewEncoder(w).Encode(map[string]any{
+			"x402Version": 1,
+			"error":       "X-PAYMENT header is required",
+			"accepts": []map[string]any{{
+				"scheme":            "exact",
+				"network":           "base-sepolia",
+				"maxAmountRequired": "10000",
+				"resource":          "http://" + r.Host + r.URL.Path,
+				"payTo":             testPayTo,
+				"maxTimeoutSeconds": 60,
+				"asset":             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+				"extra":             map[string]any{"name": "USDC", "version": "2"},
+			}},
+		})
+	}))
+	defer server.Close()
+
+	transport := newTestTransport(t, nil, DefaultConfig())
+	resp, _, err := get(t, transport.NewClient(), server.URL+"/paid")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	if resp.StatusCode != http.StatusPaymentRequired {
+		t.Errorf("GET status = %d, want 402", resp.StatusCode)
+	}
+	if got := transport.Spent(); got != testPrice {
+		t.Errorf("Spent() = %d, want %d", got, testPrice)
+	}
+}
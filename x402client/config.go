@@ -0,0 +1,25 @@
+package x402client
+
+// DefaultConfig returns all default values for the Config struct.
+func DefaultConfig() Config {
+	return Config{
+		MaxPerRequest: 1000000,
+		CacheTokens:   true,
+	}
+}
+
+// Config holds the configuration of a Transport. Amounts are in base units
+// of the asset paid with (USDC * 10^6).
+type Config struct {
+	MaxPerRequest uint64 `long:"max_per_request" description:"Most a single payment may cost, 0 means no limit"`
+	MaxPerSession uint64 `long:"max_per_session" description:"Most all payments of the transport may cost together, 0 means no limit"`
+
+	// Networks limits the networks payments are made on, by x402 v1 name
+	// (base, base-sepolia) or CAIP-2 ID (eip155:8453), either matches both
+	// protocol versions. Empty allows any EVM network.
+	Networks []string `long:"network" description:"Network payments may be made on, can be repeated"`
+
+	// CacheTokens reuses the payment of a route for later requests to it,
+	// which proxy402 accepts until the credits it bought are used up.
+	CacheTokens bool `long:"cache_tokens" description:"Reuse payments for routes while they have credits left"`
+}
@@ -0,0 +1,382 @@
+package x402client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"linkshrink/affiliates"
+	"linkshrink/bundles"
+	"linkshrink/cache"
+	"linkshrink/coupons"
+	"linkshrink/idempotency"
+	"linkshrink/ledger"
+	"linkshrink/packs"
+	"linkshrink/payers"
+	"linkshrink/purchases"
+	"linkshrink/reconciler"
+	"linkshrink/routes"
+	"linkshrink/splits"
+	"linkshrink/upstreams"
+	"linkshrink/users"
+	"linkshrink/utils"
+	"linkshrink/wallets"
+)
+
+const (
+	testPrice = 10000
+	testPayTo = "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"
+)
+
+// mockFacilitator verifies and settles every payment unless told to reject
+// them. Like a real facilitator, it answers rejections with 400 Bad Request
+// and no longer verifies an authorization once it settled it.
+type mockFacilitator struct {
+	mu           sync.Mutex
+	invalid      string
+	settleFailed string
+	verified     int
+	settled      int
+	used         map[purchases.PaymentNonce]bool
+}
+
+func (f *mockFacilitator) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/supported" {
+		json.NewEncoder(w).Encode(map[string]any{
+			"kinds": []map[string]any{
+				{"x402Version": 1, "scheme": "exact", "network": "base-sepolia"},
+				{"x402Version": 2, "scheme": "exact", "network": "eip155:84532"},
+			},
+			"extensions": []string{},
+			"signers":    map[string][]string{},
+		})
+		return
+	}
+
+	var req struct {
+		PaymentPayload      json.RawMessage `json:"paymentPayload"`
+		PaymentRequirements struct {
+			Network string `json:"network"`
+		} `json:"paymentRequirements"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.PaymentPayload) == 0 {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	nonce, err := purchases.ParsePaymentNonce(req.PaymentPayload)
+	if err != nil {
+		http.Error(w, "invalid payment payload", http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/verify":
+		f.verified++
+		invalid := f.invalid
+		if f.used[*nonce] {
+			invalid = "invalid_exact_evm_payload_authorization_nonce_used"
+		}
+		if invalid != "" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"isValid":       invalid == "",
+			"invalidReason": invalid,
+			"payer":         nonce.Payer,
+		})
+	case "/settle":
+		f.settled++
+		if f.settleFailed == "" {
+			f.used[*nonce] = true
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"success":     f.settleFailed == "",
+			"errorReason": f.settleFailed,
+			"payer":       nonce.Payer,
+			"transaction": "0xabc",
+			"network":     req.PaymentRequirements.Network,
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Embedded store interfaces are named after their package, each package
+// names its own Store.
+type (
+	routeStore      = routes.Store
+	purchaseStore   = purchases.Store
+	userStore       = users.Store
+	payerStore      = payers.Store
+	packStore       = packs.Store
+	couponStore     = coupons.Store
+	bundleStore     = bundles.Store
+	splitStore      = splits.Store
+	reconcilerStore = reconciler.Store
+	upstreamStore   = upstreams.Store
+)
+
+// proxyStore keeps the proxy's route and purchases in memory, for the
+// methods of the stores a paid request goes through.
+type proxyStore struct {
+	routeStore
+	purchaseStore
+	userStore
+	payerStore
+	packStore
+	couponStore
+	bundleStore
+	splitStore
+	reconcilerStore
+	upstreamStore
+
+	mu        sync.Mutex
+	route     *routes.PaidRoute
+	packs     []packs.Pack
+	purchases []*purchases.Purchase
+	nonces    map[purchases.PaymentNonce]uint64
+}
+
+func (s *proxyStore) FindRouteByShortCode(_ context.Context, shortCode string) (*routes.PaidRoute, error) {
+	if shortCode != s.route.ShortCode {
+		return nil, routes.ErrRouteNotFound
+	}
+	route := *s.route
+	return &route, nil
+}
+
+func (s *proxyStore) IncrementRouteAttemptCount(context.Context, string) error { return nil }
+
+func (s *proxyStore) IncrementRoutePaymentCount(context.Context, string) error { return nil }
+
+func (s *proxyStore) IncrementRouteAccessCount(context.Context, string) error { return nil }
+
+func (s *proxyStore) FindUserByID(_ context.Context, id uint64) (*users.User, error) {
+	return &users.User{ID: id}, nil
+}
+
+func (s *proxyStore) GetPayerRuleForRoute(context.Context, uint64, uint64, string) (*payers.Rule, error) {
+	return nil, payers.ErrRuleNotFound
+}
+
+func (s *proxyStore) ListRoutePacks(context.Context, uint64) ([]packs.Pack, error) {
+	return s.packs, nil
+}
+
+func (s *proxyStore) GetRouteTrial(context.Context, uint64) (uint64, error) { return 0, nil }
+
+func (s *proxyStore) GetBundlePurchaseByRouteIDAndPaymentHeader(context.Context, uint64,
+	string) (*purchases.Purchase, error) {
+
+	return nil, purchases.ErrPurchaseNotFound
+}
+
+func (s *proxyStore) ListRouteSplits(context.Context, uint64) ([]splits.Split, error) {
+	return nil, nil
+}
+
+func (s *proxyStore) CreateSettlementCheck(context.Context, uint64, string, string) error { return nil }
+
+func (s *proxyStore) ListRouteTargets(context.Context, uint64) ([]upstreams.Target, error) {
+	return nil, nil
+}
+
+func (s *proxyStore) CreatePurchase(_ context.Context, purchase *purchases.Purchase) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if purchase.PaymentNonce != nil {
+		if _, ok := s.nonces[*purchase.PaymentNonce]; ok {
+			return 0, purchases.ErrPaymentReused
+		}
+	}
+
+	created := *purchase
+	created.ID = uint64(len(s.purchases) + 1)
+	if created.Status == "" {
+		created.Status = purchases.StatusSettled
+	}
+	s.purchases = append(s.purchases, &created)
+	if purchase.PaymentNonce != nil {
+		s.nonces[*purchase.PaymentNonce] = created.ID
+	}
+	return created.ID, nil
+}
+
+func (s *proxyStore) GetPurchaseByRouteIDAndPaymentHeader(_ context.Context, routeID uint64,
+	paymentHeader string) (*purchases.Purchase, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.purchases) - 1; i >= 0; i-- {
+		purchase := s.purchases[i]
+		if purchase.PaidRouteID == routeID && purchase.PaymentHeader == paymentHeader &&
+			purchase.Status == purchases.StatusSettled {
+
+			found := *purchase
+			return &found, nil
+		}
+	}
+	return nil, purchases.ErrPurchaseNotFound
+}
+
+func (s *proxyStore) GetPurchaseByID(_ context.Context, purchaseID uint64) (*purchases.Purchase, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if purchaseID == 0 || purchaseID > uint64(len(s.purchases)) {
+		return nil, purchases.ErrPurchaseNotFound
+	}
+	found := *s.purchases[purchaseID-1]
+	return &found, nil
+}
+
+func (s *proxyStore) SettlePurchase(_ context.Context, purchaseID uint64, settleResponse []byte,
+	_ *purchases.Accounting) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	purchase := s.purchases[purchaseID-1]
+	purchase.Status = purchases.StatusSettled
+	purchase.SettleResponse = settleResponse
+	return nil
+}
+
+func (s *proxyStore) FailPurchase(_ context.Context, purchaseID uint64, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	purchase := s.purchases[purchaseID-1]
+	purchase.Status = purchases.StatusFailed
+	purchase.SettleError = reason
+	// The payment transferred nothing and can be used again.
+	delete(s.nonces, *purchase.PaymentNonce)
+	return nil
+}
+
+func (s *proxyStore) IncrementPurchaseCreditsUsed(_ context.Context, use ledger.CreditUse) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	purchase := s.purchases[use.PurchaseID-1]
+	if purchase.CreditsUsed >= purchase.CreditsAvailable {
+		return 0, purchases.ErrNoCreditsLeft
+	}
+	purchase.CreditsUsed++
+	return purchase.CreditsUsed, nil
+}
+
+// settledPurchases returns the purchases whose payment settled.
+func (s *proxyStore) settledPurchases() []purchases.Purchase {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var settled []purchases.Purchase
+	for _, purchase := range s.purchases {
+		if purchase.Status == purchases.StatusSettled {
+			settled = append(settled, *purchase)
+		}
+	}
+	return settled
+}
+
+// testProxy serves a route with the proxy's PaidRouteHandler, paid through
+// a mock facilitator. The route proxies to an upstream answering "paid".
+type testProxy struct {
+	facilitator *mockFacilitator
+	store       *proxyStore
+	server      *httptest.Server
+}
+
+// newTestProxy creates a proxy serving a testnet route at /paid, priced at
+// testPrice for credits requests, with the x402 protocol version.
+func newTestProxy(t *testing.T, version uint16, credits uint64) *testProxy {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	facilitator := &mockFacilitator{used: make(map[purchases.PaymentNonce]bool)}
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(facilitator.serve))
+	t.Cleanup(facilitatorServer.Close)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "paid")
+	}))
+	t.Cleanup(upstream.Close)
+
+	store := &proxyStore{
+		route: &routes.PaidRoute{
+			ID:                     1,
+			ShortCode:              "paid",
+			Method:                 http.MethodGet,
+			TargetURL:              upstream.URL,
+			ResourceType:           "url",
+			Price:                  testPrice,
+			Type:                   "credit",
+			Credits:                credits,
+			PaymentProtocolVersion: version,
+			IsTest:                 true,
+			UserID:                 1,
+			IsEnabled:              true,
+			BillingMode:            routes.BillingModeFixed,
+		},
+		nonces: make(map[purchases.PaymentNonce]uint64),
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clock := utils.NewRealClock()
+	cacheCfg := cache.DefaultConfig()
+	responseCache, err := cache.New(&cacheCfg, clock)
+	if err != nil {
+		t.Fatalf("failed to create response cache: %v", err)
+	}
+	upstreamsCfg := upstreams.DefaultConfig()
+	splitsCfg := splits.DefaultConfig()
+	ledgerCfg := ledger.DefaultConfig()
+	reconcilerCfg := reconciler.Config{}
+	idempotencyCfg := idempotency.DefaultConfig()
+	walletsCfg := wallets.DefaultConfig()
+	routesCfg := routes.DefaultConfig()
+	routesCfg.X402PaymentAddress = testPayTo
+	routesCfg.X402FacilitatorURL = facilitatorServer.URL
+
+	// Requests without a wallet, referral or Idempotency-Key never reach
+	// the stores left nil.
+	handler := routes.NewPaidRouteHandler(
+		routes.NewPaidRouteService(logger, store, nil, clock),
+		purchases.NewPurchaseService(logger, store),
+		users.NewUserService(logger, store),
+		responseCache,
+		upstreams.NewService(logger, store, &upstreamsCfg, clock),
+		splits.NewService(logger, store, &splitsCfg),
+		ledger.NewService(logger, nil, &ledgerCfg),
+		reconciler.NewReconciler(logger, store, &reconcilerCfg, nil, clock),
+		idempotency.NewService(logger, nil, &idempotencyCfg, clock),
+		coupons.NewService(logger, store, clock),
+		payers.NewService(logger, store),
+		packs.NewService(logger, store),
+		wallets.NewService(logger, nil, &walletsCfg, clock),
+		bundles.NewService(logger, store, clock),
+		affiliates.NewService(logger, nil),
+		nil, nil, routesCfg, logger,
+	)
+
+	router := gin.New()
+	router.Any("/:shortCode", handler.HandlePaidRoute)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return &testProxy{facilitator: facilitator, store: store, server: server}
+}
+
+// url returns the URL of the proxy's route.
+func (p *testProxy) url() string {
+	return p.server.URL + "/paid"
+}